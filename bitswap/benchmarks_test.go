@@ -118,6 +118,35 @@ func BenchmarkFixedDelay(b *testing.B) {
 	printResults(benchmarkLog)
 }
 
+func BenchmarkConditionedNetwork(b *testing.B) {
+	benchmarkLog = nil
+	// the jitter is reproducible across runs with the same seed
+	benchmarkSeed, _ := strconv.ParseInt(os.Getenv("BENCHMARK_SEED"), 10, 64)
+	conditions := tn.FixedLinkConditions(tn.LinkConditions{
+		Latency:   10 * time.Millisecond,
+		Jitter:    10 * time.Millisecond,
+		Bandwidth: mediumBandwidth,
+	})
+	bstoreLatency := time.Duration(0)
+
+	for _, bch := range benches {
+		b.Run(bch.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				net := tn.ConditionedVirtualNetwork(mockrouting.NewServer(), benchmarkSeed, conditions)
+				ig := testinstance.NewTestInstanceGenerator(net, nil, nil)
+				instances := ig.Instances(bch.nodeCount)
+				blocks := testutil.GenerateBlocksOfSize(bch.blockCount, stdBlockSize)
+				runDistribution(b, instances, blocks, bstoreLatency, bch.distFn, bch.fetchFn)
+				ig.Close()
+			}
+		})
+	}
+
+	out, _ := json.MarshalIndent(benchmarkLog, "", "  ")
+	_ = os.WriteFile("tmp/conditioned-benchmark.json", out, 0o666)
+	printResults(benchmarkLog)
+}
+
 type mixedBench struct {
 	bench
 	fetcherCount int // number of nodes that fetch data
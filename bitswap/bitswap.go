@@ -45,9 +45,10 @@ type bitswap interface {
 }
 
 var (
-	_                  exchange.SessionExchange = (*Bitswap)(nil)
-	_                  bitswap                  = (*Bitswap)(nil)
-	HasBlockBufferSize                          = defaults.HasBlockBufferSize
+	_                  exchange.SessionExchange  = (*Bitswap)(nil)
+	_                  exchange.DeletionNotifier = (*Bitswap)(nil)
+	_                  bitswap                   = (*Bitswap)(nil)
+	HasBlockBufferSize                           = defaults.HasBlockBufferSize
 )
 
 type Bitswap struct {
@@ -116,6 +117,7 @@ type Stat struct {
 	BlocksSent       uint64
 	DataSent         uint64
 	ProvideBufLen    int
+	WantsShed        uint64
 }
 
 func (bs *Bitswap) Stat() (*Stat, error) {
@@ -139,6 +141,7 @@ func (bs *Bitswap) Stat() (*Stat, error) {
 		BlocksSent:       ss.BlocksSent,
 		DataSent:         ss.DataSent,
 		ProvideBufLen:    ss.ProvideBufLen,
+		WantsShed:        ss.WantsShed,
 	}, nil
 }
 
@@ -3,8 +3,11 @@ package bitswap_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sync"
 	"testing"
@@ -826,3 +829,55 @@ func TestWithScoreLedger(t *testing.T) {
 		t.Fatal("Expected the score ledger to be closed within 5s")
 	}
 }
+
+func TestServerSnapshot(t *testing.T) {
+	net := tn.VirtualNetwork(mockrouting.NewServer(), delay.Fixed(kNetworkDelay))
+	ig := testinstance.NewTestInstanceGenerator(net, nil, nil)
+	defer ig.Close()
+
+	instances := ig.Instances(2)
+	a, b := instances[0], instances[1]
+	bg := blocksutil.NewBlockGenerator()
+	blk := bg.Next()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := b.Exchange.GetBlocks(ctx, []cid.Cid{blk.Cid()}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ps server.PeerSnapshot
+	if err := tu.WaitFor(ctx, func() error {
+		snap := a.Exchange.Snapshot()
+		if len(snap.Peers) != 1 || len(snap.Peers[0].Wantlist) != 1 {
+			return fmt.Errorf("expected one peer with one want, got %+v", snap.Peers)
+		}
+		ps = snap.Peers[0]
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if ps.Peer != b.Peer.String() || ps.Wantlist[0].Cid != blk.Cid().String() {
+		t.Fatalf("unexpected snapshot %+v", ps)
+	}
+
+	h := a.Exchange.DebugHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?peer="+b.Peer.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var got server.PeerSnapshot
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Peer != ps.Peer || len(got.Wantlist) != 1 || got.Wantlist[0].Cid != blk.Cid().String() {
+		t.Fatalf("unexpected peer snapshot %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?peer=invalid", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/ipfs/boxo/bitswap"
+	"github.com/ipfs/boxo/bitswap/client"
 	"github.com/ipfs/boxo/bitswap/client/internal/session"
 	"github.com/ipfs/boxo/bitswap/client/traceability"
 	testinstance "github.com/ipfs/boxo/bitswap/testinstance"
@@ -17,6 +18,7 @@ import (
 	cid "github.com/ipfs/go-cid"
 	blocksutil "github.com/ipfs/go-ipfs-blocksutil"
 	delay "github.com/ipfs/go-ipfs-delay"
+	ipld "github.com/ipfs/go-ipld-format"
 	tu "github.com/libp2p/go-libp2p-testing/etc"
 	"github.com/libp2p/go-libp2p/core/peer"
 )
@@ -82,6 +84,46 @@ func TestBasicSessions(t *testing.T) {
 	}
 }
 
+func TestSessionStats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	vnet := getVirtualNetwork()
+	ig := testinstance.NewTestInstanceGenerator(vnet, nil, nil)
+	defer ig.Close()
+	bgen := blocksutil.NewBlockGenerator()
+
+	block := bgen.Next()
+	inst := ig.Instances(2)
+
+	a := inst[0]
+	b := inst[1]
+
+	// Add a block to Peer B
+	if err := b.Blockstore().Put(ctx, block); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a session on Peer A and get the block
+	sesa := a.Exchange.NewSession(ctx)
+	if _, err := sesa.GetBlock(ctx, block.Cid()); err != nil {
+		t.Fatal(err)
+	}
+
+	st := sesa.(interface{ Stats() client.SessionStat }).Stats()
+	if st.BlocksReceived != 1 {
+		t.Fatalf("expected session to receive 1 block, got %d", st.BlocksReceived)
+	}
+	if st.WantsInFlight != 0 || st.WantsPending != 0 {
+		t.Fatal("expected no outstanding wants")
+	}
+
+	stats := a.Exchange.SessionStats()
+	if len(stats) != 1 || stats[0].ID != st.ID {
+		t.Fatal("expected stats for the active session")
+	}
+}
+
 func assertBlockListsFrom(from peer.ID, got, exp []blocks.Block) error {
 	if len(got) != len(exp) {
 		return fmt.Errorf("got wrong number of blocks, %d != %d", len(got), len(exp))
@@ -497,3 +539,129 @@ func TestWantlistClearsOnCancel(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type mapFetcher map[cid.Cid]blocks.Block
+
+func (f mapFetcher) GetBlock(ctx context.Context, k cid.Cid) (blocks.Block, error) {
+	if blk, ok := f[k]; ok {
+		return blk, nil
+	}
+	return nil, ipld.ErrNotFound{Cid: k}
+}
+
+func (f mapFetcher) GetBlocks(ctx context.Context, ks []cid.Cid) (<-chan blocks.Block, error) {
+	out := make(chan blocks.Block, len(ks))
+	for _, k := range ks {
+		if blk, ok := f[k]; ok {
+			out <- blk
+		}
+	}
+	close(out)
+	return out, nil
+}
+
+func TestExhaustedWantTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bgen := blocksutil.NewBlockGenerator()
+	blks := bgen.Blocks(3)
+	fallback := mapFetcher{blks[2].Cid(): blks[2]}
+
+	vnet := getVirtualNetwork()
+	ig := testinstance.NewTestInstanceGenerator(vnet, nil, []bitswap.Option{
+		bitswap.ProviderSearchDelay(10 * time.Millisecond),
+		bitswap.WithExhaustedWantTimeout(100 * time.Millisecond),
+		bitswap.WithFallbackExchange(fallback),
+	})
+	defer ig.Close()
+
+	inst := ig.Instances(2)
+	a, b := inst[0], inst[1]
+	addBlock(t, ctx, a, blks[0])
+
+	ses := b.Exchange.NewSession(ctx)
+	if _, err := ses.GetBlock(ctx, blks[0].Cid()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the only peer of the session does not have the block, nor the fallback
+	start := time.Now()
+	_, err := ses.GetBlock(ctx, blks[1].Cid())
+	var unavailable client.ErrBlockUnavailable
+	if !errors.As(err, &unavailable) || !unavailable.Cid.Equals(blks[1].Cid()) {
+		t.Fatalf("expected block to be unavailable, got %v", err)
+	}
+	if !ipld.IsNotFound(err) {
+		t.Fatal("expected a not found error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("expected to give up on the block before the context is done")
+	}
+	if wl := b.Exchange.GetWantlist(); len(wl) != 0 {
+		t.Fatalf("expected an empty wantlist, got %v", wl)
+	}
+
+	// the fallback has the block
+	blk, err := ses.GetBlock(ctx, blks[2].Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !blk.Cid().Equals(blks[2].Cid()) {
+		t.Fatal("unexpected block")
+	}
+}
+
+func TestMaxBlockSize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	type violation struct {
+		p    peer.ID
+		c    cid.Cid
+		size int
+	}
+	violations := make(chan violation, 1)
+
+	vnet := getVirtualNetwork()
+	ig := testinstance.NewTestInstanceGenerator(vnet, nil, []bitswap.Option{
+		bitswap.ProviderSearchDelay(10 * time.Millisecond),
+		bitswap.WithMaxBlockSize(1024),
+		bitswap.WithOversizedBlockHandler(func(p peer.ID, c cid.Cid, size int) {
+			select {
+			case violations <- violation{p, c, size}:
+			default:
+			}
+		}),
+	})
+	defer ig.Close()
+
+	inst := ig.Instances(2)
+	a, b := inst[0], inst[1]
+	small := blocks.NewBlock(make([]byte, 1024))
+	large := blocks.NewBlock(make([]byte, 1025))
+	addBlock(t, ctx, a, small)
+	addBlock(t, ctx, a, large)
+
+	if _, err := b.Exchange.GetBlock(ctx, small.Cid()); err != nil {
+		t.Fatal(err)
+	}
+
+	getCtx, getCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer getCancel()
+	if _, err := b.Exchange.GetBlock(getCtx, large.Cid()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the oversized block to be rejected, got %v", err)
+	}
+
+	select {
+	case v := <-violations:
+		if v.p != a.Peer || !v.c.Equals(large.Cid()) || v.size != 1025 {
+			t.Fatalf("unexpected violation %v", v)
+		}
+	case <-ctx.Done():
+		t.Fatal("expected the handler to be called")
+	}
+	if has, err := b.Blockstore().Has(ctx, large.Cid()); err != nil || has {
+		t.Fatal("expected the oversized block not to be stored")
+	}
+}
@@ -5,9 +5,11 @@ package client
 import (
 	"context"
 	"errors"
+	"net/netip"
 	"sync"
 	"time"
 
+	clientinternal "github.com/ipfs/boxo/bitswap/client/internal"
 	bsbpm "github.com/ipfs/boxo/bitswap/client/internal/blockpresencemanager"
 	bsgetter "github.com/ipfs/boxo/bitswap/client/internal/getter"
 	bsmq "github.com/ipfs/boxo/bitswap/client/internal/messagequeue"
@@ -99,6 +101,45 @@ func WithoutDuplicatedBlockStats() Option {
 	}
 }
 
+// WithMaxBlockSize sets the maximum size in bytes of the blocks accepted from
+// peers. See [defaults.MaxBlockSize] for the default, and zero disables the
+// limit. Larger blocks are dropped without being stored or delivered, their
+// sender is handled as if it sent a DONT_HAVE so that sessions ask other
+// peers, and the [WithOversizedBlockHandler] handler is called.
+func WithMaxBlockSize(size int) Option {
+	return func(bs *Client) {
+		bs.maxBlockSize = size
+	}
+}
+
+// OversizedBlockHandler is called with the peer, the CID and the size of a
+// block larger than the [WithMaxBlockSize] limit. It can penalize the peer
+// further, for example by disconnecting from it.
+type OversizedBlockHandler func(p peer.ID, c cid.Cid, size int)
+
+// WithOversizedBlockHandler sets the handler called when a peer sends a block
+// larger than the [WithMaxBlockSize] limit.
+func WithOversizedBlockHandler(handler OversizedBlockHandler) Option {
+	return func(bs *Client) {
+		bs.oversizedBlockHandler = handler
+	}
+}
+
+// BroadcastLearning configures [WithBroadcastLearning].
+type BroadcastLearning = bspm.BroadcastLearning
+
+// WithBroadcastLearning learns, for every peer, the ratio of broadcast
+// want-haves it answered with a HAVE or the block, and stops broadcasting
+// want-haves to the peers which rarely have the blocks, to save the bandwidth
+// of broadcasting to hundreds of peers. The hit rates decay over time, so
+// that suppressed peers are tried again. Wants sent to the peers of a session
+// are not affected.
+func WithBroadcastLearning(cfg BroadcastLearning) Option {
+	return func(bs *Client) {
+		bs.broadcastLearning = &cfg
+	}
+}
+
 type BlockReceivedNotifier interface {
 	// ReceivedBlocks notifies the decision engine that a peer is well-behaving
 	// and gave us useful data, potentially increasing its score and making us
@@ -154,7 +195,7 @@ func New(parent context.Context, network bsnet.BitSwapNetwork, bstore blockstore
 		rebroadcastDelay delay.D,
 		self peer.ID,
 	) bssm.Session {
-		return bssession.New(sessctx, sessmgr, id, spm, pqm, sim, pm, bpm, notif, provSearchDelay, rebroadcastDelay, self)
+		return bssession.New(sessctx, sessmgr, id, spm, pqm, sim, pm, bpm, notif, provSearchDelay, rebroadcastDelay, self, bs.localPeerFilter(sessctx), bs.relayedPeerFilter(), bs.sessionPeerOracle(), bs.missPolicy())
 	}
 	sessionPeerManagerFactory := func(ctx context.Context, id uint64) bssession.SessionPeerManager {
 		return bsspm.New(id, network.ConnectionManager())
@@ -177,6 +218,7 @@ func New(parent context.Context, network bsnet.BitSwapNetwork, bstore blockstore
 		provSearchDelay:            defaults.ProvSearchDelay,
 		rebroadcastDelay:           delay.Fixed(defaults.RebroadcastDelay),
 		simulateDontHavesOnTimeout: true,
+		maxBlockSize:               defaults.MaxBlockSize,
 	}
 
 	// apply functional options before starting and running bitswap
@@ -184,6 +226,10 @@ func New(parent context.Context, network bsnet.BitSwapNetwork, bstore blockstore
 		option(bs)
 	}
 
+	if bs.broadcastLearning != nil {
+		pm.EnableBroadcastLearning(*bs.broadcastLearning)
+	}
+
 	bs.pqm.Startup()
 
 	// bind the context and process.
@@ -249,6 +295,27 @@ type Client struct {
 
 	// dupMetric will stay at 0
 	skipDuplicatedBlocksStats bool
+
+	// subnets of the peers preferred by sessions, see WithLocalPeerPreference
+	localSubnets []netip.Prefix
+	// whether sessions avoid relayed peers, see WithDirectPeerPreference
+	preferDirectPeers bool
+
+	// index of the peers having blocks asked by sessions, see WithPeerOracle
+	peerOracle        PeerOracle
+	peerOracleTimeout time.Duration
+
+	// how long sessions wait before giving up on the blocks no peer has
+	exhaustedWantTimeout time.Duration
+	// exchange used for the blocks no peer has
+	fallback exchange.Fetcher
+
+	// blocks larger than maxBlockSize are rejected, see WithMaxBlockSize
+	maxBlockSize          int
+	oversizedBlockHandler OversizedBlockHandler
+
+	// suppresses broadcasts to peers with low hit rates, if not nil
+	broadcastLearning *BroadcastLearning
 }
 
 type counters struct {
@@ -338,6 +405,7 @@ func (bs *Client) receiveBlocksFrom(ctx context.Context, from peer.ID, blks []bl
 	combined = append(combined, haves...)
 	combined = append(combined, dontHaves...)
 	bs.pm.ResponseReceived(from, combined)
+	bs.pm.HavesReceived(from, combined[:len(allKs)+len(haves)])
 
 	// Send all block keys (including duplicates) to any sessions that want them for accounting purpose.
 	bs.sm.ReceiveFrom(ctx, from, allKs, haves, dontHaves)
@@ -372,6 +440,10 @@ func (bs *Client) ReceiveMessage(ctx context.Context, p peer.ID, incoming bsmsg.
 	}
 
 	iblocks := incoming.Blocks()
+	var oversized []cid.Cid
+	if bs.maxBlockSize > 0 {
+		iblocks, oversized = bs.rejectOversizedBlocks(p, iblocks)
+	}
 
 	if len(iblocks) > 0 {
 		bs.updateReceiveCounters(iblocks)
@@ -382,6 +454,8 @@ func (bs *Client) ReceiveMessage(ctx context.Context, p peer.ID, incoming bsmsg.
 
 	haves := incoming.Haves()
 	dontHaves := incoming.DontHaves()
+	// The peer is asked for the rejected blocks no more
+	dontHaves = append(dontHaves[:len(dontHaves):len(dontHaves)], oversized...)
 	if len(iblocks) > 0 || len(haves) > 0 || len(dontHaves) > 0 {
 		// Process blocks
 		err := bs.receiveBlocksFrom(ctx, p, iblocks, haves, dontHaves)
@@ -392,6 +466,35 @@ func (bs *Client) ReceiveMessage(ctx context.Context, p peer.ID, incoming bsmsg.
 	}
 }
 
+// rejectOversizedBlocks splits the blocks received from p between the blocks
+// within the size limit and the CIDs of the larger ones.
+func (bs *Client) rejectOversizedBlocks(p peer.ID, blks []blocks.Block) ([]blocks.Block, []cid.Cid) {
+	var oversized []cid.Cid
+	var accepted []blocks.Block
+	for i, b := range blks {
+		size := len(b.RawData())
+		if size <= bs.maxBlockSize {
+			if oversized != nil {
+				accepted = append(accepted, b)
+			}
+			continue
+		}
+		if oversized == nil {
+			accepted = append(make([]blocks.Block, 0, len(blks)-1), blks[:i]...)
+		}
+
+		log.Warnw("rejecting oversized block", "cid", b.Cid(), "peer", p, "size", size, "max", bs.maxBlockSize)
+		oversized = append(oversized, b.Cid())
+		if bs.oversizedBlockHandler != nil {
+			bs.oversizedBlockHandler(p, b.Cid(), size)
+		}
+	}
+	if oversized == nil {
+		return blks, nil
+	}
+	return accepted, oversized
+}
+
 func (bs *Client) updateReceiveCounters(blocks []blocks.Block) {
 	// Check which blocks are in the datastore
 	// (Note: any errors from the blockstore are simply logged out in
@@ -505,3 +608,59 @@ func (bs *Client) NewSession(ctx context.Context) exchange.Fetcher {
 	defer span.End()
 	return bs.sm.NewSession(ctx, bs.provSearchDelay, bs.rebroadcastDelay)
 }
+
+// SessionPriority is the priority class of a session, see
+// [WithSessionPriority].
+type SessionPriority = clientinternal.SessionPriority
+
+const (
+	// SessionPriorityForeground is for sessions fetching content a user is
+	// waiting for. It is the priority of the sessions created by
+	// [Client.NewSession].
+	SessionPriorityForeground = clientinternal.SessionPriorityForeground
+	// SessionPriorityBackground is for sessions prefetching content.
+	SessionPriorityBackground = clientinternal.SessionPriorityBackground
+)
+
+// SessionOption configures a session created by
+// [Client.NewSessionWithOptions].
+type SessionOption func(*sessionOptions)
+
+type sessionOptions struct {
+	priority      SessionPriority
+	orderedWindow int
+}
+
+// WithSessionPriority sets the priority class of the session, so that
+// background sessions yield to foreground sessions:
+//
+//   - the wants of background sessions rank after the wants of foreground
+//     sessions in the messages sent to each peer, and are sent with lower
+//     priorities, so that peers serve the wants of foreground sessions first.
+//   - the provider searches of foreground sessions start first, looking for
+//     peers for them first when searches are waiting for a free slot. At least
+//     one search of background sessions starts every 5 searches, so that
+//     background sessions are not starved.
+//
+// A want or provider search shared by sessions of both classes keeps the
+// class of the session it was first made for. Blocks are delivered to every
+// session wanting them regardless of their class.
+func WithSessionPriority(p SessionPriority) SessionOption {
+	return func(so *sessionOptions) {
+		so.priority = p
+	}
+}
+
+// NewSessionWithOptions generates a new Bitswap session like
+// [Client.NewSession], configured with the given options.
+func (bs *Client) NewSessionWithOptions(ctx context.Context, opts ...SessionOption) exchange.Fetcher {
+	var so sessionOptions
+	for _, o := range opts {
+		o(&so)
+	}
+	f := bs.NewSession(clientinternal.ContextWithSessionPriority(ctx, so.priority))
+	if so.orderedWindow > 0 {
+		f = orderedFetcher{Fetcher: f, window: so.orderedWindow}
+	}
+	return f
+}
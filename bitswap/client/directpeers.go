@@ -0,0 +1,36 @@
+package client
+
+import (
+	bsnet "github.com/ipfs/boxo/bitswap/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// WithDirectPeerPreference makes sessions only send their want-blocks to the
+// peers connected through a relay when none of the peers with a direct
+// connection, including the ones upgraded by hole punching, can be chosen.
+// Relayed connections are usually slow and limited, so peers behind them
+// are better used as a last resort.
+//
+// It requires a network implementing [bsnet.ConnectionQuality], such as the
+// one returned by [bsnet.NewFromIpfsHost].
+func WithDirectPeerPreference(prefer bool) Option {
+	return func(bs *Client) {
+		bs.preferDirectPeers = prefer
+	}
+}
+
+// relayedPeerFilter returns the function telling the relayed peers of a
+// session, or nil if direct peers are not preferred.
+func (bs *Client) relayedPeerFilter() func(peer.ID) bool {
+	if !bs.preferDirectPeers {
+		return nil
+	}
+	quality, ok := bs.network.(bsnet.ConnectionQuality)
+	if !ok {
+		return nil
+	}
+	return func(p peer.ID) bool {
+		q := quality.ConnQuality(p)
+		return q.Connected && q.Relayed
+	}
+}
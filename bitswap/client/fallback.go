@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	bsgetter "github.com/ipfs/boxo/bitswap/client/internal/getter"
+	bssession "github.com/ipfs/boxo/bitswap/client/internal/session"
+	exchange "github.com/ipfs/boxo/exchange"
+	"github.com/ipfs/go-cid"
+)
+
+// ErrBlockUnavailable is returned by GetBlock, of the client and of its
+// sessions, when all the peers sent DONT_HAVE for the block for longer than
+// the [WithExhaustedWantTimeout] timeout, and the [WithFallbackExchange]
+// exchange, if any, did not have it. It unwraps to an [ipld.ErrNotFound].
+//
+// [ipld.ErrNotFound]: https://pkg.go.dev/github.com/ipfs/go-ipld-format#ErrNotFound
+type ErrBlockUnavailable = bsgetter.ErrBlockUnavailable
+
+// WithExhaustedWantTimeout makes sessions give up on the blocks that all their
+// peers sent DONT_HAVE for, when no other peer with the block is found within
+// the timeout. GetBlock then fails with [ErrBlockUnavailable], and the
+// channels of GetBlocks are closed once all the blocks were either received
+// or given up on. Without it, which is the default, the blocks are wanted
+// until the context is done.
+func WithExhaustedWantTimeout(timeout time.Duration) Option {
+	return func(bs *Client) {
+		bs.exhaustedWantTimeout = timeout
+	}
+}
+
+// WithFallbackExchange makes sessions fetch the blocks that all their peers
+// sent DONT_HAVE for from the given exchange, for example one fetching from
+// HTTP gateways, once the [WithExhaustedWantTimeout] timeout elapsed, or
+// right away without timeout. The blocks are only given up on when the
+// fallback does not have them either. Blocks are not stored by the client, as
+// for blocks received from peers.
+func WithFallbackExchange(fallback exchange.Fetcher) Option {
+	return func(bs *Client) {
+		bs.fallback = fallback
+	}
+}
+
+func (bs *Client) missPolicy() bssession.MissPolicy {
+	mp := bssession.MissPolicy{Timeout: bs.exhaustedWantTimeout}
+	if bs.fallback != nil {
+		mp.Fallback = bs.fetchFallback
+	}
+	return mp
+}
+
+// fetchFallback fetches the blocks from the fallback exchange, and returns the
+// keys it did not have. The fetched blocks are received by the sessions that
+// want them.
+func (bs *Client) fetchFallback(ctx context.Context, ks []cid.Cid) []cid.Cid {
+	if bs.exhaustedWantTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bs.exhaustedWantTimeout)
+		defer cancel()
+	}
+
+	missing := cid.NewSet()
+	for _, k := range ks {
+		missing.Add(k)
+	}
+
+	blks, err := bs.fallback.GetBlocks(ctx, ks)
+	if err != nil {
+		log.Debugw("fallback exchange failed", "error", err)
+		return ks
+	}
+	for blk := range blks {
+		if !missing.Has(blk.Cid()) {
+			continue
+		}
+		missing.Remove(blk.Cid())
+		if err := bs.NotifyNewBlocks(ctx, blk); err != nil {
+			log.Debugw("cannot notify fallback block", "cid", blk.Cid(), "error", err)
+		}
+	}
+	return missing.Keys()
+}
@@ -3,6 +3,7 @@ package getter
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/ipfs/boxo/bitswap/client/internal"
 	notifications "github.com/ipfs/boxo/bitswap/client/internal/notifications"
@@ -15,6 +16,26 @@ import (
 
 var log = logging.Logger("bitswap")
 
+// ErrBlockUnavailable is returned when a block is given up on before the
+// context is done, because all the peers of the session sent DONT_HAVE for it
+// and no fallback exchange had it.
+type ErrBlockUnavailable struct {
+	Cid cid.Cid
+}
+
+func (e ErrBlockUnavailable) Error() string {
+	return fmt.Sprintf("block %s unavailable: no peer has it", e.Cid)
+}
+
+// Unwrap returns an [ipld.ErrNotFound] for the block.
+func (e ErrBlockUnavailable) Unwrap() error {
+	return ipld.ErrNotFound{Cid: e.Cid}
+}
+
+// missErrKey is the context key of the error set when the key requested by
+// SyncGetBlock is missed.
+type missErrKey struct{}
+
 // GetBlocksFunc is any function that can take an array of CIDs and return a
 // channel of incoming blocks.
 type GetBlocksFunc func(context.Context, []cid.Cid) (<-chan blocks.Block, error)
@@ -40,6 +61,9 @@ func SyncGetBlock(p context.Context, k cid.Cid, gb GetBlocksFunc) (blocks.Block,
 	ctx, cancel := context.WithCancel(p)
 	defer cancel()
 
+	var missErr error
+	ctx = context.WithValue(ctx, missErrKey{}, &missErr)
+
 	promise, err := gb(ctx, []cid.Cid{k})
 	if err != nil {
 		return nil, err
@@ -52,6 +76,9 @@ func SyncGetBlock(p context.Context, k cid.Cid, gb GetBlocksFunc) (blocks.Block,
 			case <-ctx.Done():
 				return nil, ctx.Err()
 			default:
+				if missErr != nil {
+					return nil, missErr
+				}
 				return nil, errors.New("promise channel was closed")
 			}
 		}
@@ -65,10 +92,11 @@ func SyncGetBlock(p context.Context, k cid.Cid, gb GetBlocksFunc) (blocks.Block,
 type WantFunc func(context.Context, []cid.Cid)
 
 // AsyncGetBlocks take a set of block cids, a pubsub channel for incoming
-// blocks, a want function, and a close function, and returns a channel of
-// incoming blocks.
+// blocks, a channel of missed blocks, a want function, and a close function,
+// and returns a channel of incoming blocks. The keys received on missed are
+// given up on, they can be nil.
 func AsyncGetBlocks(ctx context.Context, sessctx context.Context, keys []cid.Cid, notif notifications.PubSub,
-	want WantFunc, cwants func([]cid.Cid),
+	missed <-chan cid.Cid, want WantFunc, cwants func([]cid.Cid),
 ) (<-chan blocks.Block, error) {
 	ctx, span := internal.StartSpan(ctx, "Getter.AsyncGetBlocks")
 	defer span.End()
@@ -92,16 +120,19 @@ func AsyncGetBlocks(ctx context.Context, sessctx context.Context, keys []cid.Cid
 	want(ctx, keys)
 
 	out := make(chan blocks.Block)
-	go handleIncoming(ctx, sessctx, remaining, promise, out, cwants)
+	go handleIncoming(ctx, sessctx, remaining, promise, missed, out, cwants)
 	return out, nil
 }
 
 // Listens for incoming blocks, passing them to the out channel.
-// If the context is cancelled or the incoming channel closes, calls cfun with
-// any keys corresponding to blocks that were never received.
+// If the context is cancelled, the incoming channel closes or all the
+// remaining keys are missed, calls cfun with any keys corresponding to blocks
+// that were never received.
 func handleIncoming(ctx context.Context, sessctx context.Context, remaining *cid.Set,
-	in <-chan blocks.Block, out chan blocks.Block, cfun func([]cid.Cid),
+	in <-chan blocks.Block, missed <-chan cid.Cid, out chan blocks.Block, cfun func([]cid.Cid),
 ) {
+	missErr, _ := ctx.Value(missErrKey{}).(*error)
+
 	ctx, cancel := context.WithCancel(ctx)
 
 	// Clean up before exiting this function, and call the cancel function on
@@ -130,6 +161,17 @@ func handleIncoming(ctx context.Context, sessctx context.Context, remaining *cid
 			case <-sessctx.Done():
 				return
 			}
+		case k := <-missed:
+			if !remaining.Has(k) {
+				continue
+			}
+			remaining.Remove(k)
+			if missErr != nil {
+				*missErr = ErrBlockUnavailable{Cid: k}
+			}
+			if remaining.Len() == 0 {
+				return
+			}
 		case <-ctx.Done():
 			return
 		case <-sessctx.Done():
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	bswl "github.com/ipfs/boxo/bitswap/client/wantlist"
 	bsmsg "github.com/ipfs/boxo/bitswap/message"
 	pb "github.com/ipfs/boxo/bitswap/message/pb"
@@ -36,6 +37,9 @@ const (
 	sendErrorBackoff = 100 * time.Millisecond
 	// maxPriority is the max priority as defined by the bitswap protocol
 	maxPriority = math.MaxInt32
+	// maxBackgroundPriority is the max priority of the wants of background
+	// sessions, so that they rank after the wants of foreground sessions
+	maxBackgroundPriority = maxPriority / 2
 	// sendMessageDebounce is the debounce duration when calling sendMessage()
 	sendMessageDebounce = time.Millisecond
 	// when we reach sendMessageCutoff wants/cancels, we'll send the message immediately.
@@ -89,7 +93,10 @@ type MessageQueue struct {
 	bcstWants recallWantlist
 	peerWants recallWantlist
 	cancels   *cid.Set
-	priority  int32
+	// priority and bgPriority are the priorities of the next wants of
+	// foreground and background sessions
+	priority   int32
+	bgPriority int32
 
 	// Dont touch any of these variables outside of run loop
 	sender                bsnet.MessageSender
@@ -261,6 +268,7 @@ func newMessageQueue(
 		sendErrorBackoff:    sendErrorBackoff,
 		maxValidLatency:     maxValidLatency,
 		priority:            maxPriority,
+		bgPriority:          maxBackgroundPriority,
 		// For performance reasons we just clear out the fields of the message
 		// after using it, instead of creating a new one every time.
 		msg:    bsmsg.New(false),
@@ -269,8 +277,23 @@ func newMessageQueue(
 	}
 }
 
+// nextPriority returns the priority of the next want of a session of the
+// given class. Wants of foreground sessions rank before the wants of
+// background sessions, and wants of the same class rank in the order they were
+// added.
+//
+// Must be called with the wllock held.
+func (mq *MessageQueue) nextPriority(sp internal.SessionPriority) int32 {
+	if sp == internal.SessionPriorityBackground {
+		mq.bgPriority--
+		return mq.bgPriority + 1
+	}
+	mq.priority--
+	return mq.priority + 1
+}
+
 // Add want-haves that are part of a broadcast to all connected peers
-func (mq *MessageQueue) AddBroadcastWantHaves(wantHaves []cid.Cid) {
+func (mq *MessageQueue) AddBroadcastWantHaves(wantHaves []cid.Cid, sp internal.SessionPriority) {
 	if len(wantHaves) == 0 {
 		return
 	}
@@ -279,8 +302,7 @@ func (mq *MessageQueue) AddBroadcastWantHaves(wantHaves []cid.Cid) {
 	defer mq.wllock.Unlock()
 
 	for _, c := range wantHaves {
-		mq.bcstWants.Add(c, mq.priority, pb.Message_Wantlist_Have)
-		mq.priority--
+		mq.bcstWants.Add(c, mq.nextPriority(sp), pb.Message_Wantlist_Have)
 
 		// We're adding a want-have for the cid, so clear any pending cancel
 		// for the cid
@@ -292,7 +314,7 @@ func (mq *MessageQueue) AddBroadcastWantHaves(wantHaves []cid.Cid) {
 }
 
 // Add want-haves and want-blocks for the peer for this message queue.
-func (mq *MessageQueue) AddWants(wantBlocks []cid.Cid, wantHaves []cid.Cid) {
+func (mq *MessageQueue) AddWants(wantBlocks []cid.Cid, wantHaves []cid.Cid, sp internal.SessionPriority) {
 	if len(wantBlocks) == 0 && len(wantHaves) == 0 {
 		return
 	}
@@ -301,16 +323,14 @@ func (mq *MessageQueue) AddWants(wantBlocks []cid.Cid, wantHaves []cid.Cid) {
 	defer mq.wllock.Unlock()
 
 	for _, c := range wantHaves {
-		mq.peerWants.Add(c, mq.priority, pb.Message_Wantlist_Have)
-		mq.priority--
+		mq.peerWants.Add(c, mq.nextPriority(sp), pb.Message_Wantlist_Have)
 
 		// We're adding a want-have for the cid, so clear any pending cancel
 		// for the cid
 		mq.cancels.Remove(c)
 	}
 	for _, c := range wantBlocks {
-		mq.peerWants.Add(c, mq.priority, pb.Message_Wantlist_Block)
-		mq.priority--
+		mq.peerWants.Add(c, mq.nextPriority(sp), pb.Message_Wantlist_Block)
 
 		// We're adding a want-block for the cid, so clear any pending cancel
 		// for the cid
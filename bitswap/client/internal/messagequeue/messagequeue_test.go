@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	"github.com/ipfs/boxo/bitswap/internal/testutil"
 	bsmsg "github.com/ipfs/boxo/bitswap/message"
 	pb "github.com/ipfs/boxo/bitswap/message/pb"
@@ -171,7 +172,7 @@ func TestStartupAndShutdown(t *testing.T) {
 	bcstwh := testutil.GenerateCids(10)
 
 	messageQueue.Startup()
-	messageQueue.AddBroadcastWantHaves(bcstwh)
+	messageQueue.AddBroadcastWantHaves(bcstwh, internal.SessionPriorityForeground)
 	messages := collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 	if len(messages) != 1 {
 		t.Fatal("wrong number of messages were sent for broadcast want-haves")
@@ -210,8 +211,8 @@ func TestSendingMessagesDeduped(t *testing.T) {
 	wantBlocks := testutil.GenerateCids(10)
 
 	messageQueue.Startup()
-	messageQueue.AddWants(wantBlocks, wantHaves)
-	messageQueue.AddWants(wantBlocks, wantHaves)
+	messageQueue.AddWants(wantBlocks, wantHaves, internal.SessionPriorityForeground)
+	messageQueue.AddWants(wantBlocks, wantHaves, internal.SessionPriorityForeground)
 	messages := collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 
 	if totalEntriesLength(messages) != len(wantHaves)+len(wantBlocks) {
@@ -219,6 +220,41 @@ func TestSendingMessagesDeduped(t *testing.T) {
 	}
 }
 
+func TestSendingMessagesBackgroundPriority(t *testing.T) {
+	ctx := context.Background()
+	messagesSent := make(chan []bsmsg.Entry)
+	resetChan := make(chan struct{}, 1)
+	fakeSender := newFakeMessageSender(resetChan, messagesSent, true)
+	fakenet := &fakeMessageNetwork{nil, nil, fakeSender}
+	peerID := testutil.GeneratePeers(1)[0]
+	messageQueue := New(ctx, peerID, fakenet, mockTimeoutCb)
+	bgWants := testutil.GenerateCids(10)
+	fgWants := testutil.GenerateCids(10)
+
+	// background wants added first still rank after foreground wants
+	messageQueue.AddWants(bgWants, nil, internal.SessionPriorityBackground)
+	messageQueue.AddWants(fgWants, nil, internal.SessionPriorityForeground)
+	messageQueue.Startup()
+	messages := collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
+
+	priorities := make(map[cid.Cid]int32)
+	for _, m := range messages {
+		for _, e := range m {
+			priorities[e.Cid] = e.Priority
+		}
+	}
+	if len(priorities) != len(bgWants)+len(fgWants) {
+		t.Fatal("expected all the wants to be sent")
+	}
+	for _, fg := range fgWants {
+		for _, bg := range bgWants {
+			if priorities[fg] <= priorities[bg] {
+				t.Fatal("expected foreground wants to have a higher priority than background wants")
+			}
+		}
+	}
+}
+
 func TestSendingMessagesPartialDupe(t *testing.T) {
 	test.Flaky(t)
 
@@ -233,8 +269,8 @@ func TestSendingMessagesPartialDupe(t *testing.T) {
 	wantBlocks := testutil.GenerateCids(10)
 
 	messageQueue.Startup()
-	messageQueue.AddWants(wantBlocks[:8], wantHaves[:8])
-	messageQueue.AddWants(wantBlocks[3:], wantHaves[3:])
+	messageQueue.AddWants(wantBlocks[:8], wantHaves[:8], internal.SessionPriorityForeground)
+	messageQueue.AddWants(wantBlocks[3:], wantHaves[3:], internal.SessionPriorityForeground)
 	messages := collectMessages(ctx, t, messagesSent, 20*time.Millisecond)
 
 	if totalEntriesLength(messages) != len(wantHaves)+len(wantBlocks) {
@@ -260,8 +296,8 @@ func TestSendingMessagesPriority(t *testing.T) {
 	wantBlocks := append(wantBlocks1, wantBlocks2...)
 
 	messageQueue.Startup()
-	messageQueue.AddWants(wantBlocks1, wantHaves1)
-	messageQueue.AddWants(wantBlocks2, wantHaves2)
+	messageQueue.AddWants(wantBlocks1, wantHaves1, internal.SessionPriorityForeground)
+	messageQueue.AddWants(wantBlocks2, wantHaves2, internal.SessionPriorityForeground)
 	messages := collectMessages(ctx, t, messagesSent, 20*time.Millisecond)
 
 	if totalEntriesLength(messages) != len(wantHaves)+len(wantBlocks) {
@@ -325,7 +361,7 @@ func TestCancelOverridesPendingWants(t *testing.T) {
 	cancels := []cid.Cid{wantBlocks[0], wantHaves[0]}
 
 	messageQueue.Startup()
-	messageQueue.AddWants(wantBlocks, wantHaves)
+	messageQueue.AddWants(wantBlocks, wantHaves, internal.SessionPriorityForeground)
 	messageQueue.AddCancels(cancels)
 	messages := collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 
@@ -377,7 +413,7 @@ func TestWantOverridesPendingCancels(t *testing.T) {
 	messageQueue.Startup()
 
 	// Add 1 want-block and 2 want-haves
-	messageQueue.AddWants(wantBlocks, wantHaves)
+	messageQueue.AddWants(wantBlocks, wantHaves, internal.SessionPriorityForeground)
 
 	messages := collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 	if totalEntriesLength(messages) != len(wantBlocks)+len(wantHaves) {
@@ -387,7 +423,7 @@ func TestWantOverridesPendingCancels(t *testing.T) {
 	// Cancel existing wants
 	messageQueue.AddCancels(cids)
 	// Override one cancel with a want-block (before cancel is sent to network)
-	messageQueue.AddWants(cids[:1], []cid.Cid{})
+	messageQueue.AddWants(cids[:1], []cid.Cid{}, internal.SessionPriorityForeground)
 
 	messages = collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 	if totalEntriesLength(messages) != 3 {
@@ -424,7 +460,7 @@ func TestWantlistRebroadcast(t *testing.T) {
 
 	// Add some broadcast want-haves
 	messageQueue.Startup()
-	messageQueue.AddBroadcastWantHaves(bcstwh)
+	messageQueue.AddBroadcastWantHaves(bcstwh, internal.SessionPriorityForeground)
 	expectEvent(t, events, messageQueued)
 	clock.Add(sendMessageDebounce)
 	message := <-messagesSent
@@ -450,7 +486,7 @@ func TestWantlistRebroadcast(t *testing.T) {
 	// interfere with the next message collection), then send out some
 	// regular wants and collect them
 	messageQueue.SetRebroadcastInterval(1 * time.Second)
-	messageQueue.AddWants(wantBlocks, wantHaves)
+	messageQueue.AddWants(wantBlocks, wantHaves, internal.SessionPriorityForeground)
 	expectEvent(t, events, messageQueued)
 	clock.Add(10 * time.Millisecond)
 	message = <-messagesSent
@@ -530,7 +566,7 @@ func TestSendingLargeMessages(t *testing.T) {
 	messageQueue := newMessageQueue(ctx, peerID, fakenet, maxMsgSize, sendErrorBackoff, maxValidLatency, dhtm, clock.New(), nil)
 
 	messageQueue.Startup()
-	messageQueue.AddWants(wantBlocks, []cid.Cid{})
+	messageQueue.AddWants(wantBlocks, []cid.Cid{}, internal.SessionPriorityForeground)
 	messages := collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 
 	// want-block has size 44, so with maxMsgSize 44 * 3 (3 want-blocks), then if
@@ -562,7 +598,7 @@ func TestSendToPeerThatDoesntSupportHave(t *testing.T) {
 
 	// Check broadcast want-haves
 	bcwh := testutil.GenerateCids(10)
-	messageQueue.AddBroadcastWantHaves(bcwh)
+	messageQueue.AddBroadcastWantHaves(bcwh, internal.SessionPriorityForeground)
 	messages := collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 
 	if len(messages) != 1 {
@@ -581,7 +617,7 @@ func TestSendToPeerThatDoesntSupportHave(t *testing.T) {
 	// Check regular want-haves and want-blocks
 	wbs := testutil.GenerateCids(10)
 	whs := testutil.GenerateCids(10)
-	messageQueue.AddWants(wbs, whs)
+	messageQueue.AddWants(wbs, whs, internal.SessionPriorityForeground)
 	messages = collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 
 	if len(messages) != 1 {
@@ -611,7 +647,7 @@ func TestSendToPeerThatDoesntSupportHaveMonitorsTimeouts(t *testing.T) {
 	messageQueue.Startup()
 
 	wbs := testutil.GenerateCids(10)
-	messageQueue.AddWants(wbs, nil)
+	messageQueue.AddWants(wbs, nil, internal.SessionPriorityForeground)
 	collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 
 	// Check want-blocks are added to DontHaveTimeoutMgr
@@ -646,7 +682,7 @@ func TestResponseReceived(t *testing.T) {
 	cids := testutil.GenerateCids(10)
 
 	// Add some wants
-	messageQueue.AddWants(cids[:5], nil)
+	messageQueue.AddWants(cids[:5], nil, internal.SessionPriorityForeground)
 	expectEvent(t, events, messageQueued)
 	clock.Add(sendMessageDebounce)
 	<-messagesSent
@@ -656,7 +692,7 @@ func TestResponseReceived(t *testing.T) {
 	clock.Add(10 * time.Millisecond)
 
 	// Add some wants and wait another 10ms
-	messageQueue.AddWants(cids[5:8], nil)
+	messageQueue.AddWants(cids[5:8], nil, internal.SessionPriorityForeground)
 	expectEvent(t, events, messageQueued)
 	clock.Add(10 * time.Millisecond)
 	<-messagesSent
@@ -693,7 +729,7 @@ func TestResponseReceivedAppliesForFirstResponseOnly(t *testing.T) {
 	cids := testutil.GenerateCids(2)
 
 	// Add some wants and wait 10ms
-	messageQueue.AddWants(cids, nil)
+	messageQueue.AddWants(cids, nil, internal.SessionPriorityForeground)
 	collectMessages(ctx, t, messagesSent, 100*time.Millisecond)
 
 	// Receive a response for the wants
@@ -740,7 +776,7 @@ func TestResponseReceivedDiscardsOutliers(t *testing.T) {
 	cids := testutil.GenerateCids(4)
 
 	// Add some wants and wait 20ms
-	messageQueue.AddWants(cids[:2], nil)
+	messageQueue.AddWants(cids[:2], nil, internal.SessionPriorityForeground)
 	expectEvent(t, events, messageQueued)
 	clock.Add(sendMessageDebounce)
 	<-messagesSent
@@ -750,7 +786,7 @@ func TestResponseReceivedDiscardsOutliers(t *testing.T) {
 
 	// Add some more wants and wait long enough that the first wants will be
 	// outside the maximum valid latency, but the second wants will be inside
-	messageQueue.AddWants(cids[2:], nil)
+	messageQueue.AddWants(cids[2:], nil, internal.SessionPriorityForeground)
 	expectEvent(t, events, messageQueued)
 	clock.Add(sendMessageDebounce)
 	<-messagesSent
@@ -836,10 +872,10 @@ func BenchmarkMessageQueue(b *testing.B) {
 		// Alternately add either a few wants or a lot of broadcast wants
 		if rand.Intn(2) == 0 {
 			wants := testutil.GenerateCids(10)
-			qs[i].AddWants(wants[:2], wants[2:])
+			qs[i].AddWants(wants[:2], wants[2:], internal.SessionPriorityForeground)
 		} else {
 			wants := testutil.GenerateCids(60)
-			qs[i].AddBroadcastWantHaves(wants)
+			qs[i].AddBroadcastWantHaves(wants, internal.SessionPriorityForeground)
 		}
 	}
 }
@@ -0,0 +1,156 @@
+package peermanager
+
+import (
+	"math"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-metrics-interface"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// BroadcastLearning configures the suppression of broadcast want-haves to the
+// peers which rarely have the wanted blocks. The PeerManager learns, for every
+// peer and namespace, the ratio of broadcast want-haves the peer answered
+// with a HAVE or the block, and stops broadcasting to the peers whose hit rate
+// is below MinHitRate. Want-haves and want-blocks sent to the peers of a
+// session are never suppressed.
+//
+// The past broadcasts and hits decay with HalfLife, so a suppressed peer gets
+// broadcasts again once it has fewer than MinSamples decayed samples, and can
+// prove useful again. The zero fields take the defaults.
+type BroadcastLearning struct {
+	// MinHitRate is the hit rate below which broadcasts to a peer are
+	// suppressed. Defaults to 1%.
+	MinHitRate float64
+	// MinSamples is the number of broadcast want-haves sent to a peer, for
+	// a namespace, before its hit rate is trusted. Defaults to 100.
+	MinSamples float64
+	// HalfLife is the time after which past broadcasts and hits count for
+	// half. Defaults to one hour.
+	HalfLife time.Duration
+	// Namespace returns the namespace of a CID, the hit rates being learnt
+	// separately for every namespace. Defaults to a single namespace for
+	// all the CIDs.
+	Namespace func(cid.Cid) string
+}
+
+const (
+	defaultBroadcastMinHitRate = 0.01
+	defaultBroadcastMinSamples = 100
+	defaultBroadcastHalfLife   = time.Hour
+)
+
+// hitStats are the decayed number of broadcast want-haves sent to a peer,
+// and of hits, for a namespace.
+type hitStats struct {
+	sent    float64
+	hits    float64
+	updated time.Time
+}
+
+// broadcastLearner learns the hit rates of the peers.
+type broadcastLearner struct {
+	BroadcastLearning
+	stats map[peer.ID]map[string]*hitStats
+	now   func() time.Time
+
+	// suppressed counts the suppressed want-haves
+	suppressed metrics.Counter
+}
+
+func newBroadcastLearner(cfg BroadcastLearning, suppressed metrics.Counter) *broadcastLearner {
+	if cfg.MinHitRate <= 0 {
+		cfg.MinHitRate = defaultBroadcastMinHitRate
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = defaultBroadcastMinSamples
+	}
+	if cfg.HalfLife <= 0 {
+		cfg.HalfLife = defaultBroadcastHalfLife
+	}
+	if cfg.Namespace == nil {
+		cfg.Namespace = func(cid.Cid) string { return "" }
+	}
+	return &broadcastLearner{
+		BroadcastLearning: cfg,
+		stats:             make(map[peer.ID]map[string]*hitStats),
+		now:               time.Now,
+		suppressed:        suppressed,
+	}
+}
+
+// decay brings the stats up to date.
+func (bl *broadcastLearner) decay(s *hitStats, now time.Time) {
+	f := math.Exp2(-float64(now.Sub(s.updated)) / float64(bl.HalfLife))
+	s.sent *= f
+	s.hits *= f
+	s.updated = now
+}
+
+// get returns the up to date stats of the peer for the namespace, creating
+// them when create is true.
+func (bl *broadcastLearner) get(p peer.ID, ns string, now time.Time, create bool) *hitStats {
+	nss, ok := bl.stats[p]
+	if !ok {
+		if !create {
+			return nil
+		}
+		nss = make(map[string]*hitStats)
+		bl.stats[p] = nss
+	}
+	s, ok := nss[ns]
+	if !ok {
+		if !create {
+			return nil
+		}
+		s = &hitStats{updated: now}
+		nss[ns] = s
+	}
+	bl.decay(s, now)
+	return s
+}
+
+// allow returns whether the want-have can be broadcast to the peer.
+func (bl *broadcastLearner) allow(p peer.ID, c cid.Cid, now time.Time) bool {
+	s := bl.get(p, bl.Namespace(c), now, false)
+	if s == nil || s.sent < bl.MinSamples || s.hits >= s.sent*bl.MinHitRate {
+		return true
+	}
+	bl.suppressed.Inc()
+	return false
+}
+
+// broadcast records the want-have broadcast to the peer.
+func (bl *broadcastLearner) broadcast(p peer.ID, c cid.Cid, now time.Time) {
+	bl.get(p, bl.Namespace(c), now, true).sent++
+}
+
+// hit records a HAVE or a block received from the peer for a broadcast
+// want-have.
+func (bl *broadcastLearner) hit(p peer.ID, c cid.Cid) {
+	s := bl.get(p, bl.Namespace(c), bl.now(), true)
+	// do not count more hits than broadcasts, for blocks received for
+	// want-haves not broadcast to the peer
+	s.hits = min(s.hits+1, s.sent)
+}
+
+// prune forgets the stats of the disconnected peers which have too few
+// samples to be suppressed anyway.
+func (bl *broadcastLearner) prune(connected map[peer.ID]*peerWant) {
+	now := bl.now()
+	for p, nss := range bl.stats {
+		if _, ok := connected[p]; ok {
+			continue
+		}
+		for ns, s := range nss {
+			bl.decay(s, now)
+			if s.sent < bl.MinSamples {
+				delete(nss, ns)
+			}
+		}
+		if len(nss) == 0 {
+			delete(bl.stats, p)
+		}
+	}
+}
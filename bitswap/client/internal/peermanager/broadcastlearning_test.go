@@ -0,0 +1,137 @@
+package peermanager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/bitswap/client/internal"
+	"github.com/ipfs/boxo/bitswap/internal/testutil"
+	cid "github.com/ipfs/go-cid"
+)
+
+type counter struct {
+	count float64
+}
+
+func (c *counter) Inc() {
+	c.count++
+}
+
+func (c *counter) Add(v float64) {
+	c.count += v
+}
+
+func TestBroadcastLearning(t *testing.T) {
+	pwm := newPeerWantManager(&gauge{}, &gauge{})
+	suppressed := &counter{}
+	pwm.learner = newBroadcastLearner(BroadcastLearning{
+		MinHitRate: 0.5,
+		MinSamples: 4,
+		HalfLife:   time.Minute,
+	}, suppressed)
+	now := time.Now()
+	pwm.learner.now = func() time.Time { return now }
+
+	peers := testutil.GeneratePeers(2)
+	useful, useless := peers[0], peers[1]
+	usefulPQ, uselessPQ := &mockPQ{}, &mockPQ{}
+	pwm.addPeer(usefulPQ, useful)
+	pwm.addPeer(uselessPQ, useless)
+
+	// Learn that only the first peer has the blocks
+	for i := 0; i < 4; i++ {
+		cids := testutil.GenerateCids(1)
+		pwm.broadcastWantHaves(cids, internal.SessionPriorityForeground)
+		pwm.receivedHaves(useful, cids)
+		pwm.sendCancels(cids)
+	}
+	if len(uselessPQ.bcst) != 4 {
+		t.Fatal("Expected broadcasts to be sent while learning")
+	}
+	usefulPQ.clear()
+	uselessPQ.clear()
+
+	cids := testutil.GenerateCids(2)
+	pwm.broadcastWantHaves(cids, internal.SessionPriorityForeground)
+	if len(usefulPQ.bcst) != 2 {
+		t.Fatal("Expected broadcast to the useful peer")
+	}
+	if len(uselessPQ.bcst) != 0 {
+		t.Fatal("Expected no broadcast to the useless peer")
+	}
+	if suppressed.count != 2 {
+		t.Fatal("Expected suppressed want-haves to be counted")
+	}
+
+	// Wants sent to the peers of a session are not suppressed
+	pwm.sendWants(useless, nil, cids, internal.SessionPriorityForeground)
+	if len(uselessPQ.whs) != 2 {
+		t.Fatal("Expected want-haves to be sent to the session peer")
+	}
+	pwm.sendCancels(cids)
+
+	// Once the samples decayed, the useless peer is tried again
+	now = now.Add(time.Minute)
+	uselessPQ.clear()
+	pwm.broadcastWantHaves(testutil.GenerateCids(1), internal.SessionPriorityForeground)
+	if len(uselessPQ.bcst) != 1 {
+		t.Fatal("Expected broadcast to the useless peer once its samples decayed")
+	}
+}
+
+func TestBroadcastLearningReconnect(t *testing.T) {
+	pwm := newPeerWantManager(&gauge{}, &gauge{})
+	pwm.learner = newBroadcastLearner(BroadcastLearning{MinSamples: 2}, &counter{})
+	now := time.Now()
+	pwm.learner.now = func() time.Time { return now }
+
+	p := testutil.GeneratePeers(1)[0]
+	pq := &mockPQ{}
+	pwm.addPeer(pq, p)
+
+	cids := testutil.GenerateCids(2)
+	pwm.broadcastWantHaves(cids, internal.SessionPriorityForeground)
+	pwm.sendCancels(cids)
+
+	// The peer had none of the blocks, so the hit rate is remembered and
+	// it is not sent the live broadcast wants when it reconnects
+	pwm.removePeer(p)
+	pq.clear()
+	pwm.broadcastWantHaves(testutil.GenerateCids(1), internal.SessionPriorityForeground)
+	pwm.addPeer(pq, p)
+	if len(pq.bcst) != 0 {
+		t.Fatal("Expected no broadcast to the reconnected peer")
+	}
+}
+
+func TestBroadcastLearningNamespaces(t *testing.T) {
+	pwm := newPeerWantManager(&gauge{}, &gauge{})
+	pwm.learner = newBroadcastLearner(BroadcastLearning{
+		MinSamples: 2,
+		Namespace:  func(c cid.Cid) string { return fmt.Sprint(c.Type()) },
+	}, &counter{})
+	now := time.Now()
+	pwm.learner.now = func() time.Time { return now }
+
+	p := testutil.GeneratePeers(1)[0]
+	pq := &mockPQ{}
+	pwm.addPeer(pq, p)
+
+	// The peer has the raw blocks, but none of the dag-pb blocks
+	raw := func() cid.Cid { return cid.NewCidV1(cid.Raw, testutil.GenerateCids(1)[0].Hash()) }
+	pb := func() cid.Cid { return cid.NewCidV1(cid.DagProtobuf, testutil.GenerateCids(1)[0].Hash()) }
+	for i := 0; i < 2; i++ {
+		cids := []cid.Cid{raw(), pb()}
+		pwm.broadcastWantHaves(cids, internal.SessionPriorityForeground)
+		pwm.receivedHaves(p, cids[:1])
+		pwm.sendCancels(cids)
+	}
+	pq.clear()
+
+	rawCid := raw()
+	pwm.broadcastWantHaves([]cid.Cid{rawCid, pb()}, internal.SessionPriorityForeground)
+	if len(pq.bcst) != 1 || pq.bcst[0] != rawCid {
+		t.Fatal("Expected broadcast of the raw block only")
+	}
+}
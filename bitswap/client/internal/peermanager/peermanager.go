@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipfs/go-metrics-interface"
 
@@ -15,8 +16,8 @@ var log = logging.Logger("bs:peermgr")
 
 // PeerQueue provides a queue of messages to be sent for a single peer.
 type PeerQueue interface {
-	AddBroadcastWantHaves([]cid.Cid)
-	AddWants([]cid.Cid, []cid.Cid)
+	AddBroadcastWantHaves([]cid.Cid, internal.SessionPriority)
+	AddWants([]cid.Cid, []cid.Cid, internal.SessionPriority)
 	AddCancels([]cid.Cid)
 	ResponseReceived(ks []cid.Cid)
 	Startup()
@@ -130,25 +131,49 @@ func (pm *PeerManager) ResponseReceived(p peer.ID, ks []cid.Cid) {
 	}
 }
 
+// EnableBroadcastLearning suppresses the broadcast want-haves to the peers
+// which rarely have the wanted blocks, see [BroadcastLearning]. It must be
+// called before the PeerManager is used.
+func (pm *PeerManager) EnableBroadcastLearning(cfg BroadcastLearning) {
+	suppressed := metrics.NewCtx(pm.ctx, "broadcast_suppressed_total", "Number of broadcast want-haves suppressed for peers with low hit rates.").Counter()
+	pm.pwm.learner = newBroadcastLearner(cfg, suppressed)
+}
+
+// HavesReceived is called with the keys of the blocks and HAVEs received
+// from the peer, to learn its hit rate when broadcast learning is enabled.
+func (pm *PeerManager) HavesReceived(p peer.ID, ks []cid.Cid) {
+	if pm.pwm.learner == nil {
+		return
+	}
+
+	pm.pqLk.Lock()
+	defer pm.pqLk.Unlock()
+
+	pm.pwm.receivedHaves(p, ks)
+}
+
 // BroadcastWantHaves broadcasts want-haves to all peers (used by the session
 // to discover seeds).
 // For each peer it filters out want-haves that have previously been sent to
 // the peer.
+// The want-haves are sent with the priority class of the session ctx belongs
+// to.
 func (pm *PeerManager) BroadcastWantHaves(ctx context.Context, wantHaves []cid.Cid) {
 	pm.pqLk.Lock()
 	defer pm.pqLk.Unlock()
 
-	pm.pwm.broadcastWantHaves(wantHaves)
+	pm.pwm.broadcastWantHaves(wantHaves, internal.SessionPriorityFromContext(ctx))
 }
 
 // SendWants sends the given want-blocks and want-haves to the given peer.
 // It filters out wants that have previously been sent to the peer.
+// The wants are sent with the priority class of the session ctx belongs to.
 func (pm *PeerManager) SendWants(ctx context.Context, p peer.ID, wantBlocks []cid.Cid, wantHaves []cid.Cid) {
 	pm.pqLk.Lock()
 	defer pm.pqLk.Unlock()
 
 	if _, ok := pm.peerQueues[p]; ok {
-		pm.pwm.sendWants(p, wantBlocks, wantHaves)
+		pm.pwm.sendWants(p, wantBlocks, wantHaves, internal.SessionPriorityFromContext(ctx))
 	}
 }
 
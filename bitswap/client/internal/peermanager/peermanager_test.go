@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	"github.com/ipfs/boxo/bitswap/internal/testutil"
 	cid "github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -26,11 +27,11 @@ type mockPeerQueue struct {
 func (fp *mockPeerQueue) Startup()  {}
 func (fp *mockPeerQueue) Shutdown() {}
 
-func (fp *mockPeerQueue) AddBroadcastWantHaves(whs []cid.Cid) {
+func (fp *mockPeerQueue) AddBroadcastWantHaves(whs []cid.Cid, _ internal.SessionPriority) {
 	fp.msgs <- msg{fp.p, nil, whs, nil}
 }
 
-func (fp *mockPeerQueue) AddWants(wbs []cid.Cid, whs []cid.Cid) {
+func (fp *mockPeerQueue) AddWants(wbs []cid.Cid, whs []cid.Cid, _ internal.SessionPriority) {
 	fp.msgs <- msg{fp.p, wbs, whs, nil}
 }
 
@@ -328,10 +329,10 @@ type benchPeerQueue struct{}
 func (*benchPeerQueue) Startup()  {}
 func (*benchPeerQueue) Shutdown() {}
 
-func (*benchPeerQueue) AddBroadcastWantHaves(whs []cid.Cid)   {}
-func (*benchPeerQueue) AddWants(wbs []cid.Cid, whs []cid.Cid) {}
-func (*benchPeerQueue) AddCancels(cs []cid.Cid)               {}
-func (*benchPeerQueue) ResponseReceived(ks []cid.Cid)         {}
+func (*benchPeerQueue) AddBroadcastWantHaves(whs []cid.Cid, _ internal.SessionPriority)   {}
+func (*benchPeerQueue) AddWants(wbs []cid.Cid, whs []cid.Cid, _ internal.SessionPriority) {}
+func (*benchPeerQueue) AddCancels(cs []cid.Cid)                                           {}
+func (*benchPeerQueue) ResponseReceived(ks []cid.Cid)                                     {}
 
 // Simplistic benchmark to allow us to stress test
 func BenchmarkPeerManager(b *testing.B) {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	cid "github.com/ipfs/go-cid"
 	peer "github.com/libp2p/go-libp2p/core/peer"
 )
@@ -29,16 +30,27 @@ type peerWantManager struct {
 
 	// broadcastWants tracks all the current broadcast wants.
 	broadcastWants *cid.Set
+	// bgBroadcastWants are the broadcast wants which were only broadcast by
+	// background sessions, to send them with the same priority class to the
+	// peers which connect later.
+	bgBroadcastWants *cid.Set
 
 	// Keeps track of the number of active want-haves & want-blocks
 	wantGauge Gauge
 	// Keeps track of the number of active want-blocks
 	wantBlockGauge Gauge
+
+	// learner suppresses broadcasts to the peers which rarely have the
+	// blocks, if not nil
+	learner *broadcastLearner
 }
 
 type peerWant struct {
 	wantBlocks *cid.Set
 	wantHaves  *cid.Set
+	// suppressed are the broadcast wants not sent to the peer, see
+	// BroadcastLearning
+	suppressed *cid.Set
 	peerQueue  PeerQueue
 }
 
@@ -46,11 +58,12 @@ type peerWant struct {
 // number of active want-blocks (ie sent but no response received)
 func newPeerWantManager(wantGauge Gauge, wantBlockGauge Gauge) *peerWantManager {
 	return &peerWantManager{
-		broadcastWants: cid.NewSet(),
-		peerWants:      make(map[peer.ID]*peerWant),
-		wantPeers:      make(map[cid.Cid]map[peer.ID]struct{}),
-		wantGauge:      wantGauge,
-		wantBlockGauge: wantBlockGauge,
+		broadcastWants:   cid.NewSet(),
+		bgBroadcastWants: cid.NewSet(),
+		peerWants:        make(map[peer.ID]*peerWant),
+		wantPeers:        make(map[cid.Cid]map[peer.ID]struct{}),
+		wantGauge:        wantGauge,
+		wantBlockGauge:   wantBlockGauge,
 	}
 }
 
@@ -64,13 +77,27 @@ func (pwm *peerWantManager) addPeer(peerQueue PeerQueue, p peer.ID) {
 	pwm.peerWants[p] = &peerWant{
 		wantBlocks: cid.NewSet(),
 		wantHaves:  cid.NewSet(),
+		suppressed: cid.NewSet(),
 		peerQueue:  peerQueue,
 	}
 
 	// Broadcast any live want-haves to the newly connected peer
 	if pwm.broadcastWants.Len() > 0 {
-		wants := pwm.broadcastWants.Keys()
-		peerQueue.AddBroadcastWantHaves(wants)
+		wants := pwm.filterBroadcast(p, pwm.peerWants[p], pwm.broadcastWants.Keys())
+		var fgWants, bgWants []cid.Cid
+		for _, c := range wants {
+			if pwm.bgBroadcastWants.Has(c) {
+				bgWants = append(bgWants, c)
+			} else {
+				fgWants = append(fgWants, c)
+			}
+		}
+		if len(fgWants) > 0 {
+			peerQueue.AddBroadcastWantHaves(fgWants, internal.SessionPriorityForeground)
+		}
+		if len(bgWants) > 0 {
+			peerQueue.AddBroadcastWantHaves(bgWants, internal.SessionPriorityBackground)
+		}
 	}
 }
 
@@ -112,17 +139,28 @@ func (pwm *peerWantManager) removePeer(p peer.ID) {
 	})
 
 	delete(pwm.peerWants, p)
+
+	if pwm.learner != nil {
+		pwm.learner.prune(pwm.peerWants)
+	}
 }
 
 // broadcastWantHaves sends want-haves to any peers that have not yet been sent them.
-func (pwm *peerWantManager) broadcastWantHaves(wantHaves []cid.Cid) {
+// A want-have already sent keeps the priority class it was sent with.
+func (pwm *peerWantManager) broadcastWantHaves(wantHaves []cid.Cid, sp internal.SessionPriority) {
 	unsent := make([]cid.Cid, 0, len(wantHaves))
 	for _, c := range wantHaves {
 		if pwm.broadcastWants.Has(c) {
 			// Already a broadcast want, skip it.
+			if sp != internal.SessionPriorityBackground {
+				pwm.bgBroadcastWants.Remove(c)
+			}
 			continue
 		}
 		pwm.broadcastWants.Add(c)
+		if sp == internal.SessionPriorityBackground {
+			pwm.bgBroadcastWants.Add(c)
+		}
 		unsent = append(unsent, c)
 
 		// If no peer has a pending want for the key
@@ -140,7 +178,7 @@ func (pwm *peerWantManager) broadcastWantHaves(wantHaves []cid.Cid) {
 	bcstWantsBuffer := make([]cid.Cid, 0, len(unsent))
 
 	// Send broadcast wants to each peer
-	for _, pws := range pwm.peerWants {
+	for p, pws := range pwm.peerWants {
 		peerUnsent := bcstWantsBuffer[:0]
 		for _, c := range unsent {
 			// If we've already sent a want to this peer, skip them.
@@ -148,16 +186,49 @@ func (pwm *peerWantManager) broadcastWantHaves(wantHaves []cid.Cid) {
 				peerUnsent = append(peerUnsent, c)
 			}
 		}
+		peerUnsent = pwm.filterBroadcast(p, pws, peerUnsent)
 
 		if len(peerUnsent) > 0 {
-			pws.peerQueue.AddBroadcastWantHaves(peerUnsent)
+			pws.peerQueue.AddBroadcastWantHaves(peerUnsent, sp)
+		}
+	}
+}
+
+// filterBroadcast removes from ks, in place, the want-haves which must not be
+// broadcast to the peer because of its low hit rate, and records them as
+// suppressed.
+func (pwm *peerWantManager) filterBroadcast(p peer.ID, pws *peerWant, ks []cid.Cid) []cid.Cid {
+	if pwm.learner == nil {
+		return ks
+	}
+
+	now := pwm.learner.now()
+	filtered := ks[:0]
+	for _, c := range ks {
+		if pwm.learner.allow(p, c, now) {
+			pwm.learner.broadcast(p, c, now)
+			filtered = append(filtered, c)
+		} else {
+			pws.suppressed.Add(c)
+		}
+	}
+	return filtered
+}
+
+// receivedHaves records the HAVEs and blocks received from the peer for the
+// broadcast want-haves, to learn its hit rate.
+func (pwm *peerWantManager) receivedHaves(p peer.ID, ks []cid.Cid) {
+	for _, c := range ks {
+		if pwm.broadcastWants.Has(c) {
+			pwm.learner.hit(p, c)
 		}
 	}
 }
 
 // sendWants only sends the peer the want-blocks and want-haves that have not
-// already been sent to it.
-func (pwm *peerWantManager) sendWants(p peer.ID, wantBlocks []cid.Cid, wantHaves []cid.Cid) {
+// already been sent to it. A want already sent keeps the priority class it
+// was sent with.
+func (pwm *peerWantManager) sendWants(p peer.ID, wantBlocks []cid.Cid, wantHaves []cid.Cid, sp internal.SessionPriority) {
 	fltWantBlks := make([]cid.Cid, 0, len(wantBlocks))
 	fltWantHvs := make([]cid.Cid, 0, len(wantHaves))
 
@@ -201,10 +272,11 @@ func (pwm *peerWantManager) sendWants(p peer.ID, wantBlocks []cid.Cid, wantHaves
 	// Iterate over the requested want-haves
 	for _, c := range wantHaves {
 		// If we've already broadcasted this want, don't bother with a
-		// want-have.
-		if pwm.broadcastWants.Has(c) {
+		// want-have, unless the broadcast was suppressed for the peer.
+		if pwm.broadcastWants.Has(c) && !pws.suppressed.Has(c) {
 			continue
 		}
+		pws.suppressed.Remove(c)
 
 		// If the CID has not been sent as a want-block or want-have
 		if !pws.wantBlocks.Has(c) && !pws.wantHaves.Has(c) {
@@ -226,7 +298,7 @@ func (pwm *peerWantManager) sendWants(p peer.ID, wantBlocks []cid.Cid, wantHaves
 	}
 
 	// Send the want-blocks and want-haves to the peer
-	pws.peerQueue.AddWants(fltWantBlks, fltWantHvs)
+	pws.peerQueue.AddWants(fltWantBlks, fltWantHvs, sp)
 }
 
 // sendCancels sends a cancel to each peer to which a corresponding want was
@@ -255,8 +327,18 @@ func (pwm *peerWantManager) sendCancels(cancelKs []cid.Cid) {
 
 	// Send cancels to a particular peer
 	send := func(p peer.ID, pws *peerWant) {
-		// Start from the broadcast cancels
+		// Start from the broadcast cancels, but those which were
+		// suppressed for the peer
 		toCancel := broadcastCancels
+		if pws.suppressed.Len() > 0 {
+			toCancel = make([]cid.Cid, 0, len(broadcastCancels))
+			for _, c := range broadcastCancels {
+				if !pws.suppressed.Has(c) {
+					toCancel = append(toCancel, c)
+				}
+				pws.suppressed.Remove(c)
+			}
+		}
 
 		// For each key to be cancelled
 		for _, c := range cancelKs {
@@ -328,6 +410,7 @@ func (pwm *peerWantManager) sendCancels(cancelKs []cid.Cid) {
 	// Remove cancelled broadcast wants
 	for _, c := range broadcastCancels {
 		pwm.broadcastWants.Remove(c)
+		pwm.bgBroadcastWants.Remove(c)
 	}
 
 	// Batch-remove the reverse-index. There's no need to clear this index
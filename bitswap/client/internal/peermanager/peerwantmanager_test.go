@@ -3,6 +3,7 @@ package peermanager
 import (
 	"testing"
 
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	"github.com/ipfs/boxo/bitswap/internal/testutil"
 	cid "github.com/ipfs/go-cid"
 	peer "github.com/libp2p/go-libp2p/core/peer"
@@ -25,6 +26,8 @@ type mockPQ struct {
 	wbs     []cid.Cid
 	whs     []cid.Cid
 	cancels []cid.Cid
+	// bg are the wants added by background sessions
+	bg []cid.Cid
 }
 
 func (mpq *mockPQ) clear() {
@@ -32,18 +35,26 @@ func (mpq *mockPQ) clear() {
 	mpq.wbs = nil
 	mpq.whs = nil
 	mpq.cancels = nil
+	mpq.bg = nil
 }
 
 func (mpq *mockPQ) Startup()  {}
 func (mpq *mockPQ) Shutdown() {}
 
-func (mpq *mockPQ) AddBroadcastWantHaves(whs []cid.Cid) {
+func (mpq *mockPQ) AddBroadcastWantHaves(whs []cid.Cid, sp internal.SessionPriority) {
 	mpq.bcst = append(mpq.bcst, whs...)
+	if sp == internal.SessionPriorityBackground {
+		mpq.bg = append(mpq.bg, whs...)
+	}
 }
 
-func (mpq *mockPQ) AddWants(wbs []cid.Cid, whs []cid.Cid) {
+func (mpq *mockPQ) AddWants(wbs []cid.Cid, whs []cid.Cid, sp internal.SessionPriority) {
 	mpq.wbs = append(mpq.wbs, wbs...)
 	mpq.whs = append(mpq.whs, whs...)
+	if sp == internal.SessionPriorityBackground {
+		mpq.bg = append(mpq.bg, wbs...)
+		mpq.bg = append(mpq.bg, whs...)
+	}
 }
 
 func (mpq *mockPQ) AddCancels(cs []cid.Cid) {
@@ -89,7 +100,7 @@ func TestPWMBroadcastWantHaves(t *testing.T) {
 	}
 
 	// Broadcast 2 cids to 2 peers
-	pwm.broadcastWantHaves(cids)
+	pwm.broadcastWantHaves(cids, internal.SessionPriorityForeground)
 	for _, pqi := range peerQueues {
 		pq := pqi.(*mockPQ)
 		if len(pq.bcst) != 2 {
@@ -102,7 +113,7 @@ func TestPWMBroadcastWantHaves(t *testing.T) {
 
 	// Broadcasting same cids should have no effect
 	clearSent(peerQueues)
-	pwm.broadcastWantHaves(cids)
+	pwm.broadcastWantHaves(cids, internal.SessionPriorityForeground)
 	for _, pqi := range peerQueues {
 		pq := pqi.(*mockPQ)
 		if len(pq.bcst) != 0 {
@@ -112,7 +123,7 @@ func TestPWMBroadcastWantHaves(t *testing.T) {
 
 	// Broadcast 2 other cids
 	clearSent(peerQueues)
-	pwm.broadcastWantHaves(cids2)
+	pwm.broadcastWantHaves(cids2, internal.SessionPriorityForeground)
 	for _, pqi := range peerQueues {
 		pq := pqi.(*mockPQ)
 		if len(pq.bcst) != 2 {
@@ -125,7 +136,7 @@ func TestPWMBroadcastWantHaves(t *testing.T) {
 
 	// Broadcast mix of old and new cids
 	clearSent(peerQueues)
-	pwm.broadcastWantHaves(append(cids, cids3...))
+	pwm.broadcastWantHaves(append(cids, cids3...), internal.SessionPriorityForeground)
 	for _, pqi := range peerQueues {
 		pq := pqi.(*mockPQ)
 		if len(pq.bcst) != 2 {
@@ -143,9 +154,9 @@ func TestPWMBroadcastWantHaves(t *testing.T) {
 	wantBlocks := []cid.Cid{cids4[0], cids4[2]}
 	p0 := peers[0]
 	p1 := peers[1]
-	pwm.sendWants(p0, wantBlocks, []cid.Cid{})
+	pwm.sendWants(p0, wantBlocks, []cid.Cid{}, internal.SessionPriorityForeground)
 
-	pwm.broadcastWantHaves(cids4)
+	pwm.broadcastWantHaves(cids4, internal.SessionPriorityForeground)
 	pq0 := peerQueues[p0].(*mockPQ)
 	if len(pq0.bcst) != 2 { // only broadcast 2 / 4 want-haves
 		t.Fatal("Expected 2 want-haves")
@@ -176,12 +187,47 @@ func TestPWMBroadcastWantHaves(t *testing.T) {
 	}
 
 	clearSent(peerQueues)
-	pwm.broadcastWantHaves(allCids)
+	pwm.broadcastWantHaves(allCids, internal.SessionPriorityForeground)
 	if len(pq2.bcst) != 0 {
 		t.Errorf("did not expect to have CIDs to broadcast")
 	}
 }
 
+func TestPWMSessionPriority(t *testing.T) {
+	pwm := newPeerWantManager(&gauge{}, &gauge{})
+
+	peers := testutil.GeneratePeers(3)
+	bgCids := testutil.GenerateCids(2)
+	wbs := testutil.GenerateCids(1)
+
+	pq0 := &mockPQ{}
+	pwm.addPeer(pq0, peers[0])
+	pwm.broadcastWantHaves(bgCids, internal.SessionPriorityBackground)
+	if !testutil.MatchKeysIgnoreOrder(pq0.bg, bgCids) {
+		t.Fatal("expected the want-haves to be broadcast as background wants")
+	}
+
+	// background broadcast wants are sent as such to new peers
+	pq1 := &mockPQ{}
+	pwm.addPeer(pq1, peers[1])
+	if !testutil.MatchKeysIgnoreOrder(pq1.bg, bgCids) {
+		t.Fatal("expected the want-haves to be sent as background wants to the new peer")
+	}
+
+	// until a foreground session wants them too
+	pwm.broadcastWantHaves(bgCids[:1], internal.SessionPriorityForeground)
+	pq2 := &mockPQ{}
+	pwm.addPeer(pq2, peers[2])
+	if !testutil.MatchKeysIgnoreOrder(pq2.bcst, bgCids) || !testutil.MatchKeysIgnoreOrder(pq2.bg, bgCids[1:]) {
+		t.Fatal("expected only the want-have of the background session to be sent as a background want")
+	}
+
+	pwm.sendWants(peers[0], wbs, nil, internal.SessionPriorityBackground)
+	if !testutil.MatchKeysIgnoreOrder(pq0.wbs, wbs) || !testutil.MatchKeysIgnoreOrder(pq0.bg, append(bgCids, wbs...)) {
+		t.Fatal("expected the want-block to be sent as a background want")
+	}
+}
+
 func TestPWMSendWants(t *testing.T) {
 	pwm := newPeerWantManager(&gauge{}, &gauge{})
 
@@ -202,7 +248,7 @@ func TestPWMSendWants(t *testing.T) {
 
 	// Send 2 want-blocks and 2 want-haves to p0
 	clearSent(peerQueues)
-	pwm.sendWants(p0, cids, cids2)
+	pwm.sendWants(p0, cids, cids2, internal.SessionPriorityForeground)
 	if !testutil.MatchKeysIgnoreOrder(pq0.wbs, cids) {
 		t.Fatal("Expected 2 want-blocks")
 	}
@@ -216,7 +262,7 @@ func TestPWMSendWants(t *testing.T) {
 	clearSent(peerQueues)
 	cids3 := testutil.GenerateCids(2)
 	cids4 := testutil.GenerateCids(2)
-	pwm.sendWants(p0, append(cids3, cids[0]), append(cids4, cids2[0]))
+	pwm.sendWants(p0, append(cids3, cids[0]), append(cids4, cids2[0]), internal.SessionPriorityForeground)
 	if !testutil.MatchKeysIgnoreOrder(pq0.wbs, cids3) {
 		t.Fatal("Expected 2 want-blocks")
 	}
@@ -228,7 +274,7 @@ func TestPWMSendWants(t *testing.T) {
 	clearSent(peerQueues)
 	cids5 := testutil.GenerateCids(1)
 	newWantBlockOldWantHave := append(cids5, cids2[0])
-	pwm.sendWants(p0, newWantBlockOldWantHave, []cid.Cid{})
+	pwm.sendWants(p0, newWantBlockOldWantHave, []cid.Cid{}, internal.SessionPriorityForeground)
 	// If a want was sent as a want-have, it should be ok to now send it as a
 	// want-block
 	if !testutil.MatchKeysIgnoreOrder(pq0.wbs, newWantBlockOldWantHave) {
@@ -242,7 +288,7 @@ func TestPWMSendWants(t *testing.T) {
 	clearSent(peerQueues)
 	cids6 := testutil.GenerateCids(1)
 	newWantHaveOldWantBlock := append(cids6, cids[0])
-	pwm.sendWants(p0, []cid.Cid{}, newWantHaveOldWantBlock)
+	pwm.sendWants(p0, []cid.Cid{}, newWantHaveOldWantBlock, internal.SessionPriorityForeground)
 	// If a want was previously sent as a want-block, it should not be
 	// possible to now send it as a want-have
 	if !testutil.MatchKeysIgnoreOrder(pq0.whs, cids6) {
@@ -253,7 +299,7 @@ func TestPWMSendWants(t *testing.T) {
 	}
 
 	// Send 2 want-blocks and 2 want-haves to p1
-	pwm.sendWants(p1, cids, cids2)
+	pwm.sendWants(p1, cids, cids2, internal.SessionPriorityForeground)
 	if !testutil.MatchKeysIgnoreOrder(pq1.wbs, cids) {
 		t.Fatal("Expected 2 want-blocks")
 	}
@@ -285,10 +331,10 @@ func TestPWMSendCancels(t *testing.T) {
 	pq1 := peerQueues[p1].(*mockPQ)
 
 	// Send 2 want-blocks and 2 want-haves to p0
-	pwm.sendWants(p0, wb1, wh1)
+	pwm.sendWants(p0, wb1, wh1, internal.SessionPriorityForeground)
 	// Send 3 want-blocks and 3 want-haves to p1
 	// (1 overlapping want-block / want-have with p0)
-	pwm.sendWants(p1, append(wb2, wb1[1]), append(wh2, wh1[1]))
+	pwm.sendWants(p1, append(wb2, wb1[1]), append(wh2, wh1[1]), internal.SessionPriorityForeground)
 
 	if !testutil.MatchKeysIgnoreOrder(pwm.getWantBlocks(), allwb) {
 		t.Fatal("Expected 4 cids to be wanted")
@@ -357,7 +403,7 @@ func TestStats(t *testing.T) {
 	pwm.addPeer(pq, p0)
 
 	// Send 2 want-blocks and 2 want-haves to p0
-	pwm.sendWants(p0, cids, cids2)
+	pwm.sendWants(p0, cids, cids2, internal.SessionPriorityForeground)
 
 	if g.count != 4 {
 		t.Fatal("Expected 4 wants")
@@ -368,7 +414,7 @@ func TestStats(t *testing.T) {
 
 	// Send 1 old want-block and 2 new want-blocks to p0
 	cids3 := testutil.GenerateCids(2)
-	pwm.sendWants(p0, append(cids3, cids[0]), []cid.Cid{})
+	pwm.sendWants(p0, append(cids3, cids[0]), []cid.Cid{}, internal.SessionPriorityForeground)
 
 	if g.count != 6 {
 		t.Fatal("Expected 6 wants")
@@ -379,7 +425,7 @@ func TestStats(t *testing.T) {
 
 	// Broadcast 1 old want-have and 2 new want-haves
 	cids4 := testutil.GenerateCids(2)
-	pwm.broadcastWantHaves(append(cids4, cids2[0]))
+	pwm.broadcastWantHaves(append(cids4, cids2[0]), internal.SessionPriorityForeground)
 	if g.count != 8 {
 		t.Fatal("Expected 8 wants")
 	}
@@ -456,11 +502,11 @@ func TestStatsOverlappingWantBlockWantHave(t *testing.T) {
 	pwm.addPeer(&mockPQ{}, p1)
 
 	// Send 2 want-blocks and 2 want-haves to p0
-	pwm.sendWants(p0, cids, cids2)
+	pwm.sendWants(p0, cids, cids2, internal.SessionPriorityForeground)
 
 	// Send opposite:
 	// 2 want-haves and 2 want-blocks to p1
-	pwm.sendWants(p1, cids2, cids)
+	pwm.sendWants(p1, cids2, cids, internal.SessionPriorityForeground)
 
 	if g.count != 4 {
 		t.Fatal("Expected 4 wants")
@@ -495,11 +541,11 @@ func TestStatsRemovePeerOverlappingWantBlockWantHave(t *testing.T) {
 	pwm.addPeer(&mockPQ{}, p1)
 
 	// Send 2 want-blocks and 2 want-haves to p0
-	pwm.sendWants(p0, cids, cids2)
+	pwm.sendWants(p0, cids, cids2, internal.SessionPriorityForeground)
 
 	// Send opposite:
 	// 2 want-haves and 2 want-blocks to p1
-	pwm.sendWants(p1, cids2, cids)
+	pwm.sendWants(p1, cids2, cids, internal.SessionPriorityForeground)
 
 	if g.count != 4 {
 		t.Fatal("Expected 4 wants")
@@ -0,0 +1,41 @@
+package internal
+
+import "context"
+
+// SessionPriority is the priority class of a session.
+type SessionPriority int
+
+const (
+	// SessionPriorityForeground is for sessions fetching content a user is
+	// waiting for. It is the default.
+	SessionPriorityForeground SessionPriority = iota
+	// SessionPriorityBackground is for sessions prefetching content, which
+	// yield to foreground sessions.
+	SessionPriorityBackground
+)
+
+func (p SessionPriority) String() string {
+	switch p {
+	case SessionPriorityForeground:
+		return "foreground"
+	case SessionPriorityBackground:
+		return "background"
+	default:
+		return "unknown"
+	}
+}
+
+type sessionPriorityKey struct{}
+
+// ContextWithSessionPriority returns a context carrying the priority class of
+// the session it belongs to.
+func ContextWithSessionPriority(ctx context.Context, p SessionPriority) context.Context {
+	return context.WithValue(ctx, sessionPriorityKey{}, p)
+}
+
+// SessionPriorityFromContext returns the priority class of the session ctx
+// belongs to, foreground if ctx is not bound to a session.
+func SessionPriorityFromContext(ctx context.Context) SessionPriority {
+	p, _ := ctx.Value(sessionPriorityKey{}).(SessionPriority)
+	return p
+}
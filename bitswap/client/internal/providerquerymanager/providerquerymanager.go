@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
 	peer "github.com/libp2p/go-libp2p/core/peer"
@@ -17,6 +18,9 @@ const (
 	maxProviders         = 10
 	maxInProcessRequests = 6
 	defaultTimeout       = 10 * time.Second
+	// maxForegroundBurst is the number of queries of foreground sessions
+	// started in a row while queries of background sessions are waiting.
+	maxForegroundBurst = 4
 )
 
 type inProgressRequestStatus struct {
@@ -29,6 +33,7 @@ type inProgressRequestStatus struct {
 type findProviderRequest struct {
 	k   cid.Cid
 	ctx context.Context
+	sp  internal.SessionPriority
 }
 
 // ProviderQueryNetwork is an interface for finding providers and connecting to
@@ -72,6 +77,8 @@ type cancelRequestMessage struct {
 // - connect to found peers and filter them if it can't connect
 // - ensure two findprovider calls for the same block don't run concurrently
 // - manage timeouts
+// - start the queries of foreground sessions first, while still starting at
+// least one query of background sessions every maxForegroundBurst queries
 type ProviderQueryManager struct {
 	ctx                          context.Context
 	network                      ProviderQueryNetwork
@@ -274,18 +281,27 @@ func (pqm *ProviderQueryManager) findProviderWorker() {
 
 func (pqm *ProviderQueryManager) providerRequestBufferWorker() {
 	// the provider request buffer worker just maintains an unbounded
-	// buffer for incoming provider queries and dispatches to the find
-	// provider workers as they become available
+	// buffer for incoming provider queries of each priority class and
+	// dispatches to the find provider workers as they become available
 	// based on: https://medium.com/capital-one-tech/building-an-unbounded-channel-in-go-789e175cd2cd
-	var providerQueryRequestBuffer []*findProviderRequest
+	var fgRequestBuffer, bgRequestBuffer []*findProviderRequest
+	// number of foreground queries dispatched in a row while background
+	// queries were waiting
+	var fgBurst int
+	nextIsBackground := func() bool {
+		return len(bgRequestBuffer) > 0 && (len(fgRequestBuffer) == 0 || fgBurst >= maxForegroundBurst)
+	}
 	nextProviderQuery := func() *findProviderRequest {
-		if len(providerQueryRequestBuffer) == 0 {
+		if nextIsBackground() {
+			return bgRequestBuffer[0]
+		}
+		if len(fgRequestBuffer) == 0 {
 			return nil
 		}
-		return providerQueryRequestBuffer[0]
+		return fgRequestBuffer[0]
 	}
 	outgoingRequests := func() chan<- *findProviderRequest {
-		if len(providerQueryRequestBuffer) == 0 {
+		if len(fgRequestBuffer) == 0 && len(bgRequestBuffer) == 0 {
 			return nil
 		}
 		return pqm.providerRequestsProcessing
@@ -297,9 +313,21 @@ func (pqm *ProviderQueryManager) providerRequestBufferWorker() {
 			if !ok {
 				return
 			}
-			providerQueryRequestBuffer = append(providerQueryRequestBuffer, incomingRequest)
+			if incomingRequest.sp == internal.SessionPriorityBackground {
+				bgRequestBuffer = append(bgRequestBuffer, incomingRequest)
+			} else {
+				fgRequestBuffer = append(fgRequestBuffer, incomingRequest)
+			}
 		case outgoingRequests() <- nextProviderQuery():
-			providerQueryRequestBuffer = providerQueryRequestBuffer[1:]
+			if nextIsBackground() {
+				bgRequestBuffer = bgRequestBuffer[1:]
+				fgBurst = 0
+			} else {
+				fgRequestBuffer = fgRequestBuffer[1:]
+				if len(bgRequestBuffer) > 0 {
+					fgBurst++
+				}
+			}
 		case <-pqm.ctx.Done():
 			return
 		}
@@ -390,6 +418,7 @@ func (npqm *newProvideQueryMessage) handle(pqm *ProviderQueryManager) {
 		case pqm.incomingFindProviderRequests <- &findProviderRequest{
 			k:   npqm.k,
 			ctx: ctx,
+			sp:  internal.SessionPriorityFromContext(npqm.ctx),
 		}:
 		case <-pqm.ctx.Done():
 			return
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	"github.com/ipfs/boxo/bitswap/internal/testutil"
 	cid "github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -94,6 +95,36 @@ func TestNormalSimultaneousFetch(t *testing.T) {
 	}
 }
 
+func TestBackgroundRequestsYieldToForeground(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	providerQueryManager := New(ctx, &fakeProviderNetwork{})
+	go providerQueryManager.providerRequestBufferWorker()
+
+	bgKeys := cid.NewSet()
+	for _, k := range testutil.GenerateCids(6) {
+		bgKeys.Add(k)
+		providerQueryManager.incomingFindProviderRequests <- &findProviderRequest{k: k, ctx: ctx, sp: internal.SessionPriorityBackground}
+	}
+	for _, k := range testutil.GenerateCids(10) {
+		providerQueryManager.incomingFindProviderRequests <- &findProviderRequest{k: k, ctx: ctx}
+	}
+
+	// foreground requests go first, but background requests are not starved
+	var order string
+	for i := 0; i < 16; i++ {
+		req := <-providerQueryManager.providerRequestsProcessing
+		if bgKeys.Has(req.k) {
+			order += "B"
+		} else {
+			order += "F"
+		}
+	}
+	if order != "FFFFBFFFFBFFBBBB" {
+		t.Fatalf("unexpected order of requests: %s", order)
+	}
+}
+
 func TestDedupingProviderRequests(t *testing.T) {
 	peers := testutil.GeneratePeers(10)
 	fpn := &fakeProviderNetwork{
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// MissPolicy configures how a session gives up on the wants that all its
+// peers sent DONT_HAVE for. With the zero value, the wants are kept until the
+// request is cancelled.
+type MissPolicy struct {
+	// Timeout is how long to wait, after all the peers sent DONT_HAVE, for
+	// a peer to be found with the block.
+	Timeout time.Duration
+	// Fallback, if not nil, is called with the wants that timed out. It
+	// returns the wants it could not fetch, the blocks it fetched being
+	// received by the session like blocks from peers.
+	Fallback func(ctx context.Context, ks []cid.Cid) []cid.Cid
+}
+
+func (mp MissPolicy) enabled() bool {
+	return mp.Timeout > 0 || mp.Fallback != nil
+}
+
+// missNotifier tells the GetBlocks requests of a session which of their keys
+// were given up on.
+type missNotifier struct {
+	lk   sync.Mutex
+	subs map[cid.Cid]map[chan cid.Cid]struct{}
+}
+
+func newMissNotifier() *missNotifier {
+	return &missNotifier{subs: make(map[cid.Cid]map[chan cid.Cid]struct{})}
+}
+
+// subscribe returns a channel receiving the keys that are missed, and the
+// function to call once the channel is not read anymore.
+func (mn *missNotifier) subscribe(keys []cid.Cid) (<-chan cid.Cid, func()) {
+	// Each key is sent at most once, so publish never blocks.
+	ch := make(chan cid.Cid, len(keys))
+
+	mn.lk.Lock()
+	defer mn.lk.Unlock()
+	for _, k := range keys {
+		chans, ok := mn.subs[k]
+		if !ok {
+			chans = make(map[chan cid.Cid]struct{})
+			mn.subs[k] = chans
+		}
+		chans[ch] = struct{}{}
+	}
+
+	return ch, func() {
+		mn.lk.Lock()
+		defer mn.lk.Unlock()
+		for _, k := range keys {
+			if chans, ok := mn.subs[k]; ok {
+				delete(chans, ch)
+				if len(chans) == 0 {
+					delete(mn.subs, k)
+				}
+			}
+		}
+	}
+}
+
+// publish sends the missed keys to their subscribers.
+func (mn *missNotifier) publish(keys []cid.Cid) {
+	mn.lk.Lock()
+	defer mn.lk.Unlock()
+	for _, k := range keys {
+		for ch := range mn.subs[k] {
+			ch <- k
+		}
+		delete(mn.subs, k)
+	}
+}
@@ -10,6 +10,16 @@ import (
 // to send us a block for a given CID (used to rank peers)
 type peerResponseTracker struct {
 	firstResponder map[peer.ID]int
+
+	// isLocal, when set, tells which peers are on the local network. They
+	// are chosen before the other peers.
+	isLocal func(peer.ID) bool
+	local   map[peer.ID]bool
+
+	// isRelayed, when set, tells which peers are only reachable through a
+	// relay. They are only chosen when no direct peer is a candidate. It is
+	// not cached, as relayed connections can be upgraded by hole punching.
+	isRelayed func(peer.ID) bool
 }
 
 func newPeerResponseTracker() *peerResponseTracker {
@@ -30,6 +40,8 @@ func (prt *peerResponseTracker) choose(peers []peer.ID) peer.ID {
 	if len(peers) == 0 {
 		return ""
 	}
+	peers = prt.preferDirect(peers)
+	peers = prt.preferLocal(peers)
 
 	rnd := rand.Float64()
 
@@ -56,6 +68,49 @@ func (prt *peerResponseTracker) choose(peers []peer.ID) peer.ID {
 	return peers[index]
 }
 
+// preferDirect returns the candidate peers which are not relayed, or all of the
+// candidates if all of them are relayed.
+func (prt *peerResponseTracker) preferDirect(peers []peer.ID) []peer.ID {
+	if prt.isRelayed == nil {
+		return peers
+	}
+
+	var direct []peer.ID
+	for _, p := range peers {
+		if !prt.isRelayed(p) {
+			direct = append(direct, p)
+		}
+	}
+	if len(direct) == 0 {
+		return peers
+	}
+	return direct
+}
+
+// preferLocal returns the local peers among the candidate peers, or all of the
+// candidates if none of them is local.
+func (prt *peerResponseTracker) preferLocal(peers []peer.ID) []peer.ID {
+	if prt.isLocal == nil {
+		return peers
+	}
+
+	var local []peer.ID
+	for _, p := range peers {
+		isLocal, ok := prt.local[p]
+		if !ok {
+			isLocal = prt.isLocal(p)
+			prt.local[p] = isLocal
+		}
+		if isLocal {
+			local = append(local, p)
+		}
+	}
+	if len(local) == 0 {
+		return peers
+	}
+	return local
+}
+
 // getPeerCount returns the number of times the peer was first to send us a
 // block plus one (in order to never get a zero chance).
 func (prt *peerResponseTracker) getPeerCount(p peer.ID) int {
@@ -115,3 +115,60 @@ func TestPeerResponseTrackerProbabilityProportional(t *testing.T) {
 		}
 	}
 }
+
+func TestPeerResponseTrackerPrefersDirectPeers(t *testing.T) {
+	peers := testutil.GeneratePeers(3)
+	prt := newPeerResponseTracker()
+	relayed := map[peer.ID]bool{peers[0]: true, peers[1]: true}
+	prt.isRelayed = func(p peer.ID) bool {
+		return relayed[p]
+	}
+
+	// The relayed peers sent us blocks first, but direct peers are preferred
+	prt.receivedBlockFrom(peers[0])
+	prt.receivedBlockFrom(peers[1])
+	for i := 0; i < 100; i++ {
+		if p := prt.choose(peers); p != peers[2] {
+			t.Fatal("expected direct peer to be chosen")
+		}
+	}
+
+	// Relayed peers are chosen when there is no direct candidate
+	p := prt.choose([]peer.ID{peers[0], peers[1]})
+	if p != peers[0] && p != peers[1] {
+		t.Fatal("expected relayed peer to be chosen")
+	}
+
+	// Peers are direct again once their connection is upgraded
+	relayed[peers[0]] = false
+	for i := 0; i < 100; i++ {
+		if p := prt.choose(peers[:2]); p != peers[0] {
+			t.Fatal("expected upgraded peer to be chosen")
+		}
+	}
+}
+
+func TestPeerResponseTrackerPrefersLocalPeers(t *testing.T) {
+	peers := testutil.GeneratePeers(4)
+	prt := newPeerResponseTracker()
+	prt.isLocal = func(p peer.ID) bool {
+		return p == peers[1] || p == peers[2]
+	}
+	prt.local = make(map[peer.ID]bool)
+
+	// The remote peers sent us blocks first, but local peers are preferred
+	prt.receivedBlockFrom(peers[0])
+	prt.receivedBlockFrom(peers[3])
+	for i := 0; i < 100; i++ {
+		p := prt.choose(peers)
+		if p != peers[1] && p != peers[2] {
+			t.Fatal("expected local peer to be chosen")
+		}
+	}
+
+	// Remote peers are chosen when there is no local candidate
+	p := prt.choose([]peer.ID{peers[0], peers[3]})
+	if p != peers[0] && p != peers[3] {
+		t.Fatal("expected remote peer to be chosen")
+	}
+}
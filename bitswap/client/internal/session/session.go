@@ -91,11 +91,20 @@ const (
 	opBroadcast
 	// Wants sent to peers
 	opWantsSent
+	// Session statistics requested
+	opStat
+	// Peers have blocks
+	opHave
+	// Wants given up on
+	opMissed
+	// Peer oracle found no peer for wants
+	opOracleMiss
 )
 
 type op struct {
 	op   opType
 	keys []cid.Cid
+	stat chan<- Stat
 }
 
 // Session holds state for an individual bitswap transfer operation.
@@ -109,6 +118,7 @@ type Session struct {
 	pm             PeerManager
 	sprm           SessionPeerManager
 	providerFinder ProviderFinder
+	oracle         func(context.Context, []cid.Cid) map[peer.ID][]cid.Cid
 	sim            *bssim.SessionInterestManager
 
 	sw  sessionWants
@@ -120,6 +130,10 @@ type Session struct {
 	incoming      chan op
 	tickDelayReqs chan time.Duration
 
+	// closed when the run loop exits, after finalStat has been set
+	done      chan struct{}
+	finalStat Stat
+
 	// do not touch outside run loop
 	idleTick            *time.Timer
 	periodicSearchTimer *time.Timer
@@ -127,6 +141,14 @@ type Session struct {
 	consecutiveTicks    int
 	initialSearchDelay  time.Duration
 	periodicSearchDelay delay.D
+	blocksRecvd         uint64
+	dupBlocksRecvd      uint64
+	// wants all peers sent DONT_HAVE for, with the time they are given up
+	exhausted map[cid.Cid]time.Time
+	missTimer *time.Timer
+
+	missPolicy MissPolicy
+	misses     *missNotifier
 	// identifiers
 	notif notifications.PubSub
 	id    uint64
@@ -135,7 +157,13 @@ type Session struct {
 }
 
 // New creates a new bitswap session whose lifetime is bounded by the
-// given context.
+// given context. If isLocal is not nil, want-blocks are sent to the peers it
+// reports as being on the local network before the other peers. If isRelayed
+// is not nil, want-blocks are only sent to the peers it reports as relayed
+// when no direct peer can be chosen. If oracle is not nil, it is asked for
+// the peers likely to have the wants before they are broadcast, and those
+// peers are added to the session as if they had sent HAVEs. The missPolicy
+// tells when to give up on the wants no peer has.
 func New(
 	ctx context.Context,
 	sm SessionManager,
@@ -149,17 +177,23 @@ func New(
 	initialSearchDelay time.Duration,
 	periodicSearchDelay delay.D,
 	self peer.ID,
+	isLocal func(peer.ID) bool,
+	isRelayed func(peer.ID) bool,
+	oracle func(context.Context, []cid.Cid) map[peer.ID][]cid.Cid,
+	missPolicy MissPolicy,
 ) *Session {
 	ctx, cancel := context.WithCancel(ctx)
 	s := &Session{
 		sw:                  newSessionWants(broadcastLiveWantsLimit),
 		tickDelayReqs:       make(chan time.Duration),
+		done:                make(chan struct{}),
 		ctx:                 ctx,
 		shutdown:            cancel,
 		sm:                  sm,
 		pm:                  pm,
 		sprm:                sprm,
 		providerFinder:      providerFinder,
+		oracle:              oracle,
 		sim:                 sim,
 		incoming:            make(chan op, 128),
 		latencyTrkr:         latencyTracker{},
@@ -169,8 +203,24 @@ func New(
 		initialSearchDelay:  initialSearchDelay,
 		periodicSearchDelay: periodicSearchDelay,
 		self:                self,
+		missPolicy:          missPolicy,
+	}
+	if missPolicy.enabled() {
+		s.exhausted = make(map[cid.Cid]time.Time)
+		s.misses = newMissNotifier()
 	}
 	s.sws = newSessionWantSender(id, pm, sprm, sm, bpm, s.onWantsSent, s.onPeersExhausted)
+	if sp := internal.SessionPriorityFromContext(ctx); sp != internal.SessionPriorityForeground {
+		// the wants are sent with the priority class of the session
+		s.sws.ctx = internal.ContextWithSessionPriority(s.sws.ctx, sp)
+	}
+	if isLocal != nil {
+		// prefer sending want-blocks to peers on the local network
+		s.sws.peerRspTrkr.isLocal = isLocal
+		s.sws.peerRspTrkr.local = make(map[peer.ID]bool)
+	}
+	// prefer sending want-blocks to peers which are not behind a relay
+	s.sws.peerRspTrkr.isRelayed = isRelayed
 
 	go s.run(ctx)
 
@@ -199,6 +249,11 @@ func (s *Session) ReceiveFrom(from peer.ID, ks []cid.Cid, haves []cid.Cid, dontH
 	// Inform the session want sender that a message has been received
 	s.sws.Update(from, ks, haves, dontHaves)
 
+	// Wants that a peer has are not given up on
+	if s.misses != nil && len(haves) > 0 {
+		s.nonBlockingEnqueue(op{op: opHave, keys: haves})
+	}
+
 	if len(ks) == 0 {
 		return
 	}
@@ -241,7 +296,13 @@ func (s *Session) GetBlocks(ctx context.Context, keys []cid.Cid) (<-chan blocks.
 	ctx, span := internal.StartSpan(ctx, "Session.GetBlocks")
 	defer span.End()
 
-	return bsgetter.AsyncGetBlocks(ctx, s.ctx, keys, s.notif,
+	var missed <-chan cid.Cid
+	unsubscribe := func() {}
+	if s.misses != nil && len(keys) > 0 {
+		missed, unsubscribe = s.misses.subscribe(keys)
+	}
+
+	return bsgetter.AsyncGetBlocks(ctx, s.ctx, keys, s.notif, missed,
 		func(ctx context.Context, keys []cid.Cid) {
 			select {
 			case s.incoming <- op{op: opWant, keys: keys}:
@@ -250,6 +311,7 @@ func (s *Session) GetBlocks(ctx context.Context, keys []cid.Cid) (<-chan blocks.
 			}
 		},
 		func(keys []cid.Cid) {
+			unsubscribe()
 			select {
 			case s.incoming <- op{op: opCancel, keys: keys}:
 			case <-s.ctx.Done():
@@ -297,11 +359,17 @@ func (s *Session) nonBlockingEnqueue(o op) {
 // Session run loop -- everything in this function should not be called
 // outside of this loop
 func (s *Session) run(ctx context.Context) {
+	defer close(s.done)
 	go s.sws.Run()
 
 	s.idleTick = time.NewTimer(s.initialSearchDelay)
 	s.periodicSearchTimer = time.NewTimer(s.periodicSearchDelay.NextWaitTime())
 	for {
+		var missTimeout <-chan time.Time
+		if s.missTimer != nil {
+			missTimeout = s.missTimer.C
+		}
+
 		select {
 		case oper := <-s.incoming:
 			switch oper.op {
@@ -315,12 +383,26 @@ func (s *Session) run(ctx context.Context) {
 				// Wants were cancelled
 				s.sw.CancelPending(oper.keys)
 				s.sws.Cancel(oper.keys)
+				s.untrackExhausted(oper.keys)
 			case opWantsSent:
 				// Wants were sent to a peer
 				s.sw.WantsSent(oper.keys)
 			case opBroadcast:
 				// Broadcast want-haves to all peers
+				s.trackExhausted(oper.keys)
 				s.broadcast(ctx, oper.keys)
+			case opStat:
+				// Report session statistics
+				oper.stat <- s.stat()
+			case opHave:
+				// Peers have blocks
+				s.untrackExhausted(oper.keys)
+			case opMissed:
+				// Give up on wants
+				s.handleMissed(oper.keys)
+			case opOracleMiss:
+				// Broadcast the wants the peer oracle found no peer for
+				s.handleOracleMiss(ctx, oper.keys)
 			default:
 				panic("unhandled operation")
 			}
@@ -330,11 +412,15 @@ func (s *Session) run(ctx context.Context) {
 		case <-s.periodicSearchTimer.C:
 			// Periodically search for a random live want
 			s.handlePeriodicSearch(ctx)
+		case <-missTimeout:
+			// Exhausted wants timed out
+			s.handleMissTimeout(ctx)
 		case baseTickDelay := <-s.tickDelayReqs:
 			// Set the base tick delay
 			s.baseTickDelay = baseTickDelay
 		case <-ctx.Done():
 			// Shutdown
+			s.finalStat = s.stat()
 			s.handleShutdown()
 			return
 		}
@@ -362,6 +448,7 @@ func (s *Session) broadcast(ctx context.Context, wants []cid.Cid) {
 		// the rest of the blocks also.
 		log.Debugw("FindMorePeers", "session", s.id, "cid", wants[0], "pending", len(wants))
 		s.findMorePeers(ctx, wants[0])
+		s.askOracle(ctx, wants, false)
 	}
 	s.resetIdleTick()
 
@@ -400,10 +487,51 @@ func (s *Session) findMorePeers(ctx context.Context, c cid.Cid) {
 	}(c)
 }
 
+// askOracle asks the peer oracle, if any, for the peers likely to have the
+// given wants, and adds them to the session. If broadcastMiss is true, the
+// wants are broadcast if the oracle finds no peer.
+func (s *Session) askOracle(ctx context.Context, ks []cid.Cid, broadcastMiss bool) bool {
+	if s.oracle == nil || len(ks) == 0 {
+		return false
+	}
+	go func() {
+		peers := s.oracle(ctx, ks)
+		for p, haves := range peers {
+			// A peer suggested by the oracle is treated like a provider
+			s.sws.Update(p, nil, haves, nil)
+		}
+		if len(peers) == 0 && broadcastMiss {
+			s.nonBlockingEnqueue(op{op: opOracleMiss, keys: ks})
+		}
+	}()
+	return true
+}
+
+// handleOracleMiss broadcasts the wants the peer oracle found no peer for, if
+// they are still wanted and no peer was discovered in the meantime.
+func (s *Session) handleOracleMiss(ctx context.Context, ks []cid.Cid) {
+	if s.sprm.PeersDiscovered() {
+		return
+	}
+	wanted := ks[:0:0]
+	for _, c := range ks {
+		if s.sw.isWanted(c) {
+			wanted = append(wanted, c)
+		}
+	}
+	if len(wanted) > 0 {
+		log.Infow("No peers from oracle - broadcasting", "session", s.id, "want-count", len(wanted))
+		s.broadcastWantHaves(ctx, wanted)
+	}
+}
+
 // handleShutdown is called when the session shuts down
 func (s *Session) handleShutdown() {
 	// Stop the idle timer
 	s.idleTick.Stop()
+	if s.missTimer != nil {
+		s.missTimer.Stop()
+	}
 	// Shut down the session peer manager
 	s.sprm.Shutdown()
 	// Shut down the sessionWantSender (blocks until sessionWantSender stops
@@ -419,6 +547,8 @@ func (s *Session) handleReceive(ks []cid.Cid) {
 	// Record which blocks have been received and figure out the total latency
 	// for fetching the blocks
 	wanted, totalLatency := s.sw.BlocksReceived(ks)
+	s.blocksRecvd += uint64(len(wanted))
+	s.dupBlocksRecvd += uint64(len(ks) - len(wanted))
 	if len(wanted) == 0 {
 		return
 	}
@@ -429,6 +559,7 @@ func (s *Session) handleReceive(ks []cid.Cid) {
 	// Inform the SessionInterestManager that this session is no longer
 	// expecting to receive the wanted keys
 	s.sim.RemoveSessionWants(s.id, wanted)
+	s.untrackExhausted(wanted)
 
 	s.idleTick.Stop()
 
@@ -456,14 +587,117 @@ func (s *Session) wantBlocks(ctx context.Context, newks []cid.Cid) {
 		return
 	}
 
-	// No peers discovered yet, broadcast some want-haves
+	// No peers discovered yet, ask the peer oracle or broadcast some
+	// want-haves
 	ks := s.sw.GetNextWants()
+	if s.askOracle(ctx, ks, true) {
+		return
+	}
 	if len(ks) > 0 {
 		log.Infow("No peers - broadcasting", "session", s.id, "want-count", len(ks))
 		s.broadcastWantHaves(ctx, ks)
 	}
 }
 
+// trackExhausted starts the timeout of the wants all peers sent DONT_HAVE
+// for, when wants are given up on.
+func (s *Session) trackExhausted(ks []cid.Cid) {
+	if s.exhausted == nil {
+		return
+	}
+
+	schedule := len(s.exhausted) == 0
+	deadline := time.Now().Add(s.missPolicy.Timeout)
+	for _, c := range ks {
+		if _, ok := s.exhausted[c]; !ok && s.sw.isWanted(c) {
+			s.exhausted[c] = deadline
+		}
+	}
+	if schedule {
+		s.resetMissTimer()
+	}
+}
+
+// untrackExhausted stops the timeout of wants that were received, cancelled
+// or that a peer has.
+func (s *Session) untrackExhausted(ks []cid.Cid) {
+	for _, c := range ks {
+		delete(s.exhausted, c)
+	}
+}
+
+// resetMissTimer sets the miss timer to the earliest exhausted want
+// deadline. The timer can fire with no want to give up on, when they were
+// untracked in the meantime.
+func (s *Session) resetMissTimer() {
+	var next time.Time
+	for _, deadline := range s.exhausted {
+		if next.IsZero() || deadline.Before(next) {
+			next = deadline
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+
+	if s.missTimer == nil {
+		s.missTimer = time.NewTimer(time.Until(next))
+		return
+	}
+	s.missTimer.Stop()
+	s.missTimer.Reset(time.Until(next))
+}
+
+// handleMissTimeout is called when the miss timer fires. The exhausted wants
+// whose timeout elapsed are passed to the fallback, if any, or given up on.
+func (s *Session) handleMissTimeout(ctx context.Context) {
+	now := time.Now()
+	var expired []cid.Cid
+	for c, deadline := range s.exhausted {
+		if !deadline.After(now) {
+			expired = append(expired, c)
+			delete(s.exhausted, c)
+		}
+	}
+	s.resetMissTimer()
+	if len(expired) == 0 {
+		return
+	}
+
+	if s.missPolicy.Fallback == nil {
+		s.handleMissed(expired)
+		return
+	}
+	log.Debugw("fallback", "session", s.id, "cids", expired)
+	go func() {
+		missed := s.missPolicy.Fallback(ctx, expired)
+		if len(missed) > 0 {
+			s.nonBlockingEnqueue(op{op: opMissed, keys: missed})
+		}
+	}()
+}
+
+// handleMissed gives up on the wants that are still wanted: they are
+// cancelled and the requests waiting for them are told.
+func (s *Session) handleMissed(ks []cid.Cid) {
+	missed := ks[:0]
+	for _, c := range ks {
+		if s.sw.isWanted(c) {
+			missed = append(missed, c)
+		}
+	}
+	if len(missed) == 0 {
+		return
+	}
+
+	log.Debugw("wants missed", "session", s.id, "cids", missed)
+	s.sw.WantsMissed(missed)
+	// the wants are out of the wantlist by the time the requests are told
+	s.sws.CancelAndWait(missed)
+	s.untrackExhausted(missed)
+	s.misses.publish(missed)
+}
+
 // Send want-haves to all connected peers
 func (s *Session) broadcastWantHaves(ctx context.Context, wants []cid.Cid) {
 	log.Debugw("broadcastWantHaves", "session", s.id, "cids", wants)
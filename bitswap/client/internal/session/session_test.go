@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/boxo/bitswap/client/internal"
 	bsbpm "github.com/ipfs/boxo/bitswap/client/internal/blockpresencemanager"
 	notifications "github.com/ipfs/boxo/bitswap/client/internal/notifications"
 	bspm "github.com/ipfs/boxo/bitswap/client/internal/peermanager"
@@ -124,7 +125,8 @@ func (fpf *fakeProviderFinder) FindProvidersAsync(ctx context.Context, k cid.Cid
 }
 
 type wantReq struct {
-	cids []cid.Cid
+	cids     []cid.Cid
+	priority internal.SessionPriority
 }
 
 type fakePeerManager struct {
@@ -142,7 +144,7 @@ func (pm *fakePeerManager) UnregisterSession(uint64)
 func (pm *fakePeerManager) SendWants(context.Context, peer.ID, []cid.Cid, []cid.Cid) {}
 func (pm *fakePeerManager) BroadcastWantHaves(ctx context.Context, cids []cid.Cid) {
 	select {
-	case pm.wantReqs <- wantReq{cids}:
+	case pm.wantReqs <- wantReq{cids, internal.SessionPriorityFromContext(ctx)}:
 	case <-ctx.Done():
 	}
 }
@@ -161,7 +163,7 @@ func TestSessionGetBlocks(t *testing.T) {
 	defer notif.Shutdown()
 	id := testutil.GenerateSessionID()
 	sm := newMockSessionMgr()
-	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "")
+	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
 	blockGenerator := blocksutil.NewBlockGenerator()
 	blks := blockGenerator.Blocks(broadcastLiveWantsLimit * 2)
 	var cids []cid.Cid
@@ -256,7 +258,7 @@ func TestSessionFindMorePeers(t *testing.T) {
 	defer notif.Shutdown()
 	id := testutil.GenerateSessionID()
 	sm := newMockSessionMgr()
-	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "")
+	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
 	session.SetBaseTickDelay(200 * time.Microsecond)
 	blockGenerator := blocksutil.NewBlockGenerator()
 	blks := blockGenerator.Blocks(broadcastLiveWantsLimit * 2)
@@ -318,6 +320,82 @@ func TestSessionFindMorePeers(t *testing.T) {
 	}
 }
 
+func TestSessionPriority(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	fpm := newFakePeerManager()
+	notif := notifications.New()
+	defer notif.Shutdown()
+	id := testutil.GenerateSessionID()
+	sessCtx := internal.ContextWithSessionPriority(ctx, internal.SessionPriorityBackground)
+	session := New(sessCtx, newMockSessionMgr(), id, newFakeSessionPeerManager(), newFakeProviderFinder(), bssim.New(), fpm, bsbpm.New(), notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
+
+	blockGenerator := blocksutil.NewBlockGenerator()
+	blk := blockGenerator.Next()
+	if _, err := session.GetBlocks(ctx, []cid.Cid{blk.Cid()}); err != nil {
+		t.Fatal("error getting blocks")
+	}
+
+	// the wants are sent with the priority class of the session
+	if req := <-fpm.wantReqs; req.priority != internal.SessionPriorityBackground {
+		t.Fatalf("expected background wants, got %s wants", req.priority)
+	}
+	if internal.SessionPriorityFromContext(session.sws.ctx) != internal.SessionPriorityBackground {
+		t.Fatal("expected the want sender to send background wants")
+	}
+}
+
+func TestSessionPeerOracle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	blockGenerator := blocksutil.NewBlockGenerator()
+	known := blockGenerator.Next().Cid()
+	unknown := blockGenerator.Next().Cid()
+	p := testutil.GeneratePeers(1)[0]
+	oracle := func(ctx context.Context, ks []cid.Cid) map[peer.ID][]cid.Cid {
+		if len(ks) == 1 && ks[0] == known {
+			return map[peer.ID][]cid.Cid{p: {known}}
+		}
+		return nil
+	}
+
+	fpm := newFakePeerManager()
+	fspm := newFakeSessionPeerManager()
+	notif := notifications.New()
+	defer notif.Shutdown()
+	id := testutil.GenerateSessionID()
+	session := New(ctx, newMockSessionMgr(), id, fspm, newFakeProviderFinder(), bssim.New(), fpm, bsbpm.New(), notif, time.Minute, delay.Fixed(time.Minute), "", nil, nil, oracle, MissPolicy{})
+
+	// The peer found by the oracle is added to the session, and the want is
+	// not broadcast
+	if _, err := session.GetBlocks(ctx, []cid.Cid{known}); err != nil {
+		t.Fatal("error getting blocks")
+	}
+	select {
+	case req := <-fpm.wantReqs:
+		t.Fatalf("expected no broadcast, got %v", req.cids)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !testutil.MatchPeersIgnoreOrder(fspm.Peers(), []peer.ID{p}) {
+		t.Fatal("expected the peer from the oracle to be added to the session")
+	}
+
+	// Wants the oracle finds no peer for are broadcast
+	session2 := New(ctx, newMockSessionMgr(), id+1, newFakeSessionPeerManager(), newFakeProviderFinder(), bssim.New(), fpm, bsbpm.New(), notif, time.Minute, delay.Fixed(time.Minute), "", nil, nil, oracle, MissPolicy{})
+	if _, err := session2.GetBlocks(ctx, []cid.Cid{unknown}); err != nil {
+		t.Fatal("error getting blocks")
+	}
+	select {
+	case req := <-fpm.wantReqs:
+		if len(req.cids) != 1 || req.cids[0] != unknown {
+			t.Fatalf("expected broadcast of %s, got %v", unknown, req.cids)
+		}
+	case <-ctx.Done():
+		t.Fatal("expected the want to be broadcast")
+	}
+}
+
 func TestSessionOnPeersExhausted(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
 	defer cancel()
@@ -331,7 +409,7 @@ func TestSessionOnPeersExhausted(t *testing.T) {
 	defer notif.Shutdown()
 	id := testutil.GenerateSessionID()
 	sm := newMockSessionMgr()
-	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "")
+	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
 	blockGenerator := blocksutil.NewBlockGenerator()
 	blks := blockGenerator.Blocks(broadcastLiveWantsLimit + 5)
 	var cids []cid.Cid
@@ -377,7 +455,7 @@ func TestSessionFailingToGetFirstBlock(t *testing.T) {
 	defer notif.Shutdown()
 	id := testutil.GenerateSessionID()
 	sm := newMockSessionMgr()
-	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, 10*time.Millisecond, delay.Fixed(100*time.Millisecond), "")
+	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, 10*time.Millisecond, delay.Fixed(100*time.Millisecond), "", nil, nil, nil, MissPolicy{})
 	blockGenerator := blocksutil.NewBlockGenerator()
 	blks := blockGenerator.Blocks(4)
 	var cids []cid.Cid
@@ -493,7 +571,7 @@ func TestSessionCtxCancelClosesGetBlocksChannel(t *testing.T) {
 
 	// Create a new session with its own context
 	sessctx, sesscancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-	session := New(sessctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "")
+	session := New(sessctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
 
 	timerCtx, timerCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
 	defer timerCancel()
@@ -544,7 +622,7 @@ func TestSessionOnShutdownCalled(t *testing.T) {
 	// Create a new session with its own context
 	sessctx, sesscancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer sesscancel()
-	session := New(sessctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "")
+	session := New(sessctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
 
 	// Shutdown the session
 	session.Shutdown()
@@ -569,7 +647,7 @@ func TestSessionReceiveMessageAfterCtxCancel(t *testing.T) {
 	defer notif.Shutdown()
 	id := testutil.GenerateSessionID()
 	sm := newMockSessionMgr()
-	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "")
+	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
 	blockGenerator := blocksutil.NewBlockGenerator()
 	blks := blockGenerator.Blocks(2)
 	cids := []cid.Cid{blks[0].Cid(), blks[1].Cid()}
@@ -593,3 +671,70 @@ func TestSessionReceiveMessageAfterCtxCancel(t *testing.T) {
 
 	// If we don't get a panic then the test is considered passing
 }
+
+func TestSessionStats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fpm := newFakePeerManager()
+	fspm := newFakeSessionPeerManager()
+	fpf := newFakeProviderFinder()
+	sim := bssim.New()
+	bpm := bsbpm.New()
+	notif := notifications.New()
+	defer notif.Shutdown()
+	id := testutil.GenerateSessionID()
+	sm := newMockSessionMgr()
+	session := New(ctx, sm, id, fspm, fpf, sim, fpm, bpm, notif, time.Second, delay.Fixed(time.Minute), "", nil, nil, nil, MissPolicy{})
+	blockGenerator := blocksutil.NewBlockGenerator()
+	blks := blockGenerator.Blocks(4)
+	var cids []cid.Cid
+	for _, block := range blks {
+		cids = append(cids, block.Cid())
+	}
+
+	_, err := session.GetBlocks(ctx, cids)
+	if err != nil {
+		t.Fatal("error getting blocks")
+	}
+
+	// Wait for initial want request
+	<-fpm.wantReqs
+
+	st := session.Stats()
+	if st.ID != id {
+		t.Fatal("expected stats for the session")
+	}
+	if st.WantsInFlight != len(cids) || st.WantsPending != 0 {
+		t.Fatalf("expected %d wants in flight, got %d (%d pending)", len(cids), st.WantsInFlight, st.WantsPending)
+	}
+
+	// Receive the same block twice
+	p := testutil.GeneratePeers(1)[0]
+	session.ReceiveFrom(p, []cid.Cid{cids[0]}, []cid.Cid{}, []cid.Cid{})
+	session.ReceiveFrom(p, []cid.Cid{cids[0]}, []cid.Cid{}, []cid.Cid{})
+
+	time.Sleep(10 * time.Millisecond)
+
+	st = session.Stats()
+	if st.BlocksReceived != 1 || st.DupBlksReceived != 1 {
+		t.Fatalf("expected 1 block and 1 duplicate, got %d and %d", st.BlocksReceived, st.DupBlksReceived)
+	}
+	if st.WantsInFlight != len(cids)-1 {
+		t.Fatalf("expected %d wants in flight, got %d", len(cids)-1, st.WantsInFlight)
+	}
+	if st.AverageLatency <= 0 {
+		t.Fatal("expected average latency to be recorded")
+	}
+	if !testutil.MatchPeersIgnoreOrder(st.Peers, []peer.ID{p}) {
+		t.Fatal("expected peer to be reported")
+	}
+
+	// Stats are still available after shutdown
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	st = session.Stats()
+	if st.BlocksReceived != 1 || st.DupBlksReceived != 1 {
+		t.Fatal("expected final stats after shutdown")
+	}
+}
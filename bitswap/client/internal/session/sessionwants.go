@@ -151,6 +151,14 @@ func (sw *sessionWants) CancelPending(keys []cid.Cid) {
 	}
 }
 
+// WantsMissed removes the given CIDs from the fetch queue and the live wants.
+func (sw *sessionWants) WantsMissed(keys []cid.Cid) {
+	for _, k := range keys {
+		sw.toFetch.Remove(k)
+		delete(sw.liveWants, k)
+	}
+}
+
 // LiveWants returns a list of live wants
 func (sw *sessionWants) LiveWants() []cid.Cid {
 	live := make([]cid.Cid, 0, len(sw.liveWants))
@@ -61,6 +61,8 @@ type change struct {
 	add []cid.Cid
 	// wants cancelled
 	cancel []cid.Cid
+	// closed once the cancels were sent, if not nil
+	cancelled chan struct{}
 	// new message received by session (blocks / HAVEs / DONT_HAVEs)
 	update update
 	// peer has connected / disconnected
@@ -158,6 +160,20 @@ func (sws *sessionWantSender) Cancel(ks []cid.Cid) {
 	sws.addChange(change{cancel: ks})
 }
 
+// CancelAndWait is like Cancel, but it waits for the cancels to be sent.
+func (sws *sessionWantSender) CancelAndWait(ks []cid.Cid) {
+	if len(ks) == 0 {
+		return
+	}
+
+	cancelled := make(chan struct{})
+	sws.addChange(change{cancel: ks, cancelled: cancelled})
+	select {
+	case <-cancelled:
+	case <-sws.ctx.Done():
+	}
+}
+
 // Update is called when the session receives a message with incoming blocks
 // or HAVE / DONT_HAVE
 func (sws *sessionWantSender) Update(from peer.ID, ks []cid.Cid, haves []cid.Cid, dontHaves []cid.Cid) {
@@ -253,6 +269,7 @@ func (sws *sessionWantSender) onChange(changes []change) {
 	// Apply each change
 	availability := make(map[peer.ID]bool, len(changes))
 	cancels := make([]cid.Cid, 0)
+	var cancelled []chan struct{}
 	var updates []update
 	for _, chng := range changes {
 		// Initialize info for new wants
@@ -265,6 +282,9 @@ func (sws *sessionWantSender) onChange(changes []change) {
 			sws.untrackWant(c)
 			cancels = append(cancels, c)
 		}
+		if chng.cancelled != nil {
+			cancelled = append(cancelled, chng.cancelled)
+		}
 
 		// Consolidate updates and changes to availability
 		if chng.update.from != "" {
@@ -299,6 +319,9 @@ func (sws *sessionWantSender) onChange(changes []change) {
 	if len(cancels) > 0 {
 		sws.canceller.CancelSessionWants(sws.sessionID, cancels)
 	}
+	for _, ch := range cancelled {
+		close(ch)
+	}
 
 	// If there are some connected peers, send any pending wants
 	if sws.spm.HasPeers() {
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"time"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Stat is a snapshot of the statistics of a single session
+type Stat struct {
+	// ID is the session ID
+	ID uint64
+	// BlocksReceived is the number of wanted blocks received by the session
+	BlocksReceived uint64
+	// DupBlksReceived is the number of blocks received by the session that
+	// it had already received or was no longer waiting for
+	DupBlksReceived uint64
+	// AverageLatency is the average time between sending a want and
+	// receiving the corresponding block, or zero if no block was received yet
+	AverageLatency time.Duration
+	// Peers are the peers the session is currently fetching from
+	Peers []peer.ID
+	// WantsInFlight is the number of wants that have been sent to peers and
+	// are waiting for a block
+	WantsInFlight int
+	// WantsPending is the number of wants that have not been sent yet
+	WantsPending int
+}
+
+// Stats returns a snapshot of the session statistics, including every block
+// the session was told about before the call. Once the session has shut down
+// it returns the statistics at the time of shutdown.
+func (s *Session) Stats() Stat {
+	resp := make(chan Stat, 1)
+	select {
+	case s.incoming <- op{op: opStat, stat: resp}:
+	case <-s.done:
+		return s.finalStat
+	}
+	select {
+	case st := <-resp:
+		return st
+	case <-s.done:
+		return s.finalStat
+	}
+}
+
+// stat builds a statistics snapshot, it must only be called from the run loop
+func (s *Session) stat() Stat {
+	st := Stat{
+		ID:              s.id,
+		BlocksReceived:  s.blocksRecvd,
+		DupBlksReceived: s.dupBlocksRecvd,
+		Peers:           s.sprm.Peers(),
+		WantsInFlight:   len(s.sw.liveWants),
+		WantsPending:    s.sw.toFetch.Len(),
+	}
+	if s.latencyTrkr.hasLatency() {
+		st.AverageLatency = s.latencyTrkr.averageLatency()
+	}
+	return st
+}
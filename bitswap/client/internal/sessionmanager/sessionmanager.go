@@ -2,6 +2,7 @@ package sessionmanager
 
 import (
 	"context"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -25,6 +26,7 @@ type Session interface {
 	exchange.Fetcher
 	ID() uint64
 	ReceiveFrom(peer.ID, []cid.Cid, []cid.Cid, []cid.Cid)
+	Stats() bssession.Stat
 	Shutdown()
 }
 
@@ -143,6 +145,26 @@ func (sm *SessionManager) RemoveSession(sesid uint64) {
 	}
 }
 
+// SessionStats returns a statistics snapshot of every active session, ordered
+// by session ID.
+func (sm *SessionManager) SessionStats() []bssession.Stat {
+	sm.sessLk.RLock()
+	sessions := make([]Session, 0, len(sm.sessions))
+	for _, ses := range sm.sessions {
+		sessions = append(sessions, ses)
+	}
+	sm.sessLk.RUnlock()
+
+	// Query the sessions without holding the lock, a session that is
+	// shutting down needs it to remove itself
+	stats := make([]bssession.Stat, 0, len(sessions))
+	for _, ses := range sessions {
+		stats = append(stats, ses.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
+
 // GetNextSessionID returns the next sequential identifier for a session.
 func (sm *SessionManager) GetNextSessionID() uint64 {
 	sm.sessIDLk.Lock()
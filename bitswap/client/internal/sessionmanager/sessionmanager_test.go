@@ -47,6 +47,10 @@ func (fs *fakeSession) ReceiveFrom(p peer.ID, ks []cid.Cid, wantBlocks []cid.Cid
 	fs.wantHaves = append(fs.wantHaves, wantHaves...)
 }
 
+func (fs *fakeSession) Stats() bssession.Stat {
+	return bssession.Stat{ID: fs.id, BlocksReceived: uint64(len(fs.ks))}
+}
+
 func (fs *fakeSession) Shutdown() {
 	fs.sm.RemoveSession(fs.id)
 }
@@ -262,3 +266,37 @@ func TestShutdown(t *testing.T) {
 		t.Fatal("expected cancels to be sent")
 	}
 }
+
+func TestSessionStats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notif := notifications.New()
+	defer notif.Shutdown()
+	sim := bssim.New()
+	bpm := bsbpm.New()
+	pm := &fakePeerManager{}
+	sm := New(ctx, sessionFactory, sim, peerManagerFactory, bpm, pm, notif, "")
+
+	p := peer.ID(strconv.Itoa(123))
+	block := blocks.NewBlock([]byte("block"))
+
+	firstSession := sm.NewSession(ctx, time.Second, delay.Fixed(time.Minute)).(*fakeSession)
+	secondSession := sm.NewSession(ctx, time.Second, delay.Fixed(time.Minute)).(*fakeSession)
+	thirdSession := sm.NewSession(ctx, time.Second, delay.Fixed(time.Minute)).(*fakeSession)
+
+	sim.RecordSessionInterest(secondSession.ID(), []cid.Cid{block.Cid()})
+	sm.ReceiveFrom(ctx, p, []cid.Cid{block.Cid()}, []cid.Cid{}, []cid.Cid{})
+
+	thirdSession.Shutdown()
+
+	stats := sm.SessionStats()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 sessions, got %d", len(stats))
+	}
+	if stats[0].ID != firstSession.ID() || stats[1].ID != secondSession.ID() {
+		t.Fatal("expected stats ordered by session ID")
+	}
+	if stats[0].BlocksReceived != 0 || stats[1].BlocksReceived != 1 {
+		t.Fatal("unexpected blocks received in session stats")
+	}
+}
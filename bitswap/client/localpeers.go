@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"net/netip"
+
+	bsnet "github.com/ipfs/boxo/bitswap/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// defaultLocalSubnets are the private (RFC 1918 and RFC 4193), link-local and
+// loopback address ranges.
+var defaultLocalSubnets = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("::1/128"),
+}
+
+// WithLocalPeerPreference makes sessions send their want-blocks to peers on
+// the local network before peers further away. A peer is local when one of
+// its connections has a remote address in the given subnets, which default
+// to the private, link-local and loopback ranges.
+//
+// It requires a network implementing [bsnet.ConnectedAddrs], such as the one
+// returned by [bsnet.NewFromIpfsHost]. It can be overridden per session with
+// [ContextWithLocalPeerPreference].
+func WithLocalPeerPreference(subnets ...netip.Prefix) Option {
+	if len(subnets) == 0 {
+		subnets = defaultLocalSubnets
+	}
+	return func(bs *Client) {
+		bs.localSubnets = subnets
+	}
+}
+
+type localPeerPreferenceKey struct{}
+
+// ContextWithLocalPeerPreference returns a context which enables or disables
+// the preference for local peers, see [WithLocalPeerPreference], of the
+// sessions created with it by [Client.NewSession] and [Client.GetBlocks].
+// When the client was not configured with subnets, the default ones are used.
+func ContextWithLocalPeerPreference(ctx context.Context, prefer bool) context.Context {
+	return context.WithValue(ctx, localPeerPreferenceKey{}, prefer)
+}
+
+// localPeerFilter returns the function telling the local peers of a session
+// created with ctx, or nil if the session has no preference.
+func (bs *Client) localPeerFilter(ctx context.Context) func(peer.ID) bool {
+	prefer := len(bs.localSubnets) != 0
+	if v, ok := ctx.Value(localPeerPreferenceKey{}).(bool); ok {
+		prefer = v
+	}
+	if !prefer {
+		return nil
+	}
+
+	addrs, ok := bs.network.(bsnet.ConnectedAddrs)
+	if !ok {
+		return nil
+	}
+	subnets := bs.localSubnets
+	if len(subnets) == 0 {
+		subnets = defaultLocalSubnets
+	}
+	return func(p peer.ID) bool {
+		for _, a := range addrs.ConnectedAddrs(p) {
+			if isLocalAddr(a, subnets) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isLocalAddr reports whether the IP address of a is in one of the subnets.
+func isLocalAddr(a ma.Multiaddr, subnets []netip.Prefix) bool {
+	ip, err := manet.ToIP(a)
+	if err != nil {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, s := range subnets {
+		if s.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
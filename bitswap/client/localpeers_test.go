@@ -0,0 +1,34 @@
+package client
+
+import (
+	"net/netip"
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestIsLocalAddr(t *testing.T) {
+	custom := []netip.Prefix{netip.MustParsePrefix("100.64.0.0/10")}
+
+	testCases := []struct {
+		addr    string
+		subnets []netip.Prefix
+		local   bool
+	}{
+		{"/ip4/192.168.1.10/tcp/4001", defaultLocalSubnets, true},
+		{"/ip4/10.1.2.3/udp/4001/quic-v1", defaultLocalSubnets, true},
+		{"/ip4/169.254.0.1/tcp/4001", defaultLocalSubnets, true},
+		{"/ip6/fe80::1/tcp/4001", defaultLocalSubnets, true},
+		{"/ip6/fd00::1/tcp/4001", defaultLocalSubnets, true},
+		{"/ip4/8.8.8.8/tcp/4001", defaultLocalSubnets, false},
+		{"/ip6/2001:db8::1/tcp/4001", defaultLocalSubnets, false},
+		{"/dns4/example.com/tcp/4001", defaultLocalSubnets, false},
+		{"/ip4/100.64.1.1/tcp/4001", custom, true},
+		{"/ip4/192.168.1.10/tcp/4001", custom, false},
+	}
+	for _, tc := range testCases {
+		if got := isLocalAddr(ma.StringCast(tc.addr), tc.subnets); got != tc.local {
+			t.Errorf("isLocalAddr(%s) = %t, expected %t", tc.addr, got, tc.local)
+		}
+	}
+}
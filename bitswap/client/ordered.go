@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+
+	exchange "github.com/ipfs/boxo/exchange"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+)
+
+// WithOrderedBlocks makes GetBlocks of the session return the blocks in the
+// order of the requested keys, which is useful to consumers such as CAR
+// writers which need a deterministic order.
+//
+// At most window keys, starting from the first block not returned yet, are
+// requested at once, which bounds the number of blocks received out of order
+// and buffered until their turn. Blocks which are given up on, for example
+// because of [WithExhaustedWantTimeout], are skipped like without this
+// option. A window of 1 fetches the blocks one at a time.
+func WithOrderedBlocks(window int) SessionOption {
+	return func(so *sessionOptions) {
+		so.orderedWindow = window
+	}
+}
+
+// orderedFetcher is an [exchange.Fetcher] whose GetBlocks returns the blocks
+// in the order of the requested keys.
+type orderedFetcher struct {
+	exchange.Fetcher
+	window int
+}
+
+func (f orderedFetcher) GetBlocks(ctx context.Context, keys []cid.Cid) (<-chan blocks.Block, error) {
+	return orderedGetBlocks(ctx, f.Fetcher, keys, f.window)
+}
+
+// orderedRequest is a GetBlocks call of the keys between lo and hi.
+type orderedRequest struct {
+	lo, hi int
+	done   bool
+}
+
+func orderedGetBlocks(ctx context.Context, f exchange.Fetcher, keys []cid.Cid, window int) (<-chan blocks.Block, error) {
+	if window < 1 {
+		window = 1
+	}
+
+	// Every key is returned at most once, at its first position.
+	seen := cid.NewSet()
+	ks := make([]cid.Cid, 0, len(keys))
+	for _, k := range keys {
+		if seen.Visit(k) {
+			ks = append(ks, k)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	incoming := make(chan blocks.Block)
+	finished := make(chan *orderedRequest)
+
+	var requests []*orderedRequest
+	var requested int
+	request := func(next int) error {
+		hi := min(next+window, len(ks))
+		if hi <= requested {
+			return nil
+		}
+
+		ch, err := f.GetBlocks(ctx, ks[requested:hi])
+		if err != nil {
+			return err
+		}
+		req := &orderedRequest{lo: requested, hi: hi}
+		requests = append(requests, req)
+		requested = hi
+
+		go func() {
+			for blk := range ch {
+				select {
+				case incoming <- blk:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case finished <- req:
+			case <-ctx.Done():
+			}
+		}()
+		return nil
+	}
+
+	if err := request(0); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	out := make(chan blocks.Block)
+	go func() {
+		defer cancel()
+		defer close(out)
+
+		buffered := make(map[cid.Cid]blocks.Block)
+		next := 0
+		for next < len(ks) {
+			if blk, ok := buffered[ks[next]]; ok {
+				delete(buffered, ks[next])
+				select {
+				case out <- blk:
+				case <-ctx.Done():
+					return
+				}
+			} else if !requestFor(requests, next).done {
+				select {
+				case blk := <-incoming:
+					if seen.Has(blk.Cid()) {
+						buffered[blk.Cid()] = blk
+					}
+				case req := <-finished:
+					req.done = true
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			// The block was returned or given up on.
+			seen.Remove(ks[next])
+			next++
+			for len(requests) != 0 && requests[0].hi <= next {
+				requests = requests[1:]
+			}
+
+			if err := request(next); err != nil {
+				log.Debugw("cannot request blocks in order", "error", err)
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// requestFor returns the request of the key at index i.
+func requestFor(requests []*orderedRequest, i int) *orderedRequest {
+	for _, req := range requests {
+		if i >= req.lo && i < req.hi {
+			return req
+		}
+	}
+	panic("key was not requested")
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/boxo/bitswap/internal/testutil"
+	exchange "github.com/ipfs/boxo/exchange"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+)
+
+// reverseFetcher returns the blocks it has in the reverse order of the
+// requested keys, and records the largest request.
+type reverseFetcher struct {
+	exchange.Fetcher
+	blks map[cid.Cid]blocks.Block
+
+	lk         sync.Mutex
+	maxRequest int
+}
+
+func (f *reverseFetcher) GetBlocks(ctx context.Context, keys []cid.Cid) (<-chan blocks.Block, error) {
+	f.lk.Lock()
+	f.maxRequest = max(f.maxRequest, len(keys))
+	f.lk.Unlock()
+
+	out := make(chan blocks.Block, len(keys))
+	for i := len(keys) - 1; i >= 0; i-- {
+		if blk, ok := f.blks[keys[i]]; ok {
+			out <- blk
+		}
+	}
+	close(out)
+	return out, nil
+}
+
+func TestOrderedGetBlocks(t *testing.T) {
+	blks := testutil.GenerateBlocksOfSize(20, 10)
+	f := &reverseFetcher{blks: make(map[cid.Cid]blocks.Block)}
+	var keys []cid.Cid
+	for i, blk := range blks {
+		keys = append(keys, blk.Cid())
+		// One block is missing and must be skipped.
+		if i != 7 {
+			f.blks[blk.Cid()] = blk
+		}
+	}
+	// Duplicate keys are returned once.
+	keys = append(keys, keys[0])
+
+	const window = 4
+	ch, err := orderedGetBlocks(context.Background(), f, keys, window)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var i int
+	for blk := range ch {
+		if i == 7 {
+			i++
+		}
+		if blk.Cid() != blks[i].Cid() {
+			t.Fatalf("expected block %d to be %s, got %s", i, blks[i].Cid(), blk.Cid())
+		}
+		i++
+	}
+	if i != len(blks) {
+		t.Fatalf("expected %d blocks, got %d", len(blks)-1, i-1)
+	}
+	if f.maxRequest > window {
+		t.Fatalf("expected at most %d keys requested at once, got %d", window, f.maxRequest)
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs/boxo/bitswap/internal/defaults"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerOracle is an index external to the network, such as the HTTP or gRPC
+// service of a large deployment, which knows the peers likely to have blocks.
+type PeerOracle interface {
+	// FindPeers returns the peers likely to have the blocks of the given
+	// CIDs, by CID. CIDs without known peers may be omitted.
+	FindPeers(ctx context.Context, ks []cid.Cid) (map[cid.Cid][]peer.ID, error)
+}
+
+// PeerOracleFunc is an adapter to use a function as a [PeerOracle].
+type PeerOracleFunc func(ctx context.Context, ks []cid.Cid) (map[cid.Cid][]peer.ID, error)
+
+// FindPeers calls f(ctx, ks).
+func (f PeerOracleFunc) FindPeers(ctx context.Context, ks []cid.Cid) (map[cid.Cid][]peer.ID, error) {
+	return f(ctx, ks)
+}
+
+// WithPeerOracle makes sessions ask the given oracle for the peers likely to
+// have the blocks they want, before broadcasting their wants to all the
+// connected peers, and whenever they search for more peers. The client
+// connects to the peers found, which are then used like the providers found
+// by content routing. Wants are only broadcast when the oracle finds no peer.
+//
+// Each query, including the connections to the peers found, is bounded by
+// timeout, which defaults to one second when zero. Errors of the oracle are
+// logged and treated as finding no peer.
+func WithPeerOracle(oracle PeerOracle, timeout time.Duration) Option {
+	if timeout <= 0 {
+		timeout = defaults.PeerOracleTimeout
+	}
+	return func(bs *Client) {
+		bs.peerOracle = oracle
+		bs.peerOracleTimeout = timeout
+	}
+}
+
+// sessionPeerOracle returns the function finding the peers of the wants of
+// sessions with the peer oracle, or nil if there is no oracle.
+func (bs *Client) sessionPeerOracle() func(context.Context, []cid.Cid) map[peer.ID][]cid.Cid {
+	if bs.peerOracle == nil {
+		return nil
+	}
+	return bs.findOraclePeers
+}
+
+// findOraclePeers asks the peer oracle for the peers likely to have ks, and
+// returns the ones it could connect to, with the keys they likely have.
+func (bs *Client) findOraclePeers(ctx context.Context, ks []cid.Cid) map[peer.ID][]cid.Cid {
+	ctx, cancel := context.WithTimeout(ctx, bs.peerOracleTimeout)
+	defer cancel()
+
+	found, err := bs.peerOracle.FindPeers(ctx, ks)
+	if err != nil {
+		log.Debugw("peer oracle failed", "error", err)
+		return nil
+	}
+
+	wanted := cid.NewSet()
+	for _, k := range ks {
+		wanted.Add(k)
+	}
+	self := bs.network.Self()
+	peers := make(map[peer.ID][]cid.Cid)
+	for k, ps := range found {
+		if !wanted.Has(k) {
+			continue
+		}
+		for _, p := range ps {
+			if p != self {
+				peers[p] = append(peers[p], k)
+			}
+		}
+	}
+
+	var (
+		lk          sync.Mutex
+		unreachable []peer.ID
+		wg          sync.WaitGroup
+	)
+	for p := range peers {
+		wg.Add(1)
+		go func(p peer.ID) {
+			defer wg.Done()
+			if err := bs.network.ConnectTo(ctx, p); err != nil {
+				log.Debugw("cannot connect to peer from oracle", "peer", p, "error", err)
+				lk.Lock()
+				unreachable = append(unreachable, p)
+				lk.Unlock()
+			}
+		}(p)
+	}
+	wg.Wait()
+	for _, p := range unreachable {
+		delete(peers, p)
+	}
+	return peers
+}
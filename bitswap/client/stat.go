@@ -1,6 +1,7 @@
 package client
 
 import (
+	bssession "github.com/ipfs/boxo/bitswap/client/internal/session"
 	cid "github.com/ipfs/go-cid"
 )
 
@@ -28,3 +29,14 @@ func (bs *Client) Stat() (st Stat, err error) {
 
 	return st, nil
 }
+
+// SessionStat is a snapshot of the statistics of a single session. Sessions
+// returned by [Client.NewSession] also implement
+// interface{ Stats() SessionStat } to get the statistics of that session.
+type SessionStat = bssession.Stat
+
+// SessionStats returns the statistics of every active session, ordered by
+// session ID
+func (bs *Client) SessionStats() []SessionStat {
+	return bs.sm.SessionStats()
+}
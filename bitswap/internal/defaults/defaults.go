@@ -10,6 +10,10 @@ const (
 	ProvideTimeout  = time.Minute * 3
 	ProvSearchDelay = time.Second
 
+	// PeerOracleTimeout bounds the queries of the peer oracle of the client,
+	// and the connections to the peers it finds.
+	PeerOracleTimeout = time.Second
+
 	// Number of concurrent workers in decision engine that process requests to the blockstore
 	BitswapEngineBlockstoreWorkerCount = 128
 	// the total number of simultaneous threads sending outgoing messages
@@ -37,4 +41,8 @@ const (
 	// RebroadcastDelay is the default delay to trigger broadcast of
 	// random CIDs in the wantlist.
 	RebroadcastDelay = time.Minute
+
+	// MaxBlockSize is the default maximum size of the blocks accepted from
+	// peers, which is the maximum block size of the Bitswap spec.
+	MaxBlockSize = 2 << 20
 )
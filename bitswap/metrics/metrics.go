@@ -44,3 +44,7 @@ func PendingBlocksGauge(ctx context.Context) metrics.Gauge {
 func ActiveBlocksGauge(ctx context.Context) metrics.Gauge {
 	return metrics.NewCtx(ctx, "active_block_tasks", "Total number of active blockstore tasks").Gauge()
 }
+
+func ShedWantsCounter(ctx context.Context) metrics.Counter {
+	return metrics.NewCtx(ctx, "shed_wants", "Total number of wants dropped because the queue of a peer was full").Counter()
+}
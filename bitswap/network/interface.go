@@ -12,6 +12,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/connmgr"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 var (
@@ -23,6 +24,9 @@ var (
 	ProtocolBitswapOneOne = internal.ProtocolBitswapOneOne
 	// ProtocolBitswap is the current version of the bitswap protocol: 1.2.0
 	ProtocolBitswap = internal.ProtocolBitswap
+	// ProtocolBitswapControl is the protocol of the separate stream used for
+	// control messages, see [ControlStream]
+	ProtocolBitswapControl = internal.ProtocolBitswapControl
 )
 
 // BitSwapNetwork provides network connectivity for BitSwap sessions.
@@ -102,6 +106,38 @@ type Pinger interface {
 	Latency(peer.ID) time.Duration
 }
 
+// ConnectedAddrs is implemented by networks which know the remote addresses of
+// their connections. It is used by the client to tell peers on the local
+// network apart.
+type ConnectedAddrs interface {
+	// ConnectedAddrs returns the remote addresses of the open connections to
+	// the given peer.
+	ConnectedAddrs(peer.ID) []ma.Multiaddr
+}
+
+// ConnQuality describes the connections to a peer.
+type ConnQuality struct {
+	// Connected is false when there is no open connection to the peer, in
+	// which case the other fields are not set.
+	Connected bool
+	// Relayed is true when all the connections to the peer go through a
+	// relay. Connections upgraded by hole punching are direct.
+	Relayed bool
+	// Transport is the name of the transport of the best connection to the
+	// peer, such as "tcp", "quic-v1" or "webtransport".
+	Transport string
+	// RTT is the estimated round trip time to the peer, 0 if unknown.
+	RTT time.Duration
+}
+
+// ConnectionQuality is implemented by networks which know how they are
+// connected to peers. It is used by the client to prefer direct peers over
+// relayed ones.
+type ConnectionQuality interface {
+	// ConnQuality returns the quality of the connections to the peer.
+	ConnQuality(peer.ID) ConnQuality
+}
+
 // Stats is a container for statistics about the bitswap network
 // the numbers inside are specific to bitswap, and not any other protocols
 // using the same underlying network.
@@ -13,6 +13,10 @@ var (
 	ProtocolBitswapOneOne protocol.ID = "/ipfs/bitswap/1.1.0"
 	// ProtocolBitswap is the current version of the bitswap protocol: 1.2.0
 	ProtocolBitswap protocol.ID = "/ipfs/bitswap/1.2.0"
+	// ProtocolBitswapControl is used to send small control messages
+	// (want-have, cancel, have, dont-have) on a separate stream from blocks,
+	// using the 1.2.0 wire format
+	ProtocolBitswapControl protocol.ID = "/ipfs/bitswap/1.2.0/control"
 )
 
 var DefaultProtocols = []protocol.ID{
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	bsmsg "github.com/ipfs/boxo/bitswap/message"
+	pb "github.com/ipfs/boxo/bitswap/message/pb"
 	"github.com/ipfs/boxo/bitswap/network/internal"
 
 	cid "github.com/ipfs/go-cid"
@@ -35,6 +36,8 @@ var (
 	minSendTimeout = 10 * time.Second
 	sendLatency    = 2 * time.Second
 	minSendRate    = (100 * 1000) / 8 // 100kbit/s
+
+	controlSendTimeout = 5 * time.Second
 )
 
 // NewFromIpfsHost returns a BitSwapNetwork supported by underlying IPFS host.
@@ -52,6 +55,9 @@ func NewFromIpfsHost(host host.Host, r routing.ContentRouting, opts ...NetOpt) B
 
 		supportedProtocols: s.SupportedProtocols,
 	}
+	if s.ControlStream {
+		bitswapNetwork.protocolBitswapControl = s.ProtocolPrefix + ProtocolBitswapControl
+	}
 
 	return &bitswapNetwork
 }
@@ -83,6 +89,9 @@ type impl struct {
 	protocolBitswapOneOne  protocol.ID
 	protocolBitswap        protocol.ID
 
+	// protocolBitswapControl is empty unless the control stream is enabled
+	protocolBitswapControl protocol.ID
+
 	supportedProtocols []protocol.ID
 
 	// inbound messages from the network are forwarded to the receiver
@@ -95,6 +104,16 @@ type streamMessageSender struct {
 	connected bool
 	bsnet     *impl
 	opts      *MessageSenderOpts
+
+	// noControlStream is set once the peer is known not to support the
+	// control protocol
+	noControlStream bool
+	// control is the long lived stream of the control protocol,
+	// opened on the first control message
+	control network.Stream
+	// mainStreamWants are the keys wanted over the main stream, whose cancels
+	// must follow them on the same stream
+	mainStreamWants map[cid.Cid]struct{}
 }
 
 // Open a stream to the remote peer
@@ -122,6 +141,7 @@ func (s *streamMessageSender) Connect(ctx context.Context) (network.Stream, erro
 
 // Reset the stream
 func (s *streamMessageSender) Reset() error {
+	s.resetControl()
 	if s.stream != nil {
 		err := s.stream.Reset()
 		s.connected = false
@@ -132,6 +152,10 @@ func (s *streamMessageSender) Reset() error {
 
 // Close the stream
 func (s *streamMessageSender) Close() error {
+	if s.control != nil {
+		_ = s.control.Close()
+		s.control = nil
+	}
 	return s.stream.Close()
 }
 
@@ -142,9 +166,50 @@ func (s *streamMessageSender) SupportsHave() bool {
 
 // Send a message to the peer, attempting multiple times
 func (s *streamMessageSender) SendMsg(ctx context.Context, msg bsmsg.BitSwapMessage) error {
-	return s.multiAttempt(ctx, func() error {
+	if s.bsnet.protocolBitswapControl != "" && !s.noControlStream && isControlMessage(msg) && !s.cancelsMainStreamWants(msg) {
+		err := s.sendControl(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, multistream.ErrNotSupported[protocol.ID]{}) {
+			s.noControlStream = true
+		}
+		log.Debugf("control stream send to %s failed, falling back to stream: %s", s.to, err)
+	}
+
+	err := s.multiAttempt(ctx, func() error {
 		return s.send(ctx, msg)
 	})
+	if err == nil && s.bsnet.protocolBitswapControl != "" {
+		s.trackMainStreamWants(msg)
+	}
+	return err
+}
+
+// cancelsMainStreamWants returns true if msg cancels a want sent over the main
+// stream, so that the cancel cannot overtake the want on the control stream.
+func (s *streamMessageSender) cancelsMainStreamWants(msg bsmsg.BitSwapMessage) bool {
+	for _, e := range msg.Wantlist() {
+		if _, ok := s.mainStreamWants[e.Cid]; ok && e.Cancel {
+			return true
+		}
+	}
+	return false
+}
+
+// trackMainStreamWants records the keys wanted and cancelled by msg, sent over
+// the main stream.
+func (s *streamMessageSender) trackMainStreamWants(msg bsmsg.BitSwapMessage) {
+	for _, e := range msg.Wantlist() {
+		if e.Cancel {
+			delete(s.mainStreamWants, e.Cid)
+			continue
+		}
+		if s.mainStreamWants == nil {
+			s.mainStreamWants = make(map[cid.Cid]struct{})
+		}
+		s.mainStreamWants[e.Cid] = struct{}{}
+	}
 }
 
 // Perform a function with multiple attempts, and a timeout
@@ -213,6 +278,50 @@ func (s *streamMessageSender) send(ctx context.Context, msg bsmsg.BitSwapMessage
 	return nil
 }
 
+// Send a message to the peer with a single attempt over the long lived stream
+// of the control protocol, so that control messages stay in order
+func (s *streamMessageSender) sendControl(ctx context.Context, msg bsmsg.BitSwapMessage) error {
+	tctx, cancel := context.WithTimeout(ctx, controlSendTimeout)
+	defer cancel()
+
+	if s.control == nil {
+		stream, err := s.bsnet.host.NewStream(tctx, s.to, s.bsnet.protocolBitswapControl)
+		if err != nil {
+			return err
+		}
+		s.control = stream
+	}
+
+	if err := s.bsnet.msgToStream(tctx, s.control, msg, controlSendTimeout); err != nil {
+		s.resetControl()
+		return err
+	}
+	return nil
+}
+
+// resetControl resets the control stream, the next control message opens a
+// new one
+func (s *streamMessageSender) resetControl() {
+	if s.control != nil {
+		_ = s.control.Reset()
+		s.control = nil
+	}
+}
+
+// isControlMessage returns true if msg only carries entries that can be lost
+// without breaking the protocol: want-haves, cancels and block presences.
+func isControlMessage(msg bsmsg.BitSwapMessage) bool {
+	if msg.Full() || len(msg.Blocks()) != 0 {
+		return false
+	}
+	for _, e := range msg.Wantlist() {
+		if !e.Cancel && e.WantType != pb.Message_Wantlist_Have {
+			return false
+		}
+	}
+	return true
+}
+
 func (bsnet *impl) Self() peer.ID {
 	return bsnet.host.ID()
 }
@@ -228,6 +337,56 @@ func (bsnet *impl) Latency(p peer.ID) time.Duration {
 	return bsnet.host.Peerstore().LatencyEWMA(p)
 }
 
+func (bsnet *impl) ConnectedAddrs(p peer.ID) []ma.Multiaddr {
+	conns := bsnet.host.Network().ConnsToPeer(p)
+	addrs := make([]ma.Multiaddr, 0, len(conns))
+	for _, c := range conns {
+		addrs = append(addrs, c.RemoteMultiaddr())
+	}
+	return addrs
+}
+
+// ConnQuality returns the quality of the connections to the peer, describing
+// its first direct connection if it has one.
+func (bsnet *impl) ConnQuality(p peer.ID) ConnQuality {
+	conns := bsnet.host.Network().ConnsToPeer(p)
+	if len(conns) == 0 {
+		return ConnQuality{}
+	}
+	q := ConnQuality{
+		Connected: true,
+		Relayed:   true,
+		RTT:       bsnet.host.Peerstore().LatencyEWMA(p),
+	}
+	for _, c := range conns {
+		addr := c.RemoteMultiaddr()
+		if _, err := addr.ValueForProtocol(ma.P_CIRCUIT); err == nil {
+			if q.Transport == "" {
+				q.Transport = transportName(addr)
+			}
+			continue
+		}
+		q.Relayed = false
+		q.Transport = transportName(addr)
+		break
+	}
+	return q
+}
+
+// transportName returns the name of the last protocol of addr which is not a
+// peer ID or a certificate hash, such as "tcp", "quic-v1" or "p2p-circuit".
+func transportName(addr ma.Multiaddr) string {
+	protos := addr.Protocols()
+	for i := len(protos) - 1; i >= 0; i-- {
+		switch protos[i].Code {
+		case ma.P_P2P, ma.P_CERTHASH:
+			continue
+		}
+		return protos[i].Name
+	}
+	return ""
+}
+
 // Indicates whether the given protocol supports HAVE / DONT_HAVE messages
 func (bsnet *impl) SupportsHave(proto protocol.ID) bool {
 	switch proto {
@@ -251,7 +410,7 @@ func (bsnet *impl) msgToStream(ctx context.Context, s network.Stream, msg bsmsg.
 	// to convert the message to the appropriate format depending on the remote
 	// peer's Bitswap version.
 	switch s.Protocol() {
-	case bsnet.protocolBitswapOneOne, bsnet.protocolBitswap:
+	case bsnet.protocolBitswapOneOne, bsnet.protocolBitswap, bsnet.protocolBitswapControl:
 		if err := msg.ToNetV1(s); err != nil {
 			log.Debugf("error: %s", err)
 			return err
@@ -356,6 +515,9 @@ func (bsnet *impl) Start(r ...Receiver) {
 	for _, proto := range bsnet.supportedProtocols {
 		bsnet.host.SetStreamHandler(proto, bsnet.handleNewStream)
 	}
+	if bsnet.protocolBitswapControl != "" {
+		bsnet.host.SetStreamHandler(bsnet.protocolBitswapControl, bsnet.handleNewStream)
+	}
 	bsnet.host.Network().Notify((*netNotifiee)(bsnet))
 	bsnet.connectEvtMgr.Start()
 }
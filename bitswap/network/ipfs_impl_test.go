@@ -78,6 +78,7 @@ type ErrStream struct {
 	err       error
 	timingOut bool
 	closed    bool
+	writes    int
 }
 
 type ErrHost struct {
@@ -98,6 +99,7 @@ func (es *ErrStream) Write(b []byte) (int, error) {
 	if es.timingOut {
 		return 0, context.DeadlineExceeded
 	}
+	es.writes++
 	return es.Stream.Write(b)
 }
 
@@ -669,3 +671,105 @@ func TestNetworkCounters(t *testing.T) {
 		testNetworkCounters(t, 10-n, n)
 	}
 }
+
+func TestControlStream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, remoteSupport := range []bool{true, false} {
+		t.Run(fmt.Sprintf("remote-support-%v", remoteSupport), func(t *testing.T) {
+			mn := mocknet.New()
+			defer mn.Close()
+			mr := mockrouting.NewServer()
+
+			p1 := tnet.RandIdentityOrFatal(t)
+			h1, err := mn.AddPeer(p1.PrivateKey(), p1.Address())
+			if err != nil {
+				t.Fatal(err)
+			}
+			eh1 := &ErrHost{Host: h1}
+			bsnet1 := bsnet.NewFromIpfsHost(eh1, mr.ClientWithDatastore(ctx, p1, ds.NewMapDatastore()), bsnet.ControlStream(true))
+			bsnet1.Start(newReceiver())
+			t.Cleanup(bsnet1.Stop)
+
+			p2 := tnet.RandIdentityOrFatal(t)
+			h2, err := mn.AddPeer(p2.PrivateKey(), p2.Address())
+			if err != nil {
+				t.Fatal(err)
+			}
+			r2 := newReceiver()
+			bsnet2 := bsnet.NewFromIpfsHost(h2, mr.ClientWithDatastore(ctx, p2, ds.NewMapDatastore()), bsnet.ControlStream(remoteSupport))
+			bsnet2.Start(r2)
+			t.Cleanup(bsnet2.Stop)
+
+			if err = mn.LinkAll(); err != nil {
+				t.Fatal(err)
+			}
+
+			sender, err := bsnet1.NewMessageSender(ctx, p2.ID(), &bsnet.MessageSenderOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sender.Close()
+
+			// controlStreams returns the number of control streams and of
+			// writes to them
+			controlStreams := func() (streams, writes int) {
+				eh1.lk.Lock()
+				defer eh1.lk.Unlock()
+				for _, s := range eh1.streams {
+					// failed negotiations leave a nil stream
+					if s.Stream != nil && s.Protocol() == bsnet.ProtocolBitswapControl {
+						s.lk.Lock()
+						streams++
+						writes += s.writes
+						s.lk.Unlock()
+					}
+				}
+				return streams, writes
+			}
+
+			blockGenerator := blocksutil.NewBlockGenerator()
+			sendAndCheck := func(msg bsmsg.BitSwapMessage, expControl bool) {
+				t.Helper()
+				_, before := controlStreams()
+				if err := sender.SendMsg(ctx, msg); err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case <-ctx.Done():
+					t.Fatal("did not receive message sent")
+				case <-r2.messageReceived:
+				}
+				if len(r2.lastMessage.Wantlist()) != 1 {
+					t.Fatal("unexpected received wantlist")
+				}
+				if _, after := controlStreams(); (after > before) != expControl {
+					t.Fatalf("expected control stream send to be %v, got %v", expControl, after > before)
+				}
+			}
+
+			wantHave := bsmsg.New(false)
+			wantHave.AddEntry(blockGenerator.Next().Cid(), 1, pb.Message_Wantlist_Have, true)
+			sendAndCheck(wantHave, remoteSupport)
+
+			// Control messages reuse the same stream.
+			wantHave = bsmsg.New(false)
+			wantHave.AddEntry(blockGenerator.Next().Cid(), 1, pb.Message_Wantlist_Have, true)
+			sendAndCheck(wantHave, remoteSupport)
+			if n, _ := controlStreams(); n > 1 {
+				t.Fatalf("expected a single control stream, got %d", n)
+			}
+
+			wantBlock := bsmsg.New(false)
+			wantedBlock := blockGenerator.Next().Cid()
+			wantBlock.AddEntry(wantedBlock, 1, pb.Message_Wantlist_Block, true)
+			sendAndCheck(wantBlock, false)
+
+			// The cancel follows the want-block on the main stream.
+			cancelBlock := bsmsg.New(false)
+			cancelBlock.Cancel(wantedBlock)
+			sendAndCheck(cancelBlock, false)
+		})
+	}
+}
@@ -7,6 +7,7 @@ type NetOpt func(*Settings)
 type Settings struct {
 	ProtocolPrefix     protocol.ID
 	SupportedProtocols []protocol.ID
+	ControlStream      bool
 }
 
 func Prefix(prefix protocol.ID) NetOpt {
@@ -20,3 +21,19 @@ func SupportedProtocols(protos []protocol.ID) NetOpt {
 		settings.SupportedProtocols = protos
 	}
 }
+
+// ControlStream enables sending messages that only carry want-have, cancel,
+// have and dont-have entries over a separate long lived stream of the
+// [ProtocolBitswapControl] protocol per peer, with a single attempt and a
+// short timeout, so that they do not queue behind blocks on the main stream.
+// Delivery is as reliable as on the main stream: the control stream only
+// avoids head-of-line blocking between streams, the underlying connection is
+// shared. Control messages are delivered in order, and cancels of wants sent
+// over the main stream follow them on the main stream. Support is negotiated
+// per peer, messages to peers that do not support the protocol (or when the
+// send over the control stream fails) go through the main stream.
+func ControlStream(enabled bool) NetOpt {
+	return func(settings *Settings) {
+		settings.ControlStream = enabled
+	}
+}
@@ -0,0 +1,427 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bsmsg "github.com/ipfs/boxo/bitswap/message"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	msgio "github.com/libp2p/go-msgio"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+var errPingUnsupported = errors.New("ping is not supported by the transport")
+
+// Transport is the connectivity needed by a BitSwapNetwork created with
+// NewFromTransport, for environments without a libp2p host.
+type Transport interface {
+	// Self returns the identity of the local peer.
+	Self() peer.ID
+
+	// Listen starts accepting connections from remote peers, and passes
+	// them to handle until the transport is closed.
+	Listen(handle func(Conn)) error
+
+	// Dial opens a connection to the given peer.
+	Dial(context.Context, peer.ID) (Conn, error)
+
+	// Close stops accepting connections.
+	Close() error
+}
+
+// Conn is a bidirectional connection to a remote peer, opened by a Transport.
+// Bitswap messages are sent over it in the 1.2.0 wire format.
+type Conn interface {
+	net.Conn
+
+	// RemotePeer returns the identity of the remote peer.
+	RemotePeer() peer.ID
+}
+
+// AddrBook is implemented by transports which dial peers at the addresses
+// returned with the providers found by the content routing.
+type AddrBook interface {
+	// AddAddrs adds addresses to dial the given peer at.
+	AddAddrs(peer.ID, []ma.Multiaddr)
+}
+
+// NewFromTransport returns a BitSwapNetwork over the connections of a
+// Transport. The content routing is optional: without it no providers are
+// found and Provide does nothing.
+//
+// The network does not ping peers and does not manage connections, all the
+// peers are considered to support HAVE and DONT_HAVE messages.
+func NewFromTransport(t Transport, r routing.ContentRouting) BitSwapNetwork {
+	return &transportNetwork{
+		transport: t,
+		routing:   r,
+		conns:     make(map[peer.ID][]*transportConn),
+	}
+}
+
+// transportNetwork implements BitSwapNetwork over a Transport.
+type transportNetwork struct {
+	// NOTE: Stats must be at the top of the heap allocation to ensure 64bit
+	// alignment.
+	stats Stats
+
+	transport     Transport
+	routing       routing.ContentRouting
+	connectEvtMgr *connectEventManager
+
+	// inbound messages from the network are forwarded to the receivers
+	receivers []Receiver
+
+	lk    sync.Mutex
+	conns map[peer.ID][]*transportConn
+}
+
+type transportConn struct {
+	Conn
+	writeLk sync.Mutex
+}
+
+func (bsnet *transportNetwork) Self() peer.ID {
+	return bsnet.transport.Self()
+}
+
+func (bsnet *transportNetwork) Start(r ...Receiver) {
+	bsnet.receivers = r
+	connectionListeners := make([]ConnectionListener, len(r))
+	for i, v := range r {
+		connectionListeners[i] = v
+	}
+	bsnet.connectEvtMgr = newConnectEventManager(connectionListeners...)
+	bsnet.connectEvtMgr.Start()
+
+	if err := bsnet.transport.Listen(func(c Conn) { bsnet.addConn(c) }); err != nil {
+		log.Errorf("bitswap transport failed to listen: %s", err)
+	}
+}
+
+func (bsnet *transportNetwork) Stop() {
+	if err := bsnet.transport.Close(); err != nil {
+		log.Debugf("error closing bitswap transport: %s", err)
+	}
+
+	bsnet.lk.Lock()
+	var conns []*transportConn
+	for _, cs := range bsnet.conns {
+		conns = append(conns, cs...)
+	}
+	bsnet.lk.Unlock()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+
+	bsnet.connectEvtMgr.Stop()
+}
+
+// addConn registers a new connection and starts reading messages from it.
+func (bsnet *transportNetwork) addConn(c Conn) *transportConn {
+	tc := &transportConn{Conn: c}
+	p := c.RemotePeer()
+
+	bsnet.lk.Lock()
+	bsnet.conns[p] = append(bsnet.conns[p], tc)
+	first := len(bsnet.conns[p]) == 1
+	bsnet.lk.Unlock()
+
+	if first {
+		bsnet.connectEvtMgr.Connected(p)
+	}
+	go bsnet.readMessages(tc)
+	return tc
+}
+
+// removeConn closes a connection and forgets it.
+func (bsnet *transportNetwork) removeConn(tc *transportConn) {
+	_ = tc.Close()
+	p := tc.RemotePeer()
+
+	bsnet.lk.Lock()
+	conns := bsnet.conns[p]
+	found := false
+	for i, c := range conns {
+		if c == tc {
+			conns[i] = conns[len(conns)-1]
+			conns = conns[:len(conns)-1]
+			found = true
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(bsnet.conns, p)
+	} else {
+		bsnet.conns[p] = conns
+	}
+	bsnet.lk.Unlock()
+
+	if found && len(conns) == 0 {
+		bsnet.connectEvtMgr.Disconnected(p)
+	}
+}
+
+// readMessages receives the messages of a connection until it is closed.
+func (bsnet *transportNetwork) readMessages(tc *transportConn) {
+	defer bsnet.removeConn(tc)
+
+	p := tc.RemotePeer()
+	reader := msgio.NewVarintReaderSize(tc, network.MessageSizeMax)
+	for {
+		received, err := bsmsg.FromMsgReader(reader)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.ErrClosedPipe) {
+				for _, v := range bsnet.receivers {
+					v.ReceiveError(err)
+				}
+				log.Debugf("bitswap transport read from %s error: %s", p, err)
+			}
+			return
+		}
+
+		log.Debugf("bitswap transport message from %s", p)
+		bsnet.connectEvtMgr.OnMessage(p)
+		atomic.AddUint64(&bsnet.stats.MessagesRecvd, 1)
+		for _, v := range bsnet.receivers {
+			v.ReceiveMessage(context.Background(), p, received)
+		}
+	}
+}
+
+// connTo returns a connection to the peer, dialing it if needed.
+func (bsnet *transportNetwork) connTo(ctx context.Context, p peer.ID) (*transportConn, error) {
+	bsnet.lk.Lock()
+	if conns := bsnet.conns[p]; len(conns) != 0 {
+		bsnet.lk.Unlock()
+		return conns[0], nil
+	}
+	bsnet.lk.Unlock()
+
+	c, err := bsnet.transport.Dial(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return bsnet.addConn(c), nil
+}
+
+func (bsnet *transportNetwork) ConnectTo(ctx context.Context, p peer.ID) error {
+	_, err := bsnet.connTo(ctx, p)
+	return err
+}
+
+func (bsnet *transportNetwork) DisconnectFrom(ctx context.Context, p peer.ID) error {
+	bsnet.lk.Lock()
+	conns := append([]*transportConn(nil), bsnet.conns[p]...)
+	bsnet.lk.Unlock()
+
+	for _, c := range conns {
+		bsnet.removeConn(c)
+	}
+	return nil
+}
+
+// send sends a message over a connection to the peer, which is closed if
+// the message could not be written.
+func (bsnet *transportNetwork) send(ctx context.Context, p peer.ID, msg bsmsg.BitSwapMessage, timeout time.Duration) error {
+	tctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	tc, err := bsnet.connTo(tctx, p)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+
+	tc.writeLk.Lock()
+	err = tc.SetWriteDeadline(deadline)
+	if err == nil {
+		err = msg.ToNetV1(tc)
+	}
+	tc.writeLk.Unlock()
+	if err != nil {
+		bsnet.removeConn(tc)
+		return err
+	}
+
+	atomic.AddUint64(&bsnet.stats.MessagesSent, 1)
+	return nil
+}
+
+func (bsnet *transportNetwork) SendMessage(ctx context.Context, p peer.ID, outgoing bsmsg.BitSwapMessage) error {
+	return bsnet.send(ctx, p, outgoing, sendTimeout(outgoing.Size()))
+}
+
+func (bsnet *transportNetwork) NewMessageSender(ctx context.Context, p peer.ID, opts *MessageSenderOpts) (MessageSender, error) {
+	sender := &transportMessageSender{
+		to:    p,
+		bsnet: bsnet,
+		opts:  setDefaultOpts(opts),
+	}
+
+	err := sender.multiAttempt(ctx, func() error {
+		tctx, cancel := context.WithTimeout(ctx, sender.opts.SendTimeout)
+		defer cancel()
+		return bsnet.ConnectTo(tctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sender, nil
+}
+
+func (bsnet *transportNetwork) ConnectionManager() connmgr.ConnManager {
+	return &connmgr.NullConnMgr{}
+}
+
+func (bsnet *transportNetwork) Stats() Stats {
+	return Stats{
+		MessagesRecvd: atomic.LoadUint64(&bsnet.stats.MessagesRecvd),
+		MessagesSent:  atomic.LoadUint64(&bsnet.stats.MessagesSent),
+	}
+}
+
+// FindProvidersAsync returns a channel of providers for the given key.
+func (bsnet *transportNetwork) FindProvidersAsync(ctx context.Context, k cid.Cid, max int) <-chan peer.ID {
+	out := make(chan peer.ID, max)
+	if bsnet.routing == nil {
+		close(out)
+		return out
+	}
+
+	book, _ := bsnet.transport.(AddrBook)
+	go func() {
+		defer close(out)
+		providers := bsnet.routing.FindProvidersAsync(ctx, k, max)
+		for info := range providers {
+			if info.ID == bsnet.Self() {
+				continue // ignore self as provider
+			}
+			if book != nil && len(info.Addrs) != 0 {
+				book.AddAddrs(info.ID, info.Addrs)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- info.ID:
+			}
+		}
+	}()
+	return out
+}
+
+// Provide provides the key to the network
+func (bsnet *transportNetwork) Provide(ctx context.Context, k cid.Cid) error {
+	if bsnet.routing == nil {
+		return nil
+	}
+	return bsnet.routing.Provide(ctx, k, true)
+}
+
+func (bsnet *transportNetwork) Ping(ctx context.Context, p peer.ID) ping.Result {
+	return ping.Result{Error: errPingUnsupported}
+}
+
+func (bsnet *transportNetwork) Latency(p peer.ID) time.Duration {
+	return 0
+}
+
+func (bsnet *transportNetwork) ConnectedAddrs(p peer.ID) []ma.Multiaddr {
+	bsnet.lk.Lock()
+	defer bsnet.lk.Unlock()
+
+	var addrs []ma.Multiaddr
+	for _, c := range bsnet.conns[p] {
+		if addr, err := manet.FromNetAddr(c.RemoteAddr()); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// ConnQuality returns the quality of the connections to the peer, which are
+// always direct.
+func (bsnet *transportNetwork) ConnQuality(p peer.ID) ConnQuality {
+	bsnet.lk.Lock()
+	defer bsnet.lk.Unlock()
+
+	conns := bsnet.conns[p]
+	if len(conns) == 0 {
+		return ConnQuality{}
+	}
+	return ConnQuality{
+		Connected: true,
+		Transport: conns[0].RemoteAddr().Network(),
+	}
+}
+
+var (
+	_ BitSwapNetwork    = (*transportNetwork)(nil)
+	_ ConnectedAddrs    = (*transportNetwork)(nil)
+	_ ConnectionQuality = (*transportNetwork)(nil)
+)
+
+type transportMessageSender struct {
+	to    peer.ID
+	bsnet *transportNetwork
+	opts  *MessageSenderOpts
+}
+
+// Send a message to the peer, attempting multiple times
+func (s *transportMessageSender) SendMsg(ctx context.Context, msg bsmsg.BitSwapMessage) error {
+	return s.multiAttempt(ctx, func() error {
+		return s.bsnet.send(ctx, s.to, msg, s.opts.SendTimeout)
+	})
+}
+
+// Perform a function with multiple attempts
+func (s *transportMessageSender) multiAttempt(ctx context.Context, fn func() error) error {
+	var err error
+	for i := 0; i < s.opts.MaxRetries; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if i == s.opts.MaxRetries-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.opts.SendErrorBackoff):
+			log.Infof("send message to %s failed but context was not Done: %s", s.to, err)
+		}
+	}
+
+	// Failed too many times so mark the peer as unresponsive
+	s.bsnet.connectEvtMgr.MarkUnresponsive(s.to)
+	return err
+}
+
+func (s *transportMessageSender) Close() error {
+	return nil
+}
+
+func (s *transportMessageSender) Reset() error {
+	return nil
+}
+
+func (s *transportMessageSender) SupportsHave() bool {
+	return true
+}
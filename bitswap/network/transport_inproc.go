@@ -0,0 +1,85 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// InProcessHub connects the transports it creates with in-memory pipes, to
+// run bitswap networks within a single process.
+type InProcessHub struct {
+	lk       sync.Mutex
+	handlers map[peer.ID]func(Conn)
+}
+
+// NewInProcessHub returns an empty InProcessHub.
+func NewInProcessHub() *InProcessHub {
+	return &InProcessHub{handlers: make(map[peer.ID]func(Conn))}
+}
+
+// Transport returns a Transport of the given peer, which can dial the other
+// peers of the hub once they are listening.
+func (h *InProcessHub) Transport(self peer.ID) Transport {
+	return &inProcessTransport{hub: h, self: self}
+}
+
+type inProcessTransport struct {
+	hub  *InProcessHub
+	self peer.ID
+}
+
+func (t *inProcessTransport) Self() peer.ID {
+	return t.self
+}
+
+func (t *inProcessTransport) Listen(handle func(Conn)) error {
+	t.hub.lk.Lock()
+	defer t.hub.lk.Unlock()
+	if _, ok := t.hub.handlers[t.self]; ok {
+		return fmt.Errorf("peer %s is already listening", t.self)
+	}
+	t.hub.handlers[t.self] = handle
+	return nil
+}
+
+func (t *inProcessTransport) Dial(ctx context.Context, p peer.ID) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.hub.lk.Lock()
+	handle, ok := t.hub.handlers[p]
+	t.hub.lk.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("peer %s is not listening", p)
+	}
+	if p == t.self {
+		return nil, errors.New("cannot dial self")
+	}
+
+	local, remote := net.Pipe()
+	go handle(&peerConn{Conn: remote, remote: t.self})
+	return &peerConn{Conn: local, remote: p}, nil
+}
+
+func (t *inProcessTransport) Close() error {
+	t.hub.lk.Lock()
+	delete(t.hub.handlers, t.self)
+	t.hub.lk.Unlock()
+	return nil
+}
+
+// peerConn is a net.Conn to a known remote peer.
+type peerConn struct {
+	net.Conn
+	remote peer.ID
+}
+
+func (c *peerConn) RemotePeer() peer.ID {
+	return c.remote
+}
@@ -0,0 +1,259 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	msgio "github.com/libp2p/go-msgio"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// handshakeTimeout bounds the exchange of peer IDs on new connections.
+var handshakeTimeout = 10 * time.Second
+
+// maxPeerIDSize bounds the size of the peer IDs read during handshakes.
+const maxPeerIDSize = 128
+
+// DialFunc opens a connection to the given address.
+type DialFunc func(context.Context, ma.Multiaddr) (net.Conn, error)
+
+// NetTransport is a Transport over plain net.Conn connections, such as TCP
+// or WebSocket connections.
+//
+// The peers exchange their IDs when a connection is opened. The IDs are not
+// authenticated and the connections are not encrypted: NetTransport is meant
+// for trusted networks, or for connections secured by the listener and the
+// dial function.
+type NetTransport struct {
+	self     peer.ID
+	listener net.Listener
+	dial     DialFunc
+
+	lk     sync.Mutex
+	addrs  map[peer.ID][]ma.Multiaddr
+	closed bool
+}
+
+var (
+	_ Transport = (*NetTransport)(nil)
+	_ AddrBook  = (*NetTransport)(nil)
+)
+
+// NewNetTransport returns a NetTransport accepting connections from the
+// listener, and opening connections with the dial function. The listener is
+// optional, peers cannot dial the transport without it.
+func NewNetTransport(self peer.ID, listener net.Listener, dial DialFunc) *NetTransport {
+	return &NetTransport{
+		self:     self,
+		listener: listener,
+		dial:     dial,
+		addrs:    make(map[peer.ID][]ma.Multiaddr),
+	}
+}
+
+// NewTCPTransport returns a NetTransport over TCP, listening on the given
+// address, e.g. /ip4/0.0.0.0/tcp/4002.
+func NewTCPTransport(self peer.ID, listen ma.Multiaddr) (*NetTransport, error) {
+	network, host, err := tcpDialArgs(listen)
+	if err != nil {
+		return nil, err
+	}
+	l, err := net.Listen(network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	dial := func(ctx context.Context, addr ma.Multiaddr) (net.Conn, error) {
+		network, host, err := tcpDialArgs(addr)
+		if err != nil {
+			return nil, err
+		}
+		return d.DialContext(ctx, network, host)
+	}
+	return NewNetTransport(self, l, dial), nil
+}
+
+// tcpDialArgs returns the network and address to pass to net.Listen or
+// net.Dial for a TCP multiaddr.
+func tcpDialArgs(addr ma.Multiaddr) (string, string, error) {
+	network, host, err := manet.DialArgs(addr)
+	if err != nil {
+		return "", "", err
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return network, host, nil
+	default:
+		return "", "", fmt.Errorf("not a TCP address: %s", addr)
+	}
+}
+
+// Addr returns the address the transport is listening on, or nil.
+func (t *NetTransport) Addr() ma.Multiaddr {
+	if t.listener == nil {
+		return nil
+	}
+	addr, err := manet.FromNetAddr(t.listener.Addr())
+	if err != nil {
+		return nil
+	}
+	return addr
+}
+
+// AddAddrs adds addresses to dial the given peer at.
+func (t *NetTransport) AddAddrs(p peer.ID, addrs []ma.Multiaddr) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+next:
+	for _, addr := range addrs {
+		for _, known := range t.addrs[p] {
+			if known.Equal(addr) {
+				continue next
+			}
+		}
+		t.addrs[p] = append(t.addrs[p], addr)
+	}
+}
+
+func (t *NetTransport) Self() peer.ID {
+	return t.self
+}
+
+func (t *NetTransport) Listen(handle func(Conn)) error {
+	if t.listener == nil {
+		return nil
+	}
+
+	go func() {
+		for {
+			c, err := t.listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Errorf("bitswap transport stopped accepting connections: %s", err)
+				}
+				return
+			}
+			go func() {
+				pc, err := t.accept(c)
+				if err != nil {
+					log.Debugf("bitswap transport handshake with %s failed: %s", c.RemoteAddr(), err)
+					_ = c.Close()
+					return
+				}
+				handle(pc)
+			}()
+		}
+	}()
+	return nil
+}
+
+func (t *NetTransport) Dial(ctx context.Context, p peer.ID) (Conn, error) {
+	t.lk.Lock()
+	addrs := append([]ma.Multiaddr(nil), t.addrs[p]...)
+	closed := t.closed
+	t.lk.Unlock()
+	if closed {
+		return nil, net.ErrClosed
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses for peer %s", p)
+	}
+
+	var errs []error
+	for _, addr := range addrs {
+		c, err := t.dial(ctx, addr)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pc, err := t.connect(ctx, c, p)
+		if err != nil {
+			_ = c.Close()
+			errs = append(errs, err)
+			continue
+		}
+		return pc, nil
+	}
+	return nil, fmt.Errorf("failed to dial %s: %w", p, errors.Join(errs...))
+}
+
+func (t *NetTransport) Close() error {
+	t.lk.Lock()
+	t.closed = true
+	t.lk.Unlock()
+
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Close()
+}
+
+// connect performs the handshake of a dialed connection: the local peer ID
+// is sent, and the remote one is checked to be the expected one.
+func (t *NetTransport) connect(ctx context.Context, c net.Conn, p peer.ID) (Conn, error) {
+	setHandshakeDeadline(ctx, c)
+	if err := writePeerID(c, t.self); err != nil {
+		return nil, err
+	}
+	remote, err := readPeerID(c)
+	if err != nil {
+		return nil, err
+	}
+	if remote != p {
+		return nil, fmt.Errorf("dialed peer %s but %s answered", p, remote)
+	}
+	if err := c.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return &peerConn{Conn: c, remote: remote}, nil
+}
+
+// accept performs the handshake of an accepted connection.
+func (t *NetTransport) accept(c net.Conn) (Conn, error) {
+	setHandshakeDeadline(context.Background(), c)
+	remote, err := readPeerID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePeerID(c, t.self); err != nil {
+		return nil, err
+	}
+	if err := c.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return &peerConn{Conn: c, remote: remote}, nil
+}
+
+func setHandshakeDeadline(ctx context.Context, c net.Conn) {
+	deadline := time.Now().Add(handshakeTimeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		log.Warnf("error setting deadline: %s", err)
+	}
+}
+
+func writePeerID(c net.Conn, p peer.ID) error {
+	return msgio.NewVarintWriter(c).WriteMsg([]byte(p))
+}
+
+func readPeerID(c net.Conn) (peer.ID, error) {
+	// the varint reader does not read past the message, the bitswap messages
+	// that follow are left on the connection
+	r := msgio.NewVarintReaderSize(c, maxPeerIDSize)
+	b, err := r.ReadMsg()
+	if err != nil {
+		return "", err
+	}
+	defer r.ReleaseMsg(b)
+	return peer.IDFromBytes(b)
+}
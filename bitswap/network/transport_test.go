@@ -0,0 +1,190 @@
+package network_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/bitswap"
+	bsmsg "github.com/ipfs/boxo/bitswap/message"
+	pb "github.com/ipfs/boxo/bitswap/message/pb"
+	bsnet "github.com/ipfs/boxo/bitswap/network"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blocksutil "github.com/ipfs/go-ipfs-blocksutil"
+	tnet "github.com/libp2p/go-libp2p-testing/net"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func testTransportNetwork(t *testing.T, t1, t2 bsnet.Transport) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	p1, p2 := t1.Self(), t2.Self()
+	bsnet1 := bsnet.NewFromTransport(t1, nil)
+	bsnet2 := bsnet.NewFromTransport(t2, nil)
+	r1 := newReceiver()
+	r2 := newReceiver()
+	bsnet1.Start(r1)
+	t.Cleanup(bsnet1.Stop)
+	bsnet2.Start(r2)
+	t.Cleanup(bsnet2.Stop)
+
+	if err := bsnet1.ConnectTo(ctx, p2); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*receiver{r1, r2} {
+		select {
+		case <-ctx.Done():
+			t.Fatal("did not connect peer")
+		case connected := <-r.connectionEvent:
+			if !connected {
+				t.Fatal("expected a connection event")
+			}
+		}
+	}
+	if _, ok := r1.peers[p2]; !ok {
+		t.Fatal("did not connect to correct peer")
+	}
+	if _, ok := r2.peers[p1]; !ok {
+		t.Fatal("did not connect to correct peer")
+	}
+
+	blockGenerator := blocksutil.NewBlockGenerator()
+	block := blockGenerator.Next()
+	sent := bsmsg.New(false)
+	sent.AddEntry(block.Cid(), 1, pb.Message_Wantlist_Have, true)
+	if err := bsnet1.SendMessage(ctx, p2, sent); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive message sent")
+	case <-r2.messageReceived:
+	}
+	if r2.lastSender != p1 {
+		t.Fatal("received message from wrong node")
+	}
+	if wants := r2.lastMessage.Wantlist(); len(wants) != 1 || wants[0].Cid != block.Cid() {
+		t.Fatal("sent message wants did not match received message wants")
+	}
+
+	// answer over the connection opened by the other peer
+	sender, err := bsnet2.NewMessageSender(ctx, p1, &bsnet.MessageSenderOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sender.SupportsHave() {
+		t.Fatal("expected peer to support HAVE messages")
+	}
+	reply := bsmsg.New(false)
+	reply.AddBlock(block)
+	if err := sender.SendMsg(ctx, reply); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("did not receive message sent")
+	case <-r1.messageReceived:
+	}
+	if blks := r1.lastMessage.Blocks(); len(blks) != 1 || blks[0].Cid() != block.Cid() {
+		t.Fatal("sent message blocks did not match received message blocks")
+	}
+	if stats := bsnet1.Stats(); stats.MessagesSent != 1 || stats.MessagesRecvd != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	if err := bsnet1.DisconnectFrom(ctx, p2); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*receiver{r1, r2} {
+		select {
+		case <-ctx.Done():
+			t.Fatal("did not disconnect peer")
+		case connected := <-r.connectionEvent:
+			if connected {
+				t.Fatal("expected a disconnection event")
+			}
+		}
+	}
+}
+
+func TestInProcessTransport(t *testing.T) {
+	hub := bsnet.NewInProcessHub()
+	testTransportNetwork(t,
+		hub.Transport(tnet.RandIdentityOrFatal(t).ID()),
+		hub.Transport(tnet.RandIdentityOrFatal(t).ID()),
+	)
+}
+
+func TestTCPTransport(t *testing.T) {
+	listen := ma.StringCast("/ip4/127.0.0.1/tcp/0")
+	t1, err := bsnet.NewTCPTransport(tnet.RandIdentityOrFatal(t).ID(), listen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := bsnet.NewTCPTransport(tnet.RandIdentityOrFatal(t).ID(), listen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1.AddAddrs(t2.Self(), []ma.Multiaddr{t2.Addr()})
+	testTransportNetwork(t, t1, t2)
+}
+
+func TestTCPTransportWrongPeer(t *testing.T) {
+	ctx := context.Background()
+	listen := ma.StringCast("/ip4/127.0.0.1/tcp/0")
+	t1, err := bsnet.NewTCPTransport(tnet.RandIdentityOrFatal(t).ID(), listen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := bsnet.NewTCPTransport(tnet.RandIdentityOrFatal(t).ID(), listen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := t2.Listen(func(c bsnet.Conn) { c.Close() }); err != nil {
+		t.Fatal(err)
+	}
+	defer t2.Close()
+
+	other := tnet.RandIdentityOrFatal(t).ID()
+	t1.AddAddrs(other, []ma.Multiaddr{t2.Addr()})
+	if _, err := t1.Dial(ctx, other); err == nil {
+		t.Fatal("expected dialing the wrong peer to fail")
+	}
+}
+
+func TestBitswapOverInProcessTransport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hub := bsnet.NewInProcessHub()
+	newBitswap := func() (*bitswap.Bitswap, bsnet.BitSwapNetwork, blockstore.Blockstore) {
+		bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+		net := bsnet.NewFromTransport(hub.Transport(tnet.RandIdentityOrFatal(t).ID()), nil)
+		bs := bitswap.New(ctx, net, bstore)
+		t.Cleanup(func() { bs.Close() })
+		return bs, net, bstore
+	}
+	_, net1, bstore1 := newBitswap()
+	bs2, net2, _ := newBitswap()
+
+	blockGenerator := blocksutil.NewBlockGenerator()
+	block := blockGenerator.Next()
+	if err := bstore1.Put(ctx, block); err != nil {
+		t.Fatal(err)
+	}
+
+	// without routing, the peer holding the block must be connected
+	if err := net2.ConnectTo(ctx, net1.Self()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := bs2.GetBlock(ctx, block.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cid() != block.Cid() {
+		t.Fatal("fetched the wrong block")
+	}
+}
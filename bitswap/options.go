@@ -1,11 +1,15 @@
 package bitswap
 
 import (
+	"net/netip"
 	"time"
 
 	"github.com/ipfs/boxo/bitswap/client"
 	"github.com/ipfs/boxo/bitswap/server"
 	"github.com/ipfs/boxo/bitswap/tracer"
+	"github.com/ipfs/boxo/exchange"
+	"github.com/ipfs/boxo/verifcid"
+	ds "github.com/ipfs/go-datastore"
 	delay "github.com/ipfs/go-ipfs-delay"
 )
 
@@ -33,12 +37,22 @@ func MaxQueuedWantlistEntriesPerPeer(count uint) Option {
 	return Option{server.MaxQueuedWantlistEntriesPerPeer(count)}
 }
 
+// WithShedPolicy only affects the server.
+func WithShedPolicy(policy server.ShedPolicy) Option {
+	return Option{server.WithShedPolicy(policy)}
+}
+
 // MaxCidSize only affects the server.
 // If it is 0 no limit is applied.
 func MaxCidSize(n uint) Option {
 	return Option{server.MaxCidSize(n)}
 }
 
+// WithCidPolicy only affects the server.
+func WithCidPolicy(policy *verifcid.Policy) Option {
+	return Option{server.WithCidPolicy(policy)}
+}
+
 func TaskWorkerCount(count int) Option {
 	return Option{server.TaskWorkerCount(count)}
 }
@@ -59,6 +73,16 @@ func WithScoreLedger(scoreLedger server.ScoreLedger) Option {
 	return Option{server.WithScoreLedger(scoreLedger)}
 }
 
+// WithStrategy only affects the server.
+func WithStrategy(strategy server.Strategy) Option {
+	return Option{server.WithStrategy(strategy)}
+}
+
+// WithLedgerDatastore only affects the server.
+func WithLedgerDatastore(d ds.Datastore) Option {
+	return Option{server.WithLedgerDatastore(d)}
+}
+
 func WithTargetMessageSize(tms int) Option {
 	return Option{server.WithTargetMessageSize(tms)}
 }
@@ -79,6 +103,46 @@ func SetSimulateDontHavesOnTimeout(send bool) Option {
 	return Option{client.SetSimulateDontHavesOnTimeout(send)}
 }
 
+// WithLocalPeerPreference only affects the client.
+func WithLocalPeerPreference(subnets ...netip.Prefix) Option {
+	return Option{client.WithLocalPeerPreference(subnets...)}
+}
+
+// WithPeerOracle only affects the client.
+func WithPeerOracle(oracle client.PeerOracle, timeout time.Duration) Option {
+	return Option{client.WithPeerOracle(oracle, timeout)}
+}
+
+// WithDirectPeerPreference only affects the client.
+func WithDirectPeerPreference(prefer bool) Option {
+	return Option{client.WithDirectPeerPreference(prefer)}
+}
+
+// WithMaxBlockSize only affects the client.
+func WithMaxBlockSize(size int) Option {
+	return Option{client.WithMaxBlockSize(size)}
+}
+
+// WithOversizedBlockHandler only affects the client.
+func WithOversizedBlockHandler(handler client.OversizedBlockHandler) Option {
+	return Option{client.WithOversizedBlockHandler(handler)}
+}
+
+// WithExhaustedWantTimeout only affects the client.
+func WithExhaustedWantTimeout(timeout time.Duration) Option {
+	return Option{client.WithExhaustedWantTimeout(timeout)}
+}
+
+// WithFallbackExchange only affects the client.
+func WithFallbackExchange(fallback exchange.Fetcher) Option {
+	return Option{client.WithFallbackExchange(fallback)}
+}
+
+// WithBroadcastLearning only affects the client.
+func WithBroadcastLearning(cfg client.BroadcastLearning) Option {
+	return Option{client.WithBroadcastLearning(cfg)}
+}
+
 func WithTracer(tap tracer.Tracer) Option {
 	// Only trace the server, both receive the same messages anyway
 	return Option{
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	pb "github.com/ipfs/boxo/bitswap/message/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Snapshot is a point in time view of the state of the server, as returned by
+// [Server.Snapshot] and served by [Server.DebugHandler].
+type Snapshot struct {
+	Stat
+	// Peers are the peers with an open wantlist or tasks in the request queue,
+	// sorted by peer ID.
+	Peers []PeerSnapshot
+}
+
+// PeerSnapshot is the state of the server for one peer.
+type PeerSnapshot struct {
+	Peer string
+	// Wantlist is the list of blocks the peer wants from us.
+	Wantlist []WantSnapshot
+	// PendingTasks and ActiveTasks are the depth of the request queue of the
+	// peer: the tasks waiting to be processed, and the tasks being sent.
+	PendingTasks int
+	ActiveTasks  int
+	// Ledger is the score ledger receipt of the peer, nil if the score
+	// ledger does not know the peer.
+	Ledger *Receipt `json:",omitempty"`
+}
+
+// WantSnapshot is an entry of the wantlist of a peer.
+type WantSnapshot struct {
+	Cid      string
+	Priority int32
+	// WantType is "block" or "have".
+	WantType string
+}
+
+// Snapshot returns the current wantlists, request queue depths and ledgers of
+// the peers of the server, for introspection. The state of the peers is read
+// one peer at a time, so the snapshot is not atomic.
+func (bs *Server) Snapshot() Snapshot {
+	s, _ := bs.Stat()
+	snap := Snapshot{Stat: s}
+	snap.Peers = make([]PeerSnapshot, 0, len(s.Peers))
+	for _, p := range bs.engine.Peers() {
+		snap.Peers = append(snap.Peers, bs.peerSnapshot(p))
+	}
+	sort.Slice(snap.Peers, func(i, j int) bool {
+		return snap.Peers[i].Peer < snap.Peers[j].Peer
+	})
+	return snap
+}
+
+func (bs *Server) peerSnapshot(p peer.ID) PeerSnapshot {
+	entries := bs.engine.WantlistForPeer(p)
+	ps := PeerSnapshot{
+		Peer:     p.String(),
+		Wantlist: make([]WantSnapshot, len(entries)),
+		Ledger:   bs.engine.LedgerForPeer(p),
+	}
+	for i, e := range entries {
+		wantType := "block"
+		if e.WantType == pb.Message_Wantlist_Have {
+			wantType = "have"
+		}
+		ps.Wantlist[i] = WantSnapshot{
+			Cid:      e.Cid.String(),
+			Priority: e.Priority,
+			WantType: wantType,
+		}
+	}
+	ps.PendingTasks, ps.ActiveTasks = bs.engine.PeerQueueDepth(p)
+	return ps
+}
+
+// DebugHandler returns an http.Handler serving the [Snapshot] of the server as
+// JSON. The ?peer= query parameter restricts the snapshot to a single peer.
+//
+// The handler exposes the wantlists of the peers of the node, it should only be
+// mounted on a private endpoint.
+func (bs *Server) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		var v any
+		if ps := r.URL.Query().Get("peer"); ps != "" {
+			p, err := peer.Decode(ps)
+			if err != nil {
+				http.Error(w, "invalid peer: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			v = bs.peerSnapshot(p)
+		} else {
+			v = bs.Snapshot()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(v)
+	})
+}
@@ -11,4 +11,14 @@ type (
 	TaskInfo               = decision.TaskInfo
 	ScoreLedger            = decision.ScoreLedger
 	ScorePeerFunc          = decision.ScorePeerFunc
+	ShedPolicy             = decision.ShedPolicy
+	Strategy               = decision.Strategy
+	ReciprocityStrategy    = decision.ReciprocityStrategy
+)
+
+const (
+	ShedNewest = decision.ShedNewest
+	ShedOldest = decision.ShedOldest
+
+	DefaultAltruismPeriod = decision.DefaultAltruismPeriod
 )
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/bits"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,8 +17,10 @@ import (
 	pb "github.com/ipfs/boxo/bitswap/message/pb"
 	bmetrics "github.com/ipfs/boxo/bitswap/metrics"
 	bstore "github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/verifcid"
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipfs/go-metrics-interface"
 	"github.com/ipfs/go-peertaskqueue"
@@ -154,6 +157,12 @@ type Engine struct {
 
 	// an external ledger dealing with peer scores
 	scoreLedger ScoreLedger
+	// where the default score ledger persists the ledgers, if set
+	ledgerDatastore ds.Datastore
+
+	strategy Strategy
+	// weights of the peers in the request queue, set with a strategy
+	weights *peerWeights
 
 	ticker *time.Ticker
 
@@ -189,6 +198,36 @@ type Engine struct {
 
 	maxQueuedWantlistEntriesPerPeer uint
 	maxCidSize                      uint
+	cidPolicy                       *verifcid.Policy
+
+	shedPolicy ShedPolicy
+	// number of wants dropped because a peer's queue was full
+	wantsShed        atomic.Uint64
+	wantsShedCounter metrics.Counter
+}
+
+// ShedPolicy decides which wants are dropped when a peer has more queued
+// wantlist entries than allowed by [WithMaxQueuedWantlistEntriesPerPeer].
+type ShedPolicy int
+
+const (
+	// ShedNewest ignores the new wants which do not fit in the queue.
+	ShedNewest ShedPolicy = iota
+	// ShedOldest drops the oldest queued wants to make room for the new ones,
+	// and answers the ones which asked for it with DONT_HAVE, so that the peer
+	// can look for the blocks elsewhere.
+	ShedOldest
+)
+
+func (p ShedPolicy) String() string {
+	switch p {
+	case ShedNewest:
+		return "newest"
+	case ShedOldest:
+		return "oldest"
+	default:
+		return fmt.Sprintf("ShedPolicy(%d)", int(p))
+	}
 }
 
 // TaskInfo represents the details of a request from a peer.
@@ -240,6 +279,24 @@ func WithScoreLedger(scoreledger ScoreLedger) Option {
 	}
 }
 
+// WithStrategy sets the [Strategy] deciding which peers are served first, and
+// which blocks are sent to them.
+func WithStrategy(strategy Strategy) Option {
+	return func(e *Engine) {
+		e.strategy = strategy
+	}
+}
+
+// WithLedgerDatastore makes the default score ledger persist the bytes
+// exchanged with each peer in d, so that they are kept when peers reconnect
+// and across restarts. It has no effect on a ledger set with
+// [WithScoreLedger].
+func WithLedgerDatastore(d ds.Datastore) Option {
+	return func(e *Engine) {
+		e.ledgerDatastore = d
+	}
+}
+
 // WithBlockstoreWorkerCount sets the number of worker threads used for
 // blockstore operations in the decision engine
 func WithBlockstoreWorkerCount(count int) Option {
@@ -273,13 +330,22 @@ func WithMaxOutstandingBytesPerPeer(count int) Option {
 }
 
 // WithMaxQueuedWantlistEntriesPerPeer limits how much individual entries each peer is allowed to send.
-// If a peer send us more than this we will truncate newest entries.
+// If a peer send us more than this, entries are dropped according to the [ShedPolicy].
 func WithMaxQueuedWantlistEntriesPerPeer(count uint) Option {
 	return func(e *Engine) {
 		e.maxQueuedWantlistEntriesPerPeer = count
 	}
 }
 
+// WithShedPolicy sets which entries are dropped when a peer sends more
+// entries than allowed by [WithMaxQueuedWantlistEntriesPerPeer]. It defaults
+// to [ShedNewest].
+func WithShedPolicy(policy ShedPolicy) Option {
+	return func(e *Engine) {
+		e.shedPolicy = policy
+	}
+}
+
 // WithMaxQueuedWantlistEntriesPerPeer limits how much individual entries each peer is allowed to send.
 // If a peer send us more than this we will truncate newest entries.
 func WithMaxCidSize(n uint) Option {
@@ -288,6 +354,13 @@ func WithMaxCidSize(n uint) Option {
 	}
 }
 
+// WithCidPolicy ignores the wants for CIDs refused by the policy.
+func WithCidPolicy(policy *verifcid.Policy) Option {
+	return func(e *Engine) {
+		e.cidPolicy = policy
+	}
+}
+
 func WithSetSendDontHave(send bool) Option {
 	return func(e *Engine) {
 		e.sendDontHaves = send
@@ -362,6 +435,7 @@ func newEngine(
 		peerLedger:                      newPeerLedger(),
 		pendingGauge:                    bmetrics.PendingEngineGauge(ctx),
 		activeGauge:                     bmetrics.ActiveEngineGauge(ctx),
+		wantsShedCounter:                bmetrics.ShedWantsCounter(ctx),
 		targetMessageSize:               defaultTargetMessageSize,
 		tagQueued:                       fmt.Sprintf(tagFormat, "queued", uuid.New().String()),
 		tagUseful:                       fmt.Sprintf(tagFormat, "useful", uuid.New().String()),
@@ -373,6 +447,10 @@ func newEngine(
 		opt(e)
 	}
 
+	if dsl, ok := e.scoreLedger.(*DefaultScoreLedger); ok && e.ledgerDatastore != nil {
+		dsl.store = e.ledgerDatastore
+	}
+
 	e.bsm = newBlockstoreManager(bs, e.bstoreWorkerCount, bmetrics.PendingBlocksGauge(ctx), bmetrics.ActiveBlocksGauge(ctx))
 
 	// default peer task queue options
@@ -384,11 +462,19 @@ func newEngine(
 		peertaskqueue.MaxOutstandingWorkPerPeer(e.maxOutstandingBytesPerPeer),
 	}
 
+	var peerComparator peertracker.PeerComparator
 	if e.taskComparator != nil {
 		queueTaskComparator := wrapTaskComparator(e.taskComparator)
-		peerTaskQueueOpts = append(peerTaskQueueOpts, peertaskqueue.PeerComparator(peertracker.TaskPriorityPeerComparator(queueTaskComparator)))
+		peerComparator = peertracker.TaskPriorityPeerComparator(queueTaskComparator)
 		peerTaskQueueOpts = append(peerTaskQueueOpts, peertaskqueue.TaskComparator(queueTaskComparator))
 	}
+	if e.strategy != nil {
+		e.weights = newPeerWeights()
+		peerComparator = strategyPeerComparator(e.weights, peerComparator)
+	}
+	if peerComparator != nil {
+		peerTaskQueueOpts = append(peerTaskQueueOpts, peertaskqueue.PeerComparator(peerComparator))
+	}
 
 	e.peerRequestQueue = peertaskqueue.New(peerTaskQueueOpts...)
 
@@ -460,10 +546,26 @@ func (e *Engine) StartWorkers(ctx context.Context, px process.Process) {
 
 func (e *Engine) onPeerAdded(p peer.ID) {
 	e.peerTagger.TagPeer(p, e.tagQueued, queuedTagWeight)
+	if e.weights != nil {
+		// the queue moves the peer to its place right after adding it
+		e.weights.set(p, e.strategy.PeerWeight(p, e.receipt(p)))
+	}
 }
 
 func (e *Engine) onPeerRemoved(p peer.ID) {
 	e.peerTagger.UntagPeer(p, e.tagQueued)
+	if e.weights != nil {
+		e.weights.remove(p)
+	}
+}
+
+// receipt returns the receipt of the score ledger for the peer, or an empty
+// one if the ledger has none.
+func (e *Engine) receipt(p peer.ID) *Receipt {
+	if r := e.scoreLedger.GetReceipt(p); r != nil {
+		return r
+	}
+	return &Receipt{Peer: p.String()}
 }
 
 // WantlistForPeer returns the list of keys that the given peer has asked for
@@ -546,6 +648,11 @@ func (e *Engine) nextEnvelope(ctx context.Context) (*Envelope, error) {
 		// Amount of data in the request queue still waiting to be popped
 		msg.SetPendingBytes(int32(pendingBytes))
 
+		var receipt *Receipt
+		if e.strategy != nil {
+			receipt = e.receipt(p)
+		}
+
 		// Split out want-blocks, want-haves and DONT_HAVEs
 		blockCids := make([]cid.Cid, 0, len(nextTasks))
 		blockTasks := make(map[cid.Cid]*taskData, len(nextTasks))
@@ -553,7 +660,12 @@ func (e *Engine) nextEnvelope(ctx context.Context) (*Envelope, error) {
 			c := t.Topic.(cid.Cid)
 			td := t.Data.(*taskData)
 			if td.HaveBlock {
-				if td.IsWantBlock {
+				if td.IsWantBlock && e.strategy != nil && !e.strategy.SendBlock(p, receipt, td.BlockSize) {
+					// The strategy holds the block back
+					if td.SendDontHave {
+						msg.AddDontHave(c)
+					}
+				} else if td.IsWantBlock {
 					blockCids = append(blockCids, c)
 					blockTasks[c] = td
 				} else {
@@ -674,15 +786,26 @@ func (e *Engine) MessageReceived(ctx context.Context, p peer.ID, m bsmsg.BitSwap
 		e.peerLedger.ClearPeerWantlist(p)
 	}
 
+	var shed []entryForCid
 	s := uint(e.peerLedger.WantlistSizeForPeer(p))
 	if wouldBe := s + uint(len(wants)); wouldBe > e.maxQueuedWantlistEntriesPerPeer {
-		log.Debugw("wantlist overflow", "local", e.self, "remote", p, "would be", wouldBe)
-		// truncate wantlist to avoid overflow
-		available, o := bits.Sub(e.maxQueuedWantlistEntriesPerPeer, s, 0)
-		if o != 0 {
-			available = 0
+		log.Debugw("wantlist overflow", "local", e.self, "remote", p, "would be", wouldBe, "policy", e.shedPolicy)
+		if uint(len(wants)) > e.maxQueuedWantlistEntriesPerPeer {
+			e.recordShed(len(wants) - int(e.maxQueuedWantlistEntriesPerPeer))
+			wants = wants[:e.maxQueuedWantlistEntriesPerPeer]
+		}
+
+		// with ShedOldest, the oldest wants are dropped once the new ones
+		// are merged into the wantlist
+		if e.shedPolicy != ShedOldest {
+			// truncate wantlist to avoid overflow
+			available, o := bits.Sub(e.maxQueuedWantlistEntriesPerPeer, s, 0)
+			if o != 0 {
+				available = 0
+			}
+			e.recordShed(len(wants) - int(available))
+			wants = wants[:available]
 		}
-		wants = wants[:available]
 	}
 
 	filteredWants := wants[:0] // shift inplace
@@ -698,10 +821,30 @@ func (e *Engine) MessageReceived(ctx context.Context, p peer.ID, m bsmsg.BitSwap
 			// Ignore requests about CIDs that big.
 			continue
 		}
+		if e.cidPolicy != nil && e.cidPolicy.Validate(entry.Cid) != nil {
+			// Ignore requests about CIDs we don't accept.
+			continue
+		}
 
-		e.peerLedger.Wants(p, entry.Entry)
+		e.peerLedger.Wants(p, entry.Entry, entry.SendDontHave)
 		filteredWants = append(filteredWants, entry)
 	}
+	if e.shedPolicy == ShedOldest {
+		// make room for the new wants by dropping the oldest ones, the wants
+		// of this message are the newest
+		size := uint(e.peerLedger.WantlistSizeForPeer(p))
+		if size > e.maxQueuedWantlistEntriesPerPeer {
+			oldest := e.peerLedger.Oldest(p, int(size-e.maxQueuedWantlistEntriesPerPeer))
+			for _, entry := range oldest {
+				e.peerLedger.CancelWant(p, entry.Cid)
+				e.peerRequestQueue.Remove(entry.Cid, p)
+				if entry.SendDontHave {
+					shed = append(shed, entry)
+				}
+			}
+			e.recordShed(len(oldest))
+		}
+	}
 	clear := wants[len(filteredWants):]
 	for i := range clear {
 		clear[i] = bsmsg.Entry{} // early GC
@@ -754,6 +897,15 @@ func (e *Engine) MessageReceived(ctx context.Context, p peer.ID, m bsmsg.BitSwap
 		}
 	}
 
+	// Tell the peer to look elsewhere for the wants that were shed, if it
+	// asked for DONT_HAVEs
+	for _, entry := range shed {
+		sendDontHave(bsmsg.Entry{
+			Entry:        wl.Entry{Cid: entry.Cid, Priority: entry.Priority, WantType: entry.WantType},
+			SendDontHave: entry.SendDontHave,
+		})
+	}
+
 	// Deny access to blocks
 	for _, entry := range denials {
 		log.Debugw("Bitswap engine: block denied access", "local", e.self, "from", p, "cid", entry.Cid, "sendDontHave", entry.SendDontHave)
@@ -807,6 +959,30 @@ func (e *Engine) MessageReceived(ctx context.Context, p peer.ID, m bsmsg.BitSwap
 	return false
 }
 
+func (e *Engine) recordShed(n int) {
+	if n <= 0 {
+		return
+	}
+	e.wantsShed.Add(uint64(n))
+	e.wantsShedCounter.Add(float64(n))
+}
+
+// PeerQueueDepth returns the number of pending and active tasks of the peer in
+// the request queue.
+func (e *Engine) PeerQueueDepth(p peer.ID) (pending, active int) {
+	topics := e.peerRequestQueue.PeerTopics(p)
+	if topics == nil {
+		return 0, 0
+	}
+	return len(topics.Pending), len(topics.Active)
+}
+
+// WantsShed returns the number of wants that were dropped because the queue of
+// a peer was full.
+func (e *Engine) WantsShed() uint64 {
+	return e.wantsShed.Load()
+}
+
 // Split the want-have / want-block entries from the cancel entries
 func (e *Engine) splitWantsCancels(es []bsmsg.Entry) ([]bsmsg.Entry, []bsmsg.Entry) {
 	wants := make([]bsmsg.Entry, 0, len(es))
@@ -855,6 +1031,26 @@ func (e *Engine) ReceivedBlocks(from peer.ID, blks []blocks.Block) {
 	}
 }
 
+// NotifyDeletedBlocks is called when blocks are deleted locally. The responses
+// queued for the peers wanting them are dropped, so that they are not told the
+// blocks are available: the blocks are looked up again if the peers send their
+// wants again.
+func (e *Engine) NotifyDeletedBlocks(ks []cid.Cid) {
+	if len(ks) == 0 {
+		return
+	}
+
+	for _, k := range ks {
+		e.lock.RLock()
+		peers := e.peerLedger.Peers(k)
+		e.lock.RUnlock()
+
+		for _, entry := range peers {
+			e.peerRequestQueue.Remove(k, entry.Peer)
+		}
+	}
+}
+
 // NotifyNewBlocks is called when new blocks becomes available locally, and in particular when the caller of bitswap
 // decide to store those blocks and make them available on the network.
 func (e *Engine) NotifyNewBlocks(blks []blocks.Block) {
@@ -947,6 +1143,9 @@ func (e *Engine) PeerConnected(p peer.ID) {
 // PeerDisconnected is called when a peer disconnects.
 func (e *Engine) PeerDisconnected(p peer.ID) {
 	e.peerRequestQueue.Clear(p)
+	if e.weights != nil {
+		e.weights.remove(p)
+	}
 
 	e.lock.Lock()
 	defer e.lock.Unlock()
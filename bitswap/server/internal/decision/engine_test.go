@@ -18,6 +18,7 @@ import (
 	message "github.com/ipfs/boxo/bitswap/message"
 	pb "github.com/ipfs/boxo/bitswap/message/pb"
 	blockstore "github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/verifcid"
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	ds "github.com/ipfs/go-datastore"
@@ -1662,6 +1663,116 @@ func TestWantlistGrowsToLimit(t *testing.T) {
 	}
 }
 
+func TestWantlistCidPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	warsaw := newTestEngine(ctx, "warsaw", WithCidPolicy(&verifcid.Policy{DisallowCIDv0: true}))
+	riga := newTestEngine(ctx, "riga")
+
+	v0 := blocks.NewBlock([]byte("v0")).Cid()
+	v1 := cid.NewCidV1(cid.Raw, v0.Hash())
+	m := message.New(false)
+	m.AddEntry(v0, 0, pb.Message_Wantlist_Block, true)
+	m.AddEntry(v1, 0, pb.Message_Wantlist_Block, true)
+	warsaw.Engine.MessageReceived(ctx, riga.Peer, m)
+
+	wl := warsaw.Engine.WantlistForPeer(riga.Peer)
+	if len(wl) != 1 || !wl[0].Cid.Equals(v1) {
+		t.Fatal("expected the CIDv0 want to be ignored", wl)
+	}
+}
+
+func TestWantlistShedOldest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const limit = 4
+	warsaw := newTestEngine(ctx, "warsaw", WithMaxQueuedWantlistEntriesPerPeer(limit), WithShedPolicy(ShedOldest))
+	riga := newTestEngine(ctx, "riga")
+
+	// Send one want per message, so that their age is known. Only the first
+	// want asks for a DONT_HAVE.
+	blks := testutil.GenerateBlocksOfSize(limit+2, 8)
+	for i, b := range blks {
+		m := message.New(false)
+		m.AddEntry(b.Cid(), 0, pb.Message_Wantlist_Have, i == 0)
+		warsaw.Engine.MessageReceived(ctx, riga.Peer, m)
+	}
+
+	wl := warsaw.Engine.WantlistForPeer(riga.Peer)
+	if len(wl) != limit {
+		t.Fatal("wantlist does not match limit", len(wl))
+	}
+	wanted := cid.NewSet()
+	for _, e := range wl {
+		wanted.Add(e.Cid)
+	}
+	for i, b := range blks {
+		if shed := i < 2; wanted.Has(b.Cid()) == shed {
+			t.Fatalf("expected want %d to be shed: %t", i, shed)
+		}
+	}
+	if shed := warsaw.Engine.WantsShed(); shed != 2 {
+		t.Fatal("expected 2 shed wants, got", shed)
+	}
+
+	// The shed wants which asked for it are answered with DONT_HAVE
+	_, env := getNextEnvelope(warsaw.Engine, nil, 100*time.Millisecond)
+	if env == nil {
+		t.Fatal("expected envelope")
+	}
+	if env.Peer != riga.Peer {
+		t.Fatal("expected message to peer")
+	}
+	sentDontHaves := env.Message.BlockPresences()
+	if len(sentDontHaves) != 1 {
+		t.Fatal("expected 1 DONT_HAVE, got", len(sentDontHaves))
+	}
+	if bp := sentDontHaves[0]; bp.Type != pb.Message_DontHave || !bp.Cid.Equals(blks[0].Cid()) {
+		t.Fatal("expected DONT_HAVE for the first shed want")
+	}
+
+	// Wanting again the oldest want keeps it, and the cap is still enforced.
+	m := message.New(false)
+	m.AddEntry(blks[2].Cid(), 0, pb.Message_Wantlist_Have, false)
+	m.AddEntry(testutil.GenerateBlocksOfSize(1, 8)[0].Cid(), 0, pb.Message_Wantlist_Have, false)
+	warsaw.Engine.MessageReceived(ctx, riga.Peer, m)
+	wl = warsaw.Engine.WantlistForPeer(riga.Peer)
+	if len(wl) != limit {
+		t.Fatal("wantlist does not match limit", len(wl))
+	}
+	wanted = cid.NewSet()
+	for _, e := range wl {
+		wanted.Add(e.Cid)
+	}
+	if !wanted.Has(blks[2].Cid()) || wanted.Has(blks[3].Cid()) {
+		t.Fatal("expected the oldest want not wanted again to be shed")
+	}
+}
+
+func TestWantlistShedNewest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const limit = 4
+	warsaw := newTestEngine(ctx, "warsaw", WithMaxQueuedWantlistEntriesPerPeer(limit))
+	riga := newTestEngine(ctx, "riga")
+
+	m := message.New(false)
+	for _, b := range testutil.GenerateBlocksOfSize(limit+3, 8) {
+		m.AddEntry(b.Cid(), 0, pb.Message_Wantlist_Block, false)
+	}
+	warsaw.Engine.MessageReceived(ctx, riga.Peer, m)
+
+	if wl := warsaw.Engine.WantlistForPeer(riga.Peer); len(wl) != limit {
+		t.Fatal("wantlist does not match limit", len(wl))
+	}
+	if shed := warsaw.Engine.WantsShed(); shed != 3 {
+		t.Fatal("expected 3 shed wants, got", shed)
+	}
+}
+
 func TestIgnoresCidsAboveLimit(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -1733,3 +1844,39 @@ func TestKillConnectionForInlineCid(t *testing.T) {
 		t.Fatal("connection was not killed when receiving inline in cancel")
 	}
 }
+
+func TestNotifyDeletedBlocks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	blks := testutil.GenerateBlocksOfSize(2, 1024)
+	if err := bs.PutMany(ctx, blks); err != nil {
+		t.Fatal(err)
+	}
+	e := newEngineForTesting(ctx, bs, &fakePeerTagger{}, "localhost", 0, WithScoreLedger(NewTestScoreLedger(shortTerm, nil, clock.New())))
+	e.StartWorkers(ctx, process.WithTeardown(func() error { return nil }))
+
+	partner := libp2ptest.RandPeerIDFatal(t)
+	msg := message.New(false)
+	for _, blk := range blks {
+		msg.AddEntry(blk.Cid(), 1, pb.Message_Wantlist_Have, false)
+	}
+	e.MessageReceived(ctx, partner, msg)
+
+	// the HAVE queued for the deleted block is dropped
+	deleted := blks[0].Cid()
+	if err := bs.DeleteBlock(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+	e.NotifyDeletedBlocks([]cid.Cid{deleted})
+
+	_, env := getNextEnvelope(e, nil, 100*time.Millisecond)
+	if env == nil {
+		t.Fatal("expected an envelope")
+	}
+	presences := env.Message.BlockPresences()
+	if len(presences) != 1 || presences[0].Cid != blks[1].Cid() || presences[0].Type != pb.Message_Have {
+		t.Fatalf("expected only a HAVE for the block still stored, got %v", presences)
+	}
+}
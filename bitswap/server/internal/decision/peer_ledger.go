@@ -1,6 +1,8 @@
 package decision
 
 import (
+	"sort"
+
 	wl "github.com/ipfs/boxo/bitswap/client/wantlist"
 	pb "github.com/ipfs/boxo/bitswap/message/pb"
 
@@ -12,6 +14,9 @@ type peerLedger struct {
 	// thoses two maps are inversions of each other
 	peers map[peer.ID]map[cid.Cid]entry
 	cids  map[cid.Cid]map[peer.ID]entry
+
+	// seq orders the wants by arrival
+	seq uint64
 }
 
 func newPeerLedger() *peerLedger {
@@ -21,20 +26,23 @@ func newPeerLedger() *peerLedger {
 	}
 }
 
-func (l *peerLedger) Wants(p peer.ID, e wl.Entry) {
+// Wants records the want of the peer, sendDontHave tells whether the peer
+// asked for a DONT_HAVE if we do not have the block.
+func (l *peerLedger) Wants(p peer.ID, e wl.Entry, sendDontHave bool) {
 	cids, ok := l.peers[p]
 	if !ok {
 		cids = make(map[cid.Cid]entry)
 		l.peers[p] = cids
 	}
-	cids[e.Cid] = entry{e.Priority, e.WantType}
+	l.seq++
+	cids[e.Cid] = entry{e.Priority, e.WantType, sendDontHave, l.seq}
 
 	m, ok := l.cids[e.Cid]
 	if !ok {
 		m = make(map[peer.ID]entry)
 		l.cids[e.Cid] = m
 	}
-	m[p] = entry{e.Priority, e.WantType}
+	m[p] = entry{e.Priority, e.WantType, sendDontHave, l.seq}
 }
 
 // CancelWant returns true if the cid was present in the wantlist.
@@ -91,8 +99,30 @@ type entryForPeer struct {
 }
 
 type entry struct {
-	Priority int32
-	WantType pb.Message_Wantlist_WantType
+	Priority     int32
+	WantType     pb.Message_Wantlist_WantType
+	SendDontHave bool
+	seq          uint64
+}
+
+type entryForCid struct {
+	Cid cid.Cid
+	entry
+}
+
+// Oldest returns the n wants of the peer that were received first.
+func (l *peerLedger) Oldest(p peer.ID, n int) []entryForCid {
+	wants := make([]entryForCid, 0, len(l.peers[p]))
+	for c, e := range l.peers[p] {
+		wants = append(wants, entryForCid{c, e})
+	}
+	sort.Slice(wants, func(i, j int) bool {
+		return wants[i].seq < wants[j].seq
+	})
+	if n < len(wants) {
+		wants = wants[:n]
+	}
+	return wants
 }
 
 func (l *peerLedger) Peers(k cid.Cid) []entryForPeer {
@@ -1,10 +1,14 @@
 package decision
 
 import (
+	"context"
+	"encoding/binary"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/benbjohnson/clock"
+	ds "github.com/ipfs/go-datastore"
 	peer "github.com/libp2p/go-libp2p/core/peer"
 )
 
@@ -89,6 +93,36 @@ func (l *scoreledger) AddToReceivedBytes(n int) {
 	l.bytesRecv += uint64(n)
 }
 
+// marshal encodes the totals of the ledger, to persist them.
+func (l *scoreledger) marshal() []byte {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	buf := make([]byte, 0, 3*binary.MaxVarintLen64)
+	buf = binary.AppendUvarint(buf, l.bytesSent)
+	buf = binary.AppendUvarint(buf, l.bytesRecv)
+	buf = binary.AppendUvarint(buf, l.exchangeCount)
+	return buf
+}
+
+// unmarshal restores the totals encoded by marshal.
+func (l *scoreledger) unmarshal(data []byte) error {
+	var totals [3]uint64
+	for i := range totals {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("invalid ledger record")
+		}
+		totals[i] = v
+		data = data[n:]
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.bytesSent, l.bytesRecv, l.exchangeCount = totals[0], totals[1], totals[2]
+	return nil
+}
+
 // Returns the Receipt for this ledger record.
 func (l *scoreledger) Receipt() *Receipt {
 	l.lock.RLock()
@@ -118,6 +152,8 @@ type DefaultScoreLedger struct {
 	// used by the tests to detect when a sample is taken
 	sampleCh chan struct{}
 	clock    clock.Clock
+	// persists the ledgers of the peers, if set
+	store ds.Datastore
 }
 
 // scoreWorker keeps track of how "useful" our peers are, updating scores in the
@@ -254,18 +290,53 @@ func (dsl *DefaultScoreLedger) findOrCreate(p peer.ID) *scoreledger {
 		return l
 	}
 
-	// There's no ledger, so take a write lock, then check again and
-	// create the ledger if necessary.
+	// There's no ledger, so load it without the lock, the datastore may be
+	// slow, then take a write lock and check again before inserting it.
+	loaded := dsl.load(p)
 	dsl.lock.Lock()
 	defer dsl.lock.Unlock()
 	l, ok := dsl.ledgerMap[p]
 	if !ok {
-		l = newScoreLedger(p, dsl.clock)
+		l = loaded
 		dsl.ledgerMap[p] = l
 	}
 	return l
 }
 
+// load returns a new scoreledger, with the totals persisted for the peer if
+// any.
+func (dsl *DefaultScoreLedger) load(p peer.ID) *scoreledger {
+	l := newScoreLedger(p, dsl.clock)
+	if dsl.store == nil {
+		return l
+	}
+	data, err := dsl.store.Get(context.Background(), ledgerKey(p))
+	if err != nil {
+		if !errors.Is(err, ds.ErrNotFound) {
+			log.Errorw("failed to load ledger", "peer", p, "error", err)
+		}
+		return l
+	}
+	if err := l.unmarshal(data); err != nil {
+		log.Errorw("failed to load ledger", "peer", p, "error", err)
+	}
+	return l
+}
+
+// save persists the totals of the ledger, if a datastore is set.
+func (dsl *DefaultScoreLedger) save(l *scoreledger) {
+	if dsl.store == nil {
+		return
+	}
+	if err := dsl.store.Put(context.Background(), ledgerKey(l.partner), l.marshal()); err != nil {
+		log.Errorw("failed to persist ledger", "peer", l.partner, "error", err)
+	}
+}
+
+func ledgerKey(p peer.ID) ds.Key {
+	return ds.NewKey(p.String())
+}
+
 // GetReceipt returns aggregated data communication with a given peer.
 func (dsl *DefaultScoreLedger) GetReceipt(p peer.ID) *Receipt {
 	l := dsl.find(p)
@@ -289,9 +360,20 @@ func (dsl *DefaultScoreLedger) Start(scorePeer ScorePeerFunc) {
 	go dsl.scoreWorker()
 }
 
-// Stops the sampling process.
+// Stops the sampling process, and persists the ledgers.
 func (dsl *DefaultScoreLedger) Stop() {
 	close(dsl.closing)
+
+	dsl.lock.RLock()
+	ledgers := make([]*scoreledger, 0, len(dsl.ledgerMap))
+	for _, l := range dsl.ledgerMap {
+		ledgers = append(ledgers, l)
+	}
+	dsl.lock.RUnlock()
+
+	for _, l := range ledgers {
+		dsl.save(l)
+	}
 }
 
 // Initializes the score ledger.
@@ -316,20 +398,21 @@ func (dsl *DefaultScoreLedger) AddToReceivedBytes(p peer.ID, n int) {
 // PeerConnected should be called when a new peer connects, meaning
 // we should open accounting.
 func (dsl *DefaultScoreLedger) PeerConnected(p peer.ID) {
-	dsl.lock.Lock()
-	defer dsl.lock.Unlock()
-	_, ok := dsl.ledgerMap[p]
-	if !ok {
-		dsl.ledgerMap[p] = newScoreLedger(p, dsl.clock)
-	}
+	dsl.findOrCreate(p)
 }
 
 // PeerDisconnected should be called when a peer disconnects to
 // clean up the accounting.
 func (dsl *DefaultScoreLedger) PeerDisconnected(p peer.ID) {
 	dsl.lock.Lock()
-	defer dsl.lock.Unlock()
+	l, ok := dsl.ledgerMap[p]
 	delete(dsl.ledgerMap, p)
+	dsl.lock.Unlock()
+
+	// saved without the lock, the datastore may be slow
+	if ok {
+		dsl.save(l)
+	}
 }
 
 // Creates a new instance of the default score ledger.
@@ -0,0 +1,193 @@
+package decision
+
+import (
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ipfs/go-peertaskqueue/peertracker"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DefaultAltruismPeriod is the default period over which the
+// [ReciprocityStrategy.AltruismCap] applies.
+const DefaultAltruismPeriod = time.Hour
+
+// Strategy decides which peers are served first, and which blocks are sent to
+// them, from the ledger of the exchanges with each peer. It lets the exchange
+// strategy be changed without changing the engine, see [WithStrategy].
+//
+// The methods of a Strategy are called concurrently, and often: they must not
+// block.
+type Strategy interface {
+	// PeerWeight returns the weight of a peer with pending requests. The
+	// requests of peers with a higher weight are served first, peers with the
+	// same weight are served in the default order.
+	//
+	// The weight is sampled when the peer enters the request queue, and kept
+	// until the peer has no more pending requests.
+	PeerWeight(p peer.ID, r *Receipt) float64
+
+	// SendBlock returns true if a block of the given size can be sent to the
+	// peer now. Blocks which are held back are dropped from the request
+	// queue, and answered with a DONT_HAVE if the peer asked for one, so
+	// that it can look for them elsewhere or ask again later. Strategies
+	// which account for the blocks sent must count the block when returning
+	// true.
+	SendBlock(p peer.ID, r *Receipt, size int) bool
+}
+
+// ReciprocityStrategy is a [Strategy] which favors the peers which send blocks
+// in return for the blocks they get, and caps how much is given away to the
+// others. The zero value serves every peer the same, like the engine without
+// strategy.
+type ReciprocityStrategy struct {
+	// FreeBlockSize is the size up to which blocks are always sent, and are
+	// not counted against the AltruismCap.
+	FreeBlockSize int
+
+	// TitForTatWeight is the weight of the share of the data exchanged with
+	// a peer which the peer sent, in its priority. Peers which only download
+	// have the lowest priority, and peers which only upload the highest.
+	TitForTatWeight float64
+
+	// AltruismCap is the number of bytes sent to a peer per AltruismPeriod,
+	// beyond the bytes the peer sent in total. Once it is reached, the
+	// requests for blocks larger than FreeBlockSize are refused, with a
+	// DONT_HAVE if the peer asked for one, until the peer sends data or the
+	// period ends. The peer has to ask for the blocks again. 0 disables the
+	// cap.
+	AltruismCap uint64
+
+	// AltruismPeriod defaults to [DefaultAltruismPeriod].
+	AltruismPeriod time.Duration
+
+	lk        sync.Mutex
+	clock     clock.Clock
+	lastSweep time.Time
+	altruism  map[peer.ID]*altruismWindow
+}
+
+var _ Strategy = (*ReciprocityStrategy)(nil)
+
+// altruismWindow counts the bytes given away to a peer since start.
+type altruismWindow struct {
+	start time.Time
+	sent  uint64
+}
+
+func (s *ReciprocityStrategy) PeerWeight(p peer.ID, r *Receipt) float64 {
+	if s.TitForTatWeight == 0 || r == nil {
+		return 0
+	}
+	return s.TitForTatWeight * float64(r.Recv) / float64(r.Recv+r.Sent+1)
+}
+
+func (s *ReciprocityStrategy) SendBlock(p peer.ID, r *Receipt, size int) bool {
+	if s.AltruismCap == 0 || size <= s.FreeBlockSize {
+		return true
+	}
+	if r == nil {
+		r = &Receipt{}
+	}
+	// the peer sent at least as much as it gets
+	if r.Recv >= r.Sent+uint64(size) {
+		return true
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	now := s.now()
+	period := s.period()
+	if s.altruism == nil {
+		s.altruism = make(map[peer.ID]*altruismWindow)
+	}
+	// forget the peers which were not served in the last period
+	if now.Sub(s.lastSweep) >= period {
+		for id, w := range s.altruism {
+			if now.Sub(w.start) >= period {
+				delete(s.altruism, id)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	w, ok := s.altruism[p]
+	if !ok || now.Sub(w.start) >= period {
+		w = &altruismWindow{start: now}
+		s.altruism[p] = w
+	}
+	if w.sent+uint64(size) > s.AltruismCap {
+		return false
+	}
+	w.sent += uint64(size)
+	return true
+}
+
+func (s *ReciprocityStrategy) period() time.Duration {
+	if s.AltruismPeriod <= 0 {
+		return DefaultAltruismPeriod
+	}
+	return s.AltruismPeriod
+}
+
+func (s *ReciprocityStrategy) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// peerWeights holds the weights of the peers in the request queue, sampled
+// when they enter it. The weight of a peer must not change while it is in the
+// queue, or the order of the queue breaks.
+type peerWeights struct {
+	lk      sync.RWMutex
+	weights map[peer.ID]float64
+}
+
+func newPeerWeights() *peerWeights {
+	return &peerWeights{weights: make(map[peer.ID]float64)}
+}
+
+func (w *peerWeights) get(p peer.ID) float64 {
+	w.lk.RLock()
+	defer w.lk.RUnlock()
+	return w.weights[p]
+}
+
+func (w *peerWeights) set(p peer.ID, weight float64) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	w.weights[p] = weight
+}
+
+func (w *peerWeights) remove(p peer.ID) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	delete(w.weights, p)
+}
+
+// strategyPeerComparator orders the peers with pending requests by their
+// weight, and falls back to base for the peers with the same weight.
+func strategyPeerComparator(weights *peerWeights, base peertracker.PeerComparator) peertracker.PeerComparator {
+	if base == nil {
+		base = peertracker.DefaultPeerComparator
+	}
+	return func(pa, pb *peertracker.PeerTracker) bool {
+		// having no pending tasks means lowest priority
+		if pa.Stats().NumPending == 0 {
+			return false
+		}
+		if pb.Stats().NumPending == 0 {
+			return true
+		}
+
+		wa, wb := weights.get(pa.Target()), weights.get(pb.Target())
+		if wa != wb {
+			return wa > wb
+		}
+		return base(pa, pb)
+	}
+}
@@ -0,0 +1,229 @@
+package decision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ipfs/boxo/bitswap/internal/testutil"
+	message "github.com/ipfs/boxo/bitswap/message"
+	pb "github.com/ipfs/boxo/bitswap/message/pb"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	blocks "github.com/ipfs/go-block-format"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/ipfs/go-peertaskqueue"
+	"github.com/ipfs/go-peertaskqueue/peertask"
+	process "github.com/jbenet/goprocess"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	libp2ptest "github.com/libp2p/go-libp2p/core/test"
+)
+
+func TestReciprocityStrategy(t *testing.T) {
+	clk := clock.NewMock()
+	s := &ReciprocityStrategy{
+		FreeBlockSize: 100,
+		AltruismCap:   1000,
+		clock:         clk,
+	}
+	p := libp2ptest.RandPeerIDFatal(t)
+	leecher := &Receipt{Sent: 5000}
+
+	for i := 0; i < 10; i++ {
+		if !s.SendBlock(p, leecher, 100) {
+			t.Fatal("expected free blocks to be sent")
+		}
+	}
+	if !s.SendBlock(p, leecher, 600) || !s.SendBlock(p, leecher, 400) {
+		t.Fatal("expected blocks within the cap to be sent")
+	}
+	if s.SendBlock(p, leecher, 101) {
+		t.Fatal("expected the cap to be reached")
+	}
+	// peers in credit are not capped
+	if !s.SendBlock(p, &Receipt{Sent: 5000, Recv: 10000}, 1000) {
+		t.Fatal("expected blocks to be sent to a peer in credit")
+	}
+	// other peers have their own cap
+	if !s.SendBlock(libp2ptest.RandPeerIDFatal(t), leecher, 1000) {
+		t.Fatal("expected the cap to be per peer")
+	}
+
+	clk.Add(DefaultAltruismPeriod)
+	if !s.SendBlock(p, leecher, 1000) {
+		t.Fatal("expected the cap to be reset after the period")
+	}
+
+	if s.PeerWeight(p, leecher) != 0 {
+		t.Fatal("expected no weight without TitForTatWeight")
+	}
+	// custom ledgers may have no receipt for a peer
+	if !s.SendBlock(p, nil, 100) || s.PeerWeight(p, nil) != 0 {
+		t.Fatal("expected a nil receipt to count as an empty one")
+	}
+	s.TitForTatWeight = 1
+	if s.PeerWeight(p, &Receipt{Sent: 1000, Recv: 3000}) <= s.PeerWeight(p, &Receipt{Sent: 3000, Recv: 1000}) {
+		t.Fatal("expected peers which send more to have a higher weight")
+	}
+}
+
+func TestStrategyPeerComparator(t *testing.T) {
+	sl := NewTestScoreLedger(shortTerm, nil, clock.New())
+	s := &ReciprocityStrategy{TitForTatWeight: 1}
+	weights := newPeerWeights()
+	ptq := peertaskqueue.New(
+		peertaskqueue.TaskMerger(newTaskMerger()),
+		peertaskqueue.PeerComparator(strategyPeerComparator(weights, nil)),
+		peertaskqueue.OnPeerAddedHook(func(p peer.ID) {
+			weights.set(p, s.PeerWeight(p, sl.GetReceipt(p)))
+		}),
+	)
+
+	// peers which sent more are served first
+	peerIDs := make([]peer.ID, 5)
+	for i := range peerIDs {
+		peerIDs[i] = libp2ptest.RandPeerIDFatal(t)
+		sl.AddToReceivedBytes(peerIDs[i], (len(peerIDs)-i)*1000)
+	}
+	for i := len(peerIDs) - 1; i >= 0; i-- {
+		ptq.PushTasks(peerIDs[i], peertask.Task{
+			Topic:    blocks.NewBlock([]byte{byte(i)}).Cid(),
+			Priority: 1,
+			Work:     10,
+			Data:     &taskData{BlockSize: 10, HaveBlock: true, IsWantBlock: true},
+		})
+	}
+
+	for _, peerID := range peerIDs {
+		p, tasks, _ := ptq.PopTasks(100)
+		if p != peerID || len(tasks) != 1 {
+			t.Fatalf("expected a task for peer ID %s but instead got %d tasks for peer ID %s", peerID, len(tasks), p)
+		}
+	}
+}
+
+func TestStrategyHoldsBlocksBack(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	blks := testutil.GenerateBlocksOfSize(3, 8*1024)
+	if err := bs.PutMany(ctx, blks); err != nil {
+		t.Fatal(err)
+	}
+	e := newEngineForTesting(ctx, bs, &fakePeerTagger{}, "localhost", 0,
+		WithScoreLedger(NewTestScoreLedger(shortTerm, nil, clock.New())),
+		WithStrategy(&ReciprocityStrategy{AltruismCap: 10 * 1024}),
+	)
+	e.StartWorkers(ctx, process.WithTeardown(func() error { return nil }))
+
+	partner := libp2ptest.RandPeerIDFatal(t)
+	msg := message.New(false)
+	for _, blk := range blks {
+		msg.AddEntry(blk.Cid(), 1, pb.Message_Wantlist_Block, true)
+	}
+	e.MessageReceived(ctx, partner, msg)
+
+	var sent, dontHaves int
+	var next envChan
+	for sent+dontHaves < len(blks) {
+		var env *Envelope
+		next, env = getNextEnvelope(e, next, 100*time.Millisecond)
+		if env == nil {
+			t.Fatalf("expected more envelopes, got %d blocks and %d DONT_HAVEs", sent, dontHaves)
+		}
+		sent += len(env.Message.Blocks())
+		for _, bp := range env.Message.BlockPresences() {
+			if bp.Type != pb.Message_DontHave {
+				t.Fatal("expected only DONT_HAVEs")
+			}
+			dontHaves++
+		}
+		env.Sent()
+	}
+	if sent != 1 || dontHaves != 2 {
+		t.Fatalf("expected 1 block within the cap and 2 DONT_HAVEs, got %d blocks and %d DONT_HAVEs", sent, dontHaves)
+	}
+}
+
+func TestScoreLedgerPersistence(t *testing.T) {
+	store := dssync.MutexWrap(ds.NewMapDatastore())
+	p := libp2ptest.RandPeerIDFatal(t)
+
+	sl := NewTestScoreLedger(shortTerm, nil, clock.New())
+	sl.store = store
+	sl.PeerConnected(p)
+	sl.AddToSentBytes(p, 100)
+	sl.AddToReceivedBytes(p, 300)
+	sl.PeerDisconnected(p)
+
+	// the ledger is restored when the peer reconnects
+	sl.PeerConnected(p)
+	if r := sl.GetReceipt(p); r.Sent != 100 || r.Recv != 300 || r.Exchanged != 2 {
+		t.Fatalf("unexpected receipt after reconnecting: %+v", r)
+	}
+	sl.AddToSentBytes(p, 50)
+	sl.Start(func(peer.ID, int) {})
+	sl.Stop()
+
+	// and by a new ledger
+	restarted := NewTestScoreLedger(shortTerm, nil, clock.New())
+	restarted.store = store
+	restarted.AddToReceivedBytes(p, 1)
+	if r := restarted.GetReceipt(p); r.Sent != 150 || r.Recv != 301 || r.Exchanged != 4 {
+		t.Fatalf("unexpected receipt after restarting: %+v", r)
+	}
+}
+
+// blockingGetDatastore blocks Get calls until unblock is closed, and
+// signals them on getting.
+type blockingGetDatastore struct {
+	ds.Datastore
+	getting chan struct{}
+	unblock chan struct{}
+}
+
+func (d *blockingGetDatastore) Get(ctx context.Context, k ds.Key) ([]byte, error) {
+	d.getting <- struct{}{}
+	<-d.unblock
+	return d.Datastore.Get(ctx, k)
+}
+
+func TestScoreLedgerLoadWithoutLock(t *testing.T) {
+	store := &blockingGetDatastore{
+		Datastore: dssync.MutexWrap(ds.NewMapDatastore()),
+		getting:   make(chan struct{}, 1),
+		unblock:   make(chan struct{}),
+	}
+	p1 := libp2ptest.RandPeerIDFatal(t)
+	p2 := libp2ptest.RandPeerIDFatal(t)
+
+	sl := NewTestScoreLedger(shortTerm, nil, clock.New())
+	sl.store = store
+
+	connected := make(chan struct{})
+	go func() {
+		sl.PeerConnected(p1)
+		close(connected)
+	}()
+	<-store.getting
+
+	// the slow load of p1 does not hold up the ledgers of other peers
+	done := make(chan struct{})
+	go func() {
+		sl.GetReceipt(p2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ledger blocked by a datastore load")
+	}
+
+	close(store.unblock)
+	<-connected
+	if r := sl.GetReceipt(p1); r.Peer != p1.String() {
+		t.Fatalf("unexpected receipt: %+v", r)
+	}
+}
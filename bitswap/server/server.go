@@ -16,8 +16,10 @@ import (
 	"github.com/ipfs/boxo/bitswap/server/internal/decision"
 	"github.com/ipfs/boxo/bitswap/tracer"
 	blockstore "github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/verifcid"
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipfs/go-metrics-interface"
 	process "github.com/jbenet/goprocess"
@@ -161,6 +163,26 @@ func WithScoreLedger(scoreLedger decision.ScoreLedger) Option {
 	}
 }
 
+// WithStrategy sets the [Strategy] deciding which peers are served first, and
+// which blocks are sent to them, from the ledger of the exchanges with each
+// peer, for example a [ReciprocityStrategy].
+func WithStrategy(strategy Strategy) Option {
+	o := decision.WithStrategy(strategy)
+	return func(bs *Server) {
+		bs.engineOptions = append(bs.engineOptions, o)
+	}
+}
+
+// WithLedgerDatastore persists the bytes exchanged with each peer in d, so that
+// strategies can rely on them when peers reconnect and across restarts. It has
+// no effect when a score ledger is set with [WithScoreLedger].
+func WithLedgerDatastore(d ds.Datastore) Option {
+	o := decision.WithLedgerDatastore(d)
+	return func(bs *Server) {
+		bs.engineOptions = append(bs.engineOptions, o)
+	}
+}
+
 // LedgerForPeer returns aggregated data about blocks swapped and communication
 // with a given peer.
 func (bs *Server) LedgerForPeer(p peer.ID) *decision.Receipt {
@@ -213,7 +235,8 @@ func MaxOutstandingBytesPerPeer(count int) Option {
 }
 
 // MaxQueuedWantlistEntriesPerPeer limits how much individual entries each peer is allowed to send.
-// If a peer send us more than this we will truncate newest entries.
+// If a peer send us more than this, entries are dropped according to the
+// [ShedPolicy] set with [WithShedPolicy].
 // It defaults to defaults.MaxQueuedWantlistEntiresPerPeer.
 func MaxQueuedWantlistEntriesPerPeer(count uint) Option {
 	o := decision.WithMaxQueuedWantlistEntriesPerPeer(count)
@@ -222,6 +245,18 @@ func MaxQueuedWantlistEntriesPerPeer(count uint) Option {
 	}
 }
 
+// WithShedPolicy sets which entries are dropped when a peer sends more entries
+// than allowed by [MaxQueuedWantlistEntriesPerPeer].
+// [ShedNewest] (the default) ignores the new entries, while [ShedOldest] drops
+// the oldest queued entries and answers them with DONT_HAVE when the peer
+// asked for one.
+func WithShedPolicy(policy ShedPolicy) Option {
+	o := decision.WithShedPolicy(policy)
+	return func(bs *Server) {
+		bs.engineOptions = append(bs.engineOptions, o)
+	}
+}
+
 // MaxCidSize limits how big CIDs we are willing to serve.
 // We will ignore CIDs over this limit.
 // It defaults to [defaults.MaxCidSize].
@@ -233,6 +268,15 @@ func MaxCidSize(n uint) Option {
 	}
 }
 
+// WithCidPolicy ignores the wants for CIDs refused by the [verifcid.Policy].
+// By default the only CIDs ignored are the ones bigger than [MaxCidSize].
+func WithCidPolicy(policy *verifcid.Policy) Option {
+	o := decision.WithCidPolicy(policy)
+	return func(bs *Server) {
+		bs.engineOptions = append(bs.engineOptions, o)
+	}
+}
+
 // HasBlockBufferSize configure how big the new blocks buffer should be.
 func HasBlockBufferSize(count int) Option {
 	if count < 0 {
@@ -386,6 +430,8 @@ type Stat struct {
 	ProvideBufLen int
 	BlocksSent    uint64
 	DataSent      uint64
+	// WantsShed is the number of wants dropped because a peer's queue was full.
+	WantsShed uint64
 }
 
 // Stat returns aggregated statistics about bitswap operations
@@ -394,6 +440,7 @@ func (bs *Server) Stat() (Stat, error) {
 	s := bs.counters
 	bs.counterLk.Unlock()
 	s.ProvideBufLen = len(bs.newBlocks)
+	s.WantsShed = bs.engine.WantsShed()
 
 	peers := bs.engine.Peers()
 	peersStr := make([]string, len(peers))
@@ -406,6 +453,19 @@ func (bs *Server) Stat() (Stat, error) {
 	return s, nil
 }
 
+// NotifyDeletedBlocks drops the responses queued for the given blocks, which
+// were deleted from the blockstore.
+func (bs *Server) NotifyDeletedBlocks(ctx context.Context, ks ...cid.Cid) error {
+	select {
+	case <-bs.process.Closing():
+		return errors.New("bitswap is closed")
+	default:
+	}
+
+	bs.engine.NotifyDeletedBlocks(ks)
+	return nil
+}
+
 // NotifyNewBlocks announces the existence of blocks to this bitswap service. The
 // service will potentially notify its peers.
 // Bitswap itself doesn't store new blocks. It's the caller responsibility to ensure
@@ -0,0 +1,122 @@
+package bitswap
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	bsmsg "github.com/ipfs/boxo/bitswap/message"
+	mockrouting "github.com/ipfs/boxo/routing/mock"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// LinkConditions describe how the messages sent from a peer to another are
+// delivered by a network created with [ConditionedVirtualNetwork].
+type LinkConditions struct {
+	// Latency is the time it takes to deliver a message.
+	Latency time.Duration
+	// Jitter is the maximum random delay added to the latency of each
+	// message.
+	Jitter time.Duration
+	// Loss is the probability, between 0 and 1, that a message is silently
+	// dropped. Bitswap expects reliable streams, lost messages are only
+	// recovered by the periodic wantlist rebroadcasts.
+	Loss float64
+	// Bandwidth is the maximum throughput of the link in bytes per second,
+	// 0 means unlimited.
+	Bandwidth float64
+}
+
+// LinkConditionsFunc returns the conditions of the link from a peer to
+// another. It is called once per link, the first time a message is sent on
+// it.
+type LinkConditionsFunc func(from, to peer.ID) LinkConditions
+
+// FixedLinkConditions returns a [LinkConditionsFunc] applying the same
+// conditions to all the links.
+func FixedLinkConditions(c LinkConditions) LinkConditionsFunc {
+	return func(peer.ID, peer.ID) LinkConditions {
+		return c
+	}
+}
+
+// ConditionedVirtualNetwork generates a testnet instance where the messages
+// are delayed, dropped and rate limited according to the conditions of the
+// link they are sent on.
+//
+// Each link draws its jitter and losses from its own random generator,
+// seeded from seed and the IDs of its peers, so that runs with the same seed
+// and peers are reproducible.
+func ConditionedVirtualNetwork(rs mockrouting.Server, seed int64, conditions LinkConditionsFunc) Network {
+	return &network{
+		latencies:     make(map[peer.ID]map[peer.ID]time.Duration),
+		clients:       make(map[peer.ID]*receiverQueue),
+		routingserver: rs,
+		conns:         make(map[string]struct{}),
+		conditions:    conditions,
+		seed:          seed,
+		links:         make(map[[2]peer.ID]*link),
+	}
+}
+
+// link holds the state of the messages sent from a peer to another on a
+// conditioned network.
+type link struct {
+	conditions  LinkConditions
+	rng         *rand.Rand
+	rateLimiter *mocknet.RateLimiter
+}
+
+func (n *network) newLink(from, to peer.ID) *link {
+	c := n.conditions(from, to)
+
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], uint64(n.seed))
+	h.Write(seed[:])
+	h.Write([]byte(from))
+	h.Write([]byte(to))
+
+	l := &link{
+		conditions: c,
+		rng:        rand.New(rand.NewSource(int64(h.Sum64()))),
+	}
+	if c.Bandwidth > 0 {
+		l.rateLimiter = mocknet.NewRateLimiter(c.Bandwidth)
+	}
+	return l
+}
+
+// linkDelay returns how long the message takes to be delivered on the link
+// from a peer to another, or false if it is dropped. It must be called with
+// the network lock held.
+func (n *network) linkDelay(from, to peer.ID, mes bsmsg.BitSwapMessage) (time.Duration, bool) {
+	key := [2]peer.ID{from, to}
+	l, ok := n.links[key]
+	if !ok {
+		l = n.newLink(from, to)
+		n.links[key] = l
+
+		latencies, ok := n.latencies[from]
+		if !ok {
+			latencies = make(map[peer.ID]time.Duration)
+			n.latencies[from] = latencies
+		}
+		latencies[to] = l.conditions.Latency
+	}
+
+	if l.conditions.Loss > 0 && l.rng.Float64() < l.conditions.Loss {
+		return 0, false
+	}
+
+	d := l.conditions.Latency
+	if l.conditions.Jitter > 0 {
+		d += time.Duration(l.rng.Int63n(int64(l.conditions.Jitter) + 1))
+	}
+	if l.rateLimiter != nil {
+		d += l.rateLimiter.Limit(mes.ToProtoV1().Size())
+	}
+	return d, true
+}
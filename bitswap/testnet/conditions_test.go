@@ -0,0 +1,106 @@
+package bitswap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bsmsg "github.com/ipfs/boxo/bitswap/message"
+	mockrouting "github.com/ipfs/boxo/routing/mock"
+	blocks "github.com/ipfs/go-block-format"
+	tnet "github.com/libp2p/go-libp2p-testing/net"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestConditionedNetworkReproducibleLosses(t *testing.T) {
+	from, to := tnet.RandIdentityOrFatal(t), tnet.RandIdentityOrFatal(t)
+	msg := bsmsg.New(true)
+
+	losses := func(seed int64) []bool {
+		net := ConditionedVirtualNetwork(mockrouting.NewServer(), seed, FixedLinkConditions(LinkConditions{
+			Latency: time.Millisecond,
+			Jitter:  time.Millisecond,
+			Loss:    0.5,
+		})).(*network)
+		lost := make([]bool, 200)
+		for i := range lost {
+			d, ok := net.linkDelay(from.ID(), to.ID(), msg)
+			lost[i] = !ok
+			if ok && (d < time.Millisecond || d > 2*time.Millisecond) {
+				t.Fatalf("delay %s out of the latency and jitter bounds", d)
+			}
+		}
+		return lost
+	}
+
+	a, b, c := losses(1), losses(1), losses(2)
+	var lost, same int
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("expected the same losses with the same seed")
+		}
+		if a[i] {
+			lost++
+		}
+		if a[i] == c[i] {
+			same++
+		}
+	}
+	if lost < 50 || lost > 150 {
+		t.Fatalf("expected about half of the messages to be lost, got %d", lost)
+	}
+	if same == len(a) {
+		t.Fatal("expected different losses with a different seed")
+	}
+}
+
+func TestConditionedNetworkDelivery(t *testing.T) {
+	const latency = 50 * time.Millisecond
+	lossy := tnet.RandIdentityOrFatal(t)
+	net := ConditionedVirtualNetwork(mockrouting.NewServer(), 0, func(from, to peer.ID) LinkConditions {
+		if from == lossy.ID() {
+			return LinkConditions{Loss: 1}
+		}
+		return LinkConditions{Latency: latency}
+	})
+	receiverPeer := tnet.RandIdentityOrFatal(t)
+	sender := net.Adapter(tnet.RandIdentityOrFatal(t))
+	lossySender := net.Adapter(lossy)
+	receiver := net.Adapter(receiverPeer)
+
+	received := make(chan peer.ID, 2)
+	receiver.Start(lambda(func(_ context.Context, p peer.ID, _ bsmsg.BitSwapMessage) {
+		received <- p
+	}))
+	t.Cleanup(receiver.Stop)
+
+	msg := bsmsg.New(true)
+	msg.AddBlock(blocks.NewBlock([]byte("data")))
+	start := time.Now()
+	if err := lossySender.SendMessage(context.Background(), receiverPeer.ID(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.SendMessage(context.Background(), receiverPeer.ID(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case p := <-received:
+		if p != sender.Self() {
+			t.Fatal("received a message from the lossy link")
+		}
+		if time.Since(start) < latency {
+			t.Fatal("message delivered before the link latency")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+	select {
+	case <-received:
+		t.Fatal("received a message from the lossy link")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if sender.Latency(receiverPeer.ID()) != latency {
+		t.Fatal("expected the link latency to be reported")
+	}
+}
@@ -68,6 +68,11 @@ type network struct {
 	isRateLimited      bool
 	rateLimitGenerator RateLimitGenerator
 	conns              map[string]struct{}
+
+	// Set for networks created with ConditionedVirtualNetwork.
+	conditions LinkConditionsFunc
+	seed       int64
+	links      map[[2]peer.ID]*link
 }
 
 type message struct {
@@ -133,6 +138,24 @@ func (n *network) SendMessage(
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	if n.conditions != nil {
+		receiver, ok := n.clients[to]
+		if !ok {
+			return errors.New("cannot locate peer on network")
+		}
+		d, ok := n.linkDelay(from, to, mes)
+		if !ok {
+			// lost on the way
+			return nil
+		}
+		receiver.enqueue(&message{
+			from:       from,
+			msg:        mes,
+			shouldSend: time.Now().Add(d),
+		})
+		return nil
+	}
+
 	latencies, ok := n.latencies[from]
 	if !ok {
 		latencies = make(map[peer.ID]time.Duration)
@@ -0,0 +1,262 @@
+package tracer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	bsmsg "github.com/ipfs/boxo/bitswap/message"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// captureMagic starts every capture file, followed by the version of the
+// format.
+const captureMagic = "bitswap-capture\x00"
+
+const captureVersion = 1
+
+const (
+	directionReceived byte = iota
+	directionSent
+)
+
+// maxPeerIDSize bounds the size of the peer IDs read from a capture.
+const maxPeerIDSize = 256
+
+// ErrInvalidCapture is returned when reading a file which is not a bitswap
+// capture, or a capture in an unsupported version.
+var ErrInvalidCapture = errors.New("invalid bitswap capture")
+
+// Capture is a [Tracer] recording every message sent and received, with its
+// time and remote peer, so that it can be inspected or replayed later with a
+// [CaptureReader].
+//
+// A capture starts with a header, followed by one record per message:
+//
+//	direction (1 byte, 0 for received, 1 for sent)
+//	unix time in nanoseconds (uvarint)
+//	peer ID length (uvarint) and peer ID bytes
+//	message, as sent on the wire by bitswap 1.2 (uvarint-prefixed protobuf)
+//
+// Writes are buffered, the capture must be closed or flushed to be complete.
+// The first write error stops the capture and is returned by Flush and Close.
+type Capture struct {
+	lk  sync.Mutex
+	w   *bufio.Writer
+	c   io.Closer
+	err error
+	buf [binary.MaxVarintLen64]byte
+
+	now func() time.Time
+}
+
+var _ Tracer = (*Capture)(nil)
+
+// NewCapture writes the capture header to w and returns a Capture recording
+// to it. If w is an [io.Closer], it is closed by [Capture.Close].
+func NewCapture(w io.Writer) (*Capture, error) {
+	c := &Capture{
+		w:   bufio.NewWriter(w),
+		now: time.Now,
+	}
+	if closer, ok := w.(io.Closer); ok {
+		c.c = closer
+	}
+
+	if _, err := c.w.WriteString(captureMagic); err != nil {
+		return nil, err
+	}
+	if err := c.w.WriteByte(captureVersion); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Capture) MessageReceived(p peer.ID, msg bsmsg.BitSwapMessage) {
+	c.record(directionReceived, p, msg)
+}
+
+func (c *Capture) MessageSent(p peer.ID, msg bsmsg.BitSwapMessage) {
+	c.record(directionSent, p, msg)
+}
+
+func (c *Capture) record(direction byte, p peer.ID, msg bsmsg.BitSwapMessage) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = c.write(direction, p, msg)
+}
+
+func (c *Capture) write(direction byte, p peer.ID, msg bsmsg.BitSwapMessage) error {
+	if err := c.w.WriteByte(direction); err != nil {
+		return err
+	}
+	if err := c.writeUvarint(uint64(c.now().UnixNano())); err != nil {
+		return err
+	}
+	if err := c.writeUvarint(uint64(len(p))); err != nil {
+		return err
+	}
+	if _, err := c.w.WriteString(string(p)); err != nil {
+		return err
+	}
+	return msg.ToNetV1(c.w)
+}
+
+func (c *Capture) writeUvarint(v uint64) error {
+	n := binary.PutUvarint(c.buf[:], v)
+	_, err := c.w.Write(c.buf[:n])
+	return err
+}
+
+// Flush writes the buffered records.
+func (c *Capture) Flush() error {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.err = c.w.Flush()
+	return c.err
+}
+
+// Close flushes the capture and closes the underlying writer. Messages traced
+// after Close are dropped.
+func (c *Capture) Close() error {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	err := c.err
+	if err == nil {
+		err = c.w.Flush()
+	}
+	c.err = errors.New("capture closed")
+	if c.c != nil {
+		if cerr := c.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// CapturedMessage is a message read from a capture.
+type CapturedMessage struct {
+	Time time.Time
+	// Peer is the remote peer of the message.
+	Peer peer.ID
+	// Sent is true for messages sent to Peer, false for messages received
+	// from it.
+	Sent    bool
+	Message bsmsg.BitSwapMessage
+}
+
+// CaptureReader reads the messages of a capture written by [Capture].
+type CaptureReader struct {
+	r *bufio.Reader
+}
+
+// NewCaptureReader checks the capture header of r and returns a reader of
+// its messages.
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(captureMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrInvalidCapture
+		}
+		return nil, err
+	}
+	if string(header[:len(captureMagic)]) != captureMagic {
+		return nil, ErrInvalidCapture
+	}
+	if v := header[len(captureMagic)]; v != captureVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCapture, v)
+	}
+	return &CaptureReader{r: br}, nil
+}
+
+// Next returns the next message of the capture, or io.EOF once all messages
+// were read.
+func (cr *CaptureReader) Next() (CapturedMessage, error) {
+	var m CapturedMessage
+
+	direction, err := cr.r.ReadByte()
+	if err != nil {
+		return m, err
+	}
+	switch direction {
+	case directionReceived:
+	case directionSent:
+		m.Sent = true
+	default:
+		return m, fmt.Errorf("%w: unknown direction %d", ErrInvalidCapture, direction)
+	}
+
+	ts, err := binary.ReadUvarint(cr.r)
+	if err != nil {
+		return m, truncated(err)
+	}
+	m.Time = time.Unix(0, int64(ts))
+
+	size, err := binary.ReadUvarint(cr.r)
+	if err != nil {
+		return m, truncated(err)
+	}
+	if size > maxPeerIDSize {
+		return m, fmt.Errorf("%w: peer ID of %d bytes", ErrInvalidCapture, size)
+	}
+	id := make([]byte, size)
+	if _, err := io.ReadFull(cr.r, id); err != nil {
+		return m, truncated(err)
+	}
+	m.Peer = peer.ID(id)
+
+	m.Message, err = bsmsg.FromNet(cr.r)
+	if err != nil {
+		return m, truncated(err)
+	}
+	return m, nil
+}
+
+// truncated turns the end of the capture in the middle of a record into an
+// error.
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Replay reads every message of the capture in r and passes it to t, in the
+// order they were captured. It returns the number of messages replayed.
+func Replay(r io.Reader, t Tracer) (int, error) {
+	cr, err := NewCaptureReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for {
+		m, err := cr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		if m.Sent {
+			t.MessageSent(m.Peer, m.Message)
+		} else {
+			t.MessageReceived(m.Peer, m.Message)
+		}
+		n++
+	}
+}
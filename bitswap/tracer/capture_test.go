@@ -0,0 +1,130 @@
+package tracer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	bsmsg "github.com/ipfs/boxo/bitswap/message"
+	pb "github.com/ipfs/boxo/bitswap/message/pb"
+	blocks "github.com/ipfs/go-block-format"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	libp2ptest "github.com/libp2p/go-libp2p/core/test"
+)
+
+type recordingTracer struct {
+	received, sent []bsmsg.BitSwapMessage
+	peers          []peer.ID
+}
+
+func (t *recordingTracer) MessageReceived(p peer.ID, msg bsmsg.BitSwapMessage) {
+	t.received = append(t.received, msg)
+	t.peers = append(t.peers, p)
+}
+
+func (t *recordingTracer) MessageSent(p peer.ID, msg bsmsg.BitSwapMessage) {
+	t.sent = append(t.sent, msg)
+	t.peers = append(t.peers, p)
+}
+
+func TestCapture(t *testing.T) {
+	p1 := libp2ptest.RandPeerIDFatal(t)
+	p2 := libp2ptest.RandPeerIDFatal(t)
+	blk := blocks.NewBlock([]byte("hello"))
+
+	want := bsmsg.New(true)
+	want.AddEntry(blk.Cid(), 10, pb.Message_Wantlist_Have, true)
+	resp := bsmsg.New(false)
+	resp.AddBlock(blk)
+	resp.AddDontHave(blocks.NewBlock([]byte("missing")).Cid())
+
+	var buf bytes.Buffer
+	c, err := NewCapture(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1700000000, 42)
+	c.now = func() time.Time { return start }
+
+	c.MessageSent(p1, want)
+	c.MessageReceived(p2, resp)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// messages traced after Close are dropped
+	c.MessageSent(p1, want)
+
+	cr, err := NewCaptureReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := cr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Sent || m.Peer != p1 || !m.Time.Equal(start) {
+		t.Fatalf("unexpected first record: sent=%t peer=%s time=%s", m.Sent, m.Peer, m.Time)
+	}
+	wl := m.Message.Wantlist()
+	if !m.Message.Full() || len(wl) != 1 || wl[0].Cid != blk.Cid() || wl[0].Priority != 10 || wl[0].WantType != pb.Message_Wantlist_Have || !wl[0].SendDontHave {
+		t.Fatalf("unexpected wantlist %v", wl)
+	}
+
+	m, err = cr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Sent || m.Peer != p2 {
+		t.Fatalf("unexpected second record: sent=%t peer=%s", m.Sent, m.Peer)
+	}
+	if blks := m.Message.Blocks(); len(blks) != 1 || blks[0].Cid() != blk.Cid() || !bytes.Equal(blks[0].RawData(), blk.RawData()) {
+		t.Fatalf("unexpected blocks %v", blks)
+	}
+	if len(m.Message.DontHaves()) != 1 {
+		t.Fatalf("expected 1 dont-have, got %d", len(m.Message.DontHaves()))
+	}
+
+	if _, err := cr.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	// replay to another tracer
+	var rt recordingTracer
+	n, err := Replay(bytes.NewReader(buf.Bytes()), &rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(rt.sent) != 1 || len(rt.received) != 1 {
+		t.Fatalf("expected 1 sent and 1 received message, got %d sent and %d received", len(rt.sent), len(rt.received))
+	}
+	if rt.peers[0] != p1 || rt.peers[1] != p2 {
+		t.Fatal("messages replayed out of order")
+	}
+}
+
+func TestCaptureInvalid(t *testing.T) {
+	if _, err := NewCaptureReader(bytes.NewReader([]byte("not a capture at all"))); !errors.Is(err, ErrInvalidCapture) {
+		t.Fatalf("expected ErrInvalidCapture, got %v", err)
+	}
+	if _, err := NewCaptureReader(bytes.NewReader(nil)); !errors.Is(err, ErrInvalidCapture) {
+		t.Fatalf("expected ErrInvalidCapture, got %v", err)
+	}
+
+	// a truncated record is an error
+	var buf bytes.Buffer
+	c, err := NewCapture(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.MessageSent(libp2ptest.RandPeerIDFatal(t), bsmsg.New(true))
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := Replay(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), &recordingTracer{})
+	if n != 0 || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %d messages and %v", n, err)
+	}
+}
@@ -5,6 +5,8 @@ package blockservice
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"sync"
 
@@ -13,6 +15,7 @@ import (
 
 	"github.com/ipfs/boxo/blockstore"
 	"github.com/ipfs/boxo/exchange"
+	"github.com/ipfs/boxo/provider"
 	"github.com/ipfs/boxo/verifcid"
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
@@ -62,6 +65,11 @@ type BlockService interface {
 
 	// DeleteBlock deletes the given block from the blockservice.
 	DeleteBlock(ctx context.Context, o cid.Cid) error
+
+	// DeleteBlocks deletes the given blocks from the blockservice, unless a
+	// [DeleteHook] vetoes the deletion of one of them, and tells the exchange
+	// they are no longer available.
+	DeleteBlocks(ctx context.Context, ks []cid.Cid) error
 }
 
 // BoundedBlockService is a Blockservice bounded via strict multihash Allowlist.
@@ -71,15 +79,33 @@ type BoundedBlockService interface {
 	Allowlist() verifcid.Allowlist
 }
 
+// PolicedBlockService is a Blockservice validating CIDs with a
+// [verifcid.Policy].
+type PolicedBlockService interface {
+	BlockService
+
+	Policy() *verifcid.Policy
+}
+
 var _ BoundedBlockService = (*blockService)(nil)
+var _ PolicedBlockService = (*blockService)(nil)
 
 type blockService struct {
-	allowlist  verifcid.Allowlist
+	policy     *verifcid.Policy
 	blockstore blockstore.Blockstore
 	exchange   exchange.Interface
 	// If checkFirst is true then first check that a block doesn't
 	// already exist to avoid republishing the block on the exchange.
 	checkFirst bool
+
+	replicas    []blockstore.Blockstore
+	consistency Consistency
+	replicated  *replicatedBlockstore
+
+	provider      provider.Provider
+	provideFilter ProvideFilter
+
+	deleteHooks []DeleteHook
 }
 
 type Option func(*blockService)
@@ -95,7 +121,42 @@ func WriteThrough() Option {
 // WithAllowlist sets a custom [verifcid.Allowlist] which will be used
 func WithAllowlist(allowlist verifcid.Allowlist) Option {
 	return func(bs *blockService) {
-		bs.allowlist = allowlist
+		policy := *bs.policy
+		policy.Hashes = allowlist
+		bs.policy = &policy
+	}
+}
+
+// WithPolicy sets the [verifcid.Policy] validating the CIDs of the blocks.
+// It replaces the allowlist set with [WithAllowlist] before it.
+func WithPolicy(policy *verifcid.Policy) Option {
+	return func(bs *blockService) {
+		if policy == nil {
+			policy = verifcid.DefaultPolicy
+		}
+		bs.policy = policy
+	}
+}
+
+// ProvideFilter decides whether a block written by the blockservice is
+// announced by the provider set with [WithProvider]. For example, an
+// application can mark the context of its root writes to only provide roots.
+type ProvideFilter func(ctx context.Context, c cid.Cid) bool
+
+// WithProvider announces every block the blockservice writes to its
+// blockstore, either added or fetched from the exchange, with the given
+// [provider.Provider]. Providing errors are logged and don't fail the write.
+func WithProvider(prov provider.Provider) Option {
+	return func(bs *blockService) {
+		bs.provider = prov
+	}
+}
+
+// WithProvideFilter restricts the blocks announced by the provider set with
+// [WithProvider] to the ones for which filter returns true.
+func WithProvideFilter(filter ProvideFilter) Option {
+	return func(bs *blockService) {
+		bs.provideFilter = filter
 	}
 }
 
@@ -106,7 +167,7 @@ func New(bs blockstore.Blockstore, exchange exchange.Interface, opts ...Option)
 	}
 
 	service := &blockService{
-		allowlist:  verifcid.DefaultAllowlist,
+		policy:     verifcid.DefaultPolicy,
 		blockstore: bs,
 		exchange:   exchange,
 		checkFirst: true,
@@ -116,10 +177,16 @@ func New(bs blockstore.Blockstore, exchange exchange.Interface, opts ...Option)
 		opt(service)
 	}
 
+	if len(service.replicas) > 0 {
+		service.replicated = newReplicatedBlockstore(bs, service.replicas, service.consistency)
+		service.blockstore = service.replicated
+	}
+
 	return service
 }
 
-// Blockstore returns the blockstore behind this blockservice.
+// Blockstore returns the blockstore behind this blockservice. When replicas
+// are configured with [WithReplicas] it writes to all of them.
 func (s *blockService) Blockstore() blockstore.Blockstore {
 	return s.blockstore
 }
@@ -130,7 +197,14 @@ func (s *blockService) Exchange() exchange.Interface {
 }
 
 func (s *blockService) Allowlist() verifcid.Allowlist {
-	return s.allowlist
+	if s.policy.Hashes == nil {
+		return verifcid.DefaultAllowlist
+	}
+	return s.policy.Hashes
+}
+
+func (s *blockService) Policy() *verifcid.Policy {
+	return s.policy
 }
 
 // NewSession creates a new session that allows for
@@ -139,6 +213,8 @@ func (s *blockService) Allowlist() verifcid.Allowlist {
 // session will be created. Otherwise, the current exchange will be used
 // directly.
 // Sessions are lazily setup, this is cheap.
+// The lifetime of the session is bound to ctx, once it is cancelled the
+// underlying exchange session is torn down.
 func NewSession(ctx context.Context, bs BlockService) *Session {
 	ses := grabSessionFromContext(ctx, bs)
 	if ses != nil {
@@ -159,7 +235,7 @@ func (s *blockService) AddBlock(ctx context.Context, o blocks.Block) error {
 	defer span.End()
 
 	c := o.Cid()
-	err := verifcid.ValidateCid(s.allowlist, c) // hash security
+	err := s.policy.Validate(c) // hash security
 	if err != nil {
 		return err
 	}
@@ -174,6 +250,7 @@ func (s *blockService) AddBlock(ctx context.Context, o blocks.Block) error {
 	}
 
 	logger.Debugf("BlockService.BlockAdded %s", c)
+	s.provide(ctx, o)
 
 	if s.exchange != nil {
 		if err := s.exchange.NotifyNewBlocks(ctx, o); err != nil {
@@ -190,7 +267,7 @@ func (s *blockService) AddBlocks(ctx context.Context, bs []blocks.Block) error {
 
 	// hash security
 	for _, b := range bs {
-		err := verifcid.ValidateCid(s.allowlist, b.Cid())
+		err := s.policy.Validate(b.Cid())
 		if err != nil {
 			return err
 		}
@@ -219,6 +296,7 @@ func (s *blockService) AddBlocks(ctx context.Context, bs []blocks.Block) error {
 	if err != nil {
 		return err
 	}
+	s.provide(ctx, toput...)
 
 	if s.exchange != nil {
 		logger.Debugf("BlockService.BlockAdded %d blocks", len(toput))
@@ -239,7 +317,7 @@ func (s *blockService) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, e
 	ctx, span := internal.StartSpan(ctx, "blockService.GetBlock", trace.WithAttributes(attribute.Stringer("CID", c)))
 	defer span.End()
 
-	return getBlock(ctx, c, s, s.getExchangeFetcher)
+	return getBlock(ctx, c, s, s.getExchangeFetcher, &getOptions{})
 }
 
 // Look at what I have to do, no interface covariance :'(
@@ -247,14 +325,32 @@ func (s *blockService) getExchangeFetcher() exchange.Fetcher {
 	return s.exchange
 }
 
-func getBlock(ctx context.Context, c cid.Cid, bs BlockService, fetchFactory func() exchange.Fetcher) (blocks.Block, error) {
-	err := verifcid.ValidateCid(grabAllowlistFromBlockservice(bs), c) // hash security
+func getBlock(ctx context.Context, c cid.Cid, bs BlockService, fetchFactory func() exchange.Fetcher, opts *getOptions) (blocks.Block, error) {
+	err := grabPolicyFromBlockservice(bs).Validate(c) // hash security
 	if err != nil {
 		return nil, err
 	}
+	if err := opts.checkCodec(c); err != nil {
+		return nil, err
+	}
 
 	blockstore := bs.Blockstore()
 
+	if opts.maxSize > 0 {
+		// avoid reading blocks too big
+		size, err := blockstore.GetSize(ctx, c)
+		switch {
+		case err == nil:
+			if err := opts.checkSize(c, size); err != nil {
+				return nil, err
+			}
+		case ipld.IsNotFound(err):
+			break
+		default:
+			return nil, err
+		}
+	}
+
 	block, err := blockstore.Get(ctx, c)
 	switch {
 	case err == nil:
@@ -265,6 +361,10 @@ func getBlock(ctx context.Context, c cid.Cid, bs BlockService, fetchFactory func
 		return nil, err
 	}
 
+	if opts.requireCached {
+		return nil, err
+	}
+
 	fetch := fetchFactory() // lazily create session if needed
 	if fetch == nil {
 		logger.Debug("BlockService GetBlock: Not found")
@@ -276,11 +376,15 @@ func getBlock(ctx context.Context, c cid.Cid, bs BlockService, fetchFactory func
 	if err != nil {
 		return nil, err
 	}
+	if err := opts.checkSize(c, len(blk.RawData())); err != nil {
+		return nil, err
+	}
 	// also write in the blockstore for caching, inform the exchange that the block is available
 	err = blockstore.Put(ctx, blk)
 	if err != nil {
 		return nil, err
 	}
+	provideFromBlockservice(ctx, bs, blk)
 	if ex := bs.Exchange(); ex != nil {
 		err = ex.NotifyNewBlocks(ctx, blk)
 		if err != nil {
@@ -311,12 +415,12 @@ func getBlocks(ctx context.Context, ks []cid.Cid, blockservice BlockService, fet
 	go func() {
 		defer close(out)
 
-		allowlist := grabAllowlistFromBlockservice(blockservice)
+		policy := grabPolicyFromBlockservice(blockservice)
 
 		var lastAllValidIndex int
 		var c cid.Cid
 		for lastAllValidIndex, c = range ks {
-			if err := verifcid.ValidateCid(allowlist, c); err != nil {
+			if err := policy.Validate(c); err != nil {
 				break
 			}
 		}
@@ -327,7 +431,7 @@ func getBlocks(ctx context.Context, ks []cid.Cid, blockservice BlockService, fet
 			copy(ks2, ks[:lastAllValidIndex])          // fast path for already filtered elements
 			for _, c := range ks[lastAllValidIndex:] { // don't rescan already scanned elements
 				// hash security
-				if err := verifcid.ValidateCid(allowlist, c); err == nil {
+				if err := policy.Validate(c); err == nil {
 					ks2 = append(ks2, c)
 				} else {
 					logger.Errorf("unsafe CID (%s) passed to blockService.GetBlocks: %s", c, err)
@@ -383,6 +487,7 @@ func getBlocks(ctx context.Context, ks []cid.Cid, blockservice BlockService, fet
 				logger.Errorf("could not write blocks from the network to the blockstore: %s", err)
 				return
 			}
+			provideFromBlockservice(ctx, blockservice, b)
 
 			if ex != nil {
 				// inform the exchange that the blocks are available
@@ -410,15 +515,55 @@ func (s *blockService) DeleteBlock(ctx context.Context, c cid.Cid) error {
 	ctx, span := internal.StartSpan(ctx, "blockService.DeleteBlock", trace.WithAttributes(attribute.Stringer("CID", c)))
 	defer span.End()
 
-	err := s.blockstore.DeleteBlock(ctx, c)
-	if err == nil {
+	return s.deleteBlocks(ctx, []cid.Cid{c})
+}
+
+// DeleteBlocks deletes blocks in the blockservice from the datastore. The
+// deletion hooks are consulted for every block first: if one of them vetoes
+// the deletion of a block, no block is deleted. Otherwise every block is
+// deleted, even if the deletion of some of them fails, and the exchange is
+// told about the ones deleted if it implements [exchange.DeletionNotifier].
+func (s *blockService) DeleteBlocks(ctx context.Context, ks []cid.Cid) error {
+	ctx, span := internal.StartSpan(ctx, "blockService.DeleteBlocks", trace.WithAttributes(attribute.Int("NumKeys", len(ks))))
+	defer span.End()
+
+	return s.deleteBlocks(ctx, ks)
+}
+
+func (s *blockService) deleteBlocks(ctx context.Context, ks []cid.Cid) error {
+	for _, c := range ks {
+		for _, hook := range s.deleteHooks {
+			if err := hook.AllowDelete(ctx, c); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrDeletionVetoed, c, err)
+			}
+		}
+	}
+
+	var errs []error
+	deleted := make([]cid.Cid, 0, len(ks))
+	for _, c := range ks {
+		if err := s.blockstore.DeleteBlock(ctx, c); err != nil {
+			errs = append(errs, err)
+			continue
+		}
 		logger.Debugf("BlockService.BlockDeleted %s", c)
+		deleted = append(deleted, c)
 	}
-	return err
+
+	if dn, ok := s.exchange.(exchange.DeletionNotifier); ok && len(deleted) > 0 {
+		if err := dn.NotifyDeletedBlocks(ctx, deleted...); err != nil {
+			logger.Errorf("NotifyDeletedBlocks: %s", err.Error())
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 func (s *blockService) Close() error {
 	logger.Debug("blockservice is shutting down...")
+	if s.replicated != nil {
+		s.replicated.wait()
+	}
 	if s.exchange == nil {
 		return nil
 	}
@@ -450,6 +595,11 @@ func (s *Session) grabSession() exchange.Fetcher {
 		if !ok {
 			return
 		}
+		if s.sesctx.Err() != nil {
+			// the session context is already done, an exchange session created
+			// now would be torn down immediately, keep using the exchange.
+			return
+		}
 		s.ses = sesEx.NewSession(s.sesctx)
 	})
 
@@ -461,7 +611,7 @@ func (s *Session) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error)
 	ctx, span := internal.StartSpan(ctx, "Session.GetBlock", trace.WithAttributes(attribute.Stringer("CID", c)))
 	defer span.End()
 
-	return getBlock(ctx, c, s.bs, s.grabSession)
+	return getBlock(ctx, c, s.bs, s.grabSession, &getOptions{})
 }
 
 // GetBlocks gets blocks in the context of a request session
@@ -479,6 +629,8 @@ var _ BlockGetter = (*Session)(nil)
 // will be redirected to this same session instead.
 // Sessions are lazily setup, this is cheap.
 // It wont make a new session if one exists already in the context.
+// The session is torn down when ctx is cancelled, this makes it safe to use
+// with per request contexts such as the ones from HTTP handlers.
 func ContextWithSession(ctx context.Context, bs BlockService) context.Context {
 	if grabSessionFromContext(ctx, bs) != nil {
 		return ctx
@@ -511,10 +663,36 @@ func grabSessionFromContext(ctx context.Context, bs BlockService) *Session {
 	return ss
 }
 
-// grabAllowlistFromBlockservice never returns nil
-func grabAllowlistFromBlockservice(bs BlockService) verifcid.Allowlist {
+// provide announces the given blocks, which were just written to the
+// blockstore, if a provider is configured.
+func (s *blockService) provide(ctx context.Context, blks ...blocks.Block) {
+	if s.provider == nil {
+		return
+	}
+	for _, b := range blks {
+		c := b.Cid()
+		if s.provideFilter != nil && !s.provideFilter(ctx, c) {
+			continue
+		}
+		if err := s.provider.Provide(c); err != nil {
+			logger.Errorf("could not provide %s: %s", c, err)
+		}
+	}
+}
+
+func provideFromBlockservice(ctx context.Context, bs BlockService, blks ...blocks.Block) {
+	if bbs, ok := bs.(*blockService); ok {
+		bbs.provide(ctx, blks...)
+	}
+}
+
+// grabPolicyFromBlockservice never returns nil
+func grabPolicyFromBlockservice(bs BlockService) *verifcid.Policy {
+	if pbs, ok := bs.(PolicedBlockService); ok {
+		return pbs.Policy()
+	}
 	if bbs, ok := bs.(BoundedBlockService); ok {
-		return bbs.Allowlist()
+		return &verifcid.Policy{Hashes: bbs.Allowlist()}
 	}
-	return verifcid.DefaultAllowlist
+	return verifcid.DefaultPolicy
 }
@@ -2,6 +2,7 @@ package blockservice
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	blockstore "github.com/ipfs/boxo/blockstore"
@@ -253,6 +254,55 @@ func TestNilExchange(t *testing.T) {
 	}
 }
 
+type recordingProvider struct {
+	lk       sync.Mutex
+	provided []cid.Cid
+}
+
+func (p *recordingProvider) Provide(c cid.Cid) error {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	p.provided = append(p.provided, c)
+	return nil
+}
+
+func (p *recordingProvider) cids() []cid.Cid {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	return append([]cid.Cid(nil), p.provided...)
+}
+
+func TestProvideOnPut(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	ctx := context.Background()
+	bgen := butil.NewBlockGenerator()
+	blks := bgen.Blocks(5)
+	skipped := blks[4]
+
+	exchbstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	a.NoError(exchbstore.PutMany(ctx, blks[2:]))
+
+	prov := &recordingProvider{}
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bserv := New(bstore, offline.Exchange(exchbstore), WithProvider(prov), WithProvideFilter(func(_ context.Context, c cid.Cid) bool {
+		return c != skipped.Cid()
+	}))
+
+	a.NoError(bserv.AddBlock(ctx, blks[0]))
+	a.NoError(bserv.AddBlocks(ctx, blks[1:2]))
+	_, err := bserv.GetBlock(ctx, blks[2].Cid())
+	a.NoError(err)
+	for range bserv.GetBlocks(ctx, []cid.Cid{blks[3].Cid(), skipped.Cid()}) {
+	}
+
+	// blocks already in the blockstore are not provided again
+	a.NoError(bserv.AddBlock(ctx, blks[0]))
+
+	a.ElementsMatch([]cid.Cid{blks[0].Cid(), blks[1].Cid(), blks[2].Cid(), blks[3].Cid()}, prov.cids())
+}
+
 func TestAllowlist(t *testing.T) {
 	t.Parallel()
 	a := assert.New(t)
@@ -289,6 +339,27 @@ func TestAllowlist(t *testing.T) {
 	check(NewSession(ctx, blockservice).GetBlock)
 }
 
+func TestPolicy(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	v0 := blocks.NewBlock([]byte("v0"))
+	a.NoError(bs.Put(ctx, v0))
+	v1, err := blocks.NewBlockWithCid(v0.RawData(), cid.NewCidV1(cid.DagProtobuf, v0.Cid().Hash()))
+	a.NoError(err)
+	a.NoError(bs.Put(ctx, v1))
+
+	blockservice := New(bs, nil, WithPolicy(&verifcid.Policy{DisallowCIDv0: true}))
+	for _, getBlock := range []func(context.Context, cid.Cid) (blocks.Block, error){blockservice.GetBlock, NewSession(ctx, blockservice).GetBlock} {
+		_, err := getBlock(ctx, v0.Cid())
+		a.ErrorIs(err, verifcid.ErrCIDv0NotAllowed)
+		_, err = getBlock(ctx, v1.Cid())
+		a.NoError(err)
+	}
+	a.Error(blockservice.AddBlock(ctx, v0))
+}
+
 type fakeIsNewSessionCreateExchange struct {
 	ses                 exchange.Fetcher
 	newSessionWasCalled bool
@@ -353,3 +424,28 @@ func TestContextSession(t *testing.T) {
 		"session must be deduped in all invocations on the same context",
 	)
 }
+
+func TestContextSessionLifetime(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	bgen := butil.NewBlockGenerator()
+	block := bgen.Next()
+
+	bs := blockstore.NewBlockstore(ds.NewMapDatastore())
+	a.NoError(bs.Put(context.Background(), block))
+	exch := offline.Exchange(bs)
+	session := offline.Exchange(blockstore.NewBlockstore(ds.NewMapDatastore()))
+	sessionExch := &fakeSessionExchange{Interface: exch, session: session}
+
+	service := New(blockstore.NewBlockstore(ds.NewMapDatastore()), sessionExch)
+
+	sesCtx, cancel := context.WithCancel(context.Background())
+	ses := NewSession(ContextWithSession(sesCtx, service), service)
+	cancel()
+
+	b, err := ses.GetBlock(context.Background(), block.Cid())
+	a.NoError(err, "exchange should be used directly once the session context is done")
+	a.Equal(block.RawData(), b.RawData())
+	a.Equal(sessionExch, ses.ses, "no exchange session should be created on a done context")
+}
@@ -0,0 +1,38 @@
+package blockservice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ipfs/go-cid"
+)
+
+// ErrDeletionVetoed is returned by [BlockService.DeleteBlocks] when a
+// [DeleteHook] vetoed the deletion of a block.
+var ErrDeletionVetoed = errors.New("block deletion vetoed")
+
+// DeleteHook is consulted before the blockservice deletes blocks, so that the
+// pinners and filestores referencing blocks can veto their deletion, see
+// [WithDeleteHook].
+type DeleteHook interface {
+	// AllowDelete returns an error if the block must not be deleted, for
+	// example because it is pinned or still referenced.
+	AllowDelete(ctx context.Context, c cid.Cid) error
+}
+
+// DeleteHookFunc is a [DeleteHook] calling the function.
+type DeleteHookFunc func(ctx context.Context, c cid.Cid) error
+
+// AllowDelete calls f.
+func (f DeleteHookFunc) AllowDelete(ctx context.Context, c cid.Cid) error {
+	return f(ctx, c)
+}
+
+// WithDeleteHook adds hooks consulted before blocks are deleted by
+// [BlockService.DeleteBlock] and [BlockService.DeleteBlocks]. Every hook must
+// allow the deletion of every block, otherwise no block is deleted.
+func WithDeleteHook(hooks ...DeleteHook) Option {
+	return func(bs *blockService) {
+		bs.deleteHooks = append(bs.deleteHooks, hooks...)
+	}
+}
@@ -0,0 +1,71 @@
+package blockservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	blockstore "github.com/ipfs/boxo/blockstore"
+	exchange "github.com/ipfs/boxo/exchange"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	butil "github.com/ipfs/go-ipfs-blocksutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ exchange.DeletionNotifier = (*deletionRecordingExchange)(nil)
+
+type deletionRecordingExchange struct {
+	exchange.Interface
+	deleted []cid.Cid
+}
+
+func (e *deletionRecordingExchange) NotifyDeletedBlocks(ctx context.Context, ks ...cid.Cid) error {
+	e.deleted = append(e.deleted, ks...)
+	return nil
+}
+
+func TestDeleteBlocks(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	ctx := context.Background()
+	bgen := butil.NewBlockGenerator()
+	blks := bgen.Blocks(4)
+	pinned := blks[3].Cid()
+	errPinned := errors.New("pinned")
+
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	ex := &deletionRecordingExchange{Interface: offline.Exchange(bstore)}
+	bserv := New(bstore, ex, WithDeleteHook(DeleteHookFunc(func(_ context.Context, c cid.Cid) error {
+		if c == pinned {
+			return errPinned
+		}
+		return nil
+	})))
+	a.NoError(bserv.AddBlocks(ctx, blks))
+
+	a.NoError(bserv.DeleteBlocks(ctx, []cid.Cid{blks[0].Cid(), blks[1].Cid()}))
+	a.NoError(bserv.DeleteBlock(ctx, blks[2].Cid()))
+	a.Equal([]cid.Cid{blks[0].Cid(), blks[1].Cid(), blks[2].Cid()}, ex.deleted)
+	for _, b := range blks[:3] {
+		has, err := bstore.Has(ctx, b.Cid())
+		a.NoError(err)
+		a.False(has)
+	}
+
+	// a vetoed deletion deletes no block
+	a.NoError(bserv.AddBlock(ctx, blks[0]))
+	err := bserv.DeleteBlocks(ctx, []cid.Cid{blks[0].Cid(), pinned})
+	a.ErrorIs(err, ErrDeletionVetoed)
+	a.ErrorIs(err, errPinned)
+	a.ErrorIs(bserv.DeleteBlock(ctx, pinned), ErrDeletionVetoed)
+	for _, b := range []cid.Cid{blks[0].Cid(), pinned} {
+		has, err := bstore.Has(ctx, b)
+		a.NoError(err)
+		a.True(has)
+	}
+	a.Len(ex.deleted, 3)
+}
@@ -0,0 +1,136 @@
+package blockservice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ipfs/boxo/blockservice/internal"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multicodec"
+)
+
+// GetOption constrains the block returned by GetBlockWithOpts.
+type GetOption func(*getOptions)
+
+type getOptions struct {
+	maxSize       int
+	codecs        []uint64
+	requireCached bool
+}
+
+// ExpectMaxSize refuses blocks bigger than n bytes with a
+// [*BlockTooLargeError]. The size of blocks in the blockstore is checked
+// before reading them, but fetched blocks can only be checked once received,
+// they are then refused before being written to the blockstore.
+func ExpectMaxSize(n int) GetOption {
+	return func(o *getOptions) {
+		o.maxSize = n
+	}
+}
+
+// ExpectCodecs refuses CIDs with other codecs than the given ones with an
+// [*UnexpectedCodecError], without looking for the block.
+func ExpectCodecs(codecs ...uint64) GetOption {
+	return func(o *getOptions) {
+		o.codecs = codecs
+	}
+}
+
+// RequireCached only looks for the block in the blockstore, and returns an
+// [ipld.ErrNotFound] error without fetching it from the exchange if it is not
+// there.
+func RequireCached() GetOption {
+	return func(o *getOptions) {
+		o.requireCached = true
+	}
+}
+
+// BlockTooLargeError is returned by GetBlockWithOpts for blocks bigger than
+// the size set with [ExpectMaxSize].
+type BlockTooLargeError struct {
+	Cid     cid.Cid
+	Size    int
+	MaxSize int
+}
+
+func (e *BlockTooLargeError) Error() string {
+	return fmt.Sprintf("block %s is %d bytes long, more than the expected %d bytes", e.Cid, e.Size, e.MaxSize)
+}
+
+// UnexpectedCodecError is returned by GetBlockWithOpts for CIDs with codecs
+// not set with [ExpectCodecs].
+type UnexpectedCodecError struct {
+	Cid      cid.Cid
+	Expected []uint64
+}
+
+func (e *UnexpectedCodecError) Error() string {
+	expected := make([]string, len(e.Expected))
+	for i, codec := range e.Expected {
+		expected[i] = multicodec.Code(codec).String()
+	}
+	return fmt.Sprintf("block %s has codec %s, expected %s", e.Cid, multicodec.Code(e.Cid.Type()), strings.Join(expected, " or "))
+}
+
+func (o *getOptions) checkCodec(c cid.Cid) error {
+	if len(o.codecs) == 0 {
+		return nil
+	}
+	for _, codec := range o.codecs {
+		if c.Type() == codec {
+			return nil
+		}
+	}
+	return &UnexpectedCodecError{Cid: c, Expected: o.codecs}
+}
+
+func (o *getOptions) checkSize(c cid.Cid, size int) error {
+	if o.maxSize > 0 && size > o.maxSize {
+		return &BlockTooLargeError{Cid: c, Size: size, MaxSize: o.maxSize}
+	}
+	return nil
+}
+
+func newGetOptions(opts []GetOption) *getOptions {
+	o := &getOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// BlockGetterWithOpts is implemented by the [BlockService] returned by [New]
+// and by [Session], to get blocks satisfying some constraints.
+type BlockGetterWithOpts interface {
+	// GetBlockWithOpts gets the requested block, as long as it satisfies
+	// the constraints set by the options.
+	GetBlockWithOpts(ctx context.Context, c cid.Cid, opts ...GetOption) (blocks.Block, error)
+}
+
+var _ BlockGetterWithOpts = (*blockService)(nil)
+var _ BlockGetterWithOpts = (*Session)(nil)
+
+// GetBlockWithOpts is like GetBlock, with constraints on the block returned.
+func (s *blockService) GetBlockWithOpts(ctx context.Context, c cid.Cid, opts ...GetOption) (blocks.Block, error) {
+	if ses := grabSessionFromContext(ctx, s); ses != nil {
+		return ses.GetBlockWithOpts(ctx, c, opts...)
+	}
+
+	ctx, span := internal.StartSpan(ctx, "blockService.GetBlockWithOpts", trace.WithAttributes(attribute.Stringer("CID", c)))
+	defer span.End()
+
+	return getBlock(ctx, c, s, s.getExchangeFetcher, newGetOptions(opts))
+}
+
+// GetBlockWithOpts is like GetBlock, with constraints on the block returned.
+func (s *Session) GetBlockWithOpts(ctx context.Context, c cid.Cid, opts ...GetOption) (blocks.Block, error) {
+	ctx, span := internal.StartSpan(ctx, "Session.GetBlockWithOpts", trace.WithAttributes(attribute.Stringer("CID", c)))
+	defer span.End()
+
+	return getBlock(ctx, c, s.bs, s.grabSession, newGetOptions(opts))
+}
@@ -0,0 +1,75 @@
+package blockservice
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	blockstore "github.com/ipfs/boxo/blockstore"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBlockWithOpts(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	ctx := context.Background()
+
+	newBlock := func(codec uint64, size int) blocks.Block {
+		data := bytes.Repeat([]byte{byte(size)}, size)
+		mh, err := multihash.Sum(data, multihash.SHA2_256, -1)
+		a.NoError(err)
+		b, err := blocks.NewBlockWithCid(data, cid.NewCidV1(codec, mh))
+		a.NoError(err)
+		return b
+	}
+	localSmall := newBlock(cid.DagCBOR, 100)
+	localBig := newBlock(cid.Raw, 4000)
+	remoteSmall := newBlock(cid.Raw, 200)
+	remoteBig := newBlock(cid.Raw, 5000)
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	a.NoError(bs.PutMany(ctx, []blocks.Block{localSmall, localBig}))
+	exchbs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	a.NoError(exchbs.PutMany(ctx, []blocks.Block{remoteSmall, remoteBig}))
+	bserv := New(bs, offline.Exchange(exchbs))
+
+	for _, bg := range []BlockGetterWithOpts{bserv.(BlockGetterWithOpts), NewSession(ctx, bserv)} {
+		b, err := bg.GetBlockWithOpts(ctx, localSmall.Cid(), ExpectMaxSize(1000), ExpectCodecs(cid.DagCBOR, cid.DagJSON))
+		a.NoError(err)
+		a.Equal(localSmall.RawData(), b.RawData())
+
+		_, err = bg.GetBlockWithOpts(ctx, localSmall.Cid(), ExpectCodecs(cid.Raw))
+		var codecErr *UnexpectedCodecError
+		a.ErrorAs(err, &codecErr)
+		a.Equal(localSmall.Cid(), codecErr.Cid)
+		a.EqualError(err, "block "+localSmall.Cid().String()+" has codec dag-cbor, expected raw")
+
+		var sizeErr *BlockTooLargeError
+		_, err = bg.GetBlockWithOpts(ctx, localBig.Cid(), ExpectMaxSize(1000))
+		a.ErrorAs(err, &sizeErr)
+		a.Equal(4000, sizeErr.Size)
+
+		_, err = bg.GetBlockWithOpts(ctx, remoteBig.Cid(), ExpectMaxSize(1000))
+		a.ErrorAs(err, &sizeErr)
+		a.Equal(5000, sizeErr.Size)
+		has, err := bs.Has(ctx, remoteBig.Cid())
+		a.NoError(err)
+		a.False(has, "refused blocks must not be cached")
+
+		_, err = bg.GetBlockWithOpts(ctx, remoteSmall.Cid(), RequireCached())
+		a.True(ipld.IsNotFound(err))
+	}
+
+	// fetched blocks are cached as usual
+	_, err := bserv.(BlockGetterWithOpts).GetBlockWithOpts(ctx, remoteSmall.Cid(), ExpectMaxSize(1000))
+	a.NoError(err)
+	_, err = bserv.(BlockGetterWithOpts).GetBlockWithOpts(ctx, remoteSmall.Cid(), RequireCached())
+	a.NoError(err)
+}
@@ -0,0 +1,174 @@
+package blockservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/boxo/blockservice/internal"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	car "github.com/ipld/go-car/v2"
+)
+
+// DefaultImportMaxBlockSize is the largest block accepted by [ImportCAR]
+// unless set otherwise with [ImportMaxBlockSize], the largest block
+// exchanged with bitswap.
+const DefaultImportMaxBlockSize = 2 << 20
+
+// defaultImportBatchSize is the number of blocks written at once by ImportCAR.
+const defaultImportBatchSize = 128
+
+// ErrImportTooLarge is returned by [ImportCAR] when the blocks of the CAR add
+// up to more than the size set with [ImportMaxTotalSize].
+var ErrImportTooLarge = errors.New("CAR is larger than the import limit")
+
+// ImportOption configures [ImportCAR].
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	maxBlockSize int
+	maxTotalSize int64
+	batchSize    int
+}
+
+// ImportMaxBlockSize refuses blocks bigger than n bytes with a
+// [*BlockTooLargeError]. Defaults to [DefaultImportMaxBlockSize].
+func ImportMaxBlockSize(n int) ImportOption {
+	return func(o *importOptions) {
+		o.maxBlockSize = n
+	}
+}
+
+// ImportMaxTotalSize stops the import with [ErrImportTooLarge] once the blocks
+// of the CAR add up to more than n bytes, duplicates included. Unlimited by
+// default.
+func ImportMaxTotalSize(n int64) ImportOption {
+	return func(o *importOptions) {
+		o.maxTotalSize = n
+	}
+}
+
+// ImportBatchSize sets how many blocks are added to the blockservice at once.
+func ImportBatchSize(n int) ImportOption {
+	return func(o *importOptions) {
+		o.batchSize = n
+	}
+}
+
+// ImportResult reports what [ImportCAR] read.
+type ImportResult struct {
+	// Roots are the roots in the header of the CAR.
+	Roots []cid.Cid
+	// Blocks is the number of blocks added to the blockservice.
+	Blocks int
+	// Duplicates is the number of blocks skipped, because they were
+	// already in the blockstore or earlier in the CAR.
+	Duplicates int
+	// Bytes is the size of the blocks added to the blockservice.
+	Bytes int64
+}
+
+// ImportCAR adds the blocks of a CARv1 or CARv2 stream to the blockservice.
+// The data of every block is checked against its CID, and its CID against the
+// [verifcid.Policy] of the blockservice, before any block of its batch is
+// added. Blocks already in the blockstore, or repeated in the CAR, are
+// skipped.
+//
+// On error, the blocks of the previous batches stay added, and the result
+// counts them.
+func ImportCAR(ctx context.Context, bs BlockService, r io.Reader, opts ...ImportOption) (ImportResult, error) {
+	ctx, span := internal.StartSpan(ctx, "ImportCAR")
+	defer span.End()
+
+	o := importOptions{
+		maxBlockSize: DefaultImportMaxBlockSize,
+		batchSize:    defaultImportBatchSize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.batchSize < 1 {
+		o.batchSize = 1
+	}
+
+	carOpts := []car.Option{car.WithTrustedCAR(false)}
+	if o.maxBlockSize > 0 {
+		// leave room for the CID in the section
+		carOpts = append(carOpts, car.MaxAllowedSectionSize(uint64(o.maxBlockSize)+1024))
+	}
+	br, err := car.NewBlockReader(r, carOpts...)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("cannot read CAR header: %w", err)
+	}
+
+	var (
+		res    = ImportResult{Roots: br.Roots}
+		policy = grabPolicyFromBlockservice(bs)
+		seen   = cid.NewSet()
+		batch  = make([]blocks.Block, 0, o.batchSize)
+		total  int64
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := bs.AddBlocks(ctx, batch); err != nil {
+			return err
+		}
+		for _, blk := range batch {
+			res.Blocks++
+			res.Bytes += int64(len(blk.RawData()))
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		blk, err := br.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("cannot read CAR block: %w", err)
+		}
+
+		c := blk.Cid()
+		size := len(blk.RawData())
+		if o.maxBlockSize > 0 && size > o.maxBlockSize {
+			return res, &BlockTooLargeError{Cid: c, Size: size, MaxSize: o.maxBlockSize}
+		}
+		total += int64(size)
+		if o.maxTotalSize > 0 && total > o.maxTotalSize {
+			return res, ErrImportTooLarge
+		}
+		if err := policy.Validate(c); err != nil {
+			return res, err
+		}
+
+		if !seen.Visit(c) {
+			res.Duplicates++
+			continue
+		}
+		has, err := bs.Blockstore().Has(ctx, c)
+		if err != nil {
+			return res, err
+		}
+		if has {
+			res.Duplicates++
+			continue
+		}
+
+		batch = append(batch, blk)
+		if len(batch) >= o.batchSize {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, flush()
+}
@@ -0,0 +1,114 @@
+package blockservice
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	butil "github.com/ipfs/go-ipfs-blocksutil"
+	car "github.com/ipld/go-car/v2"
+	carbs "github.com/ipld/go-car/v2/blockstore"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCAR writes the blocks, in order, to a CAR file rooted at the first
+// block.
+func writeTestCAR(t *testing.T, v1 bool, blks ...blocks.Block) string {
+	t.Helper()
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "test.car")
+	var opts []car.Option
+	if v1 {
+		opts = append(opts, car.WriteAsCarV1(true))
+	}
+	// allow duplicates, to write them as they are
+	opts = append(opts, carbs.AllowDuplicatePuts(true))
+	rw, err := carbs.OpenReadWrite(path, []cid.Cid{blks[0].Cid()}, opts...)
+	require.NoError(t, err)
+	for _, blk := range blks {
+		require.NoError(t, rw.Put(ctx, blk))
+	}
+	require.NoError(t, rw.Finalize())
+	return path
+}
+
+func importTestCAR(t *testing.T, bserv BlockService, path string, opts ...ImportOption) (ImportResult, error) {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	return ImportCAR(context.Background(), bserv, f, opts...)
+}
+
+func TestImportCAR(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	for _, v1 := range []bool{true, false} {
+		bstore := newTestBlockstore()
+		bserv := New(bstore, nil)
+		bgen := butil.NewBlockGenerator()
+
+		a, b, c := bgen.Next(), bgen.Next(), bgen.Next()
+		require.NoError(t, bserv.AddBlock(ctx, c))
+
+		path := writeTestCAR(t, v1, a, b, a, c)
+		res, err := importTestCAR(t, bserv, path, ImportBatchSize(1))
+		require.NoError(t, err)
+		require.Equal(t, []cid.Cid{a.Cid()}, res.Roots)
+		require.Equal(t, 2, res.Blocks)
+		require.Equal(t, 2, res.Duplicates)
+		require.Equal(t, int64(len(a.RawData())+len(b.RawData())), res.Bytes)
+
+		for _, blk := range []blocks.Block{a, b, c} {
+			has, err := bstore.Has(ctx, blk.Cid())
+			require.NoError(t, err)
+			require.True(t, has)
+		}
+	}
+}
+
+func TestImportCARLimits(t *testing.T) {
+	t.Parallel()
+
+	bgen := butil.NewBlockGenerator()
+	small := bgen.Next()
+	big := blocks.NewBlock(make([]byte, 1024))
+	path := writeTestCAR(t, true, small, big)
+
+	bserv := New(newTestBlockstore(), nil)
+	res, err := importTestCAR(t, bserv, path, ImportMaxBlockSize(512))
+	var tooLarge *BlockTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	require.Equal(t, big.Cid(), tooLarge.Cid)
+	// the small block was not added, as it was in the same batch
+	require.Equal(t, 0, res.Blocks)
+
+	bserv = New(newTestBlockstore(), nil)
+	res, err = importTestCAR(t, bserv, path, ImportMaxTotalSize(1000), ImportBatchSize(1))
+	require.ErrorIs(t, err, ErrImportTooLarge)
+	require.Equal(t, 1, res.Blocks)
+}
+
+func TestImportCARCorrupted(t *testing.T) {
+	t.Parallel()
+
+	bgen := butil.NewBlockGenerator()
+	blk := bgen.Next()
+	corrupted, err := blocks.NewBlockWithCid([]byte("not the data"), blk.Cid())
+	require.NoError(t, err)
+	path := writeTestCAR(t, true, corrupted)
+
+	bstore := newTestBlockstore()
+	_, err = importTestCAR(t, New(bstore, nil), path)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrImportTooLarge))
+	has, err := bstore.Has(context.Background(), blk.Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+}
@@ -0,0 +1,294 @@
+package blockservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/boxo/blockstore"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// Consistency controls when a write replicated to several blockstores is
+// considered successful, see [WithReplicas].
+type Consistency int
+
+const (
+	// ConsistencyAll requires the write to succeed on the primary blockstore
+	// and on every replica.
+	ConsistencyAll Consistency = iota
+	// ConsistencyQuorum requires the write to succeed on a majority of the
+	// blockstores, the primary included.
+	ConsistencyQuorum
+	// ConsistencyAsync only waits for the primary blockstore, replicas are
+	// written in the background, in order, and failures are logged. Writes
+	// block once [AsyncReplicationQueueSize] writes are waiting.
+	ConsistencyAsync
+)
+
+// AsyncReplicationQueueSize is the number of writes waiting to be replicated
+// in the background with [ConsistencyAsync] before new writes block.
+const AsyncReplicationQueueSize = 1024
+
+func (c Consistency) String() string {
+	switch c {
+	case ConsistencyAll:
+		return "all"
+	case ConsistencyQuorum:
+		return "quorum"
+	case ConsistencyAsync:
+		return "async"
+	default:
+		return fmt.Sprintf("Consistency(%d)", int(c))
+	}
+}
+
+// ErrReplication is returned when a write did not reach enough blockstores to
+// satisfy the configured [Consistency].
+var ErrReplication = errors.New("replicated write failed")
+
+// WithReplicas makes the blockservice write every block to the given replica
+// blockstores in addition to the primary one passed to [New]. Writes succeed
+// according to consistency. Reads try the primary blockstore first and then
+// each replica in order. Has only checks the primary blockstore, so that
+// blocks missing from it are written again. Deletes apply to every
+// blockstore, after the pending background writes.
+//
+// Blocks already present in the primary blockstore are not written again
+// unless [WriteThrough] is used, which is what shadow migrations want.
+func WithReplicas(consistency Consistency, replicas ...blockstore.Blockstore) Option {
+	return func(bs *blockService) {
+		bs.consistency = consistency
+		bs.replicas = append(bs.replicas, replicas...)
+	}
+}
+
+// replicatedBlockstore is a blockstore writing to a primary blockstore and a
+// set of replicas.
+type replicatedBlockstore struct {
+	primary     blockstore.Blockstore
+	replicas    []blockstore.Blockstore
+	consistency Consistency
+
+	// viewer is the primary blockstore, if it is a blockstore.Viewer
+	viewer blockstore.Viewer
+
+	// queue holds the background operations on the replicas in async mode,
+	// applied in order by a single worker
+	queue chan func(context.Context, blockstore.Blockstore) error
+	// closeLk guards closed, writers hold it for reading while queueing
+	closeLk sync.RWMutex
+	closed  bool
+	done    chan struct{}
+}
+
+var (
+	_ blockstore.Blockstore = (*replicatedBlockstore)(nil)
+	_ blockstore.Viewer     = (*replicatedBlockstore)(nil)
+)
+
+func newReplicatedBlockstore(primary blockstore.Blockstore, replicas []blockstore.Blockstore, consistency Consistency) *replicatedBlockstore {
+	r := &replicatedBlockstore{
+		primary:     primary,
+		replicas:    replicas,
+		consistency: consistency,
+	}
+	if v, ok := primary.(blockstore.Viewer); ok {
+		r.viewer = v
+	}
+	if consistency == ConsistencyAsync {
+		r.queue = make(chan func(context.Context, blockstore.Blockstore) error, AsyncReplicationQueueSize)
+		r.done = make(chan struct{})
+		go r.worker()
+	}
+	return r
+}
+
+// worker applies the queued operations to the replicas.
+func (r *replicatedBlockstore) worker() {
+	defer close(r.done)
+	ctx := context.Background()
+	for op := range r.queue {
+		r.replicate(ctx, op)
+	}
+}
+
+// replicate applies op to every replica, logging failures.
+func (r *replicatedBlockstore) replicate(ctx context.Context, op func(context.Context, blockstore.Blockstore) error) {
+	for i, replica := range r.replicas {
+		if err := op(ctx, replica); err != nil {
+			logger.Errorw("async replication failed", "replica", i, "error", err)
+		}
+	}
+}
+
+// enqueue queues op for the replicas, waiting for room in the queue. Once the
+// blockstore is closed, op is applied synchronously.
+func (r *replicatedBlockstore) enqueue(ctx context.Context, op func(context.Context, blockstore.Blockstore) error) error {
+	r.closeLk.RLock()
+	defer r.closeLk.RUnlock()
+	if r.closed {
+		r.replicate(ctx, op)
+		return nil
+	}
+	select {
+	case r.queue <- op:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// all returns the primary blockstore followed by the replicas
+func (r *replicatedBlockstore) all() []blockstore.Blockstore {
+	return append([]blockstore.Blockstore{r.primary}, r.replicas...)
+}
+
+func (r *replicatedBlockstore) Put(ctx context.Context, b blocks.Block) error {
+	return r.write(ctx, func(ctx context.Context, bs blockstore.Blockstore) error {
+		return bs.Put(ctx, b)
+	})
+}
+
+func (r *replicatedBlockstore) PutMany(ctx context.Context, bs []blocks.Block) error {
+	// the caller may reuse the slice while async replication is running
+	bs = append([]blocks.Block(nil), bs...)
+	return r.write(ctx, func(ctx context.Context, store blockstore.Blockstore) error {
+		return store.PutMany(ctx, bs)
+	})
+}
+
+func (r *replicatedBlockstore) write(ctx context.Context, put func(context.Context, blockstore.Blockstore) error) error {
+	if r.consistency == ConsistencyAsync {
+		if err := put(ctx, r.primary); err != nil {
+			return err
+		}
+		return r.enqueue(ctx, put)
+	}
+
+	stores := r.all()
+	errs := make([]error, len(stores))
+	var wg sync.WaitGroup
+	for i, store := range stores {
+		wg.Add(1)
+		go func(i int, store blockstore.Blockstore) {
+			defer wg.Done()
+			errs[i] = put(ctx, store)
+		}(i, store)
+	}
+	wg.Wait()
+
+	var failed int
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	required := len(stores)
+	if r.consistency == ConsistencyQuorum {
+		required = len(stores)/2 + 1
+	}
+	if len(stores)-failed < required {
+		return fmt.Errorf("%w: wrote to %d of %d blockstores, %d required: %w", ErrReplication, len(stores)-failed, len(stores), required, errors.Join(errs...))
+	}
+	if failed > 0 {
+		logger.Warnw("replicated write did not reach every blockstore", "failed", failed, "error", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (r *replicatedBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	blk, err := r.primary.Get(ctx, c)
+	if err == nil {
+		return blk, nil
+	}
+	for _, replica := range r.replicas {
+		if blk, rerr := replica.Get(ctx, c); rerr == nil {
+			return blk, nil
+		}
+	}
+	return nil, err
+}
+
+func (r *replicatedBlockstore) View(ctx context.Context, c cid.Cid, callback func([]byte) error) error {
+	if r.viewer != nil {
+		err := r.viewer.View(ctx, c, callback)
+		if !ipld.IsNotFound(err) {
+			return err
+		}
+	}
+	blk, err := r.Get(ctx, c)
+	if err != nil {
+		return err
+	}
+	return callback(blk.RawData())
+}
+
+func (r *replicatedBlockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	size, err := r.primary.GetSize(ctx, c)
+	if err == nil {
+		return size, nil
+	}
+	for _, replica := range r.replicas {
+		if size, rerr := replica.GetSize(ctx, c); rerr == nil {
+			return size, nil
+		}
+	}
+	return -1, err
+}
+
+// Has only checks the primary blockstore, so that blocks only held by a
+// replica are written to the primary again.
+func (r *replicatedBlockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	return r.primary.Has(ctx, c)
+}
+
+func (r *replicatedBlockstore) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	if r.consistency == ConsistencyAsync {
+		if err := r.primary.DeleteBlock(ctx, c); err != nil {
+			return err
+		}
+		// queued after the pending writes, so they cannot bring the block back
+		return r.enqueue(ctx, func(ctx context.Context, store blockstore.Blockstore) error {
+			return store.DeleteBlock(ctx, c)
+		})
+	}
+
+	var errs []error
+	for _, store := range r.all() {
+		if err := store.DeleteBlock(ctx, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AllKeysChan returns the keys of the primary blockstore.
+func (r *replicatedBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	return r.primary.AllKeysChan(ctx)
+}
+
+func (r *replicatedBlockstore) HashOnRead(enabled bool) {
+	for _, store := range r.all() {
+		store.HashOnRead(enabled)
+	}
+}
+
+// wait blocks until background replication is done. Later writes are
+// replicated synchronously.
+func (r *replicatedBlockstore) wait() {
+	if r.queue == nil {
+		return
+	}
+	r.closeLk.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.closeLk.Unlock()
+	<-r.done
+}
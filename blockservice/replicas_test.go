@@ -0,0 +1,148 @@
+package blockservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	blockstore "github.com/ipfs/boxo/blockstore"
+	blocks "github.com/ipfs/go-block-format"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	butil "github.com/ipfs/go-ipfs-blocksutil"
+	"github.com/stretchr/testify/require"
+)
+
+var errBrokenBlockstore = errors.New("broken blockstore")
+
+type brokenBlockstore struct {
+	blockstore.Blockstore
+}
+
+func (brokenBlockstore) Put(context.Context, blocks.Block) error {
+	return errBrokenBlockstore
+}
+
+func (brokenBlockstore) PutMany(context.Context, []blocks.Block) error {
+	return errBrokenBlockstore
+}
+
+func newTestBlockstore() blockstore.Blockstore {
+	return blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+}
+
+func TestReplicasWriteAll(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	primary, replica1, replica2 := newTestBlockstore(), newTestBlockstore(), newTestBlockstore()
+	bserv := New(primary, nil, WithReplicas(ConsistencyAll, replica1, replica2))
+	bgen := butil.NewBlockGenerator()
+
+	block := bgen.Next()
+	require.NoError(t, bserv.AddBlock(ctx, block))
+	more := []blocks.Block{bgen.Next(), bgen.Next()}
+	require.NoError(t, bserv.AddBlocks(ctx, more))
+
+	for _, bs := range []blockstore.Blockstore{primary, replica1, replica2} {
+		for _, b := range append(more, block) {
+			has, err := bs.Has(ctx, b.Cid())
+			require.NoError(t, err)
+			require.True(t, has)
+		}
+	}
+
+	require.NoError(t, bserv.DeleteBlock(ctx, block.Cid()))
+	for _, bs := range []blockstore.Blockstore{primary, replica1, replica2} {
+		has, err := bs.Has(ctx, block.Cid())
+		require.NoError(t, err)
+		require.False(t, has)
+	}
+
+	broken := New(primary, nil, WithReplicas(ConsistencyAll, replica1, brokenBlockstore{newTestBlockstore()}))
+	err := broken.AddBlock(ctx, bgen.Next())
+	require.ErrorIs(t, err, ErrReplication)
+	require.ErrorIs(t, err, errBrokenBlockstore)
+}
+
+func TestReplicasWriteQuorum(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bgen := butil.NewBlockGenerator()
+
+	primary, replica := newTestBlockstore(), newTestBlockstore()
+	bserv := New(primary, nil, WithReplicas(ConsistencyQuorum, replica, brokenBlockstore{newTestBlockstore()}))
+	block := bgen.Next()
+	require.NoError(t, bserv.AddBlock(ctx, block))
+	has, err := replica.Has(ctx, block.Cid())
+	require.NoError(t, err)
+	require.True(t, has)
+
+	bserv = New(primary, nil, WithReplicas(ConsistencyQuorum, brokenBlockstore{replica}, brokenBlockstore{newTestBlockstore()}))
+	require.ErrorIs(t, bserv.AddBlock(ctx, bgen.Next()), ErrReplication)
+}
+
+func TestReplicasWriteAsync(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bgen := butil.NewBlockGenerator()
+
+	primary, replica := newTestBlockstore(), newTestBlockstore()
+	bserv := New(primary, nil, WithReplicas(ConsistencyAsync, replica, brokenBlockstore{newTestBlockstore()}))
+	block, deleted := bgen.Next(), bgen.Next()
+	require.NoError(t, bserv.AddBlock(ctx, block))
+
+	// The delete is applied to the replicas after the pending write.
+	require.NoError(t, bserv.AddBlock(ctx, deleted))
+	require.NoError(t, bserv.DeleteBlock(ctx, deleted.Cid()))
+
+	// Close waits for the background replication
+	require.NoError(t, bserv.Close())
+	has, err := replica.Has(ctx, block.Cid())
+	require.NoError(t, err)
+	require.True(t, has)
+	has, err = replica.Has(ctx, deleted.Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+
+	bserv = New(brokenBlockstore{primary}, nil, WithReplicas(ConsistencyAsync, replica))
+	require.ErrorIs(t, bserv.AddBlock(ctx, bgen.Next()), errBrokenBlockstore)
+}
+
+func TestReplicasReadFallback(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bgen := butil.NewBlockGenerator()
+
+	primary, replica := newTestBlockstore(), newTestBlockstore()
+	block := bgen.Next()
+	require.NoError(t, replica.Put(ctx, block))
+
+	bserv := New(primary, nil, WithReplicas(ConsistencyAll, replica))
+	got, err := bserv.GetBlock(ctx, block.Cid())
+	require.NoError(t, err)
+	require.Equal(t, block.RawData(), got.RawData())
+
+	size, err := bserv.Blockstore().GetSize(ctx, block.Cid())
+	require.NoError(t, err)
+	require.Equal(t, len(block.RawData()), size)
+
+	var viewed []byte
+	require.NoError(t, bserv.Blockstore().(blockstore.Viewer).View(ctx, block.Cid(), func(data []byte) error {
+		viewed = append(viewed, data...)
+		return nil
+	}))
+	require.Equal(t, block.RawData(), viewed)
+
+	// Has only answers for the primary, so the block is written to it again.
+	has, err := bserv.Blockstore().Has(ctx, block.Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+	require.NoError(t, bserv.AddBlock(ctx, block))
+	has, err = primary.Has(ctx, block.Cid())
+	require.NoError(t, err)
+	require.True(t, has)
+
+	_, err = bserv.GetBlock(ctx, bgen.Next().Cid())
+	require.Error(t, err)
+}
@@ -0,0 +1,295 @@
+package blockstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	metrics "github.com/ipfs/go-metrics-interface"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultCompressionLevel is the default zstd level of a
+// [CompressedBlockstore].
+const DefaultCompressionLevel = 3
+
+// DefaultDictionarySize is the default size of the dictionaries built by
+// [TrainDictionary].
+const DefaultDictionarySize = 64 << 10
+
+// maxDecompressedSize bounds the memory used to decompress a single block.
+const maxDecompressedSize = 64 << 20
+
+// compressedPrefix starts the stored data of the compressed blocks, followed
+// by their zstd frame.
+const compressedPrefix = 0xc5
+
+// CompressedOpts wraps options for [NewCompressedBlockstore].
+type CompressedOpts struct {
+	// Level is the zstd compression level, from 1 (fastest) to 22 (smallest).
+	// Defaults to [DefaultCompressionLevel].
+	Level int
+
+	// Dictionaries are zstd dictionaries, by codec, used to compress the
+	// blocks of that codec, see [TrainDictionary]. Small blocks of the same
+	// codec share a lot of structure, dictionaries compress them much better
+	// than zstd alone. Every dictionary must have a distinct ID, and
+	// dictionaries must be kept as long as blocks compressed with them are
+	// stored.
+	Dictionaries map[uint64][]byte
+
+	// SkipCodecs are the codecs of the blocks stored as is. Defaults to raw
+	// blocks, which mostly hold file data that is already compressed or
+	// does not compress well.
+	SkipCodecs []uint64
+}
+
+// CompressedBlockstore is a [Blockstore] which compresses blocks with zstd
+// before storing them in the wrapped blockstore. Blocks are still stored under
+// their CID, and are only compressed when it saves space. Compressed blocks
+// are stored with a prefix byte, and are only decompressed when the result
+// matches their CID, so that blocks stored uncompressed, for example before
+// the wrapper was added, are read as is even when they look compressed.
+//
+// The wrapped blockstore holds compressed data which does not match the CIDs,
+// so its hash verification is disabled: [CompressedBlockstore.HashOnRead]
+// verifies blocks once decompressed instead.
+type CompressedBlockstore struct {
+	blockstore Blockstore
+
+	skip     map[uint64]struct{}
+	encoder  *zstd.Encoder
+	encoders map[uint64]*zstd.Encoder
+	decoder  *zstd.Decoder
+
+	rehash atomic.Bool
+
+	compressed   metrics.Counter
+	uncompressed metrics.Counter
+	savedBytes   metrics.Counter
+}
+
+var _ Blockstore = (*CompressedBlockstore)(nil)
+
+// NewCompressedBlockstore wraps bs in a [CompressedBlockstore].
+func NewCompressedBlockstore(ctx context.Context, bs Blockstore, opts CompressedOpts) (*CompressedBlockstore, error) {
+	if opts.Level == 0 {
+		opts.Level = DefaultCompressionLevel
+	}
+	if opts.Level < 1 || opts.Level > 22 {
+		return nil, fmt.Errorf("invalid zstd compression level %d", opts.Level)
+	}
+	if opts.SkipCodecs == nil {
+		opts.SkipCodecs = []uint64{cid.Raw}
+	}
+	level := zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.Level))
+
+	b := &CompressedBlockstore{
+		blockstore:   bs,
+		skip:         make(map[uint64]struct{}, len(opts.SkipCodecs)),
+		encoders:     make(map[uint64]*zstd.Encoder, len(opts.Dictionaries)),
+		compressed:   metrics.NewCtx(ctx, "boxo_blockstore.compressed_blocks_total", "Number of blocks stored compressed").Counter(),
+		uncompressed: metrics.NewCtx(ctx, "boxo_blockstore.uncompressed_blocks_total", "Number of blocks stored uncompressed by the compressed blockstore, because of their codec or because compression did not save space").Counter(),
+		savedBytes:   metrics.NewCtx(ctx, "boxo_blockstore.compression_saved_bytes_total", "Number of bytes saved by compressing blocks").Counter(),
+	}
+	for _, codec := range opts.SkipCodecs {
+		b.skip[codec] = struct{}{}
+	}
+
+	var err error
+	b.encoder, err = zstd.NewWriter(nil, level, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[uint32]uint64, len(opts.Dictionaries))
+	dicts := make([][]byte, 0, len(opts.Dictionaries))
+	for codec, d := range opts.Dictionaries {
+		info, err := zstd.InspectDictionary(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dictionary for codec %d: %w", codec, err)
+		}
+		if other, ok := ids[info.ID()]; ok {
+			return nil, fmt.Errorf("dictionaries for codecs %d and %d have the same ID %d", other, codec, info.ID())
+		}
+		ids[info.ID()] = codec
+
+		enc, err := zstd.NewWriter(nil, level, zstd.WithEncoderConcurrency(1), zstd.WithEncoderDict(d))
+		if err != nil {
+			return nil, fmt.Errorf("invalid dictionary for codec %d: %w", codec, err)
+		}
+		b.encoders[codec] = enc
+		dicts = append(dicts, d)
+	}
+
+	b.decoder, err = zstd.NewReader(nil,
+		zstd.WithDecoderConcurrency(0),
+		zstd.WithDecoderMaxMemory(maxDecompressedSize),
+		zstd.WithDecoderDicts(dicts...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the wrapped blockstore cannot verify compressed data
+	bs.HashOnRead(false)
+	return b, nil
+}
+
+// compress returns the data to store for blk.
+func (b *CompressedBlockstore) compress(blk blocks.Block) (blocks.Block, error) {
+	codec := blk.Cid().Prefix().Codec
+	if _, ok := b.skip[codec]; ok {
+		b.uncompressed.Inc()
+		return blk, nil
+	}
+
+	enc, ok := b.encoders[codec]
+	if !ok {
+		enc = b.encoder
+	}
+	data := blk.RawData()
+	out := enc.EncodeAll(data, append(make([]byte, 0, len(data)), compressedPrefix))
+	if len(out) >= len(data) {
+		b.uncompressed.Inc()
+		return blk, nil
+	}
+	b.compressed.Inc()
+	b.savedBytes.Add(float64(len(data) - len(out)))
+	return blocks.NewBlockWithCid(out, blk.Cid())
+}
+
+// decompress returns the data of the block c from the stored data.
+//
+// Blocks stored uncompressed may start with compressedPrefix too, so the
+// decompressed data is only the block if it matches the multihash of c.
+// Otherwise the stored data is the block, and it is verified if HashOnRead is
+// enabled.
+func (b *CompressedBlockstore) decompress(c cid.Cid, stored []byte) ([]byte, error) {
+	if len(stored) > 0 && stored[0] == compressedPrefix {
+		// data which is not a valid frame was stored as is
+		if out, err := b.decoder.DecodeAll(stored[1:], nil); err == nil {
+			match, err := matchesHash(c, out)
+			if err != nil {
+				return nil, err
+			}
+			if match {
+				return out, nil
+			}
+		}
+	}
+
+	if b.rehash.Load() {
+		match, err := matchesHash(c, stored)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			return nil, ErrHashMismatch
+		}
+	}
+	return stored, nil
+}
+
+// matchesHash returns true if data hashes to the multihash of c.
+func matchesHash(c cid.Cid, data []byte) (bool, error) {
+	rbcid, err := c.Prefix().Sum(data)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(rbcid.Hash(), c.Hash()), nil
+}
+
+func (b *CompressedBlockstore) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	return b.blockstore.DeleteBlock(ctx, c)
+}
+
+func (b *CompressedBlockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	return b.blockstore.Has(ctx, c)
+}
+
+func (b *CompressedBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	blk, err := b.blockstore.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	data, err := b.decompress(c, blk.RawData())
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// GetSize returns the uncompressed size of the block. Blocks stored with
+// compressedPrefix are decompressed, as only their CID tells whether they
+// were compressed.
+func (b *CompressedBlockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	blk, err := b.blockstore.Get(ctx, c)
+	if err != nil {
+		return -1, err
+	}
+	stored := blk.RawData()
+	if len(stored) == 0 || stored[0] != compressedPrefix {
+		return len(stored), nil
+	}
+	data, err := b.decompress(c, stored)
+	if err != nil {
+		return -1, err
+	}
+	return len(data), nil
+}
+
+func (b *CompressedBlockstore) Put(ctx context.Context, blk blocks.Block) error {
+	stored, err := b.compress(blk)
+	if err != nil {
+		return err
+	}
+	return b.blockstore.Put(ctx, stored)
+}
+
+func (b *CompressedBlockstore) PutMany(ctx context.Context, blks []blocks.Block) error {
+	stored := make([]blocks.Block, len(blks))
+	for i, blk := range blks {
+		var err error
+		stored[i], err = b.compress(blk)
+		if err != nil {
+			return err
+		}
+	}
+	return b.blockstore.PutMany(ctx, stored)
+}
+
+func (b *CompressedBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	return b.blockstore.AllKeysChan(ctx)
+}
+
+// HashOnRead makes Get verify that the decompressed blocks match their CID.
+func (b *CompressedBlockstore) HashOnRead(enabled bool) {
+	b.rehash.Store(enabled)
+}
+
+// TrainDictionary builds a zstd dictionary of size bytes, 0 meaning
+// [DefaultDictionarySize], from the data of sample blocks, for use in
+// [CompressedOpts.Dictionaries]. The samples must be blocks of the codec the
+// dictionary is for, as the CIDs listed by most blockstores do not carry the
+// codec, it is up to the caller to pick them, for example while walking DAGs.
+//
+// Training fails when the samples are too few or too small: a few hundred
+// blocks representative of the blocks to compress are needed.
+func TrainDictionary(samples [][]byte, size int) ([]byte, error) {
+	if size == 0 {
+		size = DefaultDictionarySize
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("no sample to train the dictionary with")
+	}
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: size,
+		HashBytes:   6,
+	})
+}
@@ -0,0 +1,244 @@
+package blockstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func createCompressedStores(t *testing.T, opts CompressedOpts) (*CompressedBlockstore, Blockstore) {
+	bs := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	cbs, err := NewCompressedBlockstore(context.Background(), bs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cbs, bs
+}
+
+func newCodecBlock(t *testing.T, codec uint64, data []byte) blocks.Block {
+	c, err := cid.Prefix{Version: 1, Codec: codec, MhType: mh.SHA2_256, MhLength: -1}.Sum(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlockWithCid(data, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+func compressibleData(i int) []byte {
+	return []byte(fmt.Sprintf(`{"name":"entry %d","links":[{"name":"child","size":1024},{"name":"child","size":1024},{"name":"child","size":1024}]}`, i))
+}
+
+func TestCompressedBlockstoreInvalidOptions(t *testing.T) {
+	bs := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	if _, err := NewCompressedBlockstore(context.Background(), bs, CompressedOpts{Level: 23}); err == nil {
+		t.Fatal("expected an error for an invalid level")
+	}
+	if _, err := NewCompressedBlockstore(context.Background(), bs, CompressedOpts{Dictionaries: map[uint64][]byte{cid.DagCBOR: []byte("not a dictionary")}}); err == nil {
+		t.Fatal("expected an error for an invalid dictionary")
+	}
+}
+
+func TestCompressedBlockstore(t *testing.T) {
+	ctx := context.Background()
+	cbs, bs := createCompressedStores(t, CompressedOpts{})
+
+	compressible := newCodecBlock(t, cid.DagJSON, bytes.Repeat([]byte("compressible "), 100))
+	raw := newCodecBlock(t, cid.Raw, bytes.Repeat([]byte("raw "), 100))
+	tiny := newCodecBlock(t, cid.DagJSON, []byte("{}"))
+	if err := cbs.PutMany(ctx, []blocks.Block{compressible, raw, tiny}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := bs.Get(ctx, compressible.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.RawData()) >= len(compressible.RawData()) {
+		t.Fatalf("expected block to be stored compressed, got %d bytes for %d", len(stored.RawData()), len(compressible.RawData()))
+	}
+	for _, blk := range []blocks.Block{raw, tiny} {
+		stored, err := bs.Get(ctx, blk.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(stored.RawData(), blk.RawData()) {
+			t.Fatalf("expected %s to be stored as is", blk.Cid())
+		}
+	}
+
+	for _, blk := range []blocks.Block{compressible, raw, tiny} {
+		got, err := cbs.Get(ctx, blk.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.RawData(), blk.RawData()) {
+			t.Fatalf("block %s does not round-trip", blk.Cid())
+		}
+		size, err := cbs.GetSize(ctx, blk.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != len(blk.RawData()) {
+			t.Fatalf("expected size %d for %s, got %d", len(blk.RawData()), blk.Cid(), size)
+		}
+	}
+}
+
+func TestCompressedBlockstoreAllKeys(t *testing.T) {
+	ctx := context.Background()
+	cbs, _ := createCompressedStores(t, CompressedOpts{})
+
+	blk := newCodecBlock(t, cid.DagJSON, bytes.Repeat([]byte("compressible "), 100))
+	// raw data which looks compressed on its own
+	frame := newCodecBlock(t, cid.Raw, cbs.encoder.EncodeAll(blk.RawData(), []byte{compressedPrefix}))
+	if err := cbs.PutMany(ctx, []blocks.Block{blk, frame}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]byte{
+		string(blk.Cid().Hash()):   blk.RawData(),
+		string(frame.Cid().Hash()): frame.RawData(),
+	}
+	ch, err := cbs.AllKeysChan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for c := range ch {
+		// blocks are listed as raw CIDs
+		got, err := cbs.Get(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.RawData(), want[string(c.Hash())]) {
+			t.Fatalf("unexpected data for %s", c)
+		}
+		size, err := cbs.GetSize(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != len(want[string(c.Hash())]) {
+			t.Fatalf("unexpected size %d for %s", size, c)
+		}
+		n++
+	}
+	if n != len(want) {
+		t.Fatalf("expected %d keys, got %d", len(want), n)
+	}
+}
+
+func TestCompressedBlockstoreReadsUncompressedBlocks(t *testing.T) {
+	ctx := context.Background()
+	cbs, bs := createCompressedStores(t, CompressedOpts{})
+
+	blk := newCodecBlock(t, cid.DagJSON, bytes.Repeat([]byte("stored before compression "), 10))
+	// a block which looks compressed
+	frame := newCodecBlock(t, cid.DagJSON, cbs.encoder.EncodeAll(blk.RawData(), []byte{compressedPrefix}))
+	if err := bs.PutMany(ctx, []blocks.Block{blk, frame}); err != nil {
+		t.Fatal(err)
+	}
+
+	cbs.HashOnRead(true)
+	for _, blk := range []blocks.Block{blk, frame} {
+		got, err := cbs.Get(ctx, blk.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.RawData(), blk.RawData()) {
+			t.Fatal("uncompressed block was altered")
+		}
+		size, err := cbs.GetSize(ctx, blk.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != len(blk.RawData()) {
+			t.Fatalf("expected size %d, got %d", len(blk.RawData()), size)
+		}
+	}
+}
+
+func TestCompressedBlockstoreHashOnRead(t *testing.T) {
+	ctx := context.Background()
+	cbs, bs := createCompressedStores(t, CompressedOpts{})
+
+	blk := newCodecBlock(t, cid.DagJSON, bytes.Repeat([]byte("original "), 100))
+	other := newCodecBlock(t, cid.DagJSON, bytes.Repeat([]byte("tampered "), 100))
+	if err := cbs.Put(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := bs.Get(ctx, other.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// store the compressed data of other under the CID of blk
+	tampered, err := blocks.NewBlockWithCid(stored.RawData(), blk.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Put(ctx, tampered); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cbs.Get(ctx, blk.Cid()); err != nil {
+		t.Fatal("expected tampered block to be returned without verification")
+	}
+	cbs.HashOnRead(true)
+	if _, err := cbs.Get(ctx, blk.Cid()); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("expected ErrHashMismatch, got %v", err)
+	}
+	if _, err := cbs.Get(ctx, other.Cid()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompressedBlockstoreDictionary(t *testing.T) {
+	ctx := context.Background()
+	var samples [][]byte
+	for i := 0; i < 500; i++ {
+		samples = append(samples, compressibleData(i))
+	}
+	d, err := TrainDictionary(samples, 4<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, plainBs := createCompressedStores(t, CompressedOpts{})
+	withDict, withDictBs := createCompressedStores(t, CompressedOpts{Dictionaries: map[uint64][]byte{cid.DagJSON: d}})
+
+	blk := newCodecBlock(t, cid.DagJSON, compressibleData(1000))
+	for _, s := range []*CompressedBlockstore{plain, withDict} {
+		if err := s.Put(ctx, blk); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Get(ctx, blk.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.RawData(), blk.RawData()) {
+			t.Fatal("block does not round-trip")
+		}
+	}
+
+	plainSize, err := plainBs.GetSize(ctx, blk.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dictSize, err := withDictBs.GetSize(ctx, blk.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dictSize >= plainSize {
+		t.Fatalf("expected the dictionary to improve compression, got %d bytes with and %d without", dictSize, plainSize)
+	}
+}
@@ -2,17 +2,46 @@ package blockstore
 
 import (
 	"context"
+	"fmt"
 	"io"
 
 	blocks "github.com/ipfs/go-block-format"
 	cid "github.com/ipfs/go-cid"
+	metrics "github.com/ipfs/go-metrics-interface"
 	mh "github.com/multiformats/go-multihash"
 )
 
+// ErrIdentityTooLarge is returned for the identity CIDs whose inlined data is
+// larger than [IdStoreOpts.MaxIdentitySize].
+type ErrIdentityTooLarge struct {
+	Cid  cid.Cid
+	Size int
+	Max  int
+}
+
+func (e ErrIdentityTooLarge) Error() string {
+	return fmt.Sprintf("identity CID %s inlines %d bytes, more than the maximum of %d bytes", e.Cid, e.Size, e.Max)
+}
+
+// IdStoreOpts wraps options for [NewIdStoreWithOpts].
+type IdStoreOpts struct {
+	// MaxIdentitySize is the maximum size of the data inlined in identity
+	// CIDs. Operations on larger identity CIDs fail with
+	// [ErrIdentityTooLarge], except DeleteBlock which does nothing. Zero
+	// means no limit. Identity CIDs are not bounded by the hash length limits
+	// of verifcid, so any amount of data can be inlined in them.
+	MaxIdentitySize int
+}
+
 // idstore wraps a BlockStore to add support for identity hashes
 type idstore struct {
 	bs     Blockstore
 	viewer Viewer
+
+	maxSize int
+
+	inlineReads metrics.Counter
+	rejected    metrics.Counter
 }
 
 var (
@@ -22,7 +51,19 @@ var (
 )
 
 func NewIdStore(bs Blockstore) Blockstore {
-	ids := &idstore{bs: bs}
+	return NewIdStoreWithOpts(context.Background(), bs, IdStoreOpts{})
+}
+
+// NewIdStoreWithOpts wraps bs to serve identity CIDs from their inlined data,
+// like [NewIdStore], with the given options. It counts the reads served inline
+// and the identity CIDs rejected with the metrics of ctx.
+func NewIdStoreWithOpts(ctx context.Context, bs Blockstore, opts IdStoreOpts) Blockstore {
+	ids := &idstore{
+		bs:          bs,
+		maxSize:     opts.MaxIdentitySize,
+		inlineReads: metrics.NewCtx(ctx, "boxo_blockstore.identity_inline_reads_total", "Number of reads of identity CIDs served from their inlined data").Counter(),
+		rejected:    metrics.NewCtx(ctx, "boxo_blockstore.identity_rejected_total", "Number of operations on identity CIDs rejected for exceeding the maximum inlined size").Counter(),
+	}
 	if v, ok := bs.(Viewer); ok {
 		ids.viewer = v
 	}
@@ -42,6 +83,27 @@ func extractContents(k cid.Cid) (bool, []byte) {
 	return true, dmh.Digest
 }
 
+// identity returns whether k is an identity CID, and its inlined data. It
+// returns an error if the data exceeds the maximum size.
+func (b *idstore) identity(k cid.Cid) (bool, []byte, error) {
+	isId, bdata := extractContents(k)
+	if isId && b.maxSize > 0 && len(bdata) > b.maxSize {
+		b.rejected.Inc()
+		return true, nil, ErrIdentityTooLarge{Cid: k, Size: len(bdata), Max: b.maxSize}
+	}
+	return isId, bdata, nil
+}
+
+// readInline returns whether k is an identity CID, and its inlined data,
+// counting the read.
+func (b *idstore) readInline(k cid.Cid) (bool, []byte, error) {
+	isId, bdata, err := b.identity(k)
+	if isId && err == nil {
+		b.inlineReads.Inc()
+	}
+	return isId, bdata, err
+}
+
 func (b *idstore) DeleteBlock(ctx context.Context, k cid.Cid) error {
 	isId, _ := extractContents(k)
 	if isId {
@@ -51,9 +113,9 @@ func (b *idstore) DeleteBlock(ctx context.Context, k cid.Cid) error {
 }
 
 func (b *idstore) Has(ctx context.Context, k cid.Cid) (bool, error) {
-	isId, _ := extractContents(k)
+	isId, _, err := b.readInline(k)
 	if isId {
-		return true, nil
+		return err == nil, err
 	}
 	return b.bs.Has(ctx, k)
 }
@@ -66,33 +128,42 @@ func (b *idstore) View(ctx context.Context, k cid.Cid, callback func([]byte) err
 		}
 		return callback(blk.RawData())
 	}
-	isId, bdata := extractContents(k)
+	isId, bdata, err := b.readInline(k)
 	if isId {
+		if err != nil {
+			return err
+		}
 		return callback(bdata)
 	}
 	return b.viewer.View(ctx, k, callback)
 }
 
 func (b *idstore) GetSize(ctx context.Context, k cid.Cid) (int, error) {
-	isId, bdata := extractContents(k)
+	isId, bdata, err := b.readInline(k)
 	if isId {
+		if err != nil {
+			return -1, err
+		}
 		return len(bdata), nil
 	}
 	return b.bs.GetSize(ctx, k)
 }
 
 func (b *idstore) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
-	isId, bdata := extractContents(k)
+	isId, bdata, err := b.readInline(k)
 	if isId {
+		if err != nil {
+			return nil, err
+		}
 		return blocks.NewBlockWithCid(bdata, k)
 	}
 	return b.bs.Get(ctx, k)
 }
 
 func (b *idstore) Put(ctx context.Context, bl blocks.Block) error {
-	isId, _ := extractContents(bl.Cid())
+	isId, _, err := b.identity(bl.Cid())
 	if isId {
-		return nil
+		return err
 	}
 	return b.bs.Put(ctx, bl)
 }
@@ -100,7 +171,10 @@ func (b *idstore) Put(ctx context.Context, bl blocks.Block) error {
 func (b *idstore) PutMany(ctx context.Context, bs []blocks.Block) error {
 	toPut := make([]blocks.Block, 0, len(bs))
 	for _, bl := range bs {
-		isId, _ := extractContents(bl.Cid())
+		isId, _, err := b.identity(bl.Cid())
+		if err != nil {
+			return err
+		}
 		if isId {
 			continue
 		}
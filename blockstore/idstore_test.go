@@ -2,6 +2,7 @@ package blockstore
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	blk "github.com/ipfs/go-block-format"
@@ -160,3 +161,43 @@ func TestIdStore(t *testing.T) {
 		t.Fatalf("expected exactly two keys returned by AllKeysChan got %d", cnt)
 	}
 }
+
+func TestIdStoreMaxIdentitySize(t *testing.T) {
+	small, _ := cid.NewPrefixV1(cid.Raw, mh.IDENTITY).Sum([]byte("small"))
+	large, _ := cid.NewPrefixV1(cid.Raw, mh.IDENTITY).Sum([]byte("larger than the limit"))
+	largeBlock, _ := blk.NewBlockWithCid([]byte("larger than the limit"), large)
+
+	cd := &callbackDatastore{f: func() {}, ds: ds.NewMapDatastore()}
+	ids := NewIdStoreWithOpts(bg, NewBlockstore(cd), IdStoreOpts{MaxIdentitySize: 8})
+
+	if _, err := ids.Get(bg, small); err != nil {
+		t.Fatalf("Get() failed on small idhash: %v", err)
+	}
+
+	check := func(op string, err error) {
+		t.Helper()
+		var tooLarge ErrIdentityTooLarge
+		if !errors.As(err, &tooLarge) {
+			t.Fatalf("%s: expected ErrIdentityTooLarge, got %v", op, err)
+		}
+		if tooLarge.Size != 21 || tooLarge.Max != 8 {
+			t.Fatalf("%s: unexpected error %v", op, err)
+		}
+	}
+	has, err := ids.Has(bg, large)
+	if has {
+		t.Fatal("Has() succeeded on large idhash")
+	}
+	check("Has", err)
+	_, err = ids.Get(bg, large)
+	check("Get", err)
+	_, err = ids.GetSize(bg, large)
+	check("GetSize", err)
+	err = ids.(Viewer).View(bg, large, func([]byte) error { return nil })
+	check("View", err)
+	check("Put", ids.Put(bg, largeBlock))
+	check("PutMany", ids.PutMany(bg, []blk.Block{largeBlock}))
+	if err := ids.DeleteBlock(bg, large); err != nil {
+		t.Fatalf("DeleteBlock() failed on large idhash: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package blockstore
+
+import (
+	"context"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("boxo/blockstore")
+
+// MetricsOpts wraps options for [NewMetricsBlockstore].
+type MetricsOpts struct {
+	// Namespace is the value of the namespace label of the metrics, which
+	// tells apart the blockstores of a process.
+	Namespace string
+
+	// Registerer registers the metrics. Defaults to
+	// [prometheus.DefaultRegisterer]. Blockstores registered with the same
+	// Registerer share the metrics, with different namespace labels.
+	Registerer prometheus.Registerer
+
+	// Tracing enables the OpenTelemetry spans of every operation.
+	Tracing bool
+}
+
+// MetricsBlockstore is a [Blockstore] which records Prometheus metrics, and
+// optionally OpenTelemetry spans, for every operation of the wrapped
+// blockstore:
+//
+//   - ipfs_blockstore_operations_total: the number of operations, by op and
+//     result, which is "success", "not_found" or "error",
+//   - ipfs_blockstore_operation_duration_seconds: the duration of operations,
+//     by op,
+//   - ipfs_blockstore_block_size_bytes: the size of the blocks read and
+//     written, by op.
+//
+// All the metrics have a namespace label set to [MetricsOpts.Namespace].
+type MetricsBlockstore struct {
+	blockstore Blockstore
+	viewer     Viewer
+	namespace  string
+	tracing    bool
+
+	ops       *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+	sizes     *prometheus.HistogramVec
+}
+
+var (
+	_ Blockstore = (*MetricsBlockstore)(nil)
+	_ Viewer     = (*MetricsBlockstore)(nil)
+)
+
+// NewMetricsBlockstore wraps bs in a [MetricsBlockstore].
+func NewMetricsBlockstore(bs Blockstore, opts MetricsOpts) *MetricsBlockstore {
+	reg := opts.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	b := &MetricsBlockstore{
+		blockstore: bs,
+		namespace:  opts.Namespace,
+		tracing:    opts.Tracing,
+		ops: registerCollector(reg, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "ipfs",
+				Subsystem: "blockstore",
+				Name:      "operations_total",
+				Help:      "The number of blockstore operations, by result.",
+			},
+			[]string{"namespace", "op", "result"},
+		)),
+		durations: registerCollector(reg, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "ipfs",
+				Subsystem: "blockstore",
+				Name:      "operation_duration_seconds",
+				Help:      "The time spent in blockstore operations.",
+				Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+			},
+			[]string{"namespace", "op"},
+		)),
+		sizes: registerCollector(reg, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "ipfs",
+				Subsystem: "blockstore",
+				Name:      "block_size_bytes",
+				Help:      "The size of the blocks read and written.",
+				Buckets:   prometheus.ExponentialBuckets(256, 4, 8),
+			},
+			[]string{"namespace", "op"},
+		)),
+	}
+	if v, ok := bs.(Viewer); ok {
+		b.viewer = v
+	}
+	return b
+}
+
+// registerCollector registers c, or returns the collector already registered
+// with the same description.
+func registerCollector[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		logger.Errorf("failed to register blockstore metrics: %s", err)
+	}
+	return c
+}
+
+// start starts the span of an operation, when tracing is enabled.
+func (b *MetricsBlockstore) start(ctx context.Context, op string, c cid.Cid) (context.Context, trace.Span) {
+	if !b.tracing {
+		return ctx, nil
+	}
+	attrs := []attribute.KeyValue{attribute.String("namespace", b.namespace)}
+	if c.Defined() {
+		attrs = append(attrs, attribute.String("cid", c.String()))
+	}
+	return tracer.Start(ctx, "Blockstore."+op, trace.WithAttributes(attrs...))
+}
+
+// done records an operation started at begin.
+func (b *MetricsBlockstore) done(span trace.Span, op string, begin time.Time, err error) {
+	b.durations.WithLabelValues(b.namespace, op).Observe(time.Since(begin).Seconds())
+
+	result := "success"
+	switch {
+	case err == nil:
+	case ipld.IsNotFound(err):
+		result = "not_found"
+	default:
+		result = "error"
+	}
+	b.ops.WithLabelValues(b.namespace, op, result).Inc()
+
+	if span != nil {
+		if result == "error" {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (b *MetricsBlockstore) observeSize(op string, size int) {
+	b.sizes.WithLabelValues(b.namespace, op).Observe(float64(size))
+}
+
+func (b *MetricsBlockstore) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	ctx, span := b.start(ctx, "DeleteBlock", c)
+	begin := time.Now()
+	err := b.blockstore.DeleteBlock(ctx, c)
+	b.done(span, "delete_block", begin, err)
+	return err
+}
+
+func (b *MetricsBlockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	ctx, span := b.start(ctx, "Has", c)
+	begin := time.Now()
+	has, err := b.blockstore.Has(ctx, c)
+	b.done(span, "has", begin, err)
+	return has, err
+}
+
+func (b *MetricsBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	ctx, span := b.start(ctx, "Get", c)
+	begin := time.Now()
+	blk, err := b.blockstore.Get(ctx, c)
+	b.done(span, "get", begin, err)
+	if err == nil {
+		b.observeSize("get", len(blk.RawData()))
+	}
+	return blk, err
+}
+
+func (b *MetricsBlockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	ctx, span := b.start(ctx, "GetSize", c)
+	begin := time.Now()
+	size, err := b.blockstore.GetSize(ctx, c)
+	b.done(span, "get_size", begin, err)
+	return size, err
+}
+
+func (b *MetricsBlockstore) View(ctx context.Context, c cid.Cid, callback func([]byte) error) error {
+	ctx, span := b.start(ctx, "View", c)
+	begin := time.Now()
+	var err error
+	if b.viewer != nil {
+		err = b.viewer.View(ctx, c, func(data []byte) error {
+			b.observeSize("view", len(data))
+			return callback(data)
+		})
+	} else {
+		// fall back to Get if the underlying store doesn't support Viewer.
+		var blk blocks.Block
+		if blk, err = b.blockstore.Get(ctx, c); err == nil {
+			b.observeSize("view", len(blk.RawData()))
+			err = callback(blk.RawData())
+		}
+	}
+	b.done(span, "view", begin, err)
+	return err
+}
+
+func (b *MetricsBlockstore) Put(ctx context.Context, blk blocks.Block) error {
+	ctx, span := b.start(ctx, "Put", blk.Cid())
+	begin := time.Now()
+	err := b.blockstore.Put(ctx, blk)
+	b.done(span, "put", begin, err)
+	if err == nil {
+		b.observeSize("put", len(blk.RawData()))
+	}
+	return err
+}
+
+func (b *MetricsBlockstore) PutMany(ctx context.Context, blks []blocks.Block) error {
+	ctx, span := b.start(ctx, "PutMany", cid.Undef)
+	if span != nil {
+		span.SetAttributes(attribute.Int("count", len(blks)))
+	}
+	begin := time.Now()
+	err := b.blockstore.PutMany(ctx, blks)
+	b.done(span, "put_many", begin, err)
+	if err == nil {
+		for _, blk := range blks {
+			b.observeSize("put", len(blk.RawData()))
+		}
+	}
+	return err
+}
+
+// AllKeysChan records the time to start listing the keys, not to read them.
+func (b *MetricsBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	_, span := b.start(ctx, "AllKeysChan", cid.Undef)
+	begin := time.Now()
+	ch, err := b.blockstore.AllKeysChan(ctx)
+	b.done(span, "all_keys", begin, err)
+	return ch, err
+}
+
+func (b *MetricsBlockstore) HashOnRead(enabled bool) {
+	b.blockstore.HashOnRead(enabled)
+}
@@ -0,0 +1,63 @@
+package blockstore
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	ds "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsBlockstore(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+
+	bs := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	mbs := NewMetricsBlockstore(bs, MetricsOpts{Namespace: "data", Registerer: reg, Tracing: true})
+	other := NewMetricsBlockstore(bs, MetricsOpts{Namespace: "cache", Registerer: reg})
+
+	blk := blocks.NewBlock([]byte("some data"))
+	missing := blocks.NewBlock([]byte("missing"))
+	if err := mbs.Put(ctx, blk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mbs.Get(ctx, blk.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mbs.Get(ctx, missing.Cid()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := mbs.View(ctx, blk.Cid(), func([]byte) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Has(ctx, blk.Cid()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		namespace, op, result string
+		count                 float64
+	}{
+		{"data", "put", "success", 1},
+		{"data", "get", "success", 1},
+		{"data", "get", "not_found", 1},
+		{"data", "view", "success", 1},
+		{"cache", "has", "success", 1},
+	} {
+		got := testutil.ToFloat64(mbs.ops.WithLabelValues(tc.namespace, tc.op, tc.result))
+		if got != tc.count {
+			t.Errorf("expected %v %s %s operations in %s, got %v", tc.count, tc.op, tc.result, tc.namespace, got)
+		}
+	}
+
+	// both blockstores share the metrics
+	if n := testutil.CollectAndCount(reg, "ipfs_blockstore_operations_total"); n != 5 {
+		t.Fatalf("expected 5 series, got %d", n)
+	}
+	if n := testutil.CollectAndCount(reg, "ipfs_blockstore_block_size_bytes"); n != 3 {
+		t.Fatalf("expected 3 size series, got %d", n)
+	}
+}
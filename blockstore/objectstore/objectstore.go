@@ -0,0 +1,370 @@
+// Package objectstore implements a [blockstore.Blockstore] over an S3
+// compatible object store, so that nodes such as gateways can run without
+// local state.
+//
+// The object store is accessed through the minimal [Client] interface, which
+// is easily implemented with the SDK of any provider. Each block is stored as
+// one object, named after the base32 encoded multihash of the block.
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ipfs/boxo/blockstore"
+	dshelp "github.com/ipfs/boxo/datastore/dshelp"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+var logger = logging.Logger("blockstore/objectstore")
+
+const (
+	// DefaultMultipartThreshold is the size above which blocks are uploaded
+	// in parts, when the [Client] implements [MultipartClient].
+	DefaultMultipartThreshold = 8 << 20
+	// DefaultPartSize is the size of the parts of multipart uploads. It is
+	// the minimum part size accepted by S3.
+	DefaultPartSize = 5 << 20
+	// DefaultConcurrency is the number of requests made at the same time by
+	// PutMany and DeleteMany.
+	DefaultConcurrency = 16
+
+	// maxDeleteBatch is the maximum number of keys S3 accepts in a single
+	// DeleteObjects request.
+	maxDeleteBatch = 1000
+)
+
+// ErrNotFound must be returned, possibly wrapped, by [Client] methods when an
+// object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Client is the minimal set of operations of an object store needed by
+// [Blockstore].
+type Client interface {
+	// PutObject stores the size bytes read from r as the object key.
+	PutObject(ctx context.Context, key string, r io.Reader, size int64) error
+	// GetObject returns the content of the object key.
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	// StatObject returns the size of the object key.
+	StatObject(ctx context.Context, key string) (int64, error)
+	// DeleteObjects deletes the given objects. Objects which do not exist
+	// are ignored. At most 1000 keys are given at once.
+	DeleteObjects(ctx context.Context, keys []string) error
+	// ListObjects calls fn with the key of every object starting with
+	// prefix, until fn returns false.
+	ListObjects(ctx context.Context, prefix string, fn func(key string) bool) error
+}
+
+// MultipartClient is a [Client] which supports multipart uploads. Blocks
+// larger than the multipart threshold are uploaded in parts when the client
+// implements it.
+type MultipartClient interface {
+	Client
+	// CreateMultipartUpload starts a multipart upload of the object key and
+	// returns its ID.
+	CreateMultipartUpload(ctx context.Context, key string) (uploadID string, err error)
+	// UploadPart uploads a part of a multipart upload and returns its ETag.
+	// Parts are numbered from 1.
+	UploadPart(ctx context.Context, key, uploadID string, part int, r io.Reader, size int64) (etag string, err error)
+	// CompleteMultipartUpload assembles the uploaded parts, given by their
+	// ETags in order.
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, etags []string) error
+	// AbortMultipartUpload cancels a multipart upload and frees its parts.
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// Option is an option for [New].
+type Option func(*Blockstore)
+
+// WithPrefix stores the blocks under the given key prefix, such as "blocks/".
+func WithPrefix(prefix string) Option {
+	return func(bs *Blockstore) {
+		bs.prefix = prefix
+	}
+}
+
+// WithMultipart sets the size above which blocks are uploaded in parts, and
+// the size of the parts. It defaults to [DefaultMultipartThreshold] and
+// [DefaultPartSize].
+func WithMultipart(threshold, partSize int) Option {
+	return func(bs *Blockstore) {
+		bs.multipartThreshold = threshold
+		bs.partSize = partSize
+	}
+}
+
+// WithReadCache keeps a copy of the blocks read from the object store in the
+// given local blockstore, which is checked before the object store.
+func WithReadCache(cache blockstore.Blockstore) Option {
+	return func(bs *Blockstore) {
+		bs.cache = cache
+	}
+}
+
+// WithConcurrency sets how many requests PutMany and DeleteMany make at the
+// same time. It defaults to [DefaultConcurrency].
+func WithConcurrency(n int) Option {
+	return func(bs *Blockstore) {
+		bs.concurrency = n
+	}
+}
+
+// WriteThrough skips checking if the object store already has a block before
+// writing it.
+func WriteThrough() Option {
+	return func(bs *Blockstore) {
+		bs.writeThrough = true
+	}
+}
+
+// Blockstore is a [blockstore.Blockstore] storing blocks in an object store.
+type Blockstore struct {
+	client             Client
+	prefix             string
+	multipartThreshold int
+	partSize           int
+	cache              blockstore.Blockstore
+	concurrency        int
+	writeThrough       bool
+
+	rehash atomic.Bool
+}
+
+var _ blockstore.Blockstore = (*Blockstore)(nil)
+
+// New creates a [Blockstore] using the given client.
+func New(client Client, opts ...Option) (*Blockstore, error) {
+	bs := &Blockstore{
+		client:             client,
+		multipartThreshold: DefaultMultipartThreshold,
+		partSize:           DefaultPartSize,
+		concurrency:        DefaultConcurrency,
+	}
+	for _, opt := range opts {
+		opt(bs)
+	}
+
+	if bs.partSize <= 0 {
+		return nil, fmt.Errorf("invalid part size %d", bs.partSize)
+	}
+	if bs.concurrency <= 0 {
+		return nil, fmt.Errorf("invalid concurrency %d", bs.concurrency)
+	}
+	return bs, nil
+}
+
+func (bs *Blockstore) key(c cid.Cid) string {
+	// strip the leading "/" of the datastore key
+	return bs.prefix + dshelp.MultihashToDsKey(c.Hash()).String()[1:]
+}
+
+func (bs *Blockstore) HashOnRead(enabled bool) {
+	bs.rehash.Store(enabled)
+}
+
+func (bs *Blockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	if bs.cache != nil {
+		if has, err := bs.cache.Has(ctx, c); err == nil && has {
+			return true, nil
+		}
+	}
+
+	_, err := bs.client.StatObject(ctx, bs.key(c))
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (bs *Blockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	if bs.cache != nil {
+		if size, err := bs.cache.GetSize(ctx, c); err == nil {
+			return size, nil
+		}
+	}
+
+	size, err := bs.client.StatObject(ctx, bs.key(c))
+	if errors.Is(err, ErrNotFound) {
+		return -1, ipld.ErrNotFound{Cid: c}
+	}
+	if err != nil {
+		return -1, err
+	}
+	return int(size), nil
+}
+
+func (bs *Blockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	if !c.Defined() {
+		logger.Error("undefined cid in blockstore")
+		return nil, ipld.ErrNotFound{Cid: c}
+	}
+
+	if bs.cache != nil {
+		if blk, err := bs.cache.Get(ctx, c); err == nil {
+			return blk, nil
+		}
+	}
+
+	r, err := bs.client.GetObject(ctx, bs.key(c))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ipld.ErrNotFound{Cid: c}
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if bs.rehash.Load() {
+		rc, err := c.Prefix().Sum(data)
+		if err != nil {
+			return nil, err
+		}
+		if !rc.Equals(c) {
+			return nil, blockstore.ErrHashMismatch
+		}
+	}
+
+	blk, err := blocks.NewBlockWithCid(data, c)
+	if err != nil {
+		return nil, err
+	}
+	if bs.cache != nil {
+		if err := bs.cache.Put(ctx, blk); err != nil {
+			logger.Warnf("caching block %s: %s", c, err)
+		}
+	}
+	return blk, nil
+}
+
+func (bs *Blockstore) Put(ctx context.Context, blk blocks.Block) error {
+	key := bs.key(blk.Cid())
+
+	// StatObject is cheaper than PutObject, so see if we already have it
+	if !bs.writeThrough {
+		if _, err := bs.client.StatObject(ctx, key); err == nil {
+			return nil // already stored.
+		}
+	}
+
+	data := blk.RawData()
+	if mc, ok := bs.client.(MultipartClient); ok && len(data) > bs.multipartThreshold {
+		return bs.putMultipart(ctx, mc, key, data)
+	}
+	return bs.client.PutObject(ctx, key, bytes.NewReader(data), int64(len(data)))
+}
+
+func (bs *Blockstore) putMultipart(ctx context.Context, mc MultipartClient, key string, data []byte) error {
+	id, err := mc.CreateMultipartUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	etags := make([]string, 0, (len(data)+bs.partSize-1)/bs.partSize)
+	for part := 1; len(data) > 0; part++ {
+		n := min(bs.partSize, len(data))
+		etag, err := mc.UploadPart(ctx, key, id, part, bytes.NewReader(data[:n]), int64(n))
+		if err != nil {
+			if aerr := mc.AbortMultipartUpload(ctx, key, id); aerr != nil {
+				logger.Warnf("aborting multipart upload of %s: %s", key, aerr)
+			}
+			return fmt.Errorf("uploading part %d of %s: %w", part, key, err)
+		}
+		etags = append(etags, etag)
+		data = data[n:]
+	}
+	return mc.CompleteMultipartUpload(ctx, key, id, etags)
+}
+
+// PutMany uploads the blocks concurrently.
+func (bs *Blockstore) PutMany(ctx context.Context, blks []blocks.Block) error {
+	if len(blks) == 1 {
+		// performance fast-path
+		return bs.Put(ctx, blks[0])
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(bs.concurrency)
+	for _, blk := range blks {
+		blk := blk
+		g.Go(func() error {
+			return bs.Put(gctx, blk)
+		})
+	}
+	return g.Wait()
+}
+
+func (bs *Blockstore) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	return bs.DeleteMany(ctx, []cid.Cid{c})
+}
+
+// DeleteMany deletes the blocks in batches of up to 1000 keys.
+func (bs *Blockstore) DeleteMany(ctx context.Context, cids []cid.Cid) error {
+	if bs.cache != nil {
+		for _, c := range cids {
+			if err := bs.cache.DeleteBlock(ctx, c); err != nil {
+				return err
+			}
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(bs.concurrency)
+	for len(cids) > 0 {
+		n := min(maxDeleteBatch, len(cids))
+		keys := make([]string, n)
+		for i, c := range cids[:n] {
+			keys[i] = bs.key(c)
+		}
+		cids = cids[n:]
+
+		g.Go(func() error {
+			return bs.client.DeleteObjects(gctx, keys)
+		})
+	}
+	return g.Wait()
+}
+
+// AllKeysChan lists the objects under the prefix of the blockstore.
+//
+// As the codec of the blocks is not stored, the returned CIDs use the Raw
+// codec.
+func (bs *Blockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	output := make(chan cid.Cid, 64)
+	go func() {
+		defer close(output)
+
+		err := bs.client.ListObjects(ctx, bs.prefix, func(key string) bool {
+			name := strings.TrimPrefix(key, bs.prefix)
+			h, err := dshelp.DsKeyToMultihash(ds.RawKey("/" + name))
+			if err != nil {
+				logger.Warnf("error parsing key %q: %s", key, err)
+				return true
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case output <- cid.NewCidV1(cid.Raw, h):
+				return true
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			logger.Errorf("objectstore.AllKeysChan got err: %s", err)
+		}
+	}()
+
+	return output, nil
+}
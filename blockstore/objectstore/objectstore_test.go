@@ -0,0 +1,273 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/boxo/blockstore"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/stretchr/testify/require"
+)
+
+// memClient is an in-memory [MultipartClient].
+type memClient struct {
+	lk      sync.Mutex
+	objects map[string][]byte
+	uploads map[string][][]byte
+
+	gets, deletes, parts int
+}
+
+func newMemClient() *memClient {
+	return &memClient{
+		objects: make(map[string][]byte),
+		uploads: make(map[string][][]byte),
+	}
+}
+
+func (m *memClient) PutObject(ctx context.Context, key string, r io.Reader, size int64) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return fmt.Errorf("expected %d bytes, got %d", size, len(data))
+	}
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.objects[key] = data
+	return nil
+}
+
+func (m *memClient) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.gets++
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memClient) StatObject(ctx context.Context, key string) (int64, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return int64(len(data)), nil
+}
+
+func (m *memClient) DeleteObjects(ctx context.Context, keys []string) error {
+	if len(keys) > maxDeleteBatch {
+		return fmt.Errorf("too many keys: %d", len(keys))
+	}
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.deletes++
+	for _, k := range keys {
+		delete(m.objects, k)
+	}
+	return nil
+}
+
+func (m *memClient) ListObjects(ctx context.Context, prefix string, fn func(key string) bool) error {
+	m.lk.Lock()
+	var keys []string
+	for k := range m.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	m.lk.Unlock()
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn(k) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memClient) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.uploads[key] = nil
+	return key, nil
+}
+
+func (m *memClient) UploadPart(ctx context.Context, key, uploadID string, part int, r io.Reader, size int64) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.parts++
+	if part != len(m.uploads[uploadID])+1 {
+		return "", fmt.Errorf("unexpected part %d", part)
+	}
+	m.uploads[uploadID] = append(m.uploads[uploadID], data)
+	return fmt.Sprint(part), nil
+}
+
+func (m *memClient) CompleteMultipartUpload(ctx context.Context, key, uploadID string, etags []string) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	parts := m.uploads[uploadID]
+	if len(parts) != len(etags) {
+		return fmt.Errorf("expected %d parts, got %d", len(parts), len(etags))
+	}
+	m.objects[key] = bytes.Join(parts, nil)
+	delete(m.uploads, uploadID)
+	return nil
+}
+
+func (m *memClient) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	delete(m.uploads, uploadID)
+	return nil
+}
+
+func TestBlockstore(t *testing.T) {
+	ctx := context.Background()
+	client := newMemClient()
+	bs, err := New(client, WithPrefix("blocks/"))
+	require.NoError(t, err)
+
+	var blks []blocks.Block
+	for i := 0; i < 10; i++ {
+		blks = append(blks, blocks.NewBlock([]byte(fmt.Sprint("block ", i))))
+	}
+	require.NoError(t, bs.Put(ctx, blks[0]))
+	require.NoError(t, bs.PutMany(ctx, blks[1:]))
+	require.Len(t, client.objects, len(blks))
+
+	for _, b := range blks {
+		has, err := bs.Has(ctx, b.Cid())
+		require.NoError(t, err)
+		require.True(t, has)
+
+		size, err := bs.GetSize(ctx, b.Cid())
+		require.NoError(t, err)
+		require.Equal(t, len(b.RawData()), size)
+
+		got, err := bs.Get(ctx, b.Cid())
+		require.NoError(t, err)
+		require.Equal(t, b.RawData(), got.RawData())
+	}
+
+	ch, err := bs.AllKeysChan(ctx)
+	require.NoError(t, err)
+	var keys []string
+	for c := range ch {
+		keys = append(keys, string(c.Hash()))
+	}
+	require.Len(t, keys, len(blks))
+	for _, b := range blks {
+		require.Contains(t, keys, string(b.Cid().Hash()))
+	}
+
+	require.NoError(t, bs.DeleteBlock(ctx, blks[0].Cid()))
+	has, err := bs.Has(ctx, blks[0].Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+
+	_, err = bs.Get(ctx, blks[0].Cid())
+	require.True(t, ipld.IsNotFound(err))
+	_, err = bs.GetSize(ctx, blks[0].Cid())
+	require.True(t, ipld.IsNotFound(err))
+}
+
+func TestBlockstoreHashOnRead(t *testing.T) {
+	ctx := context.Background()
+	client := newMemClient()
+	bs, err := New(client)
+	require.NoError(t, err)
+
+	b := blocks.NewBlock([]byte("block"))
+	require.NoError(t, bs.Put(ctx, b))
+	client.objects[bs.key(b.Cid())] = []byte("corrupted")
+
+	bs.HashOnRead(true)
+	_, err = bs.Get(ctx, b.Cid())
+	require.ErrorIs(t, err, blockstore.ErrHashMismatch)
+}
+
+func TestBlockstoreMultipart(t *testing.T) {
+	ctx := context.Background()
+	client := newMemClient()
+	bs, err := New(client, WithMultipart(16, 10))
+	require.NoError(t, err)
+
+	small := blocks.NewBlock([]byte("small block"))
+	large := blocks.NewBlock(bytes.Repeat([]byte("large block "), 4))
+	require.NoError(t, bs.PutMany(ctx, []blocks.Block{small, large}))
+	require.Equal(t, 5, client.parts)
+
+	got, err := bs.Get(ctx, large.Cid())
+	require.NoError(t, err)
+	require.Equal(t, large.RawData(), got.RawData())
+}
+
+func TestBlockstoreReadCache(t *testing.T) {
+	ctx := context.Background()
+	client := newMemClient()
+	cache := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bs, err := New(client, WithReadCache(cache))
+	require.NoError(t, err)
+
+	b := blocks.NewBlock([]byte("block"))
+	require.NoError(t, bs.Put(ctx, b))
+
+	for i := 0; i < 3; i++ {
+		got, err := bs.Get(ctx, b.Cid())
+		require.NoError(t, err)
+		require.Equal(t, b.RawData(), got.RawData())
+	}
+	require.Equal(t, 1, client.gets)
+
+	has, err := cache.Has(ctx, b.Cid())
+	require.NoError(t, err)
+	require.True(t, has)
+
+	require.NoError(t, bs.DeleteBlock(ctx, b.Cid()))
+	has, err = cache.Has(ctx, b.Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func TestBlockstoreDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	client := newMemClient()
+	bs, err := New(client, WriteThrough())
+	require.NoError(t, err)
+
+	var blks []blocks.Block
+	var cids []cid.Cid
+	for i := 0; i < 2500; i++ {
+		b := blocks.NewBlock([]byte(fmt.Sprint(i)))
+		blks = append(blks, b)
+		cids = append(cids, b.Cid())
+	}
+	require.NoError(t, bs.PutMany(ctx, blks))
+	require.Len(t, client.objects, len(blks))
+
+	require.NoError(t, bs.DeleteMany(ctx, cids))
+	require.Empty(t, client.objects)
+	require.Equal(t, 3, client.deletes)
+}
@@ -0,0 +1,370 @@
+package blockstore
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	metrics "github.com/ipfs/go-metrics-interface"
+)
+
+// DefaultTTLJanitorInterval is the default interval at which expired blocks
+// are removed from a [TTLBlockstore].
+const DefaultTTLJanitorInterval = time.Minute
+
+// TTLOpts wraps options for [NewTTLBlockstore].
+type TTLOpts struct {
+	// TTL is the duration after which a block expires, counted from the last
+	// time it was put. Zero disables expiration.
+	TTL time.Duration
+
+	// MaxSize is the maximum total size in bytes of the blocks. When it is
+	// exceeded, the least recently accessed blocks are removed. Zero means
+	// unbounded.
+	MaxSize int64
+
+	// JanitorInterval is the interval at which expired blocks are removed in
+	// the background. Defaults to [DefaultTTLJanitorInterval], or TTL if it
+	// is smaller.
+	JanitorInterval time.Duration
+}
+
+type ttlEntry struct {
+	c        cid.Cid
+	size     int64
+	inserted time.Time
+
+	// Elements in TTLBlockstore.byInsertion and TTLBlockstore.byAccess.
+	insertionElem *list.Element
+	accessElem    *list.Element
+}
+
+// TTLBlockstore is a [Blockstore] which removes blocks from the wrapped
+// blockstore once they expire, or when the total size of the blocks exceeds a
+// budget, in which case the least recently accessed blocks are removed first.
+// It is intended for ephemeral caches, such as the ones used by gateways, which
+// must not grow unbounded.
+//
+// Blocks which are already in the wrapped blockstore when it is created are
+// tracked as if they were just put.
+type TTLBlockstore struct {
+	blockstore Blockstore
+	viewer     Viewer
+	opts       TTLOpts
+
+	lk      sync.Mutex
+	entries map[string]*ttlEntry
+	// Front is the oldest inserted block.
+	byInsertion *list.List
+	// Front is the least recently accessed block.
+	byAccess *list.List
+	size     int64
+
+	now func() time.Time
+
+	expired metrics.Counter
+	evicted metrics.Counter
+	blocks  metrics.Gauge
+	bytes   metrics.Gauge
+}
+
+var (
+	_ Blockstore = (*TTLBlockstore)(nil)
+	_ Viewer     = (*TTLBlockstore)(nil)
+)
+
+// NewTTLBlockstore wraps bs in a [TTLBlockstore]. The background janitor
+// removing expired blocks stops when ctx is done.
+func NewTTLBlockstore(ctx context.Context, bs Blockstore, opts TTLOpts) (*TTLBlockstore, error) {
+	if opts.TTL < 0 || opts.MaxSize < 0 || opts.JanitorInterval < 0 {
+		return nil, errors.New("all options for TTL blockstore need to be greater than or equal to zero")
+	}
+	if opts.JanitorInterval == 0 {
+		opts.JanitorInterval = DefaultTTLJanitorInterval
+		if opts.TTL > 0 && opts.TTL < opts.JanitorInterval {
+			opts.JanitorInterval = opts.TTL
+		}
+	}
+
+	b := &TTLBlockstore{
+		blockstore:  bs,
+		opts:        opts,
+		entries:     make(map[string]*ttlEntry),
+		byInsertion: list.New(),
+		byAccess:    list.New(),
+		now:         time.Now,
+		expired:     metrics.NewCtx(ctx, "boxo_blockstore.ttl_expired_total", "Number of blocks removed from the TTL blockstore because they expired").Counter(),
+		evicted:     metrics.NewCtx(ctx, "boxo_blockstore.ttl_evicted_total", "Number of blocks removed from the TTL blockstore because it exceeded its size budget").Counter(),
+		blocks:      metrics.NewCtx(ctx, "boxo_blockstore.ttl_blocks", "Number of blocks in the TTL blockstore").Gauge(),
+		bytes:       metrics.NewCtx(ctx, "boxo_blockstore.ttl_size_bytes", "Total size of the blocks in the TTL blockstore").Gauge(),
+	}
+	if v, ok := bs.(Viewer); ok {
+		b.viewer = v
+	}
+
+	if err := b.trackExisting(ctx); err != nil {
+		return nil, err
+	}
+
+	if opts.TTL > 0 {
+		go b.janitor(ctx)
+	}
+	return b, nil
+}
+
+func (b *TTLBlockstore) trackExisting(ctx context.Context) error {
+	ch, err := b.blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return err
+	}
+
+	for c := range ch {
+		size, err := b.blockstore.GetSize(ctx, c)
+		if err != nil {
+			if ipld.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if err := b.track(ctx, c, size); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (b *TTLBlockstore) janitor(ctx context.Context) {
+	t := time.NewTicker(b.opts.JanitorInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := b.removeExpired(ctx); err != nil {
+				logger.Errorf("removing expired blocks from TTL blockstore: %s", err)
+			}
+		}
+	}
+}
+
+// removeExpired removes all the expired blocks.
+func (b *TTLBlockstore) removeExpired(ctx context.Context) error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	now := b.now()
+	for e := b.byInsertion.Front(); e != nil; e = b.byInsertion.Front() {
+		entry := e.Value.(*ttlEntry)
+		if !b.isExpired(entry, now) {
+			break
+		}
+		if err := b.remove(ctx, entry); err != nil {
+			return err
+		}
+		b.expired.Inc()
+	}
+	return nil
+}
+
+func (b *TTLBlockstore) isExpired(entry *ttlEntry, now time.Time) bool {
+	return b.opts.TTL > 0 && now.Sub(entry.inserted) >= b.opts.TTL
+}
+
+// remove deletes the block from the wrapped blockstore and stops tracking it.
+// It must be called with the lock held.
+func (b *TTLBlockstore) remove(ctx context.Context, entry *ttlEntry) error {
+	if err := b.blockstore.DeleteBlock(ctx, entry.c); err != nil && !ipld.IsNotFound(err) {
+		return err
+	}
+	b.untrack(cacheKey(entry.c))
+	return nil
+}
+
+// untrack must be called with the lock held.
+func (b *TTLBlockstore) untrack(key string) {
+	entry, ok := b.entries[key]
+	if !ok {
+		return
+	}
+	delete(b.entries, key)
+	b.byInsertion.Remove(entry.insertionElem)
+	b.byAccess.Remove(entry.accessElem)
+	b.size -= entry.size
+	b.updateGauges()
+}
+
+// track records that a block was put, and removes the least recently accessed
+// blocks if the size budget is exceeded.
+func (b *TTLBlockstore) track(ctx context.Context, c cid.Cid, size int) error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	key := cacheKey(c)
+	now := b.now()
+	if entry, ok := b.entries[key]; ok {
+		entry.inserted = now
+		b.byInsertion.MoveToBack(entry.insertionElem)
+		b.byAccess.MoveToBack(entry.accessElem)
+	} else {
+		entry := &ttlEntry{c: c, size: int64(size), inserted: now}
+		entry.insertionElem = b.byInsertion.PushBack(entry)
+		entry.accessElem = b.byAccess.PushBack(entry)
+		b.entries[key] = entry
+		b.size += entry.size
+		b.updateGauges()
+	}
+
+	if b.opts.MaxSize == 0 {
+		return nil
+	}
+	for b.size > b.opts.MaxSize {
+		entry := b.byAccess.Front().Value.(*ttlEntry)
+		if err := b.remove(ctx, entry); err != nil {
+			return err
+		}
+		b.evicted.Inc()
+	}
+	return nil
+}
+
+// access records that a block was read. It returns false if the block is
+// expired, in which case it is removed.
+func (b *TTLBlockstore) access(ctx context.Context, c cid.Cid) (bool, error) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	entry, ok := b.entries[cacheKey(c)]
+	if !ok {
+		// Not tracked: either not present, or put concurrently.
+		return true, nil
+	}
+	if b.isExpired(entry, b.now()) {
+		if err := b.remove(ctx, entry); err != nil {
+			return false, err
+		}
+		b.expired.Inc()
+		return false, nil
+	}
+	b.byAccess.MoveToBack(entry.accessElem)
+	return true, nil
+}
+
+func (b *TTLBlockstore) updateGauges() {
+	b.blocks.Set(float64(len(b.entries)))
+	b.bytes.Set(float64(b.size))
+}
+
+// notFound stops tracking a block the wrapped blockstore does not have.
+func (b *TTLBlockstore) notFound(c cid.Cid) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.untrack(cacheKey(c))
+}
+
+func (b *TTLBlockstore) DeleteBlock(ctx context.Context, c cid.Cid) error {
+	err := b.blockstore.DeleteBlock(ctx, c)
+	if err == nil || ipld.IsNotFound(err) {
+		b.notFound(c)
+	}
+	return err
+}
+
+func (b *TTLBlockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	ok, err := b.access(ctx, c)
+	if err != nil || !ok {
+		return false, err
+	}
+	return b.blockstore.Has(ctx, c)
+}
+
+func (b *TTLBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	ok, err := b.access(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ipld.ErrNotFound{Cid: c}
+	}
+
+	blk, err := b.blockstore.Get(ctx, c)
+	if ipld.IsNotFound(err) {
+		b.notFound(c)
+	}
+	return blk, err
+}
+
+func (b *TTLBlockstore) View(ctx context.Context, c cid.Cid, callback func([]byte) error) error {
+	// shortcircuit and fall back to Get if the underlying store
+	// doesn't support Viewer.
+	if b.viewer == nil {
+		blk, err := b.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		return callback(blk.RawData())
+	}
+
+	ok, err := b.access(ctx, c)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ipld.ErrNotFound{Cid: c}
+	}
+
+	err = b.viewer.View(ctx, c, callback)
+	if ipld.IsNotFound(err) {
+		b.notFound(c)
+	}
+	return err
+}
+
+func (b *TTLBlockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	ok, err := b.access(ctx, c)
+	if err != nil {
+		return -1, err
+	}
+	if !ok {
+		return -1, ipld.ErrNotFound{Cid: c}
+	}
+
+	size, err := b.blockstore.GetSize(ctx, c)
+	if ipld.IsNotFound(err) {
+		b.notFound(c)
+	}
+	return size, err
+}
+
+func (b *TTLBlockstore) Put(ctx context.Context, blk blocks.Block) error {
+	if err := b.blockstore.Put(ctx, blk); err != nil {
+		return err
+	}
+	return b.track(ctx, blk.Cid(), len(blk.RawData()))
+}
+
+func (b *TTLBlockstore) PutMany(ctx context.Context, blks []blocks.Block) error {
+	if err := b.blockstore.PutMany(ctx, blks); err != nil {
+		return err
+	}
+	for _, blk := range blks {
+		if err := b.track(ctx, blk.Cid(), len(blk.RawData())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *TTLBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	return b.blockstore.AllKeysChan(ctx)
+}
+
+func (b *TTLBlockstore) HashOnRead(enabled bool) {
+	b.blockstore.HashOnRead(enabled)
+}
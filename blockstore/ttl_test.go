@@ -0,0 +1,194 @@
+package blockstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	ds "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func createTTLStores(t *testing.T, opts TTLOpts) (*TTLBlockstore, Blockstore, *fakeClock) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	bs := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	ttlbs, err := NewTTLBlockstore(ctx, bs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: time.Now()}
+	ttlbs.now = clock.now
+	return ttlbs, bs, clock
+}
+
+func TestTTLBlockstoreInvalidOptions(t *testing.T) {
+	bs := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	if _, err := NewTTLBlockstore(context.Background(), bs, TTLOpts{TTL: -1}); err == nil {
+		t.Fatal("expected an error for a negative TTL")
+	}
+	if _, err := NewTTLBlockstore(context.Background(), bs, TTLOpts{MaxSize: -1}); err == nil {
+		t.Fatal("expected an error for a negative size budget")
+	}
+}
+
+func TestTTLBlockstoreExpiresBlocks(t *testing.T) {
+	ctx := context.Background()
+	ttlbs, bs, clock := createTTLStores(t, TTLOpts{TTL: time.Hour, JanitorInterval: time.Hour})
+
+	a := blocks.NewBlock([]byte("a"))
+	b := blocks.NewBlock([]byte("b"))
+	if err := ttlbs.Put(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	clock.add(30 * time.Minute)
+	if err := ttlbs.Put(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Accessing a block does not extend its lifetime.
+	if _, err := ttlbs.Get(ctx, a.Cid()); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.add(30 * time.Minute)
+
+	// Expired blocks are not returned, even before the janitor runs.
+	if has, err := ttlbs.Has(ctx, a.Cid()); err != nil || has {
+		t.Fatalf("expected expired block to be missing, got has=%t err=%v", has, err)
+	}
+	if _, err := ttlbs.Get(ctx, a.Cid()); !ipld.IsNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if has, _ := bs.Has(ctx, a.Cid()); has {
+		t.Fatal("expired block should have been removed from the wrapped blockstore")
+	}
+
+	if has, err := ttlbs.Has(ctx, b.Cid()); err != nil || !has {
+		t.Fatalf("expected block to be present, got has=%t err=%v", has, err)
+	}
+
+	// Putting a block again extends its lifetime.
+	clock.add(20 * time.Minute)
+	if err := ttlbs.Put(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	clock.add(20 * time.Minute)
+	if err := ttlbs.removeExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := bs.Has(ctx, b.Cid()); !has {
+		t.Fatal("block put again should not have expired")
+	}
+
+	clock.add(time.Hour)
+	if err := ttlbs.removeExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := bs.Has(ctx, b.Cid()); has {
+		t.Fatal("janitor should have removed expired block")
+	}
+	if len(ttlbs.entries) != 0 || ttlbs.size != 0 {
+		t.Fatalf("expected no tracked blocks, got %d blocks of %d bytes", len(ttlbs.entries), ttlbs.size)
+	}
+}
+
+func TestTTLBlockstoreSizeBudget(t *testing.T) {
+	ctx := context.Background()
+	ttlbs, bs, _ := createTTLStores(t, TTLOpts{MaxSize: 6})
+
+	a := blocks.NewBlock([]byte("aa"))
+	b := blocks.NewBlock([]byte("bb"))
+	c := blocks.NewBlock([]byte("cc"))
+	d := blocks.NewBlock([]byte("dd"))
+
+	if err := ttlbs.PutMany(ctx, []blocks.Block{a, b, c}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Access a, so that b is the least recently used block.
+	if err := ttlbs.View(ctx, a.Cid(), func([]byte) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ttlbs.Put(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, blk := range []blocks.Block{a, c, d} {
+		if has, _ := bs.Has(ctx, blk.Cid()); !has {
+			t.Fatalf("expected %s to be kept", blk.RawData())
+		}
+	}
+	if has, _ := bs.Has(ctx, b.Cid()); has {
+		t.Fatal("expected least recently used block to be evicted")
+	}
+	if ttlbs.size != 6 {
+		t.Fatalf("expected 6 bytes to be tracked, got %d", ttlbs.size)
+	}
+}
+
+func TestTTLBlockstoreTracksExistingBlocks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bs := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	if err := bs.Put(ctx, exampleBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	ttlbs, err := NewTTLBlockstore(ctx, bs, TTLOpts{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ttlbs.entries) != 1 || ttlbs.size != int64(len(exampleBlock.RawData())) {
+		t.Fatalf("expected existing block to be tracked, got %d blocks of %d bytes", len(ttlbs.entries), ttlbs.size)
+	}
+
+	if err := ttlbs.DeleteBlock(ctx, exampleBlock.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if len(ttlbs.entries) != 0 || ttlbs.size != 0 {
+		t.Fatalf("expected no tracked blocks, got %d blocks of %d bytes", len(ttlbs.entries), ttlbs.size)
+	}
+}
+
+func TestTTLBlockstoreJanitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bs := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	ttlbs, err := NewTTLBlockstore(ctx, bs, TTLOpts{TTL: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ttlbs.Put(ctx, exampleBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if has, _ := bs.Has(ctx, exampleBlock.Cid()); !has {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("janitor did not remove expired block")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,84 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+)
+
+// Format is a file format which has a Splitter aligning chunks to its
+// records.
+type Format int
+
+const (
+	// FormatUnknown is any format without a dedicated Splitter.
+	FormatUnknown Format = iota
+	// FormatTar is the tar archive format, see NewTarSplitter.
+	FormatTar
+	// FormatSQLite is the SQLite database format, see NewSQLiteSplitter.
+	FormatSQLite
+)
+
+// DetectHeaderSize is the number of bytes DetectFormat needs to detect all
+// the formats.
+const DetectHeaderSize = tarBlockSize
+
+func (f Format) String() string {
+	switch f {
+	case FormatTar:
+		return "tar"
+	case FormatSQLite:
+		return "sqlite"
+	default:
+		return "unknown"
+	}
+}
+
+// DetectFormat returns the format of a file given its first bytes, see
+// DetectHeaderSize.
+func DetectFormat(header []byte) Format {
+	if sqlitePageSize(header) != 0 {
+		return FormatSQLite
+	}
+	if _, _, ok := parseTarHeader(header); ok {
+		return FormatTar
+	}
+	return FormatUnknown
+}
+
+// NewFormatSplitter detects the format of the data read from r and returns
+// the Splitter aligning chunks to its records, or a size-based Splitter for
+// unknown formats. The chunks are at most of the given size.
+func NewFormatSplitter(r io.Reader, size int64) (Splitter, error) {
+	header := make([]byte, DetectHeaderSize)
+	n, err := io.ReadFull(r, header)
+	switch err {
+	case nil, io.EOF, io.ErrUnexpectedEOF:
+	default:
+		return nil, err
+	}
+	header = header[:n]
+	mr := io.MultiReader(bytes.NewReader(header), r)
+
+	var spl Splitter
+	switch DetectFormat(header) {
+	case FormatTar:
+		spl = NewTarSplitter(mr, size)
+	case FormatSQLite:
+		spl = NewSQLiteSplitter(mr, size)
+	default:
+		spl = NewSizeSplitter(mr, size)
+	}
+	return &detectedSplitter{Splitter: spl, r: r}, nil
+}
+
+// detectedSplitter is a Splitter reading the header consumed by the format
+// detection before r.
+type detectedSplitter struct {
+	Splitter
+	r io.Reader
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ds *detectedSplitter) Reader() io.Reader {
+	return ds.r
+}
@@ -0,0 +1,34 @@
+package chunk
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		data   []byte
+		format Format
+	}{
+		{makeTar(t, tarFile{name: "a", content: []byte("hello"), modTime: time.Unix(1000, 0)}), FormatTar},
+		{makeSQLite(t, 1024, 2), FormatSQLite},
+		{randBuf(t, 1000), FormatUnknown},
+		{make([]byte, 1024), FormatUnknown},
+		{nil, FormatUnknown},
+	} {
+		if format := DetectFormat(tc.data); format != tc.format {
+			t.Fatalf("expected %s, got %s", tc.format, format)
+		}
+
+		spl, err := FromString(bytes.NewReader(tc.data), "auto-1024")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bytes.Join(splitAll(t, spl), nil), tc.data) {
+			t.Fatalf("%s: chunks do not add up to the input", tc.format)
+		}
+	}
+}
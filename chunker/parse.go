@@ -26,7 +26,8 @@ var (
 
 // FromString returns a Splitter depending on the given string:
 // it supports "default" (""), "size-{size}", "rabin", "rabin-{blocksize}",
-// "rabin-{min}-{avg}-{max}" and "buzhash".
+// "rabin-{min}-{avg}-{max}", "buzhash", and the format-aware "tar", "sqlite"
+// and "auto" splitters, optionally followed by "-{size}".
 func FromString(r io.Reader, chunker string) (Splitter, error) {
 	switch {
 	case chunker == "" || chunker == "default":
@@ -50,11 +51,50 @@ func FromString(r io.Reader, chunker string) (Splitter, error) {
 	case chunker == "buzhash":
 		return NewBuzhash(r), nil
 
+	case chunker == "tar" || strings.HasPrefix(chunker, "tar-"):
+		size, err := parseFormatSize(chunker)
+		if err != nil {
+			return nil, err
+		}
+		return NewTarSplitter(r, size), nil
+
+	case chunker == "sqlite" || strings.HasPrefix(chunker, "sqlite-"):
+		size, err := parseFormatSize(chunker)
+		if err != nil {
+			return nil, err
+		}
+		return NewSQLiteSplitter(r, size), nil
+
+	case chunker == "auto" || strings.HasPrefix(chunker, "auto-"):
+		size, err := parseFormatSize(chunker)
+		if err != nil {
+			return nil, err
+		}
+		return NewFormatSplitter(r, size)
+
 	default:
 		return nil, fmt.Errorf("unrecognized chunker option: %s", chunker)
 	}
 }
 
+// parseFormatSize returns the size of a "{format}-{size}" chunker string, or
+// the default block size if there is none.
+func parseFormatSize(chunker string) (int64, error) {
+	_, sizeStr, ok := strings.Cut(chunker, "-")
+	if !ok {
+		return DefaultBlockSize, nil
+	}
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return 0, err
+	} else if size <= 0 {
+		return 0, ErrSize
+	} else if size > ChunkSizeLimit {
+		return 0, ErrSizeMax
+	}
+	return int64(size), nil
+}
+
 func parseRabinString(r io.Reader, chunker string) (Splitter, error) {
 	parts := strings.Split(chunker, "-")
 	switch len(parts) {
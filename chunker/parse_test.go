@@ -81,3 +81,26 @@ func TestParseSize(t *testing.T) {
 		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
 	}
 }
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	r := bytes.NewReader(randBuf(t, 1000))
+
+	for _, chunker := range []string{"tar", "tar-1024", "sqlite", "sqlite-4096", "auto", "auto-2048"} {
+		_, err := FromString(r, chunker)
+		if err != nil {
+			t.Fatalf("%s: expected success, got: %#v", chunker, err)
+		}
+	}
+
+	_, err := FromString(r, "tar-0")
+	if err != ErrSize {
+		t.Fatalf("Expected an 'ErrSize' error, got: %#v", err)
+	}
+
+	_, err = FromString(r, fmt.Sprintf("sqlite-%d", 1+ChunkSizeLimit))
+	if err != ErrSizeMax {
+		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package chunk
+
+import (
+	"context"
+	"io"
+	"runtime"
+
+	mh "github.com/multiformats/go-multihash"
+)
+
+// Chunk is a chunk produced by a [Pipeline], with the multihash of its data.
+type Chunk struct {
+	// Offset is the position of the chunk in the data read by the splitter.
+	Offset uint64
+	Data   []byte
+	// Hash is the multihash of Data, which is the one of the CID of the
+	// chunk when it is stored as a raw leaf.
+	Hash mh.Multihash
+}
+
+// PipelineOpts wraps options for [NewPipeline].
+type PipelineOpts struct {
+	// HashFunc is the multihash function of the chunks. Defaults to
+	// sha2-256.
+	HashFunc uint64
+	// HashLength is the length of the digests, -1 for the default length of
+	// HashFunc. Defaults to -1.
+	HashLength int
+	// Workers is the number of goroutines hashing chunks. Defaults to
+	// GOMAXPROCS.
+	Workers int
+	// Buffer is the number of chunks read ahead of the consumer. Defaults to
+	// twice the number of workers.
+	Buffer int
+}
+
+// Pipeline splits data in a dedicated goroutine and hashes the chunks with
+// parallel workers, so that hashing overlaps with reading the data. Chunks
+// are delivered in order through a bounded channel.
+type Pipeline struct {
+	out    chan Chunk
+	cancel context.CancelFunc
+	err    error
+}
+
+type pipelineJob struct {
+	chunk Chunk
+	err   error
+	done  chan struct{}
+}
+
+// NewPipeline starts a [Pipeline] reading the chunks of s. The chunks must be
+// received from Chunks until the channel is closed, or the pipeline stopped
+// with Close or by canceling ctx.
+func NewPipeline(ctx context.Context, s Splitter, opts PipelineOpts) *Pipeline {
+	if opts.HashFunc == 0 {
+		opts.HashFunc = mh.SHA2_256
+	}
+	if opts.HashLength == 0 {
+		opts.HashLength = -1
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 2 * opts.Workers
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pipeline{
+		out:    make(chan Chunk),
+		cancel: cancel,
+	}
+
+	// jobs are hashed in any order by the workers, and delivered in the
+	// order of ordered
+	jobs := make(chan *pipelineJob, opts.Buffer)
+	ordered := make(chan *pipelineJob, opts.Buffer)
+	var splitErr error
+
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+
+		var offset uint64
+		for {
+			b, err := s.NextBytes()
+			if err != nil {
+				if err != io.EOF {
+					splitErr = err
+				}
+				return
+			}
+
+			j := &pipelineJob{chunk: Chunk{Offset: offset, Data: b}, done: make(chan struct{})}
+			offset += uint64(len(b))
+			select {
+			case ordered <- j:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			for j := range jobs {
+				j.chunk.Hash, j.err = mh.Sum(j.chunk.Data, opts.HashFunc, opts.HashLength)
+				close(j.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(p.out)
+		defer cancel()
+
+		for j := range ordered {
+			select {
+			case <-j.done:
+			case <-ctx.Done():
+				p.err = ctx.Err()
+				return
+			}
+			if j.err != nil {
+				p.err = j.err
+				return
+			}
+			select {
+			case p.out <- j.chunk:
+			case <-ctx.Done():
+				p.err = ctx.Err()
+				return
+			}
+		}
+		// ordered is closed once the splitter returned
+		p.err = splitErr
+		if p.err == nil {
+			p.err = ctx.Err()
+		}
+	}()
+
+	return p
+}
+
+// Chunks returns the channel of the chunks, which is closed once all the data
+// was read or the pipeline stopped.
+func (p *Pipeline) Chunks() <-chan Chunk {
+	return p.out
+}
+
+// Err returns the error which stopped the pipeline, if any. It must only be
+// called once the channel of Chunks is closed.
+func (p *Pipeline) Err() error {
+	return p.err
+}
+
+// Close stops the pipeline. A splitter blocked reading its reader is only
+// stopped once the read returns.
+func (p *Pipeline) Close() {
+	p.cancel()
+}
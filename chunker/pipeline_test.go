@@ -0,0 +1,102 @@
+package chunk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	data := randBuf(t, 1<<20)
+	p := NewPipeline(context.Background(), NewSizeSplitter(bytes.NewReader(data), 1000), PipelineOpts{Workers: 4})
+
+	var (
+		joined []byte
+		count  int
+	)
+	for c := range p.Chunks() {
+		if c.Offset != uint64(len(joined)) {
+			t.Fatalf("chunk %d has offset %d, expected %d", count, c.Offset, len(joined))
+		}
+		want, err := mh.Sum(c.Data, mh.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(c.Hash, want) {
+			t.Fatalf("chunk %d has the wrong hash", count)
+		}
+		joined = append(joined, c.Data...)
+		count++
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Fatal("chunks do not match the data")
+	}
+	if count != (len(data)+999)/1000 {
+		t.Fatalf("expected %d chunks, got %d", (len(data)+999)/1000, count)
+	}
+}
+
+type failingSplitter struct {
+	chunks int
+	err    error
+}
+
+func (s *failingSplitter) Reader() io.Reader {
+	return nil
+}
+
+func (s *failingSplitter) NextBytes() ([]byte, error) {
+	if s.chunks == 0 {
+		return nil, s.err
+	}
+	s.chunks--
+	return []byte("chunk"), nil
+}
+
+func TestPipelineError(t *testing.T) {
+	t.Parallel()
+
+	errSplit := errors.New("split error")
+	p := NewPipeline(context.Background(), &failingSplitter{chunks: 3, err: errSplit}, PipelineOpts{})
+	var count int
+	for range p.Chunks() {
+		count++
+	}
+	if count != 3 {
+		t.Fatalf("expected the 3 chunks before the error, got %d", count)
+	}
+	if !errors.Is(p.Err(), errSplit) {
+		t.Fatalf("expected split error, got %v", p.Err())
+	}
+
+	// unknown hash functions stop the pipeline
+	p = NewPipeline(context.Background(), &failingSplitter{chunks: 3, err: io.EOF}, PipelineOpts{HashFunc: 0xffffff})
+	for range p.Chunks() {
+		t.Fatal("expected no chunk")
+	}
+	if p.Err() == nil {
+		t.Fatal("expected hash error")
+	}
+}
+
+func TestPipelineClose(t *testing.T) {
+	t.Parallel()
+
+	p := NewPipeline(context.Background(), NewSizeSplitter(bytes.NewReader(randBuf(t, 1<<20)), 1000), PipelineOpts{Workers: 2})
+	<-p.Chunks()
+	p.Close()
+	for range p.Chunks() {
+	}
+	if !errors.Is(p.Err(), context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", p.Err())
+	}
+}
@@ -0,0 +1,77 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// sqliteMagic is the header string of SQLite database files.
+const sqliteMagic = "SQLite format 3\x00"
+
+// sqliteHeaderSize is the size of the start of the database header holding
+// the page size.
+const sqliteHeaderSize = 18
+
+type sqliteSplitter struct {
+	r    io.Reader
+	size int64
+
+	// spl splits the input once the page size is known
+	spl Splitter
+}
+
+// NewSQLiteSplitter returns a Splitter for SQLite database files, producing
+// chunks of the largest multiple of the database page size that does not
+// exceed the given size (or of a single page if they are larger). Pages are
+// the unit of change of the database, so the chunks of the pages that did
+// not change are the same in the different versions of a database.
+//
+// Input which is not a SQLite database is split in chunks of the given size.
+func NewSQLiteSplitter(r io.Reader, size int64) Splitter {
+	return &sqliteSplitter{r: r, size: size}
+}
+
+// NextBytes produces a new chunk.
+func (ss *sqliteSplitter) NextBytes() ([]byte, error) {
+	if ss.spl == nil {
+		hdr := make([]byte, sqliteHeaderSize)
+		n, err := io.ReadFull(ss.r, hdr)
+		switch err {
+		case nil, io.EOF, io.ErrUnexpectedEOF:
+		default:
+			return nil, err
+		}
+		hdr = hdr[:n]
+
+		size := ss.size
+		if pageSize := sqlitePageSize(hdr); pageSize != 0 {
+			size = max(size/pageSize, 1) * pageSize
+		}
+		ss.spl = NewSizeSplitter(io.MultiReader(bytes.NewReader(hdr), ss.r), size)
+	}
+	return ss.spl.NextBytes()
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ss *sqliteSplitter) Reader() io.Reader {
+	return ss.r
+}
+
+// sqlitePageSize returns the page size of a SQLite database from the start of
+// its header, or 0 if it is not a valid header.
+func sqlitePageSize(hdr []byte) int64 {
+	if len(hdr) < sqliteHeaderSize || string(hdr[:len(sqliteMagic)]) != sqliteMagic {
+		return 0
+	}
+
+	pageSize := int64(binary.BigEndian.Uint16(hdr[16:18]))
+	if pageSize == 1 {
+		return 65536
+	}
+	// a power of two between 512 and 32768
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return 0
+	}
+	return pageSize
+}
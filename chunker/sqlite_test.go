@@ -0,0 +1,61 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func makeSQLite(t *testing.T, pageSize, pages int) []byte {
+	data := randBuf(t, pageSize*pages)
+	copy(data, sqliteMagic)
+	field := uint16(pageSize)
+	if pageSize == 65536 {
+		field = 1
+	}
+	binary.BigEndian.PutUint16(data[16:18], field)
+	return data
+}
+
+func TestSQLiteSplitter(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pageSize, pages int
+		size            int64
+		chunkSize       int
+	}{
+		{4096, 10, 10000, 8192},
+		{4096, 10, 4096, 4096},
+		{65536, 3, DefaultBlockSize, 65536 * 3},
+		// pages larger than the chunk size are not split
+		{65536, 3, 1000, 65536},
+	} {
+		data := makeSQLite(t, tc.pageSize, tc.pages)
+		chunks := splitAll(t, NewSQLiteSplitter(bytes.NewReader(data), tc.size))
+		if !bytes.Equal(bytes.Join(chunks, nil), data) {
+			t.Fatal("chunks do not add up to the database")
+		}
+		for i, chunk := range chunks {
+			if i != len(chunks)-1 && len(chunk) != tc.chunkSize {
+				t.Fatalf("page size %d, size %d: expected chunks of %d bytes, got %d", tc.pageSize, tc.size, tc.chunkSize, len(chunk))
+			}
+			if len(chunk)%tc.pageSize != 0 {
+				t.Fatalf("page size %d: chunk of %d bytes is not aligned to pages", tc.pageSize, len(chunk))
+			}
+		}
+	}
+}
+
+func TestSQLiteSplitterNotSQLite(t *testing.T) {
+	t.Parallel()
+
+	data := randBuf(t, 1000)
+	chunks := splitAll(t, NewSQLiteSplitter(bytes.NewReader(data), 300))
+	if len(chunks) != 4 || len(chunks[0]) != 300 {
+		t.Fatal("expected non-sqlite input to be split by size")
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("chunks do not add up to the input")
+	}
+}
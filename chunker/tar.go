@@ -0,0 +1,179 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// tarBlockSize is the size of the records of tar archives.
+const tarBlockSize = 512
+
+// tarSplitter splits tar archives at the boundaries of their entries.
+type tarSplitter struct {
+	r    io.Reader
+	size int
+
+	// remaining is the number of bytes of the contents of the current
+	// entry, padding included, that are left to read
+	remaining int64
+
+	// fallback splits the rest of the input by size once the end of the
+	// archive, or something that is not a tar header, is reached
+	fallback Splitter
+
+	err error
+}
+
+// NewTarSplitter returns a Splitter aligning chunks to the entries of a tar
+// archive: the headers of each entry are a chunk of their own, and the
+// contents of the entry are split in chunks of the given size. This way, the
+// contents of the files that did not change produce the same chunks in the
+// different versions of an archive, wherever they are located in it.
+//
+// Input which is not a tar archive, or what follows the end of the archive,
+// is split in chunks of the given size.
+func NewTarSplitter(r io.Reader, size int64) Splitter {
+	return &tarSplitter{r: r, size: int(size)}
+}
+
+// NextBytes produces a new chunk.
+func (ts *tarSplitter) NextBytes() ([]byte, error) {
+	if ts.err != nil {
+		return nil, ts.err
+	}
+	if ts.fallback != nil {
+		return ts.fallback.NextBytes()
+	}
+
+	if ts.remaining > 0 {
+		buf := make([]byte, min(ts.remaining, int64(ts.size)))
+		n, err := io.ReadFull(ts.r, buf)
+		ts.remaining -= int64(n)
+		return ts.chunk(buf[:n], err)
+	}
+
+	// Read the headers of the next entry. Extended headers (PAX records,
+	// GNU long names) are kept in the chunk of the header they apply to.
+	var chunk []byte
+	for {
+		hdr := make([]byte, tarBlockSize)
+		n, err := io.ReadFull(ts.r, hdr)
+		if err != nil {
+			return ts.chunk(append(chunk, hdr[:n]...), err)
+		}
+
+		size, typeflag, ok := parseTarHeader(hdr)
+		if !ok {
+			// end of archive, or not a tar stream
+			ts.fallback = NewSizeSplitter(io.MultiReader(bytes.NewReader(hdr), ts.r), int64(ts.size))
+			if len(chunk) != 0 {
+				return chunk, nil
+			}
+			return ts.fallback.NextBytes()
+		}
+		chunk = append(chunk, hdr...)
+
+		padded := (size + tarBlockSize - 1) &^ (tarBlockSize - 1)
+		if isTarExtendedHeader(typeflag) && int64(len(chunk))+padded <= int64(ts.size) {
+			ext := make([]byte, padded)
+			n, err := io.ReadFull(ts.r, ext)
+			chunk = append(chunk, ext[:n]...)
+			if err != nil {
+				return ts.chunk(chunk, err)
+			}
+			continue
+		}
+
+		ts.remaining = padded
+		return chunk, nil
+	}
+}
+
+// chunk returns the chunk read before err, which ends the splitting if
+// it is not nil.
+func (ts *tarSplitter) chunk(chunk []byte, err error) ([]byte, error) {
+	switch err {
+	case nil:
+		return chunk, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		ts.err = io.EOF
+		if len(chunk) == 0 {
+			return nil, io.EOF
+		}
+		return chunk, nil
+	default:
+		ts.err = err
+		return nil, err
+	}
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ts *tarSplitter) Reader() io.Reader {
+	return ts.r
+}
+
+// isTarExtendedHeader returns true for the entries holding metadata of the
+// entry that follows them.
+func isTarExtendedHeader(typeflag byte) bool {
+	switch typeflag {
+	case 'x', 'g', 'L', 'K':
+		return true
+	default:
+		return false
+	}
+}
+
+// parseTarHeader returns the size of the contents and the type of the entry
+// of a tar header block, ok is false if the block is not a valid header.
+func parseTarHeader(hdr []byte) (size int64, typeflag byte, ok bool) {
+	if len(hdr) < tarBlockSize {
+		return 0, 0, false
+	}
+
+	chksum, err := parseTarOctal(hdr[148:156])
+	if err != nil {
+		return 0, 0, false
+	}
+	// The checksum is computed with the checksum field filled with spaces.
+	// Some historic implementations used signed bytes.
+	var unsigned, signed int64
+	for i, c := range hdr[:tarBlockSize] {
+		if i >= 148 && i < 156 {
+			c = ' '
+		}
+		unsigned += int64(c)
+		signed += int64(int8(c))
+	}
+	if chksum != unsigned && chksum != signed {
+		return 0, 0, false
+	}
+
+	if hdr[124]&0x80 != 0 {
+		// GNU base-256 encoding
+		if hdr[124]&0x40 != 0 {
+			return 0, 0, false // negative
+		}
+		size = int64(hdr[124] & 0x3f)
+		for _, c := range hdr[125:136] {
+			if size > (1<<62)>>8 {
+				return 0, 0, false
+			}
+			size = size<<8 | int64(c)
+		}
+	} else {
+		size, err = parseTarOctal(hdr[124:136])
+		if err != nil || size < 0 {
+			return 0, 0, false
+		}
+	}
+	return size, hdr[156], true
+}
+
+// parseTarOctal parses a NUL or space terminated octal number of a tar
+// header.
+func parseTarOctal(b []byte) (int64, error) {
+	s := strings.Trim(string(b), " \x00")
+	return strconv.ParseInt(s, 8, 64)
+}
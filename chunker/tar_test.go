@@ -0,0 +1,137 @@
+package chunk
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tarFile struct {
+	name    string
+	content []byte
+	modTime time.Time
+}
+
+func makeTar(t *testing.T, files ...tarFile) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     f.name,
+			Mode:     0o644,
+			Size:     int64(len(f.content)),
+			ModTime:  f.modTime,
+			Typeflag: tar.TypeReg,
+			Format:   tar.FormatPAX,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(f.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func splitAll(t *testing.T, spl Splitter) [][]byte {
+	var chunks [][]byte
+	for {
+		chunk, err := spl.NextBytes()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunk) == 0 {
+			t.Fatal("empty chunk")
+		}
+		chunks = append(chunks, copyBuf(chunk))
+	}
+}
+
+func TestTarSplitter(t *testing.T) {
+	t.Parallel()
+
+	const size = 64 << 10
+	a := tarFile{name: "a", content: randBuf(t, 100<<10), modTime: time.Unix(1000, 0)}
+	b := tarFile{name: "b", content: randBuf(t, 1000), modTime: time.Unix(1000, 0)}
+	c := tarFile{name: "c", content: randBuf(t, 300<<10), modTime: time.Unix(1000, 0)}
+
+	v1 := makeTar(t, a, b, c)
+	chunks1 := splitAll(t, NewTarSplitter(bytes.NewReader(v1), size))
+	if !bytes.Equal(bytes.Join(chunks1, nil), v1) {
+		t.Fatal("chunks do not add up to the archive")
+	}
+	for _, chunk := range chunks1 {
+		if len(chunk) > size {
+			t.Fatalf("chunk of %d bytes is larger than %d", len(chunk), size)
+		}
+	}
+
+	// a new file at the start and a touched file do not change the chunks
+	// of the contents of the other files
+	b.modTime = time.Unix(2000, 0)
+	v2 := makeTar(t, tarFile{name: "new", content: randBuf(t, 5000)}, a, b, c)
+	chunks2 := splitAll(t, NewTarSplitter(bytes.NewReader(v2), size))
+	if !bytes.Equal(bytes.Join(chunks2, nil), v2) {
+		t.Fatal("chunks do not add up to the archive")
+	}
+
+	known := make(map[string]bool)
+	for _, chunk := range chunks1 {
+		known[string(chunk)] = true
+	}
+	var shared, sharedBytes int
+	for _, chunk := range chunks2 {
+		if known[string(chunk)] {
+			shared++
+			sharedBytes += len(chunk)
+		}
+	}
+	// the contents of a, b and c, and the headers of a and c
+	if sharedBytes < len(a.content)+len(b.content)+len(c.content) {
+		t.Fatalf("only %d bytes in %d chunks are shared between the versions", sharedBytes, shared)
+	}
+}
+
+func TestTarSplitterExtendedHeaders(t *testing.T) {
+	t.Parallel()
+
+	name := strings.Repeat("long/", 40) + "name"
+	data := makeTar(t, tarFile{name: name, content: randBuf(t, 2000)})
+	chunks := splitAll(t, NewTarSplitter(bytes.NewReader(data), DefaultBlockSize))
+
+	// the PAX header holding the long name is in the chunk of the header
+	tr := tar.NewReader(bytes.NewReader(chunks[0]))
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != name {
+		t.Fatalf("expected the first chunk to hold the header of %s, got %s", name, hdr.Name)
+	}
+	if len(chunks[1]) != 2048 {
+		t.Fatalf("expected the second chunk to hold the padded contents, got %d bytes", len(chunks[1]))
+	}
+}
+
+func TestTarSplitterNotTar(t *testing.T) {
+	t.Parallel()
+
+	data := randBuf(t, 1000)
+	chunks := splitAll(t, NewTarSplitter(bytes.NewReader(data), 300))
+	if len(chunks) != 4 || len(chunks[0]) != 300 || len(chunks[3]) != 100 {
+		t.Fatal("expected non-tar input to be split by size")
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("chunks do not add up to the input")
+	}
+}
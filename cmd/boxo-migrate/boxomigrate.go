@@ -23,17 +23,21 @@ func loadConfig(configFile string) (migrate.Config, error) {
 	return migrate.DefaultConfig, nil
 }
 
-func buildMigrator(dryrun bool, configFile string) (*migrate.Migrator, error) {
+func buildMigrator(dryrun, diff bool, configFile string, only []string) (*migrate.Migrator, error) {
 	config, err := loadConfig(configFile)
 	if err != nil {
 		return nil, err
 	}
+	if len(only) > 0 {
+		config = config.Only(only)
+	}
 	dir, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("getting working dir: %w", err)
 	}
 	return &migrate.Migrator{
 		DryRun: dryrun,
+		Diff:   diff,
 		Dir:    dir,
 		Config: config,
 	}, nil
@@ -47,6 +51,10 @@ func main() {
 				Name:  "config",
 				Usage: "a JSON config file",
 			},
+			&cli.StringSliceFlag{
+				Name:  "only",
+				Usage: "only migrate the given import paths and the ones below them, can be repeated",
+			},
 		},
 		Commands: []*cli.Command{
 			{
@@ -56,6 +64,10 @@ func main() {
 					&cli.BoolFlag{
 						Name: "dryrun",
 					},
+					&cli.BoolFlag{
+						Name:  "diff",
+						Usage: "print the changes as unified diffs instead of writing them",
+					},
 					&cli.BoolFlag{
 						Name:  "force",
 						Usage: "run even if no .git folder is found",
@@ -63,15 +75,19 @@ func main() {
 				},
 				Action: func(clictx *cli.Context) error {
 					dryrun := clictx.Bool("dryrun")
+					diff := clictx.Bool("diff")
 					force := clictx.Bool("force")
 					configFile := clictx.String("config")
+					only := clictx.StringSlice("only")
 
-					migrator, err := buildMigrator(dryrun, configFile)
+					migrator, err := buildMigrator(dryrun, diff, configFile, only)
 					if err != nil {
 						return err
 					}
 
-					fmt.Printf("\n\n")
+					if !diff {
+						fmt.Printf("\n\n")
+					}
 
 					if !force {
 						p, err := os.Getwd()
@@ -101,7 +117,7 @@ check by adding the --force flag.`)
 						}
 					}
 
-					if !dryrun {
+					if !dryrun && !diff {
 						err := migrator.GoGet("github.com/ipfs/boxo@v0.8.0")
 						if err != nil {
 							return err
@@ -112,7 +128,11 @@ check by adding the --force flag.`)
 						return err
 					}
 
-					if dryrun {
+					if err := migrator.UpdateGoModReplaces(); err != nil {
+						return err
+					}
+
+					if dryrun || diff {
 						return nil
 					}
 
@@ -131,8 +151,9 @@ check by adding the --force flag.`)
 				Usage: "checks the current module for dependencies that have migrated to go-libipfs",
 				Action: func(clictx *cli.Context) error {
 					configFile := clictx.String("config")
+					only := clictx.StringSlice("only")
 
-					migrator, err := buildMigrator(false, configFile)
+					migrator, err := buildMigrator(false, false, configFile, only)
 					if err != nil {
 						return err
 					}
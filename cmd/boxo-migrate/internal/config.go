@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 )
 
 type Config struct {
@@ -112,3 +113,34 @@ func ReadConfig(r io.Reader) (Config, error) {
 	}
 	return config, nil
 }
+
+// Only returns a copy of the config restricted to the import paths and modules
+// which are equal to, or below, one of the given paths.
+func (c Config) Only(paths []string) Config {
+	matches := func(p string) bool {
+		for _, prefix := range paths {
+			if hasPathPrefix(p, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	config := Config{ImportPaths: map[string]string{}}
+	for from, to := range c.ImportPaths {
+		if matches(from) {
+			config.ImportPaths[from] = to
+		}
+	}
+	for _, mod := range c.Modules {
+		if matches(mod) {
+			config.Modules = append(config.Modules, mod)
+		}
+	}
+	return config
+}
+
+// hasPathPrefix reports whether p is equal to, or below, prefix.
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
@@ -12,16 +12,36 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 type Migrator struct {
 	DryRun bool
+	// Diff prints the changes as unified diffs instead of writing them.
+	Diff   bool
 	Dir    string
 	Config Config
 }
 
+// mapPath returns the path p is migrated to. The longest matching import path
+// of the config is used.
+func (m *Migrator) mapPath(p string) (string, bool) {
+	var from, to string
+	for f, t := range m.Config.ImportPaths {
+		if hasPathPrefix(p, f) && len(f) > len(from) {
+			from, to = f, t
+		}
+	}
+	if from == "" {
+		return "", false
+	}
+	return to + p[len(from):], true
+}
+
 func (m *Migrator) updateFileImports(filePath string) error {
 	fset := token.NewFileSet()
 	astFile, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
@@ -40,24 +60,16 @@ func (m *Migrator) updateFileImports(filePath string) error {
 				errr = err
 				return false
 			}
-			// we take the first matching prefix, so you need to make sure you don't have ambiguous mappings
-			for from, to := range m.Config.ImportPaths {
-				if strings.HasPrefix(val, from) {
-					var newVal string
-					switch {
-					case len(val) == len(from):
-						newVal = to
-					case val[len(from)] != '/':
-						continue
-					default:
-						newVal = to + val[len(from):]
-					}
-					fmt.Printf("changing %s => %s in %s\n", x.Path.Value, newVal, filePath)
-					if !m.DryRun {
-						x.Path.Value = strconv.Quote(newVal)
-						fileChanged = true
-					}
-				}
+			newVal, ok := m.mapPath(val)
+			if !ok {
+				return true
+			}
+			if !m.Diff {
+				fmt.Printf("changing %s => %s in %s\n", x.Path.Value, newVal, filePath)
+			}
+			if !m.DryRun {
+				x.Path.Value = strconv.Quote(newVal)
+				fileChanged = true
 			}
 		}
 		return true
@@ -70,21 +82,45 @@ func (m *Migrator) updateFileImports(filePath string) error {
 		return nil
 	}
 
-	f, err := os.Create(filePath)
+	var buf bytes.Buffer
+	err = format.Node(&buf, fset, astFile)
 	if err != nil {
-		return err
+		return fmt.Errorf("formatting %q: %w", filePath, err)
+	}
+
+	return m.writeFile(filePath, buf.Bytes())
+}
+
+// writeFile writes the new content of a file, or prints the unified diff of
+// the changes in diff mode.
+func (m *Migrator) writeFile(filePath string, content []byte) error {
+	if !m.Diff {
+		err := os.WriteFile(filePath, content, 0o644)
+		if err != nil {
+			return fmt.Errorf("writing %q: %w", filePath, err)
+		}
+		return nil
 	}
-	defer f.Close()
-	err = format.Node(f, fset, astFile)
+
+	old, err := os.ReadFile(filePath)
 	if err != nil {
-		f.Close()
-		return fmt.Errorf("formatting %q: %w", filePath, err)
+		return fmt.Errorf("reading %q: %w", filePath, err)
+	}
+	name := filePath
+	if rel, err := filepath.Rel(m.Dir, filePath); err == nil {
+		name = filepath.ToSlash(rel)
 	}
-	err = f.Close()
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(old)),
+		B:        difflib.SplitLines(string(content)),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
 	if err != nil {
-		return fmt.Errorf("closing %q: %w", filePath, err)
+		return fmt.Errorf("computing diff of %q: %w", filePath, err)
 	}
-
+	fmt.Print(diff)
 	return nil
 }
 
@@ -175,6 +211,78 @@ func (m *Migrator) UpdateImports() error {
 	return nil
 }
 
+// UpdateGoModReplaces rewrites the replace directives of the go.mod file of
+// the current module for any modules that have been migrated. Directives
+// replacing modules which have been migrated to Boxo are removed, since the
+// replaced code is not used anymore, and the other ones are rewritten to
+// replace the new module path.
+func (m *Migrator) UpdateGoModReplaces() error {
+	goModPath := filepath.Join(m.Dir, "go.mod")
+	content, err := os.ReadFile(goModPath)
+	if err != nil {
+		return fmt.Errorf("reading go.mod: %w", err)
+	}
+
+	var (
+		out         bytes.Buffer
+		inBlock     bool
+		fileChanged bool
+	)
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		fields := strings.Fields(line)
+		directive := fields
+		switch {
+		case inBlock && len(fields) > 0 && fields[0] == ")":
+			inBlock = false
+			directive = nil
+		case inBlock:
+		case len(fields) == 2 && fields[0] == "replace" && fields[1] == "(":
+			inBlock = true
+			directive = nil
+		case len(fields) > 1 && fields[0] == "replace":
+			directive = fields[1:]
+		default:
+			directive = nil
+		}
+
+		if len(directive) == 0 || strings.HasPrefix(directive[0], "//") {
+			out.WriteString(line)
+			continue
+		}
+		oldPath := directive[0]
+		newPath, ok := m.mapPath(oldPath)
+		if !ok || newPath == oldPath {
+			out.WriteString(line)
+			continue
+		}
+
+		if hasPathPrefix(newPath, "github.com/ipfs/boxo") {
+			if !m.Diff {
+				fmt.Printf("removing replace directive for %s in go.mod, it has been migrated to %s\n", oldPath, newPath)
+			}
+			if !m.DryRun {
+				fileChanged = true
+				continue
+			}
+		} else {
+			if !m.Diff {
+				fmt.Printf("changing replace directive for %s => %s in go.mod\n", oldPath, newPath)
+			}
+			if !m.DryRun {
+				fileChanged = true
+				i := strings.Index(line, oldPath)
+				line = line[:i] + newPath + line[i+len(oldPath):]
+			}
+		}
+		out.WriteString(line)
+	}
+
+	if !fileChanged {
+		return nil
+	}
+	return m.writeFile(goModPath, out.Bytes())
+}
+
 func (m *Migrator) GoModTidy() error {
 	fmt.Printf("\n\nRunning 'go mod tidy'...\n\n")
 	_, err := m.runOrErr("go", "mod", "tidy")
@@ -0,0 +1,173 @@
+package dshelp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/keytransform"
+	dsq "github.com/ipfs/go-datastore/query"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// migrateBatchSize is the number of keys moved per batch by ShardKeys and
+// UnshardKeys.
+const migrateBatchSize = 1024
+
+// ShardedKeyTransform partitions the keys created by MultihashToDsKey into
+// nested namespaces named after the first bytes of the multihash digest, in
+// hexadecimal. With two levels, /blocks/CIQ... becomes /blocks/ab/cd/CIQ...
+// where ab and cd are the first two bytes of the digest.
+//
+// Only the last component of the keys is considered, so the transform can
+// wrap the datastore of a blockstore which adds its own namespace. Keys that
+// are not multihashes are left untouched.
+//
+// On datastores ordered by key, the partitions keep the keys of a prefix
+// close to each other and bound the size of the key ranges that need to be
+// scanned, listed or backed up together.
+type ShardedKeyTransform struct {
+	levels int
+}
+
+var _ keytransform.KeyTransform = (*ShardedKeyTransform)(nil)
+
+// NewShardedKeyTransform returns a ShardedKeyTransform with the given number
+// of nested namespaces, each named after one byte of the digest.
+func NewShardedKeyTransform(levels int) *ShardedKeyTransform {
+	if levels < 1 {
+		panic("sharded key transform needs at least one level")
+	}
+	return &ShardedKeyTransform{levels: levels}
+}
+
+// Wrap returns a datastore applying the transform to the keys of d.
+func (t *ShardedKeyTransform) Wrap(d datastore.Datastore) *keytransform.Datastore {
+	return keytransform.Wrap(d, t)
+}
+
+// shards returns the namespaces of the multihash key name, or nil if it is
+// not a multihash key.
+func (t *ShardedKeyTransform) shards(name string) []string {
+	k, err := DsKeyToMultihash(datastore.RawKey("/" + name))
+	if err != nil {
+		return nil
+	}
+	dmh, err := mh.Decode(k)
+	if err != nil || len(dmh.Digest) < t.levels {
+		return nil
+	}
+	shards := make([]string, t.levels)
+	for i := range shards {
+		shards[i] = hex.EncodeToString(dmh.Digest[i : i+1])
+	}
+	return shards
+}
+
+// ConvertKey moves a flat multihash key to its partition.
+func (t *ShardedKeyTransform) ConvertKey(k datastore.Key) datastore.Key {
+	shards := t.shards(k.Name())
+	if shards == nil {
+		return k
+	}
+	return k.Parent().Child(datastore.NewKey(strings.Join(shards, "/"))).ChildString(k.Name())
+}
+
+// InvertKey returns the flat key of a partitioned multihash key.
+func (t *ShardedKeyTransform) InvertKey(k datastore.Key) datastore.Key {
+	list := k.List()
+	if len(list) <= t.levels {
+		return k
+	}
+	name := list[len(list)-1]
+	shards := t.shards(name)
+	if shards == nil {
+		return k
+	}
+	parents := list[:len(list)-1-t.levels]
+	for i, shard := range shards {
+		if list[len(parents)+i] != shard {
+			return k
+		}
+	}
+	return datastore.KeyWithNamespaces(append(parents, name))
+}
+
+// ShardKeys moves the flat multihash keys under prefix in d to their
+// partition of the transform, to migrate a datastore written without the
+// transform. The keys to move are listed before being moved, in batches. It
+// returns the number of keys moved.
+func ShardKeys(ctx context.Context, d datastore.Batching, prefix datastore.Key, t *ShardedKeyTransform) (int, error) {
+	return migrateKeys(ctx, d, prefix, func(k datastore.Key) (datastore.Key, bool) {
+		if t.InvertKey(k) != k {
+			return k, false // already sharded
+		}
+		nk := t.ConvertKey(k)
+		return nk, nk != k
+	})
+}
+
+// UnshardKeys moves the partitioned multihash keys under prefix in d back to
+// their flat location, to stop using the transform. It returns the number of
+// keys moved.
+func UnshardKeys(ctx context.Context, d datastore.Batching, prefix datastore.Key, t *ShardedKeyTransform) (int, error) {
+	return migrateKeys(ctx, d, prefix, func(k datastore.Key) (datastore.Key, bool) {
+		nk := t.InvertKey(k)
+		return nk, nk != k
+	})
+}
+
+func migrateKeys(ctx context.Context, d datastore.Batching, prefix datastore.Key, move func(datastore.Key) (datastore.Key, bool)) (int, error) {
+	res, err := d.Query(ctx, dsq.Query{Prefix: prefix.String(), KeysOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	var keys []datastore.Key
+	for r := range res.Next() {
+		if r.Error != nil {
+			res.Close()
+			return 0, r.Error
+		}
+		if k := datastore.RawKey(r.Key); !k.Equal(prefix) {
+			keys = append(keys, k)
+		}
+	}
+	if err := res.Close(); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for len(keys) != 0 {
+		n := min(len(keys), migrateBatchSize)
+		batch, err := d.Batch(ctx)
+		if err != nil {
+			return moved, err
+		}
+		count := 0
+		for _, k := range keys[:n] {
+			nk, ok := move(k)
+			if !ok {
+				continue
+			}
+			v, err := d.Get(ctx, k)
+			if err != nil {
+				return moved, fmt.Errorf("reading %s: %w", k, err)
+			}
+			if err := batch.Put(ctx, nk, v); err != nil {
+				return moved, err
+			}
+			if err := batch.Delete(ctx, k); err != nil {
+				return moved, err
+			}
+			count++
+		}
+		if err := batch.Commit(ctx); err != nil {
+			return moved, err
+		}
+		moved += count
+		keys = keys[n:]
+	}
+	return moved, nil
+}
@@ -0,0 +1,131 @@
+package dshelp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	dsq "github.com/ipfs/go-datastore/query"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestShardedKeyTransform(t *testing.T) {
+	h, err := mh.Sum([]byte("hello"), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat := datastore.NewKey("blocks").Child(MultihashToDsKey(h))
+
+	tr := NewShardedKeyTransform(2)
+	sharded := tr.ConvertKey(flat)
+	expected := "/blocks/2c/f2/" + flat.Name()
+	if sharded.String() != expected {
+		t.Fatalf("expected %s, got %s", expected, sharded)
+	}
+	if inv := tr.InvertKey(sharded); !inv.Equal(flat) {
+		t.Fatalf("expected %s, got %s", flat, inv)
+	}
+
+	// other keys are left as-is
+	for _, k := range []string{"/blocks", "/local/pins", "/blocks/aa/bb/" + flat.Name()} {
+		if conv := tr.InvertKey(datastore.NewKey(k)); conv.String() != k {
+			t.Fatalf("expected %s to be left as-is, got %s", k, conv)
+		}
+	}
+	if conv := tr.ConvertKey(datastore.NewKey("/local/pins")); conv.String() != "/local/pins" {
+		t.Fatalf("expected /local/pins to be left as-is, got %s", conv)
+	}
+}
+
+func TestShardKeys(t *testing.T) {
+	ctx := context.Background()
+	mds := datastore.NewMapDatastore()
+	tr := NewShardedKeyTransform(2)
+	prefix := datastore.NewKey("blocks")
+
+	// write flat keys, as a blockstore without the transform would
+	flatDs := namespace.Wrap(mds, prefix)
+	var keys []datastore.Key
+	for _, s := range []string{"a", "b", "c", "d"} {
+		h, err := mh.Sum([]byte(s), mh.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k := MultihashToDsKey(h)
+		keys = append(keys, k)
+		if err := flatDs.Put(ctx, k, []byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mds.Put(ctx, datastore.NewKey("/local/other"), []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := ShardKeys(ctx, mds, prefix, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != len(keys) {
+		t.Fatalf("expected %d keys to be moved, got %d", len(keys), moved)
+	}
+
+	// the raw keys are sharded and the blocks readable through the transform
+	res, err := mds.Query(ctx, dsq.Query{Prefix: "/blocks", KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Count(e.Key, "/") != 4 {
+			t.Fatalf("expected a sharded key, got %s", e.Key)
+		}
+	}
+	shardedDs := namespace.Wrap(tr.Wrap(mds), prefix)
+	for _, k := range keys {
+		if has, err := shardedDs.Has(ctx, k); err != nil || !has {
+			t.Fatalf("expected %s to be found, got %v, %v", k, has, err)
+		}
+	}
+	res, err = shardedDs.Query(ctx, dsq.Query{KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = res.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(keys) {
+		t.Fatalf("expected %d keys to be listed, got %d", len(keys), len(entries))
+	}
+	for _, e := range entries {
+		if _, err := DsKeyToMultihash(datastore.RawKey(e.Key)); err != nil {
+			t.Fatalf("expected a flat key to be listed, got %s", e.Key)
+		}
+	}
+
+	// migrating twice is a no-op
+	if moved, err = ShardKeys(ctx, mds, prefix, tr); err != nil || moved != 0 {
+		t.Fatalf("expected no keys to be moved, got %d, %v", moved, err)
+	}
+
+	moved, err = UnshardKeys(ctx, mds, prefix, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != len(keys) {
+		t.Fatalf("expected %d keys to be moved, got %d", len(keys), moved)
+	}
+	for _, k := range keys {
+		if has, err := flatDs.Has(ctx, k); err != nil || !has {
+			t.Fatalf("expected %s to be found, got %v, %v", k, has, err)
+		}
+	}
+	if has, _ := mds.Has(ctx, datastore.NewKey("/local/other")); !has {
+		t.Fatal("expected keys out of the prefix to be left untouched")
+	}
+}
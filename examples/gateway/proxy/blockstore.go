@@ -15,9 +15,10 @@ import (
 type proxyExchange struct {
 	httpClient *http.Client
 	gatewayURL string
+	sign       requestSigner
 }
 
-func newProxyExchange(gatewayURL string, client *http.Client) exchange.Interface {
+func newProxyExchange(gatewayURL string, client *http.Client, sign requestSigner) exchange.Interface {
 	if client == nil {
 		client = &http.Client{
 			Transport: otelhttp.NewTransport(http.DefaultTransport),
@@ -27,6 +28,7 @@ func newProxyExchange(gatewayURL string, client *http.Client) exchange.Interface
 	return &proxyExchange{
 		gatewayURL: gatewayURL,
 		httpClient: client,
+		sign:       sign,
 	}
 }
 
@@ -37,6 +39,11 @@ func (e *proxyExchange) fetch(ctx context.Context, c cid.Cid) (blocks.Block, err
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/vnd.ipld.raw")
+	if e.sign != nil {
+		if err := e.sign(req); err != nil {
+			return nil, err
+		}
+	}
 	resp, err := e.httpClient.Do(req)
 	if err != nil {
 		return nil, err
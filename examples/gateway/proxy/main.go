@@ -21,8 +21,17 @@ func main() {
 
 	gatewayUrlPtr := flag.String("g", "", "gateway to proxy to")
 	port := flag.Int("p", 8040, "port to run this gateway from")
+	headers := headerFlag{}
+	flag.Var(headers, "H", "header sent with the requests to the remote gateway, as \"Name: value\", can be repeated")
 	flag.Parse()
 
+	// Adds the headers to the requests sent to the remote gateway, for
+	// example to authenticate to a gateway that requires a bearer token.
+	var sign requestSigner
+	if len(headers) > 0 {
+		sign = headerSigner(http.Header(headers))
+	}
+
 	// Setups up tracing. This is optional and only required if the implementer
 	// wants to be able to enable tracing.
 	tp, err := common.SetupTracing(ctx, "CAR Gateway Example")
@@ -37,11 +46,11 @@ func main() {
 	blockStore := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
 
 	// Sets up the exchange, which will proxy the block requests to the given gateway.
-	e := newProxyExchange(*gatewayUrlPtr, nil)
+	e := newProxyExchange(*gatewayUrlPtr, nil, sign)
 	blockService := blockservice.New(blockStore, e)
 
 	// Sets up the routing system, which will proxy the IPNS routing requests to the given gateway.
-	routing := newProxyRouting(*gatewayUrlPtr, nil)
+	routing := newProxyRouting(*gatewayUrlPtr, nil, sign)
 
 	// Creates the gateway with the block service and the routing.
 	backend, err := gateway.NewBlocksBackend(blockService, gateway.WithValueStore(routing))
@@ -24,10 +24,14 @@ const (
 )
 
 func newProxyGateway(t *testing.T, rs *httptest.Server) *httptest.Server {
+	return newSigningProxyGateway(t, rs, nil)
+}
+
+func newSigningProxyGateway(t *testing.T, rs *httptest.Server, sign requestSigner) *httptest.Server {
 	blockStore := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
-	exch := newProxyExchange(rs.URL, nil)
+	exch := newProxyExchange(rs.URL, nil, sign)
 	blockService := blockservice.New(blockStore, exch)
-	routing := newProxyRouting(rs.URL, nil)
+	routing := newProxyRouting(rs.URL, nil, sign)
 
 	backend, err := gateway.NewBlocksBackend(blockService, gateway.WithValueStore(routing))
 	if err != nil {
@@ -75,6 +79,35 @@ func TestPassOnOnCorrectContent(t *testing.T) {
 	assert.EqualValues(t, string(body), "hello world")
 }
 
+func TestRequestSigner(t *testing.T) {
+	rs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	t.Cleanup(rs.Close)
+
+	headers := headerFlag{}
+	assert.NoError(t, headers.Set("Authorization: Bearer secret"))
+	assert.Error(t, headers.Set("invalid"))
+
+	for _, tc := range []struct {
+		sign   requestSigner
+		status int
+	}{
+		{nil, http.StatusInternalServerError},
+		{headerSigner(http.Header(headers)), http.StatusOK},
+	} {
+		ts := newSigningProxyGateway(t, rs, tc.sign)
+		res, err := http.Get(ts.URL + "/ipfs/" + HelloWorldCID)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.EqualValues(t, tc.status, res.StatusCode)
+	}
+}
+
 func TestTraceContext(t *testing.T) {
 	doCheckRequest := func(t *testing.T, req *http.Request) {
 		res, err := http.DefaultClient.Do(req)
@@ -15,9 +15,10 @@ import (
 type proxyRouting struct {
 	gatewayURL string
 	httpClient *http.Client
+	sign       requestSigner
 }
 
-func newProxyRouting(gatewayURL string, client *http.Client) routing.ValueStore {
+func newProxyRouting(gatewayURL string, client *http.Client, sign requestSigner) routing.ValueStore {
 	if client == nil {
 		client = &http.Client{
 			Transport: otelhttp.NewTransport(http.DefaultTransport),
@@ -27,6 +28,7 @@ func newProxyRouting(gatewayURL string, client *http.Client) routing.ValueStore
 	return &proxyRouting{
 		gatewayURL: gatewayURL,
 		httpClient: client,
+		sign:       sign,
 	}
 }
 
@@ -79,6 +81,11 @@ func (ps *proxyRouting) fetch(ctx context.Context, name ipns.Name) ([]byte, erro
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/vnd.ipfs.ipns-record")
+	if ps.sign != nil {
+		if err := ps.sign(req); err != nil {
+			return nil, err
+		}
+	}
 	resp, err := ps.httpClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// requestSigner adds the authorization expected by the remote gateway to a
+// request before it is sent, for example a bearer token, custom headers, or a
+// signature of the URL computed with a key shared with the remote gateway.
+type requestSigner func(*http.Request) error
+
+// headerSigner returns a requestSigner that sets the given headers on every
+// request.
+func headerSigner(h http.Header) requestSigner {
+	return func(r *http.Request) error {
+		for k, v := range h {
+			r.Header[k] = v
+		}
+		return nil
+	}
+}
+
+// headerFlag collects the "Name: value" headers passed with -H.
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	return fmt.Sprint(http.Header(h))
+}
+
+func (h headerFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("invalid header %q, expected \"Name: value\"", s)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	return nil
+}
@@ -38,3 +38,12 @@ type SessionExchange interface {
 	// in a row. The exchange can leverage that to be more efficient.
 	NewSession(context.Context) Fetcher
 }
+
+// DeletionNotifier is implemented by exchanges which keep state about the
+// blocks available locally, such as the responses queued for peers, to be
+// told about the blocks deleted from the local blockstore.
+type DeletionNotifier interface {
+	// NotifyDeletedBlocks tells the exchange that the blocks were deleted and
+	// can no longer be served.
+	NotifyDeletedBlocks(ctx context.Context, ks ...cid.Cid) error
+}
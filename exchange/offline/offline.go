@@ -4,6 +4,7 @@ package offline
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	blockstore "github.com/ipfs/boxo/blockstore"
@@ -13,27 +14,97 @@ import (
 	ipld "github.com/ipfs/go-ipld-format"
 )
 
-func Exchange(bs blockstore.Blockstore) exchange.Interface {
-	return &offlineExchange{bs: bs}
+// ErrBlockNotLocal is returned by the offline exchange for the blocks missing
+// from the blockstore. It wraps the error of the blockstore, so it is also an
+// [ipld.ErrNotFound].
+type ErrBlockNotLocal struct {
+	Cid cid.Cid
+	Err error
+}
+
+func (e *ErrBlockNotLocal) Error() string {
+	return fmt.Sprintf("block was not found locally (offline): %s", e.Err)
+}
+
+func (e *ErrBlockNotLocal) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the *ErrBlockNotLocal targets for the same CID, or for any CID
+// if the CID of the target is undefined.
+func (e *ErrBlockNotLocal) Is(target error) bool {
+	t, ok := target.(*ErrBlockNotLocal)
+	return ok && (!t.Cid.Defined() || t.Cid == e.Cid)
+}
+
+// Option is an option for [Exchange].
+type Option func(*offlineExchange)
+
+// WithStrict makes GetBlocks check that all the requested blocks are local
+// before returning any of them, and fail with the [*ErrBlockNotLocal] errors
+// of the missing ones, joined. By default, the missing blocks are skipped.
+func WithStrict(strict bool) Option {
+	return func(e *offlineExchange) {
+		e.strict = strict
+	}
+}
+
+// BatchGetter is implemented by the offline exchange, for callers which need
+// to know which blocks are missing.
+type BatchGetter interface {
+	// GetBlocksPartial returns the blocks found locally, in the order of ks,
+	// and the CIDs of the missing ones. The error is only set when the
+	// blockstore fails for other reasons than missing blocks.
+	GetBlocksPartial(ctx context.Context, ks []cid.Cid) ([]blocks.Block, []cid.Cid, error)
+}
+
+// Exchange returns an exchange which only gets the blocks of bs.
+func Exchange(bs blockstore.Blockstore, opts ...Option) exchange.Interface {
+	e := &offlineExchange{bs: bs}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // offlineExchange implements the Exchange interface but doesn't return blocks.
 // For use in offline mode.
 type offlineExchange struct {
-	bs blockstore.Blockstore
+	bs     blockstore.Blockstore
+	strict bool
 }
 
-// GetBlock returns nil to signal that a block could not be retrieved for the
-// given key.
+var _ BatchGetter = (*offlineExchange)(nil)
+
+// GetBlock returns an [*ErrBlockNotLocal] error to signal that a block could
+// not be retrieved for the given key.
 // NB: This function may return before the timeout expires.
 func (e *offlineExchange) GetBlock(ctx context.Context, k cid.Cid) (blocks.Block, error) {
 	blk, err := e.bs.Get(ctx, k)
 	if ipld.IsNotFound(err) {
-		return nil, fmt.Errorf("block was not found locally (offline): %w", err)
+		return nil, &ErrBlockNotLocal{Cid: k, Err: err}
 	}
 	return blk, err
 }
 
+// GetBlocksPartial implements [BatchGetter].
+func (e *offlineExchange) GetBlocksPartial(ctx context.Context, ks []cid.Cid) ([]blocks.Block, []cid.Cid, error) {
+	var found []blocks.Block
+	var missing []cid.Cid
+	for _, k := range ks {
+		blk, err := e.bs.Get(ctx, k)
+		switch {
+		case err == nil:
+			found = append(found, blk)
+		case ipld.IsNotFound(err):
+			missing = append(missing, k)
+		default:
+			return found, missing, err
+		}
+	}
+	return found, missing, nil
+}
+
 // NotifyNewBlocks tells the exchange that new blocks are available and can be served.
 func (e *offlineExchange) NotifyNewBlocks(ctx context.Context, blocks ...blocks.Block) error {
 	// as an offline exchange we have nothing to do
@@ -48,6 +119,22 @@ func (e *offlineExchange) Close() error {
 }
 
 func (e *offlineExchange) GetBlocks(ctx context.Context, ks []cid.Cid) (<-chan blocks.Block, error) {
+	if e.strict {
+		var errs []error
+		for _, k := range ks {
+			has, err := e.bs.Has(ctx, k)
+			if err != nil {
+				return nil, err
+			}
+			if !has {
+				errs = append(errs, &ErrBlockNotLocal{Cid: k, Err: ipld.ErrNotFound{Cid: k}})
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+	}
+
 	out := make(chan blocks.Block)
 	go func() {
 		defer close(out)
@@ -2,6 +2,7 @@ package offline
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	blockstore "github.com/ipfs/boxo/blockstore"
@@ -10,6 +11,7 @@ import (
 	ds "github.com/ipfs/go-datastore"
 	ds_sync "github.com/ipfs/go-datastore/sync"
 	blocksutil "github.com/ipfs/go-ipfs-blocksutil"
+	ipld "github.com/ipfs/go-ipld-format"
 )
 
 func TestBlockReturnsErr(t *testing.T) {
@@ -61,6 +63,58 @@ func TestGetBlocks(t *testing.T) {
 	}
 }
 
+func TestBlockNotLocal(t *testing.T) {
+	ctx := context.Background()
+	store := bstore()
+	g := blocksutil.NewBlockGenerator()
+	blks := g.Blocks(3)
+	if err := store.PutMany(ctx, blks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	ks := []cid.Cid{blks[0].Cid(), blks[1].Cid(), blks[2].Cid()}
+
+	_, err := Exchange(store).GetBlock(ctx, ks[1])
+	var notLocal *ErrBlockNotLocal
+	if !errors.As(err, &notLocal) || notLocal.Cid != ks[1] {
+		t.Fatalf("expected ErrBlockNotLocal for %s, got %v", ks[1], err)
+	}
+	if !errors.Is(err, &ErrBlockNotLocal{}) || !errors.Is(err, &ErrBlockNotLocal{Cid: ks[1]}) || errors.Is(err, &ErrBlockNotLocal{Cid: ks[2]}) {
+		t.Fatal("ErrBlockNotLocal does not match the CID")
+	}
+	if !ipld.IsNotFound(err) {
+		t.Fatal("expected ErrBlockNotLocal to be a not found error")
+	}
+
+	found, missing, err := Exchange(store).(BatchGetter).GetBlocksPartial(ctx, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Cid() != ks[0] {
+		t.Fatalf("expected to find %s, got %v", ks[0], found)
+	}
+	if len(missing) != 2 || missing[0] != ks[1] || missing[1] != ks[2] {
+		t.Fatalf("expected %v to be missing, got %v", ks[1:], missing)
+	}
+
+	// strict mode does not return anything when blocks are missing
+	_, err = Exchange(store, WithStrict(true)).GetBlocks(ctx, ks)
+	for _, k := range ks[1:] {
+		if !errors.Is(err, &ErrBlockNotLocal{Cid: k}) {
+			t.Fatalf("expected ErrBlockNotLocal for %s, got %v", k, err)
+		}
+	}
+	if errors.Is(err, &ErrBlockNotLocal{Cid: ks[0]}) {
+		t.Fatalf("unexpected ErrBlockNotLocal for %s", ks[0])
+	}
+	ch, err := Exchange(store, WithStrict(true)).GetBlocks(ctx, ks[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blk := <-ch; blk == nil || blk.Cid() != ks[0] {
+		t.Fatalf("expected %s, got %v", ks[0], blk)
+	}
+}
+
 func bstore() blockstore.Blockstore {
 	return blockstore.NewBlockstore(ds_sync.MutexWrap(ds.NewMapDatastore()))
 }
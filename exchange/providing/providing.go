@@ -0,0 +1,250 @@
+// Package providing implements an exchange wrapper which announces the blocks
+// given to NotifyNewBlocks to a content router.
+package providing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/ipfs/boxo/exchange"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"golang.org/x/sync/errgroup"
+)
+
+var log = logging.Logger("exchange/providing")
+
+const (
+	// DefaultBatchSize is the maximum number of CIDs announced per batch.
+	DefaultBatchSize = 128
+	// DefaultBatchInterval is the time between two batches.
+	DefaultBatchInterval = time.Second
+	// DefaultConcurrency is the number of Provide calls made at the same time.
+	DefaultConcurrency = 8
+	// DefaultMaxPending is the maximum number of CIDs waiting to be announced.
+	DefaultMaxPending = 1 << 16
+	// DefaultRecentSize is the number of recently announced CIDs which are
+	// not announced again.
+	DefaultRecentSize = 1 << 14
+)
+
+// Option is an option for [New].
+type Option func(*Exchange)
+
+// WithBatchSize sets the maximum number of CIDs announced per batch. It
+// defaults to [DefaultBatchSize].
+func WithBatchSize(n int) Option {
+	return func(e *Exchange) {
+		e.batchSize = n
+	}
+}
+
+// WithBatchInterval sets the time between two batches, so that at most one
+// batch of CIDs is announced per interval. It defaults to
+// [DefaultBatchInterval].
+func WithBatchInterval(d time.Duration) Option {
+	return func(e *Exchange) {
+		e.batchInterval = d
+	}
+}
+
+// WithConcurrency sets how many CIDs of a batch are announced at the same
+// time. It defaults to [DefaultConcurrency].
+func WithConcurrency(n int) Option {
+	return func(e *Exchange) {
+		e.concurrency = n
+	}
+}
+
+// WithMaxPending sets how many CIDs can wait to be announced. CIDs notified
+// while the queue is full are not announced. It defaults to
+// [DefaultMaxPending].
+func WithMaxPending(n int) Option {
+	return func(e *Exchange) {
+		e.maxPending = n
+	}
+}
+
+// WithRecentSize sets how many announced CIDs are remembered, and not
+// announced again when notified. It defaults to [DefaultRecentSize].
+func WithRecentSize(n int) Option {
+	return func(e *Exchange) {
+		e.recentSize = n
+	}
+}
+
+// Exchange is an [exchange.Interface] which announces the CIDs of the blocks
+// given to NotifyNewBlocks through a [routing.ContentRouting], in
+// deduplicated and rate-limited batches.
+type Exchange struct {
+	exchange.Interface
+	router routing.ContentRouting
+
+	batchSize     int
+	batchInterval time.Duration
+	concurrency   int
+	maxPending    int
+	recentSize    int
+
+	lk      sync.Mutex
+	queue   []cid.Cid
+	pending map[cid.Cid]struct{}
+	recent  *lru.Cache[cid.Cid, struct{}]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ exchange.SessionExchange = (*Exchange)(nil)
+
+// New returns an [Exchange] wrapping base, and starts announcing the notified
+// blocks until it is closed.
+func New(base exchange.Interface, router routing.ContentRouting, opts ...Option) (*Exchange, error) {
+	e := &Exchange{
+		Interface:     base,
+		router:        router,
+		batchSize:     DefaultBatchSize,
+		batchInterval: DefaultBatchInterval,
+		concurrency:   DefaultConcurrency,
+		maxPending:    DefaultMaxPending,
+		recentSize:    DefaultRecentSize,
+		pending:       make(map[cid.Cid]struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", e.batchSize)
+	}
+	if e.batchInterval <= 0 {
+		return nil, fmt.Errorf("invalid batch interval %s", e.batchInterval)
+	}
+	if e.concurrency <= 0 {
+		return nil, fmt.Errorf("invalid concurrency %d", e.concurrency)
+	}
+	if e.recentSize > 0 {
+		recent, err := lru.New[cid.Cid, struct{}](e.recentSize)
+		if err != nil {
+			return nil, err
+		}
+		e.recent = recent
+	}
+
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	go e.run()
+	return e, nil
+}
+
+// NotifyNewBlocks notifies the wrapped exchange, and queues the blocks to be
+// announced.
+func (e *Exchange) NotifyNewBlocks(ctx context.Context, blks ...blocks.Block) error {
+	if err := e.Interface.NotifyNewBlocks(ctx, blks...); err != nil {
+		return err
+	}
+
+	e.lk.Lock()
+	defer e.lk.Unlock()
+	dropped := 0
+	for _, b := range blks {
+		c := b.Cid()
+		if _, ok := e.pending[c]; ok {
+			continue
+		}
+		if e.recent != nil && e.recent.Contains(c) {
+			continue
+		}
+		if e.maxPending > 0 && len(e.queue) >= e.maxPending {
+			dropped++
+			continue
+		}
+		e.pending[c] = struct{}{}
+		e.queue = append(e.queue, c)
+	}
+	if dropped != 0 {
+		log.Warnf("provide queue full, %d blocks will not be announced", dropped)
+	}
+	return nil
+}
+
+// NewSession returns a session of the wrapped exchange if it supports them,
+// or the wrapped exchange itself.
+func (e *Exchange) NewSession(ctx context.Context) exchange.Fetcher {
+	if se, ok := e.Interface.(exchange.SessionExchange); ok {
+		return se.NewSession(ctx)
+	}
+	return e.Interface
+}
+
+// Close stops announcing blocks and closes the wrapped exchange. The CIDs
+// waiting to be announced are dropped.
+func (e *Exchange) Close() error {
+	e.cancel()
+	<-e.done
+	return e.Interface.Close()
+}
+
+func (e *Exchange) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.batchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if batch := e.nextBatch(); len(batch) != 0 {
+			e.provide(batch)
+		}
+	}
+}
+
+// nextBatch dequeues up to batchSize CIDs.
+func (e *Exchange) nextBatch() []cid.Cid {
+	e.lk.Lock()
+	defer e.lk.Unlock()
+
+	n := min(e.batchSize, len(e.queue))
+	batch := make([]cid.Cid, n)
+	copy(batch, e.queue)
+	clear(e.queue[:n])
+	e.queue = e.queue[n:]
+	if len(e.queue) == 0 {
+		// release the backing array
+		e.queue = nil
+	}
+	return batch
+}
+
+func (e *Exchange) provide(batch []cid.Cid) {
+	g := new(errgroup.Group)
+	g.SetLimit(e.concurrency)
+	for _, c := range batch {
+		c := c
+		g.Go(func() error {
+			if err := e.router.Provide(e.ctx, c, true); err != nil {
+				if e.ctx.Err() == nil {
+					log.Warnf("announcing %s: %s", c, err)
+				}
+			} else if e.recent != nil {
+				e.recent.Add(c, struct{}{})
+			}
+
+			e.lk.Lock()
+			delete(e.pending, c)
+			e.lk.Unlock()
+			return nil
+		})
+	}
+	g.Wait()
+}
@@ -0,0 +1,95 @@
+package providing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	blockstore "github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blocksutil "github.com/ipfs/go-ipfs-blocksutil"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRouter struct {
+	lk       sync.Mutex
+	provided map[cid.Cid]int
+	times    []time.Time
+}
+
+func (r *mockRouter) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.provided[c]++
+	r.times = append(r.times, time.Now())
+	return nil
+}
+
+func (r *mockRouter) FindProvidersAsync(ctx context.Context, c cid.Cid, n int) <-chan peer.AddrInfo {
+	ch := make(chan peer.AddrInfo)
+	close(ch)
+	return ch
+}
+
+func (r *mockRouter) count() int {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	return len(r.times)
+}
+
+func TestProvidingExchange(t *testing.T) {
+	ctx := context.Background()
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	router := &mockRouter{provided: make(map[cid.Cid]int)}
+	ex, err := New(offline.Exchange(bs), router, WithBatchSize(2), WithBatchInterval(20*time.Millisecond))
+	require.NoError(t, err)
+	defer ex.Close()
+
+	g := blocksutil.NewBlockGenerator()
+	blks := g.Blocks(5)
+	require.NoError(t, ex.NotifyNewBlocks(ctx, blks...))
+	// duplicates of pending blocks are not announced twice
+	require.NoError(t, ex.NotifyNewBlocks(ctx, blks[:2]...))
+
+	require.Eventually(t, func() bool { return router.count() == len(blks) }, 5*time.Second, 10*time.Millisecond)
+
+	// recently announced blocks are not announced again
+	require.NoError(t, ex.NotifyNewBlocks(ctx, blks...))
+	time.Sleep(100 * time.Millisecond)
+
+	router.lk.Lock()
+	defer router.lk.Unlock()
+	require.Len(t, router.times, len(blks))
+	for _, b := range blks {
+		require.Equal(t, 1, router.provided[b.Cid()])
+	}
+	// at most 2 CIDs are announced per batch, so the 5 CIDs take 3 batches
+	require.GreaterOrEqual(t, router.times[4].Sub(router.times[0]), 30*time.Millisecond)
+}
+
+func TestProvidingExchangeMaxPending(t *testing.T) {
+	ctx := context.Background()
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	router := &mockRouter{provided: make(map[cid.Cid]int)}
+	ex, err := New(offline.Exchange(bs), router, WithMaxPending(3), WithBatchInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	g := blocksutil.NewBlockGenerator()
+	blks := g.Blocks(5)
+	require.NoError(t, ex.NotifyNewBlocks(ctx, blks...))
+	require.Eventually(t, func() bool { return router.count() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, ex.Close())
+	require.Equal(t, 3, router.count())
+}
+
+func TestProvidingExchangeInvalidOptions(t *testing.T) {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	_, err := New(offline.Exchange(bs), &mockRouter{}, WithBatchSize(0))
+	require.Error(t, err)
+}
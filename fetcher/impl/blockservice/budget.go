@@ -0,0 +1,163 @@
+package bsfetcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/datamodel"
+)
+
+// Budget limits how much a single fetcher session is allowed to load, in order
+// to defend against maliciously deep or wide DAGs. A zero value for any of the
+// fields means the corresponding resource is not limited.
+type Budget struct {
+	// MaxBlocks is the maximum number of blocks loaded.
+	MaxBlocks int64
+
+	// MaxBytes is the maximum total size of the blocks loaded.
+	MaxBytes int64
+
+	// MaxDepth is the maximum number of links followed from the root of a
+	// traversal to reach a block. The root block has a depth of zero. Unlike
+	// the other limits, it applies to each traversal of the session.
+	MaxDepth int64
+}
+
+// BudgetResource identifies the resource of a [Budget] which was exceeded.
+type BudgetResource string
+
+const (
+	BudgetBlocks BudgetResource = "blocks"
+	BudgetBytes  BudgetResource = "bytes"
+	BudgetDepth  BudgetResource = "depth"
+)
+
+// ErrBudgetExceeded is returned when a fetcher session exceeds its [Budget].
+type ErrBudgetExceeded struct {
+	Resource BudgetResource
+	Limit    int64
+}
+
+func (e *ErrBudgetExceeded) Error() string {
+	return fmt.Sprintf("fetcher budget exceeded: more than %d %s", e.Limit, e.Resource)
+}
+
+// budgetTracker accounts for the resources used by a session. A nil
+// *budgetTracker does not enforce anything.
+type budgetTracker struct {
+	budget Budget
+
+	lk     sync.Mutex
+	blocks int64
+	bytes  int64
+}
+
+func newBudgetTracker(budget Budget) *budgetTracker {
+	if budget == (Budget{}) {
+		return nil
+	}
+	return &budgetTracker{budget: budget}
+}
+
+func (t *budgetTracker) beforeLoad(lnkCtx ipld.LinkContext) error {
+	if t == nil {
+		return nil
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if t.budget.MaxBlocks > 0 && t.blocks >= t.budget.MaxBlocks {
+		return &ErrBudgetExceeded{Resource: BudgetBlocks, Limit: t.budget.MaxBlocks}
+	}
+
+	if t.budget.MaxDepth > 0 && lnkCtx.Ctx != nil {
+		if d, ok := lnkCtx.Ctx.Value(depthTrackerKey{}).(*depthTracker); ok {
+			if !d.enter(lnkCtx.LinkPath, t.budget.MaxDepth) {
+				return &ErrBudgetExceeded{Resource: BudgetDepth, Limit: t.budget.MaxDepth}
+			}
+		}
+	}
+
+	t.blocks++
+	return nil
+}
+
+func (t *budgetTracker) afterLoad(size int) error {
+	if t == nil {
+		return nil
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	t.bytes += int64(size)
+	if t.budget.MaxBytes > 0 && t.bytes > t.budget.MaxBytes {
+		return &ErrBudgetExceeded{Resource: BudgetBytes, Limit: t.budget.MaxBytes}
+	}
+	return nil
+}
+
+type depthTrackerKey struct{}
+
+// depthTracker computes the depth of the blocks loaded by a traversal. It
+// keeps the chain of blocks leading to the last one loaded: traversals are
+// depth first, so the blocks outside of the chain are done with, and the
+// memory used is bounded by the depth of the DAG. A depthTracker is used by a
+// single traversal, carried by the context of the traversal.
+type depthTracker struct {
+	lk    sync.Mutex
+	chain []depthEntry
+}
+
+type depthEntry struct {
+	path  datamodel.Path
+	depth int64
+}
+
+// withDepthTracker returns a context carrying a new depthTracker, with the
+// root block of the traversal at the given path.
+func withDepthTracker(ctx context.Context, root datamodel.Path) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d := &depthTracker{chain: []depthEntry{{path: root}}}
+	return context.WithValue(ctx, depthTrackerKey{}, d)
+}
+
+// enter records the block linked at linkPath, one level deeper than the
+// closest block loaded at a parent path. It returns false, without recording
+// it, if the block is deeper than maxDepth.
+func (d *depthTracker) enter(linkPath datamodel.Path, maxDepth int64) bool {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	for len(d.chain) > 0 && !isParentPath(d.chain[len(d.chain)-1].path, linkPath) {
+		d.chain = d.chain[:len(d.chain)-1]
+	}
+	var depth int64
+	if len(d.chain) > 0 {
+		depth = d.chain[len(d.chain)-1].depth + 1
+	}
+	if depth > maxDepth {
+		return false
+	}
+	d.chain = append(d.chain, depthEntry{path: linkPath, depth: depth})
+	return true
+}
+
+// isParentPath returns true if parent is a strict prefix of p.
+func isParentPath(parent, p datamodel.Path) bool {
+	if parent.Len() >= p.Len() {
+		return false
+	}
+	segs := p.Segments()
+	for i, seg := range parent.Segments() {
+		if !seg.Equals(segs[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -19,6 +19,8 @@ import (
 type fetcherSession struct {
 	linkSystem   ipld.LinkSystem
 	protoChooser traversal.LinkTargetNodePrototypeChooser
+	// trackDepth is set when the depth of the traversals is limited
+	trackDepth bool
 }
 
 // FetcherConfig defines a configuration object from which Fetcher instances are constructed
@@ -26,6 +28,10 @@ type FetcherConfig struct {
 	blockService     blockservice.BlockService
 	NodeReifier      ipld.NodeReifier
 	PrototypeChooser traversal.LinkTargetNodePrototypeChooser
+
+	// Budget limits how much each session is allowed to load. The zero value
+	// does not set any limit.
+	Budget Budget
 }
 
 // NewFetcherConfig creates a FetchConfig from which session may be created and nodes retrieved.
@@ -47,11 +53,11 @@ func (fc FetcherConfig) FetcherWithSession(ctx context.Context, s *blockservice.
 	// while we may be loading blocks remotely, they are already hash verified by the time they load
 	// into ipld-prime
 	ls.TrustedStorage = true
-	ls.StorageReadOpener = blockOpener(ctx, s)
+	ls.StorageReadOpener = blockOpener(ctx, s, newBudgetTracker(fc.Budget))
 	ls.NodeReifier = fc.NodeReifier
 
 	protoChooser := fc.PrototypeChooser
-	return &fetcherSession{linkSystem: ls, protoChooser: protoChooser}
+	return &fetcherSession{linkSystem: ls, protoChooser: protoChooser, trackDepth: fc.Budget.MaxDepth > 0}
 }
 
 // WithReifier derives a different fetcher factory from the same source but
@@ -61,6 +67,7 @@ func (fc FetcherConfig) WithReifier(nr ipld.NodeReifier) fetcher.Factory {
 		blockService:     fc.blockService,
 		NodeReifier:      nr,
 		PrototypeChooser: fc.PrototypeChooser,
+		Budget:           fc.Budget,
 	}
 }
 
@@ -77,6 +84,10 @@ func (f *fetcherSession) nodeMatching(ctx context.Context, initialProgress trave
 	if err != nil {
 		return err
 	}
+	if f.trackDepth {
+		// the depths are tracked for this traversal only, and released with it
+		initialProgress.Cfg.Ctx = withDepthTracker(initialProgress.Cfg.Ctx, initialProgress.Path)
+	}
 	return initialProgress.WalkMatching(node, matchSelector, func(prog traversal.Progress, n ipld.Node) error {
 		return cb(fetcher.FetchResult{
 			Node:          n,
@@ -90,6 +101,7 @@ func (f *fetcherSession) nodeMatching(ctx context.Context, initialProgress trave
 func (f *fetcherSession) blankProgress(ctx context.Context) traversal.Progress {
 	return traversal.Progress{
 		Cfg: &traversal.Config{
+			Ctx:                            ctx,
 			LinkSystem:                     f.linkSystem,
 			LinkTargetNodePrototypeChooser: f.protoChooser,
 		},
@@ -131,18 +143,26 @@ var DefaultPrototypeChooser = func(lnk ipld.Link, lnkCtx ipld.LinkContext) (ipld
 	return basicnode.Prototype.Any, nil
 }
 
-func blockOpener(ctx context.Context, bs *blockservice.Session) ipld.BlockReadOpener {
-	return func(_ ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+func blockOpener(ctx context.Context, bs *blockservice.Session, budget *budgetTracker) ipld.BlockReadOpener {
+	return func(lnkCtx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
 		cidLink, ok := lnk.(cidlink.Link)
 		if !ok {
 			return nil, fmt.Errorf("invalid link type for loading: %v", lnk)
 		}
 
+		if err := budget.beforeLoad(lnkCtx); err != nil {
+			return nil, err
+		}
+
 		blk, err := bs.GetBlock(ctx, cidLink.Cid)
 		if err != nil {
 			return nil, err
 		}
 
+		if err := budget.afterLoad(len(blk.RawData())); err != nil {
+			return nil, err
+		}
+
 		return bytes.NewReader(blk.RawData()), nil
 	}
 }
@@ -12,12 +12,16 @@ import (
 	testinstance "github.com/ipfs/boxo/bitswap/testinstance"
 	tn "github.com/ipfs/boxo/bitswap/testnet"
 	"github.com/ipfs/boxo/blockservice"
+	"github.com/ipfs/boxo/blockstore"
+	offline "github.com/ipfs/boxo/exchange/offline"
 	"github.com/ipfs/boxo/fetcher"
 	"github.com/ipfs/boxo/fetcher/helpers"
 	bsfetcher "github.com/ipfs/boxo/fetcher/impl/blockservice"
 	"github.com/ipfs/boxo/fetcher/testutil"
 	mockrouting "github.com/ipfs/boxo/routing/mock"
 	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
 	delay "github.com/ipfs/go-ipfs-delay"
 	"github.com/ipld/go-ipld-prime"
 	"github.com/ipld/go-ipld-prime/fluent"
@@ -363,3 +367,65 @@ func TestNodeReification(t *testing.T) {
 	underlying4 := retrievedNode4.(*selfLoader).Node
 	assert.Equal(t, node4, underlying4)
 }
+
+func TestFetchBudget(t *testing.T) {
+	block3, _, link3 := testutil.EncodeBlock(fluent.MustBuildMap(basicnode.Prototype__Map{}, 1, func(na fluent.MapAssembler) {
+		na.AssembleEntry("three").AssignBool(true)
+	}))
+	block4, _, link4 := testutil.EncodeBlock(fluent.MustBuildMap(basicnode.Prototype__Map{}, 1, func(na fluent.MapAssembler) {
+		na.AssembleEntry("four").AssignBool(true)
+	}))
+	block2, _, link2 := testutil.EncodeBlock(fluent.MustBuildMap(basicnode.Prototype__Map{}, 2, func(na fluent.MapAssembler) {
+		na.AssembleEntry("link3").AssignLink(link3)
+		na.AssembleEntry("link4").AssignLink(link4)
+	}))
+	block1, _, _ := testutil.EncodeBlock(fluent.MustBuildMap(basicnode.Prototype__Map{}, 2, func(na fluent.MapAssembler) {
+		na.AssembleEntry("nested").CreateMap(1, func(na fluent.MapAssembler) {
+			na.AssembleEntry("link2").AssignLink(link2)
+		})
+	}))
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	err := bs.PutMany(bg, []blocks.Block{block1, block2, block3, block4})
+	require.NoError(t, err)
+	bsrv := blockservice.New(bs, offline.Exchange(bs))
+
+	totalBytes := int64(len(block1.RawData()) + len(block2.RawData()) + len(block3.RawData()) + len(block4.RawData()))
+
+	test := func(budget bsfetcher.Budget, expected bsfetcher.BudgetResource) {
+		t.Helper()
+
+		fetcherConfig := bsfetcher.NewFetcherConfig(bsrv)
+		fetcherConfig.Budget = budget
+		session := fetcherConfig.NewSession(context.Background())
+
+		err := helpers.BlockAll(bg, session, cidlink.Link{Cid: block1.Cid()}, func(fetcher.FetchResult) error {
+			return nil
+		})
+		if expected == "" {
+			require.NoError(t, err)
+			return
+		}
+
+		var budgetErr *bsfetcher.ErrBudgetExceeded
+		require.ErrorAs(t, err, &budgetErr)
+		require.Equal(t, expected, budgetErr.Resource)
+	}
+
+	test(bsfetcher.Budget{}, "")
+	test(bsfetcher.Budget{MaxBlocks: 4, MaxBytes: totalBytes, MaxDepth: 2}, "")
+	test(bsfetcher.Budget{MaxBlocks: 3}, bsfetcher.BudgetBlocks)
+	test(bsfetcher.Budget{MaxBytes: totalBytes - 1}, bsfetcher.BudgetBytes)
+	test(bsfetcher.Budget{MaxDepth: 1}, bsfetcher.BudgetDepth)
+
+	// The depth is limited per traversal of the session.
+	fetcherConfig := bsfetcher.NewFetcherConfig(bsrv)
+	fetcherConfig.Budget = bsfetcher.Budget{MaxDepth: 1}
+	session := fetcherConfig.NewSession(context.Background())
+	for i := 0; i < 2; i++ {
+		err := helpers.BlockAll(bg, session, cidlink.Link{Cid: block2.Cid()}, func(fetcher.FetchResult) error {
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
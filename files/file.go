@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"time"
 )
 
 var (
@@ -21,9 +22,20 @@ type Node interface {
 	// all files stored in the tree should be returned). Some implementations may
 	// choose not to implement this
 	Size() (int64, error)
+
+	// Mode returns the permissions and special mode bits of this file, or 0
+	// if they are not known.
+	Mode() os.FileMode
+
+	// ModTime returns the last modification time of this file, or the zero
+	// time if it is not known.
+	ModTime() time.Time
 }
 
 // Node represents a regular Unix file
+//
+// Files may also implement io.WriterTo, to be copied without intermediate
+// buffers, and io.ReaderAt, to be read at random offsets without seeking.
 type File interface {
 	Node
 
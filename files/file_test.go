@@ -49,6 +49,37 @@ func TestReaderFiles(t *testing.T) {
 	}
 }
 
+func TestReaderFileWriteToReadAt(t *testing.T) {
+	message := "beep boop"
+	rf := NewBytesFile([]byte(message)).(*ReaderFile)
+
+	buf := make([]byte, 4)
+	if n, err := rf.ReadAt(buf, 5); n != 4 || err != nil || string(buf) != "boop" {
+		t.Fatalf("expected to read boop, got %q, %v", buf[:n], err)
+	}
+	if _, err := rf.Read(buf[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if n, err := rf.WriteTo(&sb); n != int64(len(message)-1) || err != nil {
+		t.Fatalf("expected to write %d bytes, got %d, %v", len(message)-1, n, err)
+	}
+	if sb.String() != message[1:] {
+		t.Fatalf("expected %q, got %q", message[1:], sb.String())
+	}
+
+	rf = NewReaderFile(strings.NewReader(message)).(*ReaderFile)
+	sb.Reset()
+	if _, err := rf.WriteTo(&sb); err != nil || sb.String() != message {
+		t.Fatalf("expected %q, got %q, %v", message, sb.String(), err)
+	}
+	rf = NewReaderFile(io.MultiReader(strings.NewReader(message))).(*ReaderFile)
+	if _, err := rf.ReadAt(buf, 0); err != ErrNotSupported {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+}
+
 func TestMultipartFiles(t *testing.T) {
 	data := `
 --Boundary!
@@ -33,7 +33,7 @@ func WriteTo(nd Node, fpath string) error {
 		if err != nil {
 			return err
 		}
-		return nil
+		return setFileMetadata(nd, fpath)
 	case Directory:
 		err := os.Mkdir(fpath, 0o777)
 		if err != nil {
@@ -54,8 +54,29 @@ func WriteTo(nd Node, fpath string) error {
 				return err
 			}
 		}
-		return entries.Err()
+		if err := entries.Err(); err != nil {
+			return err
+		}
+		// Applied last so that read-only directories can still be filled and
+		// writing the children does not bump the modification time.
+		return setFileMetadata(nd, fpath)
 	default:
 		return fmt.Errorf("file type %T at %q is not supported", nd, fpath)
 	}
 }
+
+// setFileMetadata applies the mode and modification time of nd to fpath when
+// they are known.
+func setFileMetadata(nd Node, fpath string) error {
+	if mode := nd.Mode(); mode != 0 {
+		if err := os.Chmod(fpath, mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+	}
+	if mtime := nd.ModTime(); !mtime.IsZero() {
+		if err := os.Chtimes(fpath, mtime, mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -31,3 +32,29 @@ func TestWriteToInvalidPaths(t *testing.T) {
 		os.RemoveAll(path)
 	}
 }
+
+func TestWriteToPreservesModeAndModTime(t *testing.T) {
+	tmppath, err := os.MkdirTemp("", "files-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmppath)
+
+	src := filepath.Join(tmppath, "src")
+	assert.NoError(t, os.WriteFile(src, []byte("beep"), 0o644))
+	assert.NoError(t, os.Chmod(src, 0o600))
+	mtime := time.Unix(1700000000, 0)
+	assert.NoError(t, os.Chtimes(src, mtime, mtime))
+
+	stat, err := os.Stat(src)
+	assert.NoError(t, err)
+	sf, err := NewSerialFile(src, false, stat)
+	assert.NoError(t, err)
+	defer sf.Close()
+
+	dst := filepath.Join(tmppath, "dst")
+	assert.NoError(t, WriteTo(sf, dst))
+
+	stat, err = os.Stat(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), stat.Mode().Perm())
+	assert.True(t, stat.ModTime().Equal(mtime))
+}
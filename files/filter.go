@@ -2,10 +2,16 @@ package files
 
 import (
 	"os"
+	"path"
+	"strings"
 
 	ignore "github.com/crackcomm/go-gitignore"
 )
 
+// IpfsIgnoreFileName is the conventional name of per-directory ignore files,
+// see [FilterIgnoreFileName].
+const IpfsIgnoreFileName = ".ipfsignore"
+
 // Filter represents a set of rules for determining if a file should be included or excluded.
 // A rule follows the syntax for patterns used in .gitgnore files for specifying untracked files.
 // Examples:
@@ -19,6 +25,97 @@ type Filter struct {
 	IncludeHidden bool
 	// Rules - File filter rules
 	Rules *ignore.GitIgnore
+	// Include - If set, only the files matching these rules are included,
+	// directories are always traversed. Only used by ExcludePath.
+	Include *ignore.GitIgnore
+	// MaxFileSize - If positive, larger files are excluded. Only used by
+	// ExcludePath.
+	MaxFileSize int64
+	// IgnoreFileName - If set, the rules of the files with this name apply to
+	// the content of their directory. Only used by NewFilteredDirectory.
+	IgnoreFileName string
+}
+
+// FilterOption is an option for [NewFilterWithOptions].
+type FilterOption func(*filterConfig)
+
+type filterConfig struct {
+	filter      Filter
+	ignoreFile  string
+	exclude     []string
+	include     []string
+	withInclude bool
+}
+
+// FilterIgnoreFile reads exclude rules from the given .gitignore-style file.
+func FilterIgnoreFile(ignoreFile string) FilterOption {
+	return func(c *filterConfig) {
+		c.ignoreFile = ignoreFile
+	}
+}
+
+// FilterExclude excludes the files and directories matching the given
+// .gitignore-style rules.
+func FilterExclude(rules ...string) FilterOption {
+	return func(c *filterConfig) {
+		c.exclude = append(c.exclude, rules...)
+	}
+}
+
+// FilterInclude only includes the files matching the given .gitignore-style
+// rules, such as "*.jpg". Directories are still traversed, and exclude rules
+// take precedence.
+func FilterInclude(rules ...string) FilterOption {
+	return func(c *filterConfig) {
+		c.include = append(c.include, rules...)
+		c.withInclude = true
+	}
+}
+
+// FilterIncludeHidden includes hidden files, which are excluded by default.
+func FilterIncludeHidden(include bool) FilterOption {
+	return func(c *filterConfig) {
+		c.filter.IncludeHidden = include
+	}
+}
+
+// FilterMaxFileSize excludes the files larger than size bytes.
+func FilterMaxFileSize(size int64) FilterOption {
+	return func(c *filterConfig) {
+		c.filter.MaxFileSize = size
+	}
+}
+
+// FilterIgnoreFileName applies the rules of the files with the given name,
+// such as [IpfsIgnoreFileName], to the content of the directories they are in,
+// like .gitignore files. Ignore files are only read from directories on the
+// local filesystem, such as those created by [NewSerialFile].
+func FilterIgnoreFileName(name string) FilterOption {
+	return func(c *filterConfig) {
+		c.filter.IgnoreFileName = name
+	}
+}
+
+// NewFilterWithOptions creates a new file filter configured with the given
+// options, to be used with [NewFilteredDirectory].
+func NewFilterWithOptions(opts ...FilterOption) (*Filter, error) {
+	var c filterConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	f, err := NewFilter(c.ignoreFile, c.exclude, c.filter.IncludeHidden)
+	if err != nil {
+		return nil, err
+	}
+	if c.withInclude {
+		if f.Include, err = ignore.CompileIgnoreLines(c.include...); err != nil {
+			return nil, err
+		}
+	}
+	f.MaxFileSize = c.filter.MaxFileSize
+	f.IgnoreFileName = c.filter.IgnoreFileName
+	return f, nil
 }
 
 // NewFilter creates a new file filter from a .gitignore file and/or a list of ignore rules.
@@ -47,3 +144,47 @@ func (filter *Filter) ShouldExclude(fileInfo os.FileInfo) (result bool) {
 	}
 	return filter.Rules.MatchesPath(path)
 }
+
+// ExcludePath determines if the node at the given slash separated path,
+// relative to the root of the traversal, should be excluded. Unlike
+// ShouldExclude, the rules are matched against the whole path, and the
+// Include and MaxFileSize fields are used.
+func (filter *Filter) ExcludePath(p string, nd Node) bool {
+	if !filter.IncludeHidden && isHiddenNode(path.Base(p), nd) {
+		return true
+	}
+
+	_, isDir := nd.(Directory)
+	if isDir {
+		// match rules such as "bar/" which only apply to directories
+		p = strings.TrimSuffix(p, "/") + "/"
+	}
+	if filter.Rules != nil && filter.Rules.MatchesPath(p) {
+		return true
+	}
+	if isDir {
+		return false
+	}
+
+	if filter.Include != nil && !filter.Include.MatchesPath(p) {
+		return true
+	}
+	if filter.MaxFileSize > 0 {
+		if size, err := nd.Size(); err == nil && size > filter.MaxFileSize {
+			return true
+		}
+	}
+	return false
+}
+
+// isHiddenNode uses the file attributes of the node when it is on the local
+// filesystem, and its name otherwise.
+func isHiddenNode(name string, nd Node) bool {
+	if fi, ok := nd.(FileInfo); ok && fi.Stat() != nil {
+		return isHidden(fi.Stat())
+	}
+	if sf, ok := nd.(*serialFile); ok {
+		return isHidden(sf.stat)
+	}
+	return name != "" && name != "." && name != ".." && name[0] == '.'
+}
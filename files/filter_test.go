@@ -3,6 +3,7 @@ package files
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -51,3 +52,92 @@ func TestFileFilter(t *testing.T) {
 		t.Errorf("filter should've excluded expected file from ignoreFile: %s", "a.txt")
 	}
 }
+
+func TestFilteredDirectory(t *testing.T) {
+	filter, err := NewFilterWithOptions(
+		FilterExclude("build/", "*.tmp"),
+		FilterInclude("*.txt", "*.md"),
+		FilterMaxFileSize(10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := NewMapDirectory(map[string]Node{
+		"a.txt":   NewBytesFile([]byte("small")),
+		"b.txt":   NewBytesFile([]byte("larger than ten bytes")),
+		"c.jpg":   NewBytesFile([]byte("image")),
+		"d.tmp":   NewBytesFile([]byte("temp")),
+		".hidden": NewBytesFile([]byte("hidden")),
+		"build": NewMapDirectory(map[string]Node{
+			"out.txt": NewBytesFile([]byte("out")),
+		}),
+		"docs": NewMapDirectory(map[string]Node{
+			"README.md": NewBytesFile([]byte("readme")),
+			"x.tmp":     NewBytesFile([]byte("temp")),
+		}),
+	})
+
+	var got []string
+	err = Walk(NewFilteredDirectory(dir, filter), func(fpath string, nd Node) error {
+		got = append(got, filepath.ToSlash(fpath))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"", "a.txt", "docs", "docs/README.md"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestFilteredDirectoryIgnoreFile(t *testing.T) {
+	tmppath := t.TempDir()
+	for name, content := range map[string]string{
+		".ipfsignore":         "*.log\n",
+		"a.txt":               "a",
+		"a.log":               "a",
+		"sub/.ipfsignore":     "secret/\nb.txt\n",
+		"sub/b.txt":           "b",
+		"sub/c.txt":           "c",
+		"sub/c.log":           "c",
+		"sub/secret/key":      "key",
+		"other/b.txt":         "b",
+		"other/secret/public": "public",
+	} {
+		p := filepath.Join(tmppath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filter, err := NewFilterWithOptions(FilterIgnoreFileName(IpfsIgnoreFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(tmppath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, err := NewSerialFile(tmppath, true, stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = Walk(NewFilteredDirectory(sf.(Directory), filter), func(fpath string, nd Node) error {
+		got = append(got, filepath.ToSlash(fpath))
+		return nd.Close()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"", "a.txt", "other", "other/b.txt", "other/secret", "other/secret/public", "sub", "sub/c.txt"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
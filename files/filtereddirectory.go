@@ -0,0 +1,181 @@
+package files
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	ignore "github.com/crackcomm/go-gitignore"
+)
+
+// scopedRules are the rules of an ignore file, which apply to the content of
+// the directory at dir.
+type scopedRules struct {
+	dir   string
+	rules *ignore.GitIgnore
+}
+
+func (s scopedRules) matches(p string) bool {
+	if s.dir != "" {
+		p = strings.TrimPrefix(p, s.dir+"/")
+	}
+	return s.rules.MatchesPath(p)
+}
+
+type filteredDirectory struct {
+	Directory
+	filter *Filter
+	path   string
+	scoped []scopedRules
+}
+
+// NewFilteredDirectory returns a [Directory] whose entries, recursively, are
+// the ones of dir which are not excluded by filter, see [Filter.ExcludePath].
+// Excluded nodes are closed. If filter has an IgnoreFileName, the rules of the
+// ignore files found in the directories are applied to their content.
+//
+// Size iterates over the entries, so it can only be used on directories
+// whose Entries can be called more than once.
+func NewFilteredDirectory(dir Directory, filter *Filter) Directory {
+	return &filteredDirectory{Directory: dir, filter: filter}
+}
+
+func (d *filteredDirectory) Entries() DirIterator {
+	scoped := d.scoped
+	if d.filter.IgnoreFileName != "" {
+		rules, err := d.readIgnoreFile()
+		if err != nil {
+			return &filteredIterator{err: err}
+		}
+		if rules != nil {
+			// copy, the parent slice is shared with sibling directories
+			scoped = append(scoped[:len(scoped):len(scoped)], scopedRules{dir: d.path, rules: rules})
+		}
+	}
+
+	return &filteredIterator{
+		it:     d.Directory.Entries(),
+		filter: d.filter,
+		path:   d.path,
+		scoped: scoped,
+	}
+}
+
+// readIgnoreFile compiles the ignore file of the directory, if it is on the
+// local filesystem and has one.
+func (d *filteredDirectory) readIgnoreFile() (*ignore.GitIgnore, error) {
+	var dir string
+	switch f := d.Directory.(type) {
+	case *serialFile:
+		dir = f.path
+	case FileInfo:
+		dir = f.AbsPath()
+	default:
+		return nil, nil
+	}
+
+	rules, err := ignore.CompileIgnoreFile(filepath.Join(dir, d.filter.IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return rules, err
+}
+
+func (d *filteredDirectory) Size() (int64, error) {
+	var size int64
+
+	it := d.Entries()
+	for it.Next() {
+		s, err := it.Node().Size()
+		if err != nil {
+			return 0, err
+		}
+		size += s
+	}
+
+	return size, it.Err()
+}
+
+// sizeEstimate only iterates over the entries of directories which can be
+// estimated, as the entries of the others may not be iterable twice.
+func (d *filteredDirectory) sizeEstimate(budget *int) (int64, error) {
+	if _, ok := d.Directory.(sizeEstimator); !ok {
+		return 0, ErrNotSupported
+	}
+	return estimateEntries(d, budget)
+}
+
+type filteredIterator struct {
+	it     DirIterator
+	filter *Filter
+	path   string
+	scoped []scopedRules
+
+	cur Node
+	err error
+}
+
+func (it *filteredIterator) Name() string {
+	return it.it.Name()
+}
+
+func (it *filteredIterator) Node() Node {
+	return it.cur
+}
+
+func (it *filteredIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	for it.it.Next() {
+		p := path.Join(it.path, it.it.Name())
+		nd := it.it.Node()
+		if it.exclude(p, nd) {
+			if err := nd.Close(); err != nil {
+				it.err = err
+				return false
+			}
+			continue
+		}
+
+		if dir, ok := nd.(Directory); ok {
+			nd = &filteredDirectory{Directory: dir, filter: it.filter, path: p, scoped: it.scoped}
+		}
+		it.cur = nd
+		return true
+	}
+	return false
+}
+
+func (it *filteredIterator) exclude(p string, nd Node) bool {
+	if it.filter.ExcludePath(p, nd) {
+		return true
+	}
+
+	if _, ok := nd.(Directory); ok {
+		p += "/"
+	}
+	for _, s := range it.scoped {
+		if s.matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *filteredIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	if it.it == nil {
+		return nil
+	}
+	return it.it.Err()
+}
+
+var (
+	_ Directory   = &filteredDirectory{}
+	_ DirIterator = &filteredIterator{}
+)
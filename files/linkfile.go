@@ -3,12 +3,14 @@ package files
 import (
 	"os"
 	"strings"
+	"time"
 )
 
 type Symlink struct {
 	Target string
 
 	stat   os.FileInfo
+	mtime  time.Time
 	reader strings.Reader
 }
 
@@ -18,6 +20,14 @@ func NewLinkFile(target string, stat os.FileInfo) File {
 	return lf
 }
 
+// NewSymlinkFile creates a symlink without a backing [os.FileInfo], mtime is
+// reported by [Symlink.ModTime] and may be the zero time.
+func NewSymlinkFile(target string, mtime time.Time) File {
+	lf := &Symlink{Target: target, mtime: mtime}
+	lf.reader.Reset(lf.Target)
+	return lf
+}
+
 func (lf *Symlink) Close() error {
 	return nil
 }
@@ -34,6 +44,20 @@ func (lf *Symlink) Size() (int64, error) {
 	return lf.reader.Size(), nil
 }
 
+func (lf *Symlink) Mode() os.FileMode {
+	if lf.stat == nil {
+		return 0
+	}
+	return lf.stat.Mode()
+}
+
+func (lf *Symlink) ModTime() time.Time {
+	if lf.stat == nil {
+		return lf.mtime
+	}
+	return lf.stat.ModTime()
+}
+
 func ToSymlink(n Node) *Symlink {
 	l, _ := n.(*Symlink)
 	return l
@@ -0,0 +1,116 @@
+package files
+
+import (
+	"os"
+	"path"
+	"sync/atomic"
+)
+
+// ProgressFunc is called by the nodes created with [NewProgressFile] and
+// [NewProgressDirectory] after every read, with the cumulative number of bytes
+// read so far and the path of the file being read.
+type ProgressFunc func(total int64, path string)
+
+// progress is the state shared by the nodes of a progress reporting tree.
+type progress struct {
+	total atomic.Int64
+	fn    ProgressFunc
+}
+
+func (p *progress) wrap(nd Node, name string) Node {
+	switch nd := nd.(type) {
+	case *Symlink:
+		// importers tell symlinks by their type
+		return nd
+	case File:
+		return p.newFile(nd, name)
+	case Directory:
+		return &progressDirectory{Directory: nd, progress: p, path: name}
+	default:
+		return nd
+	}
+}
+
+func (p *progress) newFile(f File, name string) File {
+	pf := &progressFile{File: f, progress: p, path: name}
+	if fi, ok := f.(FileInfo); ok {
+		return &progressFileInfo{progressFile: pf, fi: fi}
+	}
+	return pf
+}
+
+type progressFile struct {
+	File
+	progress *progress
+	path     string
+}
+
+// NewProgressFile returns a [File] reading f which calls fn with the number of
+// bytes read so far and the given path, after every read. Bytes read again
+// after seeking back are counted again. The returned file implements
+// [FileInfo] if f does.
+func NewProgressFile(f File, path string, fn ProgressFunc) File {
+	return (&progress{fn: fn}).newFile(f, path)
+}
+
+func (f *progressFile) Read(b []byte) (int, error) {
+	n, err := f.File.Read(b)
+	if n > 0 {
+		f.progress.fn(f.progress.total.Add(int64(n)), f.path)
+	}
+	return n, err
+}
+
+type progressDirectory struct {
+	Directory
+	progress *progress
+	path     string
+}
+
+// NewProgressDirectory returns a [Directory] whose files, recursively, call fn
+// after every read with the number of bytes read so far from all the files of
+// dir, and the path of the file relative to dir. Symlinks and other special
+// nodes are returned as is. The returned files implement [FileInfo] if the
+// files they wrap do.
+func NewProgressDirectory(dir Directory, fn ProgressFunc) Directory {
+	return &progressDirectory{Directory: dir, progress: &progress{fn: fn}}
+}
+
+func (d *progressDirectory) Entries() DirIterator {
+	return &progressIterator{DirIterator: d.Directory.Entries(), dir: d}
+}
+
+func (d *progressDirectory) sizeEstimate(budget *int) (int64, error) {
+	return estimateSize(d.Directory, budget)
+}
+
+type progressIterator struct {
+	DirIterator
+	dir *progressDirectory
+}
+
+func (it *progressIterator) Node() Node {
+	return it.dir.progress.wrap(it.DirIterator.Node(), path.Join(it.dir.path, it.Name()))
+}
+
+// progressFileInfo is a progressFile wrapping a FileInfo, so that importers
+// can still tell files on disk, to add them without copying them.
+type progressFileInfo struct {
+	*progressFile
+	fi FileInfo
+}
+
+func (f *progressFileInfo) AbsPath() string {
+	return f.fi.AbsPath()
+}
+
+func (f *progressFileInfo) Stat() os.FileInfo {
+	return f.fi.Stat()
+}
+
+var (
+	_ File        = &progressFile{}
+	_ Directory   = &progressDirectory{}
+	_ DirIterator = &progressIterator{}
+	_ FileInfo    = &progressFileInfo{}
+)
@@ -0,0 +1,94 @@
+package files
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type progressCall struct {
+	total int64
+	path  string
+}
+
+func TestProgressFile(t *testing.T) {
+	var calls []progressCall
+	f := NewProgressFile(NewBytesFile([]byte("hello world")), "a.txt", func(total int64, path string) {
+		calls = append(calls, progressCall{total, path})
+	})
+
+	buf := make([]byte, 5)
+	_, err := io.ReadFull(f, buf)
+	require.NoError(t, err)
+	require.Equal(t, []progressCall{{5, "a.txt"}}, calls)
+
+	rest, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, " world", string(rest))
+	require.Equal(t, progressCall{11, "a.txt"}, calls[len(calls)-1])
+
+	size, err := f.Size()
+	require.NoError(t, err)
+	require.EqualValues(t, 11, size)
+}
+
+func TestProgressDirectory(t *testing.T) {
+	var calls []progressCall
+	d := NewProgressDirectory(NewMapDirectory(map[string]Node{
+		"a": NewBytesFile([]byte("aa")),
+		"b": NewMapDirectory(map[string]Node{
+			"c": NewBytesFile([]byte("ccc")),
+		}),
+		"l": NewSymlinkFile("a", time.Time{}),
+	}), func(total int64, path string) {
+		calls = append(calls, progressCall{total, path})
+	})
+
+	err := Walk(d, func(fpath string, nd Node) error {
+		if f, ok := nd.(File); ok {
+			_, err := io.Copy(io.Discard, f)
+			return err
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []progressCall{{2, "a"}, {5, "b/c"}}, calls)
+
+	size, err := d.Size()
+	require.NoError(t, err)
+	require.EqualValues(t, 6, size)
+	size, exact, err := SizeEstimate(d, 10)
+	require.NoError(t, err)
+	require.True(t, exact)
+	require.EqualValues(t, 6, size)
+}
+
+func TestProgressFileInfo(t *testing.T) {
+	dir := t.TempDir()
+	fpath := filepath.Join(dir, "a")
+	require.NoError(t, os.WriteFile(fpath, []byte("aa"), 0o644))
+	stat, err := os.Stat(dir)
+	require.NoError(t, err)
+
+	// files on disk keep their path, which NoCopy imports need
+	rf, err := NewReaderPathFile(fpath, io.NopCloser(strings.NewReader("aa")), nil)
+	require.NoError(t, err)
+	fi, ok := NewProgressFile(rf, "a", func(int64, string) {}).(FileInfo)
+	require.True(t, ok)
+	require.Equal(t, fpath, fi.AbsPath())
+
+	sf, err := NewSerialFile(dir, false, stat)
+	require.NoError(t, err)
+	it := NewProgressDirectory(sf.(Directory), func(int64, string) {}).Entries()
+	require.True(t, it.Next())
+	fi, ok = it.Node().(FileInfo)
+	require.True(t, ok)
+	require.Equal(t, fpath, fi.AbsPath())
+	require.NotNil(t, fi.Stat())
+	require.NoError(t, fi.Close())
+}
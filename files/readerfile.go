@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // ReaderFile is a implementation of File created from an `io.Reader`.
@@ -62,6 +63,26 @@ func (f *ReaderFile) Read(p []byte) (int, error) {
 	return f.reader.Read(p)
 }
 
+// WriteTo implements io.WriterTo, copying the rest of the file to w without an
+// intermediate buffer when the underlying reader is an io.WriterTo.
+func (f *ReaderFile) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := f.reader.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	// Hide the WriterTo method of f from io.Copy.
+	return io.Copy(w, struct{ io.Reader }{f.reader})
+}
+
+// ReadAt implements io.ReaderAt when the underlying reader is an io.ReaderAt,
+// it returns ErrNotSupported otherwise.
+func (f *ReaderFile) ReadAt(p []byte, off int64) (int, error) {
+	if ra, ok := f.reader.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+
+	return 0, ErrNotSupported
+}
+
 func (f *ReaderFile) Close() error {
 	return f.reader.Close()
 }
@@ -80,6 +101,20 @@ func (f *ReaderFile) Size() (int64, error) {
 	return f.stat.Size(), nil
 }
 
+func (f *ReaderFile) Mode() os.FileMode {
+	if f.stat == nil {
+		return 0
+	}
+	return f.stat.Mode()
+}
+
+func (f *ReaderFile) ModTime() time.Time {
+	if f.stat == nil {
+		return time.Time{}
+	}
+	return f.stat.ModTime()
+}
+
 func (f *ReaderFile) Seek(offset int64, whence int) (int64, error) {
 	if s, ok := f.reader.(io.Seeker); ok {
 		return s.Seek(offset, whence)
@@ -89,6 +124,8 @@ func (f *ReaderFile) Seek(offset int64, whence int) (int64, error) {
 }
 
 var (
-	_ File     = &ReaderFile{}
-	_ FileInfo = &ReaderFile{}
+	_ File        = &ReaderFile{}
+	_ FileInfo    = &ReaderFile{}
+	_ io.WriterTo = &ReaderFile{}
+	_ io.ReaderAt = &ReaderFile{}
 )
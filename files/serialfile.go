@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // serialFile implements Node, and reads from a path on the OS filesystem.
@@ -138,6 +139,44 @@ func (f *serialFile) Stat() os.FileInfo {
 	return f.stat
 }
 
+func (f *serialFile) Mode() os.FileMode {
+	return f.stat.Mode()
+}
+
+func (f *serialFile) ModTime() time.Time {
+	return f.stat.ModTime()
+}
+
+func (f *serialFile) sizeEstimate(budget *int) (int64, error) {
+	var du int64
+	err := filepath.WalkDir(f.path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == f.path {
+			return nil
+		}
+		if *budget <= 0 {
+			return errSizeBudget
+		}
+		*budget--
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if f.filter.ShouldExclude(fi) {
+			if fi.Mode().IsDir() {
+				return filepath.SkipDir
+			}
+		} else if fi.Mode().IsRegular() {
+			du += fi.Size()
+		}
+		return nil
+	})
+	return du, err
+}
+
 func (f *serialFile) Size() (int64, error) {
 	if !f.stat.IsDir() {
 		// something went terribly, terribly wrong
@@ -0,0 +1,142 @@
+package files
+
+import (
+	"errors"
+	"sync"
+)
+
+// sizeCache caches the cumulative size of a directory. It is invalidated when
+// the directory, or a directory nested in it, changes.
+type sizeCache struct {
+	mu    sync.Mutex
+	valid bool
+	size  int64
+
+	// parents are invalidated with the cache
+	parents []func()
+}
+
+// get returns the cached size, computing it if needed. Errors are not
+// cached.
+func (c *sizeCache) get(compute func() (int64, error)) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.valid {
+		return c.size, nil
+	}
+	size, err := compute()
+	if err != nil {
+		return 0, err
+	}
+	c.size, c.valid = size, true
+	return size, nil
+}
+
+// cached returns the cached size, if valid.
+func (c *sizeCache) cached() (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size, c.valid
+}
+
+// invalidate drops the cached size, and the ones of the parents.
+func (c *sizeCache) invalidate() {
+	c.mu.Lock()
+	c.valid = false
+	parents := c.parents
+	c.mu.Unlock()
+
+	for _, invalidate := range parents {
+		invalidate()
+	}
+}
+
+func (c *sizeCache) onInvalidate(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parents = append(c.parents, f)
+}
+
+// sizeNotifier is implemented by the directories caching their size, to
+// invalidate the caches of the directories containing them.
+type sizeNotifier interface {
+	onSizeChange(func())
+}
+
+// watchSize invalidates c when the size of nd changes, if it can tell.
+func watchSize(c *sizeCache, nd Node) {
+	if n, ok := nd.(sizeNotifier); ok {
+		n.onSizeChange(c.invalidate)
+	}
+}
+
+// errSizeBudget stops the size estimates which exceeded their budget.
+var errSizeBudget = errors.New("size estimate budget exceeded")
+
+// sizeEstimator is implemented by the nodes whose size can be estimated
+// within a budget of visited entries.
+type sizeEstimator interface {
+	// sizeEstimate returns the size, decrementing budget for every entry
+	// visited, or errSizeBudget with a lower bound of the size once budget
+	// is exhausted.
+	sizeEstimate(budget *int) (int64, error)
+}
+
+// SizeEstimate returns the size of n like Size, if it is cached or takes
+// visiting at most maxEntries entries of the nested directories, with exact
+// set to true. Else, it returns early with a lower bound of the size, which
+// is cheap enough to report the progress of long operations. The size of
+// the directories which cannot be estimated, like multipart ones, counts as
+// zero.
+func SizeEstimate(n Node, maxEntries int) (size int64, exact bool, err error) {
+	budget := maxEntries
+	size, err = estimateSize(n, &budget)
+	if err == errSizeBudget || err == ErrNotSupported {
+		return size, false, nil
+	}
+	return size, err == nil, err
+}
+
+func estimateSize(n Node, budget *int) (int64, error) {
+	switch n := n.(type) {
+	case sizeEstimator:
+		return n.sizeEstimate(budget)
+	case Directory:
+		return 0, ErrNotSupported
+	default:
+		return n.Size()
+	}
+}
+
+// estimateEntries estimates the cumulative size of the entries of d.
+func estimateEntries(d Directory, budget *int) (int64, error) {
+	var (
+		size        int64
+		unsupported bool
+	)
+	it := d.Entries()
+	for it.Next() {
+		if *budget <= 0 {
+			return size, errSizeBudget
+		}
+		*budget--
+
+		s, err := estimateSize(it.Node(), budget)
+		size += s
+		switch err {
+		case nil:
+		case ErrNotSupported:
+			// keep the lower bound
+			unsupported = true
+		default:
+			return size, err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return size, err
+	}
+	if unsupported {
+		return size, ErrNotSupported
+	}
+	return size, nil
+}
@@ -0,0 +1,79 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectorySizeCache(t *testing.T) {
+	nested := NewSortedDirectory()
+	require.NoError(t, nested.Add("a", NewBytesFile([]byte("aa"))))
+	d := NewMapDirectory(map[string]Node{
+		"nested": nested,
+		"b":      NewBytesFile([]byte("bbb")),
+	})
+
+	size, err := d.Size()
+	require.NoError(t, err)
+	require.EqualValues(t, 5, size)
+
+	// Adding to the nested directory invalidates the cached size of the
+	// parent
+	require.NoError(t, nested.Add("c", NewBytesFile([]byte("c"))))
+	size, err = d.Size()
+	require.NoError(t, err)
+	require.EqualValues(t, 6, size)
+}
+
+func TestSizeEstimate(t *testing.T) {
+	d := NewMapDirectory(map[string]Node{
+		"a": NewBytesFile([]byte("a")),
+		"b": NewBytesFile([]byte("bb")),
+		"c": NewMapDirectory(map[string]Node{
+			"d": NewBytesFile([]byte("ddd")),
+		}),
+	})
+
+	size, exact, err := SizeEstimate(d, 4)
+	require.NoError(t, err)
+	require.True(t, exact)
+	require.EqualValues(t, 6, size)
+
+	size, exact, err = SizeEstimate(d, 2)
+	require.NoError(t, err)
+	require.False(t, exact)
+	require.EqualValues(t, 3, size)
+
+	// Cached sizes are exact without visiting the entries
+	_, err = d.Size()
+	require.NoError(t, err)
+	size, exact, err = SizeEstimate(d, 0)
+	require.NoError(t, err)
+	require.True(t, exact)
+	require.EqualValues(t, 6, size)
+}
+
+func TestSerialFileSizeEstimate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "b"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b", "c"), []byte("cc"), 0o644))
+
+	stat, err := os.Stat(dir)
+	require.NoError(t, err)
+	sf, err := NewSerialFile(dir, false, stat)
+	require.NoError(t, err)
+
+	size, exact, err := SizeEstimate(sf, 10)
+	require.NoError(t, err)
+	require.True(t, exact)
+	require.EqualValues(t, 3, size)
+
+	size, exact, err = SizeEstimate(sf, 1)
+	require.NoError(t, err)
+	require.False(t, exact)
+	require.EqualValues(t, 1, size)
+}
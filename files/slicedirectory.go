@@ -1,6 +1,10 @@
 package files
 
-import "sort"
+import (
+	"os"
+	"sort"
+	"time"
+)
 
 type fileEntry struct {
 	name string
@@ -47,8 +51,12 @@ func (it *sliceIterator) Err() error {
 // SliceFile implements Node, and provides simple directory handling.
 // It contains children files, and is created from a `[]Node`.
 // SliceFiles are always directories, and can't be read from or closed.
+//
+// The size of a SliceFile is cached. It is invalidated when a nested
+// [SortedDirectory] changes, or with InvalidateSize.
 type SliceFile struct {
 	files []DirEntry
+	size  sizeCache
 }
 
 func NewMapDirectory(f map[string]Node) Directory {
@@ -64,7 +72,11 @@ func NewMapDirectory(f map[string]Node) Directory {
 }
 
 func NewSliceDirectory(files []DirEntry) Directory {
-	return &SliceFile{files}
+	f := &SliceFile{files: files}
+	for _, e := range files {
+		watchSize(&f.size, e.Node())
+	}
+	return f
 }
 
 func (f *SliceFile) Entries() DirIterator {
@@ -79,18 +91,46 @@ func (f *SliceFile) Length() int {
 	return len(f.files)
 }
 
-func (f *SliceFile) Size() (int64, error) {
-	var size int64
+func (f *SliceFile) Mode() os.FileMode {
+	return 0
+}
 
-	for _, file := range f.files {
-		s, err := file.Node().Size()
-		if err != nil {
-			return 0, err
+func (f *SliceFile) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (f *SliceFile) Size() (int64, error) {
+	return f.size.get(func() (int64, error) {
+		var size int64
+
+		for _, file := range f.files {
+			s, err := file.Node().Size()
+			if err != nil {
+				return 0, err
+			}
+			size += s
 		}
-		size += s
-	}
 
-	return size, nil
+		return size, nil
+	})
+}
+
+// InvalidateSize drops the cached size of the directory, and of the
+// directories containing it, after the size of an entry changed in a way the
+// directory cannot tell.
+func (f *SliceFile) InvalidateSize() {
+	f.size.invalidate()
+}
+
+func (f *SliceFile) onSizeChange(invalidate func()) {
+	f.size.onInvalidate(invalidate)
+}
+
+func (f *SliceFile) sizeEstimate(budget *int) (int64, error) {
+	if size, ok := f.size.cached(); ok {
+		return size, nil
+	}
+	return estimateEntries(f, budget)
 }
 
 var (
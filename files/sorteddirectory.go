@@ -0,0 +1,297 @@
+package files
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+)
+
+// ErrDuplicateEntry is returned when two entries of a [SortedDirectory] have
+// the same name.
+var ErrDuplicateEntry = errors.New("duplicate directory entry")
+
+// NodeCodec serializes the nodes a [SortedDirectory] spills to its datastore.
+// DecodeNode must recreate a node equivalent to the one given to EncodeNode,
+// for example by storing the path of a file on disk.
+type NodeCodec interface {
+	EncodeNode(Node) ([]byte, error)
+	DecodeNode([]byte) (Node, error)
+}
+
+// SortedDirectoryOption is an option for [NewSortedDirectory].
+type SortedDirectoryOption func(*SortedDirectory)
+
+// WithSpill makes the directory move its entries to the given datastore,
+// encoded with codec, every time more than threshold entries are held in
+// memory. Spilled nodes are closed once encoded.
+//
+// The datastore should be a temporary one dedicated to the directory, its
+// keys are read back in order when iterating.
+func WithSpill(d ds.Batching, threshold int, codec NodeCodec) SortedDirectoryOption {
+	return func(sd *SortedDirectory) {
+		sd.spill = d
+		sd.threshold = threshold
+		sd.codec = codec
+	}
+}
+
+// SortedDirectory is a [Directory] which is built incrementally with Add and
+// iterates its entries sorted by name. Unlike [NewMapDirectory], it only sorts
+// when entries were added out of order, and it can spill entries to a
+// datastore to build directories with millions of entries.
+//
+// The size of a SortedDirectory is cached. It is invalidated by Add, when a
+// nested directory changes, or with InvalidateSize.
+type SortedDirectory struct {
+	entries []DirEntry
+	sorted  bool
+	size    sizeCache
+
+	spill     ds.Batching
+	codec     NodeCodec
+	threshold int
+	spilled   int
+}
+
+// NewSortedDirectory creates an empty [SortedDirectory].
+func NewSortedDirectory(opts ...SortedDirectoryOption) *SortedDirectory {
+	d := &SortedDirectory{sorted: true}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Add adds an entry to the directory. Names must be unique, a duplicate is
+// reported by Add when the entries are spilled, or else by the iterator.
+func (d *SortedDirectory) Add(name string, nd Node) error {
+	if n := len(d.entries); n > 0 && name <= d.entries[n-1].Name() {
+		d.sorted = false
+	}
+	d.entries = append(d.entries, FileEntry(name, nd))
+	watchSize(&d.size, nd)
+	d.size.invalidate()
+
+	if d.spill != nil && len(d.entries) > d.threshold {
+		return d.flush(context.Background())
+	}
+	return nil
+}
+
+// Len returns the number of entries in the directory.
+func (d *SortedDirectory) Len() int {
+	return len(d.entries) + d.spilled
+}
+
+func (d *SortedDirectory) sort() error {
+	if !d.sorted {
+		sort.SliceStable(d.entries, func(i, j int) bool {
+			return d.entries[i].Name() < d.entries[j].Name()
+		})
+		d.sorted = true
+	}
+	for i := 1; i < len(d.entries); i++ {
+		if d.entries[i].Name() == d.entries[i-1].Name() {
+			return fmt.Errorf("%w: %q", ErrDuplicateEntry, d.entries[i].Name())
+		}
+	}
+	return nil
+}
+
+// flush moves the entries held in memory to the spill datastore.
+func (d *SortedDirectory) flush(ctx context.Context) error {
+	if err := d.sort(); err != nil {
+		return err
+	}
+
+	b, err := d.spill.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range d.entries {
+		key := spillKey(e.Name())
+		has, err := d.spill.Has(ctx, key)
+		if err != nil {
+			return err
+		}
+		if has {
+			return fmt.Errorf("%w: %q", ErrDuplicateEntry, e.Name())
+		}
+
+		data, err := d.codec.EncodeNode(e.Node())
+		if err != nil {
+			return fmt.Errorf("encoding %q: %w", e.Name(), err)
+		}
+		if err := b.Put(ctx, key, data); err != nil {
+			return err
+		}
+	}
+	if err := b.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, e := range d.entries {
+		if err := e.Node().Close(); err != nil {
+			return err
+		}
+	}
+	d.spilled += len(d.entries)
+	d.entries = nil
+	return nil
+}
+
+// spillKey is the hex encoded name, which keeps the order of the names.
+func spillKey(name string) ds.Key {
+	return ds.RawKey("/" + hex.EncodeToString([]byte(name)))
+}
+
+func (d *SortedDirectory) Entries() DirIterator {
+	if err := d.sort(); err != nil {
+		return &sortedIterator{err: err}
+	}
+	if d.spilled == 0 {
+		return &sliceIterator{files: d.entries, n: -1}
+	}
+
+	res, err := d.spill.Query(context.Background(), dsq.Query{Orders: []dsq.Order{dsq.OrderByKey{}}})
+	if err != nil {
+		return &sortedIterator{err: err}
+	}
+	return &sortedIterator{mem: d.entries, res: res, codec: d.codec}
+}
+
+func (d *SortedDirectory) Close() error {
+	return nil
+}
+
+func (d *SortedDirectory) Mode() os.FileMode {
+	return 0
+}
+
+func (d *SortedDirectory) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (d *SortedDirectory) Size() (int64, error) {
+	return d.size.get(func() (int64, error) {
+		var size int64
+
+		it := d.Entries()
+		for it.Next() {
+			s, err := it.Node().Size()
+			if err != nil {
+				return 0, err
+			}
+			size += s
+		}
+
+		return size, it.Err()
+	})
+}
+
+// InvalidateSize drops the cached size of the directory, and of the
+// directories containing it, after the size of an entry changed in a way the
+// directory cannot tell.
+func (d *SortedDirectory) InvalidateSize() {
+	d.size.invalidate()
+}
+
+func (d *SortedDirectory) onSizeChange(invalidate func()) {
+	d.size.onInvalidate(invalidate)
+}
+
+func (d *SortedDirectory) sizeEstimate(budget *int) (int64, error) {
+	if size, ok := d.size.cached(); ok {
+		return size, nil
+	}
+	return estimateEntries(d, budget)
+}
+
+// sortedIterator merges the entries held in memory with the spilled ones.
+type sortedIterator struct {
+	mem   []DirEntry
+	res   dsq.Results
+	codec NodeCodec
+
+	// next spilled entry, if any
+	spillName  string
+	spillValue []byte
+	hasSpill   bool
+	spillDone  bool
+
+	cur DirEntry
+	err error
+}
+
+func (it *sortedIterator) Name() string {
+	return it.cur.Name()
+}
+
+func (it *sortedIterator) Node() Node {
+	return it.cur.Node()
+}
+
+func (it *sortedIterator) Next() bool {
+	if it.err != nil || it.res == nil {
+		return false
+	}
+
+	if !it.hasSpill && !it.spillDone {
+		r, ok := it.res.NextSync()
+		switch {
+		case !ok:
+			it.spillDone = true
+		case r.Error != nil:
+			return it.fail(r.Error)
+		default:
+			name, err := hex.DecodeString(ds.RawKey(r.Key).BaseNamespace())
+			if err != nil {
+				return it.fail(fmt.Errorf("invalid spilled entry %q: %w", r.Key, err))
+			}
+			it.spillName, it.spillValue, it.hasSpill = string(name), r.Value, true
+		}
+	}
+
+	switch {
+	case it.hasSpill && (len(it.mem) == 0 || it.spillName < it.mem[0].Name()):
+		nd, err := it.codec.DecodeNode(it.spillValue)
+		if err != nil {
+			return it.fail(fmt.Errorf("decoding %q: %w", it.spillName, err))
+		}
+		it.cur = FileEntry(it.spillName, nd)
+		it.hasSpill = false
+	case len(it.mem) > 0:
+		if it.hasSpill && it.spillName == it.mem[0].Name() {
+			return it.fail(fmt.Errorf("%w: %q", ErrDuplicateEntry, it.spillName))
+		}
+		it.cur = it.mem[0]
+		it.mem = it.mem[1:]
+	default:
+		it.res.Close()
+		it.res = nil
+		return false
+	}
+	return true
+}
+
+func (it *sortedIterator) fail(err error) bool {
+	it.err = err
+	if it.res != nil {
+		it.res.Close()
+		it.res = nil
+	}
+	return false
+}
+
+func (it *sortedIterator) Err() error {
+	return it.err
+}
+
+var _ Directory = &SortedDirectory{}
@@ -0,0 +1,100 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+type bytesCodec struct{}
+
+func (bytesCodec) EncodeNode(nd Node) ([]byte, error) {
+	f, ok := nd.(File)
+	if !ok {
+		return nil, errors.New("not a file")
+	}
+	return io.ReadAll(f)
+}
+
+func (bytesCodec) DecodeNode(b []byte) (Node, error) {
+	return NewBytesFile(b), nil
+}
+
+func collectEntries(t *testing.T, d Directory) ([]string, []string) {
+	t.Helper()
+	var names, contents []string
+	it := d.Entries()
+	for it.Next() {
+		names = append(names, it.Name())
+		b, err := io.ReadAll(ToFile(it.Node()))
+		require.NoError(t, err)
+		contents = append(contents, string(b))
+	}
+	require.NoError(t, it.Err())
+	return names, contents
+}
+
+func TestSortedDirectory(t *testing.T) {
+	d := NewSortedDirectory()
+	for _, name := range []string{"b", "c", "a"} {
+		require.NoError(t, d.Add(name, NewBytesFile([]byte(name))))
+	}
+	require.Equal(t, 3, d.Len())
+
+	names, contents := collectEntries(t, d)
+	require.Equal(t, []string{"a", "b", "c"}, names)
+	require.Equal(t, names, contents)
+
+	size, err := d.Size()
+	require.NoError(t, err)
+	require.EqualValues(t, 3, size)
+
+	require.NoError(t, d.Add("a", NewBytesFile(nil)))
+	it := d.Entries()
+	require.False(t, it.Next())
+	require.ErrorIs(t, it.Err(), ErrDuplicateEntry)
+}
+
+func TestSortedDirectorySpill(t *testing.T) {
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := NewSortedDirectory(WithSpill(dstore, 4, bytesCodec{}))
+
+	var expected []string
+	for i := 19; i >= 0; i-- {
+		name := fmt.Sprintf("file-%02d", i)
+		expected = append([]string{name}, expected...)
+		require.NoError(t, d.Add(name, NewBytesFile([]byte(name))))
+	}
+	require.Equal(t, 20, d.Len())
+	require.Less(t, len(d.entries), 5)
+
+	names, contents := collectEntries(t, d)
+	require.Equal(t, expected, names)
+	require.Equal(t, expected, contents)
+
+	// duplicates of spilled entries are detected
+	require.NoError(t, d.Add("file-00", NewBytesFile(nil)))
+	it := d.Entries()
+	for it.Next() {
+	}
+	require.ErrorIs(t, it.Err(), ErrDuplicateEntry)
+}
+
+func TestSortedDirectorySpillDuplicateInMemory(t *testing.T) {
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := NewSortedDirectory(WithSpill(dstore, 2, bytesCodec{}))
+
+	for _, name := range []string{"a", "b", "c", "b"} {
+		require.NoError(t, d.Add(name, NewBytesFile([]byte(name))))
+	}
+
+	it := d.Entries()
+	for it.Next() {
+	}
+	require.ErrorIs(t, it.Err(), ErrDuplicateEntry)
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 )
@@ -16,21 +18,72 @@ type TarWriter struct {
 	TarW       *tar.Writer
 	baseDirSet bool
 	baseDir    string
+
+	sorted       bool
+	omitMetadata bool
+	modTime      time.Time
+}
+
+// TarWriterOption is an option for [NewTarWriter].
+type TarWriterOption func(*TarWriter)
+
+// WithTarSortedEntries writes the entries of every directory sorted by name,
+// rather than in the order of the directory iterator. The entries of a
+// directory are held in memory while it is written.
+func WithTarSortedEntries() TarWriterOption {
+	return func(w *TarWriter) {
+		w.sorted = true
+	}
+}
+
+// WithTarModTime sets the modification time written for the entries which do
+// not have one. Defaults to the time the entry is written. Use a fixed time,
+// such as the Unix epoch, for reproducible archives.
+func WithTarModTime(t time.Time) TarWriterOption {
+	return func(w *TarWriter) {
+		w.modTime = t
+	}
+}
+
+// WithTarOmitMetadata ignores the mode and modification time of the nodes,
+// and writes default permissions and the [WithTarModTime] time instead.
+func WithTarOmitMetadata() TarWriterOption {
+	return func(w *TarWriter) {
+		w.omitMetadata = true
+	}
 }
 
 // NewTarWriter wraps given io.Writer into a new tar writer
-func NewTarWriter(w io.Writer) (*TarWriter, error) {
-	return &TarWriter{
+func NewTarWriter(w io.Writer, opts ...TarWriterOption) (*TarWriter, error) {
+	tw := &TarWriter{
 		TarW: tar.NewWriter(w),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(tw)
+	}
+	return tw, nil
 }
 
 func (w *TarWriter) writeDir(f Directory, fpath string) error {
-	if err := writeDirHeader(w.TarW, fpath); err != nil {
+	if err := writeDirHeader(w.TarW, fpath, w.mode(f.Mode(), 0o777), w.mtime(f.ModTime())); err != nil {
 		return err
 	}
 
 	it := f.Entries()
+	if w.sorted {
+		var entries []DirEntry
+		for it.Next() {
+			entries = append(entries, FileEntry(it.Name(), it.Node()))
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+		it = &sliceIterator{files: entries, n: -1}
+	}
+
 	for it.Next() {
 		if err := w.WriteFile(it.Node(), path.Join(fpath, it.Name())); err != nil {
 			return err
@@ -45,7 +98,7 @@ func (w *TarWriter) writeFile(f File, fpath string) error {
 		return err
 	}
 
-	if err := writeFileHeader(w.TarW, fpath, uint64(size)); err != nil {
+	if err := writeFileHeader(w.TarW, fpath, uint64(size), w.mode(f.Mode(), 0o644), w.mtime(f.ModTime())); err != nil {
 		return err
 	}
 
@@ -89,7 +142,11 @@ func (w *TarWriter) WriteFile(nd Node, fpath string) error {
 
 	switch nd := nd.(type) {
 	case *Symlink:
-		return writeSymlinkHeader(w.TarW, nd.Target, fpath)
+		mtime := nd.ModTime()
+		if mtime.IsZero() || w.omitMetadata {
+			mtime = w.modTime
+		}
+		return writeSymlinkHeader(w.TarW, nd.Target, fpath, mtime)
 	case File:
 		return w.writeFile(nd, fpath)
 	case Directory:
@@ -104,32 +161,55 @@ func (w *TarWriter) Close() error {
 	return w.TarW.Close()
 }
 
-func writeDirHeader(w *tar.Writer, fpath string) error {
+func writeDirHeader(w *tar.Writer, fpath string, mode int64, mtime time.Time) error {
 	return w.WriteHeader(&tar.Header{
 		Name:     fpath,
 		Typeflag: tar.TypeDir,
-		Mode:     0o777,
-		ModTime:  time.Now().Truncate(time.Second),
-		// TODO: set mode, dates, etc. when added to unixFS
+		Mode:     mode,
+		ModTime:  mtime,
 	})
 }
 
-func writeFileHeader(w *tar.Writer, fpath string, size uint64) error {
+func writeFileHeader(w *tar.Writer, fpath string, size uint64, mode int64, mtime time.Time) error {
 	return w.WriteHeader(&tar.Header{
 		Name:     fpath,
 		Size:     int64(size),
 		Typeflag: tar.TypeReg,
-		Mode:     0o644,
-		ModTime:  time.Now().Truncate(time.Second),
-		// TODO: set mode, dates, etc. when added to unixFS
+		Mode:     mode,
+		ModTime:  mtime,
 	})
 }
 
-func writeSymlinkHeader(w *tar.Writer, target, fpath string) error {
-	return w.WriteHeader(&tar.Header{
+func writeSymlinkHeader(w *tar.Writer, target, fpath string, mtime time.Time) error {
+	hdr := &tar.Header{
 		Name:     fpath,
 		Linkname: target,
 		Mode:     0o777,
 		Typeflag: tar.TypeSymlink,
-	})
+	}
+	if !mtime.IsZero() {
+		hdr.ModTime = mtime
+	}
+	return w.WriteHeader(hdr)
+}
+
+// mode returns the POSIX permissions of mode, or def if mode is not known or
+// metadata is omitted.
+func (w *TarWriter) mode(mode os.FileMode, def int64) int64 {
+	if mode == 0 || w.omitMetadata {
+		return def
+	}
+	return int64(ModePermsToUnixPerms(mode))
+}
+
+// mtime returns mtime, or the [WithTarModTime] time if mtime is not known or
+// metadata is omitted, or else the current time.
+func (w *TarWriter) mtime(mtime time.Time) time.Time {
+	if !mtime.IsZero() && !w.omitMetadata {
+		return mtime
+	}
+	if !w.modTime.IsZero() {
+		return w.modTime
+	}
+	return time.Now().Truncate(time.Second)
 }
@@ -2,8 +2,11 @@ package files
 
 import (
 	"archive/tar"
+	"bytes"
 	"errors"
 	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -147,3 +150,75 @@ func TestTarWriterFailsFileOutsideRootWithBaseDir(t *testing.T) {
 		t.Errorf("unexpected error, wanted: %v; got: %v", ErrUnixFSPathOutsideRoot, err)
 	}
 }
+
+type metadataFileInfo struct {
+	os.FileInfo
+	size  int64
+	mode  os.FileMode
+	mtime time.Time
+}
+
+func (m metadataFileInfo) Size() int64        { return m.size }
+func (m metadataFileInfo) Mode() os.FileMode  { return m.mode }
+func (m metadataFileInfo) ModTime() time.Time { return m.mtime }
+
+func TestTarWriterDeterministic(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	newDir := func() Directory {
+		return NewSliceDirectory([]DirEntry{
+			FileEntry("b.txt", NewBytesFile([]byte("bloop"))),
+			FileEntry("a.txt", NewReaderStatFile(strings.NewReader("bleep"), metadataFileInfo{size: 5, mode: 0o600, mtime: mtime})),
+		})
+	}
+	write := func(opts ...TarWriterOption) []byte {
+		var buf bytes.Buffer
+		tw, err := NewTarWriter(&buf, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.WriteFile(newDir(), "root"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	headers := func(b []byte) []*tar.Header {
+		var hdrs []*tar.Header
+		tr := tar.NewReader(bytes.NewReader(b))
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return hdrs
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			hdrs = append(hdrs, hdr)
+		}
+	}
+
+	opts := []TarWriterOption{WithTarSortedEntries(), WithTarModTime(time.Unix(0, 0))}
+	first := write(opts...)
+	time.Sleep(time.Second)
+	if !bytes.Equal(first, write(opts...)) {
+		t.Fatal("expected identical archives")
+	}
+
+	hdrs := headers(first)
+	if len(hdrs) != 3 || hdrs[1].Name != "root/a.txt" || hdrs[2].Name != "root/b.txt" {
+		t.Fatalf("expected entries sorted by name, got %v", hdrs)
+	}
+	if hdrs[1].Mode != 0o600 || !hdrs[1].ModTime.Equal(mtime) {
+		t.Errorf("expected metadata to be preserved, got mode %o and mtime %s", hdrs[1].Mode, hdrs[1].ModTime)
+	}
+	if hdrs[2].ModTime.Unix() != 0 {
+		t.Errorf("expected default mtime, got %s", hdrs[2].ModTime)
+	}
+
+	hdrs = headers(write(append(opts, WithTarOmitMetadata())...))
+	if hdrs[1].Mode != 0o644 || hdrs[1].ModTime.Unix() != 0 {
+		t.Errorf("expected metadata to be omitted, got mode %o and mtime %s", hdrs[1].Mode, hdrs[1].ModTime)
+	}
+}
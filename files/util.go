@@ -1,5 +1,7 @@
 package files
 
+import "os"
+
 // ToFile is an alias for n.(File). If the file isn't a regular file, nil value
 // will be returned
 func ToFile(n Node) File {
@@ -23,3 +25,35 @@ func FileFromEntry(e DirEntry) File {
 func DirFromEntry(e DirEntry) Directory {
 	return ToDir(e.Node())
 }
+
+// ModePermsToUnixPerms converts the permission and special mode bits of an
+// [os.FileMode] to the POSIX layout used by UnixFS (0o7777).
+func ModePermsToUnixPerms(fileMode os.FileMode) uint32 {
+	perms := uint32(fileMode.Perm())
+	if fileMode&os.ModeSetuid != 0 {
+		perms |= 0o4000
+	}
+	if fileMode&os.ModeSetgid != 0 {
+		perms |= 0o2000
+	}
+	if fileMode&os.ModeSticky != 0 {
+		perms |= 0o1000
+	}
+	return perms
+}
+
+// UnixPermsToModePerms converts POSIX permission and special mode bits as
+// stored by UnixFS to an [os.FileMode].
+func UnixPermsToModePerms(unixPerms uint32) os.FileMode {
+	fileMode := os.FileMode(unixPerms) & os.ModePerm
+	if unixPerms&0o4000 != 0 {
+		fileMode |= os.ModeSetuid
+	}
+	if unixPerms&0o2000 != 0 {
+		fileMode |= os.ModeSetgid
+	}
+	if unixPerms&0o1000 != 0 {
+		fileMode |= os.ModeSticky
+	}
+	return fileMode
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 )
 
 // WebFile is an implementation of File which reads it
@@ -77,6 +78,14 @@ func (wf *WebFile) Size() (int64, error) {
 	return wf.contentLength, nil
 }
 
+func (wf *WebFile) Mode() os.FileMode {
+	return 0
+}
+
+func (wf *WebFile) ModTime() time.Time {
+	return time.Time{}
+}
+
 func (wf *WebFile) AbsPath() string {
 	return wf.url.String()
 }
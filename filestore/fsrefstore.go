@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 
@@ -32,8 +31,10 @@ var FilestorePrefix = ds.NewKey("filestore")
 type FileManager struct {
 	AllowFiles bool
 	AllowUrls  bool
-	ds         ds.Batching
-	root       string
+	// URLStore configures how the blocks backed by URLs are read.
+	URLStore URLStoreConfig
+	ds       ds.Batching
+	root     string
 }
 
 // CorruptReferenceError implements the error interface.
@@ -214,57 +215,6 @@ func (f *FileManager) readFileDataObj(m mh.Multihash, d *pb.DataObj) ([]byte, er
 	return outbuf, nil
 }
 
-// reads and verifies the block from URL
-func (f *FileManager) readURLDataObj(ctx context.Context, m mh.Multihash, d *pb.DataObj) ([]byte, error) {
-	if !f.AllowUrls {
-		return nil, ErrUrlstoreNotEnabled
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", d.GetFilePath(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Add("Range", fmt.Sprintf("bytes=%d-%d", d.GetOffset(), d.GetOffset()+d.GetSize_()-1))
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, &CorruptReferenceError{StatusFileError, err}
-	}
-	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
-		return nil, &CorruptReferenceError{
-			StatusFileError,
-			fmt.Errorf("expected HTTP 200 or 206 got %d", res.StatusCode),
-		}
-	}
-
-	outbuf := make([]byte, d.GetSize_())
-	_, err = io.ReadFull(res.Body, outbuf)
-	if err == io.EOF || err == io.ErrUnexpectedEOF {
-		return nil, &CorruptReferenceError{StatusFileChanged, err}
-	} else if err != nil {
-		return nil, &CorruptReferenceError{StatusFileError, err}
-	}
-	res.Body.Close()
-
-	// Work with CIDs for this, as they are a nice wrapper and things
-	// will not break if multihashes underlying types change.
-	origCid := cid.NewCidV1(cid.Raw, m)
-	outcid, err := origCid.Prefix().Sum(outbuf)
-	if err != nil {
-		return nil, err
-	}
-
-	if !origCid.Equals(outcid) {
-		return nil, &CorruptReferenceError{
-			StatusFileChanged,
-			fmt.Errorf("data in file did not match. %s offset %d", d.GetFilePath(), d.GetOffset()),
-		}
-	}
-
-	return outbuf, nil
-}
-
 // Has returns if the FileManager is storing a block reference. It does not
 // validate the data, nor checks if the reference is valid.
 func (f *FileManager) Has(ctx context.Context, c cid.Cid) (bool, error) {
@@ -0,0 +1,202 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	pb "github.com/ipfs/boxo/filestore/pb"
+	cid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// DefaultURLRetryBackoff is the default time waited before retrying a failed
+// request of a block backed by a URL.
+const DefaultURLRetryBackoff = time.Second
+
+// URLStoreConfig configures how the blocks backed by URLs are read, when
+// [FileManager.AllowUrls] is set. Blocks are read with HTTP Range requests of
+// their extent of the remote file.
+type URLStoreConfig struct {
+	// Client sends the requests. Defaults to [http.DefaultClient].
+	Client *http.Client
+
+	// Timeout bounds every request. Zero means no timeout.
+	Timeout time.Duration
+
+	// Retries is the number of times a request is retried after a network
+	// error, a 5xx response or a 429 Too Many Requests response.
+	Retries int
+
+	// RetryBackoff is the time waited before the first retry, which doubles
+	// after every retry. Defaults to [DefaultURLRetryBackoff].
+	RetryBackoff time.Duration
+
+	// Cache, if set, keeps the data of the most recently read extents, so
+	// that they are not fetched again.
+	Cache *URLCache
+}
+
+// URLCache is an LRU cache of the data of the extents read by the urlstore.
+// It can be shared by several [FileManager].
+type URLCache struct {
+	extents *lru.Cache[urlCacheKey, []byte]
+}
+
+type urlExtent struct {
+	url          string
+	offset, size uint64
+}
+
+// urlCacheKey is the extent of a block and its multihash, as several blocks
+// may reference the same extent, for example after the remote file changed.
+type urlCacheKey struct {
+	urlExtent
+	mh string
+}
+
+// NewURLCache returns a [URLCache] holding the data of up to size extents.
+// Each extent is one block.
+func NewURLCache(size int) (*URLCache, error) {
+	extents, err := lru.New[urlCacheKey, []byte](size)
+	if err != nil {
+		return nil, err
+	}
+	return &URLCache{extents: extents}, nil
+}
+
+// retryableError is a failed request which may succeed when retried.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string { return e.err.Error() }
+func (e retryableError) Unwrap() error { return e.err }
+
+// reads and verifies the block from URL
+func (f *FileManager) readURLDataObj(ctx context.Context, m mh.Multihash, d *pb.DataObj) ([]byte, error) {
+	if !f.AllowUrls {
+		return nil, ErrUrlstoreNotEnabled
+	}
+
+	extent := urlExtent{url: d.GetFilePath(), offset: d.GetOffset(), size: d.GetSize_()}
+	key := urlCacheKey{urlExtent: extent, mh: string(m)}
+	cache := f.URLStore.Cache
+	if cache != nil {
+		if data, ok := cache.extents.Get(key); ok {
+			return data, nil
+		}
+	}
+
+	backoff := f.URLStore.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultURLRetryBackoff
+	}
+
+	var outbuf []byte
+	var err error
+	for attempt := 0; ; attempt++ {
+		outbuf, err = f.fetchURLExtent(ctx, extent)
+		var rerr retryableError
+		if err == nil || !errors.As(err, &rerr) || attempt >= f.URLStore.Retries {
+			break
+		}
+
+		logger.Debugf("retrying %s after error: %s", extent.url, err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, &CorruptReferenceError{StatusFileError, ctx.Err()}
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		var cerr *CorruptReferenceError
+		if errors.As(err, &cerr) {
+			return nil, cerr
+		}
+		return nil, &CorruptReferenceError{StatusFileError, err}
+	}
+
+	// Work with CIDs for this, as they are a nice wrapper and things
+	// will not break if multihashes underlying types change.
+	origCid := cid.NewCidV1(cid.Raw, m)
+	outcid, err := origCid.Prefix().Sum(outbuf)
+	if err != nil {
+		return nil, err
+	}
+
+	if !origCid.Equals(outcid) {
+		return nil, &CorruptReferenceError{
+			StatusFileChanged,
+			fmt.Errorf("data in file did not match. %s offset %d", d.GetFilePath(), d.GetOffset()),
+		}
+	}
+
+	if cache != nil {
+		cache.extents.Add(key, outbuf)
+	}
+	return outbuf, nil
+}
+
+// fetchURLExtent reads the extent with a Range request.
+func (f *FileManager) fetchURLExtent(ctx context.Context, extent urlExtent) ([]byte, error) {
+	if f.URLStore.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.URLStore.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, extent.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Range", fmt.Sprintf("bytes=%d-%d", extent.offset, extent.offset+extent.size-1))
+
+	client := f.URLStore.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, retryableError{err}
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+	case res.StatusCode == http.StatusOK:
+		// The server ignored the Range header and sends the whole file.
+		if _, err := io.CopyN(io.Discard, res.Body, int64(extent.offset)); err != nil {
+			return nil, &CorruptReferenceError{StatusFileChanged, err}
+		}
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return nil, &CorruptReferenceError{
+			StatusFileNotFound,
+			fmt.Errorf("expected HTTP 200 or 206 got %d", res.StatusCode),
+		}
+	case res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests:
+		return nil, retryableError{fmt.Errorf("expected HTTP 200 or 206 got %d", res.StatusCode)}
+	default:
+		return nil, fmt.Errorf("expected HTTP 200 or 206 got %d", res.StatusCode)
+	}
+
+	outbuf := make([]byte, extent.size)
+	_, err = io.ReadFull(res.Body, outbuf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, &CorruptReferenceError{StatusFileChanged, err}
+	} else if err != nil {
+		return nil, retryableError{err}
+	}
+	return outbuf, nil
+}
@@ -0,0 +1,104 @@
+package filestore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	posinfo "github.com/ipfs/boxo/filestore/posinfo"
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+)
+
+func TestURLStore(t *testing.T) {
+	buf := make([]byte, 1000)
+	rand.Read(buf)
+
+	var requests, failures atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failures.Load() > 0 {
+			failures.Add(-1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "data", time.Time{}, bytes.NewReader(buf))
+	}))
+	defer ts.Close()
+
+	_, fs := newTestFilestore(t)
+	cache, err := NewURLCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm := fs.FileManager()
+	fm.AllowUrls = true
+	fm.URLStore = URLStoreConfig{
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+		Cache:        cache,
+	}
+
+	n := &posinfo.FilestoreNode{
+		PosInfo: &posinfo.PosInfo{
+			FullPath: ts.URL + "/data",
+			Offset:   100,
+		},
+		Node: dag.NewRawNode(buf[100:200]),
+	}
+	if err := fs.Put(bg, n); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first two requests fail and are retried.
+	failures.Store(2)
+	blk, err := fs.Get(bg, n.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blk.RawData(), buf[100:200]) {
+		t.Fatal("got the wrong data")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Fatalf("expected 3 requests, got %d", got)
+	}
+
+	// The extent is cached.
+	if _, err := fs.Get(bg, n.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Fatalf("expected the cached extent to be used, got %d requests", got)
+	}
+
+	// Requests are not retried more than configured.
+	cache.extents.Purge()
+	failures.Store(3)
+	if _, err := fs.Get(bg, n.Cid()); err == nil {
+		t.Fatal("expected an error after the retries")
+	}
+	if got := requests.Load(); got != 6 {
+		t.Fatalf("expected 6 requests, got %d", got)
+	}
+
+	// The cached extent is not used for another block referencing it.
+	if _, err := fs.Get(bg, n.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	other := &posinfo.FilestoreNode{
+		PosInfo: n.PosInfo,
+		Node:    dag.NewRawNode(buf[200:300]),
+	}
+	if err := fs.Put(bg, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Get(bg, other.Cid()); err == nil {
+		t.Fatal("expected the data not to match the other block")
+	}
+	if got := requests.Load(); got != 8 {
+		t.Fatalf("expected 8 requests, got %d", got)
+	}
+}
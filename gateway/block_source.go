@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/exchange"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// BlockSource is where a backend fetched a block from, used to label the
+// block fetch latencies, see [ObserveBlockFetch].
+type BlockSource string
+
+const (
+	// BlockSourceLocal is the local blockstore.
+	BlockSourceLocal BlockSource = "local"
+	// BlockSourceBitswap is the Bitswap network.
+	BlockSourceBitswap BlockSource = "bitswap"
+	// BlockSourceRemoteCAR is a remote gateway, queried for CARs.
+	BlockSourceRemoteCAR BlockSource = "remote_car"
+	// BlockSourceRemoteBlock is a remote gateway, queried for single blocks.
+	BlockSourceRemoteBlock BlockSource = "remote_block"
+)
+
+// Block fetch latencies range from microseconds for local blocks to seconds
+// for blocks fetched from the network: from 100µs to 26s.
+var blockFetchDurationBuckets = prometheus.ExponentialBuckets(0.0001, 4, 10)
+
+type blockFetchMetrics struct {
+	blockFetch *prometheus.HistogramVec
+	firstBlock *prometheus.HistogramVec
+}
+
+var getBlockFetchMetrics = sync.OnceValue(func() *blockFetchMetrics {
+	return &blockFetchMetrics{
+		blockFetch: newBlockSourceHistogramMetric(
+			"block_fetch_duration_seconds",
+			"The time spent fetching each block, by source.",
+		),
+		firstBlock: newBlockSourceHistogramMetric(
+			"first_block_duration_seconds",
+			"The time from the beginning of IPFSBackend API calls returning content until their first block was fetched, by source of that block.",
+		),
+	}
+})
+
+func newBlockSourceHistogramMetric(name string, help string) *prometheus.HistogramVec {
+	histogramMetric := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "ipfs",
+			Subsystem: "gw_backend",
+			Name:      name,
+			Help:      help,
+			Buckets:   blockFetchDurationBuckets,
+		},
+		[]string{"source"},
+	)
+	if err := prometheus.Register(histogramMetric); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			histogramMetric = are.ExistingCollector.(*prometheus.HistogramVec)
+		} else {
+			log.Errorf("failed to register ipfs_gw_backend_%s: %v", name, err)
+		}
+	}
+	return histogramMetric
+}
+
+type firstBlockKey struct{}
+
+// firstBlock tracks the first block fetched by a backend call.
+type firstBlock struct {
+	begin   time.Time
+	fetched atomic.Bool
+}
+
+// contextWithFirstBlock starts timing the first block fetched with ctx.
+func contextWithFirstBlock(ctx context.Context) context.Context {
+	return context.WithValue(ctx, firstBlockKey{}, &firstBlock{begin: time.Now()})
+}
+
+// ObserveBlockFetch records that a block was fetched from source in d. The
+// first block fetched by a backend call is also recorded with the time since
+// the call began, so that the time to first byte can be attributed to the
+// source of the first block. Backends fetching blocks from remote gateways
+// call it for every block, [BlocksBackend] reports them through the
+// blockstore and exchange of its blockservice, see
+// [NewBlockstoreWithSourceMetrics] and [NewExchangeWithSourceMetrics].
+func ObserveBlockFetch(ctx context.Context, source BlockSource, d time.Duration) {
+	m := getBlockFetchMetrics()
+	m.blockFetch.WithLabelValues(string(source)).Observe(d.Seconds())
+	if fb, ok := ctx.Value(firstBlockKey{}).(*firstBlock); ok && fb.fetched.CompareAndSwap(false, true) {
+		m.firstBlock.WithLabelValues(string(source)).Observe(time.Since(fb.begin).Seconds())
+	}
+}
+
+// NewBlockstoreWithSourceMetrics wraps bs to record the blocks read from it
+// with [ObserveBlockFetch] as [BlockSourceLocal]. Use it as the blockstore of
+// the blockservice of a [BlocksBackend].
+func NewBlockstoreWithSourceMetrics(bs blockstore.Blockstore) blockstore.Blockstore {
+	return &blockstoreWithSourceMetrics{bs}
+}
+
+type blockstoreWithSourceMetrics struct {
+	blockstore.Blockstore
+}
+
+func (bs *blockstoreWithSourceMetrics) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	begin := time.Now()
+	blk, err := bs.Blockstore.Get(ctx, c)
+	if err == nil {
+		ObserveBlockFetch(ctx, BlockSourceLocal, time.Since(begin))
+	}
+	return blk, err
+}
+
+// NewExchangeWithSourceMetrics wraps ex to record the blocks fetched through it
+// with [ObserveBlockFetch] as source. Use it as the exchange of the
+// blockservice of a [BlocksBackend]. Sessions, and deletion notifications, are
+// preserved if ex supports them.
+func NewExchangeWithSourceMetrics(ex exchange.Interface, source BlockSource) exchange.Interface {
+	return &exchangeWithSourceMetrics{
+		Interface: ex,
+		fetcher:   fetcherWithSourceMetrics{ex, source},
+	}
+}
+
+var (
+	_ exchange.SessionExchange  = (*exchangeWithSourceMetrics)(nil)
+	_ exchange.DeletionNotifier = (*exchangeWithSourceMetrics)(nil)
+)
+
+type exchangeWithSourceMetrics struct {
+	exchange.Interface
+	fetcher fetcherWithSourceMetrics
+}
+
+func (ex *exchangeWithSourceMetrics) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	return ex.fetcher.GetBlock(ctx, c)
+}
+
+func (ex *exchangeWithSourceMetrics) GetBlocks(ctx context.Context, cids []cid.Cid) (<-chan blocks.Block, error) {
+	return ex.fetcher.GetBlocks(ctx, cids)
+}
+
+func (ex *exchangeWithSourceMetrics) NewSession(ctx context.Context) exchange.Fetcher {
+	sex, ok := ex.Interface.(exchange.SessionExchange)
+	if !ok {
+		return ex.fetcher
+	}
+	return fetcherWithSourceMetrics{sex.NewSession(ctx), ex.fetcher.source}
+}
+
+func (ex *exchangeWithSourceMetrics) NotifyDeletedBlocks(ctx context.Context, ks ...cid.Cid) error {
+	dn, ok := ex.Interface.(exchange.DeletionNotifier)
+	if !ok {
+		return nil
+	}
+	return dn.NotifyDeletedBlocks(ctx, ks...)
+}
+
+type fetcherWithSourceMetrics struct {
+	fetcher exchange.Fetcher
+	source  BlockSource
+}
+
+func (f fetcherWithSourceMetrics) GetBlock(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	begin := time.Now()
+	blk, err := f.fetcher.GetBlock(ctx, c)
+	if err == nil {
+		ObserveBlockFetch(ctx, f.source, time.Since(begin))
+	}
+	return blk, err
+}
+
+// GetBlocks records the time from the call until each block is received.
+func (f fetcherWithSourceMetrics) GetBlocks(ctx context.Context, cids []cid.Cid) (<-chan blocks.Block, error) {
+	begin := time.Now()
+	in, err := f.fetcher.GetBlocks(ctx, cids)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan blocks.Block)
+	go func() {
+		defer close(out)
+		for blk := range in {
+			ObserveBlockFetch(ctx, f.source, time.Since(begin))
+			select {
+			case out <- blk:
+			case <-ctx.Done():
+				// drain so that the fetcher is not blocked
+				for range in {
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	chunker "github.com/ipfs/boxo/chunker"
+	"github.com/ipfs/boxo/exchange"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/ipld/unixfs/importer"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// blockSourceSamples returns the number of samples of the block source
+// histogram by source.
+func blockSourceSamples(t *testing.T, name string) map[string]uint64 {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	samples := make(map[string]uint64)
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "source" {
+					samples[l.GetValue()] = m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return samples
+}
+
+func TestBlockSourceMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	network := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dserv := merkledag.NewDAGService(blockservice.New(network, nil))
+
+	content := make([]byte, 1000)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	cached, err := importer.BuildDagFromReader(dserv, chunker.NewSizeSplitter(bytes.NewReader(content), 100))
+	require.NoError(t, err)
+	remote, err := importer.BuildDagFromReader(dserv, chunker.NewSizeSplitter(bytes.NewReader([]byte("remote")), 100))
+	require.NoError(t, err)
+
+	// all the blocks but one leaf of the cached file are stored locally
+	local := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	stored := []cid.Cid{cached.Cid()}
+	for _, l := range cached.Links()[1:] {
+		stored = append(stored, l.Cid)
+	}
+	for _, c := range stored {
+		blk, err := network.Get(ctx, c)
+		require.NoError(t, err)
+		require.NoError(t, local.Put(ctx, blk))
+	}
+
+	bsrv := blockservice.New(
+		NewBlockstoreWithSourceMetrics(local),
+		NewExchangeWithSourceMetrics(offline.Exchange(network), BlockSourceBitswap),
+	)
+	backend, err := NewBlocksBackend(bsrv)
+	require.NoError(t, err)
+	ts := newTestServer(t, backend)
+
+	get := func(url string) []byte {
+		res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+url, nil))
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return body
+	}
+
+	const (
+		blockFetch = "ipfs_gw_backend_block_fetch_duration_seconds"
+		firstBlock = "ipfs_gw_backend_first_block_duration_seconds"
+	)
+	fetchesBefore := blockSourceSamples(t, blockFetch)
+	firstBefore := blockSourceSamples(t, firstBlock)
+
+	require.Equal(t, content, get("/ipfs/"+cached.Cid().String()))
+	fetches := blockSourceSamples(t, blockFetch)
+	first := blockSourceSamples(t, firstBlock)
+	require.Greater(t, fetches["local"], fetchesBefore["local"])
+	require.Equal(t, fetchesBefore["bitswap"]+1, fetches["bitswap"])
+	require.Greater(t, first["local"], firstBefore["local"])
+	require.Equal(t, firstBefore["bitswap"], first["bitswap"])
+
+	require.Equal(t, []byte("remote"), get("/ipfs/"+remote.Cid().String()))
+	first = blockSourceSamples(t, firstBlock)
+	require.Greater(t, first["bitswap"], firstBefore["bitswap"])
+}
+
+type deletionRecordingExchange struct {
+	exchange.Interface
+	deleted []cid.Cid
+}
+
+func (e *deletionRecordingExchange) NotifyDeletedBlocks(ctx context.Context, ks ...cid.Cid) error {
+	e.deleted = append(e.deleted, ks...)
+	return nil
+}
+
+func TestExchangeWithSourceMetricsDeletionNotifier(t *testing.T) {
+	ctx := context.Background()
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	ex := &deletionRecordingExchange{Interface: offline.Exchange(bs)}
+	bsrv := blockservice.New(bs, NewExchangeWithSourceMetrics(ex, BlockSourceBitswap))
+
+	nd := merkledag.NewRawNode([]byte("deleted"))
+	require.NoError(t, bsrv.AddBlock(ctx, nd))
+	require.NoError(t, bsrv.DeleteBlock(ctx, nd.Cid()))
+	require.Equal(t, []cid.Cid{nd.Cid()}, ex.deleted)
+
+	// exchanges without deletion notifications are fine too
+	ns := NewExchangeWithSourceMetrics(offline.Exchange(bs), BlockSourceBitswap).(exchange.DeletionNotifier)
+	require.NoError(t, ns.NotifyDeletedBlocks(ctx, nd.Cid()))
+}
@@ -0,0 +1,190 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+)
+
+// ipfsBackendWithBlocking checks the paths given to and resolved by an
+// [IPFSBackend] with a [ContentBlocker]. The roots of all path segments are
+// checked, so that content is blocked even when it is reached through a path
+// under a parent CID.
+type ipfsBackendWithBlocking struct {
+	backend IPFSBackend
+	blocker ContentBlocker
+}
+
+func newIPFSBackendWithBlocking(backend IPFSBackend, blocker ContentBlocker) *ipfsBackendWithBlocking {
+	return &ipfsBackendWithBlocking{backend: backend, blocker: blocker}
+}
+
+func (b *ipfsBackendWithBlocking) checkMetadata(md ContentPathMetadata) error {
+	for _, c := range md.PathSegmentRoots {
+		if err := b.blocker.CheckPath(path.FromCid(c)); err != nil {
+			return err
+		}
+	}
+	if md.LastSegment.RootCid().Defined() {
+		return b.blocker.CheckPath(md.LastSegment)
+	}
+	return nil
+}
+
+func (b *ipfsBackendWithBlocking) Get(ctx context.Context, path path.ImmutablePath, ranges ...ByteRange) (ContentPathMetadata, *GetResponse, error) {
+	if err := b.blocker.CheckPath(path); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, f, err := b.backend.Get(ctx, path, ranges...)
+	if err == nil {
+		if err = b.checkMetadata(md); err != nil {
+			f.Close()
+			return md, nil, err
+		}
+	}
+	return md, f, err
+}
+
+func (b *ipfsBackendWithBlocking) GetAll(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.Node, error) {
+	if err := b.blocker.CheckPath(path); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, n, err := b.backend.GetAll(ctx, path)
+	if err == nil {
+		if err = b.checkMetadata(md); err != nil {
+			n.Close()
+			return md, nil, err
+		}
+	}
+	return md, n, err
+}
+
+func (b *ipfsBackendWithBlocking) GetBlock(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.File, error) {
+	if err := b.blocker.CheckPath(path); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, n, err := b.backend.GetBlock(ctx, path)
+	if err == nil {
+		if err = b.checkMetadata(md); err != nil {
+			n.Close()
+			return md, nil, err
+		}
+	}
+	return md, n, err
+}
+
+func (b *ipfsBackendWithBlocking) Head(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, *HeadResponse, error) {
+	if err := b.blocker.CheckPath(path); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, n, err := b.backend.Head(ctx, path)
+	if err == nil {
+		if err = b.checkMetadata(md); err != nil {
+			n.Close()
+			return md, nil, err
+		}
+	}
+	return md, n, err
+}
+
+func (b *ipfsBackendWithBlocking) ResolvePath(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, error) {
+	if err := b.blocker.CheckPath(path); err != nil {
+		return ContentPathMetadata{}, err
+	}
+	md, err := b.backend.ResolvePath(ctx, path)
+	if err == nil {
+		err = b.checkMetadata(md)
+	}
+	return md, err
+}
+
+func (b *ipfsBackendWithBlocking) GetCAR(ctx context.Context, path path.ImmutablePath, params CarParams) (ContentPathMetadata, io.ReadCloser, error) {
+	if err := b.blocker.CheckPath(path); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, rc, err := b.backend.GetCAR(ctx, path, params)
+	if err == nil {
+		if err = b.checkMetadata(md); err != nil {
+			rc.Close()
+			return md, nil, err
+		}
+	}
+	return md, rc, err
+}
+
+func (b *ipfsBackendWithBlocking) IsCached(ctx context.Context, path path.Path) bool {
+	if b.blocker.CheckPath(path) != nil {
+		return false
+	}
+	return b.backend.IsCached(ctx, path)
+}
+
+func (b *ipfsBackendWithBlocking) GetIPNSRecord(ctx context.Context, c cid.Cid) ([]byte, error) {
+	if p, err := path.NewPath("/" + path.IPNSNamespace + "/" + c.String()); err == nil {
+		if err := b.blocker.CheckPath(p); err != nil {
+			return nil, err
+		}
+	}
+	return b.backend.GetIPNSRecord(ctx, c)
+}
+
+func (b *ipfsBackendWithBlocking) ResolveMutable(ctx context.Context, p path.Path) (path.ImmutablePath, time.Duration, time.Time, error) {
+	if err := b.blocker.CheckPath(p); err != nil {
+		return path.ImmutablePath{}, 0, time.Time{}, err
+	}
+	ip, ttl, lastMod, err := b.backend.ResolveMutable(ctx, p)
+	if err == nil {
+		if err = b.blocker.CheckPath(ip); err != nil {
+			return path.ImmutablePath{}, 0, time.Time{}, err
+		}
+	}
+	return ip, ttl, lastMod, err
+}
+
+func (b *ipfsBackendWithBlocking) GetDNSLinkRecord(ctx context.Context, fqdn string) (path.Path, error) {
+	if p, err := path.NewPath("/" + path.IPNSNamespace + "/" + fqdn); err == nil {
+		if err := b.blocker.CheckPath(p); err != nil {
+			return nil, err
+		}
+	}
+	p, err := b.backend.GetDNSLinkRecord(ctx, fqdn)
+	if err == nil {
+		if err = b.blocker.CheckPath(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, err
+}
+
+var _ IPFSBackend = (*ipfsBackendWithBlocking)(nil)
+var _ WithContextHint = (*ipfsBackendWithBlocking)(nil)
+
+func (b *ipfsBackendWithBlocking) WrapContextForRequest(ctx context.Context) context.Context {
+	if withCtxWrap, ok := b.backend.(WithContextHint); ok {
+		return withCtxWrap.WrapContextForRequest(ctx)
+	}
+	return ctx
+}
+
+var _ WithIPNSPublishing = (*ipfsBackendWithBlocking)(nil)
+
+func (b *ipfsBackendWithBlocking) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	if err := b.blocker.CheckPath(name.AsPath()); err != nil {
+		return err
+	}
+	return putIPNSRecord(ctx, b.backend, name, record)
+}
+
+var _ WithDeterministicCAR = (*ipfsBackendWithBlocking)(nil)
+
+func (b *ipfsBackendWithBlocking) IsDeterministicCAR(params CarParams) bool {
+	if withDeterministicCAR, ok := b.backend.(WithDeterministicCAR); ok {
+		return withDeterministicCAR.IsDeterministicCAR(params)
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/boxo/gateway/denylist"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/boxo/verifcid"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentBlocking(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	p, err := path.Join(path.FromCid(root), "subdir")
+	require.NoError(t, err)
+	subdir, err := backend.resolvePathNoRootsReturned(ctx, p)
+	require.NoError(t, err)
+	backend.namesys["/ipns/example.com"] = newMockNamesysItem(path.FromCid(root), 0)
+	backend.namesys["/ipns/blocked.example.com"] = newMockNamesysItem(path.FromCid(root), 0)
+
+	file := filepath.Join(t.TempDir(), "test.deny")
+	require.NoError(t, os.WriteFile(file, []byte("/ipfs/"+subdir.RootCid().String()+" reason=test\n/ipns/blocked.example.com\n"), 0o644))
+	blocker, err := denylist.NewBlocker(file)
+	require.NoError(t, err)
+
+	ts := newTestServerWithConfig(t, backend, Config{
+		DeserializedResponses: true,
+		ContentBlocker:        blocker,
+	})
+
+	for _, tc := range []struct {
+		path   string
+		status int
+	}{
+		{"/ipfs/" + root.String() + "/", http.StatusOK},
+		{"/ipfs/" + root.String() + "/subdir/fnord", http.StatusGone},
+		{"/ipfs/" + subdir.RootCid().String() + "/fnord", http.StatusGone},
+		{"/ipns/example.com/subdir/", http.StatusGone},
+		{"/ipns/blocked.example.com/", http.StatusGone},
+	} {
+		res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+tc.path, nil))
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, tc.status, res.StatusCode, tc.path)
+		if tc.status == http.StatusGone && tc.path != "/ipns/blocked.example.com/" {
+			require.Contains(t, string(body), "blocked and cannot be provided: test", tc.path)
+		}
+	}
+}
+
+func TestCidPolicy(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	backend.namesys["/ipns/example.com"] = newMockNamesysItem(path.FromCid(root), 0)
+
+	ts := newTestServerWithConfig(t, backend, Config{
+		DeserializedResponses: true,
+		CidPolicy:             &verifcid.Policy{Codecs: verifcid.NewAllowlist(map[uint64]bool{cid.Raw: true})},
+	})
+
+	for _, p := range []string{
+		"/ipfs/" + root.String() + "/",
+		"/ipns/example.com/",
+	} {
+		res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+p, nil))
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode, p)
+		require.Contains(t, string(body), "codec not allowed", p)
+	}
+}
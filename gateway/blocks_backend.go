@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ipfs/boxo/blockservice"
@@ -59,14 +61,19 @@ type BlocksBackend struct {
 	// Optional routing system to handle /ipns addresses.
 	namesys namesys.NameSystem
 	routing routing.ValueStore
+
+	symlinks  SymlinkResolution
+	localOnce sync.Once
+	local     *localBlocksBackend
 }
 
 var _ IPFSBackend = (*BlocksBackend)(nil)
 
 type blocksBackendOptions struct {
-	ns namesys.NameSystem
-	vs routing.ValueStore
-	r  resolver.Resolver
+	ns       namesys.NameSystem
+	vs       routing.ValueStore
+	r        resolver.Resolver
+	symlinks SymlinkResolution
 }
 
 // WithNameSystem sets the name system to use with the [BlocksBackend]. If not set
@@ -95,6 +102,34 @@ func WithResolver(r resolver.Resolver) BlocksBackendOption {
 	}
 }
 
+// SymlinkResolution tells whether the [BlocksBackend] follows the UnixFS
+// symlinks in content paths, see [WithSymlinkResolution].
+type SymlinkResolution int
+
+const (
+	// SymlinksNotResolved does not follow symlinks: paths going through
+	// symlinks are not found, and symlinks are served as is. It is the
+	// default.
+	SymlinksNotResolved SymlinkResolution = iota
+	// SymlinksResolved follows the symlinks in the middle of paths, and
+	// serves the symlinks at their end as is.
+	SymlinksResolved
+	// SymlinksResolvedThrough follows every symlink, serving the content
+	// they point to.
+	SymlinksResolvedThrough
+)
+
+// WithSymlinkResolution sets whether the [BlocksBackend] follows the UnixFS
+// symlinks in content paths. Symlinks are resolved within the CID at the root
+// of the path, with [unixfile.ResolvePath]: symlinks with absolute targets or
+// pointing above the root, and symlink loops, are answered with a 404.
+func WithSymlinkResolution(r SymlinkResolution) BlocksBackendOption {
+	return func(opts *blocksBackendOptions) error {
+		opts.symlinks = r
+		return nil
+	}
+}
+
 type BlocksBackendOption func(options *blocksBackendOptions) error
 
 func NewBlocksBackend(blockService blockservice.BlockService, opts ...BlocksBackendOption) (*BlocksBackend, error) {
@@ -135,11 +170,7 @@ func NewBlocksBackend(blockService blockservice.BlockService, opts ...BlocksBack
 
 	r = compiledOptions.r
 	if r == nil {
-		// Setup the UnixFS resolver.
-		fetcherCfg := bsfetcher.NewFetcherConfig(blockService)
-		fetcherCfg.PrototypeChooser = dagpb.AddSupportToChooser(bsfetcher.DefaultPrototypeChooser)
-		fetcher := fetcherCfg.WithReifier(unixfsnode.Reify)
-		r = resolver.NewBasicResolver(fetcher)
+		r = newUnixFSResolver(blockService)
 	}
 
 	return &BlocksBackend{
@@ -149,18 +180,28 @@ func NewBlocksBackend(blockService blockservice.BlockService, opts ...BlocksBack
 		resolver:     r,
 		routing:      vs,
 		namesys:      ns,
+		symlinks:     compiledOptions.symlinks,
 	}, nil
 }
 
+// newUnixFSResolver returns a path resolver for UnixFS paths fetching blocks
+// with blockService.
+func newUnixFSResolver(blockService blockservice.BlockService) resolver.Resolver {
+	fetcherCfg := bsfetcher.NewFetcherConfig(blockService)
+	fetcherCfg.PrototypeChooser = dagpb.AddSupportToChooser(bsfetcher.DefaultPrototypeChooser)
+	fetcher := fetcherCfg.WithReifier(unixfsnode.Reify)
+	return resolver.NewBasicResolver(fetcher)
+}
+
 func (bb *BlocksBackend) Get(ctx context.Context, path path.ImmutablePath, ranges ...ByteRange) (ContentPathMetadata, *GetResponse, error) {
 	md, nd, err := bb.getNode(ctx, path)
 	if err != nil {
 		return md, nil, err
 	}
 
-	// Only a single range is supported in responses to HTTP Range Requests.
-	// When more than one is passed in the Range header, this library will
-	// return a response for the first one and ignores remaining ones.
+	// The returned file starts at the first range. When more than one is
+	// passed in the Range header, the handler seeks the file to the other
+	// ones, only fetching the blocks they cover.
 	var ra *ByteRange
 	if len(ranges) > 0 {
 		ra = &ranges[0]
@@ -663,6 +704,17 @@ func (bb *BlocksBackend) GetIPNSRecord(ctx context.Context, c cid.Cid) ([]byte,
 	return bb.routing.GetValue(ctx, string(name.RoutingKey()))
 }
 
+var _ WithIPNSPublishing = (*BlocksBackend)(nil)
+
+// PutIPNSRecord puts the record to the routing system given with
+// [WithValueStore].
+func (bb *BlocksBackend) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	if bb.routing == nil {
+		return NewErrorStatusCode(errors.New("IPNS Record publishing is not supported by this gateway"), http.StatusNotImplemented)
+	}
+	return bb.routing.PutValue(ctx, string(name.RoutingKey()), record)
+}
+
 func (bb *BlocksBackend) GetDNSLinkRecord(ctx context.Context, hostname string) (path.Path, error) {
 	if bb.namesys != nil {
 		p, err := path.NewPath("/ipns/" + hostname)
@@ -670,7 +722,7 @@ func (bb *BlocksBackend) GetDNSLinkRecord(ctx context.Context, hostname string)
 			return nil, err
 		}
 		res, err := bb.namesys.Resolve(ctx, p, namesys.ResolveWithDepth(1))
-		if err == namesys.ErrResolveRecursion {
+		if errors.Is(err, namesys.ErrResolveRecursion) {
 			err = nil
 		}
 		return res.Path, err
@@ -689,6 +741,15 @@ func (bb *BlocksBackend) IsCached(ctx context.Context, p path.Path) bool {
 	return has
 }
 
+var _ WithDeterministicCAR = (*BlocksBackend)(nil)
+
+// IsDeterministicCAR returns true when params ask for DFS order without
+// duplicates: [BlocksBackend.GetCAR] always walks the DAG depth-first, and
+// writes a CARv1 header that only lists the requested root.
+func (bb *BlocksBackend) IsDeterministicCAR(params CarParams) bool {
+	return params.Deterministic()
+}
+
 var _ WithContextHint = (*BlocksBackend)(nil)
 
 func (bb *BlocksBackend) WrapContextForRequest(ctx context.Context) context.Context {
@@ -727,6 +788,12 @@ func (bb *BlocksBackend) resolvePath(ctx context.Context, p path.Path) (path.Imm
 		return path.ImmutablePath{}, nil, err
 	}
 
+	if bb.symlinks != SymlinksNotResolved {
+		if resolved, ok, err := bb.resolveSymlinks(ctx, imPath); ok || err != nil {
+			return resolved, nil, err
+		}
+	}
+
 	node, remainder, err := bb.resolver.ResolveToLastNode(ctx, imPath)
 	if err != nil {
 		return path.ImmutablePath{}, nil, err
@@ -745,6 +812,34 @@ func (bb *BlocksBackend) resolvePath(ctx context.Context, p path.Path) (path.Imm
 	return imPath, remainder, nil
 }
 
+// resolveSymlinks resolves p following the UnixFS symlinks in it. It returns
+// false if the root of p is not a UnixFS directory, which the resolver is left
+// to resolve.
+func (bb *BlocksBackend) resolveSymlinks(ctx context.Context, p path.ImmutablePath) (path.ImmutablePath, bool, error) {
+	segments := p.Segments()[2:]
+	if len(segments) == 0 {
+		return path.ImmutablePath{}, false, nil
+	}
+	root, err := bb.dagService.Get(ctx, p.RootCid())
+	if err != nil {
+		return path.ImmutablePath{}, false, err
+	}
+	if _, err := uio.NewDirectoryFromNode(bb.dagService, root); err != nil {
+		return path.ImmutablePath{}, false, nil
+	}
+
+	nd, err := ufile.ResolvePath(ctx, bb.dagService, root, strings.Join(segments, "/"), ufile.ResolveThrough(bb.symlinks == SymlinksResolvedThrough))
+	switch {
+	case errors.Is(err, ufile.ErrSymlinkLoop), errors.Is(err, ufile.ErrSymlinkEscapesRoot):
+		return path.ImmutablePath{}, true, NewErrorStatusCode(err, http.StatusNotFound)
+	case errors.Is(err, os.ErrNotExist), errors.Is(err, uio.ErrNotADir):
+		return path.ImmutablePath{}, true, &resolver.ErrNoLink{Name: segments[len(segments)-1], Node: p.RootCid()}
+	case err != nil:
+		return path.ImmutablePath{}, true, err
+	}
+	return path.FromCid(nd.Cid()), true, nil
+}
+
 type nodeGetterToCarExporer struct {
 	ng format.NodeGetter
 	cw storage.WritableCar
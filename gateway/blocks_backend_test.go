@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/ipld/unixfs/importer"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocksBackendSymlinks(t *testing.T) {
+	ctx := context.Background()
+	bsrv := blockservice.New(blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore())), nil)
+	symlink := func(target string) files.Node {
+		return files.NewSymlinkFile(target, time.Time{})
+	}
+	res, err := importer.ImportTree(ctx, merkledag.NewDAGService(bsrv), files.NewMapDirectory(map[string]files.Node{
+		"dir": files.NewMapDirectory(map[string]files.Node{
+			"sub.txt": files.NewBytesFile([]byte("sub")),
+		}),
+		"link-dir":  symlink("dir"),
+		"link-file": symlink("dir/sub.txt"),
+		"escape":    symlink("../outside"),
+		"absolute":  symlink("/etc/passwd"),
+		"loop":      symlink("loop"),
+	}))
+	require.NoError(t, err)
+	root := "/ipfs/" + res.Root.Cid().String()
+
+	get := func(t *testing.T, resolution SymlinkResolution, p string) (int, string) {
+		backend, err := NewBlocksBackend(bsrv, WithSymlinkResolution(resolution))
+		require.NoError(t, err)
+		ts := newTestServer(t, backend)
+		res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+root+p, nil))
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(body)
+	}
+
+	t.Run("Not resolved", func(t *testing.T) {
+		code, _ := get(t, SymlinksNotResolved, "/link-dir/sub.txt")
+		require.Equal(t, http.StatusNotFound, code)
+		code, body := get(t, SymlinksNotResolved, "/link-file")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "dir/sub.txt", body)
+	})
+
+	t.Run("Resolved", func(t *testing.T) {
+		code, body := get(t, SymlinksResolved, "/link-dir/sub.txt")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "sub", body)
+		code, body = get(t, SymlinksResolved, "/link-file")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "dir/sub.txt", body)
+		code, _ = get(t, SymlinksResolved, "/link-dir/missing")
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("Resolved through", func(t *testing.T) {
+		code, body := get(t, SymlinksResolvedThrough, "/link-file")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "sub", body)
+	})
+
+	t.Run("Escaping the root", func(t *testing.T) {
+		for _, p := range []string{"/escape", "/absolute", "/loop", "/escape/sub.txt"} {
+			code, body := get(t, SymlinksResolvedThrough, p)
+			require.Equal(t, http.StatusNotFound, code, p)
+			require.NotContains(t, body, "root:", p)
+		}
+	})
+}
@@ -0,0 +1,446 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+)
+
+// broadcastChunkSize is the size of the reads of coalesced CAR streams.
+const broadcastChunkSize = 32 << 10
+
+// broadcastWindow is the maximum number of chunks of a coalesced CAR stream
+// kept in memory for the readers behind the fastest one.
+const broadcastWindow = 64
+
+// errBroadcastLagging is returned to the readers of a coalesced CAR stream
+// which fell more than [broadcastWindow] chunks behind the fastest reader.
+var errBroadcastLagging = errors.New("coalesced CAR stream reader fell behind")
+
+// ipfsBackendWithCoalescing shares the result of identical concurrent calls
+// to an [IPFSBackend]: GetCAR streams are broadcast to all the requests with
+// the same path and parameters, and GetBlock and ResolvePath results are
+// shared by the requests made while they are pending. The other methods are
+// passed through.
+//
+// The fetches run with a context of their own, which does not carry the
+// values of any request, and is canceled once all the requests sharing it are
+// gone. CAR streams are buffered up to [broadcastWindow] chunks behind the
+// fastest reader, slower readers are disconnected with an error.
+type ipfsBackendWithCoalescing struct {
+	backend IPFSBackend
+
+	blocks   flightGroup[[]byte]
+	resolves flightGroup[struct{}]
+
+	carsLk sync.Mutex
+	cars   map[string]*broadcast
+}
+
+func newIPFSBackendWithCoalescing(backend IPFSBackend) *ipfsBackendWithCoalescing {
+	return &ipfsBackendWithCoalescing{
+		backend: backend,
+		cars:    make(map[string]*broadcast),
+	}
+}
+
+func carFlightKey(p path.ImmutablePath, params CarParams) string {
+	key := fmt.Sprintf("%s|%s|%s|%s", p, params.Scope, params.Order, params.Duplicates)
+	if params.Range != nil {
+		key += fmt.Sprintf("|%d", params.Range.From)
+		if params.Range.To != nil {
+			key += fmt.Sprintf(":%d", *params.Range.To)
+		}
+	}
+	return key
+}
+
+func (b *ipfsBackendWithCoalescing) Get(ctx context.Context, path path.ImmutablePath, ranges ...ByteRange) (ContentPathMetadata, *GetResponse, error) {
+	return b.backend.Get(ctx, path, ranges...)
+}
+
+func (b *ipfsBackendWithCoalescing) GetAll(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.Node, error) {
+	return b.backend.GetAll(ctx, path)
+}
+
+func (b *ipfsBackendWithCoalescing) GetBlock(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.File, error) {
+	md, data, err := b.blocks.do(ctx, path.String(), func(ctx context.Context) (ContentPathMetadata, []byte, error) {
+		md, f, err := b.backend.GetBlock(ctx, path)
+		if err != nil {
+			return md, nil, err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		return md, data, err
+	})
+	if err != nil {
+		return md, nil, err
+	}
+	return md, files.NewBytesFile(data), nil
+}
+
+func (b *ipfsBackendWithCoalescing) Head(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, *HeadResponse, error) {
+	return b.backend.Head(ctx, path)
+}
+
+func (b *ipfsBackendWithCoalescing) ResolvePath(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, error) {
+	md, _, err := b.resolves.do(ctx, path.String(), func(ctx context.Context) (ContentPathMetadata, struct{}, error) {
+		md, err := b.backend.ResolvePath(ctx, path)
+		return md, struct{}{}, err
+	})
+	return md, err
+}
+
+func (b *ipfsBackendWithCoalescing) GetCAR(ctx context.Context, path path.ImmutablePath, params CarParams) (ContentPathMetadata, io.ReadCloser, error) {
+	key := carFlightKey(path, params)
+
+	b.carsLk.Lock()
+	bc := b.cars[key]
+	r := bc.join()
+	if r == nil {
+		fctx, cancel := context.WithCancel(context.Background())
+		bc = newBroadcast(cancel, func() {
+			b.carsLk.Lock()
+			if b.cars[key] == bc {
+				delete(b.cars, key)
+			}
+			b.carsLk.Unlock()
+		})
+		b.cars[key] = bc
+		r = bc.join()
+		go bc.run(func() (ContentPathMetadata, io.ReadCloser, error) {
+			return b.backend.GetCAR(fctx, path, params)
+		})
+	}
+	b.carsLk.Unlock()
+
+	select {
+	case <-bc.ready:
+	case <-ctx.Done():
+		r.Close()
+		return ContentPathMetadata{}, nil, ctx.Err()
+	}
+	if bc.err != nil {
+		r.Close()
+		return bc.md, nil, bc.err
+	}
+	return bc.md, r, nil
+}
+
+func (b *ipfsBackendWithCoalescing) IsCached(ctx context.Context, path path.Path) bool {
+	return b.backend.IsCached(ctx, path)
+}
+
+func (b *ipfsBackendWithCoalescing) GetIPNSRecord(ctx context.Context, c cid.Cid) ([]byte, error) {
+	return b.backend.GetIPNSRecord(ctx, c)
+}
+
+func (b *ipfsBackendWithCoalescing) ResolveMutable(ctx context.Context, p path.Path) (path.ImmutablePath, time.Duration, time.Time, error) {
+	return b.backend.ResolveMutable(ctx, p)
+}
+
+func (b *ipfsBackendWithCoalescing) GetDNSLinkRecord(ctx context.Context, fqdn string) (path.Path, error) {
+	return b.backend.GetDNSLinkRecord(ctx, fqdn)
+}
+
+var _ IPFSBackend = (*ipfsBackendWithCoalescing)(nil)
+var _ WithContextHint = (*ipfsBackendWithCoalescing)(nil)
+
+func (b *ipfsBackendWithCoalescing) WrapContextForRequest(ctx context.Context) context.Context {
+	if withCtxWrap, ok := b.backend.(WithContextHint); ok {
+		return withCtxWrap.WrapContextForRequest(ctx)
+	}
+	return ctx
+}
+
+var _ WithIPNSPublishing = (*ipfsBackendWithCoalescing)(nil)
+
+func (b *ipfsBackendWithCoalescing) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	return putIPNSRecord(ctx, b.backend, name, record)
+}
+
+var _ WithDeterministicCAR = (*ipfsBackendWithCoalescing)(nil)
+
+func (b *ipfsBackendWithCoalescing) IsDeterministicCAR(params CarParams) bool {
+	if withDeterministicCAR, ok := b.backend.(WithDeterministicCAR); ok {
+		return withDeterministicCAR.IsDeterministicCAR(params)
+	}
+	return false
+}
+
+// flight is a pending call of a [flightGroup].
+type flight[T any] struct {
+	done    chan struct{}
+	md      ContentPathMetadata
+	val     T
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup runs one call at a time per key, and shares its result with
+// the callers arriving while it is pending.
+type flightGroup[T any] struct {
+	lk      sync.Mutex
+	flights map[string]*flight[T]
+}
+
+func (g *flightGroup[T]) do(ctx context.Context, key string, fn func(context.Context) (ContentPathMetadata, T, error)) (ContentPathMetadata, T, error) {
+	g.lk.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		if g.flights == nil {
+			g.flights = make(map[string]*flight[T])
+		}
+		fctx, cancel := context.WithCancel(context.Background())
+		f = &flight[T]{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go func() {
+			defer cancel()
+			md, val, err := fn(fctx)
+
+			g.lk.Lock()
+			f.md, f.val, f.err = md, val, err
+			g.forget(key, f)
+			g.lk.Unlock()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.lk.Unlock()
+
+	select {
+	case <-f.done:
+		return f.md, f.val, f.err
+	case <-ctx.Done():
+		g.lk.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// nobody is waiting anymore, give up
+			g.forget(key, f)
+			f.cancel()
+		}
+		g.lk.Unlock()
+		var zero T
+		return ContentPathMetadata{}, zero, ctx.Err()
+	}
+}
+
+func (g *flightGroup[T]) forget(key string, f *flight[T]) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// broadcast streams the result of a GetCAR call to several readers. The
+// stream is kept in memory from the position of the slowest reader, up to
+// [broadcastWindow] chunks, new readers can join as long as its start has not
+// been dropped. When the window is full, the stream waits for the readers
+// unless one of them already read everything, in which case the slowest
+// readers are disconnected.
+type broadcast struct {
+	ready chan struct{}
+	md    ContentPathMetadata
+	err   error
+
+	lk      sync.Mutex
+	cond    *sync.Cond
+	chunks  [][]byte // dropped chunks are nil
+	dropped int      // number of dropped chunks
+	readErr error    // io.EOF once the stream is complete
+	readers map[*broadcastReader]struct{}
+	closed  bool
+
+	cancel context.CancelFunc
+	forget func()
+}
+
+func newBroadcast(cancel context.CancelFunc, forget func()) *broadcast {
+	bc := &broadcast{
+		ready:   make(chan struct{}),
+		readers: make(map[*broadcastReader]struct{}),
+		cancel:  cancel,
+		forget:  forget,
+	}
+	bc.cond = sync.NewCond(&bc.lk)
+	return bc
+}
+
+// join returns a reader from the start of the stream, or nil if it cannot be
+// joined anymore.
+func (bc *broadcast) join() *broadcastReader {
+	if bc == nil {
+		return nil
+	}
+
+	bc.lk.Lock()
+	defer bc.lk.Unlock()
+	if bc.closed || bc.dropped != 0 {
+		return nil
+	}
+	r := &broadcastReader{bc: bc}
+	bc.readers[r] = struct{}{}
+	return r
+}
+
+func (bc *broadcast) run(get func() (ContentPathMetadata, io.ReadCloser, error)) {
+	md, rc, err := get()
+	bc.md, bc.err = md, err
+	close(bc.ready)
+	if err != nil {
+		bc.finish(err)
+		return
+	}
+	defer rc.Close()
+
+	for {
+		if !bc.waitForRoom() {
+			return
+		}
+
+		buf := make([]byte, broadcastChunkSize)
+		n, err := io.ReadFull(rc, buf)
+		bc.lk.Lock()
+		if n > 0 {
+			bc.chunks = append(bc.chunks, buf[:n:n])
+		}
+		bc.cond.Broadcast()
+		bc.lk.Unlock()
+
+		switch err {
+		case nil:
+			continue
+		case io.ErrUnexpectedEOF:
+			err = io.EOF
+		}
+		bc.finish(err)
+		return
+	}
+}
+
+// waitForRoom waits until there is room for a chunk in the window, and
+// returns false if nobody is reading anymore.
+func (bc *broadcast) waitForRoom() bool {
+	bc.lk.Lock()
+	defer bc.lk.Unlock()
+	for !bc.closed && len(bc.chunks)-bc.dropped >= broadcastWindow {
+		fastest := 0
+		for r := range bc.readers {
+			fastest = max(fastest, r.chunk)
+		}
+		if fastest < len(bc.chunks) {
+			// every reader has buffered data left to read
+			bc.cond.Wait()
+			continue
+		}
+
+		// a reader is waiting for more data, disconnect the slowest ones
+		for r := range bc.readers {
+			if r.chunk == bc.dropped {
+				r.err = errBroadcastLagging
+				delete(bc.readers, r)
+			}
+		}
+		bc.drop()
+	}
+	return !bc.closed
+}
+
+// finish records the end of the stream, which cannot be joined anymore.
+func (bc *broadcast) finish(err error) {
+	bc.lk.Lock()
+	bc.readErr = err
+	bc.closed = true
+	bc.cond.Broadcast()
+	bc.lk.Unlock()
+	bc.forget()
+}
+
+// drop releases the chunks read by all the readers. It must be called with
+// the lock held.
+func (bc *broadcast) drop() {
+	low := len(bc.chunks)
+	for r := range bc.readers {
+		low = min(low, r.chunk)
+	}
+	for ; bc.dropped < low; bc.dropped++ {
+		bc.chunks[bc.dropped] = nil
+	}
+	// wake up the stream waiting for room in the window
+	bc.cond.Broadcast()
+}
+
+type broadcastReader struct {
+	bc     *broadcast
+	chunk  int
+	offset int
+	closed bool
+	err    error // set when disconnected for falling behind
+}
+
+func (r *broadcastReader) Read(p []byte) (int, error) {
+	bc := r.bc
+	bc.lk.Lock()
+	defer bc.lk.Unlock()
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	for r.chunk >= len(bc.chunks) {
+		if bc.readErr != nil {
+			return 0, bc.readErr
+		}
+		bc.cond.Wait()
+	}
+
+	n := copy(p, bc.chunks[r.chunk][r.offset:])
+	r.offset += n
+	if r.offset == len(bc.chunks[r.chunk]) {
+		r.chunk++
+		r.offset = 0
+		bc.drop()
+	}
+	return n, nil
+}
+
+func (r *broadcastReader) Close() error {
+	bc := r.bc
+	bc.lk.Lock()
+	if r.closed {
+		bc.lk.Unlock()
+		return nil
+	}
+	r.closed = true
+	if _, ok := bc.readers[r]; !ok {
+		// already disconnected
+		bc.lk.Unlock()
+		return nil
+	}
+	delete(bc.readers, r)
+	last := len(bc.readers) == 0
+	if last {
+		// nobody is reading anymore, stop fetching
+		bc.closed = true
+		bc.cond.Broadcast()
+	} else {
+		bc.drop()
+	}
+	bc.lk.Unlock()
+
+	if last {
+		bc.cancel()
+		bc.forget()
+	}
+	return nil
+}
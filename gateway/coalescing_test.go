@@ -0,0 +1,202 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedBackend is an [IPFSBackend] whose fetches wait for release to be
+// closed.
+type gatedBackend struct {
+	IPFSBackend
+
+	data    []byte
+	release chan struct{}
+	calls   atomic.Int32
+	ctxErr  chan error
+}
+
+func newGatedBackend(data []byte) *gatedBackend {
+	return &gatedBackend{data: data, release: make(chan struct{}), ctxErr: make(chan error, 1)}
+}
+
+func (b *gatedBackend) wait(ctx context.Context) error {
+	b.calls.Add(1)
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		b.ctxErr <- ctx.Err()
+		return ctx.Err()
+	}
+}
+
+func (b *gatedBackend) GetCAR(ctx context.Context, p path.ImmutablePath, params CarParams) (ContentPathMetadata, io.ReadCloser, error) {
+	if err := b.wait(ctx); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	return ContentPathMetadata{LastSegment: p}, io.NopCloser(bytes.NewReader(b.data)), nil
+}
+
+func (b *gatedBackend) GetBlock(ctx context.Context, p path.ImmutablePath) (ContentPathMetadata, files.File, error) {
+	if err := b.wait(ctx); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	return ContentPathMetadata{LastSegment: p}, files.NewBytesFile(b.data), nil
+}
+
+func testImmutablePath(t *testing.T) path.ImmutablePath {
+	c, err := cid.Decode("bafkqaaa")
+	require.NoError(t, err)
+	return path.FromCid(c)
+}
+
+func TestCoalescingGetCAR(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), 3*broadcastChunkSize/10+7)
+	backend := newGatedBackend(data)
+	b := newIPFSBackendWithCoalescing(backend)
+	p := testImmutablePath(t)
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, rc, err := b.GetCAR(ctx, p, CarParams{Scope: DagScopeAll})
+			require.NoError(t, err)
+			defer rc.Close()
+			results[i], err = io.ReadAll(rc)
+			require.NoError(t, err)
+		}(i)
+	}
+
+	require.Eventually(t, func() bool { return backend.calls.Load() == 1 }, time.Second, time.Millisecond)
+	// wait for the requests to join the pending fetch
+	require.Eventually(t, func() bool {
+		b.carsLk.Lock()
+		defer b.carsLk.Unlock()
+		for _, bc := range b.cars {
+			bc.lk.Lock()
+			defer bc.lk.Unlock()
+			return len(bc.readers) == n
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	require.EqualValues(t, 1, backend.calls.Load())
+	for _, res := range results {
+		require.Equal(t, data, res)
+	}
+
+	// once done, a new request fetches again
+	_, rc, err := b.GetCAR(ctx, p, CarParams{Scope: DagScopeAll})
+	require.NoError(t, err)
+	res, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	require.Equal(t, data, res)
+	require.EqualValues(t, 2, backend.calls.Load())
+
+	// different parameters are not coalesced
+	_, rc, err = b.GetCAR(ctx, p, CarParams{Scope: DagScopeBlock})
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	require.EqualValues(t, 3, backend.calls.Load())
+}
+
+func TestCoalescingCancel(t *testing.T) {
+	backend := newGatedBackend([]byte("data"))
+	b := newIPFSBackendWithCoalescing(backend)
+	p := testImmutablePath(t)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		go func(ctx context.Context) {
+			_, _, err := b.GetBlock(ctx, p)
+			errs <- err
+		}(ctx)
+	}
+	require.Eventually(t, func() bool {
+		b.blocks.lk.Lock()
+		defer b.blocks.lk.Unlock()
+		f := b.blocks.flights[p.String()]
+		return f != nil && f.waiters == 2
+	}, time.Second, time.Millisecond)
+
+	// the fetch continues while a request is waiting for it
+	cancel1()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case err := <-backend.ctxErr:
+		t.Fatalf("unexpected backend cancellation: %s", err)
+	default:
+	}
+
+	// and is canceled when no request is left
+	cancel2()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	require.ErrorIs(t, <-backend.ctxErr, context.Canceled)
+	require.EqualValues(t, 1, backend.calls.Load())
+}
+
+func TestCoalescingSlowReader(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("x"), 3*broadcastWindow*broadcastChunkSize)
+	backend := newGatedBackend(data)
+	b := newIPFSBackendWithCoalescing(backend)
+	p := testImmutablePath(t)
+
+	readers := make(chan io.ReadCloser, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, rc, err := b.GetCAR(ctx, p, CarParams{Scope: DagScopeAll})
+			require.NoError(t, err)
+			readers <- rc
+		}()
+	}
+	var bc *broadcast
+	require.Eventually(t, func() bool {
+		b.carsLk.Lock()
+		defer b.carsLk.Unlock()
+		for _, bc = range b.cars {
+			bc.lk.Lock()
+			defer bc.lk.Unlock()
+			return len(bc.readers) == 2
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	close(backend.release)
+	fast, slow := <-readers, <-readers
+	defer slow.Close()
+
+	// the fast reader gets the whole stream while the slow one does not read
+	res, err := io.ReadAll(fast)
+	require.NoError(t, err)
+	require.Equal(t, data, res)
+	require.NoError(t, fast.Close())
+
+	bc.lk.Lock()
+	require.LessOrEqual(t, len(bc.chunks)-bc.dropped, broadcastWindow)
+	bc.lk.Unlock()
+
+	// and the slow reader was disconnected
+	_, err = io.ReadAll(slow)
+	require.ErrorIs(t, err, errBroadcastLagging)
+}
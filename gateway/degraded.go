@@ -0,0 +1,422 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/ipfs/boxo/blockservice"
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// ErrNotCached is returned, with a 504 Gateway Timeout status, for the
+// requests which need content missing from the local blockstore while the
+// gateway is in [DegradedMode].
+var ErrNotCached = NewErrorStatusCode(errors.New("content is not available locally and fetching is paused"), http.StatusGatewayTimeout)
+
+// DegradedMode is a switch which restricts a gateway to the content it
+// already has, for example during maintenance windows when fetching content
+// from the network must be paused. It can be toggled at any time, and is
+// enabled for a handler by setting [Config.DegradedMode].
+//
+// While enabled, requests are served by the [WithLocalOnly] view of the
+// backend, and fail immediately with [ErrNotCached] when a block they need is
+// missing. Backends which do not implement [WithLocalOnly] fail every request
+// with [ErrNotCached]. As resolving IPNS names and DNSLinks needs the network,
+// the view of [BlocksBackend] fails them with [ErrNotCached] too.
+type DegradedMode struct {
+	enabled atomic.Bool
+}
+
+// Enable restricts the gateway to the content it already has.
+func (m *DegradedMode) Enable() {
+	m.enabled.Store(true)
+}
+
+// Disable resumes fetching content.
+func (m *DegradedMode) Disable() {
+	m.enabled.Store(false)
+}
+
+// Enabled returns true if the gateway is restricted to the content it already
+// has.
+func (m *DegradedMode) Enabled() bool {
+	return m != nil && m.enabled.Load()
+}
+
+// WithLocalOnly is an optional interface of [IPFSBackend] required to serve
+// content in [DegradedMode].
+type WithLocalOnly interface {
+	// LocalOnly returns a view of the backend which only uses the blocks it
+	// already has, and fails with [format.ErrNotFound] when a block is
+	// missing, instead of fetching it.
+	LocalOnly() IPFSBackend
+}
+
+// ipfsBackendWithDegradedMode sends the calls to the local only view of the
+// backend while degraded mode is enabled.
+type ipfsBackendWithDegradedMode struct {
+	backend IPFSBackend
+	// local is nil if the backend does not implement WithLocalOnly.
+	local IPFSBackend
+	mode  *DegradedMode
+}
+
+func newIPFSBackendWithDegradedMode(backend IPFSBackend, mode *DegradedMode) *ipfsBackendWithDegradedMode {
+	b := &ipfsBackendWithDegradedMode{
+		backend: backend,
+		mode:    mode,
+	}
+	if lo, ok := backend.(WithLocalOnly); ok {
+		b.local = lo.LocalOnly()
+	}
+	return b
+}
+
+// pick returns the backend to use for a call, or ErrNotCached if there is none.
+func (b *ipfsBackendWithDegradedMode) pick() (IPFSBackend, bool, error) {
+	if !b.mode.Enabled() {
+		return b.backend, false, nil
+	}
+	if b.local == nil {
+		return nil, true, ErrNotCached
+	}
+	return b.local, true, nil
+}
+
+// notCached turns missing blocks into ErrNotCached when degraded.
+func notCached(degraded bool, err error) error {
+	if degraded && format.IsNotFound(err) {
+		return fmt.Errorf("%w: %w", ErrNotCached, err)
+	}
+	return err
+}
+
+func (b *ipfsBackendWithDegradedMode) Get(ctx context.Context, path path.ImmutablePath, ranges ...ByteRange) (ContentPathMetadata, *GetResponse, error) {
+	backend, degraded, err := b.pick()
+	if err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, f, err := backend.Get(ctx, path, ranges...)
+	return md, f, notCached(degraded, err)
+}
+
+func (b *ipfsBackendWithDegradedMode) GetAll(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.Node, error) {
+	backend, degraded, err := b.pick()
+	if err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, n, err := backend.GetAll(ctx, path)
+	return md, n, notCached(degraded, err)
+}
+
+func (b *ipfsBackendWithDegradedMode) GetBlock(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.File, error) {
+	backend, degraded, err := b.pick()
+	if err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, f, err := backend.GetBlock(ctx, path)
+	return md, f, notCached(degraded, err)
+}
+
+func (b *ipfsBackendWithDegradedMode) Head(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, *HeadResponse, error) {
+	backend, degraded, err := b.pick()
+	if err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, h, err := backend.Head(ctx, path)
+	return md, h, notCached(degraded, err)
+}
+
+func (b *ipfsBackendWithDegradedMode) GetCAR(ctx context.Context, path path.ImmutablePath, params CarParams) (ContentPathMetadata, io.ReadCloser, error) {
+	backend, degraded, err := b.pick()
+	if err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	md, rc, err := backend.GetCAR(ctx, path, params)
+	return md, rc, notCached(degraded, err)
+}
+
+func (b *ipfsBackendWithDegradedMode) ResolvePath(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, error) {
+	backend, degraded, err := b.pick()
+	if err != nil {
+		return ContentPathMetadata{}, err
+	}
+	md, err := backend.ResolvePath(ctx, path)
+	return md, notCached(degraded, err)
+}
+
+func (b *ipfsBackendWithDegradedMode) IsCached(ctx context.Context, path path.Path) bool {
+	return b.backend.IsCached(ctx, path)
+}
+
+func (b *ipfsBackendWithDegradedMode) GetIPNSRecord(ctx context.Context, c cid.Cid) ([]byte, error) {
+	backend, _, err := b.pick()
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetIPNSRecord(ctx, c)
+}
+
+func (b *ipfsBackendWithDegradedMode) ResolveMutable(ctx context.Context, p path.Path) (path.ImmutablePath, time.Duration, time.Time, error) {
+	backend, _, err := b.pick()
+	if err != nil {
+		return path.ImmutablePath{}, 0, time.Time{}, err
+	}
+	return backend.ResolveMutable(ctx, p)
+}
+
+func (b *ipfsBackendWithDegradedMode) GetDNSLinkRecord(ctx context.Context, fqdn string) (path.Path, error) {
+	backend, _, err := b.pick()
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetDNSLinkRecord(ctx, fqdn)
+}
+
+var _ IPFSBackend = (*ipfsBackendWithDegradedMode)(nil)
+var _ WithContextHint = (*ipfsBackendWithDegradedMode)(nil)
+
+func (b *ipfsBackendWithDegradedMode) WrapContextForRequest(ctx context.Context) context.Context {
+	if withCtxWrap, ok := b.backend.(WithContextHint); ok {
+		return withCtxWrap.WrapContextForRequest(ctx)
+	}
+	return ctx
+}
+
+var _ WithIPNSPublishing = (*ipfsBackendWithDegradedMode)(nil)
+
+func (b *ipfsBackendWithDegradedMode) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	return putIPNSRecord(ctx, b.backend, name, record)
+}
+
+var _ WithDeterministicCAR = (*ipfsBackendWithDegradedMode)(nil)
+
+func (b *ipfsBackendWithDegradedMode) IsDeterministicCAR(params CarParams) bool {
+	if withDeterministicCAR, ok := b.backend.(WithDeterministicCAR); ok {
+		return withDeterministicCAR.IsDeterministicCAR(params)
+	}
+	return false
+}
+
+var _ WithLocalOnly = (*BlocksBackend)(nil)
+
+// LocalOnly implements [WithLocalOnly]. The returned backend reads blocks from
+// the blockstore of the [blockservice.BlockService], without fetching missing
+// ones, and resolves paths over these blocks instead of with the resolver
+// passed with [WithResolver]. IPNS names and DNSLinks are not resolved, and
+// fail with [ErrNotCached]. Before serving a UnixFS file, a TAR archive or a
+// CAR with the whole DAG, it checks that the blocks the response needs are
+// stored, so that responses are not cut short by a missing block. The DAGs
+// found complete are not checked again for [localCompleteTTL].
+func (bb *BlocksBackend) LocalOnly() IPFSBackend {
+	bb.localOnce.Do(func() {
+		blockService := blockservice.New(bb.blockStore, nil)
+		bb.local = &localBlocksBackend{
+			BlocksBackend: &BlocksBackend{
+				blockStore:   bb.blockStore,
+				blockService: blockService,
+				dagService:   merkledag.NewDAGService(blockService),
+				resolver:     newUnixFSResolver(blockService),
+				routing:      bb.routing,
+				namesys:      bb.namesys,
+				symlinks:     bb.symlinks,
+			},
+			complete: expirable.NewLRU[cid.Cid, struct{}](localCompleteSize, nil, localCompleteTTL),
+		}
+	})
+	return bb.local
+}
+
+const (
+	// localCompleteSize is the number of DAGs remembered as complete by the
+	// local only view of a BlocksBackend.
+	localCompleteSize = 1024
+	// localCompleteTTL is how long a DAG is remembered as complete, after
+	// which it is checked again in case its blocks were removed.
+	localCompleteTTL = time.Minute
+)
+
+// localBlocksBackend is the local only view of a BlocksBackend.
+type localBlocksBackend struct {
+	*BlocksBackend
+
+	// complete are the roots of the DAGs recently found with all their
+	// blocks stored.
+	complete *expirable.LRU[cid.Cid, struct{}]
+}
+
+func (lb *localBlocksBackend) Get(ctx context.Context, path path.ImmutablePath, ranges ...ByteRange) (ContentPathMetadata, *GetResponse, error) {
+	if err := lb.checkStored(ctx, path, true, ranges); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	return lb.BlocksBackend.Get(ctx, path, ranges...)
+}
+
+func (lb *localBlocksBackend) GetAll(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.Node, error) {
+	if err := lb.checkStored(ctx, path, false, nil); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	return lb.BlocksBackend.GetAll(ctx, path)
+}
+
+func (lb *localBlocksBackend) GetCAR(ctx context.Context, path path.ImmutablePath, params CarParams) (ContentPathMetadata, io.ReadCloser, error) {
+	if params.Scope == DagScopeAll && params.Range == nil {
+		if err := lb.checkStored(ctx, path, false, nil); err != nil && format.IsNotFound(err) {
+			return ContentPathMetadata{}, nil, err
+		}
+	}
+	return lb.BlocksBackend.GetCAR(ctx, path, params)
+}
+
+func (lb *localBlocksBackend) ResolveMutable(ctx context.Context, p path.Path) (path.ImmutablePath, time.Duration, time.Time, error) {
+	if p.Namespace() != path.IPFSNamespace {
+		return path.ImmutablePath{}, 0, time.Time{}, ErrNotCached
+	}
+	return lb.BlocksBackend.ResolveMutable(ctx, p)
+}
+
+func (lb *localBlocksBackend) GetIPNSRecord(ctx context.Context, c cid.Cid) ([]byte, error) {
+	return nil, ErrNotCached
+}
+
+func (lb *localBlocksBackend) GetDNSLinkRecord(ctx context.Context, fqdn string) (path.Path, error) {
+	return nil, ErrNotCached
+}
+
+// checkStored returns a format.ErrNotFound error if a block of the DAG at p is
+// missing. If filesOnly is set, only the DAGs of UnixFS files are checked, and
+// only their blocks covering the ranges if there are any.
+func (lb *localBlocksBackend) checkStored(ctx context.Context, p path.ImmutablePath, filesOnly bool, ranges []ByteRange) error {
+	_, nd, err := lb.getNode(ctx, p)
+	if err != nil {
+		return err
+	}
+	if lb.complete.Contains(nd.Cid()) {
+		return nil
+	}
+	if filesOnly {
+		fsn, err := ft.ExtractFSNode(nd)
+		if err != nil || (fsn.Type() != ft.TFile && fsn.Type() != ft.TRaw) {
+			return nil
+		}
+		if len(ranges) > 0 {
+			return lb.hasRangeBlocks(ctx, nd, 0, fileSpans(fsn.FileSize(), ranges))
+		}
+	}
+	if err := lb.hasAllBlocks(ctx, nd, cid.NewSet()); err != nil {
+		return err
+	}
+	lb.complete.Add(nd.Cid(), struct{}{})
+	return nil
+}
+
+// fileSpans returns the [from, to) spans of a file of the given size covered
+// by the ranges.
+func fileSpans(size uint64, ranges []ByteRange) [][2]uint64 {
+	spans := make([][2]uint64, 0, len(ranges))
+	for _, r := range ranges {
+		to := size
+		if r.To != nil && *r.To >= 0 {
+			to = min(uint64(*r.To)+1, size)
+		}
+		if r.From < to {
+			spans = append(spans, [2]uint64{r.From, to})
+		}
+	}
+	return spans
+}
+
+// hasRangeBlocks returns a format.ErrNotFound error if a block of the UnixFS
+// file nd, starting at offset in the file, is missing in one of the spans.
+func (lb *localBlocksBackend) hasRangeBlocks(ctx context.Context, nd format.Node, offset uint64, spans [][2]uint64) error {
+	pn, ok := nd.(*merkledag.ProtoNode)
+	if !ok {
+		return nil
+	}
+	fsn, err := ft.FSNodeFromBytes(pn.Data())
+	if err != nil || fsn.NumChildren() != len(pn.Links()) {
+		// the blocks cannot be located in the file
+		return lb.hasAllBlocks(ctx, nd, cid.NewSet())
+	}
+
+	offset += uint64(len(fsn.Data()))
+	for i, l := range pn.Links() {
+		start, end := offset, offset+fsn.BlockSize(i)
+		offset = end
+		covered := false
+		for _, s := range spans {
+			if start < s[1] && s[0] < end {
+				covered = true
+				break
+			}
+		}
+		if !covered || l.Cid.Prefix().MhType == mh.IDENTITY {
+			continue
+		}
+		if l.Cid.Prefix().Codec == cid.Raw {
+			has, err := lb.blockStore.Has(ctx, l.Cid)
+			if err != nil {
+				return err
+			}
+			if !has {
+				return format.ErrNotFound{Cid: l.Cid}
+			}
+			continue
+		}
+		child, err := lb.dagService.Get(ctx, l.Cid)
+		if err != nil {
+			if format.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+		if err := lb.hasRangeBlocks(ctx, child, start, spans); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lb *localBlocksBackend) hasAllBlocks(ctx context.Context, nd format.Node, visited *cid.Set) error {
+	for _, l := range nd.Links() {
+		if !visited.Visit(l.Cid) || l.Cid.Prefix().MhType == mh.IDENTITY {
+			continue
+		}
+		// leaves do not need to be read
+		if l.Cid.Prefix().Codec == cid.Raw {
+			has, err := lb.blockStore.Has(ctx, l.Cid)
+			if err != nil {
+				return err
+			}
+			if !has {
+				return format.ErrNotFound{Cid: l.Cid}
+			}
+			continue
+		}
+		child, err := lb.dagService.Get(ctx, l.Cid)
+		if err != nil {
+			if format.IsNotFound(err) {
+				return err
+			}
+			// the block is stored, but its links cannot be decoded
+			continue
+		}
+		if err := lb.hasAllBlocks(ctx, child, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
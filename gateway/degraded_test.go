@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	chunker "github.com/ipfs/boxo/chunker"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/ipld/unixfs/importer"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDegradedMode(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// the blocks of the network, which the gateway fetches through the
+	// exchange when they are not stored locally
+	network := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dserv := merkledag.NewDAGService(blockservice.New(network, nil))
+
+	content := make([]byte, 10000)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	big, err := importer.BuildDagFromReader(dserv, chunker.NewSizeSplitter(bytes.NewReader(content), 100))
+	require.NoError(t, err)
+	small, err := importer.BuildDagFromReader(dserv, chunker.NewSizeSplitter(bytes.NewReader([]byte("cached")), 100))
+	require.NoError(t, err)
+
+	// all the blocks but one leaf of the big file are stored locally
+	local := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	keys, err := network.AllKeysChan(ctx)
+	require.NoError(t, err)
+	for c := range keys {
+		blk, err := network.Get(ctx, c)
+		require.NoError(t, err)
+		require.NoError(t, local.Put(ctx, blk))
+	}
+	require.NoError(t, local.DeleteBlock(ctx, big.Links()[42].Cid))
+
+	backend, err := NewBlocksBackend(blockservice.New(local, offline.Exchange(network)))
+	require.NoError(t, err)
+	mode := &DegradedMode{}
+	ts := newTestServerWithConfig(t, backend, Config{
+		DeserializedResponses: true,
+		DegradedMode:          mode,
+	})
+
+	getRange := func(url, byteRange string) (int, []byte) {
+		req := mustNewRequest(t, http.MethodGet, ts.URL+url, nil)
+		if byteRange != "" {
+			req.Header.Set("Range", "bytes="+byteRange)
+		}
+		res := mustDoWithoutRedirect(t, req)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, body
+	}
+	get := func(url string) (int, []byte) {
+		return getRange(url, "")
+	}
+
+	mode.Enable()
+	require.True(t, mode.Enabled())
+
+	status, body := get("/ipfs/" + small.Cid().String())
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "cached", string(body))
+
+	// content with a missing block fails right away, whatever the format
+	for _, url := range []string{
+		"/ipfs/" + big.Cid().String(),
+		"/ipfs/" + big.Cid().String() + "?format=car",
+		"/ipfs/" + big.Cid().String() + "?format=tar",
+		"/ipfs/" + big.Links()[42].Cid.String() + "?format=raw",
+		// names are not resolved
+		"/ipns/example.com",
+	} {
+		status, _ := get(url)
+		require.Equal(t, http.StatusGatewayTimeout, status, url)
+	}
+
+	// only the blocks of the requested ranges are needed
+	status, body = getRange("/ipfs/"+big.Cid().String(), "0-999")
+	require.Equal(t, http.StatusPartialContent, status)
+	require.Equal(t, content[:1000], body)
+	status, _ = getRange("/ipfs/"+big.Cid().String(), "4000-4999")
+	require.Equal(t, http.StatusGatewayTimeout, status)
+
+	// the missing block is fetched once degraded mode is disabled
+	mode.Disable()
+	status, body = get("/ipfs/" + big.Cid().String())
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, content, body)
+
+	mode.Enable()
+	status, body = get("/ipfs/" + big.Cid().String())
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, content, body)
+}
+
+func TestDegradedModeWithoutLocalOnly(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	mode := &DegradedMode{}
+	ts := newTestServerWithConfig(t, backend, Config{
+		DeserializedResponses: true,
+		DegradedMode:          mode,
+	})
+
+	get := func() int {
+		res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+"/subdir/fnord", nil))
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, get())
+	// backends which cannot serve stored content only fail every request
+	mode.Enable()
+	require.Equal(t, http.StatusGatewayTimeout, get())
+	mode.Disable()
+	require.Equal(t, http.StatusOK, get())
+}
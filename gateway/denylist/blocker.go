@@ -0,0 +1,148 @@
+package denylist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ipfs/boxo/path"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("denylist")
+
+// Blocker checks content paths against a set of denylist files, which can be
+// reloaded while the Blocker is in use.
+type Blocker struct {
+	files []string
+
+	lk     sync.RWMutex
+	lists  []*Denylist
+	mtimes []time.Time
+}
+
+// NewBlocker creates a [Blocker] which loads the given denylist files.
+func NewBlocker(files ...string) (*Blocker, error) {
+	b := &Blocker{files: files}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Load reads a denylist file. The name of the file is used as the name of the
+// denylist when its header does not have one.
+func Load(file string) (*Denylist, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if d.Header.Name == "" {
+		d.Header.Name = filepath.Base(file)
+	}
+	return d, nil
+}
+
+// Reload loads the denylist files again. The previous denylists are kept if
+// any of the files cannot be loaded.
+func (b *Blocker) Reload() error {
+	lists := make([]*Denylist, len(b.files))
+	mtimes := make([]time.Time, len(b.files))
+	for i, file := range b.files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		mtimes[i] = fi.ModTime()
+
+		if lists[i], err = Load(file); err != nil {
+			return err
+		}
+	}
+
+	b.lk.Lock()
+	b.lists, b.mtimes = lists, mtimes
+	b.lk.Unlock()
+	return nil
+}
+
+// Watch reloads the denylists every time one of the files is modified, until
+// the context is cancelled. Files are checked at the given interval.
+func (b *Blocker) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !b.modified() {
+			continue
+		}
+		if err := b.Reload(); err != nil {
+			log.Errorf("reloading denylists: %s", err)
+			continue
+		}
+		log.Info("denylists reloaded")
+	}
+}
+
+func (b *Blocker) modified() bool {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+
+	for i, file := range b.files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if !fi.ModTime().Equal(b.mtimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Denylists returns the loaded denylists.
+func (b *Blocker) Denylists() []*Denylist {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+	return b.lists
+}
+
+// CheckPath returns a [*BlockedError] if the given path is blocked by any of
+// the denylists. A path allowed by a "!" rule of any denylist is not blocked.
+func (b *Blocker) CheckPath(p path.Path) error {
+	var err *BlockedError
+	for _, d := range b.Denylists() {
+		block, allow := d.match(p)
+		if allow != nil {
+			return nil
+		}
+		if block != nil && err == nil {
+			err = &BlockedError{Path: p, Rule: block, List: d.Header.Name, Reason: d.reason(block)}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsBlocked returns true if err is a [*BlockedError].
+func IsBlocked(err error) bool {
+	return errors.Is(err, &BlockedError{})
+}
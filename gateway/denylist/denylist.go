@@ -0,0 +1,376 @@
+// Package denylist implements the compact denylist format specified in
+// [IPIP-383], which is used to block content served by gateways.
+//
+// A denylist is made of an optional YAML header, terminated by a "---" line,
+// followed by one rule per line. The supported rules are:
+//
+//	/ipfs/<cid>                block the CID (matched by multihash) and any path under it
+//	/ipfs/<cid>/<path>         block a path under the CID
+//	/ipfs/<cid>/<path>*        block every path starting with <path> under the CID
+//	/ipns/<name>[/<path>[*]]   block an IPNS name or DNSLink, or paths under it
+//	/path/<path>[*]            block a path under any CID or name
+//	//<multihash>              block a double-hashed CID or name, see below
+//	//<sha256 hex>             legacy double-hash, as used by the Bad Bits denylist
+//
+// Prefixing a rule with "!" allows the content it matches, even when it is
+// blocked by another rule. Rules can be followed by space-separated hints,
+// such as "reason=DMCA". Lines starting with "#" are comments.
+//
+// Double-hashes block content without disclosing it: they are the hash of
+// "<cid>/<path>", where <cid> is the CIDv1 of the content in base32 and <path>
+// is empty to block the CID itself, or of "<name>/<path>" for IPNS names.
+//
+// [IPIP-383]: https://specs.ipfs.tech/ipips/ipip-0383/
+package denylist
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	mh "github.com/multiformats/go-multihash"
+	"gopkg.in/yaml.v3"
+)
+
+// ReasonHint is the hint giving the reason why content is blocked. It can be
+// set on rules or in the header of a denylist.
+const ReasonHint = "reason"
+
+// Header is the optional header of a denylist.
+type Header struct {
+	Version     int               `yaml:"version"`
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Author      string            `yaml:"author"`
+	Hints       map[string]string `yaml:"hints"`
+}
+
+type ruleKind int
+
+const (
+	cidRule ruleKind = iota
+	ipnsRule
+	pathRule
+	doubleHashRule
+)
+
+// Rule is a single rule of a denylist.
+type Rule struct {
+	// Line is the line number of the rule in its denylist.
+	Line int
+	// Text is the rule as written in the denylist, without the hints.
+	Text string
+	// Allow is true for rules starting with "!".
+	Allow bool
+	// Hints are the hints given after the rule.
+	Hints map[string]string
+
+	kind ruleKind
+	key  string // multihash bytes, IPNS name or double-hash digest
+	path string
+	// prefix is true when path ended with "*"
+	prefix bool
+}
+
+func (r *Rule) matchPath(p string) bool {
+	if r.prefix {
+		return strings.HasPrefix(p, r.path)
+	}
+	// rules without a path block everything under the root
+	return r.path == "" || r.path == p
+}
+
+// Denylist is a parsed denylist.
+type Denylist struct {
+	Header Header
+	Rules  []*Rule
+
+	byHash map[string][]*Rule
+	byName map[string][]*Rule
+	paths  []*Rule
+	// double-hash rules by multihash code
+	doubleHashes map[uint64]map[string][]*Rule
+}
+
+// Parse reads a denylist.
+func Parse(r io.Reader) (*Denylist, error) {
+	d := &Denylist{
+		byHash:       make(map[string][]*Rule),
+		byName:       make(map[string][]*Rule),
+		doubleHashes: make(map[uint64]map[string][]*Rule),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	// Lines are held until it is known whether they are part of a header.
+	var pending []string
+	inHeader := true
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if inHeader {
+			switch {
+			case line == "---":
+				if err := yaml.Unmarshal([]byte(strings.Join(pending, "\n")), &d.Header); err != nil {
+					return nil, fmt.Errorf("invalid denylist header: %w", err)
+				}
+				inHeader = false
+				pending = nil
+				continue
+			case !looksLikeRule(line):
+				pending = append(pending, line)
+				continue
+			}
+
+			// no header, the held lines are comments
+			inHeader = false
+			for i, l := range pending {
+				if err := d.addLine(i+1, l); err != nil {
+					return nil, err
+				}
+			}
+			pending = nil
+		}
+
+		if err := d.addLine(n, line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for i, l := range pending {
+		if err := d.addLine(i+1, l); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+func looksLikeRule(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "/") || strings.HasPrefix(line, "!")
+}
+
+func (d *Denylist) addLine(n int, line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	rule, err := parseRule(line)
+	if err != nil {
+		return fmt.Errorf("denylist line %d: %w", n, err)
+	}
+	rule.Line = n
+	d.Rules = append(d.Rules, rule)
+
+	switch rule.kind {
+	case cidRule:
+		d.byHash[rule.key] = append(d.byHash[rule.key], rule)
+	case ipnsRule:
+		d.byName[rule.key] = append(d.byName[rule.key], rule)
+	case pathRule:
+		d.paths = append(d.paths, rule)
+	case doubleHashRule:
+		dmh, _ := mh.Decode([]byte(rule.key))
+		digests, ok := d.doubleHashes[dmh.Code]
+		if !ok {
+			digests = make(map[string][]*Rule)
+			d.doubleHashes[dmh.Code] = digests
+		}
+		digests[string(dmh.Digest)] = append(digests[string(dmh.Digest)], rule)
+	}
+	return nil
+}
+
+func parseRule(line string) (*Rule, error) {
+	fields := strings.Fields(line)
+	rule := &Rule{Text: fields[0]}
+	if len(fields) > 1 {
+		rule.Hints = make(map[string]string, len(fields)-1)
+		for _, hint := range fields[1:] {
+			k, v, _ := strings.Cut(hint, "=")
+			rule.Hints[k] = v
+		}
+	}
+
+	text, allow := strings.CutPrefix(rule.Text, "!")
+	rule.Allow = allow
+
+	if digest, ok := strings.CutPrefix(text, "//"); ok {
+		key, err := parseDoubleHash(digest)
+		if err != nil {
+			return nil, err
+		}
+		rule.kind, rule.key = doubleHashRule, key
+		return rule, nil
+	}
+
+	segments := path.StringToSegments(text)
+	if len(segments) < 2 {
+		return nil, fmt.Errorf("invalid rule %q", rule.Text)
+	}
+	rule.path, rule.prefix = strings.CutSuffix(strings.Join(segments[2:], "/"), "*")
+
+	switch segments[0] {
+	case path.IPFSNamespace, path.IPLDNamespace:
+		c, err := cid.Decode(segments[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", rule.Text, err)
+		}
+		rule.kind, rule.key = cidRule, string(c.Hash())
+	case path.IPNSNamespace:
+		rule.kind, rule.key = ipnsRule, nameKey(segments[1])
+	case "path":
+		rule.kind = pathRule
+		rule.path, rule.prefix = strings.CutSuffix(strings.Join(segments[1:], "/"), "*")
+	default:
+		return nil, fmt.Errorf("invalid rule %q: unknown namespace", rule.Text)
+	}
+	return rule, nil
+}
+
+// parseDoubleHash returns the multihash of a double-hash rule, which is
+// either a multihash in base58 or multibase, or a sha256 digest in hex.
+func parseDoubleHash(s string) (string, error) {
+	if len(s) == 64 {
+		if digest, err := hex.DecodeString(s); err == nil {
+			m, err := mh.Encode(digest, mh.SHA2_256)
+			return string(m), err
+		}
+	}
+
+	m, err := mh.FromB58String(s)
+	if err != nil {
+		_, data, merr := multibase.Decode(s)
+		if merr != nil {
+			return "", fmt.Errorf("invalid double-hash %q: %w", s, err)
+		}
+		if m, err = mh.Cast(data); err != nil {
+			return "", fmt.Errorf("invalid double-hash %q: %w", s, err)
+		}
+	}
+	return string(m), nil
+}
+
+// nameKey normalizes IPNS names so that names given as CIDs or peer IDs
+// match regardless of their encoding.
+func nameKey(name string) string {
+	if c, err := cid.Decode(name); err == nil {
+		return string(c.Hash())
+	}
+	if m, err := mh.FromB58String(name); err == nil {
+		return string(m)
+	}
+	return strings.ToLower(name)
+}
+
+// Blocked returns the rule blocking the given path, or nil if the path is not
+// blocked by this denylist.
+func (d *Denylist) Blocked(p path.Path) *Rule {
+	block, allow := d.match(p)
+	if allow != nil {
+		return nil
+	}
+	return block
+}
+
+// match returns the first block and allow rules matching p.
+func (d *Denylist) match(p path.Path) (block, allow *Rule) {
+	segments := p.Segments()
+	root, rest := segments[1], strings.Join(segments[2:], "/")
+
+	check := func(rules []*Rule, p string) {
+		for _, r := range rules {
+			if !r.matchPath(p) {
+				continue
+			}
+			if r.Allow {
+				if allow == nil {
+					allow = r
+				}
+			} else if block == nil {
+				block = r
+			}
+		}
+	}
+
+	// hashed identifies the root in double-hashes
+	var hashed string
+	switch p.Namespace() {
+	case path.IPFSNamespace, path.IPLDNamespace:
+		c, err := cid.Decode(root)
+		if err != nil {
+			return nil, nil
+		}
+		check(d.byHash[string(c.Hash())], rest)
+		hashed = cid.NewCidV1(c.Type(), c.Hash()).String()
+	case path.IPNSNamespace:
+		check(d.byName[nameKey(root)], rest)
+		hashed = root
+	}
+	check(d.paths, rest)
+
+	for code, digests := range d.doubleHashes {
+		inputs := []string{hashed + "/"}
+		if rest != "" {
+			inputs = append(inputs, hashed+"/"+rest)
+		}
+		for _, input := range inputs {
+			m, err := mh.Sum([]byte(input), code, -1)
+			if err != nil {
+				break
+			}
+			dmh, _ := mh.Decode(m)
+			check(digests[string(dmh.Digest)], "")
+		}
+	}
+
+	return block, allow
+}
+
+// reason returns the reason why content is blocked by the given rule.
+func (d *Denylist) reason(r *Rule) string {
+	if reason, ok := r.Hints[ReasonHint]; ok {
+		return reason
+	}
+	return d.Header.Hints[ReasonHint]
+}
+
+// BlockedError is the error returned for blocked content.
+type BlockedError struct {
+	// Path is the blocked path.
+	Path path.Path
+	// Rule is the rule blocking the path.
+	Rule *Rule
+	// List is the name of the denylist with the rule.
+	List string
+	// Reason is the reason given by the rule or denylist hints, if any.
+	Reason string
+}
+
+// Error implements the [error] interface.
+func (e *BlockedError) Error() string {
+	msg := e.Path.String() + " is blocked and cannot be provided"
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Is implements the [errors.Is] interface.
+func (e *BlockedError) Is(err error) bool {
+	switch err.(type) {
+	case *BlockedError:
+		return true
+	default:
+		return false
+	}
+}
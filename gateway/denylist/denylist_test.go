@@ -0,0 +1,155 @@
+package denylist
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCidV0 = "QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR"
+	testCidV1 = "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+	otherCid  = "bafkqaaa"
+)
+
+func mustPath(t *testing.T, s string) path.Path {
+	t.Helper()
+	p, err := path.NewPath(s)
+	require.NoError(t, err)
+	return p
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	d, err := Parse(strings.NewReader(`# comment
+version: 1
+name: test list
+hints:
+  reason: default reason
+---
+# blocks
+/ipfs/` + testCidV0 + `
+/ipfs/` + otherCid + `/secret reason=specific
+/ipfs/` + otherCid + `/private/*
+/ipns/example.com
+/path/malware.exe
+!/ipfs/` + otherCid + `/private/ok
+`))
+	require.NoError(t, err)
+	require.Equal(t, 1, d.Header.Version)
+	require.Equal(t, "test list", d.Header.Name)
+	require.Len(t, d.Rules, 6)
+	require.Equal(t, 8, d.Rules[0].Line)
+
+	for _, tc := range []struct {
+		path    string
+		blocked bool
+	}{
+		// CIDs are matched by multihash, with any path under them
+		{"/ipfs/" + testCidV0, true},
+		{"/ipfs/" + testCidV1 + "/a/b", true},
+		{"/ipfs/" + otherCid, false},
+		{"/ipfs/" + otherCid + "/secret", true},
+		{"/ipfs/" + otherCid + "/secret/other", false},
+		{"/ipfs/" + otherCid + "/private/a/b", true},
+		{"/ipfs/" + otherCid + "/private/ok", false},
+		{"/ipns/example.com", true},
+		{"/ipns/EXAMPLE.com/a", true},
+		{"/ipns/example.org", false},
+		{"/ipns/example.org/malware.exe", true},
+	} {
+		rule := d.Blocked(mustPath(t, tc.path))
+		require.Equal(t, tc.blocked, rule != nil, tc.path)
+	}
+
+	rule := d.Blocked(mustPath(t, "/ipfs/"+otherCid+"/secret"))
+	require.Equal(t, "specific", d.reason(rule))
+	rule = d.Blocked(mustPath(t, "/ipfs/"+testCidV0))
+	require.Equal(t, "default reason", d.reason(rule))
+}
+
+func TestParseWithoutHeader(t *testing.T) {
+	t.Parallel()
+
+	d, err := Parse(strings.NewReader("# comment\n\n/ipfs/" + testCidV0 + "\n"))
+	require.NoError(t, err)
+	require.Empty(t, d.Header.Name)
+	require.Len(t, d.Rules, 1)
+	require.Equal(t, 3, d.Rules[0].Line)
+
+	_, err = Parse(strings.NewReader("/ipfs/" + testCidV0 + "\n/ipfs/not-a-cid\n"))
+	require.ErrorContains(t, err, "line 2")
+}
+
+func TestDoubleHash(t *testing.T) {
+	t.Parallel()
+
+	c, err := cid.Decode(testCidV0)
+	require.NoError(t, err)
+	v1 := cid.NewCidV1(c.Type(), c.Hash()).String()
+
+	legacy := sha256.Sum256([]byte(v1 + "/"))
+	m, err := mh.Sum([]byte(v1+"/secret"), mh.SHA2_256, -1)
+	require.NoError(t, err)
+
+	d, err := Parse(strings.NewReader("//" + hex.EncodeToString(legacy[:]) + "\n"))
+	require.NoError(t, err)
+	require.NotNil(t, d.Blocked(mustPath(t, "/ipfs/"+testCidV0)))
+	require.NotNil(t, d.Blocked(mustPath(t, "/ipfs/"+testCidV1+"/any")))
+	require.Nil(t, d.Blocked(mustPath(t, "/ipfs/"+otherCid)))
+
+	d, err = Parse(strings.NewReader("//" + m.B58String() + "\n"))
+	require.NoError(t, err)
+	require.Nil(t, d.Blocked(mustPath(t, "/ipfs/"+testCidV0)))
+	require.NotNil(t, d.Blocked(mustPath(t, "/ipfs/"+testCidV0+"/secret")))
+}
+
+func TestBlocker(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.deny"), filepath.Join(dir, "second.deny")
+	require.NoError(t, os.WriteFile(first, []byte("/ipfs/"+testCidV0+" reason=DMCA\n"), 0o644))
+	require.NoError(t, os.WriteFile(second, []byte("!/ipfs/"+otherCid+"\n"), 0o644))
+
+	b, err := NewBlocker(first, second)
+	require.NoError(t, err)
+
+	err = b.CheckPath(mustPath(t, "/ipfs/"+testCidV1))
+	require.True(t, IsBlocked(err))
+	var berr *BlockedError
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, "first.deny", berr.List)
+	require.Equal(t, "DMCA", berr.Reason)
+	require.Contains(t, berr.Error(), "blocked and cannot be provided: DMCA")
+	require.NoError(t, b.CheckPath(mustPath(t, "/ipfs/"+otherCid)))
+
+	// Allow rules of any list take precedence
+	require.NoError(t, os.WriteFile(first, []byte("/ipfs/"+otherCid+"\n"), 0o644))
+	require.NoError(t, os.Chtimes(first, time.Now(), time.Now().Add(time.Hour)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Watch(ctx, time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return b.CheckPath(mustPath(t, "/ipfs/"+testCidV0)) == nil
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, b.CheckPath(mustPath(t, "/ipfs/"+otherCid)))
+
+	// Invalid denylists are not loaded
+	require.NoError(t, os.WriteFile(second, []byte("/invalid\n"), 0o644))
+	require.Error(t, b.Reload())
+	require.NoError(t, b.CheckPath(mustPath(t, "/ipfs/"+otherCid)))
+}
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,8 +11,12 @@ import (
 	"time"
 
 	"github.com/ipfs/boxo/gateway/assets"
+	"github.com/ipfs/boxo/gateway/denylist"
+	"github.com/ipfs/boxo/namesys"
+	"github.com/ipfs/boxo/path"
 	"github.com/ipfs/boxo/path/resolver"
 	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
 	"github.com/ipld/go-ipld-prime/datamodel"
 )
 
@@ -127,9 +132,45 @@ func (e *ErrorStatusCode) Unwrap() error {
 	return e.Err
 }
 
+// ErrorCode is a stable, machine-readable identifier of the kind of error
+// returned in [ErrorResponse].
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest   ErrorCode = "invalid-request"
+	ErrorCodeNotFound         ErrorCode = "not-found"
+	ErrorCodeIPNSUnresolvable ErrorCode = "ipns-unresolvable"
+	ErrorCodeContentBlocked   ErrorCode = "content-blocked"
+	ErrorCodeTimeout          ErrorCode = "timeout"
+	ErrorCodeTooManyRequests  ErrorCode = "too-many-requests"
+	ErrorCodeUnavailable      ErrorCode = "unavailable"
+	ErrorCodeInternal         ErrorCode = "internal"
+)
+
+// ErrorResponse is the body of error responses sent to clients that send the
+// "Accept: application/json" header.
+type ErrorResponse struct {
+	// Code identifies the kind of error, it is meant for programmatic use.
+	Code ErrorCode `json:"code"`
+	// Message is the text of the HTTP status code.
+	Message string `json:"message"`
+	// Details is the underlying error message.
+	Details string `json:"details,omitempty"`
+	// Cid is the CID related to the error, if known.
+	Cid string `json:"cid,omitempty"`
+	// Path is the requested content path.
+	Path string `json:"path,omitempty"`
+}
+
 func webError(w http.ResponseWriter, r *http.Request, c *Config, err error, defaultCode int) {
 	code := defaultCode
 
+	// Say which phase of the request timed out
+	var te *TimeoutError
+	if errors.Is(err, context.DeadlineExceeded) && !errors.As(err, &te) {
+		err = withTimeoutCause(r.Context(), err)
+	}
+
 	// Pass Retry-After hint to the client
 	var era *ErrorRetryAfter
 	if errors.As(err, &era) {
@@ -162,6 +203,7 @@ func webError(w http.ResponseWriter, r *http.Request, c *Config, err error, defa
 	}
 
 	acceptsHTML := !c.DisableHTMLErrors && strings.Contains(r.Header.Get("Accept"), "text/html")
+	acceptsJSON := strings.Contains(r.Header.Get("Accept"), jsonResponseFormat)
 	if acceptsHTML {
 		w.Header().Set("Content-Type", "text/html")
 		w.WriteHeader(code)
@@ -176,11 +218,72 @@ func webError(w http.ResponseWriter, r *http.Request, c *Config, err error, defa
 		if err != nil {
 			_, _ = w.Write([]byte(fmt.Sprintf("error during body generation: %v", err)))
 		}
+	} else if acceptsJSON {
+		w.Header().Set("Content-Type", jsonResponseFormat)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{
+			Code:    errorCode(err, code),
+			Message: http.StatusText(code),
+			Details: err.Error(),
+			Cid:     errorCid(r, err),
+			Path:    r.URL.Path,
+		})
 	} else {
 		http.Error(w, err.Error(), code)
 	}
 }
 
+// errorCode returns the [ErrorCode] matching err and the HTTP status code
+// picked for it.
+func errorCode(err error, code int) ErrorCode {
+	switch {
+	case errors.Is(err, namesys.ErrResolveFailed):
+		return ErrorCodeIPNSUnresolvable
+	case isErrContentBlocked(err):
+		return ErrorCodeContentBlocked
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	}
+
+	switch code {
+	case http.StatusNotFound, http.StatusGone:
+		return ErrorCodeNotFound
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return ErrorCodeTimeout
+	case http.StatusTooManyRequests:
+		return ErrorCodeTooManyRequests
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return ErrorCodeUnavailable
+	}
+	if code >= 400 && code < 500 {
+		return ErrorCodeInvalidRequest
+	}
+	return ErrorCodeInternal
+}
+
+// errorCid returns the CID of the block that caused err, or the root CID of
+// the requested path, or an empty string if none is known.
+func errorCid(r *http.Request, err error) string {
+	var errNotFound ipld.ErrNotFound
+	if errors.As(err, &errNotFound) && errNotFound.Cid.Defined() {
+		return errNotFound.Cid.String()
+	}
+
+	var errNoLink *resolver.ErrNoLink
+	if errors.As(err, &errNoLink) && errNoLink.Node.Defined() {
+		return errNoLink.Node.String()
+	}
+
+	if p, err := path.NewPath(r.URL.Path); err == nil && p.Namespace() == path.IPFSNamespace {
+		if ip, err := path.NewImmutablePath(p); err == nil {
+			return ip.RootCid().String()
+		}
+	}
+
+	return ""
+}
+
 // isErrNotFound returns true for IPLD errors that should return 4xx errors (e.g. the path doesn't exist, the data is
 // the wrong type, etc.), rather than issues with just finding and retrieving the data.
 func isErrNotFound(err error) bool {
@@ -210,6 +313,10 @@ func isErrNotFound(err error) bool {
 
 // isErrContentBlocked returns true for content filtering system errors
 func isErrContentBlocked(err error) bool {
+	if denylist.IsBlocked(err) {
+		return true
+	}
+
 	// TODO: we match error message to avoid pulling nopfs as a dependency
 	// Ref. https://github.com/ipfs-shipyard/nopfs/blob/cde3b5ba964c13e977f4a95f3bd8ca7d7710fbda/status.go#L87-L89
 	return strings.Contains(err.Error(), "blocked and cannot be provided")
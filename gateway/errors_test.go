@@ -1,6 +1,8 @@
 package gateway
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -8,6 +10,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/boxo/namesys"
+	"github.com/ipfs/boxo/path/resolver"
+	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -104,12 +109,59 @@ func TestWebError(t *testing.T) {
 
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest(http.MethodGet, "/blah", nil)
-		r.Header.Set("Accept", "application/json")
+		r.Header.Set("Accept", "something/else")
 		webError(w, r, config, NewErrorStatusCodeFromStatus(http.StatusTeapot), http.StatusInternalServerError)
 		require.Equal(t, http.StatusTeapot, w.Result().StatusCode)
 		require.Contains(t, w.Result().Header.Get("Content-Type"), "text/plain")
 	})
 
+	t.Run("Error is sent as JSON when 'Accept' header contains 'application/json'", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := cid.Decode("bafkqaaa")
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/ipfs/"+c.String()+"/foo", nil)
+		r.Header.Set("Accept", "application/json")
+		webError(w, r, config, fmt.Errorf("wrapped for testing: %w", &resolver.ErrNoLink{Name: "foo", Node: c}), http.StatusInternalServerError)
+		require.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+		require.Contains(t, w.Result().Header.Get("Content-Type"), "application/json")
+
+		var res ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&res))
+		require.Equal(t, ErrorCodeNotFound, res.Code)
+		require.Equal(t, http.StatusText(http.StatusNotFound), res.Message)
+		require.Contains(t, res.Details, "wrapped for testing")
+		require.Equal(t, c.String(), res.Cid)
+		require.Equal(t, "/ipfs/"+c.String()+"/foo", res.Path)
+	})
+
+	t.Run("JSON error codes", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			err  error
+			code ErrorCode
+		}{
+			{fmt.Errorf("%w: boom", namesys.ErrResolveFailed), ErrorCodeIPNSUnresolvable},
+			{errors.New("blocked and cannot be provided"), ErrorCodeContentBlocked},
+			{context.DeadlineExceeded, ErrorCodeTimeout},
+			{ErrTooManyRequests, ErrorCodeTooManyRequests},
+			{errors.New("boom"), ErrorCodeInternal},
+		} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/ipns/example.net", nil)
+			r.Header.Set("Accept", "application/json")
+			webError(w, r, config, tc.err, http.StatusInternalServerError)
+
+			var res ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&res))
+			require.Equal(t, tc.code, res.Code, tc.err.Error())
+			require.Empty(t, res.Cid)
+		}
+	})
+
 	t.Run("Error is sent as plain text when 'Accept' header contains 'text/html' and config.DisableHTMLErrors is true", func(t *testing.T) {
 		t.Parallel()
 
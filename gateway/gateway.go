@@ -12,7 +12,9 @@ import (
 	"github.com/ipfs/boxo/files"
 	"github.com/ipfs/boxo/gateway/assets"
 	"github.com/ipfs/boxo/ipld/unixfs"
+	"github.com/ipfs/boxo/ipns"
 	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/boxo/verifcid"
 	"github.com/ipfs/go-cid"
 )
 
@@ -40,14 +42,148 @@ type Config struct {
 	// is being proxied by other service, which wants to use the error message.
 	DisableHTMLErrors bool
 
+	// ForcedRedirects enables forced rules (i.e. rules with a status code
+	// suffixed with "!") in _redirects files. They are applied even when the
+	// requested path exists, which requires looking up the _redirects file for
+	// every website request with origin isolation.
+	ForcedRedirects bool
+
 	// PublicGateways configures the behavior of known public gateways. Each key is
-	// a fully qualified domain name (FQDN). To be used with WithHostname.
+	// a fully qualified domain name (FQDN), or a pattern where `*` matches a
+	// single DNS label, such as `*.example.com`. Exact hostnames take precedence
+	// over patterns, and `*.domain.tld` patterns over the other ones. To be used
+	// with WithHostname.
 	PublicGateways map[string]*PublicGateway
 
 	// Menu adds items to the gateway menu that are shown in pages, such as
 	// directory listings, DAG previews and errors. These will be displayed to the
 	// right of "About IPFS" and "Install IPFS".
 	Menu []assets.MenuItem
+
+	// ContentBlocker, if set, is used to refuse serving content, such as the
+	// content blocked by a [denylist.Blocker]. Blocked requests get a 410 Gone
+	// response with the reason for blocking.
+	ContentBlocker ContentBlocker
+
+	// CidPolicy, if set, is used to refuse requests for root CIDs it does not
+	// allow with a 400 Bad Request response. The CIDs of the blocks fetched by
+	// the backend are checked by the backend itself, for instance by the
+	// [verifcid.Policy] given to its blockservice.
+	CidPolicy *verifcid.Policy
+
+	// CoalesceRequests shares the backend fetches of identical concurrent
+	// requests: CAR responses for the same path and parameters are streamed
+	// to all the requests from a single GetCAR call, and blocks and path
+	// resolutions are only fetched once while pending. A shared CAR stream is
+	// buffered up to 2 MiB behind its fastest reader, slower readers are
+	// disconnected.
+	CoalesceRequests bool
+
+	// ServerTiming adds a [Server-Timing] header to the responses, with the
+	// time spent resolving names and paths, and fetching the first block of
+	// the content. The time spent streaming the body is sent in a trailer.
+	//
+	// [Server-Timing]: https://www.w3.org/TR/server-timing/
+	ServerTiming bool
+
+	// EarlyHints sends a 103 Early Hints response, with preload Link headers
+	// for the stylesheets, scripts and fonts next to the index.html of a
+	// UnixFS directory, before serving the index.html. Browsers can then
+	// fetch them while the page is still loading. The Link headers are also
+	// sent with the page. Only the first entries of large directories are
+	// looked at.
+	EarlyHints bool
+
+	// IPNSPublishing accepts signed IPNS records PUT to /ipns/{name}, with
+	// the application/vnd.ipfs.ipns-record content type. Records are
+	// validated against the name before being published by the backend,
+	// which must implement [WithIPNSPublishing].
+	IPNSPublishing bool
+
+	// NameResolutionTimeout bounds the resolution of each IPNS name and
+	// DNSLink. Zero means no limit.
+	NameResolutionTimeout time.Duration
+
+	// PathResolutionTimeout bounds the resolution of content paths to their
+	// CID, when done on its own. Zero means no limit.
+	PathResolutionTimeout time.Duration
+
+	// FirstBlockTimeout bounds the time until the backend returns the
+	// requested content, which includes resolving its path and fetching its
+	// first block. Zero means no limit.
+	FirstBlockTimeout time.Duration
+
+	// ResponseTimeout bounds the whole request, including streaming the
+	// response body. Zero means a hard limit of one hour.
+	//
+	// Requests exceeding one of these timeouts before the response is sent
+	// get a 504 Gateway Timeout response saying which phase timed out, see
+	// [TimeoutError]. Responses already being streamed are aborted.
+	ResponseTimeout time.Duration
+
+	// DAGStatsBlockBudget is the maximum number of blocks traversed to
+	// compute application/vnd.ipfs.dag-stats responses, which are marked as
+	// incomplete for larger DAGs. Defaults to [DefaultDAGStatsBlockBudget].
+	DAGStatsBlockBudget int
+
+	// MaxExportBytes is the maximum size of the bodies of CAR, TAR and
+	// application/x-ndjson responses, and MaxExportBlocks the maximum number
+	// of blocks of CAR and application/x-ndjson responses. Zero means no
+	// limit.
+	//
+	// Exports reaching a limit before anything was sent get a 413 Content
+	// Too Large response. Others are truncated, at the last block within the
+	// limits for CARs and NDJSON, and get an X-Stream-Truncated trailer with
+	// the limit reached.
+	MaxExportBytes  int64
+	MaxExportBlocks int64
+
+	// TAR configures application/x-tar responses, see [TARConfig].
+	TAR TARConfig
+
+	// DegradedMode, if set, can be enabled at runtime to only serve content
+	// which is already stored locally, see [DegradedMode].
+	DegradedMode *DegradedMode
+}
+
+// TARConfig configures the archives of application/x-tar responses.
+type TARConfig struct {
+	// Deterministic writes the entries of every directory sorted by name and
+	// uses the Unix epoch as the modification time of the entries without
+	// one, so that the same content always produces the same archive.
+	// Otherwise, entries are in the order of the directories and the current
+	// time is used.
+	Deterministic bool
+
+	// OmitMetadata drops the UnixFS mode and mtime of the entries, which are
+	// included when present by default.
+	OmitMetadata bool
+
+	// RootName is the naming policy of the top-level directory (or file) of
+	// the archive. Defaults to [TARRootCID].
+	RootName TARRootName
+}
+
+// TARRootName is a naming policy of the top-level entry of TAR archives.
+type TARRootName int
+
+const (
+	// TARRootCID names the top-level entry with the CID of the content.
+	TARRootCID TARRootName = iota
+	// TARRootFilename names the top-level entry with the filename query
+	// parameter, without its .tar extension, or else with the CID.
+	TARRootFilename
+	// TARRootPathName names the top-level entry with the last segment of the
+	// requested content path, such as "dir" for /ipfs/{cid}/dir, or with the
+	// CID when the path has no segment after the root.
+	TARRootPathName
+)
+
+// ContentBlocker decides which content paths the gateway refuses to serve.
+type ContentBlocker interface {
+	// CheckPath returns an error if the given path must not be served. The
+	// gateway responds with 410 Gone to [*denylist.BlockedError] errors.
+	CheckPath(path.Path) error
 }
 
 // PublicGateway is the specification of an IPFS Public Gateway.
@@ -76,6 +212,13 @@ type PublicGateway struct {
 	// overrides the global setting.
 	NoDNSLink bool
 
+	// DNSLinkOnly configures this gateway to only serve the DNSLink website of
+	// the FQDN provided in `Host` HTTP header. All requests are handled as
+	// DNSLink requests, without looking up the DNSLink record beforehand, so
+	// Paths, UseSubdomains and NoDNSLink are ignored. Useful for hosting many
+	// DNSLink websites with a pattern like `*.example.com`.
+	DNSLinkOnly bool
+
 	// InlineDNSLink configures this gateway to always inline DNSLink names
 	// (FQDN) into a single DNS label in order to interop with wildcard TLS certs
 	// and Origin per CID isolation provided by rules like https://publicsuffix.org
@@ -189,6 +332,13 @@ func NewDuplicateBlocksPolicy(dupsValue string) (DuplicateBlocksPolicy, error) {
 	return 0, fmt.Errorf("unsupported application/vnd.ipld.car content type dups parameter: %q", dupsValue)
 }
 
+// Deterministic returns true if the parameters ask for a stable block order
+// without duplicates, which is a requirement for byte-for-byte deterministic
+// CAR responses.
+func (p CarParams) Deterministic() bool {
+	return p.Order == DagOrderDFS && p.Duplicates == DuplicateBlocksExcluded
+}
+
 func (d DuplicateBlocksPolicy) Bool() bool {
 	// duplicates should be returned only when explicitly requested,
 	// so any other state than DuplicateBlocksIncluded should return false
@@ -387,6 +537,26 @@ type WithContextHint interface {
 	WrapContextForRequest(context.Context) context.Context
 }
 
+// WithDeterministicCAR is an optional interface that an [IPFSBackend] can
+// implement to report that the CAR streams returned by GetCAR are
+// byte-for-byte deterministic for a given path and [CarParams]. Such responses
+// are sent with a strong Etag, allowing them to be content-addressed, cached
+// and signed by intermediaries.
+type WithDeterministicCAR interface {
+	// IsDeterministicCAR returns true if CAR streams returned for the given
+	// parameters only depend on the requested path.
+	IsDeterministicCAR(CarParams) bool
+}
+
+// WithIPNSPublishing is an optional interface that an [IPFSBackend] can
+// implement to publish the IPNS records PUT to the gateway when
+// [Config.IPNSPublishing] is enabled.
+type WithIPNSPublishing interface {
+	// PutIPNSRecord publishes the raw record of the name, for instance to
+	// the routing system. The record was validated against the name.
+	PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error
+}
+
 // RequestContextKey is a type representing a [context.Context] value key.
 type RequestContextKey string
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -152,6 +153,34 @@ func TestHeaders(t *testing.T) {
 		}
 	})
 
+	t.Run("ETag is weak for /ipns/ and If-None-Match returns cache headers", func(t *testing.T) {
+		t.Parallel()
+
+		ts, backend, root := newTestServerAndNode(t, nil, "ipns-hostname-redirects.car")
+		backend.namesys["/ipns/example.net"] = newMockNamesysItem(path.FromCid(root), time.Second*30)
+
+		for _, p := range []string{
+			"/ipns/example.net/",                 // As generated directory listing
+			"/ipns/example.net/foo/index.html",   // As deserialized UnixFS file
+			"/ipns/example.net/?format=raw",      // As Raw block
+			"/ipns/example.net/?format=dag-json", // As DAG-JSON block
+			"/ipns/example.net/?format=car",      // As CAR block
+		} {
+			req := mustNewRequest(t, http.MethodGet, ts.URL+p, nil)
+			res := mustDoWithoutRedirect(t, req)
+			require.Equal(t, http.StatusOK, res.StatusCode, p)
+			etag := res.Header.Get("Etag")
+			require.True(t, strings.HasPrefix(etag, `W/"`), "%s: unexpected Etag %q", p, etag)
+
+			req = mustNewRequest(t, http.MethodGet, ts.URL+p, nil)
+			req.Header.Set("If-None-Match", etag)
+			res = mustDoWithoutRedirect(t, req)
+			require.Equal(t, http.StatusNotModified, res.StatusCode, p)
+			assert.Equal(t, etag, res.Header.Get("Etag"), p)
+			assert.Equal(t, "public, max-age=30", res.Header.Get("Cache-Control"), p)
+		}
+	})
+
 	t.Run("Cache-Control is not immutable on generated /ipfs/ HTML dir listings", func(t *testing.T) {
 		req := mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+rootCID+"/", nil)
 		res := mustDoWithoutRedirect(t, req)
@@ -297,6 +326,26 @@ func TestHeaders(t *testing.T) {
 		test(dagCborResponseFormat, dagCborPath, dagCborRoots)
 	})
 
+	t.Run("Provenance headers are set on errors", func(t *testing.T) {
+		// CAR responses for missing paths succeed, with the blocks along the path
+		for _, responseFormat := range []string{"", "text/html", rawResponseFormat, tarResponseFormat, dagJsonResponseFormat} {
+			t.Run(responseFormat, func(t *testing.T) {
+				p := "/ipfs/" + rootCID + "/missing"
+				req := mustNewRequest(t, http.MethodGet, ts.URL+p, nil)
+				req.Header.Add("Accept", responseFormat)
+				res := mustDoWithoutRedirect(t, req)
+				_, err := io.Copy(io.Discard, res.Body)
+				require.NoError(t, err)
+				defer res.Body.Close()
+				require.NotEqual(t, http.StatusOK, res.StatusCode)
+				require.Equal(t, p, res.Header.Get("X-Ipfs-Path"))
+				require.Equal(t, p, res.Header.Get("X-Content-Path"))
+				// only the root of the path was resolved
+				require.Equal(t, rootCID, res.Header.Get("X-Ipfs-Roots"))
+			})
+		}
+	})
+
 	t.Run("If-None-Match with wrong value forces path resolution, but X-Ipfs-Roots is correct (regression)", func(t *testing.T) {
 		test := func(responseFormat string, path string, roots string) {
 			t.Run(responseFormat, func(t *testing.T) {
@@ -665,6 +714,38 @@ func TestRedirects(t *testing.T) {
 	})
 }
 
+func TestDNSLinkOnlyWildcard(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	backend.namesys["/ipns/site.tenant.com"] = newMockNamesysItem(path.FromCid(root), 0)
+
+	ts := newTestServerWithConfig(t, backend, Config{
+		PublicGateways: map[string]*PublicGateway{
+			"*.tenant.com": {
+				DNSLinkOnly:           true,
+				DeserializedResponses: true,
+			},
+		},
+	})
+
+	do := func(host, path string) int {
+		req := mustNewRequest(t, http.MethodGet, ts.URL+path, nil)
+		req.Host = host
+		res := mustDoWithoutRedirect(t, req)
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	// The DNSLink website is served, with deserialized responses enabled by
+	// the wildcard configuration.
+	require.Equal(t, http.StatusOK, do("site.tenant.com", "/"))
+	// Paths are not handled as content paths.
+	require.NotEqual(t, http.StatusOK, do("site.tenant.com", "/ipfs/"+root.String()))
+	// Hostnames without DNSLink are not served.
+	require.NotEqual(t, http.StatusOK, do("missing.tenant.com", "/"))
+}
+
 func TestDeserializedResponses(t *testing.T) {
 	t.Parallel()
 
@@ -787,6 +868,40 @@ func TestDeserializedResponses(t *testing.T) {
 	})
 }
 
+func TestServerTiming(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	backend.namesys["/ipns/example.com"] = newMockNamesysItem(path.FromCid(root), 0)
+	ts := newTestServerWithConfig(t, backend, Config{
+		DeserializedResponses: true,
+		ServerTiming:          true,
+	})
+
+	res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+"/ipns/example.com/subdir/fnord", nil))
+	_, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	timing := res.Header.Get("Server-Timing")
+	require.Regexp(t, `^resolve-name;dur=[0-9.]+, first-block;dur=[0-9.]+$`, timing)
+
+	// streamed responses report the body in a trailer
+	res = mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+"?format=car", nil))
+	_, err = io.ReadAll(res.Body)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Regexp(t, `^first-block;dur=[0-9.]+$`, res.Header.Get("Server-Timing"))
+	require.Regexp(t, `^body;dur=[0-9.]+$`, res.Trailer.Get("Server-Timing"))
+
+	// and nothing is reported by default
+	ts = newTestServer(t, backend)
+	res = mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+"?format=raw", nil))
+	res.Body.Close()
+	require.Empty(t, res.Header.Get("Server-Timing"))
+}
+
 type errorMockBackend struct {
 	err error
 }
@@ -12,9 +12,11 @@ import (
 	gopath "path"
 	"regexp"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/ipfs/boxo/gateway/assets"
 	"github.com/ipfs/boxo/ipns"
 	"github.com/ipfs/boxo/path"
@@ -47,6 +49,7 @@ var (
 type handler struct {
 	config  *Config
 	backend IPFSBackend
+	hosts   *hostnameGateways
 
 	// response type metrics
 	requestTypeMetric            *prometheus.CounterVec
@@ -59,8 +62,12 @@ type handler struct {
 	rawBlockGetMetric            *prometheus.HistogramVec
 	tarStreamGetMetric           *prometheus.HistogramVec
 	tarStreamFailMetric          *prometheus.HistogramVec
+	ndjsonStreamGetMetric        *prometheus.HistogramVec
+	ndjsonStreamFailMetric       *prometheus.HistogramVec
 	jsoncborDocumentGetMetric    *prometheus.HistogramVec
+	dagStatsGetMetric            *prometheus.HistogramVec
 	ipnsRecordGetMetric          *prometheus.HistogramVec
+	ipnsRecordPutMetric          *prometheus.HistogramVec
 }
 
 // NewHandler returns an [http.Handler] that provides the functionality
@@ -129,13 +136,27 @@ func (i *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer panicHandler(w)
 
 	// the hour is a hard fallback, we don't expect it to happen, but just in case
-	ctx, cancel := context.WithTimeout(r.Context(), time.Hour)
+	timeout := time.Hour
+	var timeoutErr error
+	if i.config.ResponseTimeout > 0 {
+		timeout = i.config.ResponseTimeout
+		timeoutErr = &TimeoutError{Phase: TimeoutPhaseResponse, Timeout: timeout}
+	}
+	ctx, cancel := context.WithTimeoutCause(r.Context(), timeout, timeoutErr)
 	defer cancel()
 
 	if withCtxWrap, ok := i.backend.(WithContextHint); ok {
 		ctx = withCtxWrap.WrapContextForRequest(ctx)
 	}
 
+	if i.config.ServerTiming {
+		var timing *serverTiming
+		ctx, timing = contextWithServerTiming(ctx)
+		tw := &serverTimingResponseWriter{ResponseWriter: w, timing: timing}
+		defer tw.finish()
+		w = tw
+	}
+
 	r = r.WithContext(ctx)
 
 	switch r.Method {
@@ -145,26 +166,34 @@ func (i *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case http.MethodOptions:
 		i.optionsHandler(w, r)
 		return
+	case http.MethodPut:
+		if i.config.IPNSPublishing {
+			i.putIpnsRecord(w, r)
+			return
+		}
 	}
 
-	addAllowHeader(w)
+	i.addAllowHeader(w)
 
 	errmsg := "Method " + r.Method + " not allowed: read only access"
 	http.Error(w, errmsg, http.StatusMethodNotAllowed)
 }
 
 func (i *handler) optionsHandler(w http.ResponseWriter, r *http.Request) {
-	addAllowHeader(w)
+	i.addAllowHeader(w)
 	// OPTIONS is a noop request that is used by the browsers to check if server accepts
 	// cross-site XMLHttpRequest, which is indicated by the presence of CORS headers:
 	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Access_control_CORS#Preflighted_requests
 }
 
 // addAllowHeader sets Allow header with supported HTTP methods
-func addAllowHeader(w http.ResponseWriter) {
+func (i *handler) addAllowHeader(w http.ResponseWriter) {
 	w.Header().Add("Allow", http.MethodGet)
 	w.Header().Add("Allow", http.MethodHead)
 	w.Header().Add("Allow", http.MethodOptions)
+	if i.config.IPNSPublishing {
+		w.Header().Add("Allow", http.MethodPut)
+	}
 }
 
 type requestData struct {
@@ -242,7 +271,7 @@ func (i *handler) getOrHeadHandler(w http.ResponseWriter, r *http.Request) {
 	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("ResponseFormat", responseFormat))
 	i.requestTypeMetric.WithLabelValues(contentPath.Namespace(), responseFormat).Inc()
 
-	w.Header().Set("X-Ipfs-Path", contentPath.String())
+	SetProvenanceHeaders(w.Header(), contentPath, path.ImmutablePath{}, nil)
 
 	// Fail fast if unsupported request type was sent to a Trustless Gateway.
 	if !i.isDeserializedResponsePossible(r) && !i.isTrustlessRequest(contentPath, responseFormat) {
@@ -284,6 +313,18 @@ func (i *handler) getOrHeadHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if i.config.CidPolicy != nil {
+		if err := i.config.CidPolicy.Validate(rq.immutablePath.RootCid()); err != nil {
+			err = fmt.Errorf("CID %s is not allowed: %w", rq.immutablePath.RootCid(), err)
+			i.webError(w, r, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The roots are only partially known until the path is resolved, which
+	// is still worth telling if it fails.
+	SetProvenanceHeaders(w.Header(), contentPath, rq.immutablePath, nil)
+
 	// CAR response format can be handled now, since (1) it explicitly needs the
 	// full immutable path to include in the CAR, and (2) has custom If-None-Match
 	// header handling due to custom ETag.
@@ -293,6 +334,26 @@ func (i *handler) getOrHeadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Likewise for NDJSON, which streams the same blocks as CARs.
+	if responseFormat == ndjsonResponseFormat {
+		logger.Debugw("serving ndjson stream", "path", contentPath)
+		success = i.serveNDJSON(r.Context(), w, r, rq)
+		return
+	}
+
+	// Forced _redirects rules apply even when the requested path exists, so they
+	// need to be evaluated before anything else is resolved.
+	if isWebRequest(responseFormat) && i.config.ForcedRedirects && hasOriginIsolation(r) {
+		newContentPath, ok, hadMatchingRule := i.serveRedirectsIfPresent(w, r, rq.immutablePath, rq.immutablePath, contentPath, true, logger)
+		if hadMatchingRule {
+			logger.Debugw("applied a forced rule from _redirects file")
+			if !ok {
+				return
+			}
+			rq.immutablePath = newContentPath
+		}
+	}
+
 	// Detect when If-None-Match HTTP header allows returning HTTP 304 Not Modified.
 	if i.handleIfNoneMatch(w, r, rq) {
 		return
@@ -311,6 +372,9 @@ func (i *handler) getOrHeadHandler(w http.ResponseWriter, r *http.Request) {
 	case dagJsonResponseFormat, dagCborResponseFormat:
 		logger.Debugw("serving codec", "path", contentPath)
 		success = i.serveCodec(r.Context(), w, r, rq)
+	case dagStatsResponseFormat:
+		logger.Debugw("serving dag stats", "path", contentPath)
+		success = i.serveDAGStats(r.Context(), w, r, rq)
 	default: // catch-all for unsuported application/vnd.*
 		err := fmt.Errorf("unsupported format %q", responseFormat)
 		i.webError(w, r, err, http.StatusBadRequest)
@@ -336,7 +400,7 @@ func (i *handler) isDeserializedResponsePossible(r *http.Request) bool {
 	}
 
 	// If the gateway is defined, return whatever is set.
-	if gw, ok := i.config.PublicGateways[host]; ok {
+	if gw, ok := i.hosts.isKnownHostname(host); ok {
 		return gw.DeserializedResponses
 	}
 
@@ -397,7 +461,7 @@ func addCacheControlHeaders(w http.ResponseWriter, r *http.Request, contentPath
 	// Best effort attempt to set an Etag based on the CID and response format.
 	// Setting an ETag is handled separately for CARs and IPNS records.
 	if etag := getEtag(r, cid, responseFormat); etag != "" {
-		w.Header().Set("Etag", etag)
+		w.Header().Set("Etag", weakEtagIfMutable(contentPath, etag))
 	}
 
 	// Set Cache-Control and Last-Modified based on contentPath properties
@@ -467,42 +531,14 @@ func setContentDispositionHeader(w http.ResponseWriter, filename string, disposi
 }
 
 // setIpfsRootsHeader sets the X-Ipfs-Roots header with logical CID array for
-// efficient HTTP cache invalidation.
+// efficient HTTP cache invalidation, see [SetProvenanceHeaders].
 func setIpfsRootsHeader(w http.ResponseWriter, rq *requestData, md *ContentPathMetadata) {
 	// Update requestData with the latest ContentPathMetadata if it wasn't set yet.
 	if rq.pathMetadata == nil {
 		rq.pathMetadata = md
 	}
 
-	// These are logical roots where each CID represent one path segment
-	// and resolves to either a directory or the root block of a file.
-	// The main purpose of this header is allow HTTP caches to do smarter decisions
-	// around cache invalidation (eg. keep specific subdirectory/file if it did not change)
-
-	// A good example is Wikipedia, which is HAMT-sharded, but we only care about
-	// logical roots that represent each segment of the human-readable content
-	// path:
-
-	// Given contentPath = /ipns/en.wikipedia-on-ipfs.org/wiki/Block_of_Wikipedia_in_Turkey
-	// rootCidList is a generated by doing `ipfs resolve -r` on each sub path:
-	// 	/ipns/en.wikipedia-on-ipfs.org → bafybeiaysi4s6lnjev27ln5icwm6tueaw2vdykrtjkwiphwekaywqhcjze
-	// 	/ipns/en.wikipedia-on-ipfs.org/wiki/ → bafybeihn2f7lhumh4grizksi2fl233cyszqadkn424ptjajfenykpsaiw4
-	// 	/ipns/en.wikipedia-on-ipfs.org/wiki/Block_of_Wikipedia_in_Turkey → bafkreibn6euazfvoghepcm4efzqx5l3hieof2frhp254hio5y7n3hv5rma
-
-	// The result is an ordered array of values:
-	// 	X-Ipfs-Roots: bafybeiaysi4s6lnjev27ln5icwm6tueaw2vdykrtjkwiphwekaywqhcjze,bafybeihn2f7lhumh4grizksi2fl233cyszqadkn424ptjajfenykpsaiw4,bafkreibn6euazfvoghepcm4efzqx5l3hieof2frhp254hio5y7n3hv5rma
-
-	// Note that while the top one will change every time any article is changed,
-	// the last root (responsible for specific article) may not change at all.
-
-	var pathRoots []string
-	for _, c := range rq.pathMetadata.PathSegmentRoots {
-		pathRoots = append(pathRoots, c.String())
-	}
-	pathRoots = append(pathRoots, rq.pathMetadata.LastSegment.RootCid().String())
-	rootCidList := strings.Join(pathRoots, ",") // convention from rfc2616#sec4.2
-
-	w.Header().Set("X-Ipfs-Roots", rootCidList)
+	SetProvenanceHeaders(w.Header(), rq.contentPath, rq.immutablePath, rq.pathMetadata)
 }
 
 // etagMatch evaluates if we can respond with HTTP 304 Not Modified
@@ -556,10 +592,10 @@ func getEtag(r *http.Request, cid cid.Cid, responseFormat string) string {
 	switch responseFormat {
 	case "":
 		// Do nothing.
-	case carResponseFormat, ipnsRecordResponseFormat:
-		// CARs and IPNS Record ETags are handled differently, in their respective handler.
+	case carResponseFormat, ndjsonResponseFormat, ipnsRecordResponseFormat:
+		// CARs, NDJSON and IPNS Record ETags are handled differently, in their respective handler.
 		return ""
-	case tarResponseFormat:
+	case tarResponseFormat, dagStatsResponseFormat:
 		// Weak Etag W/ for formats that we can't guarantee byte-for-byte identical
 		// responses, but still want to benefit from HTTP Caching.
 		prefix = "W/" + prefix
@@ -572,9 +608,26 @@ func getEtag(r *http.Request, cid cid.Cid, responseFormat string) string {
 		suffix = `.` + shortFormat + suffix
 	}
 
+	// Selected fields of documents: "cid.shortFmt.hash"
+	if sel := r.URL.Query().Get(codecSelectKey); sel != "" {
+		suffix = strings.TrimSuffix(suffix, `"`) + `.` + strconv.FormatUint(xxhash.Sum64String(sel), 32) + `"`
+	}
+
 	return prefix + cid.String() + suffix
 }
 
+// weakEtagIfMutable returns etag as a weak validator when contentPath is
+// mutable. The content behind an /ipns/ path changes when the name is updated,
+// so their responses only get weak Etags, derived from the resolved CID, while
+// the TTL of the name tells how long they are fresh (see
+// addCacheControlHeaders).
+func weakEtagIfMutable(contentPath path.Path, etag string) string {
+	if etag == "" || !contentPath.Mutable() || strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return "W/" + etag
+}
+
 const (
 	rawResponseFormat        = "application/vnd.ipld.raw"
 	carResponseFormat        = "application/vnd.ipld.car"
@@ -584,6 +637,8 @@ const (
 	dagJsonResponseFormat    = "application/vnd.ipld.dag-json"
 	dagCborResponseFormat    = "application/vnd.ipld.dag-cbor"
 	ipnsRecordResponseFormat = "application/vnd.ipfs.ipns-record"
+	ndjsonResponseFormat     = "application/x-ndjson"
+	dagStatsResponseFormat   = "application/vnd.ipfs.dag-stats"
 )
 
 // return explicit response format if specified in request as query parameter or via Accept HTTP header
@@ -602,6 +657,7 @@ func customResponseFormat(r *http.Request) (mediaType string, params map[string]
 			if strings.HasPrefix(accept, "application/vnd.ipld") ||
 				strings.HasPrefix(accept, "application/vnd.ipfs") ||
 				strings.HasPrefix(accept, tarResponseFormat) ||
+				strings.HasPrefix(accept, ndjsonResponseFormat) ||
 				strings.HasPrefix(accept, jsonResponseFormat) ||
 				strings.HasPrefix(accept, cborResponseFormat) {
 				mediatype, params, err := mime.ParseMediaType(accept)
@@ -632,6 +688,10 @@ func customResponseFormat(r *http.Request) (mediaType string, params map[string]
 			return dagCborResponseFormat, nil, nil
 		case "ipns-record":
 			return ipnsRecordResponseFormat, nil, nil
+		case "ndjson":
+			return ndjsonResponseFormat, nil, nil
+		case "dag-stats":
+			return dagStatsResponseFormat, nil, nil
 		}
 	}
 
@@ -677,8 +737,21 @@ func (i *handler) handleIfNoneMatch(w http.ResponseWriter, r *http.Request, rq *
 		dirEtag := getDirListingEtag(pathCid)
 		dagEtag := getDagIndexEtag(pathCid)
 
-		if etagMatch(ifNoneMatch, cidEtag, dirEtag, dagEtag) {
-			// Finish early if client already has a matching Etag
+		for _, etag := range []string{cidEtag, dirEtag, dagEtag} {
+			if etag == "" || !etagMatch(ifNoneMatch, etag) {
+				continue
+			}
+
+			// Finish early if client already has a matching Etag. The 304
+			// response carries the same caching headers as the full response
+			// would, so that clients can refresh their cached copy.
+			if etag == cidEtag {
+				addCacheControlHeaders(w, r, rq.contentPath, rq.ttl, rq.lastMod, pathCid, rq.responseFormat)
+			} else if rq.ttl > 0 {
+				// generated HTML listings only get the TTL, see serveDirectory
+				w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(rq.ttl.Seconds())))
+			}
+			w.Header().Set("Etag", weakEtagIfMutable(rq.contentPath, etag))
 			w.WriteHeader(http.StatusNotModified)
 			return true
 		}
@@ -739,7 +812,7 @@ func (i *handler) handleWebRequestErrors(w http.ResponseWriter, r *http.Request,
 	// we can leverage the presence of an _redirects file and apply rules defined there.
 	// See: https://github.com/ipfs/specs/pull/290
 	if hasOriginIsolation(r) {
-		newContentPath, ok, hadMatchingRule := i.serveRedirectsIfPresent(w, r, maybeResolvedImPath, immutableContentPath, contentPath, logger)
+		newContentPath, ok, hadMatchingRule := i.serveRedirectsIfPresent(w, r, maybeResolvedImPath, immutableContentPath, contentPath, false, logger)
 		if hadMatchingRule {
 			logger.Debugw("applied a rule from _redirects file")
 			return newContentPath, ok
@@ -5,7 +5,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -61,8 +60,13 @@ func (i *handler) serveCAR(ctx context.Context, w http.ResponseWriter, r *http.R
 	// Set Cache-Control (same logic as for a regular files)
 	addCacheControlHeaders(w, r, rq.contentPath, rq.ttl, rq.lastMod, rootCid, carResponseFormat)
 
-	// Generate the CAR Etag.
+	// Generate the CAR Etag. It is only strong if the backend guarantees
+	// the response to be byte-for-byte deterministic.
 	etag := getCarEtag(rq.immutablePath, params, rootCid)
+	if withDeterministicCAR, ok := i.backend.(WithDeterministicCAR); ok && withDeterministicCAR.IsDeterministicCAR(params) {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+	etag = weakEtagIfMutable(rq.contentPath, etag)
 	w.Header().Set("Etag", etag)
 
 	// Terminate early if Etag matches. We cannot rely on handleIfNoneMatch since
@@ -87,13 +91,18 @@ func (i *handler) serveCAR(ctx context.Context, w http.ResponseWriter, r *http.R
 	w.Header().Set("Content-Type", buildContentTypeFromCarParams(params))
 	w.Header().Set("X-Content-Type-Options", "nosniff") // no funny business in the browsers :^)
 
-	_, copyErr := io.Copy(w, carFile)
+	limiter := i.newExportLimiter(w)
+	copyErr := limiter.copyCAR(w, carFile)
 	carErr := carFile.Close()
 	streamErr := multierr.Combine(carErr, copyErr)
 	if streamErr != nil {
 		// Update fail metric
 		i.carStreamFailMetric.WithLabelValues(rq.contentPath.Namespace()).Observe(time.Since(rq.begin).Seconds())
 
+		if i.handleExportLimit(w, r, limiter, copyErr) {
+			return false
+		}
+
 		// We return error as a trailer, however it is not something browsers can access
 		// (https://github.com/mdn/browser-compat-data/issues/14703)
 		// Due to this, we suggest client always verify that
@@ -226,6 +235,12 @@ func getCarRootCidAndLastSegment(imPath path.ImmutablePath) (cid.Cid, string, er
 }
 
 func getCarEtag(imPath path.ImmutablePath, params CarParams, rootCid cid.Cid) string {
+	return getDagStreamEtag(imPath, params, rootCid, "car")
+}
+
+// getDagStreamEtag returns a weak Etag for a stream of the blocks selected by
+// params, in the given format.
+func getDagStreamEtag(imPath path.ImmutablePath, params CarParams, rootCid cid.Cid, format string) string {
 	h := xxhash.New()
 	h.WriteString(imPath.String())
 	// be careful with hashes here, we need boundaries and per entry salt, we don't want a request that has:
@@ -264,5 +279,5 @@ func getCarEtag(imPath path.ImmutablePath, params CarParams, rootCid cid.Cid) st
 	}
 
 	suffix := strconv.FormatUint(h.Sum64(), 32)
-	return `W/"` + rootCid.String() + ".car." + suffix + `"`
+	return `W/"` + rootCid.String() + "." + format + "." + suffix + `"`
 }
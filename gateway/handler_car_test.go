@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/ipfs/boxo/path"
@@ -194,3 +195,37 @@ func TestGetCarEtag(t *testing.T) {
 		require.NotEqual(t, a, b)
 	})
 }
+
+type deterministicCARBackend struct {
+	*mockBackend
+}
+
+func (mb *deterministicCARBackend) IsDeterministicCAR(params CarParams) bool {
+	return params.Deterministic()
+}
+
+func TestDeterministicCarEtag(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	ts := newTestServer(t, &deterministicCARBackend{backend})
+
+	test := func(accept string, weak bool) {
+		t.Run(accept, func(t *testing.T) {
+			req := mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String(), nil)
+			req.Header.Add("Accept", accept)
+			res := mustDoWithoutRedirect(t, req)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			etag := res.Header.Get("Etag")
+			require.NotEmpty(t, etag)
+			require.Equal(t, weak, strings.HasPrefix(etag, "W/"))
+		})
+	}
+
+	test(carResponseFormat, false)
+	test(carResponseFormat+"; order=dfs; dups=n", false)
+	test(carResponseFormat+"; dups=y", true)
+	test(carResponseFormat+"; order=unk", true)
+}
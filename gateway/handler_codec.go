@@ -13,6 +13,7 @@ import (
 	"github.com/ipfs/boxo/gateway/assets"
 	"github.com/ipfs/boxo/path"
 	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/datamodel"
 	"github.com/ipld/go-ipld-prime/multicodec"
 	"github.com/ipld/go-ipld-prime/node/basicnode"
 	mc "github.com/multiformats/go-multicodec"
@@ -35,6 +36,10 @@ var codecToContentType = map[mc.Code]string{
 	mc.DagCbor: dagCborResponseFormat,
 }
 
+// codecSelectKey is the URL query parameter selecting a field of DAG-JSON and
+// DAG-CBOR documents, see [selectCodecNode].
+const codecSelectKey = "select"
+
 // contentTypeToRaw maps the HTTP Content Type to the respective codec that
 // allows raw response without any conversion.
 var contentTypeToRaw = map[string][]mc.Code{
@@ -116,6 +121,10 @@ func (i *handler) renderCodec(ctx context.Context, w http.ResponseWriter, r *htt
 	w.Header().Set("Content-Type", responseContentType)
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 
+	// A selected field is served in the requested codec, or in the codec of
+	// the block, like the whole document would be.
+	sel := r.URL.Query().Get(codecSelectKey)
+
 	// No content type is specified by the user (via Accept, or format=). However,
 	// we support this format. Let's handle it.
 	if rq.responseFormat == "" {
@@ -123,8 +132,10 @@ func (i *handler) renderCodec(ctx context.Context, w http.ResponseWriter, r *htt
 		acceptsHTML := strings.Contains(r.Header.Get("Accept"), "text/html")
 		download := r.URL.Query().Get("download") == "true"
 
-		if isDAG && acceptsHTML && !download {
+		if isDAG && acceptsHTML && !download && sel == "" {
 			return i.serveCodecHTML(ctx, w, r, blockCid, blockData, resolvedPath, rq.contentPath)
+		} else if sel != "" {
+			return i.serveCodecConverted(ctx, w, r, blockCid, blockData, rq.contentPath, cidCodec, sel, modtime, rq.begin)
 		} else {
 			// This covers CIDs with codec 'json' and 'cbor' as those do not have
 			// an explicit requested content type.
@@ -138,6 +149,9 @@ func (i *handler) renderCodec(ctx context.Context, w http.ResponseWriter, r *htt
 	if ok {
 		for _, skipCodec := range skipCodecs {
 			if skipCodec == cidCodec {
+				if sel != "" {
+					return i.serveCodecConverted(ctx, w, r, blockCid, blockData, rq.contentPath, cidCodec, sel, modtime, rq.begin)
+				}
 				return i.serveCodecRaw(ctx, w, r, blockSize, blockData, rq.contentPath, modtime, rq.begin)
 			}
 		}
@@ -153,7 +167,7 @@ func (i *handler) renderCodec(ctx context.Context, w http.ResponseWriter, r *htt
 	}
 
 	// This handles DAG-* conversions and validations.
-	return i.serveCodecConverted(ctx, w, r, blockCid, blockData, rq.contentPath, toCodec, modtime, rq.begin)
+	return i.serveCodecConverted(ctx, w, r, blockCid, blockData, rq.contentPath, toCodec, sel, modtime, rq.begin)
 }
 
 func (i *handler) serveCodecHTML(ctx context.Context, w http.ResponseWriter, r *http.Request, blockCid cid.Cid, blockData io.Reader, resolvedPath path.ImmutablePath, contentPath path.Path) bool {
@@ -187,7 +201,7 @@ func (i *handler) serveCodecHTML(ctx context.Context, w http.ResponseWriter, r *
 
 	// Generated index requires custom Etag (output may change between Kubo versions)
 	dagEtag := getDagIndexEtag(resolvedPath.RootCid())
-	w.Header().Set("Etag", dagEtag)
+	w.Header().Set("Etag", weakEtagIfMutable(contentPath, dagEtag))
 
 	// Remove Cache-Control for now to match UnixFS dir-index-html responses
 	// (we don't want browser to cache HTML forever)
@@ -252,8 +266,9 @@ func (i *handler) serveCodecRaw(ctx context.Context, w http.ResponseWriter, r *h
 	return dataSent
 }
 
-// serveCodecConverted returns payload converted to codec specified in toCodec
-func (i *handler) serveCodecConverted(ctx context.Context, w http.ResponseWriter, r *http.Request, blockCid cid.Cid, blockData io.ReadCloser, contentPath path.Path, toCodec mc.Code, modtime, begin time.Time) bool {
+// serveCodecConverted returns payload converted to codec specified in toCodec,
+// or only its field selected by sel if not empty.
+func (i *handler) serveCodecConverted(ctx context.Context, w http.ResponseWriter, r *http.Request, blockCid cid.Cid, blockData io.ReadCloser, contentPath path.Path, toCodec mc.Code, sel string, modtime, begin time.Time) bool {
 	codec := blockCid.Prefix().Codec
 	decoder, err := multicodec.LookupDecoder(codec)
 	if err != nil {
@@ -268,6 +283,16 @@ func (i *handler) serveCodecConverted(ctx context.Context, w http.ResponseWriter
 		return false
 	}
 
+	nd := node.Build()
+	if sel != "" {
+		nd, err = selectCodecNode(nd, sel)
+		if err != nil {
+			err = fmt.Errorf("cannot select %q of %s: %w", sel, blockCid, err)
+			i.webError(w, r, err, http.StatusNotFound)
+			return false
+		}
+	}
+
 	encoder, err := multicodec.LookupEncoder(uint64(toCodec))
 	if err != nil {
 		i.webError(w, r, err, http.StatusInternalServerError)
@@ -276,7 +301,7 @@ func (i *handler) serveCodecConverted(ctx context.Context, w http.ResponseWriter
 
 	// Ensure IPLD node conforms to the codec specification.
 	var buf bytes.Buffer
-	err = encoder(node.Build(), &buf)
+	err = encoder(nd, &buf)
 	if err != nil {
 		i.webError(w, r, err, http.StatusInternalServerError)
 		return false
@@ -298,6 +323,29 @@ func (i *handler) serveCodecConverted(ctx context.Context, w http.ResponseWriter
 	return false
 }
 
+// selectCodecNode returns the field of nd at the path sel, made of map keys
+// and list indexes separated by slashes, so that clients can fetch a branch
+// of large documents. The path cannot cross links, which are resolved with
+// the content path instead.
+func selectCodecNode(nd datamodel.Node, sel string) (datamodel.Node, error) {
+	var selected []string
+	for _, seg := range strings.Split(sel, "/") {
+		if seg == "" {
+			continue
+		}
+		if nd.Kind() == datamodel.Kind_Link {
+			return nil, NewErrorStatusCode(fmt.Errorf("%q is a link, select its fields with the content path instead", strings.Join(selected, "/")), http.StatusBadRequest)
+		}
+		next, err := nd.LookupBySegment(datamodel.ParsePathSegment(seg))
+		if err != nil {
+			return nil, err
+		}
+		nd = next
+		selected = append(selected, seg)
+	}
+	return nd, nil
+}
+
 func setCodecContentDisposition(w http.ResponseWriter, r *http.Request, resolvedPath path.ImmutablePath, contentType string) string {
 	var dispType, name string
 
@@ -1,13 +1,29 @@
 package gateway
 
 import (
+	"bytes"
 	"context"
 	"html"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
 	"github.com/ipfs/boxo/path"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/must"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multihash"
 	"github.com/stretchr/testify/require"
 )
 
@@ -80,3 +96,92 @@ func TestDagJsonCborPreview(t *testing.T) {
 		require.NotContains(t, string(body), script)
 	})
 }
+
+func TestCodecSelect(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	putDagJSON := func(t *testing.T, nd datamodel.Node) cid.Cid {
+		var buf bytes.Buffer
+		require.NoError(t, dagjson.Encode(nd, &buf))
+		c, err := cid.Prefix{Version: 1, Codec: cid.DagJSON, MhType: multihash.SHA2_256, MhLength: -1}.Sum(buf.Bytes())
+		require.NoError(t, err)
+		blk, err := blocks.NewBlockWithCid(buf.Bytes(), c)
+		require.NoError(t, err)
+		require.NoError(t, bstore.Put(ctx, blk))
+		return c
+	}
+	child := putDagJSON(t, basicnode.NewString("child"))
+	doc, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "a", qp.Map(1, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "b", qp.List(3, func(la datamodel.ListAssembler) {
+				qp.ListEntry(la, qp.Int(1))
+				qp.ListEntry(la, qp.Int(2))
+				qp.ListEntry(la, qp.Map(1, func(ma datamodel.MapAssembler) {
+					qp.MapEntry(ma, "c", qp.String("deep"))
+				}))
+			}))
+		}))
+		qp.MapEntry(ma, "link", qp.Link(cidlink.Link{Cid: child}))
+	})
+	require.NoError(t, err)
+	root := putDagJSON(t, doc)
+
+	backend, err := NewBlocksBackend(blockservice.New(bstore, nil))
+	require.NoError(t, err)
+	ts := newTestServer(t, backend)
+
+	get := func(t *testing.T, query string, header ...string) (*http.Response, string) {
+		req := mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+query, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		res := mustDoWithoutRedirect(t, req)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	res, _ := get(t, "?format=dag-json")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	docEtag := res.Header.Get("Etag")
+
+	res, body := get(t, "?format=dag-json&select=a/b/2")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, dagJsonResponseFormat, res.Header.Get("Content-Type"))
+	require.Equal(t, `{"c":"deep"}`, body)
+	require.NotEqual(t, docEtag, res.Header.Get("Etag"))
+	require.Regexp(t, `^"`+root.String()+`\.dag-json\.[0-9a-v]+"$`, res.Header.Get("Etag"))
+	selEtag := res.Header.Get("Etag")
+
+	// the Etag of the whole document does not match the selected field
+	res, _ = get(t, "?format=dag-json&select=a/b/2", "If-None-Match", docEtag)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res, _ = get(t, "?format=dag-json&select=a/b/2", "If-None-Match", selEtag)
+	require.Equal(t, http.StatusNotModified, res.StatusCode)
+
+	// fields are served in the codec of the block by default
+	res, body = get(t, "?select=/a/b/0/")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "1", body)
+	fieldEtag := res.Header.Get("Etag")
+	require.Regexp(t, `^"`+root.String()+`(\.[a-z-]+)?\.[0-9a-v]+"$`, fieldEtag)
+	res, _ = get(t, "")
+	require.NotEqual(t, res.Header.Get("Etag"), fieldEtag)
+
+	res, body = get(t, "?format=dag-cbor&select=a/b/2/c")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	nb := basicnode.Prototype.Any.NewBuilder()
+	require.NoError(t, dagcbor.Decode(nb, strings.NewReader(body)))
+	require.Equal(t, "deep", must.String(nb.Build()))
+
+	res, _ = get(t, "?format=dag-json&select=a/missing")
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	res, _ = get(t, "?format=dag-json&select=a/b/3")
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	res, body = get(t, "?format=dag-json&select=link/x")
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	require.Contains(t, body, "select its fields with the content path instead")
+}
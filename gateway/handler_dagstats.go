@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-car/v2"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/ipld/go-ipld-prime/traversal"
+	mc "github.com/multiformats/go-multicodec"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultDAGStatsBlockBudget is the default maximum number of blocks traversed
+// to compute a DAG stats response, see [Config.DAGStatsBlockBudget].
+const DefaultDAGStatsBlockBudget = 10000
+
+// dagStats is the body of application/vnd.ipfs.dag-stats responses.
+type dagStats struct {
+	// Cid is the root of the DAG, the CID the content path resolves to.
+	Cid string `json:"cid"`
+	// TotalSize is the sum of the sizes of the blocks of the DAG.
+	TotalSize uint64 `json:"totalSize"`
+	// Blocks is the number of unique blocks of the DAG.
+	Blocks uint64 `json:"blocks"`
+	// MaxDepth is the length of the longest chain of links from the root
+	// found by the traversal, zero for a DAG made of a single block.
+	MaxDepth uint64 `json:"maxDepth"`
+	// Codecs is the number of blocks per codec name.
+	Codecs map[string]uint64 `json:"codecs"`
+	// Complete is false when the traversal stopped at the block budget of
+	// the gateway, the stats then cover the blocks traversed so far.
+	Complete bool `json:"complete"`
+}
+
+// serveDAGStats returns the aggregate stats of the DAG the content path
+// resolves to, computed from the CAR of the whole DAG returned by the backend,
+// up to the block budget of the gateway.
+func (i *handler) serveDAGStats(ctx context.Context, w http.ResponseWriter, r *http.Request, rq *requestData) bool {
+	ctx, span := spanTrace(ctx, "Handler.ServeDAGStats", trace.WithAttributes(attribute.String("path", rq.immutablePath.String())))
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pathMetadata := rq.pathMetadata
+	if pathMetadata == nil {
+		md, err := i.backend.ResolvePath(ctx, rq.immutablePath)
+		if !i.handleRequestErrors(w, r, rq.contentPath, err) {
+			return false
+		}
+		pathMetadata = &md
+	}
+	rootCid := pathMetadata.LastSegment.RootCid()
+
+	budget := i.config.DAGStatsBlockBudget
+	if budget <= 0 {
+		budget = DefaultDAGStatsBlockBudget
+	}
+
+	_, carFile, err := i.backend.GetCAR(ctx, path.FromCid(rootCid), CarParams{Scope: DagScopeAll, Order: DagOrderDFS, Duplicates: DuplicateBlocksExcluded})
+	if !i.handleRequestErrors(w, r, rq.contentPath, err) {
+		return false
+	}
+	defer carFile.Close()
+
+	stats, err := computeDAGStats(rootCid, carFile, budget)
+	if err != nil {
+		err = fmt.Errorf("failed to compute the stats of %s: %w", rootCid, err)
+		i.webError(w, r, err, http.StatusBadGateway)
+		return false
+	}
+
+	setIpfsRootsHeader(w, rq, pathMetadata)
+	addCacheControlHeaders(w, r, rq.contentPath, rq.ttl, rq.lastMod, rootCid, dagStatsResponseFormat)
+	w.Header().Set("Content-Type", dagStatsResponseFormat)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		return false
+	}
+
+	i.dagStatsGetMetric.WithLabelValues(rq.contentPath.Namespace()).Observe(time.Since(rq.begin).Seconds())
+	return true
+}
+
+// computeDAGStats reads the blocks of a CAR of the DAG under root, in
+// depth-first order, until the end of the CAR or budget blocks are read.
+// The depths are computed from the links of the blocks that can be decoded,
+// the others are counted as leaves.
+func computeDAGStats(root cid.Cid, carFile io.Reader, budget int) (*dagStats, error) {
+	stats := &dagStats{
+		Cid:      root.String(),
+		Codecs:   make(map[string]uint64),
+		Complete: true,
+	}
+
+	br, err := car.NewBlockReader(carFile)
+	if err != nil {
+		return nil, err
+	}
+
+	depths := map[cid.Cid]uint64{root: 0}
+	for {
+		blk, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			return stats, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stats.Blocks >= uint64(budget) {
+			stats.Complete = false
+			return stats, nil
+		}
+
+		stats.Blocks++
+		stats.TotalSize += uint64(len(blk.RawData()))
+		codec := blk.Cid().Prefix().Codec
+		stats.Codecs[mc.Code(codec).String()]++
+
+		depth := depths[blk.Cid()]
+		stats.MaxDepth = max(stats.MaxDepth, depth)
+
+		decoder, err := multicodec.LookupDecoder(codec)
+		if err != nil {
+			continue
+		}
+		nb := basicnode.Prototype.Any.NewBuilder()
+		if err := decoder(nb, bytes.NewReader(blk.RawData())); err != nil {
+			continue
+		}
+		links, err := traversal.SelectLinks(nb.Build())
+		if err != nil {
+			continue
+		}
+		for _, l := range links {
+			cl, ok := l.(cidlink.Link)
+			if !ok {
+				continue
+			}
+			if d, ok := depths[cl.Cid]; !ok || d < depth+1 {
+				depths[cl.Cid] = depth + 1
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ipld/go-car/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeDAGStats(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+
+	// the expected block count and size come from the CAR of the whole DAG
+	ts := newTestServer(t, backend)
+	res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+"?format=car", nil))
+	br, err := car.NewBlockReader(res.Body)
+	require.NoError(t, err)
+	var blocks, size uint64
+	for {
+		blk, err := br.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		blocks++
+		size += uint64(len(blk.RawData()))
+	}
+	res.Body.Close()
+
+	readStats := func(t *testing.T, ts string, accept, query string) (*http.Response, dagStats) {
+		req := mustNewRequest(t, http.MethodGet, ts+"/ipfs/"+root.String()+query, nil)
+		if accept != "" {
+			req.Header.Add("Accept", accept)
+		}
+		res := mustDoWithoutRedirect(t, req)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, dagStatsResponseFormat, res.Header.Get("Content-Type"))
+
+		var stats dagStats
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&stats))
+		return res, stats
+	}
+
+	for _, tc := range []struct{ accept, query string }{
+		{"", "?format=dag-stats"},
+		{dagStatsResponseFormat, ""},
+	} {
+		res, stats := readStats(t, ts.URL, tc.accept, tc.query)
+		require.Equal(t, `W/"`+root.String()+`.dag-stats"`, res.Header.Get("Etag"))
+		require.Equal(t, root.String(), stats.Cid)
+		require.Equal(t, blocks, stats.Blocks)
+		require.Equal(t, size, stats.TotalSize)
+		require.NotZero(t, stats.Codecs["dag-pb"])
+		var codecBlocks uint64
+		for _, n := range stats.Codecs {
+			codecBlocks += n
+		}
+		require.Equal(t, blocks, codecBlocks)
+		require.NotZero(t, stats.MaxDepth)
+		require.True(t, stats.Complete)
+	}
+
+	// the traversal stops at the block budget
+	ts = newTestServerWithConfig(t, backend, Config{DeserializedResponses: true, DAGStatsBlockBudget: 1})
+	_, stats := readStats(t, ts.URL, "", "?format=dag-stats")
+	require.Equal(t, uint64(1), stats.Blocks)
+	require.Zero(t, stats.MaxDepth)
+	require.False(t, stats.Complete)
+}
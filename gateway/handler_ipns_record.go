@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
@@ -58,9 +60,15 @@ func (i *handler) serveIpnsRecord(ctx context.Context, w http.ResponseWriter, r
 	// TTL is not present, we use the Last-Modified tag. We are tracking IPNS
 	// caching on: https://github.com/ipfs/kubo/issues/1818.
 	// TODO: use addCacheControlHeaders once #1818 is fixed.
-	recordEtag := strconv.FormatUint(xxhash.Sum64(rawRecord), 32)
+	recordEtag := `"` + strconv.FormatUint(xxhash.Sum64(rawRecord), 32) + `"`
 	w.Header().Set("Etag", recordEtag)
 
+	if ttl, err := record.TTL(); err == nil {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+	} else {
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	}
+
 	// Terminate early if Etag matches. We cannot rely on handleIfNoneMatch since
 	// we use the raw record to generate the etag value.
 	if etagMatch(r.Header.Get("If-None-Match"), recordEtag) {
@@ -68,12 +76,6 @@ func (i *handler) serveIpnsRecord(ctx context.Context, w http.ResponseWriter, r
 		return false
 	}
 
-	if ttl, err := record.TTL(); err == nil {
-		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
-	} else {
-		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
-	}
-
 	// Set Content-Disposition
 	var name string
 	if urlFilename := r.URL.Query().Get("filename"); urlFilename != "" {
@@ -95,3 +97,71 @@ func (i *handler) serveIpnsRecord(ctx context.Context, w http.ResponseWriter, r
 
 	return false
 }
+
+// putIpnsRecord publishes the signed IPNS record PUT to /ipns/{name}, once
+// validated against the name.
+func (i *handler) putIpnsRecord(w http.ResponseWriter, r *http.Request) {
+	begin := time.Now()
+	ctx, span := spanTrace(r.Context(), "Handler.PutIPNSRecord", trace.WithAttributes(attribute.String("path", r.URL.Path)))
+	defer span.End()
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/ipns/")
+	key = strings.TrimSuffix(key, "/")
+	if !ok || key == "" || strings.Contains(key, "/") {
+		i.webError(w, r, errors.New("IPNS records can only be published to /ipns/{name}"), http.StatusBadRequest)
+		return
+	}
+
+	name, err := ipns.NameFromString(key)
+	if err != nil {
+		i.webError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != ipnsRecordResponseFormat {
+		err := fmt.Errorf("IPNS records must be sent with the %s content type", ipnsRecordResponseFormat)
+		i.webError(w, r, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// read one more byte than allowed to tell apart records which are too
+	// large
+	rawRecord, err := io.ReadAll(io.LimitReader(r.Body, int64(ipns.MaxRecordSize)+1))
+	if err != nil {
+		i.webError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if len(rawRecord) > ipns.MaxRecordSize {
+		err := fmt.Errorf("IPNS record is larger than %d bytes", ipns.MaxRecordSize)
+		i.webError(w, r, err, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	record, err := ipns.UnmarshalRecord(rawRecord)
+	if err != nil {
+		i.webError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if err := ipns.ValidateWithName(record, name); err != nil {
+		i.webError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := putIPNSRecord(ctx, i.backend, name, rawRecord); err != nil {
+		i.webError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	i.ipnsRecordPutMetric.WithLabelValues(path.IPNSNamespace).Observe(time.Since(begin).Seconds())
+}
+
+// putIPNSRecord publishes the record with the backend, if it implements
+// [WithIPNSPublishing].
+func putIPNSRecord(ctx context.Context, backend IPFSBackend, name ipns.Name, record []byte) error {
+	publisher, ok := backend.(WithIPNSPublishing)
+	if !ok {
+		return NewErrorStatusCode(errors.New("IPNS Record publishing is not supported by this gateway"), http.StatusNotImplemented)
+	}
+	return publisher.PutIPNSRecord(ctx, name, record)
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	ic "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type publishingBackend struct {
+	*mockBackend
+	records map[string][]byte
+}
+
+func (mb *publishingBackend) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	mb.records[name.String()] = record
+	return nil
+}
+
+func newTestIPNSRecord(t *testing.T) (ipns.Name, []byte) {
+	t.Helper()
+	sk, _, err := ic.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	pid, err := peer.IDFromPrivateKey(sk)
+	require.NoError(t, err)
+
+	p, err := path.NewPath("/ipfs/bafkqaaa")
+	require.NoError(t, err)
+	rec, err := ipns.NewRecord(sk, p, 1, time.Now().Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	raw, err := ipns.MarshalRecord(rec)
+	require.NoError(t, err)
+	return ipns.NameFromPeer(pid), raw
+}
+
+func mustPutIPNSRecord(t *testing.T, url, contentType string, body []byte) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	res := mustDoWithoutRedirect(t, req)
+	res.Body.Close()
+	return res
+}
+
+func TestPutIPNSRecord(t *testing.T) {
+	t.Parallel()
+
+	mb, _ := newMockBackend(t, "fixtures.car")
+	backend := &publishingBackend{mockBackend: mb, records: map[string][]byte{}}
+	ts := newTestServerWithConfig(t, backend, Config{IPNSPublishing: true})
+
+	name, raw := newTestIPNSRecord(t)
+	otherName, _ := newTestIPNSRecord(t)
+
+	t.Run("Valid record is published", func(t *testing.T) {
+		res := mustPutIPNSRecord(t, ts.URL+"/ipns/"+name.String(), ipnsRecordResponseFormat, raw)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Equal(t, raw, backend.records[name.String()])
+	})
+
+	t.Run("Record of another name is refused", func(t *testing.T) {
+		res := mustPutIPNSRecord(t, ts.URL+"/ipns/"+otherName.String(), ipnsRecordResponseFormat, raw)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NotContains(t, backend.records, otherName.String())
+	})
+
+	t.Run("Invalid record is refused", func(t *testing.T) {
+		res := mustPutIPNSRecord(t, ts.URL+"/ipns/"+otherName.String(), ipnsRecordResponseFormat, []byte("not a record"))
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("Other content types are refused", func(t *testing.T) {
+		res := mustPutIPNSRecord(t, ts.URL+"/ipns/"+name.String(), "application/octet-stream", raw)
+		require.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+	})
+
+	t.Run("Subpaths are refused", func(t *testing.T) {
+		res := mustPutIPNSRecord(t, ts.URL+"/ipns/"+name.String()+"/sub", ipnsRecordResponseFormat, raw)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestPutIPNSRecordDisabled(t *testing.T) {
+	t.Parallel()
+
+	mb, _ := newMockBackend(t, "fixtures.car")
+	name, raw := newTestIPNSRecord(t)
+
+	// publishing is disabled by default
+	ts := newTestServer(t, &publishingBackend{mockBackend: mb, records: map[string][]byte{}})
+	res := mustPutIPNSRecord(t, ts.URL+"/ipns/"+name.String(), ipnsRecordResponseFormat, raw)
+	require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	// backends that cannot publish are not implemented
+	ts = newTestServerWithConfig(t, mb, Config{IPNSPublishing: true})
+	res = mustPutIPNSRecord(t, ts.URL+"/ipns/"+name.String(), ipnsRecordResponseFormat, raw)
+	require.Equal(t, http.StatusNotImplemented, res.StatusCode)
+}
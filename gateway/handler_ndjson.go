@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ipld/go-car/v2"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+)
+
+// serveNDJSON streams the blocks of a DAG traversal, as specified by the same
+// parameters as CAR requests (dag-scope and entity-bytes), in newline
+// delimited JSON. Each line is a dag-json object with the "cid" of a block and
+// its decoded "node", which allows clients to process huge DAGs incrementally.
+// Blocks are streamed in the order of the CAR returned by the backend.
+func (i *handler) serveNDJSON(ctx context.Context, w http.ResponseWriter, r *http.Request, rq *requestData) bool {
+	ctx, span := spanTrace(ctx, "Handler.ServeNDJSON", trace.WithAttributes(attribute.String("path", rq.immutablePath.String())))
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The stream does not depend on the CAR content type parameters, so only
+	// the implicit defaults are used.
+	params, err := buildCarParams(r, nil)
+	if err != nil {
+		i.webError(w, r, err, http.StatusBadRequest)
+		return false
+	}
+
+	rootCid, lastSegment, err := getCarRootCidAndLastSegment(rq.immutablePath)
+	if err != nil {
+		i.webError(w, r, err, http.StatusInternalServerError)
+		return false
+	}
+
+	// Set Content-Disposition
+	var name string
+	if urlFilename := r.URL.Query().Get("filename"); urlFilename != "" {
+		name = urlFilename
+	} else {
+		name = rootCid.String()
+		if lastSegment != "" {
+			name += "_" + lastSegment
+		}
+		name += ".ndjson"
+	}
+	setContentDispositionHeader(w, name, "inline")
+
+	addCacheControlHeaders(w, r, rq.contentPath, rq.ttl, rq.lastMod, rootCid, ndjsonResponseFormat)
+
+	// Blocks are re-encoded, so the Etag is always weak.
+	etag := getDagStreamEtag(rq.immutablePath, params, rootCid, "ndjson")
+	w.Header().Set("Etag", etag)
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	}
+
+	md, carFile, err := i.backend.GetCAR(ctx, rq.immutablePath, params)
+	if !i.handleRequestErrors(w, r, rq.contentPath, err) {
+		return false
+	}
+	defer carFile.Close()
+	setIpfsRootsHeader(w, rq, &md)
+
+	w.Header().Set("Accept-Ranges", "none")
+	w.Header().Set("Content-Type", ndjsonResponseFormat)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	limiter := i.newExportLimiter(w)
+	writeErr := writeNDJSONBlocks(w, carFile, limiter)
+	streamErr := multierr.Combine(writeErr, carFile.Close())
+	if streamErr != nil {
+		// Like for CARs, we return the error as a trailer. Clients should check
+		// that the last line they received is the last node they expected.
+		i.ndjsonStreamFailMetric.WithLabelValues(rq.contentPath.Namespace()).Observe(time.Since(rq.begin).Seconds())
+		if i.handleExportLimit(w, r, limiter, writeErr) {
+			return false
+		}
+		w.Header().Set("X-Stream-Error", streamErr.Error())
+		return false
+	}
+
+	i.ndjsonStreamGetMetric.WithLabelValues(rq.contentPath.Namespace()).Observe(time.Since(rq.begin).Seconds())
+	return true
+}
+
+// writeNDJSONBlocks writes each block of the CAR stream as a dag-json line.
+// Blocks with a codec that cannot be decoded are written as bytes. The stream
+// is cut at the last line within the limits.
+func writeNDJSONBlocks(w http.ResponseWriter, carFile io.Reader, limiter *exportLimiter) error {
+	br, err := car.NewBlockReader(carFile)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for {
+		blk, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var node datamodel.Node
+		if decoder, err := multicodec.LookupDecoder(blk.Cid().Prefix().Codec); err == nil {
+			nb := basicnode.Prototype.Any.NewBuilder()
+			if err := decoder(nb, bytes.NewReader(blk.RawData())); err != nil {
+				return fmt.Errorf("decoding block %s: %w", blk.Cid(), err)
+			}
+			node = nb.Build()
+		} else {
+			node = basicnode.NewBytes(blk.RawData())
+		}
+
+		line, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "cid", qp.Link(cidlink.Link{Cid: blk.Cid()}))
+			qp.MapEntry(ma, "node", qp.Node(node))
+		})
+		if err != nil {
+			return err
+		}
+		buf.Reset()
+		if err := dagjson.Encode(line, &buf); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		if err := limiter.add(int64(buf.Len()), 1); err != nil {
+			return err
+		}
+		if err := limiter.write(w, buf.Bytes()); err != nil {
+			return err
+		}
+
+		// Flush after each line, so that clients can process the nodes as soon
+		// as they are traversed.
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	return nil
+}
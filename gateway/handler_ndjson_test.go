@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-car/v2"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeNDJSON(t *testing.T) {
+	t.Parallel()
+
+	ts, _, root := newTestServerAndNode(t, nil, "fixtures.car")
+
+	readCarCids := func(t *testing.T, query string) []cid.Cid {
+		req := mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+"?format=car"+query, nil)
+		res := mustDoWithoutRedirect(t, req)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		br, err := car.NewBlockReader(res.Body)
+		require.NoError(t, err)
+		var cids []cid.Cid
+		for {
+			blk, err := br.Next()
+			if err == io.EOF {
+				return cids
+			}
+			require.NoError(t, err)
+			cids = append(cids, blk.Cid())
+		}
+	}
+
+	readNDJSONCids := func(t *testing.T, accept, query string) []cid.Cid {
+		req := mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+query, nil)
+		if accept != "" {
+			req.Header.Add("Accept", accept)
+		}
+		res := mustDoWithoutRedirect(t, req)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, ndjsonResponseFormat, res.Header.Get("Content-Type"))
+		require.True(t, strings.HasPrefix(res.Header.Get("Etag"), `W/"`+root.String()+".ndjson."))
+
+		var cids []cid.Cid
+		s := bufio.NewScanner(res.Body)
+		s.Buffer(nil, 4<<20)
+		for s.Scan() {
+			nb := basicnode.Prototype.Any.NewBuilder()
+			require.NoError(t, dagjson.Decode(nb, strings.NewReader(s.Text())))
+			line := nb.Build()
+			require.Equal(t, datamodel.Kind_Map, line.Kind())
+
+			n, err := line.LookupByString("cid")
+			require.NoError(t, err)
+			lnk, err := n.AsLink()
+			require.NoError(t, err)
+			cids = append(cids, lnk.(cidlink.Link).Cid)
+
+			_, err = line.LookupByString("node")
+			require.NoError(t, err)
+		}
+		require.NoError(t, s.Err())
+		return cids
+	}
+
+	t.Run("Entire DAG", func(t *testing.T) {
+		expected := readCarCids(t, "")
+		require.Greater(t, len(expected), 1)
+		require.Equal(t, expected, readNDJSONCids(t, ndjsonResponseFormat, ""))
+		require.Equal(t, expected, readNDJSONCids(t, "", "?format=ndjson"))
+	})
+
+	t.Run("Block scope", func(t *testing.T) {
+		cids := readNDJSONCids(t, "", "?format=ndjson&dag-scope=block")
+		require.Equal(t, []cid.Cid{root}, cids)
+	})
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/go-cid"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -43,7 +45,15 @@ func (i *handler) serveTAR(ctx context.Context, w http.ResponseWriter, r *http.R
 	setContentDispositionHeader(w, name, "attachment")
 
 	// Construct the TAR writer
-	tarw, err := files.NewTarWriter(w)
+	limiter := i.newExportLimiter(w)
+	var tarOpts []files.TarWriterOption
+	if i.config.TAR.Deterministic {
+		tarOpts = append(tarOpts, files.WithTarSortedEntries(), files.WithTarModTime(unixEpochTime))
+	}
+	if i.config.TAR.OmitMetadata {
+		tarOpts = append(tarOpts, files.WithTarOmitMetadata())
+	}
+	tarw, err := files.NewTarWriter(limiter.writer(w), tarOpts...)
 	if err != nil {
 		i.webError(w, r, fmt.Errorf("could not build tar writer: %w", err), http.StatusInternalServerError)
 		return false
@@ -60,11 +70,17 @@ func (i *handler) serveTAR(ctx context.Context, w http.ResponseWriter, r *http.R
 	w.Header().Set("Content-Type", tarResponseFormat)
 	w.Header().Set("X-Content-Type-Options", "nosniff") // no funny business in the browsers :^)
 
-	// The TAR has a top-level directory (or file) named by the CID.
-	if err := tarw.WriteFile(file, rootCid.String()); err != nil {
+	// The TAR has a top-level directory (or file) named by the CID, or
+	// following the configured policy.
+	if err := tarw.WriteFile(file, tarRootName(i.config.TAR.RootName, r, rq, rootCid)); err != nil {
 		// Update fail metric
 		i.tarStreamFailMetric.WithLabelValues(rq.contentPath.Namespace()).Observe(time.Since(rq.begin).Seconds())
 
+		// The TAR is cut at the byte limit, not at a file boundary.
+		if i.handleExportLimit(w, r, limiter, err) {
+			return false
+		}
+
 		w.Header().Set("X-Stream-Error", err.Error())
 		// Trailer headers do not work in web browsers
 		// (see https://github.com/mdn/browser-compat-data/issues/14703)
@@ -80,3 +96,22 @@ func (i *handler) serveTAR(ctx context.Context, w http.ResponseWriter, r *http.R
 	i.tarStreamGetMetric.WithLabelValues(rq.contentPath.Namespace()).Observe(time.Since(rq.begin).Seconds())
 	return true
 }
+
+// tarRootName returns the name of the top-level entry of the TAR following
+// the given policy.
+func tarRootName(policy TARRootName, r *http.Request, rq *requestData, rootCid cid.Cid) string {
+	var name string
+	switch policy {
+	case TARRootFilename:
+		name = strings.TrimSuffix(r.URL.Query().Get("filename"), ".tar")
+	case TARRootPathName:
+		if segments := rq.contentPath.Segments(); len(segments) > 2 {
+			name = segments[len(segments)-1]
+		}
+	}
+
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return rootCid.String()
+	}
+	return name
+}
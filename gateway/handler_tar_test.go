@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/ipld/unixfs/importer"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTARConfig(t *testing.T) {
+	t.Parallel()
+
+	bsrv := blockservice.New(blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore())), nil)
+	dir, err := importer.ImportTree(context.Background(), merkledag.NewDAGService(bsrv), files.NewMapDirectory(map[string]files.Node{
+		"sub": files.NewMapDirectory(map[string]files.Node{
+			"b.txt": files.NewBytesFile([]byte("b")),
+			"a.txt": files.NewBytesFile([]byte("a")),
+		}),
+	}))
+	require.NoError(t, err)
+	backend, err := NewBlocksBackend(bsrv)
+	require.NoError(t, err)
+	rootCid := dir.Root.Cid().String()
+
+	get := func(t *testing.T, config TARConfig, p string) []byte {
+		ts := newTestServerWithConfig(t, backend, Config{DeserializedResponses: true, TAR: config})
+		res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+p, nil))
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return body
+	}
+	names := func(t *testing.T, body []byte) []string {
+		var names []string
+		tr := tar.NewReader(bytes.NewReader(body))
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return names
+			}
+			require.NoError(t, err)
+			names = append(names, hdr.Name)
+		}
+	}
+
+	t.Run("Deterministic archives are reproducible", func(t *testing.T) {
+		t.Parallel()
+
+		config := TARConfig{Deterministic: true}
+		first := get(t, config, "/ipfs/"+rootCid+"?format=tar")
+		time.Sleep(time.Second)
+		require.Equal(t, first, get(t, config, "/ipfs/"+rootCid+"?format=tar"))
+		require.Equal(t, []string{rootCid, rootCid + "/sub", rootCid + "/sub/a.txt", rootCid + "/sub/b.txt"}, names(t, first))
+	})
+
+	t.Run("Root named after the filename", func(t *testing.T) {
+		t.Parallel()
+
+		body := get(t, TARConfig{RootName: TARRootFilename}, "/ipfs/"+rootCid+"?format=tar&filename=export.tar")
+		require.Equal(t, "export", names(t, body)[0])
+
+		// Unsafe names fall back to the CID.
+		body = get(t, TARConfig{RootName: TARRootFilename}, "/ipfs/"+rootCid+"?format=tar&filename=../x.tar")
+		require.Equal(t, rootCid, names(t, body)[0])
+	})
+
+	t.Run("Root named after the path", func(t *testing.T) {
+		t.Parallel()
+
+		body := get(t, TARConfig{RootName: TARRootPathName}, "/ipfs/"+rootCid+"/sub?format=tar")
+		require.Equal(t, []string{"sub", "sub/a.txt", "sub/b.txt"}, names(t, body))
+
+		body = get(t, TARConfig{RootName: TARRootPathName}, "/ipfs/"+rootCid+"?format=tar")
+		require.Equal(t, rootCid, names(t, body)[0])
+	})
+}
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/ipfs/boxo/path"
-	redirects "github.com/ipfs/go-ipfs-redirects-file"
 	"go.uber.org/zap"
 )
 
@@ -35,9 +34,13 @@ import (
 // In this case, we don't perform a redirect, but do need to return a `path.Resolved` and `path.Path` corresponding to
 // the rewrite destination path.
 //
+// Scenario 4:
+// If a path exists and [Config.ForcedRedirects] is enabled, forced rules (i.e. rules with a status suffixed
+// with "!") are evaluated before the path is resolved. In this case, exists is true and other rules are ignored.
+//
 // Note that for security reasons, redirect rules are only processed when the request has origin isolation.
 // See https://github.com/ipfs/specs/pull/290 for more information.
-func (i *handler) serveRedirectsIfPresent(w http.ResponseWriter, r *http.Request, maybeResolvedImPath, immutableContentPath path.ImmutablePath, contentPath path.Path, logger *zap.SugaredLogger) (newContentPath path.ImmutablePath, continueProcessing bool, hadMatchingRule bool) {
+func (i *handler) serveRedirectsIfPresent(w http.ResponseWriter, r *http.Request, maybeResolvedImPath, immutableContentPath path.ImmutablePath, contentPath path.Path, exists bool, logger *zap.SugaredLogger) (newContentPath path.ImmutablePath, continueProcessing bool, hadMatchingRule bool) {
 	// contentPath is the full ipfs path to the requested resource,
 	// regardless of whether path or subdomain resolution is used.
 	rootPath, err := getRootPath(immutableContentPath)
@@ -69,7 +72,7 @@ func (i *handler) serveRedirectsIfPresent(w http.ResponseWriter, r *http.Request
 	}
 
 	if foundRedirect {
-		redirected, newPath, err := i.handleRedirectsFileRules(w, r, immutableContentPath, contentPath, redirectRules, logger)
+		redirected, newPath, err := i.handleRedirectsFileRules(w, r, immutableContentPath, contentPath, redirectRules, exists, logger)
 		if err != nil {
 			err = fmt.Errorf("trouble processing _redirects file at %q: %w", redirectsPath, err)
 			i.webError(w, r, err, http.StatusInternalServerError)
@@ -103,22 +106,15 @@ func (i *handler) serveRedirectsIfPresent(w http.ResponseWriter, r *http.Request
 	return maybeResolvedImPath, true, false
 }
 
-func (i *handler) handleRedirectsFileRules(w http.ResponseWriter, r *http.Request, immutableContentPath path.ImmutablePath, cPath path.Path, redirectRules []redirects.Rule, logger *zap.SugaredLogger) (redirected bool, newContentPath string, err error) {
+func (i *handler) handleRedirectsFileRules(w http.ResponseWriter, r *http.Request, immutableContentPath path.ImmutablePath, cPath path.Path, redirectRules []RedirectRule, exists bool, logger *zap.SugaredLogger) (redirected bool, newContentPath string, err error) {
 	// Attempt to match a rule to the URL path, and perform the corresponding redirect or rewrite
 	pathParts := strings.Split(immutableContentPath.String(), "/")
 	if len(pathParts) > 3 {
 		// All paths should start with /ipfs/cid/, so get the path after that
 		urlPath := "/" + strings.Join(pathParts[3:], "/")
 		rootPath := strings.Join(pathParts[:3], "/")
-		// Trim off the trailing /
-		urlPath = strings.TrimSuffix(urlPath, "/")
-
-		for _, rule := range redirectRules {
-			// Error right away if the rule is invalid
-			if !rule.MatchAndExpandPlaceholders(urlPath) {
-				continue
-			}
 
+		if rule, ok := EvaluateRedirects(redirectRules, requestHost(r), urlPath, exists); ok {
 			// We have a match!
 
 			// Rewrite
@@ -174,7 +170,7 @@ func (i *handler) handleRedirectsFileRules(w http.ResponseWriter, r *http.Reques
 // getRedirectRules fetches the _redirects file corresponding to a given path and returns the rules
 // Returns whether _redirects was found, the rules (if they exist) and if there was an error (other than a missing _redirects)
 // If there is an error returns (false, nil, err)
-func (i *handler) getRedirectRules(r *http.Request, redirectsPath path.ImmutablePath) (bool, []redirects.Rule, error) {
+func (i *handler) getRedirectRules(r *http.Request, redirectsPath path.ImmutablePath) (bool, []RedirectRule, error) {
 	// Check for _redirects file.
 	// Any path resolution failures are ignored and we just assume there's no _redirects file.
 	// Note that ignoring these errors also ensures that the use of the empty CID (bafkqaaa) in tests doesn't fail.
@@ -193,7 +189,7 @@ func (i *handler) getRedirectRules(r *http.Request, redirectsPath path.Immutable
 	f := redirectsFileGetResp.bytes
 
 	// Parse redirect rules from file
-	redirectRules, err := redirects.Parse(f)
+	redirectRules, err := ParseRedirects(f)
 	if err != nil {
 		return false, nil, fmt.Errorf("could not parse _redirects: %w", err)
 	}
@@ -231,6 +227,15 @@ func (i *handler) serve4xx(w http.ResponseWriter, r *http.Request, content4xxPat
 	return err
 }
 
+// requestHost returns the hostname the request was made for, without port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if xHost := r.Header.Get("X-Forwarded-Host"); xHost != "" {
+		host = xHost
+	}
+	return stripPort(host)
+}
+
 func hasOriginIsolation(r *http.Request) bool {
 	_, subdomainGw := r.Context().Value(SubdomainHostnameKey).(string)
 	_, dnslink := r.Context().Value(DNSLinkHostnameKey).(string)
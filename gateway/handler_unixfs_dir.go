@@ -113,6 +113,9 @@ func (i *handler) serveDirectory(ctx context.Context, w http.ResponseWriter, r *
 
 	if err == nil {
 		rq.logger.Debugw("serving index.html file", "path", idxPath)
+		if i.config.EarlyHints && !isHeadRequest && len(ranges) == 0 && directoryMetadata != nil {
+			i.sendEarlyHints(w, r, directoryMetadata.entries)
+		}
 		originalContentPath := rq.contentPath
 		rq.contentPath = idxPath
 		// write to request
@@ -136,7 +139,7 @@ func (i *handler) serveDirectory(ctx context.Context, w http.ResponseWriter, r *
 
 	// Generated dir index requires custom Etag (output may change between go-libipfs versions)
 	dirEtag := getDirListingEtag(resolvedPath.RootCid())
-	w.Header().Set("Etag", dirEtag)
+	w.Header().Set("Etag", weakEtagIfMutable(rq.contentPath, dirEtag))
 
 	// Add TTL if known.
 	if rq.ttl > 0 {
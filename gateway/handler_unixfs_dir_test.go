@@ -4,6 +4,8 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"testing"
 
 	"github.com/ipfs/boxo/path"
@@ -88,3 +90,66 @@ func TestIPNSHostnameBacklinks(t *testing.T) {
 	require.Contains(t, s, "<a href=\"/foo%3F%20%23%3C%27/bar/file.txt\">", "expected file in directory listing")
 	require.Contains(t, s, k3.RootCid().String(), "expected hash in directory listing")
 }
+
+func TestEarlyHints(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newRedirectsTestBackend(t, "", map[string]string{
+		"index.html":      "<html></html>\n",
+		"style.css":       "body {}\n",
+		"app.js":          "\n",
+		"font name.woff2": "\n",
+		"image.png":       "\n",
+	})
+
+	get := func(t *testing.T, config Config, header http.Header) (*http.Response, []textproto.MIMEHeader) {
+		ts := newTestServerWithConfig(t, backend, config)
+		var hints []textproto.MIMEHeader
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+				require.Equal(t, http.StatusEarlyHints, code)
+				hints = append(hints, header)
+				return nil
+			},
+		}
+		req := mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+"/", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		res := mustDoWithoutRedirect(t, req)
+		_, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return res, hints
+	}
+
+	expected := []string{
+		"<./app.js>; rel=preload; as=script",
+		"<./font%20name.woff2>; rel=preload; as=font; crossorigin",
+		"<./style.css>; rel=preload; as=style",
+	}
+
+	res, hints := get(t, Config{DeserializedResponses: true, EarlyHints: true}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, hints, 1)
+	require.ElementsMatch(t, expected, hints[0].Values("Link"))
+	require.ElementsMatch(t, expected, res.Header.Values("Link"))
+
+	// no hints for range requests, or when disabled
+	res, hints = get(t, Config{DeserializedResponses: true, EarlyHints: true}, http.Header{"Range": {"bytes=1-"}})
+	require.Equal(t, http.StatusPartialContent, res.StatusCode)
+	require.Empty(t, hints)
+
+	res, hints = get(t, Config{DeserializedResponses: true}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Empty(t, hints)
+	require.Empty(t, res.Header.Values("Link"))
+
+	// the timings are sent with the final response, not the hints
+	res, hints = get(t, Config{DeserializedResponses: true, EarlyHints: true, ServerTiming: true}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, hints, 1)
+	require.Empty(t, hints[0].Get("Server-Timing"))
+	require.NotEmpty(t, res.Header.Get("Server-Timing"))
+}
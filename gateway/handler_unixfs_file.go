@@ -58,7 +58,15 @@ func (i *handler) serveFile(ctx context.Context, w http.ResponseWriter, r *http.
 			}
 
 			ctype = mimeType.String()
-			content = io.MultiReader(&buf, fileBytes)
+			if seeker, ok := fileBytes.(io.Seeker); ok {
+				// keep the content seekable for multipart range responses
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					http.Error(w, "cannot seek content: "+err.Error(), http.StatusInternalServerError)
+					return false
+				}
+			} else {
+				content = io.MultiReader(&buf, fileBytes)
+			}
 		}
 		// Strip the encoding from the HTML Content-Type header and let the
 		// browser figure it out.
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	chunker "github.com/ipfs/boxo/chunker"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/ipld/unixfs/importer"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+// countingBlockstore counts the blocks read from the blockstore.
+type countingBlockstore struct {
+	blockstore.Blockstore
+	gets atomic.Int64
+}
+
+func (bs *countingBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	bs.gets.Add(1)
+	return bs.Blockstore.Get(ctx, c)
+}
+
+func TestMultiRangeRequest(t *testing.T) {
+	t.Parallel()
+
+	bs := &countingBlockstore{Blockstore: blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))}
+	bsrv := blockservice.New(bs, offline.Exchange(bs))
+	dserv := merkledag.NewDAGService(bsrv)
+
+	// 100 blocks of 100 bytes
+	content := make([]byte, 10000)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	root, err := importer.BuildDagFromReader(dserv, chunker.NewSizeSplitter(bytes.NewReader(content), 100))
+	require.NoError(t, err)
+
+	backend, err := NewBlocksBackend(bsrv)
+	require.NoError(t, err)
+	ts := newTestServer(t, backend)
+
+	get := func(method, rangeHeader string) *http.Response {
+		req := mustNewRequest(t, method, ts.URL+"/ipfs/"+root.Cid().String(), nil)
+		req.Method = method
+		req.Header.Set("Range", rangeHeader)
+		return mustDoWithoutRedirect(t, req)
+	}
+
+	bs.gets.Store(0)
+	res := get(http.MethodGet, "bytes=10-19,5000-5009,-5")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusPartialContent, res.StatusCode)
+
+	mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/byteranges", mediaType)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, res.Header.Get("Content-Length"), strconv.Itoa(len(body)))
+
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for _, expected := range []struct {
+		contentRange string
+		data         []byte
+	}{
+		{"bytes 10-19/10000", content[10:20]},
+		{"bytes 5000-5009/10000", content[5000:5010]},
+		{"bytes 9995-9999/10000", content[9995:]},
+	} {
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		require.Equal(t, expected.contentRange, part.Header.Get("Content-Range"))
+		data, err := io.ReadAll(part)
+		require.NoError(t, err)
+		require.Equal(t, expected.data, data)
+	}
+	_, err = mr.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+
+	// Only the root and the blocks of the ranges, and those read ahead of
+	// them, were read, not all the 100 leaves
+	require.Less(t, bs.gets.Load(), int64(50))
+
+	// HEAD requests get the headers of the multipart response
+	head := get(http.MethodHead, "bytes=10-19,5000-5009,-5")
+	defer head.Body.Close()
+	require.Equal(t, http.StatusPartialContent, head.StatusCode)
+	require.True(t, strings.HasPrefix(head.Header.Get("Content-Type"), "multipart/byteranges; boundary="))
+	require.NotEmpty(t, head.Header.Get("Content-Length"))
+
+	// A single range is not sent as multipart
+	single := get(http.MethodGet, "bytes=10-19")
+	defer single.Body.Close()
+	require.Equal(t, http.StatusPartialContent, single.StatusCode)
+	require.Equal(t, "bytes 10-19/10000", single.Header.Get("Content-Range"))
+	data, err := io.ReadAll(single.Body)
+	require.NoError(t, err)
+	require.Equal(t, content[10:20], data)
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	gopath "path"
+	"strings"
+
+	"github.com/ipfs/boxo/ipld/unixfs"
+)
+
+const (
+	// maxEarlyHints is the maximum number of sub-resources hinted for a page.
+	maxEarlyHints = 16
+	// maxEarlyHintsEntries is the maximum number of directory entries read to
+	// find the sub-resources of a page. It is kept low as the entries are
+	// read before serving the page, and bounds the blocks fetched for HAMT
+	// directories.
+	maxEarlyHintsEntries = 64
+)
+
+// preloadLinks maps the extensions of the sub-resources hinted by
+// [Config.EarlyHints] to the parameters of their preload Link.
+var preloadLinks = map[string]string{
+	".css":   "rel=preload; as=style",
+	".js":    "rel=preload; as=script",
+	".mjs":   "rel=modulepreload",
+	".woff2": "rel=preload; as=font; crossorigin",
+	".woff":  "rel=preload; as=font; crossorigin",
+	".ttf":   "rel=preload; as=font; crossorigin",
+	".otf":   "rel=preload; as=font; crossorigin",
+}
+
+// sendEarlyHints adds preload Link headers for the stylesheets, scripts and
+// fonts among the directory entries, and sends them in a 103 Early Hints
+// response to clients that support it. The Link headers are also sent with
+// the final response.
+func (i *handler) sendEarlyHints(w http.ResponseWriter, r *http.Request, entries <-chan unixfs.LinkResult) {
+	var links []string
+	for n := 0; n < maxEarlyHintsEntries && len(links) < maxEarlyHints; n++ {
+		l, ok := <-entries
+		if !ok || l.Err != nil {
+			break
+		}
+		name := l.Link.Name
+		if params, ok := preloadLinks[strings.ToLower(gopath.Ext(name))]; ok {
+			links = append(links, "<./"+url.PathEscape(name)+">; "+params)
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+
+	for _, l := range links {
+		w.Header().Add("Link", l)
+	}
+	// Informational responses must not be sent to HTTP/1.0 clients.
+	if r.ProtoAtLeast(1, 1) {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+}
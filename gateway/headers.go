@@ -77,6 +77,7 @@ func (h *Headers) ApplyCors() *Headers {
 			"X-Stream-Output",
 			"X-Ipfs-Path",
 			"X-Ipfs-Roots",
+			"X-Content-Path",
 		}, h.headers[ACEHeadersName]...))
 
 	return h
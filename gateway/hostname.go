@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 
 	cid "github.com/ipfs/go-cid"
@@ -50,6 +51,15 @@ func NewHostnameHandler(c Config, backend IPFSBackend, next http.Handler) http.H
 
 		// HTTP Host & Path check: is this one of our  "known gateways"?
 		if gw, ok := gateways.isKnownHostname(host); ok {
+			// Is this hostname only used for DNSLink websites?
+			if gw.DNSLinkOnly {
+				// Handle as DNSLink, without looking up the record first: a
+				// missing record is reported by the gateway.
+				r.URL.Path = "/ipns/" + stripPort(host) + r.URL.Path
+				next.ServeHTTP(w, withDNSLinkContext(r, host))
+				return
+			}
+
 			// This is a known gateway but request is not using
 			// the subdomain feature.
 
@@ -309,6 +319,64 @@ func toDNSLabel(rootID string, rootCID cid.Cid) (dnsCID string, err error) {
 	return "", fmt.Errorf("CID incompatible with DNS label length limit of 63: %s", rootID)
 }
 
+// toSubdomainCIDLabel converts rootID to the DNS label used for it in the ns
+// namespace of a subdomain gateway, if it is a CID or a PeerID. ok is false
+// when rootID is neither.
+func toSubdomainCIDLabel(ns, rootID string) (dnsCID string, ok bool, err error) {
+	// Normalize problematic PeerIDs (eg. ed25519+identity) to CID representation
+	if isPeerIDNamespace(ns) && !isDomainNameAndNotPeerID(rootID) {
+		peerID, err := peer.Decode(rootID)
+		// Note: PeerID CIDv1 with protobuf multicodec will fail, but we fix it
+		// in the next block
+		if err == nil {
+			rootID = peer.ToCid(peerID).String()
+		}
+	}
+
+	rootCID, err := cid.Decode(rootID)
+	if err != nil {
+		return "", false, nil
+	}
+
+	multicodec := rootCID.Type()
+	var base mbase.Encoding = mbase.Base32
+
+	// Normalizations specific to /ipns/{libp2p-key}
+	if isPeerIDNamespace(ns) {
+		// Using Base36 for /ipns/ for consistency
+		// Context: https://github.com/ipfs/kubo/pull/7441#discussion_r452372828
+		base = mbase.Base36
+
+		// PeerIDs represented as CIDv1 are expected to have libp2p-key
+		// multicodec (https://github.com/libp2p/specs/pull/209).
+		// We ease the transition by fixing multicodec on the fly:
+		// https://github.com/ipfs/kubo/issues/5287#issuecomment-492163929
+		if multicodec != cid.Libp2pKey {
+			multicodec = cid.Libp2pKey
+		}
+	}
+
+	// Ensure CID text representation used in subdomain is compatible
+	// with the way DNS and URIs are implemented in user agents.
+	//
+	// 1. Switch to CIDv1 and enable case-insensitive Base encoding
+	//    to avoid issues when user agent force-lowercases the hostname
+	//    before making the request
+	//    (https://github.com/ipfs/in-web-browsers/issues/89)
+	rootCID = cid.NewCidV1(multicodec, rootCID.Hash())
+	rootID, err = rootCID.StringOfBase(base)
+	if err != nil {
+		return "", true, err
+	}
+	// 2. Make sure CID fits in a DNS label, adjust encoding if needed
+	//    (https://github.com/ipfs/kubo/issues/7318)
+	rootID, err = toDNSLabel(rootID, rootCID)
+	if err != nil {
+		return "", true, err
+	}
+	return rootID, true, nil
+}
+
 // Returns true if HTTP request involves TLS certificate.
 // See https://github.com/ipfs/in-web-browsers/issues/169 to understand how it
 // impacts DNSLink websites on public gateways.
@@ -400,54 +468,12 @@ func toSubdomainURL(hostname, path string, r *http.Request, inlineDNSLink bool,
 		return "", nil
 	}
 
-	// Normalize problematic PeerIDs (eg. ed25519+identity) to CID representation
-	if isPeerIDNamespace(ns) && !isDomainNameAndNotPeerID(rootID) {
-		peerID, err := peer.Decode(rootID)
-		// Note: PeerID CIDv1 with protobuf multicodec will fail, but we fix it
-		// in the next block
-		if err == nil {
-			rootID = peer.ToCid(peerID).String()
-		}
-	}
-
 	// If rootID is a CID, ensure it uses DNS-friendly text representation
-	if rootCID, err := cid.Decode(rootID); err == nil {
-		multicodec := rootCID.Type()
-		var base mbase.Encoding = mbase.Base32
-
-		// Normalizations specific to /ipns/{libp2p-key}
-		if isPeerIDNamespace(ns) {
-			// Using Base36 for /ipns/ for consistency
-			// Context: https://github.com/ipfs/kubo/pull/7441#discussion_r452372828
-			base = mbase.Base36
-
-			// PeerIDs represented as CIDv1 are expected to have libp2p-key
-			// multicodec (https://github.com/libp2p/specs/pull/209).
-			// We ease the transition by fixing multicodec on the fly:
-			// https://github.com/ipfs/kubo/issues/5287#issuecomment-492163929
-			if multicodec != cid.Libp2pKey {
-				multicodec = cid.Libp2pKey
-			}
-		}
-
-		// Ensure CID text representation used in subdomain is compatible
-		// with the way DNS and URIs are implemented in user agents.
-		//
-		// 1. Switch to CIDv1 and enable case-insensitive Base encoding
-		//    to avoid issues when user agent force-lowercases the hostname
-		//    before making the request
-		//    (https://github.com/ipfs/in-web-browsers/issues/89)
-		rootCID = cid.NewCidV1(multicodec, rootCID.Hash())
-		rootID, err = rootCID.StringOfBase(base)
-		if err != nil {
-			return "", err
-		}
-		// 2. Make sure CID fits in a DNS label, adjust encoding if needed
-		//    (https://github.com/ipfs/kubo/issues/7318)
-		rootID, err = toDNSLabel(rootID, rootCID)
+	if dnsCID, ok, err := toSubdomainCIDLabel(ns, rootID); ok {
 		if err != nil {
 			return "", err
 		}
+		rootID = dnsCID
 	} else { // rootID is not a CID
 
 		// If rootID is an inlined notation of a FQDN with DNSLink we need to
@@ -536,36 +562,66 @@ func stripPort(hostname string) string {
 }
 
 type hostnameGateways struct {
-	exact    map[string]*PublicGateway
-	wildcard map[*regexp.Regexp]*PublicGateway
+	exact map[string]*PublicGateway
+	// subdomain holds the *.domain.tld gateways by their .domain.tld suffix,
+	// so that they are matched with a single lookup.
+	subdomain map[string]*PublicGateway
+	// wildcard holds the other wildcard gateways, such as *.*.domain.tld,
+	// the most specific first.
+	wildcard []wildcardGateway
+}
+
+type wildcardGateway struct {
+	re *regexp.Regexp
+	gw *PublicGateway
 }
 
 // prepareHostnameGateways converts the user given gateways into an internal format
 // split between exact and wildcard-based gateway hostnames.
 func prepareHostnameGateways(gateways map[string]*PublicGateway) *hostnameGateways {
 	h := &hostnameGateways{
-		exact:    map[string]*PublicGateway{},
-		wildcard: map[*regexp.Regexp]*PublicGateway{},
+		exact:     map[string]*PublicGateway{},
+		subdomain: map[string]*PublicGateway{},
 	}
 
+	var patterns []string
 	for hostname, gw := range gateways {
-		if strings.Contains(hostname, "*") {
-			// from *.domain.tld, construct a regexp that match any direct subdomain
-			// of .domain.tld.
-			//
-			// Regexp will be in the form of ^[^.]+\.domain.tld(?::\d+)?$
-			escaped := strings.ReplaceAll(hostname, ".", `\.`)
-			regexed := strings.ReplaceAll(escaped, "*", "[^.]+")
+		switch {
+		case !strings.Contains(hostname, "*"):
+			h.exact[hostname] = gw
+		case strings.HasPrefix(hostname, "*.") && !strings.Contains(hostname[2:], "*"):
+			h.subdomain[hostname[1:]] = gw
+		default:
+			patterns = append(patterns, hostname)
+		}
+	}
 
-			re, err := regexp.Compile(fmt.Sprintf(`^%s(?::\d+)?$`, regexed))
-			if err != nil {
-				log.Warn("invalid wildcard gateway hostname \"%s\"", hostname)
-			}
+	// Patterns with fewer wildcards and more labels are more specific.
+	sort.Slice(patterns, func(i, j int) bool {
+		wi, wj := strings.Count(patterns[i], "*"), strings.Count(patterns[j], "*")
+		if wi != wj {
+			return wi < wj
+		}
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, hostname := range patterns {
+		// from *.*.domain.tld, construct a regexp that match any subdomain of
+		// .domain.tld with two labels.
+		//
+		// Regexp will be in the form of ^[^.]+\.[^.]+\.domain.tld(?::\d+)?$
+		escaped := strings.ReplaceAll(hostname, ".", `\.`)
+		regexed := strings.ReplaceAll(escaped, "*", "[^.]+")
 
-			h.wildcard[re] = gw
-		} else {
-			h.exact[hostname] = gw
+		re, err := regexp.Compile(fmt.Sprintf(`^%s(?::\d+)?$`, regexed))
+		if err != nil {
+			log.Warnf("invalid wildcard gateway hostname %q", hostname)
+			continue
 		}
+
+		h.wildcard = append(h.wildcard, wildcardGateway{re: re, gw: gateways[hostname]})
 	}
 
 	return h
@@ -584,9 +640,16 @@ func (gws *hostnameGateways) isKnownHostname(hostname string) (gw *PublicGateway
 	}
 
 	// Wildcard support. Test both with and without port.
-	for re, spec := range gws.wildcard {
-		if re.MatchString(hostname) {
-			return spec, true
+	for _, h := range []string{hostname, stripPort(hostname)} {
+		if i := strings.IndexByte(h, '.'); i > 0 {
+			if gw, ok = gws.subdomain[h[i:]]; ok {
+				return gw, ok
+			}
+		}
+	}
+	for _, w := range gws.wildcard {
+		if w.re.MatchString(hostname) {
+			return w.gw, true
 		}
 	}
 
@@ -313,6 +313,46 @@ func TestKnownSubdomainDetails(t *testing.T) {
 	}
 }
 
+func TestIsKnownHostname(t *testing.T) {
+	t.Parallel()
+
+	gwExact := &PublicGateway{}
+	gwSubdomain := &PublicGateway{}
+	gwTwoLabels := &PublicGateway{}
+	gwMiddle := &PublicGateway{}
+
+	gateways := prepareHostnameGateways(map[string]*PublicGateway{
+		"exact.example.com": gwExact,
+		"*.example.com":     gwSubdomain,
+		"*.*.example.com":   gwTwoLabels,
+		"a.*.example.com":   gwMiddle,
+		"*.localhost:8080":  gwSubdomain,
+	})
+
+	for _, test := range []struct {
+		hostHeader string
+		gw         *PublicGateway
+	}{
+		{"exact.example.com", gwExact},
+		{"exact.example.com:8080", gwExact},
+		{"site.example.com", gwSubdomain},
+		{"site.example.com:8080", gwSubdomain},
+		{"b.site.example.com", gwTwoLabels},
+		{"a.site.example.com", gwMiddle},
+		{"site.localhost:8080", gwSubdomain},
+		{"example.com", nil},
+		{".example.com", nil},
+		{"c.b.a.example.com", nil},
+		{"site.localhost:8081", nil},
+	} {
+		t.Run(test.hostHeader, func(t *testing.T) {
+			gw, ok := gateways.isKnownHostname(test.hostHeader)
+			assert.Equal(t, test.gw != nil, ok)
+			assert.Same(t, test.gw, gw)
+		})
+	}
+}
+
 const testInlinedDNSLinkA = "example-com"
 const testInlinedDNSLinkB = "docs-ipfs-tech"
 const testInlinedDNSLinkC = "en-wikipedia--on--ipfs-org"
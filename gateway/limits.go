@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// truncatedTrailer is the trailer set on the export responses truncated at
+// [Config.MaxExportBytes] or [Config.MaxExportBlocks], with the limit reached.
+// It is declared up front, as trailers are otherwise dropped from the small
+// responses sent with a Content-Length.
+const truncatedTrailer = "X-Stream-Truncated"
+
+// maxCARSectionSize is the maximum size of the sections of the CARs limited by
+// the gateway, the default of go-car.
+const maxCARSectionSize = 8 << 20
+
+// exportLimitError is returned when an export response reaches one of the
+// limits of the gateway.
+type exportLimitError struct {
+	max  int64
+	unit string
+}
+
+func (e *exportLimitError) Error() string {
+	return fmt.Sprintf("response exceeds the maximum of %d %s", e.max, e.unit)
+}
+
+// exportLimiter enforces [Config.MaxExportBytes] and [Config.MaxExportBlocks]
+// on a single export response, and tracks what was sent.
+type exportLimiter struct {
+	maxBytes  int64
+	maxBlocks int64
+
+	bytes   int64
+	blocks  int64
+	sent    int64
+	reached error
+}
+
+// newExportLimiter returns the limiter of an export response written to w,
+// which must not have been written yet.
+func (i *handler) newExportLimiter(w http.ResponseWriter) *exportLimiter {
+	if i.config.MaxExportBytes > 0 || i.config.MaxExportBlocks > 0 {
+		w.Header().Set("Trailer", truncatedTrailer)
+	}
+	return &exportLimiter{
+		maxBytes:  i.config.MaxExportBytes,
+		maxBlocks: i.config.MaxExportBlocks,
+	}
+}
+
+// add accounts for n more bytes and the given number of blocks, and returns
+// an error if this exceeds the limits. Once a limit is reached, nothing else
+// can be added.
+func (l *exportLimiter) add(n, blocks int64) error {
+	if l.reached != nil {
+		return l.reached
+	}
+	switch {
+	case l.maxBlocks > 0 && l.blocks+blocks > l.maxBlocks:
+		l.reached = &exportLimitError{max: l.maxBlocks, unit: "blocks"}
+	case l.maxBytes > 0 && l.bytes+n > l.maxBytes:
+		l.reached = &exportLimitError{max: l.maxBytes, unit: "bytes"}
+	default:
+		l.bytes += n
+		l.blocks += blocks
+		return nil
+	}
+	return l.reached
+}
+
+// write writes p to w, which must have been accounted for with add.
+func (l *exportLimiter) write(w io.Writer, p []byte) error {
+	n, err := w.Write(p)
+	l.sent += int64(n)
+	return err
+}
+
+// writer returns a writer to w refusing the writes exceeding the byte limit.
+func (l *exportLimiter) writer(w io.Writer) io.Writer {
+	return limitedWriter{l: l, w: w}
+}
+
+type limitedWriter struct {
+	l *exportLimiter
+	w io.Writer
+}
+
+func (lw limitedWriter) Write(p []byte) (int, error) {
+	// Empty writes would send the response headers.
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := lw.l.add(int64(len(p)), 0); err != nil {
+		return 0, err
+	}
+	n, err := lw.w.Write(p)
+	lw.l.sent += int64(n)
+	return n, err
+}
+
+// copyCAR copies the CARv1 stream r to w, up to the last block within the
+// limits, so that truncated responses are still valid CARs. The CAR header is
+// only written with the first block, so that nothing is sent when the first
+// block already exceeds the limits.
+func (l *exportLimiter) copyCAR(w io.Writer, r io.Reader) error {
+	if l.maxBytes <= 0 && l.maxBlocks <= 0 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	header, err := readCARSection(br)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if err := l.add(int64(len(header)), 0); err != nil {
+		return err
+	}
+
+	for {
+		section, err := readCARSection(br)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := l.add(int64(len(section)), 1); err != nil {
+			return err
+		}
+		if header != nil {
+			if err := l.write(w, header); err != nil {
+				return err
+			}
+			header = nil
+		}
+		if err := l.write(w, section); err != nil {
+			return err
+		}
+	}
+	if header != nil {
+		return l.write(w, header)
+	}
+	return nil
+}
+
+// readCARSection reads a varint length prefixed section of a CARv1 stream,
+// and returns it with its prefix. It returns io.EOF at the end of the stream.
+func readCARSection(br *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(br)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if length > maxCARSectionSize {
+		return nil, fmt.Errorf("CAR section of %d bytes exceeds the maximum of %d bytes", length, maxCARSectionSize)
+	}
+	prefix := binary.AppendUvarint(nil, length)
+	section := make([]byte, len(prefix)+int(length))
+	n := copy(section, prefix)
+	if _, err := io.ReadFull(br, section[n:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return section, nil
+}
+
+// handleExportLimit handles the errors of export responses reaching the
+// limits of the gateway, and returns false for other errors. Responses which
+// did not send anything yet get a 413 error, others are truncated and get the
+// [truncatedTrailer] trailer.
+func (i *handler) handleExportLimit(w http.ResponseWriter, r *http.Request, l *exportLimiter, err error) bool {
+	var limitErr *exportLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	if l.sent == 0 {
+		for _, h := range []string{"Etag", "Cache-Control", "Last-Modified", "Content-Disposition", "Accept-Ranges", "Trailer"} {
+			w.Header().Del(h)
+		}
+		i.webError(w, r, limitErr, http.StatusRequestEntityTooLarge)
+		return true
+	}
+	w.Header().Set(truncatedTrailer, limitErr.Error())
+	return true
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/ipld/unixfs/importer"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/ipld/go-car/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportLimits(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	carPath := "/ipfs/" + root.String()
+	get := func(t *testing.T, backend IPFSBackend, config Config, p string) (*http.Response, []byte) {
+		config.DeserializedResponses = true
+		ts := newTestServerWithConfig(t, backend, config)
+		res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+p, nil))
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+	countCARBlocks := func(t *testing.T, body []byte) int {
+		br, err := car.NewBlockReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		var n int
+		for {
+			_, err := br.Next()
+			if err == io.EOF {
+				return n
+			}
+			require.NoError(t, err)
+			n++
+		}
+	}
+
+	res, body := get(t, backend, Config{}, carPath+"?format=car")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Empty(t, res.Trailer.Get(truncatedTrailer))
+	total := countCARBlocks(t, body)
+	require.Greater(t, total, 3)
+
+	t.Run("CAR truncated at the block limit", func(t *testing.T) {
+		res, body := get(t, backend, Config{MaxExportBlocks: 3}, carPath+"?format=car")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, 3, countCARBlocks(t, body))
+		require.Equal(t, "response exceeds the maximum of 3 blocks", res.Trailer.Get(truncatedTrailer))
+	})
+
+	t.Run("CAR truncated at the byte limit", func(t *testing.T) {
+		res, truncated := get(t, backend, Config{MaxExportBytes: int64(len(body) - 1)}, carPath+"?format=car")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Less(t, len(truncated), len(body))
+		require.Equal(t, body[:len(truncated)], truncated)
+		require.Equal(t, total-1, countCARBlocks(t, truncated))
+		require.Contains(t, res.Trailer.Get(truncatedTrailer), "bytes")
+	})
+
+	t.Run("CAR larger than the limit before the first block", func(t *testing.T) {
+		res, body := get(t, backend, Config{MaxExportBytes: 10}, carPath+"?format=car")
+		require.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+		require.Contains(t, string(body), "response exceeds the maximum of 10 bytes")
+		require.Empty(t, res.Header.Get("Etag"))
+	})
+
+	t.Run("NDJSON truncated at the block limit", func(t *testing.T) {
+		res, body := get(t, backend, Config{MaxExportBlocks: 2}, carPath+"?format=ndjson")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, 2, bytes.Count(body, []byte("\n")))
+		require.Equal(t, "response exceeds the maximum of 2 blocks", res.Trailer.Get(truncatedTrailer))
+	})
+
+	t.Run("TAR truncated at the byte limit", func(t *testing.T) {
+		bsrv := blockservice.New(blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore())), nil)
+		dir, err := importer.ImportTree(context.Background(), merkledag.NewDAGService(bsrv), files.NewMapDirectory(map[string]files.Node{
+			"file.bin": files.NewBytesFile(bytes.Repeat([]byte{'a'}, 4096)),
+		}))
+		require.NoError(t, err)
+		tarBackend, err := NewBlocksBackend(bsrv)
+		require.NoError(t, err)
+		tarPath := "/ipfs/" + dir.Root.Cid().String()
+
+		res, body := get(t, tarBackend, Config{}, tarPath+"?format=tar")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Greater(t, len(body), 4096)
+
+		// Block limits do not apply to TARs, which are not cut at a file
+		// boundary.
+		res, truncated := get(t, tarBackend, Config{MaxExportBytes: 2048, MaxExportBlocks: 1}, tarPath+"?format=tar")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotEmpty(t, truncated)
+		require.LessOrEqual(t, len(truncated), 2048)
+		require.Equal(t, "response exceeds the maximum of 2048 bytes", res.Trailer.Get(truncatedTrailer))
+
+		// The first header of the TAR does not fit.
+		res, _ = get(t, tarBackend, Config{MaxExportBytes: 511}, tarPath+"?format=tar")
+		require.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+	})
+}
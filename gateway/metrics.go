@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipns"
 	"github.com/ipfs/boxo/path"
 	"github.com/ipfs/go-cid"
 	prometheus "github.com/prometheus/client_golang/prometheus"
@@ -19,6 +20,20 @@ import (
 // We use fixed definition here, as we don't want to break existing buckets if we need to add more.
 var defaultDurationHistogramBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60, 120, 240, 480, 960, 1920}
 
+// backendCallTimings maps the backend calls to the Server-Timing phase they
+// are accounted in.
+var backendCallTimings = map[string]string{
+	"IPFSBackend.Get":              timingFirstBlock,
+	"IPFSBackend.GetAll":           timingFirstBlock,
+	"IPFSBackend.GetBlock":         timingFirstBlock,
+	"IPFSBackend.Head":             timingFirstBlock,
+	"IPFSBackend.GetCAR":           timingFirstBlock,
+	"IPFSBackend.ResolvePath":      timingResolvePath,
+	"IPFSBackend.GetIPNSRecord":    timingResolveName,
+	"IPFSBackend.ResolveMutable":   timingResolveName,
+	"IPFSBackend.GetDNSLinkRecord": timingResolveName,
+}
+
 type ipfsBackendWithMetrics struct {
 	backend           IPFSBackend
 	backendCallMetric *prometheus.HistogramVec
@@ -50,8 +65,13 @@ func newIPFSBackendWithMetrics(backend IPFSBackend) *ipfsBackendWithMetrics {
 	return &ipfsBackendWithMetrics{backend, backendCallMetric}
 }
 
-func (b *ipfsBackendWithMetrics) updateBackendCallMetric(name string, err error, begin time.Time) {
-	end := time.Since(begin).Seconds()
+func (b *ipfsBackendWithMetrics) updateBackendCallMetric(ctx context.Context, name string, err error, begin time.Time) {
+	d := time.Since(begin)
+	if phase, ok := backendCallTimings[name]; ok {
+		addServerTiming(ctx, phase, d)
+	}
+
+	end := d.Seconds()
 	if err == nil {
 		b.backendCallMetric.WithLabelValues(name, "success").Observe(end)
 	} else {
@@ -64,10 +84,11 @@ func (b *ipfsBackendWithMetrics) Get(ctx context.Context, path path.ImmutablePat
 	name := "IPFSBackend.Get"
 	ctx, span := spanTrace(ctx, name, trace.WithAttributes(attribute.String("path", path.String()), attribute.Int("ranges", len(ranges))))
 	defer span.End()
+	ctx = contextWithFirstBlock(ctx)
 
 	md, f, err := b.backend.Get(ctx, path, ranges...)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return md, f, err
 }
 
@@ -76,10 +97,11 @@ func (b *ipfsBackendWithMetrics) GetAll(ctx context.Context, path path.Immutable
 	name := "IPFSBackend.GetAll"
 	ctx, span := spanTrace(ctx, name, trace.WithAttributes(attribute.String("path", path.String())))
 	defer span.End()
+	ctx = contextWithFirstBlock(ctx)
 
 	md, n, err := b.backend.GetAll(ctx, path)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return md, n, err
 }
 
@@ -88,10 +110,11 @@ func (b *ipfsBackendWithMetrics) GetBlock(ctx context.Context, path path.Immutab
 	name := "IPFSBackend.GetBlock"
 	ctx, span := spanTrace(ctx, name, trace.WithAttributes(attribute.String("path", path.String())))
 	defer span.End()
+	ctx = contextWithFirstBlock(ctx)
 
 	md, n, err := b.backend.GetBlock(ctx, path)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return md, n, err
 }
 
@@ -100,10 +123,11 @@ func (b *ipfsBackendWithMetrics) Head(ctx context.Context, path path.ImmutablePa
 	name := "IPFSBackend.Head"
 	ctx, span := spanTrace(ctx, name, trace.WithAttributes(attribute.String("path", path.String())))
 	defer span.End()
+	ctx = contextWithFirstBlock(ctx)
 
 	md, n, err := b.backend.Head(ctx, path)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return md, n, err
 }
 
@@ -115,7 +139,7 @@ func (b *ipfsBackendWithMetrics) ResolvePath(ctx context.Context, path path.Immu
 
 	md, err := b.backend.ResolvePath(ctx, path)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return md, err
 }
 
@@ -124,9 +148,10 @@ func (b *ipfsBackendWithMetrics) GetCAR(ctx context.Context, path path.Immutable
 	name := "IPFSBackend.GetCAR"
 	ctx, span := spanTrace(ctx, name, trace.WithAttributes(attribute.String("path", path.String())))
 	defer span.End()
+	ctx = contextWithFirstBlock(ctx)
 
 	md, rc, err := b.backend.GetCAR(ctx, path, params)
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return md, rc, err
 }
 
@@ -138,7 +163,7 @@ func (b *ipfsBackendWithMetrics) IsCached(ctx context.Context, path path.Path) b
 
 	bln := b.backend.IsCached(ctx, path)
 
-	b.updateBackendCallMetric(name, nil, begin)
+	b.updateBackendCallMetric(ctx, name, nil, begin)
 	return bln
 }
 
@@ -150,7 +175,7 @@ func (b *ipfsBackendWithMetrics) GetIPNSRecord(ctx context.Context, cid cid.Cid)
 
 	r, err := b.backend.GetIPNSRecord(ctx, cid)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return r, err
 }
 
@@ -162,7 +187,7 @@ func (b *ipfsBackendWithMetrics) ResolveMutable(ctx context.Context, path path.P
 
 	p, ttl, lastMod, err := b.backend.ResolveMutable(ctx, path)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return p, ttl, lastMod, err
 }
 
@@ -174,7 +199,7 @@ func (b *ipfsBackendWithMetrics) GetDNSLinkRecord(ctx context.Context, fqdn stri
 
 	p, err := b.backend.GetDNSLinkRecord(ctx, fqdn)
 
-	b.updateBackendCallMetric(name, err, begin)
+	b.updateBackendCallMetric(ctx, name, err, begin)
 	return p, err
 }
 
@@ -188,10 +213,47 @@ func (b *ipfsBackendWithMetrics) WrapContextForRequest(ctx context.Context) cont
 	return ctx
 }
 
+var _ WithDeterministicCAR = (*ipfsBackendWithMetrics)(nil)
+
+func (b *ipfsBackendWithMetrics) IsDeterministicCAR(params CarParams) bool {
+	if withDeterministicCAR, ok := b.backend.(WithDeterministicCAR); ok {
+		return withDeterministicCAR.IsDeterministicCAR(params)
+	}
+	return false
+}
+
+var _ WithIPNSPublishing = (*ipfsBackendWithMetrics)(nil)
+
+func (b *ipfsBackendWithMetrics) PutIPNSRecord(ctx context.Context, ipnsName ipns.Name, record []byte) error {
+	begin := time.Now()
+	name := "IPFSBackend.PutIPNSRecord"
+	ctx, span := spanTrace(ctx, name, trace.WithAttributes(attribute.String("name", ipnsName.String())))
+	defer span.End()
+
+	err := putIPNSRecord(ctx, b.backend, ipnsName, record)
+
+	b.updateBackendCallMetric(ctx, name, err, begin)
+	return err
+}
+
 func newHandlerWithMetrics(c *Config, backend IPFSBackend) *handler {
+	if c.DegradedMode != nil {
+		backend = newIPFSBackendWithDegradedMode(backend, c.DegradedMode)
+	}
+	if c.CoalesceRequests {
+		backend = newIPFSBackendWithCoalescing(backend)
+	}
+	if c.ContentBlocker != nil {
+		backend = newIPFSBackendWithBlocking(backend, c.ContentBlocker)
+	}
+	if c.NameResolutionTimeout > 0 || c.PathResolutionTimeout > 0 || c.FirstBlockTimeout > 0 {
+		backend = newIPFSBackendWithTimeouts(backend, c)
+	}
+
 	i := &handler{
 		config:  c,
 		backend: newIPFSBackendWithMetrics(backend),
+		hosts:   prepareHostnameGateways(c.PublicGateways),
 
 		// Response-type specific metrics
 		// ----------------------------
@@ -243,16 +305,34 @@ func newHandlerWithMetrics(c *Config, backend IPFSBackend) *handler {
 			"gw_tar_stream_fail_duration_seconds",
 			"How long a TAR was streamed before failing mid-stream.",
 		),
+		// NDJSON: time it takes to return requested NDJSON stream
+		ndjsonStreamGetMetric: newHistogramMetric(
+			"gw_ndjson_stream_get_duration_seconds",
+			"The time to GET an entire NDJSON stream from the gateway.",
+		),
+		ndjsonStreamFailMetric: newHistogramMetric(
+			"gw_ndjson_stream_fail_duration_seconds",
+			"How long a NDJSON was streamed before failing mid-stream.",
+		),
 		// JSON/CBOR: time it takes to return requested DAG-JSON/-CBOR document
 		jsoncborDocumentGetMetric: newHistogramMetric(
 			"gw_jsoncbor_get_duration_seconds",
 			"The time to GET an entire DAG-JSON/CBOR block from the gateway.",
 		),
+		// DAG stats: time it takes to traverse the DAG and return its stats
+		dagStatsGetMetric: newHistogramMetric(
+			"gw_dag_stats_get_duration_seconds",
+			"The time to GET the stats of a DAG from the gateway.",
+		),
 		// IPNS Record: time it takes to return IPNS record
 		ipnsRecordGetMetric: newHistogramMetric(
 			"gw_ipns_record_get_duration_seconds",
 			"The time to GET an entire IPNS Record from the gateway.",
 		),
+		ipnsRecordPutMetric: newHistogramMetric(
+			"gw_ipns_record_put_duration_seconds",
+			"The time to PUT and publish an IPNS Record to the gateway.",
+		),
 	}
 	return i
 }
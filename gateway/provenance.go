@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ipfs/boxo/path"
+)
+
+// SetProvenanceHeaders sets the headers telling where the response to a
+// request for contentPath comes from, for embedders writing custom handlers:
+//
+//   - X-Ipfs-Path: the requested content path,
+//   - X-Content-Path: the immutable path it resolved to, if resolved is not
+//     the zero value,
+//   - X-Ipfs-Roots: the CIDs of the logical roots of each segment of the
+//     path, from md. If md is nil, because the path could only be partially
+//     resolved, it only has the root CID of resolved.
+//
+// The gateway sets them on all its responses, including errors.
+func SetProvenanceHeaders(h http.Header, contentPath path.Path, resolved path.ImmutablePath, md *ContentPathMetadata) {
+	h.Set("X-Ipfs-Path", contentPath.String())
+	if !resolved.RootCid().Defined() {
+		return
+	}
+	h.Set("X-Content-Path", resolved.String())
+
+	if md == nil {
+		h.Set("X-Ipfs-Roots", resolved.RootCid().String())
+		return
+	}
+
+	// These are logical roots where each CID represent one path segment
+	// and resolves to either a directory or the root block of a file.
+	// The main purpose of this header is allow HTTP caches to do smarter decisions
+	// around cache invalidation (eg. keep specific subdirectory/file if it did not change)
+
+	// A good example is Wikipedia, which is HAMT-sharded, but we only care about
+	// logical roots that represent each segment of the human-readable content
+	// path:
+
+	// Given contentPath = /ipns/en.wikipedia-on-ipfs.org/wiki/Block_of_Wikipedia_in_Turkey
+	// rootCidList is a generated by doing `ipfs resolve -r` on each sub path:
+	// 	/ipns/en.wikipedia-on-ipfs.org → bafybeiaysi4s6lnjev27ln5icwm6tueaw2vdykrtjkwiphwekaywqhcjze
+	// 	/ipns/en.wikipedia-on-ipfs.org/wiki/ → bafybeihn2f7lhumh4grizksi2fl233cyszqadkn424ptjajfenykpsaiw4
+	// 	/ipns/en.wikipedia-on-ipfs.org/wiki/Block_of_Wikipedia_in_Turkey → bafkreibn6euazfvoghepcm4efzqx5l3hieof2frhp254hio5y7n3hv5rma
+
+	// The result is an ordered array of values:
+	// 	X-Ipfs-Roots: bafybeiaysi4s6lnjev27ln5icwm6tueaw2vdykrtjkwiphwekaywqhcjze,bafybeihn2f7lhumh4grizksi2fl233cyszqadkn424ptjajfenykpsaiw4,bafkreibn6euazfvoghepcm4efzqx5l3hieof2frhp254hio5y7n3hv5rma
+
+	// Note that while the top one will change every time any article is changed,
+	// the last root (responsible for specific article) may not change at all.
+
+	var pathRoots []string
+	for _, c := range md.PathSegmentRoots {
+		pathRoots = append(pathRoots, c.String())
+	}
+	pathRoots = append(pathRoots, md.LastSegment.RootCid().String())
+	rootCidList := strings.Join(pathRoots, ",") // convention from rfc2616#sec4.2
+
+	h.Set("X-Ipfs-Roots", rootCidList)
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	redirects "github.com/ipfs/go-ipfs-redirects-file"
+)
+
+// RedirectRule is a single rule of a [_redirects file].
+//
+// [_redirects file]: https://specs.ipfs.tech/http-gateways/web-redirects-file/
+type RedirectRule struct {
+	redirects.Rule
+
+	// Force is true when the status code is suffixed with "!". Forced rules are
+	// applied even when the requested path exists, which is often used for 200
+	// rewrites in single-page applications. They are only evaluated by the
+	// gateway if [Config.ForcedRedirects] is enabled.
+	Force bool
+
+	// Host scopes the rule to requests for the given hostname. It is set when
+	// the "from" field of the rule is a full URL such as https://example.com/foo,
+	// which allows a single _redirects file to be shared by several hostnames.
+	Host string
+}
+
+// ParseRedirects parses a _redirects file. In addition to the syntax supported
+// by [redirects.Parse], the "from" field can be a full http(s) URL in order to
+// scope a rule to a hostname, and the status code can be suffixed with "!" in
+// order to force the rule.
+func ParseRedirects(r io.Reader) ([]RedirectRule, error) {
+	limiter := &io.LimitedReader{R: r, N: redirects.MaxFileSizeInBytes + 1}
+	s := bufio.NewScanner(limiter)
+
+	var rules []RedirectRule
+	for s.Scan() {
+		// detect when we've read one byte beyond MaxFileSizeInBytes
+		// and return user-friendly error
+		if limiter.N <= 0 {
+			return nil, fmt.Errorf("redirects file size cannot exceed %d bytes", redirects.MaxFileSizeInBytes)
+		}
+
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule RedirectRule
+		fields := strings.Fields(line)
+
+		if len(fields) > 0 && !strings.HasPrefix(fields[0], "/") {
+			u, err := url.Parse(fields[0])
+			if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+				rule.Host = strings.ToLower(u.Hostname())
+				fields[0] = u.EscapedPath()
+				if fields[0] == "" {
+					fields[0] = "/"
+				}
+			}
+		}
+
+		if len(fields) > 2 && strings.HasSuffix(fields[2], "!") {
+			rule.Force = true
+			fields[2] = strings.TrimSuffix(fields[2], "!")
+		}
+
+		// The remaining validation is the same as for regular rules.
+		parsed, err := redirects.ParseString(strings.Join(fields, " "))
+		if err != nil {
+			return nil, err
+		}
+		rule.Rule = parsed[0]
+
+		rules = append(rules, rule)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// EvaluateRedirects returns the first rule matching a request for urlPath on
+// the given host. The returned rule has the placeholders and splat of its "to"
+// field expanded. If exists is true, the requested path exists and only forced
+// rules are considered. Rules scoped to a hostname are skipped if host is
+// empty or different.
+func EvaluateRedirects(rules []RedirectRule, host, urlPath string, exists bool) (RedirectRule, bool) {
+	host = strings.ToLower(stripPort(host))
+	urlPath = strings.TrimSuffix(urlPath, "/")
+
+	for _, rule := range rules {
+		if exists && !rule.Force {
+			continue
+		}
+		if rule.Host != "" && rule.Host != host {
+			continue
+		}
+		// MatchAndExpandPlaceholders modifies the rule, which is a copy.
+		if rule.MatchAndExpandPlaceholders(urlPath) {
+			return rule, true
+		}
+	}
+
+	return RedirectRule{}, false
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedirects(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ParseRedirects(strings.NewReader(`
+# comment
+/home /
+/blog/:year/:month /posts/:year-:month 302
+/* /index.html 200!
+https://example.com/docs/* /documentation/:splat 301
+https://Example.net /net.html 200
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 5)
+
+	require.Equal(t, "/home", rules[0].From)
+	require.Equal(t, 301, rules[0].Status)
+	require.False(t, rules[0].Force)
+	require.Empty(t, rules[0].Host)
+
+	require.Equal(t, 302, rules[1].Status)
+
+	require.Equal(t, "/*", rules[2].From)
+	require.Equal(t, 200, rules[2].Status)
+	require.True(t, rules[2].Force)
+
+	require.Equal(t, "/docs/*", rules[3].From)
+	require.Equal(t, "example.com", rules[3].Host)
+	require.False(t, rules[3].Force)
+
+	require.Equal(t, "/", rules[4].From)
+	require.Equal(t, "example.net", rules[4].Host)
+
+	for _, invalid := range []string{
+		"/from",
+		"from /to",
+		"ftp://example.com/from /to",
+		"/from /to 999!",
+		"/from /to 200 extra",
+	} {
+		_, err := ParseRedirects(strings.NewReader(invalid))
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestEvaluateRedirects(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ParseRedirects(strings.NewReader(`
+https://example.com/docs/* /documentation/:splat 301
+/blog/:year/:month /posts/:year-:month 302
+/app/* /app/index.html 200!
+/* /404.html 404
+`))
+	require.NoError(t, err)
+
+	test := func(host, urlPath string, exists bool, expectedTo string, expectedStatus int) {
+		t.Helper()
+		rule, ok := EvaluateRedirects(rules, host, urlPath, exists)
+		if expectedStatus == 0 {
+			require.False(t, ok, "%s%s", host, urlPath)
+			return
+		}
+		require.True(t, ok, "%s%s", host, urlPath)
+		require.Equal(t, expectedTo, rule.To)
+		require.Equal(t, expectedStatus, rule.Status)
+	}
+
+	test("example.com", "/docs/a/b", false, "/documentation/a/b", 301)
+	test("EXAMPLE.com:8080", "/docs/a/b", false, "/documentation/a/b", 301)
+	test("example.net", "/docs/a/b", false, "/404.html", 404)
+	test("", "/blog/2023/10/", false, "/posts/2023-10", 302)
+	test("", "/app/settings", false, "/app/index.html", 200)
+
+	// Only forced rules apply to existing paths.
+	test("example.com", "/docs/a/b", true, "", 0)
+	test("", "/app/settings", true, "/app/index.html", 200)
+}
+
+func newRedirectsTestBackend(t *testing.T, redirects string, files map[string]string) (*mockBackend, cid.Cid) {
+	ctx := context.Background()
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bsrv := blockservice.New(bs, offline.Exchange(bs))
+	dserv := merkledag.NewDAGService(bsrv)
+
+	dir := uio.NewDirectory(dserv)
+	files["_redirects"] = redirects
+	for name, content := range files {
+		nd := merkledag.NodeWithData(ft.FilePBData([]byte(content), uint64(len(content))))
+		require.NoError(t, dserv.Add(ctx, nd))
+		require.NoError(t, dir.AddChild(ctx, name, nd))
+	}
+	root, err := dir.GetNode()
+	require.NoError(t, err)
+	require.NoError(t, dserv.Add(ctx, root))
+
+	n := mockNamesys{}
+	backend, err := NewBlocksBackend(bsrv, WithNameSystem(n))
+	require.NoError(t, err)
+
+	return &mockBackend{gw: backend, namesys: n}, root.Cid()
+}
+
+func TestForcedRedirects(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newRedirectsTestBackend(t, `
+https://other.example.com/about.html /other.html 200!
+/page.html /index.html 200!
+/about.html /index.html 200
+`, map[string]string{
+		"index.html": "index\n",
+		"page.html":  "page\n",
+		"about.html": "about\n",
+		"other.html": "other\n",
+	})
+	backend.namesys["/ipns/example.com"] = newMockNamesysItem(path.FromCid(root), 0)
+	backend.namesys["/ipns/other.example.com"] = newMockNamesysItem(path.FromCid(root), 0)
+
+	test := func(forced bool, host, urlPath, expected string) {
+		t.Run(host+urlPath, func(t *testing.T) {
+			ts := newTestServerWithConfig(t, backend, Config{
+				DeserializedResponses: true,
+				ForcedRedirects:       forced,
+			})
+
+			req := mustNewRequest(t, http.MethodGet, ts.URL+urlPath, nil)
+			req.Header.Add("Accept", "text/html")
+			req.Host = host
+
+			res := mustDoWithoutRedirect(t, req)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, expected, string(body))
+		})
+	}
+
+	test(true, "example.com", "/page.html", "index\n")
+	test(false, "example.com", "/page.html", "page\n")
+
+	// Non-forced rules do not apply to existing paths.
+	test(true, "example.com", "/about.html", "about\n")
+
+	// Rules scoped to a hostname only apply to that hostname.
+	test(true, "other.example.com", "/about.html", "other\n")
+}
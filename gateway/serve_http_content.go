@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/textproto"
 	"strconv"
@@ -39,7 +40,8 @@ func headerGetExact(h http.Header, key string) string {
 // Notable differences from http.ServeContent
 // 1. Takes an io.Reader instead of an io.ReaderSeeker
 // 2. Requires the size to be passed in explicitly instead of discovered via Seeker behavior
-// 3. Only handles a single HTTP Range, if multiple are requested it returns the first
+// 3. Only handles multiple HTTP Ranges when content is an io.Seeker, or for HEAD
+// requests, else it returns the first
 // 4. The passed io.Reader must start at wherever the HTTP Range Request will start
 // 4. Requires the Content-Type header to already be set
 // 5. Does not require the name to be passed in for content sniffing
@@ -87,7 +89,13 @@ func httpServeContent(w http.ResponseWriter, r *http.Request, modtime time.Time,
 		ranges = nil
 	}
 
-	// We only support a single range request, if more than one is submitted we just send back the first
+	// Multiple ranges are sent as a multipart response when the content can be
+	// seeked to each range, else we just send back the first
+	if _, seekable := content.(io.Seeker); len(ranges) > 1 && (seekable || r.Method == http.MethodHead) {
+		serveMultipartRanges(w, r, size, ranges, content)
+		return
+	}
+
 	if len(ranges) > 0 {
 		ra := ranges[0]
 		// RFC 7233, Section 4.1:
@@ -119,6 +127,64 @@ func httpServeContent(w http.ResponseWriter, r *http.Request, modtime time.Time,
 	}
 }
 
+// serveMultipartRanges sends the ranges of content as a multipart/byteranges
+// response, seeking content to the start of every range, so that only the
+// data of the ranges is read. content may be nil for HEAD requests.
+func serveMultipartRanges(w http.ResponseWriter, r *http.Request, size int64, ranges []httpRange, content io.Reader) {
+	ctype := w.Header().Get("Content-Type")
+	sendSize, boundary := rangesMIMESize(ranges, ctype, size)
+
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+	w.Header().Set("Accept-Ranges", "bytes")
+	if w.Header().Get("Content-Encoding") == "" {
+		w.Header().Set("Content-Length", strconv.FormatInt(sendSize, 10))
+	}
+	w.WriteHeader(http.StatusPartialContent)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	seeker := content.(io.Seeker)
+	mw := multipart.NewWriter(w)
+	_ = mw.SetBoundary(boundary)
+	for _, ra := range ranges {
+		part, err := mw.CreatePart(ra.mimeHeader(ctype, size))
+		if err != nil {
+			return
+		}
+		if _, err := seeker.Seek(ra.start, io.SeekStart); err != nil {
+			return
+		}
+		if _, err := io.CopyN(part, content, ra.length); err != nil {
+			return
+		}
+	}
+	mw.Close()
+}
+
+// rangesMIMESize returns the number of bytes it takes to encode the provided
+// ranges as a multipart response, and the boundary of the parts.
+func rangesMIMESize(ranges []httpRange, contentType string, contentSize int64) (encSize int64, boundary string) {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	for _, ra := range ranges {
+		_, _ = mw.CreatePart(ra.mimeHeader(contentType, contentSize))
+		encSize += ra.length
+	}
+	mw.Close()
+	encSize += int64(w)
+	return encSize, mw.Boundary()
+}
+
+// countingWriter counts how many bytes have been written to it.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
 // scanETag determines if a syntactically valid ETag is present at s. If so,
 // the ETag and remaining text after consuming ETag is returned. Otherwise,
 // it returns "", "".
@@ -371,6 +437,16 @@ func (r httpRange) contentRange(size int64) string {
 	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
 }
 
+func (r httpRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{
+		"Content-Range": {r.contentRange(size)},
+	}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	return h
+}
+
 // parseRange parses a Range header string as per RFC 7233.
 // errNoOverlap is returned if none of the ranges overlap.
 func parseRange(s string, size int64) ([]httpRange, error) {
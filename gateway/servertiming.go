@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phases reported in the Server-Timing header when [Config.ServerTiming] is
+// set.
+const (
+	// timingResolveName is the time spent resolving IPNS names and DNSLinks.
+	timingResolveName = "resolve-name"
+	// timingResolvePath is the time spent resolving content paths on their
+	// own. The path resolution done by the calls fetching content is part of
+	// timingFirstBlock.
+	timingResolvePath = "resolve-path"
+	// timingFirstBlock is the time spent in the backend calls returning
+	// content, until the first block is available.
+	timingFirstBlock = "first-block"
+	// timingBody is the time spent streaming the response body, reported in
+	// a trailer.
+	timingBody = "body"
+)
+
+type serverTimingKey struct{}
+
+// serverTiming accumulates the duration of the phases of a request.
+type serverTiming struct {
+	lk     sync.Mutex
+	phases []string
+	durs   map[string]time.Duration
+}
+
+func contextWithServerTiming(ctx context.Context) (context.Context, *serverTiming) {
+	t := &serverTiming{durs: make(map[string]time.Duration)}
+	return context.WithValue(ctx, serverTimingKey{}, t), t
+}
+
+// addServerTiming adds d to the duration of the phase if the request reports
+// its timings.
+func addServerTiming(ctx context.Context, phase string, d time.Duration) {
+	t, ok := ctx.Value(serverTimingKey{}).(*serverTiming)
+	if !ok {
+		return
+	}
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	if _, ok := t.durs[phase]; !ok {
+		t.phases = append(t.phases, phase)
+	}
+	t.durs[phase] += d
+}
+
+func (t *serverTiming) String() string {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	metrics := make([]string, len(t.phases))
+	for i, phase := range t.phases {
+		metrics[i] = formatServerTiming(phase, t.durs[phase])
+	}
+	return strings.Join(metrics, ", ")
+}
+
+func formatServerTiming(phase string, d time.Duration) string {
+	return fmt.Sprintf("%s;dur=%.1f", phase, float64(d)/float64(time.Millisecond))
+}
+
+// serverTimingResponseWriter sets the Server-Timing header with the phases
+// timed before the final response is written, and records when the body
+// starts. Informational responses are passed through.
+type serverTimingResponseWriter struct {
+	http.ResponseWriter
+	timing    *serverTiming
+	bodyStart time.Time
+}
+
+func (w *serverTimingResponseWriter) WriteHeader(code int) {
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.bodyStart.IsZero() {
+		if timings := w.timing.String(); timings != "" {
+			w.Header().Set("Server-Timing", timings)
+		}
+		w.bodyStart = time.Now()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *serverTimingResponseWriter) Write(p []byte) (int, error) {
+	if w.bodyStart.IsZero() {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// ReadFrom exposes serverTimingResponseWriter's underlying ResponseWriter to
+// io.Copy to allow optimized methods to be taken advantage of.
+func (w *serverTimingResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.bodyStart.IsZero() {
+		w.WriteHeader(http.StatusOK)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+func (w *serverTimingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *serverTimingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish reports the time spent streaming the body in a trailer, which is
+// only sent with chunked responses.
+func (w *serverTimingResponseWriter) finish() {
+	if w.bodyStart.IsZero() {
+		return
+	}
+	w.Header().Set(http.TrailerPrefix+"Server-Timing", formatServerTiming(timingBody, time.Since(w.bodyStart)))
+}
@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ErrNotSubdomainHostname is returned by [SubdomainGateways.Validate] when a
+// hostname is not served by any of the subdomain gateways.
+var ErrNotSubdomainHostname = errors.New("hostname is not served by a known subdomain gateway")
+
+// SubdomainHostname returns the hostname at which the content root rootID of
+// the namespace ns is served by the subdomain gateway at gatewayHostname, in
+// the form of {rootID}.{ns}.{gatewayHostname}.
+//
+// CIDs and PeerIDs are normalized to the case-insensitive representation that
+// fits in a single DNS label, the same one the gateway redirects to. DNSLink
+// names in the ipns namespace are inlined with [InlineDNSLink], so that the
+// hostname is covered by a wildcard TLS certificate for *.{ns}.{gatewayHostname}.
+func SubdomainHostname(gatewayHostname, ns, rootID string) (string, error) {
+	if !isSubdomainNamespace(ns) {
+		return "", fmt.Errorf("%q is not a subdomain gateway namespace", ns)
+	}
+	if rootID == "" {
+		return "", errors.New("empty content root")
+	}
+
+	label, ok, err := toSubdomainCIDLabel(ns, rootID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if ns != "ipns" {
+			return "", fmt.Errorf("%q is not a valid CID", rootID)
+		}
+		fqdn := rootID
+		if !strings.Contains(fqdn, ".") {
+			fqdn = UninlineDNSLink(fqdn)
+		}
+		if _, ok := dns.IsDomainName(fqdn); !ok {
+			return "", fmt.Errorf("%q is not a valid DNSLink name", rootID)
+		}
+		label, err = InlineDNSLink(fqdn)
+		if err != nil {
+			return "", err
+		}
+	}
+	return label + "." + ns + "." + gatewayHostname, nil
+}
+
+// SubdomainHost holds the components of a subdomain gateway hostname:
+// {RootID}.{Namespace}.{Gateway}.
+type SubdomainHost struct {
+	// Gateway is the hostname of the subdomain gateway, including the port
+	// if the parsed hostname had one.
+	Gateway string
+	// Namespace is the content path namespace, e.g. "ipfs" or "ipns".
+	Namespace string
+	// RootID is the content root as it appears in the hostname.
+	RootID string
+}
+
+// Path returns the content path served at the hostname, e.g. /ipfs/{cid}.
+// Inlined DNSLink names are un-inlined, without checking that they have a
+// DNSLink record.
+func (h SubdomainHost) Path() string {
+	return "/" + h.Namespace + "/" + h.root()
+}
+
+// root returns RootID with inlined DNSLink names un-inlined.
+func (h SubdomainHost) root() string {
+	if h.Namespace == "ipns" && !strings.Contains(h.RootID, ".") {
+		if _, ok, _ := toSubdomainCIDLabel(h.Namespace, h.RootID); !ok {
+			return UninlineDNSLink(h.RootID)
+		}
+	}
+	return h.RootID
+}
+
+// SubdomainGateways matches hostnames against the subdomain gateways of a
+// [Config.PublicGateways] configuration. It can be used to decide which
+// hostnames to obtain TLS certificates for, see [SubdomainGateways.HostPolicy].
+type SubdomainGateways struct {
+	gateways map[string]*PublicGateway
+	hosts    *hostnameGateways
+}
+
+// NewSubdomainGateways returns a [SubdomainGateways] for the given public
+// gateways. Only the gateways with UseSubdomains set are considered.
+func NewSubdomainGateways(gateways map[string]*PublicGateway) *SubdomainGateways {
+	subdomains := make(map[string]*PublicGateway, len(gateways))
+	for hostname, gw := range gateways {
+		if gw != nil && gw.UseSubdomains {
+			subdomains[hostname] = gw
+		}
+	}
+	return &SubdomainGateways{
+		gateways: subdomains,
+		hosts:    prepareHostnameGateways(subdomains),
+	}
+}
+
+// Parse returns the components of hostname if it is the hostname of a content
+// root served by one of the subdomain gateways. The hostname may include a
+// port.
+func (s *SubdomainGateways) Parse(hostname string) (SubdomainHost, bool) {
+	gw, gwHostname, ns, rootID, ok := s.hosts.knownSubdomainDetails(hostname)
+	if !ok || rootID == "" || !hasPrefix("/"+ns+"/"+rootID, gw.Paths...) {
+		return SubdomainHost{}, false
+	}
+	return SubdomainHost{Gateway: gwHostname, Namespace: ns, RootID: rootID}, true
+}
+
+// Validate checks that hostname is either one of the subdomain gateways, or
+// the canonical hostname of a content root they serve, as returned by
+// [SubdomainHostname]. Non-canonical hostnames, which the gateway redirects,
+// are refused. No DNSLink lookups are made.
+func (s *SubdomainGateways) Validate(hostname string) error {
+	hostname = strings.ToLower(stripPort(hostname))
+	if _, ok := s.hosts.isKnownHostname(hostname); ok {
+		return nil
+	}
+
+	h, ok := s.Parse(hostname)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotSubdomainHostname, hostname)
+	}
+	canonical, err := SubdomainHostname(h.Gateway, h.Namespace, h.root())
+	if err != nil {
+		return fmt.Errorf("invalid subdomain gateway hostname %s: %w", hostname, err)
+	}
+	if canonical != hostname {
+		return fmt.Errorf("non-canonical subdomain gateway hostname %s, expected %s", hostname, canonical)
+	}
+	return nil
+}
+
+// HostPolicy returns a function that refuses the hostnames that do not pass
+// [SubdomainGateways.Validate]. It can be used as the HostPolicy of an
+// autocert.Manager from golang.org/x/crypto/acme/autocert, to obtain
+// certificates on demand for the content roots served by the gateways, when
+// wildcard certificates are not available.
+func (s *SubdomainGateways) HostPolicy() func(ctx context.Context, host string) error {
+	return func(_ context.Context, host string) error {
+		return s.Validate(host)
+	}
+}
+
+// WildcardNames returns the sorted wildcard DNS names that a TLS certificate
+// must cover to serve all the subdomain gateways, e.g. *.ipfs.dweb.link and
+// *.ipns.dweb.link. Gateways with wildcard hostnames are skipped, as they
+// cannot be covered by a single certificate.
+func (s *SubdomainGateways) WildcardNames() []string {
+	var names []string
+	for hostname, gw := range s.gateways {
+		if strings.Contains(hostname, "*") {
+			continue
+		}
+		hostname = stripPort(hostname)
+		for _, ns := range []string{"ipfs", "ipns"} {
+			if hasPrefix("/"+ns, gw.Paths...) {
+				names = append(names, "*."+ns+"."+hostname)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
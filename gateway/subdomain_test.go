@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubdomainHostname(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		gw, ns, rootID string
+		hostname       string
+		err            bool
+	}{
+		// CIDv0 is converted to case-insensitive CIDv1
+		{"dweb.link", "ipfs", "QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n", "bafybeif7a7gdklt6hodwdrmwmxnhksctcuav6lfxlcyfz4khzl3qfmvcgu.ipfs.dweb.link", false},
+		// PeerIDs use base36 and libp2p-key
+		{"dweb.link", "ipns", "12D3KooWFB51PRY9BxcXSH6khFXw1BZeszeLDy7C8GciskqCTZn5", "k51qzi5uqu5di608geewp3nqkg0bpujoasmka7ftkyxgcm3fh1aroup0gsdrna.ipns.dweb.link", false},
+		// DNSLink names are inlined, inlined names are kept as-is
+		{"dweb.link", "ipns", "en.wikipedia-on-ipfs.org", "en-wikipedia--on--ipfs-org.ipns.dweb.link", false},
+		{"dweb.link", "ipns", "en-wikipedia--on--ipfs-org", "en-wikipedia--on--ipfs-org.ipns.dweb.link", false},
+		{"dweb.link", "ipfs", "not-a-cid", "", true},
+		{"dweb.link", "foo", "QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n", "", true},
+		{"dweb.link", "ipfs", "", "", true},
+	} {
+		hostname, err := SubdomainHostname(test.gw, test.ns, test.rootID)
+		if test.err {
+			require.Error(t, err, test.rootID)
+			continue
+		}
+		require.NoError(t, err, test.rootID)
+		require.Equal(t, test.hostname, hostname)
+	}
+}
+
+func TestSubdomainGateways(t *testing.T) {
+	t.Parallel()
+
+	gws := NewSubdomainGateways(map[string]*PublicGateway{
+		"dweb.link":     {Paths: []string{"/ipfs", "/ipns"}, UseSubdomains: true},
+		"ipfs.io":       {Paths: []string{"/ipfs", "/ipns"}},
+		"*.example.com": {Paths: []string{"/ipfs"}, UseSubdomains: true},
+	})
+
+	h, ok := gws.Parse("en-wikipedia--on--ipfs-org.ipns.dweb.link:443")
+	require.True(t, ok)
+	require.Equal(t, SubdomainHost{Gateway: "dweb.link:443", Namespace: "ipns", RootID: "en-wikipedia--on--ipfs-org"}, h)
+	require.Equal(t, "/ipns/en.wikipedia-on-ipfs.org", h.Path())
+
+	h, ok = gws.Parse("bafkqaaa.ipfs.gw.example.com")
+	require.True(t, ok)
+	require.Equal(t, "gw.example.com", h.Gateway)
+	require.Equal(t, "/ipfs/bafkqaaa", h.Path())
+
+	// gateways without subdomains and unsupported namespaces are ignored
+	_, ok = gws.Parse("bafkqaaa.ipfs.ipfs.io")
+	require.False(t, ok)
+	_, ok = gws.Parse("k51qzi5uqu5di608geewp3nqkg0bpujoasmka7ftkyxgcm3fh1aroup0gsdrna.ipns.gw.example.com")
+	require.False(t, ok)
+
+	require.Equal(t, []string{"*.ipfs.dweb.link", "*.ipns.dweb.link"}, gws.WildcardNames())
+
+	policy := gws.HostPolicy()
+	for _, test := range []struct {
+		host string
+		ok   bool
+	}{
+		{"dweb.link", true},
+		{"bafybeif7a7gdklt6hodwdrmwmxnhksctcuav6lfxlcyfz4khzl3qfmvcgu.ipfs.dweb.link", true},
+		{"k51qzi5uqu5di608geewp3nqkg0bpujoasmka7ftkyxgcm3fh1aroup0gsdrna.ipns.dweb.link", true},
+		{"en-wikipedia--on--ipfs-org.ipns.dweb.link", true},
+		{"bafkqaaa.ipfs.gw.example.com", true},
+		// non-canonical CIDs are redirected by the gateway
+		{"QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n.ipfs.dweb.link", false},
+		{"en.wikipedia-on-ipfs.org.ipns.dweb.link", false},
+		{"not-a-cid.ipfs.dweb.link", false},
+		{"bafkqaaa.ipfs.ipfs.io", false},
+		{"example.org", false},
+	} {
+		err := policy(context.Background(), test.host)
+		if test.ok {
+			require.NoError(t, err, test.host)
+		} else {
+			require.Error(t, err, test.host)
+		}
+	}
+	require.ErrorIs(t, gws.Validate("example.org"), ErrNotSubdomainHostname)
+}
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	"github.com/ipfs/go-cid"
+)
+
+// Phases of a request which can time out, see [TimeoutError].
+const (
+	TimeoutPhaseNameResolution = "name resolution"
+	TimeoutPhasePathResolution = "path resolution"
+	TimeoutPhaseFirstBlock     = "time to first block"
+	TimeoutPhaseResponse       = "response"
+)
+
+// TimeoutError is returned when a phase of a request exceeds the timeout
+// configured for it in [Config]. It is a [context.DeadlineExceeded] error,
+// which results in a 504 Gateway Timeout response naming the phase.
+type TimeoutError struct {
+	Phase   string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Phase, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// timeoutCause returns the *TimeoutError which ended ctx, if any.
+func timeoutCause(ctx context.Context) (*TimeoutError, bool) {
+	var te *TimeoutError
+	if ctx.Err() != nil && errors.As(context.Cause(ctx), &te) {
+		return te, true
+	}
+	return nil, false
+}
+
+// withTimeoutCause replaces err by the *TimeoutError which ended ctx, so that
+// responses say which phase timed out.
+func withTimeoutCause(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if te, ok := timeoutCause(ctx); ok {
+		return te
+	}
+	return err
+}
+
+// ipfsBackendWithTimeouts bounds the duration of the calls made to an
+// [IPFSBackend] with the per-phase timeouts of [Config].
+type ipfsBackendWithTimeouts struct {
+	backend IPFSBackend
+
+	nameResolution time.Duration
+	pathResolution time.Duration
+	firstBlock     time.Duration
+}
+
+func newIPFSBackendWithTimeouts(backend IPFSBackend, c *Config) *ipfsBackendWithTimeouts {
+	return &ipfsBackendWithTimeouts{
+		backend:        backend,
+		nameResolution: c.NameResolutionTimeout,
+		pathResolution: c.PathResolutionTimeout,
+		firstBlock:     c.FirstBlockTimeout,
+	}
+}
+
+// withPhaseTimeout returns a context which is canceled once the phase has
+// lasted longer than timeout, if timeout is positive.
+func withPhaseTimeout(ctx context.Context, phase string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeout, &TimeoutError{Phase: phase, Timeout: timeout})
+}
+
+// withFirstBlockTimeout returns a context which is canceled if the call
+// fetching content has not returned within the time to first block timeout.
+// Unlike withPhaseTimeout, the context outlives the call, as the content it
+// returns may be fetched lazily while the response is streamed: stop must be
+// called once the call returned.
+func (b *ipfsBackendWithTimeouts) withFirstBlockTimeout(ctx context.Context) (context.Context, func() bool) {
+	if b.firstBlock <= 0 {
+		return ctx, func() bool { return true }
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	cause := &TimeoutError{Phase: TimeoutPhaseFirstBlock, Timeout: b.firstBlock}
+	t := time.AfterFunc(b.firstBlock, func() { cancel(cause) })
+	return ctx, t.Stop
+}
+
+func (b *ipfsBackendWithTimeouts) Get(ctx context.Context, path path.ImmutablePath, ranges ...ByteRange) (ContentPathMetadata, *GetResponse, error) {
+	ctx, stop := b.withFirstBlockTimeout(ctx)
+	md, f, err := b.backend.Get(ctx, path, ranges...)
+	stop()
+	return md, f, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) GetAll(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.Node, error) {
+	ctx, stop := b.withFirstBlockTimeout(ctx)
+	md, n, err := b.backend.GetAll(ctx, path)
+	stop()
+	return md, n, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) GetBlock(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, files.File, error) {
+	ctx, stop := b.withFirstBlockTimeout(ctx)
+	md, f, err := b.backend.GetBlock(ctx, path)
+	stop()
+	return md, f, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) Head(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, *HeadResponse, error) {
+	ctx, stop := b.withFirstBlockTimeout(ctx)
+	md, h, err := b.backend.Head(ctx, path)
+	stop()
+	return md, h, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) GetCAR(ctx context.Context, path path.ImmutablePath, params CarParams) (ContentPathMetadata, io.ReadCloser, error) {
+	ctx, stop := b.withFirstBlockTimeout(ctx)
+	md, rc, err := b.backend.GetCAR(ctx, path, params)
+	stop()
+	return md, rc, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) ResolvePath(ctx context.Context, path path.ImmutablePath) (ContentPathMetadata, error) {
+	ctx, cancel := withPhaseTimeout(ctx, TimeoutPhasePathResolution, b.pathResolution)
+	defer cancel()
+	md, err := b.backend.ResolvePath(ctx, path)
+	return md, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) IsCached(ctx context.Context, path path.Path) bool {
+	return b.backend.IsCached(ctx, path)
+}
+
+func (b *ipfsBackendWithTimeouts) GetIPNSRecord(ctx context.Context, c cid.Cid) ([]byte, error) {
+	ctx, cancel := withPhaseTimeout(ctx, TimeoutPhaseNameResolution, b.nameResolution)
+	defer cancel()
+	rec, err := b.backend.GetIPNSRecord(ctx, c)
+	return rec, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) ResolveMutable(ctx context.Context, p path.Path) (path.ImmutablePath, time.Duration, time.Time, error) {
+	ctx, cancel := withPhaseTimeout(ctx, TimeoutPhaseNameResolution, b.nameResolution)
+	defer cancel()
+	ip, ttl, lastMod, err := b.backend.ResolveMutable(ctx, p)
+	return ip, ttl, lastMod, withTimeoutCause(ctx, err)
+}
+
+func (b *ipfsBackendWithTimeouts) GetDNSLinkRecord(ctx context.Context, fqdn string) (path.Path, error) {
+	ctx, cancel := withPhaseTimeout(ctx, TimeoutPhaseNameResolution, b.nameResolution)
+	defer cancel()
+	p, err := b.backend.GetDNSLinkRecord(ctx, fqdn)
+	return p, withTimeoutCause(ctx, err)
+}
+
+var _ IPFSBackend = (*ipfsBackendWithTimeouts)(nil)
+var _ WithContextHint = (*ipfsBackendWithTimeouts)(nil)
+
+func (b *ipfsBackendWithTimeouts) WrapContextForRequest(ctx context.Context) context.Context {
+	if withCtxWrap, ok := b.backend.(WithContextHint); ok {
+		return withCtxWrap.WrapContextForRequest(ctx)
+	}
+	return ctx
+}
+
+var _ WithIPNSPublishing = (*ipfsBackendWithTimeouts)(nil)
+
+func (b *ipfsBackendWithTimeouts) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	return putIPNSRecord(ctx, b.backend, name, record)
+}
+
+var _ WithDeterministicCAR = (*ipfsBackendWithTimeouts)(nil)
+
+func (b *ipfsBackendWithTimeouts) IsDeterministicCAR(params CarParams) bool {
+	if withDeterministicCAR, ok := b.backend.(WithDeterministicCAR); ok {
+		return withDeterministicCAR.IsDeterministicCAR(params)
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/path"
+	"github.com/stretchr/testify/require"
+)
+
+// stallingBackend stalls the calls of a phase until their context is done.
+type stallingBackend struct {
+	IPFSBackend
+	stall string
+}
+
+func (sb *stallingBackend) wait(ctx context.Context, phase string) error {
+	if sb.stall != phase {
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (sb *stallingBackend) Get(ctx context.Context, p path.ImmutablePath, ranges ...ByteRange) (ContentPathMetadata, *GetResponse, error) {
+	if err := sb.wait(ctx, TimeoutPhaseFirstBlock); err != nil {
+		return ContentPathMetadata{}, nil, err
+	}
+	return sb.IPFSBackend.Get(ctx, p, ranges...)
+}
+
+func (sb *stallingBackend) ResolveMutable(ctx context.Context, p path.Path) (path.ImmutablePath, time.Duration, time.Time, error) {
+	if err := sb.wait(ctx, TimeoutPhaseNameResolution); err != nil {
+		return path.ImmutablePath{}, 0, time.Time{}, err
+	}
+	return sb.IPFSBackend.ResolveMutable(ctx, p)
+}
+
+func (sb *stallingBackend) ResolvePath(ctx context.Context, p path.ImmutablePath) (ContentPathMetadata, error) {
+	if err := sb.wait(ctx, TimeoutPhasePathResolution); err != nil {
+		return ContentPathMetadata{}, err
+	}
+	return sb.IPFSBackend.ResolvePath(ctx, p)
+}
+
+func TestPhaseTimeouts(t *testing.T) {
+	t.Parallel()
+
+	backend, root := newMockBackend(t, "fixtures.car")
+	p, err := path.Join(path.FromCid(root), "subdir", "fnord")
+	require.NoError(t, err)
+	backend.namesys["/ipns/example.net"] = newMockNamesysItem(p, 0)
+
+	config := Config{
+		DeserializedResponses: true,
+		NameResolutionTimeout: 20 * time.Millisecond,
+		PathResolutionTimeout: 30 * time.Millisecond,
+		FirstBlockTimeout:     40 * time.Millisecond,
+	}
+
+	for _, tc := range []struct {
+		phase       string
+		config      Config
+		url         string
+		ifNoneMatch string
+	}{
+		{TimeoutPhaseNameResolution, config, "/ipns/example.net", ""},
+		{TimeoutPhaseFirstBlock, config, "/ipfs/" + root.String() + "/subdir/fnord", ""},
+		// paths are resolved on their own to check If-None-Match
+		{TimeoutPhasePathResolution, config, "/ipfs/" + root.String() + "/subdir/fnord", `"etag"`},
+		{TimeoutPhaseResponse, Config{DeserializedResponses: true, ResponseTimeout: 50 * time.Millisecond}, "/ipfs/" + root.String() + "/subdir/fnord", ""},
+	} {
+		tc := tc
+		t.Run(tc.phase, func(t *testing.T) {
+			t.Parallel()
+
+			stall := tc.phase
+			if stall == TimeoutPhaseResponse {
+				stall = TimeoutPhaseFirstBlock
+			}
+			ts := newTestServerWithConfig(t, &stallingBackend{IPFSBackend: backend, stall: stall}, tc.config)
+
+			req := mustNewRequest(t, http.MethodGet, ts.URL+tc.url, nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			res := mustDoWithoutRedirect(t, req)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusGatewayTimeout, res.StatusCode, string(body))
+			require.Contains(t, string(body), tc.phase+" timed out after")
+		})
+	}
+
+	// without stalls, the timeouts do not get in the way
+	ts := newTestServerWithConfig(t, backend, config)
+	res := mustDoWithoutRedirect(t, mustNewRequest(t, http.MethodGet, ts.URL+"/ipfs/"+root.String()+"/subdir/fnord", nil))
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "fnord", string(body))
+}
@@ -184,7 +184,7 @@ func (mb *mockBackend) GetDNSLinkRecord(ctx context.Context, hostname string) (p
 			return nil, err
 		}
 		res, err := mb.namesys.Resolve(ctx, p, namesys.ResolveWithDepth(1))
-		if err == namesys.ErrResolveRecursion {
+		if errors.Is(err, namesys.ErrResolveRecursion) {
 			err = nil
 		}
 		p = res.Path
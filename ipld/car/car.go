@@ -0,0 +1,220 @@
+// Package car provides helpers to export IPLD DAGs as CAR streams.
+package car
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-unixfsnode"
+	"github.com/ipfs/go-unixfsnode/data"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/ipld/go-car/v2/storage"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/ipld/go-ipld-prime/schema"
+	"github.com/ipld/go-ipld-prime/traversal"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	selectorparse "github.com/ipld/go-ipld-prime/traversal/selector/parse"
+)
+
+// DagScope is the part of the DAG under the root to export, as per the
+// [Trustless Gateway] specification.
+//
+// [Trustless Gateway]: https://specs.ipfs.tech/http-gateways/trustless-gateway/
+type DagScope string
+
+const (
+	// DagScopeAll exports the whole DAG.
+	DagScopeAll DagScope = "all"
+	// DagScopeEntity exports the blocks needed to read the UnixFS entity of
+	// the root: the bytes of a file (or of its entity-bytes range), the whole
+	// of a sharded directory, or the root block alone for other entities.
+	DagScopeEntity DagScope = "entity"
+	// DagScopeBlock only exports the root block.
+	DagScopeBlock DagScope = "block"
+)
+
+// DagByteRange is the entity-bytes range of a file exported with
+// [DagScopeEntity]. Negative values count from the end of the file, a nil To
+// reads until the end of the file. Both ends are inclusive.
+type DagByteRange struct {
+	From int64
+	To   *int64
+}
+
+// WriteDeterministic writes the scope of the DAG rooted at root to w as a
+// CARv1 stream. An empty scope is [DagScopeAll], and entityBytes, only used
+// with [DagScopeEntity], may be nil to export the whole file. The output only
+// depends on the DAG and the parameters, which makes it suitable to be
+// content-addressed, cached or signed:
+//
+//   - the header only lists root,
+//   - blocks are written in depth-first order, following links in the order
+//     they appear in their parent block, and in the order of the file data,
+//   - every block is written only once, at its first occurrence.
+//
+// Blocks are retrieved from ng as the traversal progresses, an error is
+// returned if any of them can't be found.
+func WriteDeterministic(ctx context.Context, ng format.NodeGetter, root cid.Cid, scope DagScope, entityBytes *DagByteRange, w io.Writer) error {
+	cw, err := storage.NewWritable(w, []cid.Cid{root}, carv2.WriteAsCarV1(true), carv2.AllowDuplicatePuts(false))
+	if err != nil {
+		return err
+	}
+
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.TrustedStorage = true
+	unixfsnode.AddUnixFSReificationToLinkSystem(&lsys)
+	lsys.StorageReadOpener = func(lctx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+		cidLink, ok := lnk.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("invalid link type for loading: %v", lnk)
+		}
+
+		nd, err := ng.Get(lctx.Ctx, cidLink.Cid)
+		if err != nil {
+			return nil, err
+		}
+		if err := cw.Put(lctx.Ctx, nd.Cid().KeyString(), nd.RawData()); err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(nd.RawData()), nil
+	}
+
+	rootLink := cidlink.Link{Cid: root}
+	lctx := ipld.LinkContext{Ctx: ctx}
+	switch scope {
+	case DagScopeBlock:
+		_, err := lsys.LoadRaw(lctx, rootLink)
+		return err
+	case DagScopeEntity:
+		return walkEntity(lctx, &lsys, rootLink, entityBytes)
+	case DagScopeAll, "":
+	default:
+		return fmt.Errorf("unsupported dag-scope %q", scope)
+	}
+
+	chooser := dagpb.AddSupportToChooser(func(ipld.Link, ipld.LinkContext) (ipld.NodePrototype, error) {
+		return basicnode.Prototype.Any, nil
+	})
+
+	np, err := chooser(rootLink, lctx)
+	if err != nil {
+		return err
+	}
+	rootNode, err := lsys.Load(lctx, rootLink, np)
+	if err != nil {
+		return err
+	}
+
+	sel, err := selector.ParseSelector(selectorparse.CommonSelector_ExploreAllRecursively)
+	if err != nil {
+		return err
+	}
+
+	progress := traversal.Progress{
+		Cfg: &traversal.Config{
+			Ctx:                            ctx,
+			LinkSystem:                     lsys,
+			LinkTargetNodePrototypeChooser: chooser,
+			LinkVisitOnlyOnce:              true,
+		},
+	}
+	return progress.WalkMatching(rootNode, sel, func(traversal.Progress, datamodel.Node) error {
+		return nil
+	})
+}
+
+// walkEntity loads the blocks of the UnixFS entity at root, see
+// [DagScopeEntity].
+func walkEntity(lctx ipld.LinkContext, lsys *ipld.LinkSystem, root cidlink.Link, entityBytes *DagByteRange) error {
+	chooser := dagpb.AddSupportToChooser(func(lnk ipld.Link, lnkCtx ipld.LinkContext) (ipld.NodePrototype, error) {
+		if tlnkNd, ok := lnkCtx.LinkNode.(schema.TypedLinkNode); ok {
+			return tlnkNd.LinkTargetNodePrototype(), nil
+		}
+		return basicnode.Prototype.Any, nil
+	})
+	np, err := chooser(root, lctx)
+	if err != nil {
+		return err
+	}
+	rootNode, err := lsys.Load(lctx, root, np)
+	if err != nil {
+		return err
+	}
+
+	pbn, ok := rootNode.(dagpb.PBNode)
+	if !ok || !pbn.FieldData().Exists() {
+		// not UnixFS, the root block is the whole entity
+		return nil
+	}
+	fsData, err := data.DecodeUnixFSData(pbn.Data.Must().Bytes())
+	if err != nil {
+		return nil
+	}
+
+	switch fsData.FieldDataType().Int() {
+	case data.Data_HAMTShard:
+		_, err := lsys.KnownReifiers["unixfs-preload"](lctx, rootNode, lsys)
+		return err
+	case data.Data_File:
+	default:
+		// directories, symlinks and other types are a single block
+		return nil
+	}
+
+	nd, err := unixfsnode.Reify(lctx, rootNode, lsys)
+	if err != nil {
+		return err
+	}
+	lbn, ok := nd.(datamodel.LargeBytesNode)
+	if !ok {
+		return errors.New("could not process file since it did not present as large bytes")
+	}
+	f, err := lbn.AsLargeBytes()
+	if err != nil {
+		return err
+	}
+
+	if entityBytes == nil {
+		entityBytes = &DagByteRange{From: 0}
+	}
+	var size int64
+	if entityBytes.From < 0 || (entityBytes.To != nil && *entityBytes.To < 0) {
+		if size, err = f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	from := entityBytes.From
+	if from < 0 {
+		from = max(size+from, 0)
+	}
+	if _, err := f.Seek(from, io.SeekStart); err != nil {
+		return err
+	}
+	if entityBytes.To == nil {
+		_, err = io.Copy(io.Discard, f)
+		return err
+	}
+
+	to := *entityBytes.To
+	if to < 0 {
+		to += size
+	}
+	if to < from {
+		return errors.New("tried to read less than zero bytes")
+	}
+	_, err = io.CopyN(io.Discard, f, 1+to-from)
+	if errors.Is(err, io.EOF) {
+		// the range goes past the end of the file
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package car
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	chunker "github.com/ipfs/boxo/chunker"
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	mdtest "github.com/ipfs/boxo/ipld/merkledag/test"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	"github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
+	h "github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
+	cid "github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDeterministic(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+
+	shared := dag.NewRawNode([]byte("shared"))
+	leaf := dag.NewRawNode([]byte("leaf"))
+	inner := &dag.ProtoNode{}
+	require.NoError(t, inner.AddNodeLink("shared", shared))
+	require.NoError(t, inner.AddNodeLink("leaf", leaf))
+	root := &dag.ProtoNode{}
+	require.NoError(t, root.AddNodeLink("a", shared))
+	require.NoError(t, root.AddNodeLink("b", inner))
+	require.NoError(t, ds.AddMany(ctx, []format.Node{shared, leaf, inner, root}))
+
+	var first, second bytes.Buffer
+	require.NoError(t, WriteDeterministic(ctx, ds, root.Cid(), DagScopeAll, nil, &first))
+	require.NoError(t, WriteDeterministic(ctx, ds, root.Cid(), DagScopeAll, nil, &second))
+	require.Equal(t, first.Bytes(), second.Bytes(), "output must be byte-for-byte identical")
+
+	br, err := carv2.NewBlockReader(&first)
+	require.NoError(t, err)
+	require.Equal(t, []cid.Cid{root.Cid()}, br.Roots)
+
+	require.Equal(t, []cid.Cid{root.Cid(), shared.Cid(), inner.Cid(), leaf.Cid()}, readBlocks(t, br), "blocks must be in DFS order without duplicates")
+}
+
+func readBlocks(t *testing.T, br *carv2.BlockReader) []cid.Cid {
+	var got []cid.Cid
+	for {
+		blk, err := br.Next()
+		if err == io.EOF {
+			return got
+		}
+		require.NoError(t, err)
+		got = append(got, blk.Cid())
+	}
+}
+
+func TestWriteDeterministicScope(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+
+	// a file of 4 raw leaves of 256 bytes under a single root
+	data := make([]byte, 1024)
+	for i := range data {
+		data[i] = byte(i + i/256)
+	}
+	dbp := h.DagBuilderParams{
+		Dagserv:   ds,
+		Maxlinks:  h.DefaultLinksPerBlock,
+		RawLeaves: true,
+	}
+	db, err := dbp.New(chunker.NewSizeSplitter(bytes.NewReader(data), 256))
+	require.NoError(t, err)
+	file, err := balanced.Layout(db)
+	require.NoError(t, err)
+	leaves := file.Links()
+	require.Len(t, leaves, 4)
+
+	to := func(i int64) *int64 { return &i }
+	for _, tc := range []struct {
+		name        string
+		scope       DagScope
+		entityBytes *DagByteRange
+		expected    []cid.Cid
+	}{
+		{"block", DagScopeBlock, nil, nil},
+		{"entity", DagScopeEntity, nil, []cid.Cid{leaves[0].Cid, leaves[1].Cid, leaves[2].Cid, leaves[3].Cid}},
+		{"entity range", DagScopeEntity, &DagByteRange{From: 300, To: to(600)}, []cid.Cid{leaves[1].Cid, leaves[2].Cid}},
+		{"entity suffix", DagScopeEntity, &DagByteRange{From: -100}, []cid.Cid{leaves[3].Cid}},
+		{"entity past the end", DagScopeEntity, &DagByteRange{From: 1000, To: to(5000)}, []cid.Cid{leaves[3].Cid}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var first, second bytes.Buffer
+			require.NoError(t, WriteDeterministic(ctx, ds, file.Cid(), tc.scope, tc.entityBytes, &first))
+			require.NoError(t, WriteDeterministic(ctx, ds, file.Cid(), tc.scope, tc.entityBytes, &second))
+			require.Equal(t, first.Bytes(), second.Bytes(), "output must be byte-for-byte identical")
+
+			br, err := carv2.NewBlockReader(&first)
+			require.NoError(t, err)
+			require.Equal(t, []cid.Cid{file.Cid()}, br.Roots)
+			require.Equal(t, append([]cid.Cid{file.Cid()}, tc.expected...), readBlocks(t, br))
+		})
+	}
+
+	t.Run("entity of a directory", func(t *testing.T) {
+		dir := ft.EmptyDirNode()
+		require.NoError(t, dir.AddNodeLink("file", file))
+		require.NoError(t, ds.Add(ctx, dir))
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteDeterministic(ctx, ds, dir.Cid(), DagScopeEntity, nil, &buf))
+		br, err := carv2.NewBlockReader(&buf)
+		require.NoError(t, err)
+		require.Equal(t, []cid.Cid{dir.Cid()}, readBlocks(t, br))
+	})
+}
+
+func TestWriteDeterministicMissingBlock(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+
+	root := &dag.ProtoNode{}
+	require.NoError(t, root.AddNodeLink("missing", dag.NewRawNode([]byte("missing"))))
+	require.NoError(t, ds.Add(ctx, root))
+
+	require.Error(t, WriteDeterministic(ctx, ds, root.Cid(), DagScopeAll, nil, io.Discard))
+}
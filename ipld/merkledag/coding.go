@@ -78,7 +78,7 @@ func fromImmutableNode(encoded *immutableProtoNode) *ProtoNode {
 }
 
 func (n *ProtoNode) marshalImmutable() (*immutableProtoNode, error) {
-	links := n.Links()
+	links := n.sortedLinks()
 	nd, err := qp.BuildMap(dagpb.Type.PBNode, 2, func(ma ipld.MapAssembler) {
 		qp.MapEntry(ma, "Links", qp.List(int64(len(links)), func(la ipld.ListAssembler) {
 			for _, link := range links {
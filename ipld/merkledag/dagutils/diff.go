@@ -106,8 +106,8 @@ func Diff(ctx context.Context, ds ipld.DAGService, a, b ipld.Node) ([]*Change, e
 		return []*Change{}, nil
 	}
 
-	cleanA, okA := a.Copy().(*dag.ProtoNode)
-	cleanB, okB := b.Copy().(*dag.ProtoNode)
+	pbA, okA := a.(*dag.ProtoNode)
+	pbB, okB := b.(*dag.ProtoNode)
 
 	linksA := a.Links()
 	linksB := b.Links()
@@ -116,6 +116,12 @@ func Diff(ctx context.Context, ds ipld.DAGService, a, b ipld.Node) ([]*Change, e
 		return []*Change{{Type: Mod, Before: a.Cid(), After: b.Cid()}}, nil
 	}
 
+	// The copies only track the links left to compare, they never escape.
+	cleanA := dag.AcquireCopy(pbA)
+	defer cleanA.Release()
+	cleanB := dag.AcquireCopy(pbB)
+	defer cleanB.Release()
+
 	var out []*Change
 	for _, linkA := range linksA {
 		linkB, _, err := b.ResolveLink([]string{linkA.Name})
@@ -0,0 +1,232 @@
+package merkledag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	bserv "github.com/ipfs/boxo/blockservice"
+	cid "github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	legacy "github.com/ipfs/go-ipld-legacy"
+)
+
+// NodeResult is the result of fetching one of the nodes requested with
+// [GetManyResults].
+//
+// Err is nil when Node was fetched. Otherwise it is a [format.ErrNotFound] if
+// the node could not be found, a *[FetchError] if fetching it failed, for
+// example because the context was canceled, or a *[DecodeError] if the node
+// could not be decoded. Only the nodes with a FetchError are worth retrying.
+//
+// The blockservice does not tell why it did not deliver a block, so the nodes
+// it did not deliver get a FetchError wrapping a [format.ErrNotFound]: they
+// are both retryable and reported by [format.IsNotFound].
+type NodeResult struct {
+	Cid  cid.Cid
+	Node format.Node
+	Err  error
+}
+
+// FetchError is the error of the nodes which could not be fetched, because of
+// the context or of the NodeGetter.
+type FetchError struct {
+	Cid cid.Cid
+	Err error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("failed to fetch %s: %s", e.Cid, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is the error of the nodes which were fetched but could not be
+// decoded.
+type DecodeError struct {
+	Cid cid.Cid
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s: %s", e.Cid, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// getManyOptions represent the parameters of GetManyResults.
+type getManyOptions struct {
+	Ordered bool
+}
+
+// GetManyOption is a setter for getManyOptions.
+type GetManyOption func(*getManyOptions)
+
+// Ordered makes GetManyResults return the results in the order of the
+// requested CIDs, instead of as soon as they are available.
+func Ordered() GetManyOption {
+	return func(opts *getManyOptions) {
+		opts.Ordered = true
+	}
+}
+
+// GetManyResults gets many nodes at once like GetMany, but returns a result
+// for every requested CID, duplicates excepted, including the ones which could
+// not be fetched, so that callers can retry the failed subset.
+//
+// Nodes are fetched as a batch from the blockservice of the DAGServices and
+// sessions of this package. Other NodeGetters are used through their GetMany
+// method, and their errors are reported as FetchErrors, unless they are a
+// [format.ErrNotFound].
+func GetManyResults(ctx context.Context, ng format.NodeGetter, keys []cid.Cid, options ...GetManyOption) <-chan *NodeResult {
+	var opts getManyOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	keys = dedupKeys(keys)
+	var out <-chan *NodeResult
+	switch ng := unwrapCombo(ng).(type) {
+	case *dagService:
+		out = getResultsFromBG(ctx, ng.Blocks, keys, ng.decoder)
+	case *sesGetter:
+		out = getResultsFromBG(ctx, ng.bs, keys, ng.decoder)
+	default:
+		out = getResultsFromNodeGetter(ctx, ng, keys)
+	}
+	if opts.Ordered {
+		out = orderResults(keys, out)
+	}
+	return out
+}
+
+func unwrapCombo(ng format.NodeGetter) format.NodeGetter {
+	for {
+		cs, ok := ng.(*ComboService)
+		if !ok {
+			return ng
+		}
+		ng = cs.Read
+	}
+}
+
+func getResultsFromBG(ctx context.Context, bs bserv.BlockGetter, keys []cid.Cid, decoder *legacy.Decoder) <-chan *NodeResult {
+	// Every CID gets a single result, so sending never blocks.
+	out := make(chan *NodeResult, len(keys))
+	pending := cid.NewSet()
+	for _, c := range keys {
+		pending.Add(c)
+	}
+	blocks := bs.GetBlocks(ctx, keys)
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case b, ok := <-blocks:
+				if !ok {
+					// the block may be missing, or the exchange may have
+					// failed to fetch it
+					sendMissingResults(ctx, out, keys, pending, errNotDelivered)
+					return
+				}
+				c := b.Cid()
+				if !pending.Has(c) {
+					continue
+				}
+				pending.Remove(c)
+
+				nd, err := decoder.DecodeNode(ctx, b)
+				if err != nil {
+					out <- &NodeResult{Cid: c, Err: &DecodeError{Cid: c, Err: err}}
+					continue
+				}
+				out <- &NodeResult{Cid: c, Node: nd}
+
+			case <-ctx.Done():
+				sendMissingResults(ctx, out, keys, pending, nil)
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func getResultsFromNodeGetter(ctx context.Context, ng format.NodeGetter, keys []cid.Cid) <-chan *NodeResult {
+	out := make(chan *NodeResult, len(keys))
+	pending := cid.NewSet()
+	for _, c := range keys {
+		pending.Add(c)
+	}
+	nds := ng.GetMany(ctx, keys)
+
+	go func() {
+		defer close(out)
+		var err error
+		for opt := range nds {
+			if opt.Err != nil {
+				err = opt.Err
+				continue
+			}
+			c := opt.Node.Cid()
+			if !pending.Has(c) {
+				continue
+			}
+			pending.Remove(c)
+			out <- &NodeResult{Cid: c, Node: opt.Node}
+		}
+		sendMissingResults(ctx, out, keys, pending, err)
+	}()
+	return out
+}
+
+// errNotDelivered is given to sendMissingResults for the CIDs the blockservice
+// did not deliver without telling why.
+var errNotDelivered = errors.New("block not delivered")
+
+// sendMissingResults sends the results of the pending CIDs, which were not
+// returned by the NodeGetter, which may have reported err.
+func sendMissingResults(ctx context.Context, out chan<- *NodeResult, keys []cid.Cid, pending *cid.Set, err error) {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	for _, c := range keys {
+		if !pending.Has(c) {
+			continue
+		}
+		switch {
+		case err == errNotDelivered:
+			out <- &NodeResult{Cid: c, Err: &FetchError{Cid: c, Err: format.ErrNotFound{Cid: c}}}
+		case err == nil || format.IsNotFound(err):
+			out <- &NodeResult{Cid: c, Err: format.ErrNotFound{Cid: c}}
+		default:
+			out <- &NodeResult{Cid: c, Err: &FetchError{Cid: c, Err: err}}
+		}
+	}
+}
+
+// orderResults returns the results of in in the order of keys.
+func orderResults(keys []cid.Cid, in <-chan *NodeResult) <-chan *NodeResult {
+	out := make(chan *NodeResult, len(keys))
+	go func() {
+		defer close(out)
+		held := make(map[cid.Cid]*NodeResult)
+		for r := range in {
+			held[r.Cid] = r
+			for len(keys) > 0 {
+				next, ok := held[keys[0]]
+				if !ok {
+					break
+				}
+				delete(held, keys[0])
+				keys = keys[1:]
+				out <- next
+			}
+		}
+	}()
+	return out
+}
@@ -100,6 +100,11 @@ func (n *dagService) GetLinks(ctx context.Context, c cid.Cid) ([]*format.Link, e
 	return node.Links(), nil
 }
 
+// BlockService returns the block service the nodes are stored in.
+func (n *dagService) BlockService() bserv.BlockService {
+	return n.Blocks
+}
+
 func (n *dagService) Remove(ctx context.Context, c cid.Cid) error {
 	return n.Blocks.DeleteBlock(ctx, c)
 }
@@ -111,12 +116,7 @@ func (n *dagService) Remove(ctx context.Context, c cid.Cid) error {
 // not have been removed.
 func (n *dagService) RemoveMany(ctx context.Context, cids []cid.Cid) error {
 	// TODO(#4608): make this batch all the way down.
-	for _, c := range cids {
-		if err := n.Blocks.DeleteBlock(ctx, c); err != nil {
-			return err
-		}
-	}
-	return nil
+	return n.Blocks.DeleteBlocks(ctx, cids)
 }
 
 // GetLinksDirect creates a function to get the links for a node, from
@@ -224,20 +224,24 @@ func FetchGraphWithDepthLimit(ctx context.Context, root cid.Cid, depthLim int, s
 //
 // This method may not return all requested nodes (and may or may not return an
 // error indicating that it failed to do so. It is up to the caller to verify
-// that it received all nodes.
+// that it received all nodes. See [GetManyResults] for a result per node.
 func (n *dagService) GetMany(ctx context.Context, keys []cid.Cid) <-chan *format.NodeOption {
 	return getNodesFromBG(ctx, n.Blocks, keys, n.decoder)
 }
 
+// dedupKeys removes the duplicates of keys, keeping their order.
 func dedupKeys(keys []cid.Cid) []cid.Cid {
 	set := cid.NewSet()
+	var deduped []cid.Cid
 	for _, c := range keys {
-		set.Add(c)
+		if set.Visit(c) {
+			deduped = append(deduped, c)
+		}
 	}
-	if set.Len() == len(keys) {
+	if len(deduped) == len(keys) {
 		return keys
 	}
-	return set.Keys()
+	return deduped
 }
 
 func getNodesFromBG(ctx context.Context, bs bserv.BlockGetter, keys []cid.Cid, decoder *legacy.Decoder) <-chan *format.NodeOption {
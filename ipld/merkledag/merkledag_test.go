@@ -1228,3 +1228,103 @@ func mkNodeWithChildren(getChild func() *ProtoNode, width int) *ProtoNode {
 
 	return cur
 }
+
+// nodeGetter hides the type of a NodeGetter.
+type nodeGetter struct {
+	ipld.NodeGetter
+}
+
+func TestGetManyResults(t *testing.T) {
+	ctx := context.Background()
+
+	bs := dstest.Bserv()
+	srv := NewDAGService(bs)
+	a := NodeWithData([]byte("a"))
+	b := NodeWithData([]byte("b"))
+	if err := srv.AddMany(ctx, []ipld.Node{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	missing := NodeWithData([]byte("missing")).Cid()
+
+	garbage := []byte("not a dag-pb node")
+	badCid, err := cid.Prefix{Version: 1, Codec: cid.DagProtobuf, MhType: mh.SHA2_256, MhLength: -1}.Sum(garbage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err := blocks.NewBlockWithCid(garbage, badCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.AddBlock(ctx, bad); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []cid.Cid{b.Cid(), missing, badCid, a.Cid(), b.Cid()}
+	check := func(t *testing.T, ng ipld.NodeGetter) {
+		var got []cid.Cid
+		for r := range GetManyResults(ctx, ng, keys, Ordered()) {
+			got = append(got, r.Cid)
+			switch r.Cid {
+			case a.Cid(), b.Cid():
+				if r.Err != nil {
+					t.Fatal(r.Err)
+				}
+				if !r.Node.Cid().Equals(r.Cid) {
+					t.Fatal("got wrong node")
+				}
+			case missing:
+				// the blockservice does not tell missing blocks from failed
+				// fetches
+				var fetchErr *FetchError
+				if !ipld.IsNotFound(r.Err) || !errors.As(r.Err, &fetchErr) {
+					t.Fatalf("expected not found fetch error, got %v", r.Err)
+				}
+			case badCid:
+				var decodeErr *DecodeError
+				if !errors.As(r.Err, &decodeErr) {
+					t.Fatalf("expected decode error, got %v", r.Err)
+				}
+			}
+		}
+		want := []cid.Cid{b.Cid(), missing, badCid, a.Cid()}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("expected results for %v, got %v", want, got)
+		}
+	}
+	t.Run("DAGService", func(t *testing.T) { check(t, srv) })
+	t.Run("Session", func(t *testing.T) { check(t, srv.Session(ctx)) })
+
+	t.Run("Canceled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		var got int
+		for r := range GetManyResults(cctx, srv, []cid.Cid{missing}) {
+			got++
+			var fetchErr *FetchError
+			if !errors.As(r.Err, &fetchErr) || !errors.Is(r.Err, context.Canceled) {
+				t.Fatalf("expected fetch error, got %v", r.Err)
+			}
+		}
+		if got != 1 {
+			t.Fatalf("expected 1 result, got %d", got)
+		}
+	})
+
+	t.Run("Other NodeGetter", func(t *testing.T) {
+		var got int
+		for r := range GetManyResults(ctx, nodeGetter{srv}, []cid.Cid{a.Cid(), missing}) {
+			got++
+			if r.Cid == missing {
+				var fetchErr *FetchError
+				if !errors.As(r.Err, &fetchErr) {
+					t.Fatalf("expected fetch error, got %v", r.Err)
+				}
+			} else if r.Err != nil {
+				t.Fatal(r.Err)
+			}
+		}
+		if got != 2 {
+			t.Fatalf("expected 2 results, got %d", got)
+		}
+	})
+}
@@ -65,6 +65,9 @@ type ProtoNode struct {
 
 	// builder specifies cid version and hashing function
 	builder cid.Builder
+
+	// pooled is set for nodes which can be released to the pool, see pool.go
+	pooled bool
 }
 
 var v0CidPrefix = cid.Prefix{
@@ -175,9 +178,14 @@ func (n *ProtoNode) AddNodeLink(name string, that format.Node) error {
 	}
 
 	lnk.Name = name
+	if err := checkLink(lnk); err != nil {
+		return err
+	}
 
-	n.AddRawLink(name, lnk)
-
+	// lnk is ours, no need to copy it like AddRawLink does
+	n.links = append(n.links, lnk)
+	n.linksDirty = true // needs a sort
+	n.encoded = nil
 	return nil
 }
 
@@ -487,13 +495,18 @@ func (n *ProtoNode) Multihash() mh.Multihash {
 
 // Links returns a copy of the node's links.
 func (n *ProtoNode) Links() []*format.Link {
+	return append([]*format.Link(nil), n.sortedLinks()...)
+}
+
+// sortedLinks returns the node's links, without copying them.
+func (n *ProtoNode) sortedLinks() []*format.Link {
 	if n.linksDirty {
 		// there was a mutation involving links, make sure we sort
 		sort.Stable(LinkSlice(n.links))
 		n.linksDirty = false
 		n.encoded = nil
 	}
-	return append([]*format.Link(nil), n.links...)
+	return n.links
 }
 
 // SetLinks replaces the node links with a copy of the provided links. Sorting
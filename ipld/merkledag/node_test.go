@@ -164,6 +164,47 @@ func TestNodeCopy(t *testing.T) {
 	}
 }
 
+func TestProtoNodePool(t *testing.T) {
+	nd := &ProtoNode{}
+	nd.SetLinks([]*ipld.Link{
+		{Name: "a", Cid: sampleCid},
+		{Name: "b", Cid: sampleCid},
+	})
+	nd.SetData([]byte("testing"))
+
+	pnd := AcquireCopy(nd)
+	if !pnd.Cid().Equals(nd.Cid()) {
+		t.Fatal("pooled copy should have the same CID")
+	}
+	raw := append([]byte(nil), pnd.RawData()...)
+	rawRef := pnd.RawData()
+	links := pnd.Links()
+	pnd.Release()
+	pnd.Release() // releasing twice does nothing
+
+	if !bytes.Equal(raw, rawRef) || len(links) != 2 || links[0].Name != "a" {
+		t.Fatal("data returned by a node must stay valid after Release")
+	}
+
+	// nodes from the pool are empty
+	for i := 0; i < 10; i++ {
+		empty := AcquireProtoNode()
+		if len(empty.Links()) != 0 || empty.Data() != nil {
+			t.Fatal("acquired node should be empty")
+		}
+		if !empty.Cid().Equals((&ProtoNode{}).Cid()) {
+			t.Fatal("acquired node should have the CID of an empty node")
+		}
+		defer empty.Release()
+	}
+
+	// releasing a node not from the pool does nothing
+	nd.Release()
+	if len(nd.Links()) != 2 {
+		t.Fatal("Release should not modify nodes which are not from the pool")
+	}
+}
+
 func TestJsonRoundtrip(t *testing.T) {
 	nd := new(ProtoNode)
 	nd.SetLinks([]*ipld.Link{
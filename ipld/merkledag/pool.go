@@ -0,0 +1,63 @@
+package merkledag
+
+import (
+	"sort"
+	"sync"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// protoNodePool recycles the ProtoNodes, and their link slices, of code
+// building many short lived nodes, such as importers.
+var protoNodePool = sync.Pool{
+	New: func() any { return new(ProtoNode) },
+}
+
+// AcquireProtoNode returns an empty ProtoNode from a pool. It behaves like a
+// node created with new(ProtoNode), and may be given back to the pool with
+// [ProtoNode.Release] once it is not used anymore.
+func AcquireProtoNode() *ProtoNode {
+	n := protoNodePool.Get().(*ProtoNode)
+	n.pooled = true
+	return n
+}
+
+// AcquireCopy is like [ProtoNode.Copy], but the copy is taken from the pool
+// used by [AcquireProtoNode].
+func AcquireCopy(n *ProtoNode) *ProtoNode {
+	nnode := AcquireProtoNode()
+	if len(n.data) > 0 {
+		nnode.data = append(nnode.data, n.data...)
+	}
+	if len(n.links) > 0 {
+		nnode.links = append(nnode.links, n.links...)
+		sort.Stable(LinkSlice(nnode.links))
+	}
+	nnode.builder = n.builder
+	return nnode
+}
+
+// Release gives a node obtained from [AcquireProtoNode] or [AcquireCopy] back
+// to the pool. It does nothing for other nodes, or if the node was already
+// released.
+//
+// The caller must own the node: it must not be used after Release, and it
+// must not be referenced anymore by anything else, such as a DAGService
+// batching nodes until they are committed. The data given to SetData, the
+// links returned by Links and the encoded bytes returned by RawData are not
+// reused and stay valid.
+func (n *ProtoNode) Release() {
+	if !n.pooled {
+		return
+	}
+
+	clear(n.links)
+	*n = ProtoNode{links: n.links[:0], cached: cid.Undef}
+	if cap(n.links) > maxPooledLinks {
+		n.links = nil
+	}
+	protoNodePool.Put(n)
+}
+
+// maxPooledLinks bounds the capacity of the link slices kept in the pool.
+const maxPooledLinks = 1 << 10
@@ -0,0 +1,118 @@
+package unixfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	gopath "path"
+	"strings"
+
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// MaxSymlinks is the maximum number of symlinks followed by [ResolvePath], as
+// on Linux.
+const MaxSymlinks = 40
+
+var (
+	// ErrSymlinkLoop is returned by [ResolvePath] when resolving the path
+	// follows more than [MaxSymlinks] symlinks.
+	ErrSymlinkLoop = errors.New("too many levels of symbolic links")
+	// ErrSymlinkEscapesRoot is returned by [ResolvePath] when a symlink
+	// points outside of the root of the resolution: absolute targets and
+	// relative ones going above the root cannot be resolved within a DAG.
+	ErrSymlinkEscapesRoot = errors.New("symlink escapes the root")
+)
+
+// ResolveOption configures [ResolvePath].
+type ResolveOption func(*resolveSettings)
+
+type resolveSettings struct {
+	through bool
+}
+
+// ResolveThrough makes [ResolvePath] follow a symlink in the last component of
+// the path too, like stat, instead of returning the symlink node, like lstat.
+func ResolveThrough(through bool) ResolveOption {
+	return func(s *resolveSettings) {
+		s.through = through
+	}
+}
+
+// IsSymlink returns whether nd is a UnixFS symlink, and its target if so.
+func IsSymlink(nd ipld.Node) (string, bool) {
+	pn, ok := nd.(*dag.ProtoNode)
+	if !ok {
+		return "", false
+	}
+	fsn, err := ft.FSNodeFromBytes(pn.Data())
+	if err != nil || fsn.Type() != ft.TSymlink {
+		return "", false
+	}
+	return string(fsn.Data()), true
+}
+
+// ResolvePath resolves the slash-separated path p from the UnixFS directory
+// root, following the symlinks met on the way. Relative symlink targets are
+// resolved from the directory holding the symlink, and neither symlinks nor
+// ".." components may leave root, see [ErrSymlinkEscapesRoot].
+//
+// The symlinks in the last component of p are only followed with
+// [ResolveThrough]. Components which do not exist fail with an error
+// wrapping [os.ErrNotExist].
+func ResolvePath(ctx context.Context, dserv ipld.DAGService, root ipld.Node, p string, opts ...ResolveOption) (ipld.Node, error) {
+	var settings resolveSettings
+	for _, o := range opts {
+		o(&settings)
+	}
+
+	// dirs are the directories from root to the current one
+	dirs := []ipld.Node{root}
+	components := strings.Split(p, "/")
+	var followed int
+	for len(components) > 0 {
+		name := components[0]
+		components = components[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(dirs) == 1 {
+				return nil, fmt.Errorf("%s: %w", p, ErrSymlinkEscapesRoot)
+			}
+			dirs = dirs[:len(dirs)-1]
+			continue
+		}
+
+		dir, err := uio.NewDirectoryFromNode(dserv, dirs[len(dirs)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		nd, err := dir.Find(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+
+		target, ok := IsSymlink(nd)
+		if !ok || (len(components) == 0 && !settings.through) {
+			dirs = append(dirs, nd)
+			continue
+		}
+		followed++
+		if followed > MaxSymlinks {
+			return nil, fmt.Errorf("%s: %w", p, ErrSymlinkLoop)
+		}
+		if target == "" {
+			return nil, fmt.Errorf("%s: %s is an empty symlink: %w", p, name, os.ErrNotExist)
+		}
+		if gopath.IsAbs(target) {
+			return nil, fmt.Errorf("%s: %s points to %q: %w", p, name, target, ErrSymlinkEscapesRoot)
+		}
+		components = append(strings.Split(target, "/"), components...)
+	}
+	return dirs[len(dirs)-1], nil
+}
@@ -0,0 +1,100 @@
+package unixfile
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	mdtest "github.com/ipfs/boxo/ipld/merkledag/test"
+	"github.com/ipfs/boxo/ipld/unixfs/importer"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePath(t *testing.T) {
+	ctx := context.Background()
+	dserv := mdtest.Mock()
+	symlink := func(target string) files.Node {
+		return files.NewSymlinkFile(target, time.Time{})
+	}
+	res, err := importer.ImportTree(ctx, dserv, files.NewMapDirectory(map[string]files.Node{
+		"file.txt": files.NewBytesFile([]byte("file")),
+		"dir": files.NewMapDirectory(map[string]files.Node{
+			"sub.txt": files.NewBytesFile([]byte("sub")),
+			"up":      symlink("../file.txt"),
+		}),
+		"link-dir":  symlink("dir"),
+		"link-file": symlink("./dir/sub.txt"),
+		"chain":     symlink("link-dir/up"),
+		"loop1":     symlink("loop2"),
+		"loop2":     symlink("loop1"),
+		"escape":    symlink("dir/../../outside"),
+		"absolute":  symlink("/etc/passwd"),
+		"dangling":  symlink("missing"),
+		"empty":     symlink(""),
+	}))
+	require.NoError(t, err)
+	root := res.Root
+
+	resolve := func(p string, opts ...ResolveOption) (string, error) {
+		nd, err := ResolvePath(ctx, dserv, root, p, opts...)
+		if err != nil {
+			return "", err
+		}
+		return nd.Cid().String(), nil
+	}
+	through := ResolveThrough(true)
+
+	for p, want := range map[string]string{
+		"dir/sub.txt":          "dir/sub.txt",
+		"link-dir/sub.txt":     "dir/sub.txt",
+		"link-dir/../file.txt": "file.txt",
+		"./dir/./sub.txt":      "dir/sub.txt",
+	} {
+		got, err := resolve(p)
+		require.NoError(t, err, p)
+		require.Equal(t, res.Files[want].String(), got, p)
+	}
+
+	// the symlinks in the last component are only followed through
+	nd, err := ResolvePath(ctx, dserv, root, "link-file")
+	require.NoError(t, err)
+	target, ok := IsSymlink(nd)
+	require.True(t, ok)
+	require.Equal(t, "./dir/sub.txt", target)
+	got, err := resolve("link-file", through)
+	require.NoError(t, err)
+	require.Equal(t, res.Files["dir/sub.txt"].String(), got)
+	got, err = resolve("chain", through)
+	require.NoError(t, err)
+	require.Equal(t, res.Files["file.txt"].String(), got)
+
+	// directories are resolved through too
+	nd, err = ResolvePath(ctx, dserv, root, "link-dir", through)
+	require.NoError(t, err)
+	_, err = uio.NewDirectoryFromNode(dserv, nd)
+	require.NoError(t, err)
+
+	_, err = resolve("loop1", through)
+	require.ErrorIs(t, err, ErrSymlinkLoop)
+	_, err = resolve("loop1/file.txt")
+	require.ErrorIs(t, err, ErrSymlinkLoop)
+
+	// symlinks cannot escape the root
+	for _, p := range []string{"escape", "absolute", "..", "dir/../.."} {
+		_, err = resolve(p, through)
+		require.ErrorIs(t, err, ErrSymlinkEscapesRoot, p)
+	}
+	// unless they are not followed
+	_, err = resolve("escape")
+	require.NoError(t, err)
+
+	for _, p := range []string{"missing", "dangling", "empty", "link-dir/missing"} {
+		_, err = resolve(p, through)
+		require.ErrorIs(t, err, os.ErrNotExist, p)
+	}
+	_, err = resolve("file.txt/sub.txt")
+	require.ErrorIs(t, err, uio.ErrNotADir)
+}
@@ -3,6 +3,9 @@ package unixfile
 import (
 	"context"
 	"errors"
+	"io"
+	"os"
+	"time"
 
 	ft "github.com/ipfs/boxo/ipld/unixfs"
 	uio "github.com/ipfs/boxo/ipld/unixfs/io"
@@ -21,6 +24,8 @@ type ufsDirectory struct {
 	dserv ipld.DAGService
 	dir   uio.Directory
 	size  int64
+	mode  os.FileMode
+	mtime time.Time
 }
 
 type ufsIterator struct {
@@ -122,15 +127,42 @@ func (d *ufsDirectory) Size() (int64, error) {
 	return d.size, nil
 }
 
+func (d *ufsDirectory) Mode() os.FileMode {
+	return d.mode
+}
+
+func (d *ufsDirectory) ModTime() time.Time {
+	return d.mtime
+}
+
 type ufsFile struct {
 	uio.DagReader
+	mode  os.FileMode
+	mtime time.Time
 }
 
 func (f *ufsFile) Size() (int64, error) {
 	return int64(f.DagReader.Size()), nil
 }
 
-func newUnixfsDir(ctx context.Context, dserv ipld.DAGService, nd *dag.ProtoNode) (files.Directory, error) {
+// ReadAt implements io.ReaderAt when the DagReader does, and returns
+// files.ErrNotSupported otherwise.
+func (f *ufsFile) ReadAt(p []byte, off int64) (int, error) {
+	if ra, ok := f.DagReader.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+	return 0, files.ErrNotSupported
+}
+
+func (f *ufsFile) Mode() os.FileMode {
+	return f.mode
+}
+
+func (f *ufsFile) ModTime() time.Time {
+	return f.mtime
+}
+
+func newUnixfsDir(ctx context.Context, dserv ipld.DAGService, nd *dag.ProtoNode, fsn *ft.FSNode) (files.Directory, error) {
 	dir, err := uio.NewDirectoryFromNode(dserv, nd)
 	if err != nil {
 		return nil, err
@@ -145,23 +177,27 @@ func newUnixfsDir(ctx context.Context, dserv ipld.DAGService, nd *dag.ProtoNode)
 		ctx:   ctx,
 		dserv: dserv,
 
-		dir:  dir,
-		size: int64(size),
+		dir:   dir,
+		size:  int64(size),
+		mode:  fsn.Mode(),
+		mtime: fsn.ModTime(),
 	}, nil
 }
 
 func NewUnixfsFile(ctx context.Context, dserv ipld.DAGService, nd ipld.Node) (files.Node, error) {
+	var fsn *ft.FSNode
 	switch dn := nd.(type) {
 	case *dag.ProtoNode:
-		fsn, err := ft.FSNodeFromBytes(dn.Data())
+		var err error
+		fsn, err = ft.FSNodeFromBytes(dn.Data())
 		if err != nil {
 			return nil, err
 		}
 		if fsn.IsDir() {
-			return newUnixfsDir(ctx, dserv, dn)
+			return newUnixfsDir(ctx, dserv, dn, fsn)
 		}
 		if fsn.Type() == ft.TSymlink {
-			return files.NewLinkFile(string(fsn.Data()), nil), nil
+			return files.NewSymlinkFile(string(fsn.Data()), fsn.ModTime()), nil
 		}
 
 	case *dag.RawNode:
@@ -174,12 +210,19 @@ func NewUnixfsFile(ctx context.Context, dserv ipld.DAGService, nd ipld.Node) (fi
 		return nil, err
 	}
 
-	return &ufsFile{
+	f := &ufsFile{
 		DagReader: dr,
-	}, nil
+	}
+	if fsn != nil {
+		f.mode = fsn.Mode()
+		f.mtime = fsn.ModTime()
+	}
+	return f, nil
 }
 
 var (
 	_ files.Directory = &ufsDirectory{}
 	_ files.File      = &ufsFile{}
+	_ io.WriterTo     = &ufsFile{}
+	_ io.ReaderAt     = &ufsFile{}
 )
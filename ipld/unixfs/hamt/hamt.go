@@ -49,6 +49,38 @@ func init() {
 	internal.HAMTHashFunction = murmur3Hash
 }
 
+// HashFunction hashes the keys of a HAMT. The returned digest must be long
+// enough to index every level of the trie, murmur3 returns 64 bits.
+type HashFunction func(val []byte) []byte
+
+var (
+	hashFunctionsLk sync.RWMutex
+	hashFunctions   = map[uint64]HashFunction{
+		// Indirect so that tests can swap internal.HAMTHashFunction.
+		HashMurmur3: func(val []byte) []byte { return internal.HAMTHashFunction(val) },
+	}
+)
+
+// RegisterHashFunction makes the hash function identified by the given
+// multicodec code available to [NewShardWithHash] and [NewHamtFromDag].
+// Murmur3 is always registered and is the only hash function other
+// implementations are expected to support.
+func RegisterHashFunction(code uint64, fn HashFunction) {
+	hashFunctionsLk.Lock()
+	defer hashFunctionsLk.Unlock()
+	hashFunctions[code] = fn
+}
+
+func getHashFunction(code uint64) (HashFunction, error) {
+	hashFunctionsLk.RLock()
+	defer hashFunctionsLk.RUnlock()
+	fn, ok := hashFunctions[code]
+	if !ok {
+		return nil, fmt.Errorf("unsupported HAMT hash function 0x%x", code)
+	}
+	return fn, nil
+}
+
 func (ds *Shard) isValueNode() bool {
 	return ds.key != "" && ds.val != nil
 }
@@ -65,6 +97,7 @@ type Shard struct {
 
 	builder  cid.Builder
 	hashFunc uint64
+	hash     HashFunction
 
 	// String format with number of zeros that will be present in the hexadecimal
 	// encoding of the child index to always reach the fixed maxpadlen chars.
@@ -90,23 +123,26 @@ func NewShard(dserv ipld.DAGService, size int) (*Shard, error) {
 	return NewShardValue(dserv, size, "", nil)
 }
 
+// NewShardWithHash creates a new, empty HAMT shard with the given size and
+// hash function, identified by its multicodec code. See [RegisterHashFunction].
+func NewShardWithHash(dserv ipld.DAGService, size int, hashFunc uint64) (*Shard, error) {
+	return makeShard(dserv, size, hashFunc, "", nil)
+}
+
 // NewShardValue creates a new, empty HAMT shard with the given key, value and size.
 func NewShardValue(dserv ipld.DAGService, size int, key string, value *ipld.Link) (*Shard, error) {
-	ds, err := makeShard(dserv, size, key, value)
-	if err != nil {
-		return nil, err
-	}
-
-	// FIXME: Make this at least a static configuration for testing.
-	ds.hashFunc = HashMurmur3
-	return ds, nil
+	return makeShard(dserv, size, HashMurmur3, key, value)
 }
 
-func makeShard(ds ipld.DAGService, size int, key string, val *ipld.Link) (*Shard, error) {
+func makeShard(ds ipld.DAGService, size int, hashFunc uint64, key string, val *ipld.Link) (*Shard, error) {
 	lg2s, err := Logtwo(size)
 	if err != nil {
 		return nil, err
 	}
+	hash, err := getHashFunction(hashFunc)
+	if err != nil {
+		return nil, err
+	}
 	childer, err := newChilder(ds, size)
 	if err != nil {
 		return nil, err
@@ -118,6 +154,8 @@ func makeShard(ds ipld.DAGService, size int, key string, val *ipld.Link) (*Shard
 		maxpadlen:    len(maxpadding),
 		childer:      childer,
 		tableSize:    size,
+		hashFunc:     hashFunc,
+		hash:         hash,
 		dserv:        ds,
 
 		key: key,
@@ -145,20 +183,15 @@ func NewHamtFromDag(dserv ipld.DAGService, nd ipld.Node) (*Shard, error) {
 		return nil, errors.New("node was not a dir shard")
 	}
 
-	if fsn.HashType() != HashMurmur3 {
-		return nil, errors.New("only murmur3 supported as hash function")
-	}
-
 	size := int(fsn.Fanout())
 
-	ds, err := makeShard(dserv, size, "", nil)
+	ds, err := makeShard(dserv, size, fsn.HashType(), "", nil)
 	if err != nil {
 		return nil, err
 	}
 
 	ds.childer.makeChilder(fsn.Data(), pbnd.Links())
 
-	ds.hashFunc = fsn.HashType()
 	ds.builder = pbnd.CidBuilder()
 
 	return ds, nil
@@ -210,7 +243,7 @@ func (ds *Shard) Node() (ipld.Node, error) {
 		sliceIndex++
 	}
 
-	data, err := format.HAMTShardData(ds.childer.bitfield.Bytes(), uint64(ds.tableSize), HashMurmur3)
+	data, err := format.HAMTShardData(ds.childer.bitfield.Bytes(), uint64(ds.tableSize), ds.hashFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +260,7 @@ func (ds *Shard) Node() (ipld.Node, error) {
 
 func (ds *Shard) makeShardValue(lnk *ipld.Link) (*Shard, error) {
 	lnk2 := *lnk
-	s, err := makeShard(ds.dserv, ds.tableSize, "", nil)
+	s, err := makeShard(ds.dserv, ds.tableSize, ds.hashFunc, "", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -248,7 +281,7 @@ func (ds *Shard) Set(ctx context.Context, name string, nd ipld.Node) error {
 // given link. This avoids writing the given node, then reading it to making a
 // link out of it.
 func (ds *Shard) SetLink(ctx context.Context, name string, lnk *ipld.Link) error {
-	hv := newHashBits(name)
+	hv := ds.newHashBits(name)
 
 	newLink := ipld.Link{
 		Name: lnk.Name,
@@ -268,7 +301,7 @@ func (ds *Shard) SetLink(ctx context.Context, name string, lnk *ipld.Link) error
 // name key in this Shard or its children. It also returns the previous link
 // under that name key (if any).
 func (ds *Shard) Swap(ctx context.Context, name string, node ipld.Node) (*ipld.Link, error) {
-	hv := newHashBits(name)
+	hv := ds.newHashBits(name)
 	err := ds.dserv.Add(ctx, node)
 	if err != nil {
 		return nil, err
@@ -296,13 +329,13 @@ func (ds *Shard) Remove(ctx context.Context, name string) error {
 // Take is similar to the public Remove but also returns the
 // old removed link (if it exists).
 func (ds *Shard) Take(ctx context.Context, name string) (*ipld.Link, error) {
-	hv := newHashBits(name)
+	hv := ds.newHashBits(name)
 	return ds.swapValue(ctx, hv, name, nil)
 }
 
 // Find searches for a child node by 'name' within this hamt
 func (ds *Shard) Find(ctx context.Context, name string) (*ipld.Link, error) {
-	hv := newHashBits(name)
+	hv := ds.newHashBits(name)
 
 	var out *ipld.Link
 	err := ds.getValue(ctx, hv, name, func(sv *Shard) error {
@@ -675,12 +708,12 @@ func (ds *Shard) swapValue(ctx context.Context, hv *hashBits, key string, value
 		// will be a child of this new shard (along with the new value being
 		// inserted).
 		grandChild := child
-		child, err = NewShard(ds.dserv, ds.tableSize)
+		child, err = NewShardWithHash(ds.dserv, ds.tableSize, ds.hashFunc)
 		if err != nil {
 			return nil, err
 		}
 		child.builder = ds.builder
-		chhv := newConsumedHashBits(grandChild.key, hv.consumed)
+		chhv := ds.newConsumedHashBits(grandChild.key, hv.consumed)
 
 		// We explicitly ignore the oldValue returned by the next two insertions
 		// (which will be nil) to highlight there is no overwrite here: they are
@@ -819,7 +852,7 @@ func (s *childer) insert(key string, lnk *ipld.Link, idx int) error {
 	lnk.Name = s.sd.linkNamePrefix(idx) + key
 	i := s.sliceIndex(idx)
 
-	sd, err := NewShardValue(s.dserv, 256, key, lnk)
+	sd, err := makeShard(s.dserv, 256, s.sd.hashFunc, key, lnk)
 	if err != nil {
 		return err
 	}
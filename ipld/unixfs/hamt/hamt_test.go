@@ -749,3 +749,9 @@ func TestHamtBadSize(t *testing.T) {
 		}
 	}
 }
+
+func TestHamtUnknownHashFunction(t *testing.T) {
+	if _, err := NewShardWithHash(nil, 256, 0x1337); err == nil {
+		t.Fatal("should have failed to construct hamt with unregistered hash function")
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"errors"
 	"math/bits"
 
-	"github.com/ipfs/boxo/ipld/unixfs/internal"
-
 	"github.com/spaolacci/murmur3"
 )
 
@@ -15,12 +13,12 @@ type hashBits struct {
 	consumed int
 }
 
-func newHashBits(val string) *hashBits {
-	return &hashBits{b: internal.HAMTHashFunction([]byte(val))}
+func (ds *Shard) newHashBits(val string) *hashBits {
+	return &hashBits{b: ds.hash([]byte(val))}
 }
 
-func newConsumedHashBits(val string, consumed int) *hashBits {
-	hv := &hashBits{b: internal.HAMTHashFunction([]byte(val))}
+func (ds *Shard) newConsumedHashBits(val string, consumed int) *hashBits {
+	hv := &hashBits{b: ds.hash([]byte(val))}
 	hv.consumed = consumed
 	return hv
 }
@@ -139,6 +139,11 @@ func Layout(db *h.DagBuilderHelper) (ipld.Node, error) {
 		// This works without Filestore support (`ProcessFileStore`).
 		// TODO: Why? Is there a test case missing?
 
+		root, err = db.ApplyFileAttributes(root, 0)
+		if err != nil {
+			return nil, err
+		}
+
 		return root, db.Add(root)
 	}
 
@@ -172,6 +177,13 @@ func Layout(db *h.DagBuilderHelper) (ipld.Node, error) {
 		}
 	}
 
+	// Store the optional mode and mtime in the root (wrapping it if it
+	// is a single raw leaf).
+	root, err = db.ApplyFileAttributes(root, fileSize)
+	if err != nil {
+		return nil, err
+	}
+
 	return root, db.Add(root)
 }
 
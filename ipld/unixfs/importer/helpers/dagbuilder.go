@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"time"
 
 	dag "github.com/ipfs/boxo/ipld/merkledag"
 
@@ -31,6 +32,10 @@ type DagBuilderHelper struct {
 	maxlinks   int
 	cidBuilder cid.Builder
 
+	// Optional UnixFS metadata stored in the root node of the file.
+	fileMode    os.FileMode
+	fileModTime time.Time
+
 	// Filestore support variables.
 	// ----------------------------
 	// TODO: Encapsulate in `FilestoreNode` (which is basically what they are).
@@ -65,17 +70,27 @@ type DagBuilderParams struct {
 	// NoCopy signals to the chunker that it should track fileinfo for
 	// filestore adds
 	NoCopy bool
+
+	// FileMode is the optional file mode (permissions and special bits) to
+	// store in the root node of the file
+	FileMode os.FileMode
+
+	// FileModTime is the optional modification time to store in the root
+	// node of the file
+	FileModTime time.Time
 }
 
 // New generates a new DagBuilderHelper from the given params and a given
 // chunker.Splitter as data source.
 func (dbp *DagBuilderParams) New(spl chunker.Splitter) (*DagBuilderHelper, error) {
 	db := &DagBuilderHelper{
-		dserv:      dbp.Dagserv,
-		spl:        spl,
-		rawLeaves:  dbp.RawLeaves,
-		cidBuilder: dbp.CidBuilder,
-		maxlinks:   dbp.Maxlinks,
+		dserv:       dbp.Dagserv,
+		spl:         spl,
+		rawLeaves:   dbp.RawLeaves,
+		cidBuilder:  dbp.CidBuilder,
+		maxlinks:    dbp.Maxlinks,
+		fileMode:    dbp.FileMode,
+		fileModTime: dbp.FileModTime,
 	}
 	if fi, ok := spl.Reader().(files.FileInfo); dbp.NoCopy && ok {
 		db.fullPath = fi.AbsPath()
@@ -259,12 +274,52 @@ func (db *DagBuilderHelper) Add(node ipld.Node) error {
 	return db.dserv.Add(context.TODO(), node)
 }
 
+// FileMode returns the optional file mode to store in the root node.
+func (db *DagBuilderHelper) FileMode() os.FileMode {
+	return db.fileMode
+}
+
+// FileModTime returns the optional modification time to store in the root
+// node.
+func (db *DagBuilderHelper) FileModTime() time.Time {
+	return db.fileModTime
+}
+
 // Maxlinks returns the configured maximum number for links
 // for nodes built with this helper.
 func (db *DagBuilderHelper) Maxlinks() int {
 	return db.maxlinks
 }
 
+// ApplyFileAttributes stores the file mode and modification time given in
+// the DagBuilderParams in the `root` node of the file. Roots that can't
+// carry UnixFS metadata (raw and Filestore leaves) are wrapped in a new
+// `File` node with `root` as its only child of `fileSize`. The root is
+// returned unchanged if no attributes were set.
+func (db *DagBuilderHelper) ApplyFileAttributes(root ipld.Node, fileSize uint64) (ipld.Node, error) {
+	if db.fileMode == 0 && db.fileModTime.IsZero() {
+		return root, nil
+	}
+
+	var fsn *FSNodeOverDag
+	if pbn, ok := root.(*dag.ProtoNode); ok {
+		var err error
+		fsn, err = NewFSNFromDag(pbn)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		fsn = db.NewFSNodeOverDag(ft.TFile)
+		if err := fsn.AddChild(root, fileSize, db); err != nil {
+			return nil, err
+		}
+	}
+
+	fsn.SetFileMode(db.fileMode)
+	fsn.SetFileModTime(db.fileModTime)
+	return fsn.Commit()
+}
+
 // FSNodeOverDag encapsulates an `unixfs.FSNode` that will be stored in a
 // `dag.ProtoNode`. Instead of just having a single `ipld.Node` that
 // would need to be constantly (un)packed to access and modify its
@@ -338,7 +393,8 @@ func (n *FSNodeOverDag) AddChild(child ipld.Node, fileSize uint64, db *DagBuilde
 // RemoveChild deletes the child node at the given index.
 func (n *FSNodeOverDag) RemoveChild(index int, dbh *DagBuilderHelper) {
 	n.file.RemoveBlockSize(index)
-	n.dag.SetLinks(append(n.dag.Links()[:index], n.dag.Links()[index+1:]...))
+	links := n.dag.Links()
+	n.dag.SetLinks(append(links[:index], links[index+1:]...))
 }
 
 // Commit unifies (resolves) the cache nodes into a single `ipld.Node`
@@ -374,6 +430,16 @@ func (n *FSNodeOverDag) SetFileData(fileData []byte) {
 	n.file.SetData(fileData)
 }
 
+// SetFileMode sets the file mode of the `ft.FSNode`.
+func (n *FSNodeOverDag) SetFileMode(mode os.FileMode) {
+	n.file.SetMode(mode)
+}
+
+// SetFileModTime sets the modification time of the `ft.FSNode`.
+func (n *FSNodeOverDag) SetFileModTime(mtime time.Time) {
+	n.file.SetModTime(mtime)
+}
+
 // GetDagNode fills out the proper formatting for the FSNodeOverDag node
 // inside of a DAG node and returns the dag node.
 // TODO: Check if we have committed (passed the UnixFS information
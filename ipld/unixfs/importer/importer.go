@@ -3,11 +3,16 @@
 package importer
 
 import (
+	"context"
+
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
 	bal "github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
 	h "github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
 	trickle "github.com/ipfs/boxo/ipld/unixfs/importer/trickle"
 
 	chunker "github.com/ipfs/boxo/chunker"
+	cid "github.com/ipfs/go-cid"
 	ipld "github.com/ipfs/go-ipld-format"
 )
 
@@ -39,3 +44,33 @@ func BuildTrickleDagFromReader(ds ipld.DAGService, spl chunker.Splitter) (ipld.N
 	}
 	return trickle.Layout(db)
 }
+
+// BuildSymlink creates a UnixFS symlink node pointing to target and adds it
+// to ds. The target is stored as is, relative targets are resolved from the
+// directory holding the symlink.
+func BuildSymlink(ctx context.Context, ds ipld.DAGService, target string) (ipld.Node, error) {
+	nd, err := newSymlinkNode(target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.Add(ctx, nd); err != nil {
+		return nil, err
+	}
+	return nd, nil
+}
+
+// newSymlinkNode returns a UnixFS symlink node pointing to target, with the
+// CIDs of builder if not nil.
+func newSymlinkNode(target string, builder cid.Builder) (*dag.ProtoNode, error) {
+	data, err := ft.SymlinkData(target)
+	if err != nil {
+		return nil, err
+	}
+	nd := dag.NodeWithData(data)
+	if builder != nil {
+		if err := nd.SetCidBuilder(builder); err != nil {
+			return nil, err
+		}
+	}
+	return nd, nil
+}
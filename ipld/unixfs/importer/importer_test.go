@@ -4,8 +4,15 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/ipfs/boxo/files"
+	unixfile "github.com/ipfs/boxo/ipld/unixfs/file"
+	bal "github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
+	h "github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
+	trickle "github.com/ipfs/boxo/ipld/unixfs/importer/trickle"
 	uio "github.com/ipfs/boxo/ipld/unixfs/io"
 
 	chunker "github.com/ipfs/boxo/chunker"
@@ -150,3 +157,81 @@ func runReadBench(b *testing.B, nd ipld.Node, ds ipld.DAGService) {
 		cancel()
 	}
 }
+
+func TestFileAttributes(t *testing.T) {
+	mode := os.FileMode(0o640)
+	mtime := time.Unix(1700000000, 0)
+
+	for _, tc := range []struct {
+		name   string
+		layout func(*h.DagBuilderHelper) (ipld.Node, error)
+		size   int
+	}{
+		{"balanced-empty", bal.Layout, 0},
+		{"balanced-single-leaf", bal.Layout, 100},
+		{"balanced-multi-leaf", bal.Layout, 10000},
+		{"trickle", trickle.Layout, 10000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := mdtest.Mock()
+			buf := make([]byte, tc.size)
+			u.NewTimeSeededRand().Read(buf)
+
+			dbp := h.DagBuilderParams{
+				Dagserv:     ds,
+				Maxlinks:    h.DefaultLinksPerBlock,
+				RawLeaves:   true,
+				FileMode:    mode,
+				FileModTime: mtime,
+			}
+			db, err := dbp.New(chunker.NewSizeSplitter(bytes.NewReader(buf), 512))
+			if err != nil {
+				t.Fatal(err)
+			}
+			nd, err := tc.layout(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := unixfile.NewUnixfsFile(context.Background(), ds, nd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Mode() != mode {
+				t.Fatalf("expected mode %s, got %s", mode, f.Mode())
+			}
+			if !f.ModTime().Equal(mtime) {
+				t.Fatalf("expected mtime %s, got %s", mtime, f.ModTime())
+			}
+
+			out, err := io.ReadAll(f.(io.Reader))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, buf) {
+				t.Fatal("bad read")
+			}
+		})
+	}
+}
+
+func TestBuildSymlink(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+	nd, err := BuildSymlink(ctx, ds, "../target")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := ds.Get(ctx, nd.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := unixfile.NewUnixfsFile(ctx, ds, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := f.(*files.Symlink); !ok || s.Target != "../target" {
+		t.Fatalf("expected a symlink to ../target, got %#v", f)
+	}
+}
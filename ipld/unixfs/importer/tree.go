@@ -0,0 +1,377 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	gopath "path"
+	"strings"
+	"sync"
+
+	chunker "github.com/ipfs/boxo/chunker"
+	"github.com/ipfs/boxo/files"
+	bal "github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
+	h "github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
+	trickle "github.com/ipfs/boxo/ipld/unixfs/importer/trickle"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// DefaultTreeConcurrency is the default number of files chunked in parallel
+// by [ImportTree].
+const DefaultTreeConcurrency = 4
+
+// TreeOption configures [ImportTree].
+type TreeOption func(*treeSettings) error
+
+type treeSettings struct {
+	chunker     string
+	rawLeaves   bool
+	cidBuilder  cid.Builder
+	trickle     bool
+	concurrency int
+	dedup       bool
+	dirOpts     []uio.DirectoryOption
+}
+
+// WithChunker sets the chunker used to split files, in the format of
+// [chunker.FromString]. Defaults to the default chunker.
+func WithChunker(spec string) TreeOption {
+	return func(s *treeSettings) error {
+		if _, err := chunker.FromString(strings.NewReader(""), spec); err != nil {
+			return err
+		}
+		s.chunker = spec
+		return nil
+	}
+}
+
+// WithRawLeaves makes the leaves of files raw blocks instead of UnixFS
+// nodes.
+func WithRawLeaves(raw bool) TreeOption {
+	return func(s *treeSettings) error {
+		s.rawLeaves = raw
+		return nil
+	}
+}
+
+// WithCidBuilder sets the CID builder of the files and directories.
+func WithCidBuilder(builder cid.Builder) TreeOption {
+	return func(s *treeSettings) error {
+		s.cidBuilder = builder
+		return nil
+	}
+}
+
+// WithTrickle lays out files with the trickle layout instead of the
+// balanced one.
+func WithTrickle(trickle bool) TreeOption {
+	return func(s *treeSettings) error {
+		s.trickle = trickle
+		return nil
+	}
+}
+
+// WithConcurrency sets how many files are chunked in parallel. Directories
+// whose files must be read in iteration order, such as multipart requests,
+// must be imported with a concurrency of 1.
+func WithConcurrency(n int) TreeOption {
+	return func(s *treeSettings) error {
+		if n < 1 {
+			return fmt.Errorf("concurrency must be at least 1, got %d", n)
+		}
+		s.concurrency = n
+		return nil
+	}
+}
+
+// WithLeafDedup adds the blocks shared by several files, such as identical
+// leaves, only once to the DAGService.
+func WithLeafDedup(dedup bool) TreeOption {
+	return func(s *treeSettings) error {
+		s.dedup = dedup
+		return nil
+	}
+}
+
+// WithDirectoryOptions sets the options of the directories created by
+// ImportTree, such as their sharding.
+func WithDirectoryOptions(opts ...uio.DirectoryOption) TreeOption {
+	return func(s *treeSettings) error {
+		s.dirOpts = append(s.dirOpts, opts...)
+		return nil
+	}
+}
+
+// TreeResult is the outcome of [ImportTree].
+type TreeResult struct {
+	// Root is the directory node of the imported tree.
+	Root ipld.Node
+	// Files maps the slash-separated path of every file and symlink,
+	// relative to the root, to the root CID of its DAG.
+	Files map[string]cid.Cid
+	// Duplicates is the number of blocks not added again because an
+	// identical block was already added by the import. It is only counted
+	// when WithLeafDedup is set.
+	Duplicates int
+}
+
+// ImportTree imports a whole directory tree into ds. All the nodes are
+// added through a single batch, committed once the tree is imported, and
+// files are chunked in parallel.
+func ImportTree(ctx context.Context, ds ipld.DAGService, dir files.Directory, opts ...TreeOption) (*TreeResult, error) {
+	settings := &treeSettings{
+		chunker:     "default",
+		concurrency: DefaultTreeConcurrency,
+	}
+	for _, o := range opts {
+		if err := o(settings); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ti := &treeImporter{
+		settings: settings,
+		adder: &batchAdder{
+			DAGService: ds,
+			batch:      ipld.NewBatch(ctx, ds),
+			dedup:      settings.dedup,
+			seen:       cid.NewSet(),
+		},
+		sem:    make(chan struct{}, settings.concurrency),
+		cancel: cancel,
+	}
+
+	root, err := ti.walk(ctx, "", dir)
+	ti.wg.Wait()
+	if err == nil {
+		err = ti.err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nd, err := ti.buildDirectory(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	if err := ti.adder.batch.Commit(); err != nil {
+		return nil, err
+	}
+
+	res := &TreeResult{
+		Root:       nd,
+		Files:      make(map[string]cid.Cid),
+		Duplicates: ti.adder.duplicates,
+	}
+	root.collectFiles(res.Files)
+	return res, nil
+}
+
+type treeImporter struct {
+	settings *treeSettings
+	adder    *batchAdder
+
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	errOnce sync.Once
+	err     error
+}
+
+// treeEntry is a file or directory of the tree being imported. The node of
+// a file is set once it was chunked.
+type treeEntry struct {
+	name     string
+	path     string
+	children []*treeEntry
+	isDir    bool
+	node     ipld.Node
+}
+
+func (ti *treeImporter) fail(err error) {
+	ti.errOnce.Do(func() {
+		ti.err = err
+		ti.cancel()
+	})
+}
+
+// walk lists dir and starts importing its files.
+func (ti *treeImporter) walk(ctx context.Context, path string, dir files.Directory) (*treeEntry, error) {
+	e := &treeEntry{path: path, isDir: true}
+	it := dir.Entries()
+	for it.Next() {
+		child := &treeEntry{
+			name: it.Name(),
+			path: gopath.Join(path, it.Name()),
+		}
+
+		switch n := it.Node().(type) {
+		case files.Directory:
+			sub, err := ti.walk(ctx, child.path, n)
+			if err != nil {
+				return nil, err
+			}
+			sub.name = child.name
+			child = sub
+		case *files.Symlink:
+			nd, err := newSymlinkNode(n.Target, ti.settings.cidBuilder)
+			if err != nil {
+				return nil, err
+			}
+			if err := ti.adder.Add(ctx, nd); err != nil {
+				return nil, err
+			}
+			child.node = nd
+		case files.File:
+			if err := ti.importFile(ctx, child, n); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%s: unsupported file type %T", child.path, n)
+		}
+		e.children = append(e.children, child)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// importFile chunks f in the background once a slot is available.
+func (ti *treeImporter) importFile(ctx context.Context, e *treeEntry, f files.File) error {
+	select {
+	case ti.sem <- struct{}{}:
+	case <-ctx.Done():
+		if ti.err != nil {
+			return ti.err
+		}
+		return ctx.Err()
+	}
+
+	ti.wg.Add(1)
+	go func() {
+		defer ti.wg.Done()
+		defer func() { <-ti.sem }()
+
+		nd, err := ti.buildFile(f)
+		if err != nil {
+			ti.fail(fmt.Errorf("%s: %w", e.path, err))
+			return
+		}
+		e.node = nd
+	}()
+	return nil
+}
+
+func (ti *treeImporter) buildFile(f files.File) (ipld.Node, error) {
+	defer f.Close()
+
+	spl, err := chunker.FromString(f, ti.settings.chunker)
+	if err != nil {
+		return nil, err
+	}
+	dbp := h.DagBuilderParams{
+		Dagserv:    ti.adder,
+		Maxlinks:   h.DefaultLinksPerBlock,
+		RawLeaves:  ti.settings.rawLeaves,
+		CidBuilder: ti.settings.cidBuilder,
+	}
+	db, err := dbp.New(spl)
+	if err != nil {
+		return nil, err
+	}
+	if ti.settings.trickle {
+		return trickle.Layout(db)
+	}
+	return bal.Layout(db)
+}
+
+// buildDirectory creates the directory nodes of e, bottom-up.
+func (ti *treeImporter) buildDirectory(ctx context.Context, e *treeEntry) (ipld.Node, error) {
+	dir := uio.NewDirectory(ti.adder, ti.settings.dirOpts...)
+	if ti.settings.cidBuilder != nil {
+		dir.SetCidBuilder(ti.settings.cidBuilder)
+	}
+	for _, child := range e.children {
+		nd := child.node
+		if child.isDir {
+			var err error
+			nd, err = ti.buildDirectory(ctx, child)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := dir.AddChild(ctx, child.name, nd); err != nil {
+			return nil, fmt.Errorf("%s: %w", child.path, err)
+		}
+	}
+
+	nd, err := dir.GetNode()
+	if err != nil {
+		return nil, err
+	}
+	if err := ti.adder.Add(ctx, nd); err != nil {
+		return nil, err
+	}
+	e.node = nd
+	return nd, nil
+}
+
+func (e *treeEntry) collectFiles(m map[string]cid.Cid) {
+	for _, child := range e.children {
+		if child.isDir {
+			child.collectFiles(m)
+		} else {
+			m[child.path] = child.node.Cid()
+		}
+	}
+}
+
+// batchAdder is a DAGService adding nodes to a batch shared by all the
+// goroutines of an import, and optionally skipping the nodes it already
+// added.
+type batchAdder struct {
+	ipld.DAGService
+
+	lk         sync.Mutex
+	batch      *ipld.Batch
+	dedup      bool
+	seen       *cid.Set
+	duplicates int
+}
+
+var errBatchAdderRemove = errors.New("cannot remove nodes while importing a tree")
+
+func (b *batchAdder) Add(ctx context.Context, nd ipld.Node) error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if b.dedup && !b.seen.Visit(nd.Cid()) {
+		b.duplicates++
+		return nil
+	}
+	return b.batch.Add(ctx, nd)
+}
+
+func (b *batchAdder) AddMany(ctx context.Context, nds []ipld.Node) error {
+	for _, nd := range nds {
+		if err := b.Add(ctx, nd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *batchAdder) Remove(context.Context, cid.Cid) error {
+	return errBatchAdderRemove
+}
+
+func (b *batchAdder) RemoveMany(context.Context, []cid.Cid) error {
+	return errBatchAdderRemove
+}
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	chunker "github.com/ipfs/boxo/chunker"
+	"github.com/ipfs/boxo/files"
+	mdtest "github.com/ipfs/boxo/ipld/merkledag/test"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	bal "github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
+	h "github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	u "github.com/ipfs/boxo/util"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// countingDAGService counts the nodes added to it.
+type countingDAGService struct {
+	ipld.DAGService
+	added int
+}
+
+func (ds *countingDAGService) AddMany(ctx context.Context, nds []ipld.Node) error {
+	ds.added += len(nds)
+	return ds.DAGService.AddMany(ctx, nds)
+}
+
+func testTree(t *testing.T) (files.Directory, []byte) {
+	shared := make([]byte, 4096)
+	u.NewSeededRand(1).Read(shared)
+	other := make([]byte, 4096)
+	u.NewSeededRand(2).Read(other)
+
+	return files.NewMapDirectory(map[string]files.Node{
+		"a.bin": files.NewBytesFile(shared),
+		"sub": files.NewMapDirectory(map[string]files.Node{
+			"b.bin":  files.NewBytesFile(shared),
+			"c.bin":  files.NewBytesFile(other),
+			"link":   files.NewLinkFile("../a.bin", nil),
+			"nested": files.NewMapDirectory(map[string]files.Node{}),
+		}),
+	}), shared
+}
+
+func TestImportTree(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+	dir, shared := testTree(t)
+
+	res, err := ImportTree(ctx, ds, dir, WithChunker("size-1024"), WithRawLeaves(true), WithConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Files) != 4 {
+		t.Fatalf("expected 4 files, got %v", res.Files)
+	}
+	if res.Files["a.bin"] != res.Files["sub/b.bin"] {
+		t.Fatal("identical files should have the same root")
+	}
+	if res.Files["a.bin"] == res.Files["sub/c.bin"] {
+		t.Fatal("different files should have different roots")
+	}
+	if res.Duplicates != 0 {
+		t.Fatalf("expected no duplicates without dedup, got %d", res.Duplicates)
+	}
+
+	// every file must match the one imported alone
+	dbp := h.DagBuilderParams{Dagserv: ds, Maxlinks: h.DefaultLinksPerBlock, RawLeaves: true}
+	db, err := dbp.New(chunker.NewSizeSplitter(bytes.NewReader(shared), 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := bal.Layout(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Files["a.bin"] != expected.Cid() {
+		t.Fatalf("expected file root %s, got %s", expected.Cid(), res.Files["a.bin"])
+	}
+
+	// the root directory links all the imported entries
+	rootDir, err := uio.NewDirectoryFromNode(ds, res.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := rootDir.Find(ctx, "sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subDir, err := uio.NewDirectoryFromNode(ds, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links, err := subDir.Links(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 4 {
+		t.Fatalf("expected 4 links in sub, got %d", len(links))
+	}
+	for _, l := range links {
+		if l.Name == "link" {
+			nd, err := ds.Get(ctx, l.Cid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fsn, err := ft.ExtractFSNode(nd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fsn.Type() != ft.TSymlink || string(fsn.Data()) != "../a.bin" {
+				t.Fatalf("unexpected symlink %v %q", fsn.Type(), fsn.Data())
+			}
+		}
+	}
+
+	// the import is deterministic
+	dir, _ = testTree(t)
+	again, err := ImportTree(ctx, mdtest.Mock(), dir, WithChunker("size-1024"), WithRawLeaves(true), WithConcurrency(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Root.Cid() != res.Root.Cid() {
+		t.Fatalf("expected root %s, got %s", res.Root.Cid(), again.Root.Cid())
+	}
+}
+
+func TestImportTreeDedup(t *testing.T) {
+	ctx := context.Background()
+
+	dir, _ := testTree(t)
+	plain := &countingDAGService{DAGService: mdtest.Mock()}
+	res, err := ImportTree(ctx, plain, dir, WithChunker("size-1024"), WithRawLeaves(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, _ = testTree(t)
+	deduped := &countingDAGService{DAGService: mdtest.Mock()}
+	dres, err := ImportTree(ctx, deduped, dir, WithChunker("size-1024"), WithRawLeaves(true), WithLeafDedup(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dres.Root.Cid() != res.Root.Cid() {
+		t.Fatalf("dedup changed the root: %s != %s", dres.Root.Cid(), res.Root.Cid())
+	}
+	// the 4 leaves and the root of the shared file are only added once
+	if dres.Duplicates != 5 {
+		t.Fatalf("expected 5 duplicates, got %d", dres.Duplicates)
+	}
+	if deduped.added != plain.added-5 {
+		t.Fatalf("expected %d nodes added, got %d", plain.added-5, deduped.added)
+	}
+}
+
+func TestImportTreeErrors(t *testing.T) {
+	ctx := context.Background()
+	dir, _ := testTree(t)
+
+	if _, err := ImportTree(ctx, mdtest.Mock(), dir, WithChunker("nope")); err == nil {
+		t.Fatal("expected an invalid chunker error")
+	}
+	if _, err := ImportTree(ctx, mdtest.Mock(), dir, WithConcurrency(0)); err == nil {
+		t.Fatal("expected an invalid concurrency error")
+	}
+}
@@ -38,6 +38,8 @@ const depthRepeat = 4
 // explanation.
 func Layout(db *h.DagBuilderHelper) (ipld.Node, error) {
 	newRoot := db.NewFSNodeOverDag(ft.TFile)
+	newRoot.SetFileMode(db.FileMode())
+	newRoot.SetFileModTime(db.FileModTime())
 	root, _, err := fillTrickleRec(db, newRoot, -1)
 	if err != nil {
 		return nil, err
@@ -5,10 +5,15 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 
+	chunk "github.com/ipfs/boxo/chunker"
 	mdag "github.com/ipfs/boxo/ipld/merkledag"
 	unixfs "github.com/ipfs/boxo/ipld/unixfs"
+	"github.com/ipfs/go-cid"
 	ipld "github.com/ipfs/go-ipld-format"
+
+	lru "github.com/hashicorp/golang-lru/v2"
 )
 
 // Common errors
@@ -26,6 +31,8 @@ var (
 // A DagReader provides read-only read and seek acess to a unixfs file.
 // Different implementations of readers are used for the different
 // types of unixfs/protobuf-encoded nodes.
+//
+// The readers returned by [NewDagReader] also implement [io.ReaderAt].
 type DagReader interface {
 	ReadSeekCloser
 	Size() uint64
@@ -42,7 +49,12 @@ type ReadSeekCloser interface {
 
 // NewDagReader creates a new reader object that reads the data represented by
 // the given node, using the passed in DAGService for data retrieval.
-func NewDagReader(ctx context.Context, n ipld.Node, serv ipld.NodeGetter) (DagReader, error) {
+func NewDagReader(ctx context.Context, n ipld.Node, serv ipld.NodeGetter, opts ...DagReaderOption) (DagReader, error) {
+	var o dagReaderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	var size uint64
 
 	switch n := n.(type) {
@@ -76,7 +88,7 @@ func NewDagReader(ctx context.Context, n ipld.Node, serv ipld.NodeGetter) (DagRe
 			if !ok {
 				return nil, mdag.ErrNotProtobuf
 			}
-			return NewDagReader(ctx, childpb, serv)
+			return NewDagReader(ctx, childpb, serv, opts...)
 		case unixfs.TSymlink:
 			return nil, ErrCantReadSymlinks
 		default:
@@ -88,14 +100,16 @@ func NewDagReader(ctx context.Context, n ipld.Node, serv ipld.NodeGetter) (DagRe
 
 	ctxWithCancel, cancel := context.WithCancel(ctx)
 
-	return &dagReader{
+	dr := &dagReader{
 		ctx:       ctxWithCancel,
 		cancel:    cancel,
 		serv:      serv,
 		size:      size,
 		rootNode:  n,
-		dagWalker: ipld.NewWalker(ctxWithCancel, ipld.NewNavigableIPLDNode(n, serv)),
-	}, nil
+		readAhead: o.readAhead,
+	}
+	dr.dagWalker = ipld.NewWalker(ctxWithCancel, dr.navigableRoot())
+	return dr, nil
 }
 
 // dagReader provides a way to easily read the data contained in a dag.
@@ -130,6 +144,27 @@ type dagReader struct {
 	// Passed to the `dagWalker` that will use it to request nodes.
 	// TODO: Revisit name.
 	serv ipld.NodeGetter
+
+	// Number of blocks requested ahead, see `WithReadAhead`.
+	readAhead int
+
+	// Intermediate nodes fetched by `ReadAt`, created on its first call.
+	readAtNodesOnce sync.Once
+	readAtNodes     *lru.Cache[cid.Cid, ipld.Node]
+}
+
+var _ io.ReaderAt = (*dagReader)(nil)
+
+// readAtCacheSize is the number of intermediate nodes kept between `ReadAt`
+// calls, so that they do not fetch the path to the leaves again.
+const readAtCacheSize = 128
+
+// navigableRoot returns the root node to create the `dagWalker` with.
+func (dr *dagReader) navigableRoot() ipld.NavigableNode {
+	if dr.readAhead > 0 {
+		return newReadAheadNode(dr.rootNode, dr.serv, dr.readAhead)
+	}
+	return ipld.NewNavigableIPLDNode(dr.rootNode, dr.serv)
 }
 
 // Size returns the total size of the data from the DAG structured file.
@@ -324,6 +359,108 @@ func (dr *dagReader) WriteTo(w io.Writer) (n int64, err error) {
 	return n, err
 }
 
+// ReadAt implements `io.ReaderAt`, reading from a new position of the DAG
+// without changing the position of the reader, so it can be called
+// concurrently with other methods. The intermediate nodes are cached between
+// calls. The nodes are fetched with the internal context of the reader, and
+// the blocks read ahead are limited to the ones the call may need, and
+// cancelled when it returns.
+func (dr *dagReader) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("invalid offset")
+	}
+	if off >= int64(dr.size) {
+		return 0, io.EOF
+	}
+
+	dr.readAtNodesOnce.Do(func() {
+		dr.readAtNodes, _ = lru.New[cid.Cid, ipld.Node](readAtCacheSize)
+	})
+
+	ctx, cancel := context.WithCancel(dr.ctx)
+	defer cancel()
+
+	// only request the blocks the read may need
+	readAhead := len(b)/int(chunk.DefaultBlockSize) + 1
+	if dr.readAhead > 0 {
+		readAhead = min(readAhead, dr.readAhead)
+	}
+	r := &dagReader{
+		ctx:       ctx,
+		cancel:    cancel,
+		serv:      &cachedNodeGetter{NodeGetter: dr.serv, nodes: dr.readAtNodes},
+		size:      dr.size,
+		rootNode:  dr.rootNode,
+		readAhead: readAhead,
+	}
+	r.dagWalker = ipld.NewWalker(ctx, r.navigableRoot())
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := r.CtxReadFull(ctx, b)
+	if err == nil && n < len(b) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// cachedNodeGetter keeps the intermediate nodes it gets in a cache. Leaves,
+// which hold the data, are not cached.
+type cachedNodeGetter struct {
+	ipld.NodeGetter
+	nodes *lru.Cache[cid.Cid, ipld.Node]
+}
+
+func (g *cachedNodeGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	if nd, ok := g.nodes.Get(c); ok {
+		return nd, nil
+	}
+	nd, err := g.NodeGetter.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	g.add(nd)
+	return nd, nil
+}
+
+func (g *cachedNodeGetter) GetMany(ctx context.Context, keys []cid.Cid) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(keys))
+	var missing []cid.Cid
+	for _, c := range keys {
+		if nd, ok := g.nodes.Get(c); ok {
+			out <- &ipld.NodeOption{Node: nd}
+		} else {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) == 0 {
+		close(out)
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		for opt := range g.NodeGetter.GetMany(ctx, missing) {
+			if opt.Err == nil {
+				g.add(opt.Node)
+			}
+			select {
+			case out <- opt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (g *cachedNodeGetter) add(nd ipld.Node) {
+	if len(nd.Links()) > 0 {
+		g.nodes.Add(nd.Cid(), nd)
+	}
+}
+
 // Close the reader (cancelling fetch node operations requested with
 // the internal context, that is, `Read` calls but not `CtxReadFull`
 // with user-supplied contexts).
@@ -478,7 +615,7 @@ func (dr *dagReader) resetPosition() {
 	dr.currentNodeData = nil
 	dr.offset = 0
 
-	dr.dagWalker = ipld.NewWalker(dr.ctx, ipld.NewNavigableIPLDNode(dr.rootNode, dr.serv))
+	dr.dagWalker = ipld.NewWalker(dr.ctx, dr.navigableRoot())
 	// TODO: This could be avoided (along with storing the `dr.rootNode` and
 	// `dr.serv` just for this call) if `Reset` is supported in the `Walker`.
 }
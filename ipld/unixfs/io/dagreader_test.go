@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	mdag "github.com/ipfs/boxo/ipld/merkledag"
@@ -12,6 +13,8 @@ import (
 	context "context"
 
 	testu "github.com/ipfs/boxo/ipld/unixfs/test"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
 )
 
 func TestBasicRead(t *testing.T) {
@@ -284,6 +287,84 @@ func TestWriteTo(t *testing.T) {
 	}
 }
 
+func TestReadAt(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	inbuf, node := testu.GetRandomNode(t, dserv, 10000, testu.UseProtoBufLeaves)
+	ctx, closer := context.WithCancel(context.Background())
+	defer closer()
+
+	reader, err := NewDagReader(ctx, node, dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Seek(100, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, off := range []int64{0, 1, 4095, 4096, 5000, 9990} {
+		out := make([]byte, 20)
+		n, err := reader.(io.ReaderAt).ReadAt(out, off)
+		expected := inbuf[off:min(off+20, int64(len(inbuf)))]
+		if n != len(expected) {
+			t.Fatalf("read %d bytes at offset %d, expected %d", n, off, len(expected))
+		}
+		if len(expected) < len(out) && err != io.EOF {
+			t.Fatalf("expected EOF at offset %d, got %v", off, err)
+		} else if len(expected) == len(out) && err != nil {
+			t.Fatal(err)
+		}
+		if err := testu.ArrComp(expected, out[:n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := reader.(io.ReaderAt).ReadAt(make([]byte, 1), int64(len(inbuf))); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	// the position of the reader is unchanged
+	if getOffset(reader) != 100 {
+		t.Fatal("expected ReadAt to keep the offset of the reader")
+	}
+	if out := readByte(t, reader); out != inbuf[100] {
+		t.Fatalf("read %d, expected %d", out, inbuf[100])
+	}
+
+	// the intermediate nodes are not fetched again
+	_, node = testu.GetRandomNode(t, dserv, 200000, testu.UseProtoBufLeaves)
+	counting := &countingNodeGetter{NodeGetter: dserv}
+	reader, err = NewDagReader(ctx, node, counting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra := reader.(io.ReaderAt)
+	if _, err := ra.ReadAt(make([]byte, 10), 190000); err != nil {
+		t.Fatal(err)
+	}
+	first := counting.count.Load()
+	if _, err := ra.ReadAt(make([]byte, 10), 190000); err != nil {
+		t.Fatal(err)
+	}
+	if second := counting.count.Load() - first; second >= first {
+		t.Fatalf("expected the cached nodes not to be fetched again, got %d then %d nodes", first, second)
+	}
+}
+
+// countingNodeGetter counts the nodes requested from it.
+type countingNodeGetter struct {
+	ipld.NodeGetter
+	count atomic.Int64
+}
+
+func (g *countingNodeGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	g.count.Add(1)
+	return g.NodeGetter.Get(ctx, c)
+}
+
+func (g *countingNodeGetter) GetMany(ctx context.Context, keys []cid.Cid) <-chan *ipld.NodeOption {
+	g.count.Add(int64(len(keys)))
+	return g.NodeGetter.GetMany(ctx, keys)
+}
+
 func TestReaderSzie(t *testing.T) {
 	dserv := testu.GetDAGServ()
 	size := int64(1024)
@@ -301,6 +382,104 @@ func TestReaderSzie(t *testing.T) {
 	}
 }
 
+// countingGetter counts the nodes requested from the node getter.
+type countingGetter struct {
+	ipld.NodeGetter
+	requested atomic.Int64
+}
+
+func (g *countingGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	g.requested.Add(1)
+	return g.NodeGetter.Get(ctx, c)
+}
+
+func (g *countingGetter) GetMany(ctx context.Context, ks []cid.Cid) <-chan *ipld.NodeOption {
+	g.requested.Add(int64(len(ks)))
+	return g.NodeGetter.GetMany(ctx, ks)
+}
+
+func TestReadAhead(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	inbuf, node := testu.GetRandomNode(t, dserv, 50000, testu.UseRawLeaves)
+	ctx, closer := context.WithCancel(context.Background())
+	defer closer()
+
+	getter := &countingGetter{NodeGetter: dserv}
+	reader, err := NewDagReader(ctx, node, getter, WithReadAhead(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the first byte requests the blocks of the read-ahead window
+	if out := readByte(t, reader); out != inbuf[0] {
+		t.Fatalf("read %d, expected %d", out, inbuf[0])
+	}
+	if requested := getter.requested.Load(); requested < 20 {
+		t.Fatalf("expected at least 20 blocks to be requested, got %d", requested)
+	}
+
+	outbuf, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testu.ArrComp(inbuf[1:], outbuf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadAheadSeek(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	inbuf, node := testu.GetRandomNode(t, dserv, 50000, testu.UseRawLeaves)
+	ctx, closer := context.WithCancel(context.Background())
+	defer closer()
+
+	reader, err := NewDagReader(ctx, node, dserv, WithReadAhead(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(off int64, n int) {
+		t.Helper()
+		out := make([]byte, n)
+		if _, err := io.ReadFull(reader, out); err != nil {
+			t.Fatal(err)
+		}
+		if err := testu.ArrComp(inbuf[off:off+int64(n)], out); err != nil {
+			t.Fatalf("at offset %d: %s", off, err)
+		}
+	}
+
+	read(0, 1200)
+	// forward, past the read-ahead window
+	if _, err := reader.Seek(30000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	read(30000, 1000)
+	// backward
+	if _, err := reader.Seek(700, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	read(700, 2000)
+	// relative, within the read-ahead window
+	if _, err := reader.Seek(1500, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	read(4200, 3000)
+	// from the end
+	if _, err := reader.Seek(-100, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	read(int64(len(inbuf))-100, 100)
+
+	out := make([]byte, 500)
+	if _, err := reader.(io.ReaderAt).ReadAt(out, 20000); err != nil {
+		t.Fatal(err)
+	}
+	if err := testu.ArrComp(inbuf[20000:20500], out); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func readByte(t testing.TB, reader DagReader) byte {
 	out := make([]byte, 1)
 	c, err := reader.Read(out)
@@ -0,0 +1,316 @@
+package io
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	mdag "github.com/ipfs/boxo/ipld/merkledag"
+	format "github.com/ipfs/boxo/ipld/unixfs"
+	pb "github.com/ipfs/boxo/ipld/unixfs/pb"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// ChangeType is the kind of a [Change].
+type ChangeType int
+
+const (
+	// ChangeAdded is an entry only found in the new directory.
+	ChangeAdded ChangeType = iota
+	// ChangeRemoved is an entry only found in the old directory.
+	ChangeRemoved
+	// ChangeModified is an entry found in both directories with a different
+	// CID, which is not a directory on both sides.
+	ChangeModified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return fmt.Sprintf("ChangeType(%d)", int(t))
+	}
+}
+
+// Change is a difference between two UnixFS DAGs found by [Diff].
+type Change struct {
+	Type ChangeType
+	// Path is the slash-separated path of the entry relative to the roots,
+	// empty when the roots themselves are not both directories.
+	Path string
+
+	// Before and BeforeType are the CID and UnixFS type of the entry in the
+	// old DAG, unset for added entries. Raw leaves have the TFile type.
+	Before     cid.Cid
+	BeforeType pb.Data_DataType
+	// After and AfterType are the CID and UnixFS type of the entry in the
+	// new DAG, unset for removed entries.
+	After     cid.Cid
+	AfterType pb.Data_DataType
+}
+
+// DiffResult is a [Change] or an error streamed by [Diff].
+type DiffResult struct {
+	Change Change
+	Err    error
+}
+
+// Diff compares the UnixFS DAGs of oldRoot and newRoot and streams their
+// differences, depth-first and sorted by name. Directories found on both sides
+// are walked recursively, skipping the entries, and the HAMT shards, that
+// have the same CID on both sides. Added and removed directories are reported
+// as a single change, without their content.
+//
+// The channel is closed after the last change, or after an error.
+func Diff(ctx context.Context, dserv ipld.DAGService, oldRoot, newRoot cid.Cid) <-chan DiffResult {
+	out := make(chan DiffResult)
+	go func() {
+		defer close(out)
+		d := differ{dserv: dserv, out: out}
+		if err := d.diff(ctx, "", oldRoot, newRoot); err != nil {
+			select {
+			case out <- DiffResult{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out
+}
+
+type differ struct {
+	dserv ipld.DAGService
+	out   chan<- DiffResult
+}
+
+func (d *differ) emit(ctx context.Context, c Change) error {
+	select {
+	case d.out <- DiffResult{Change: c}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *differ) diff(ctx context.Context, p string, before, after cid.Cid) error {
+	if before.Equals(after) {
+		return nil
+	}
+
+	oldNode, err := d.dserv.Get(ctx, before)
+	if err != nil {
+		return err
+	}
+	newNode, err := d.dserv.Get(ctx, after)
+	if err != nil {
+		return err
+	}
+	oldFsn, oldType, err := nodeType(oldNode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", before, err)
+	}
+	newFsn, newType, err := nodeType(newNode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", after, err)
+	}
+
+	if !isDir(oldType) || !isDir(newType) {
+		return d.emit(ctx, Change{
+			Type:       ChangeModified,
+			Path:       p,
+			Before:     before,
+			BeforeType: oldType,
+			After:      after,
+			AfterType:  newType,
+		})
+	}
+
+	oldEntries := make(map[string]cid.Cid)
+	newEntries := make(map[string]cid.Cid)
+	if oldType == format.THAMTShard && newType == format.THAMTShard &&
+		oldFsn.Fanout() > 0 && oldFsn.Fanout() == newFsn.Fanout() && oldFsn.HashType() == newFsn.HashType() {
+		padLen := len(fmt.Sprintf("%X", oldFsn.Fanout()-1))
+		err = d.diffShards(ctx, oldNode.(*mdag.ProtoNode), newNode.(*mdag.ProtoNode), padLen, oldEntries, newEntries)
+	} else {
+		if err = d.dirEntries(ctx, oldNode, oldEntries); err == nil {
+			err = d.dirEntries(ctx, newNode, newEntries)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(oldEntries)+len(newEntries))
+	for name := range oldEntries {
+		names = append(names, name)
+	}
+	for name := range newEntries {
+		if _, ok := oldEntries[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldCid, inOld := oldEntries[name]
+		newCid, inNew := newEntries[name]
+		childPath := path.Join(p, name)
+		switch {
+		case inOld && inNew:
+			err = d.diff(ctx, childPath, oldCid, newCid)
+		case inOld:
+			err = d.emitEntry(ctx, ChangeRemoved, childPath, oldCid)
+		default:
+			err = d.emitEntry(ctx, ChangeAdded, childPath, newCid)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitEntry emits the addition or removal of an entry, loading it to find its
+// type.
+func (d *differ) emitEntry(ctx context.Context, t ChangeType, p string, c cid.Cid) error {
+	nd, err := d.dserv.Get(ctx, c)
+	if err != nil {
+		return err
+	}
+	_, typ, err := nodeType(nd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c, err)
+	}
+	change := Change{Type: t, Path: p}
+	if t == ChangeAdded {
+		change.After, change.AfterType = c, typ
+	} else {
+		change.Before, change.BeforeType = c, typ
+	}
+	return d.emit(ctx, change)
+}
+
+// dirEntries adds all the entries of a basic or HAMT directory to entries.
+func (d *differ) dirEntries(ctx context.Context, nd ipld.Node, entries map[string]cid.Cid) error {
+	dir, err := NewDirectoryFromNode(d.dserv, nd)
+	if err != nil {
+		return err
+	}
+	return dir.ForEachLink(ctx, func(l *ipld.Link) error {
+		entries[l.Name] = l.Cid
+		return nil
+	})
+}
+
+// diffShards collects the entries of two HAMT shards with the same fanout and
+// hash function, skipping the buckets holding the same entry or sub-shard on
+// both sides. Entries can move between levels of the HAMT, so the collected
+// entries still need to be compared by name.
+func (d *differ) diffShards(ctx context.Context, oldShard, newShard *mdag.ProtoNode, padLen int, oldEntries, newEntries map[string]cid.Cid) error {
+	oldLinks := shardBuckets(oldShard, padLen)
+	newLinks := shardBuckets(newShard, padLen)
+
+	for prefix, ol := range oldLinks {
+		nl, ok := newLinks[prefix]
+		if !ok {
+			if err := d.shardEntries(ctx, ol, padLen, oldEntries); err != nil {
+				return err
+			}
+			continue
+		}
+		if ol.Cid.Equals(nl.Cid) {
+			continue
+		}
+		if len(ol.Name) == padLen && len(nl.Name) == padLen {
+			oldChild, err := d.loadShard(ctx, ol.Cid)
+			if err != nil {
+				return err
+			}
+			newChild, err := d.loadShard(ctx, nl.Cid)
+			if err != nil {
+				return err
+			}
+			if err := d.diffShards(ctx, oldChild, newChild, padLen, oldEntries, newEntries); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.shardEntries(ctx, ol, padLen, oldEntries); err != nil {
+			return err
+		}
+		if err := d.shardEntries(ctx, nl, padLen, newEntries); err != nil {
+			return err
+		}
+	}
+	for prefix, nl := range newLinks {
+		if _, ok := oldLinks[prefix]; !ok {
+			if err := d.shardEntries(ctx, nl, padLen, newEntries); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// shardBuckets indexes the links of a HAMT shard by bucket.
+func shardBuckets(shard *mdag.ProtoNode, padLen int) map[string]*ipld.Link {
+	links := make(map[string]*ipld.Link, len(shard.Links()))
+	for _, l := range shard.Links() {
+		if len(l.Name) >= padLen {
+			links[l.Name[:padLen]] = l
+		}
+	}
+	return links
+}
+
+// shardEntries adds the entry of a HAMT shard link, or all the entries of the
+// sub-shard it links to, to entries.
+func (d *differ) shardEntries(ctx context.Context, l *ipld.Link, padLen int, entries map[string]cid.Cid) error {
+	if len(l.Name) > padLen {
+		entries[l.Name[padLen:]] = l.Cid
+		return nil
+	}
+	child, err := d.loadShard(ctx, l.Cid)
+	if err != nil {
+		return err
+	}
+	return d.dirEntries(ctx, child, entries)
+}
+
+func (d *differ) loadShard(ctx context.Context, c cid.Cid) (*mdag.ProtoNode, error) {
+	nd, err := d.dserv.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	pn, ok := nd.(*mdag.ProtoNode)
+	if !ok {
+		return nil, mdag.ErrNotProtobuf
+	}
+	return pn, nil
+}
+
+// nodeType returns the UnixFS node and type of nd, raw nodes being files.
+func nodeType(nd ipld.Node) (*format.FSNode, pb.Data_DataType, error) {
+	switch nd := nd.(type) {
+	case *mdag.RawNode:
+		return nil, format.TFile, nil
+	case *mdag.ProtoNode:
+		fsn, err := format.FSNodeFromBytes(nd.Data())
+		if err != nil {
+			return nil, 0, err
+		}
+		return fsn, fsn.Type(), nil
+	default:
+		return nil, 0, ErrUnkownNodeType
+	}
+}
+
+func isDir(t pb.Data_DataType) bool {
+	return t == format.TDirectory || t == format.THAMTShard
+}
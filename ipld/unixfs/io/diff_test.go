@@ -0,0 +1,128 @@
+package io
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	mdag "github.com/ipfs/boxo/ipld/merkledag"
+	mdtest "github.com/ipfs/boxo/ipld/merkledag/test"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+func makeDiffDir(t *testing.T, ds ipld.DAGService, entries map[string]ipld.Node, opts ...DirectoryOption) ipld.Node {
+	t.Helper()
+	ctx := context.Background()
+	dir := NewDirectory(ds, opts...)
+	for name, nd := range entries {
+		if err := ds.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+		if err := dir.AddChild(ctx, name, nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nd, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Add(ctx, nd); err != nil {
+		t.Fatal(err)
+	}
+	return nd
+}
+
+func collectDiff(t *testing.T, ds ipld.DAGService, before, after ipld.Node) []Change {
+	t.Helper()
+	var changes []Change
+	for res := range Diff(context.Background(), ds, before.Cid(), after.Cid()) {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		changes = append(changes, res.Change)
+	}
+	return changes
+}
+
+func TestDiff(t *testing.T) {
+	ds := mdtest.Mock()
+
+	fileA := mdag.NewRawNode([]byte("a"))
+	fileB := mdag.NewRawNode([]byte("b"))
+	fileB2 := mdag.NewRawNode([]byte("b2"))
+	gone := mdag.NewRawNode([]byte("gone"))
+	added := mdag.NewRawNode([]byte("added"))
+	x := mdag.NewRawNode([]byte("x"))
+	y := mdag.NewRawNode([]byte("y"))
+
+	oldSub := makeDiffDir(t, ds, map[string]ipld.Node{"x": x})
+	newSub := makeDiffDir(t, ds, map[string]ipld.Node{"x": x, "y": y})
+	empty := ft.EmptyDirNode()
+
+	before := makeDiffDir(t, ds, map[string]ipld.Node{"a": fileA, "b": fileB, "gone": gone, "sub": oldSub, "type": fileA})
+	after := makeDiffDir(t, ds, map[string]ipld.Node{"a": fileA, "b": fileB2, "new": added, "sub": newSub, "type": empty, "z": empty})
+
+	expected := []Change{
+		{Type: ChangeModified, Path: "b", Before: fileB.Cid(), BeforeType: ft.TFile, After: fileB2.Cid(), AfterType: ft.TFile},
+		{Type: ChangeRemoved, Path: "gone", Before: gone.Cid(), BeforeType: ft.TFile},
+		{Type: ChangeAdded, Path: "new", After: added.Cid(), AfterType: ft.TFile},
+		{Type: ChangeAdded, Path: "sub/y", After: y.Cid(), AfterType: ft.TFile},
+		{Type: ChangeModified, Path: "type", Before: fileA.Cid(), BeforeType: ft.TFile, After: empty.Cid(), AfterType: ft.TDirectory},
+		{Type: ChangeAdded, Path: "z", After: empty.Cid(), AfterType: ft.TDirectory},
+	}
+	if changes := collectDiff(t, ds, before, after); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+
+	if changes := collectDiff(t, ds, before, before); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+
+	// roots that are not both directories are a single change
+	changes := collectDiff(t, ds, fileA, after)
+	if len(changes) != 1 || changes[0].Path != "" || changes[0].AfterType != ft.TDirectory {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+}
+
+func TestDiffHAMT(t *testing.T) {
+	ds := mdtest.Mock()
+
+	oldEntries := make(map[string]ipld.Node)
+	for i := 0; i < 300; i++ {
+		oldEntries[fmt.Sprintf("file-%d", i)] = mdag.NewRawNode([]byte(fmt.Sprint(i)))
+	}
+	newEntries := make(map[string]ipld.Node, len(oldEntries))
+	for name, nd := range oldEntries {
+		newEntries[name] = nd
+	}
+	delete(newEntries, "file-1")
+	modified := mdag.NewRawNode([]byte("modified"))
+	newEntries["file-2"] = modified
+	added := mdag.NewRawNode([]byte("added"))
+	newEntries["file-300"] = added
+
+	expected := []Change{
+		{Type: ChangeRemoved, Path: "file-1", Before: oldEntries["file-1"].Cid(), BeforeType: ft.TFile},
+		{Type: ChangeModified, Path: "file-2", Before: oldEntries["file-2"].Cid(), BeforeType: ft.TFile, After: modified.Cid(), AfterType: ft.TFile},
+		{Type: ChangeAdded, Path: "file-300", After: added.Cid(), AfterType: ft.TFile},
+	}
+
+	shardOpts := []DirectoryOption{WithShardingSize(1), WithShardWidth(16)}
+	before := makeDiffDir(t, ds, oldEntries, shardOpts...)
+	after := makeDiffDir(t, ds, newEntries, shardOpts...)
+	if fsn, err := ft.ExtractFSNode(after); err != nil || fsn.Type() != ft.THAMTShard {
+		t.Fatalf("expected a HAMT directory, got %v", err)
+	}
+	if changes := collectDiff(t, ds, before, after); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+
+	// a basic directory compared with a HAMT
+	basic := makeDiffDir(t, ds, newEntries, WithShardingSize(0))
+	if changes := collectDiff(t, ds, before, basic); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+}
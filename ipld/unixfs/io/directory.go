@@ -3,6 +3,7 @@ package io
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 
 	"github.com/ipfs/boxo/ipld/unixfs/hamt"
@@ -30,6 +31,74 @@ var HAMTShardingSize = int(256 * units.KiB)
 // Needs to be a power of two (shard entry size) and multiple of 8 (bitfield size).
 var DefaultShardWidth = 256
 
+// DirectoryOption configures the HAMT sharding of the directories created by
+// [NewDirectory] and [NewDirectoryFromNode]. Unset parameters use the
+// [HAMTShardingSize] and [DefaultShardWidth] globals and the murmur3 hash.
+type DirectoryOption func(*directoryOptions)
+
+type directoryOptions struct {
+	shardWidth   int
+	hashFunc     uint64
+	shardingSize *int
+}
+
+// WithShardWidth sets the fanout of the HAMT shards created when the
+// directory grows above the sharding threshold. It must be a power of two
+// and a multiple of 8.
+func WithShardWidth(width int) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.shardWidth = width
+	}
+}
+
+// WithShardHashFunction sets the hash function, identified by its multicodec
+// code, of the HAMT shards created when the directory grows above the sharding
+// threshold. Other hash functions than [hamt.HashMurmur3] must be registered
+// with [hamt.RegisterHashFunction] and may not be supported by other
+// implementations.
+func WithShardHashFunction(code uint64) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.hashFunc = code
+	}
+}
+
+// WithShardingSize sets the estimated size in bytes above which the directory
+// switches to a HAMT, see [HAMTShardingSize]. Zero disables sharding.
+func WithShardingSize(size int) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.shardingSize = &size
+	}
+}
+
+func processDirectoryOptions(opts []DirectoryOption) directoryOptions {
+	var o directoryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o directoryOptions) getShardWidth() int {
+	if o.shardWidth == 0 {
+		return DefaultShardWidth
+	}
+	return o.shardWidth
+}
+
+func (o directoryOptions) getHashFunc() uint64 {
+	if o.hashFunc == 0 {
+		return hamt.HashMurmur3
+	}
+	return o.hashFunc
+}
+
+func (o directoryOptions) getShardingSize() int {
+	if o.shardingSize == nil {
+		return HAMTShardingSize
+	}
+	return *o.shardingSize
+}
+
 // Directory defines a UnixFS directory. It is used for creating, reading and
 // editing directories. It allows to work with different directory schemes,
 // like the basic or the HAMT implementation.
@@ -97,6 +166,8 @@ type BasicDirectory struct {
 	// (We maintain this value up to date even if the HAMTShardingSize is off
 	// since potentially the option could be activated on the fly.)
 	estimatedSize int
+
+	opts directoryOptions
 }
 
 // HAMTDirectory is the HAMT implementation of `Directory`.
@@ -108,6 +179,8 @@ type HAMTDirectory struct {
 	// Track the changes in size by the AddChild and RemoveChild calls
 	// for the HAMTShardingSize option.
 	sizeChange int
+
+	opts directoryOptions
 }
 
 func newEmptyBasicDirectory(dserv ipld.DAGService) *BasicDirectory {
@@ -127,16 +200,21 @@ func newBasicDirectoryFromNode(dserv ipld.DAGService, node *mdag.ProtoNode) *Bas
 
 // NewDirectory returns a Directory implemented by DynamicDirectory
 // containing a BasicDirectory that can be converted to a HAMTDirectory.
-func NewDirectory(dserv ipld.DAGService) Directory {
-	return &DynamicDirectory{newEmptyBasicDirectory(dserv)}
+// Invalid sharding options are reported by AddChild when the directory
+// switches to a HAMT.
+func NewDirectory(dserv ipld.DAGService, opts ...DirectoryOption) Directory {
+	basicDir := newEmptyBasicDirectory(dserv)
+	basicDir.opts = processDirectoryOptions(opts)
+	return &DynamicDirectory{basicDir}
 }
 
 // ErrNotADir implies that the given node was not a unixfs directory
 var ErrNotADir = errors.New("merkledag node was not a directory or shard")
 
 // NewDirectoryFromNode loads a unixfs directory from the given IPLD node and
-// DAGService.
-func NewDirectoryFromNode(dserv ipld.DAGService, node ipld.Node) (Directory, error) {
+// DAGService. The options apply when the directory switches between a basic
+// directory and a HAMT, an existing HAMT keeps its fanout and hash function.
+func NewDirectoryFromNode(dserv ipld.DAGService, node ipld.Node, opts ...DirectoryOption) (Directory, error) {
 	protoBufNode, ok := node.(*mdag.ProtoNode)
 	if !ok {
 		return nil, ErrNotADir
@@ -149,13 +227,15 @@ func NewDirectoryFromNode(dserv ipld.DAGService, node ipld.Node) (Directory, err
 
 	switch fsNode.Type() {
 	case format.TDirectory:
-		return &DynamicDirectory{newBasicDirectoryFromNode(dserv, protoBufNode.Copy().(*mdag.ProtoNode))}, nil
+		basicDir := newBasicDirectoryFromNode(dserv, protoBufNode.Copy().(*mdag.ProtoNode))
+		basicDir.opts = processDirectoryOptions(opts)
+		return &DynamicDirectory{basicDir}, nil
 	case format.THAMTShard:
 		shard, err := hamt.NewHamtFromDag(dserv, node)
 		if err != nil {
 			return nil, err
 		}
-		return &DynamicDirectory{&HAMTDirectory{shard, dserv, 0}}, nil
+		return &DynamicDirectory{&HAMTDirectory{shard: shard, dserv: dserv, opts: processDirectoryOptions(opts)}}, nil
 	}
 
 	return nil, ErrNotADir
@@ -203,7 +283,8 @@ func (d *BasicDirectory) AddChild(ctx context.Context, name string, node ipld.No
 }
 
 func (d *BasicDirectory) needsToSwitchToHAMTDir(name string, nodeToAdd ipld.Node) (bool, error) {
-	if HAMTShardingSize == 0 { // Option disabled.
+	shardingSize := d.opts.getShardingSize()
+	if shardingSize == 0 { // Option disabled.
 		return false, nil
 	}
 
@@ -220,7 +301,7 @@ func (d *BasicDirectory) needsToSwitchToHAMTDir(name string, nodeToAdd ipld.Node
 		operationSizeChange += linksize.LinkSizeFunction(name, nodeToAdd.Cid())
 	}
 
-	return d.estimatedSize+operationSizeChange >= HAMTShardingSize, nil
+	return d.estimatedSize+operationSizeChange >= shardingSize, nil
 }
 
 // addLinkChild adds the link as an entry to this directory under the given
@@ -325,10 +406,11 @@ func (d *BasicDirectory) GetCidBuilder() cid.Builder {
 func (d *BasicDirectory) switchToSharding(ctx context.Context) (*HAMTDirectory, error) {
 	hamtDir := new(HAMTDirectory)
 	hamtDir.dserv = d.dserv
+	hamtDir.opts = d.opts
 
-	shard, err := hamt.NewShard(d.dserv, DefaultShardWidth)
+	shard, err := hamt.NewShardWithHash(d.dserv, d.opts.getShardWidth(), d.opts.getHashFunc())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid HAMT parameters: %w", err)
 	}
 	shard.SetCidBuilder(d.node.CidBuilder())
 	hamtDir.shard = shard
@@ -415,6 +497,7 @@ func (d *HAMTDirectory) GetCidBuilder() cid.Builder {
 // switchToBasic returns a BasicDirectory implementation of this directory.
 func (d *HAMTDirectory) switchToBasic(ctx context.Context) (*BasicDirectory, error) {
 	basicDir := newEmptyBasicDirectory(d.dserv)
+	basicDir.opts = d.opts
 	basicDir.SetCidBuilder(d.GetCidBuilder())
 
 	err := d.ForEachLink(ctx, func(lnk *ipld.Link) error {
@@ -452,7 +535,7 @@ func (d *HAMTDirectory) removeFromSizeChange(name string, linkCid cid.Cid) {
 // nodeToAdd is nil). We compute both (potential) future subtraction and
 // addition to the size change.
 func (d *HAMTDirectory) needsToSwitchToBasicDir(ctx context.Context, name string, nodeToAdd ipld.Node) (switchToBasic bool, err error) {
-	if HAMTShardingSize == 0 { // Option disabled.
+	if d.opts.getShardingSize() == 0 { // Option disabled.
 		return false, nil
 	}
 
@@ -490,7 +573,8 @@ func (d *HAMTDirectory) needsToSwitchToBasicDir(ctx context.Context, name string
 // to keep counting) or an error occurs (like the context being canceled
 // if we take too much time fetching the necessary shards).
 func (d *HAMTDirectory) sizeBelowThreshold(ctx context.Context, sizeChange int) (below bool, err error) {
-	if HAMTShardingSize == 0 {
+	shardingSize := d.opts.getShardingSize()
+	if shardingSize == 0 {
 		panic("asked to compute HAMT size with HAMTShardingSize option off (0)")
 	}
 
@@ -508,7 +592,7 @@ func (d *HAMTDirectory) sizeBelowThreshold(ctx context.Context, sizeChange int)
 		}
 
 		partialSize += linksize.LinkSizeFunction(linkResult.Link.Name, linkResult.Link.Cid)
-		if partialSize+sizeChange >= HAMTShardingSize {
+		if partialSize+sizeChange >= shardingSize {
 			// We have already fetched enough shards to assert we are
 			//  above the threshold, so no need to keep fetching.
 			return false, nil
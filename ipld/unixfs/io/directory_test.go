@@ -2,6 +2,7 @@ package io
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"math"
 	"sort"
@@ -316,6 +317,62 @@ func TestIntegrityOfDirectorySwitch(t *testing.T) {
 	compareDirectoryEntries(t, hamtDir, hamtDirFromSwitch)
 }
 
+func TestDirectoryShardingOptions(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+	child := ft.EmptyDirNode()
+	assert.NoError(t, ds.Add(ctx, child))
+
+	hamt.RegisterHashFunction(0x12, func(val []byte) []byte {
+		sum := sha256.Sum256(val)
+		return sum[:]
+	})
+
+	for _, tc := range []struct {
+		width    int
+		hashFunc uint64
+	}{
+		{16, hamt.HashMurmur3},
+		{8, 0x12},
+	} {
+		dir := NewDirectory(ds, WithShardingSize(1), WithShardWidth(tc.width), WithShardHashFunction(tc.hashFunc))
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, dir.AddChild(ctx, strconv.Itoa(i), child))
+		}
+		checkHAMTDirectory(t, dir, "directory should have switched to a HAMT")
+
+		nd, err := dir.GetNode()
+		assert.NoError(t, err)
+		assert.NoError(t, ds.Add(ctx, nd))
+
+		// The parameters round-trip through the DAG
+		fsn, err := ft.FSNodeFromBytes(nd.(*mdag.ProtoNode).Data())
+		assert.NoError(t, err)
+		assert.EqualValues(t, tc.width, fsn.Fanout())
+		assert.Equal(t, tc.hashFunc, fsn.HashType())
+
+		loaded, err := NewDirectoryFromNode(ds, nd)
+		assert.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			_, err := loaded.Find(ctx, strconv.Itoa(i))
+			assert.NoError(t, err)
+		}
+	}
+
+	// Invalid parameters are reported when switching to a HAMT
+	for _, opt := range []DirectoryOption{WithShardWidth(12), WithShardHashFunction(0x1337)} {
+		dir := NewDirectory(ds, WithShardingSize(1), opt)
+		assert.Error(t, dir.AddChild(ctx, "a", child))
+	}
+
+	// Sharding can be disabled per directory
+	dir := NewDirectory(ds, WithShardingSize(0))
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, dir.AddChild(ctx, strconv.Itoa(i), child))
+	}
+	checkBasicDirectory(t, dir, "directory should not have switched to a HAMT")
+}
+
 // This is the value of concurrent fetches during dag.Walk. Used in
 // test to better predict how many nodes will be fetched.
 var defaultConcurrentFetch = 32
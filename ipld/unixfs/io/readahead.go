@@ -0,0 +1,112 @@
+package io
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// DagReaderOption configures the readers created by [NewDagReader].
+type DagReaderOption func(*dagReaderOptions)
+
+type dagReaderOptions struct {
+	readAhead int
+}
+
+// WithReadAhead keeps up to n sibling blocks, following the block being
+// read, requested from the node getter, so that sequential reads over
+// high-latency exchanges do not wait for every block in turn. Blocks are only
+// read ahead among the children of the same node. Seeking discards the blocks
+// read ahead of the previous position. Without it, blocks are requested in
+// batches of 10.
+func WithReadAhead(n int) DagReaderOption {
+	return func(o *dagReaderOptions) {
+		o.readAhead = n
+	}
+}
+
+// readAheadNode is an [ipld.NavigableNode] keeping readAhead children
+// requested ahead of the child being fetched.
+type readAheadNode struct {
+	node      ipld.Node
+	childCIDs []cid.Cid
+	promises  []*ipld.NodePromise
+	serv      ipld.NodeGetter
+	readAhead int
+}
+
+func newReadAheadNode(node ipld.Node, serv ipld.NodeGetter, readAhead int) *readAheadNode {
+	links := node.Links()
+	childCIDs := make([]cid.Cid, len(links))
+	for i, l := range links {
+		childCIDs[i] = l.Cid
+	}
+	return &readAheadNode{
+		node:      node,
+		childCIDs: childCIDs,
+		promises:  make([]*ipld.NodePromise, len(childCIDs)),
+		serv:      serv,
+		readAhead: readAhead,
+	}
+}
+
+// FetchChild returns the child, requesting the children in the read-ahead
+// window which are not requested yet.
+func (n *readAheadNode) FetchChild(ctx context.Context, childIndex uint) (ipld.NavigableNode, error) {
+	n.request(ctx, childIndex)
+	child, err := n.get(ctx, childIndex)
+	switch err {
+	case nil:
+	case context.DeadlineExceeded, context.Canceled:
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// The context of a previous read, used to request the child ahead,
+		// was cancelled: request it again with the current one.
+		n.request(ctx, childIndex)
+		child, err = n.get(ctx, childIndex)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	return newReadAheadNode(child, n.serv, n.readAhead), nil
+}
+
+// request requests the children of the read-ahead window starting at
+// childIndex which are not requested yet.
+func (n *readAheadNode) request(ctx context.Context, childIndex uint) {
+	end := min(int(childIndex)+n.readAhead, len(n.childCIDs))
+	var (
+		indexes []int
+		keys    []cid.Cid
+	)
+	for i := int(childIndex); i < end; i++ {
+		if n.promises[i] == nil {
+			indexes = append(indexes, i)
+			keys = append(keys, n.childCIDs[i])
+		}
+	}
+	for i, p := range ipld.GetNodes(ctx, n.serv, keys) {
+		n.promises[indexes[i]] = p
+	}
+}
+
+// get waits for the child, and forgets its promise.
+func (n *readAheadNode) get(ctx context.Context, childIndex uint) (ipld.Node, error) {
+	child, err := n.promises[childIndex].Get(ctx)
+	n.promises[childIndex] = nil
+	return child, err
+}
+
+func (n *readAheadNode) GetIPLDNode() ipld.Node {
+	return n.node
+}
+
+func (n *readAheadNode) ChildTotal() uint {
+	return uint(len(n.childCIDs))
+}
@@ -0,0 +1,748 @@
+package mod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	chunker "github.com/ipfs/boxo/chunker"
+	mdag "github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	help "github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// ErrInlineData is returned by [Writer] for files with data stored in their
+// non-leaf nodes, which it cannot modify.
+var ErrInlineData = errors.New("files with data in non-leaf nodes are not supported")
+
+// DefaultWriterBufferSize is the amount of written data a [Writer] buffers
+// before applying it to the DAG.
+const DefaultWriterBufferSize = 4 << 20
+
+// WriterOption is an option for [NewWriter].
+type WriterOption func(*Writer)
+
+// WithChunkSize sets the size of the leaves created by the writer. It
+// defaults to [chunker.DefaultBlockSize].
+func WithChunkSize(size int) WriterOption {
+	return func(w *Writer) {
+		w.chunkSize = size
+	}
+}
+
+// WithMaxLinks sets the maximum number of links of the nodes created by the
+// writer. It defaults to [help.DefaultLinksPerBlock].
+func WithMaxLinks(n int) WriterOption {
+	return func(w *Writer) {
+		w.maxLinks = n
+	}
+}
+
+// WithRawLeaves sets whether the leaves created by the writer are raw nodes.
+// By default, raw leaves are used when the file has a CIDv1.
+func WithRawLeaves(rawLeaves bool) WriterOption {
+	return func(w *Writer) {
+		w.rawLeaves = rawLeaves
+	}
+}
+
+// WithCidBuilder sets the CID builder of the nodes created by the writer. By
+// default, the CID prefix of the file is used.
+func WithCidBuilder(builder cid.Builder) WriterOption {
+	return func(w *Writer) {
+		w.builder = builder
+	}
+}
+
+// WithBufferSize sets the amount of written data buffered before being
+// applied to the DAG. It defaults to [DefaultWriterBufferSize].
+func WithBufferSize(size int) WriterOption {
+	return func(w *Writer) {
+		w.bufSize = size
+	}
+}
+
+// extent is a range of written data.
+type extent struct {
+	off  uint64
+	data []byte
+}
+
+func (e extent) end() uint64 {
+	return e.off + uint64(len(e.data))
+}
+
+// Writer applies random writes and truncations to a UnixFS file.
+//
+// Unlike [DagModifier], writes are buffered as extents and applied together
+// on Sync, or once the buffer is full. Only the nodes covering written ranges
+// are rewritten, the other subtrees are reused as they are, whatever the
+// layout of the file. Data written past the end of the file is appended along
+// the right edge of the DAG, in the shape of the balanced layout.
+//
+// A Writer is not safe for concurrent use.
+type Writer struct {
+	ctx     context.Context
+	dagserv ipld.DAGService
+
+	root ipld.Node
+	size uint64
+
+	builder   cid.Builder
+	rawLeaves bool
+	chunkSize int
+	maxLinks  int
+	bufSize   int
+
+	// sorted, non overlapping and non adjacent
+	extents  []extent
+	buffered int
+
+	offset int64
+}
+
+var (
+	_ io.Writer   = (*Writer)(nil)
+	_ io.WriterAt = (*Writer)(nil)
+	_ io.Seeker   = (*Writer)(nil)
+)
+
+// NewWriter returns a [Writer] modifying the file from.
+func NewWriter(ctx context.Context, from ipld.Node, serv ipld.DAGService, opts ...WriterOption) (*Writer, error) {
+	switch from.(type) {
+	case *mdag.ProtoNode, *mdag.RawNode:
+		// ok
+	default:
+		return nil, ErrNotUnixfs
+	}
+	size, err := fileSize(from)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := from.Cid().Prefix()
+	prefix.Codec = cid.DagProtobuf
+	w := &Writer{
+		ctx:       ctx,
+		dagserv:   serv,
+		root:      from,
+		size:      size,
+		builder:   prefix,
+		rawLeaves: prefix.Version > 0,
+		chunkSize: int(chunker.DefaultBlockSize),
+		maxLinks:  help.DefaultLinksPerBlock,
+		bufSize:   DefaultWriterBufferSize,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if w.chunkSize <= 0 || w.chunkSize > help.BlockSizeLimit {
+		return nil, fmt.Errorf("invalid chunk size %d", w.chunkSize)
+	}
+	if w.maxLinks < 2 {
+		return nil, fmt.Errorf("invalid max links %d", w.maxLinks)
+	}
+	return w, nil
+}
+
+// Size returns the size of the file, including the buffered writes.
+func (w *Writer) Size() int64 {
+	size := w.size
+	if n := len(w.extents); n > 0 {
+		size = max(size, w.extents[n-1].end())
+	}
+	return int64(size)
+}
+
+// Write writes b at the current offset.
+func (w *Writer) Write(b []byte) (int, error) {
+	n, err := w.WriteAt(b, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// WriteAt writes b at the given offset. Writing past the end of the file
+// fills the gap with zeros.
+func (w *Writer) WriteAt(b []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	w.addExtent(uint64(offset), b)
+	if w.buffered > w.bufSize {
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// addExtent records a copy of data, merging it with the extents it overlaps
+// or touches.
+func (w *Writer) addExtent(off uint64, data []byte) {
+	end := off + uint64(len(data))
+
+	// extents[i:j] are merged with the new one
+	i := sort.Search(len(w.extents), func(i int) bool { return w.extents[i].end() >= off })
+	j := i
+	for j < len(w.extents) && w.extents[j].off <= end {
+		j++
+	}
+
+	start := off
+	if i < j {
+		start = min(start, w.extents[i].off)
+		end = max(end, w.extents[j-1].end())
+	}
+	merged := extent{off: start, data: make([]byte, end-start)}
+	for _, e := range w.extents[i:j] {
+		copy(merged.data[e.off-start:], e.data)
+		w.buffered -= len(e.data)
+	}
+	copy(merged.data[off-start:], data)
+	w.buffered += len(merged.data)
+
+	w.extents = append(w.extents[:i], append([]extent{merged}, w.extents[j:]...)...)
+}
+
+// Seek sets the offset of the next Write. Seeking past the end of the file
+// does not extend it, until data is written there.
+func (w *Writer) Seek(offset int64, whence int) (int64, error) {
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = offset
+	case io.SeekCurrent:
+		newOffset = w.offset + offset
+	case io.SeekEnd:
+		newOffset = w.Size() + offset
+	default:
+		return 0, ErrUnrecognizedWhence
+	}
+	if newOffset < 0 {
+		return 0, ErrSeekFail
+	}
+	w.offset = newOffset
+	return newOffset, nil
+}
+
+// Truncate changes the size of the file. Growing the file fills it with
+// zeros.
+func (w *Writer) Truncate(size int64) error {
+	if size < 0 {
+		return fmt.Errorf("negative size %d", size)
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	switch {
+	case uint64(size) > w.size:
+		return w.appendTail(&chunkSource{
+			size: w.chunkSize,
+			r:    io.LimitReader(zeroReader{}, size-int64(w.size)),
+		})
+	case uint64(size) == w.size:
+		return nil
+	}
+
+	root, err := w.truncate(w.root, uint64(size))
+	if err != nil {
+		return err
+	}
+	root, err = w.collapse(root)
+	if err != nil {
+		return err
+	}
+	w.root, w.size = root, uint64(size)
+	return nil
+}
+
+// Sync applies the buffered writes and returns the new root of the file.
+func (w *Writer) Sync() (ipld.Node, error) {
+	if err := w.flush(); err != nil {
+		return nil, err
+	}
+	return w.root, nil
+}
+
+func (w *Writer) flush() error {
+	if len(w.extents) == 0 {
+		return nil
+	}
+
+	// Split the extents between the ones overwriting the existing data,
+	// and the ones past the end of the file.
+	var inside, tail []extent
+	for _, e := range w.extents {
+		switch {
+		case e.off >= w.size:
+			tail = append(tail, e)
+		case e.end() > w.size:
+			n := w.size - e.off
+			inside = append(inside, extent{off: e.off, data: e.data[:n]})
+			tail = append(tail, extent{off: w.size, data: e.data[n:]})
+		default:
+			inside = append(inside, e)
+		}
+	}
+
+	if len(inside) != 0 {
+		root, err := w.overwrite(w.root, 0, inside)
+		if err != nil {
+			return err
+		}
+		w.root = root
+	}
+	if len(tail) != 0 {
+		src := &chunkSource{size: w.chunkSize, r: &extentsReader{off: w.size, extents: tail}}
+		if err := w.appendTail(src); err != nil {
+			return err
+		}
+	}
+
+	w.extents = nil
+	w.buffered = 0
+	return nil
+}
+
+// overwrite applies the extents, which all start within n, to the subtree n
+// covering the file from offset start.
+func (w *Writer) overwrite(n ipld.Node, start uint64, extents []extent) (ipld.Node, error) {
+	if len(n.Links()) == 0 {
+		data, err := leafData(n)
+		if err != nil {
+			return nil, err
+		}
+		ndata := make([]byte, len(data))
+		copy(ndata, data)
+		end := start + uint64(len(data))
+		for _, e := range extents {
+			lo, hi := max(e.off, start), min(e.end(), end)
+			if lo < hi {
+				copy(ndata[lo-start:hi-start], e.data[lo-e.off:hi-e.off])
+			}
+		}
+		return w.replaceLeaf(n, ndata)
+	}
+
+	pn, fsn, err := w.internalNode(n)
+	if err != nil {
+		return nil, err
+	}
+	links := copyLinks(pn.Links())
+	cur := start
+	for i, bs := range fsn.BlockSizes() {
+		childEnd := cur + bs
+		var sub []extent
+		for _, e := range extents {
+			if e.off < childEnd && e.end() > cur {
+				sub = append(sub, e)
+			}
+		}
+		if len(sub) != 0 {
+			child, err := links[i].GetNode(w.ctx, w.dagserv)
+			if err != nil {
+				return nil, err
+			}
+			nchild, err := w.overwrite(child, cur, sub)
+			if err != nil {
+				return nil, err
+			}
+			if links[i], err = ipld.MakeLink(nchild); err != nil {
+				return nil, err
+			}
+		}
+		cur = childEnd
+	}
+	return w.newInternalNode(fsn, links, fsn.BlockSizes())
+}
+
+// appendTail appends the data of src to the file.
+func (w *Writer) appendTail(src *chunkSource) error {
+	root := w.root
+	depth, err := w.depth(root)
+	if err != nil {
+		return err
+	}
+
+	for {
+		root, err = w.fill(root, depth, src)
+		if err != nil {
+			return err
+		}
+		if src.done() {
+			break
+		}
+
+		// The tree is full, add a level. The new root is only stored once
+		// filled.
+		fsn := ft.NewFSNode(ft.TFile)
+		if pn, ok := root.(*mdag.ProtoNode); ok {
+			if rfsn, err := ft.FSNodeFromBytes(pn.Data()); err == nil {
+				fsn.SetMode(rfsn.Mode())
+				fsn.SetModTime(rfsn.ModTime())
+			}
+		}
+		size, err := fileSize(root)
+		if err != nil {
+			return err
+		}
+		lnk, err := ipld.MakeLink(root)
+		if err != nil {
+			return err
+		}
+		if root, err = w.makeInternalNode(fsn, []*ipld.Link{lnk}, []uint64{size}); err != nil {
+			return err
+		}
+		depth++
+	}
+	if src.err != nil {
+		return src.err
+	}
+
+	size, err := fileSize(root)
+	if err != nil {
+		return err
+	}
+	w.root, w.size = root, size
+	return nil
+}
+
+// fill appends data from src to the subtree n of the given depth, along its
+// right edge, until it is full or src is exhausted.
+func (w *Writer) fill(n ipld.Node, depth int, src *chunkSource) (ipld.Node, error) {
+	if src.done() {
+		return n, nil
+	}
+
+	if depth == 0 {
+		data, err := leafData(n)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) >= w.chunkSize {
+			return n, nil
+		}
+		ndata := append(append([]byte(nil), data...), src.next(w.chunkSize-len(data))...)
+		if pn, ok := n.(*mdag.ProtoNode); ok && !w.rawLeaves {
+			return w.replaceLeaf(pn, ndata)
+		}
+		return w.newLeaf(ndata)
+	}
+
+	pn, fsn, err := w.internalNode(n)
+	if err != nil {
+		return nil, err
+	}
+	links := copyLinks(pn.Links())
+	sizes := append([]uint64(nil), fsn.BlockSizes()...)
+	changed := false
+	if len(links) != 0 {
+		last := len(links) - 1
+		child, err := links[last].GetNode(w.ctx, w.dagserv)
+		if err != nil {
+			return nil, err
+		}
+		nchild, err := w.fill(child, depth-1, src)
+		if err != nil {
+			return nil, err
+		}
+		if nchild != child {
+			changed = true
+			if links[last], err = ipld.MakeLink(nchild); err != nil {
+				return nil, err
+			}
+			if sizes[last], err = fileSize(nchild); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for len(links) < w.maxLinks && !src.done() {
+		child, err := w.build(depth-1, src)
+		if err != nil {
+			return nil, err
+		}
+		lnk, err := ipld.MakeLink(child)
+		if err != nil {
+			return nil, err
+		}
+		size, err := fileSize(child)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, lnk)
+		sizes = append(sizes, size)
+		changed = true
+	}
+	if !changed {
+		// the subtree is full
+		return n, nil
+	}
+	return w.newInternalNode(fsn, links, sizes)
+}
+
+// build creates a subtree of the given depth with the data from src.
+func (w *Writer) build(depth int, src *chunkSource) (ipld.Node, error) {
+	if depth == 0 {
+		return w.newLeaf(src.next(w.chunkSize))
+	}
+	return w.fill(nil, depth, src)
+}
+
+// truncate returns the subtree n cut at size.
+func (w *Writer) truncate(n ipld.Node, size uint64) (ipld.Node, error) {
+	if len(n.Links()) == 0 {
+		data, err := leafData(n)
+		if err != nil {
+			return nil, err
+		}
+		return w.replaceLeaf(n, data[:size])
+	}
+
+	pn, fsn, err := w.internalNode(n)
+	if err != nil {
+		return nil, err
+	}
+	links := copyLinks(pn.Links())
+	var sizes []uint64
+	var cur uint64
+	for i, bs := range fsn.BlockSizes() {
+		if cur+bs > size {
+			child, err := links[i].GetNode(w.ctx, w.dagserv)
+			if err != nil {
+				return nil, err
+			}
+			nchild, err := w.truncate(child, size-cur)
+			if err != nil {
+				return nil, err
+			}
+			if links[i], err = ipld.MakeLink(nchild); err != nil {
+				return nil, err
+			}
+			bs = size - cur
+		}
+		sizes = append(sizes, bs)
+		cur += bs
+		if cur == size {
+			links = links[:i+1]
+			break
+		}
+	}
+	return w.newInternalNode(fsn, links, sizes)
+}
+
+// collapse removes the root nodes with a single child left by truncate, as
+// long as they do not hold metadata.
+func (w *Writer) collapse(root ipld.Node) (ipld.Node, error) {
+	for len(root.Links()) == 1 {
+		pn, fsn, err := w.internalNode(root)
+		if err != nil {
+			return nil, err
+		}
+		if fsn.Mode() != 0 || !fsn.ModTime().IsZero() {
+			break
+		}
+		if root, err = pn.Links()[0].GetNode(w.ctx, w.dagserv); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// depth returns the number of levels below n, along its right edge.
+func (w *Writer) depth(n ipld.Node) (int, error) {
+	depth := 0
+	for len(n.Links()) != 0 {
+		links := n.Links()
+		var err error
+		if n, err = links[len(links)-1].GetNode(w.ctx, w.dagserv); err != nil {
+			return 0, err
+		}
+		depth++
+	}
+	return depth, nil
+}
+
+// internalNode decodes a node with links, or returns an empty file node when
+// n is nil.
+func (w *Writer) internalNode(n ipld.Node) (*mdag.ProtoNode, *ft.FSNode, error) {
+	if n == nil {
+		return new(mdag.ProtoNode), ft.NewFSNode(ft.TFile), nil
+	}
+	pn, ok := n.(*mdag.ProtoNode)
+	if !ok {
+		return nil, nil, ErrNotUnixfs
+	}
+	fsn, err := ft.FSNodeFromBytes(pn.Data())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(fsn.Data()) != 0 {
+		return nil, nil, ErrInlineData
+	}
+	return pn, fsn, nil
+}
+
+func (w *Writer) newInternalNode(fsn *ft.FSNode, links []*ipld.Link, sizes []uint64) (ipld.Node, error) {
+	nd, err := w.makeInternalNode(fsn, links, sizes)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.dagserv.Add(w.ctx, nd); err != nil {
+		return nil, err
+	}
+	return nd, nil
+}
+
+func (w *Writer) makeInternalNode(fsn *ft.FSNode, links []*ipld.Link, sizes []uint64) (*mdag.ProtoNode, error) {
+	fsn.RemoveAllBlockSizes()
+	for _, s := range sizes {
+		fsn.AddBlockSize(s)
+	}
+	data, err := fsn.GetBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	nd := mdag.NodeWithData(data)
+	if err := nd.SetCidBuilder(w.builder); err != nil {
+		return nil, err
+	}
+	if err := nd.SetLinks(links); err != nil {
+		return nil, err
+	}
+	return nd, nil
+}
+
+// replaceLeaf returns a leaf like n, holding data.
+func (w *Writer) replaceLeaf(n ipld.Node, data []byte) (ipld.Node, error) {
+	pn, ok := n.(*mdag.ProtoNode)
+	if !ok {
+		nd, err := mdag.NewRawNodeWPrefix(data, w.builder)
+		if err != nil {
+			return nil, err
+		}
+		return nd, w.dagserv.Add(w.ctx, nd)
+	}
+
+	fsn, err := ft.FSNodeFromBytes(pn.Data())
+	if err != nil {
+		return nil, err
+	}
+	fsn.SetData(data)
+	b, err := fsn.GetBytes()
+	if err != nil {
+		return nil, err
+	}
+	nd := mdag.NodeWithData(b)
+	if err := nd.SetCidBuilder(w.builder); err != nil {
+		return nil, err
+	}
+	return nd, w.dagserv.Add(w.ctx, nd)
+}
+
+func (w *Writer) newLeaf(data []byte) (ipld.Node, error) {
+	if w.rawLeaves {
+		nd, err := mdag.NewRawNodeWPrefix(data, w.builder)
+		if err != nil {
+			return nil, err
+		}
+		return nd, w.dagserv.Add(w.ctx, nd)
+	}
+	return w.replaceLeaf(mdag.NodeWithData(ft.FilePBData(nil, 0)), data)
+}
+
+func leafData(n ipld.Node) ([]byte, error) {
+	switch nd := n.(type) {
+	case *mdag.ProtoNode:
+		fsn, err := ft.FSNodeFromBytes(nd.Data())
+		if err != nil {
+			return nil, err
+		}
+		return fsn.Data(), nil
+	case *mdag.RawNode:
+		return nd.RawData(), nil
+	default:
+		return nil, ErrNotUnixfs
+	}
+}
+
+func copyLinks(links []*ipld.Link) []*ipld.Link {
+	nlinks := make([]*ipld.Link, len(links))
+	for i, l := range links {
+		nl := *l
+		nlinks[i] = &nl
+	}
+	return nlinks
+}
+
+// chunkSource cuts the appended data in chunks.
+type chunkSource struct {
+	size int
+	r    io.Reader
+	buf  []byte
+	eof  bool
+	err  error
+}
+
+// done reports whether all the data was consumed.
+func (s *chunkSource) done() bool {
+	if len(s.buf) == 0 && !s.eof {
+		buf := make([]byte, s.size)
+		n, err := io.ReadFull(s.r, buf)
+		s.buf = buf[:n]
+		if err != nil {
+			s.eof = true
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				s.err = err
+			}
+		}
+	}
+	return len(s.buf) == 0 && s.eof
+}
+
+// next returns up to n bytes.
+func (s *chunkSource) next(n int) []byte {
+	if s.done() {
+		return nil
+	}
+	n = min(n, len(s.buf))
+	b := s.buf[:n:n]
+	s.buf = s.buf[n:]
+	return b
+}
+
+// extentsReader reads the extents from off, with zeros in between.
+type extentsReader struct {
+	off     uint64
+	extents []extent
+}
+
+func (r *extentsReader) Read(b []byte) (int, error) {
+	if len(r.extents) == 0 {
+		return 0, io.EOF
+	}
+
+	e := r.extents[0]
+	var n int
+	if r.off < e.off {
+		n = int(min(uint64(len(b)), e.off-r.off))
+		clear(b[:n])
+	} else {
+		n = copy(b, e.data[r.off-e.off:])
+		if r.off+uint64(n) == e.end() {
+			r.extents = r.extents[1:]
+		}
+	}
+	r.off += uint64(n)
+	return n, nil
+}
@@ -0,0 +1,222 @@
+package mod
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	testu "github.com/ipfs/boxo/ipld/unixfs/test"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+func verifyWriter(t *testing.T, expected []byte, w *Writer) ipld.Node {
+	nd, err := w.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := fileSize(nd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != uint64(len(expected)) {
+		t.Fatalf("expected size %d, got %d", len(expected), size)
+	}
+
+	rd, err := uio.NewDagReader(context.Background(), nd, w.dagserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testu.ArrComp(after, expected); err != nil {
+		t.Fatal(err)
+	}
+	return nd
+}
+
+func TestWriterRandomOps(t *testing.T) {
+	runAllSubtests(t, testWriterRandomOps)
+}
+
+func testWriterRandomOps(t *testing.T, opts testu.NodeOpts) {
+	dserv := testu.GetDAGServ()
+	b, n := testu.GetRandomNode(t, dserv, 50000, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := NewWriter(ctx, n, dserv, WithChunkSize(512), WithMaxLinks(4), WithBufferSize(4096), WithRawLeaves(opts.RawLeavesUsed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		switch op := rnd.Intn(10); {
+		case op < 7:
+			off := rnd.Intn(len(b) + 2000)
+			data := make([]byte, rnd.Intn(3000)+1)
+			rnd.Read(data)
+			if end := off + len(data); end > len(b) {
+				b = append(b, make([]byte, end-len(b))...)
+			}
+			copy(b[off:], data)
+			if _, err := w.WriteAt(data, int64(off)); err != nil {
+				t.Fatal(err)
+			}
+		case op < 9:
+			size := rnd.Intn(len(b) + 1000)
+			if size > len(b) {
+				b = append(b, make([]byte, size-len(b))...)
+			}
+			b = b[:size]
+			if err := w.Truncate(int64(size)); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			verifyWriter(t, b, w)
+		}
+
+		if w.Size() != int64(len(b)) {
+			t.Fatalf("expected size %d, got %d", len(b), w.Size())
+		}
+	}
+	verifyWriter(t, b, w)
+}
+
+func TestWriterSeekWrite(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := NewWriter(ctx, testu.GetEmptyNode(t, dserv, testu.UseCidV1), dserv, WithChunkSize(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Seek(-5, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("there")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Seek(4, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("!")); err != nil {
+		t.Fatal(err)
+	}
+	verifyWriter(t, []byte("hello there\x00\x00\x00\x00!"), w)
+
+	if _, err := w.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("expected error seeking before the start")
+	}
+	if _, err := w.Seek(0, 42); err != ErrUnrecognizedWhence {
+		t.Fatal("expected ErrUnrecognizedWhence")
+	}
+}
+
+// countingDAGService counts the nodes added.
+type countingDAGService struct {
+	ipld.DAGService
+	added int
+}
+
+func (c *countingDAGService) Add(ctx context.Context, nd ipld.Node) error {
+	c.added++
+	return c.DAGService.Add(ctx, nd)
+}
+
+func TestWriterRewritesAffectedNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dserv := &countingDAGService{DAGService: testu.GetDAGServ()}
+	w, err := NewWriter(ctx, testu.GetEmptyNode(t, dserv, testu.UseCidV1), dserv, WithChunkSize(64), WithMaxLinks(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4^4 leaves, a balanced tree of depth 4
+	data := make([]byte, 64*256)
+	rand.New(rand.NewSource(1)).Read(data)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	nd := verifyWriter(t, data, w)
+	if depth, err := w.depth(nd); err != nil || depth != 4 {
+		t.Fatalf("expected depth 4, got %d (%v)", depth, err)
+	}
+
+	// overwriting a single leaf rewrites one node per level
+	dserv.added = 0
+	copy(data[1000:], "modified")
+	if _, err := w.WriteAt([]byte("modified"), 1000); err != nil {
+		t.Fatal(err)
+	}
+	verifyWriter(t, data, w)
+	if dserv.added != 5 {
+		t.Fatalf("expected 5 nodes to be rewritten, got %d", dserv.added)
+	}
+
+	// appending to the full tree adds a level above the existing root
+	prev := w.root.Cid()
+	dserv.added = 0
+	data = append(data, "appended"...)
+	if _, err := w.WriteAt([]byte("appended"), int64(len(data)-8)); err != nil {
+		t.Fatal(err)
+	}
+	nd = verifyWriter(t, data, w)
+	if dserv.added != 6 {
+		t.Fatalf("expected 6 nodes to be added, got %d", dserv.added)
+	}
+	if nd.Links()[0].Cid != prev {
+		t.Fatal("expected previous root to be reused")
+	}
+
+	// truncating removes the added level
+	data = data[:len(data)-8]
+	if err := w.Truncate(int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	nd = verifyWriter(t, data, w)
+	if depth, err := w.depth(nd); err != nil || depth != 4 {
+		t.Fatalf("expected depth 4, got %d (%v)", depth, err)
+	}
+}
+
+func TestWriterProtoLeaves(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	b, n := testu.GetRandomNode(t, dserv, 5000, testu.UseProtoBufLeaves)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := NewWriter(ctx, n, dserv, WithChunkSize(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, make([]byte, 3000)...)
+	if _, err := w.WriteAt(b[4000:], 4000); err != nil {
+		t.Fatal(err)
+	}
+	nd := verifyWriter(t, b, w)
+
+	err = dag.Walk(ctx, dag.GetLinksWithDAG(dserv), nd.Cid(), func(c cid.Cid) bool {
+		if c.Type() == cid.Raw {
+			t.Fatal("unexpected raw leaf")
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
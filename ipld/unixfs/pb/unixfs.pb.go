@@ -82,6 +82,8 @@ type Data struct {
 	Blocksizes           []uint64       `protobuf:"varint,4,rep,name=blocksizes" json:"blocksizes,omitempty"`
 	HashType             *uint64        `protobuf:"varint,5,opt,name=hashType" json:"hashType,omitempty"`
 	Fanout               *uint64        `protobuf:"varint,6,opt,name=fanout" json:"fanout,omitempty"`
+	Mode                 *uint32        `protobuf:"varint,7,opt,name=mode" json:"mode,omitempty"`
+	Mtime                *IPFSTimestamp `protobuf:"bytes,8,opt,name=mtime" json:"mtime,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
 	XXX_unrecognized     []byte         `json:"-"`
 	XXX_sizecache        int32          `json:"-"`
@@ -158,6 +160,20 @@ func (m *Data) GetFanout() uint64 {
 	return 0
 }
 
+func (m *Data) GetMode() uint32 {
+	if m != nil && m.Mode != nil {
+		return *m.Mode
+	}
+	return 0
+}
+
+func (m *Data) GetMtime() *IPFSTimestamp {
+	if m != nil {
+		return m.Mtime
+	}
+	return nil
+}
+
 type Metadata struct {
 	MimeType             *string  `protobuf:"bytes,1,opt,name=MimeType" json:"MimeType,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -201,31 +217,87 @@ func (m *Metadata) GetMimeType() string {
 	return ""
 }
 
+type IPFSTimestamp struct {
+	Seconds              *int64   `protobuf:"varint,1,req,name=seconds" json:"seconds,omitempty"`
+	Nanos                *uint32  `protobuf:"fixed32,2,opt,name=nanos" json:"nanos,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IPFSTimestamp) Reset()         { *m = IPFSTimestamp{} }
+func (m *IPFSTimestamp) String() string { return proto.CompactTextString(m) }
+func (*IPFSTimestamp) ProtoMessage()    {}
+func (*IPFSTimestamp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e2fd76cc44dfc7c3, []int{2}
+}
+
+func (m *IPFSTimestamp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IPFSTimestamp.Unmarshal(m, b)
+}
+
+func (m *IPFSTimestamp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IPFSTimestamp.Marshal(b, m, deterministic)
+}
+
+func (m *IPFSTimestamp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IPFSTimestamp.Merge(m, src)
+}
+
+func (m *IPFSTimestamp) XXX_Size() int {
+	return xxx_messageInfo_IPFSTimestamp.Size(m)
+}
+
+func (m *IPFSTimestamp) XXX_DiscardUnknown() {
+	xxx_messageInfo_IPFSTimestamp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IPFSTimestamp proto.InternalMessageInfo
+
+func (m *IPFSTimestamp) GetSeconds() int64 {
+	if m != nil && m.Seconds != nil {
+		return *m.Seconds
+	}
+	return 0
+}
+
+func (m *IPFSTimestamp) GetNanos() uint32 {
+	if m != nil && m.Nanos != nil {
+		return *m.Nanos
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("unixfs.v1.pb.Data_DataType", Data_DataType_name, Data_DataType_value)
 	proto.RegisterType((*Data)(nil), "unixfs.v1.pb.Data")
 	proto.RegisterType((*Metadata)(nil), "unixfs.v1.pb.Metadata")
+	proto.RegisterType((*IPFSTimestamp)(nil), "unixfs.v1.pb.IPFSTimestamp")
 }
 
 func init() { proto.RegisterFile("unixfs.proto", fileDescriptor_e2fd76cc44dfc7c3) }
 
 var fileDescriptor_e2fd76cc44dfc7c3 = []byte{
-	// 267 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0x41, 0x4f, 0x83, 0x30,
-	0x18, 0x86, 0x05, 0xba, 0x0d, 0xbe, 0xa1, 0x69, 0xbe, 0x83, 0x21, 0x9a, 0x18, 0xc2, 0xc1, 0x70,
-	0xc2, 0xe8, 0x3f, 0xd0, 0x2c, 0xc6, 0x0b, 0x97, 0x6e, 0xf1, 0xe0, 0xc5, 0x94, 0xad, 0x84, 0x66,
-	0x8c, 0x12, 0xe8, 0x54, 0xfc, 0x1b, 0xfe, 0x61, 0x53, 0x18, 0xdb, 0x2e, 0x4d, 0x9e, 0xf6, 0x79,
-	0x9b, 0x37, 0x2f, 0xf8, 0xfb, 0x4a, 0xfe, 0xe4, 0x6d, 0x52, 0x37, 0x4a, 0x2b, 0x1c, 0xe9, 0xeb,
-	0x31, 0xa9, 0xb3, 0xe8, 0xcf, 0x06, 0xb2, 0xe0, 0x9a, 0xe3, 0x03, 0x90, 0x55, 0x57, 0x8b, 0xc0,
-	0x0a, 0xed, 0xf8, 0xea, 0xe9, 0x36, 0x39, 0xb7, 0x12, 0x63, 0xf4, 0x87, 0x51, 0x58, 0x2f, 0x22,
-	0x0e, 0xc1, 0xc0, 0x0e, 0xad, 0xd8, 0x67, 0xc3, 0x27, 0x37, 0xe0, 0xe6, 0xb2, 0x14, 0xad, 0xfc,
-	0x15, 0x81, 0x13, 0x5a, 0x31, 0x61, 0x47, 0xc6, 0x3b, 0x80, 0xac, 0x54, 0xeb, 0xad, 0x81, 0x36,
-	0x20, 0xa1, 0x13, 0x13, 0x76, 0x76, 0x63, 0xb2, 0x05, 0x6f, 0x8b, 0xbe, 0xc4, 0x64, 0xc8, 0x8e,
-	0x8c, 0xd7, 0x30, 0xcd, 0x79, 0xa5, 0xf6, 0x3a, 0x98, 0xf6, 0x2f, 0x07, 0x8a, 0xde, 0xc1, 0x1d,
-	0x5b, 0xe1, 0x0c, 0x1c, 0xc6, 0xbf, 0xe9, 0x05, 0x5e, 0x82, 0xb7, 0x90, 0x8d, 0x58, 0x6b, 0xd5,
-	0x74, 0xd4, 0x42, 0x17, 0xc8, 0xab, 0x2c, 0x05, 0xb5, 0xd1, 0x07, 0x37, 0x15, 0x9a, 0x6f, 0xb8,
-	0xe6, 0xd4, 0xc1, 0x39, 0xcc, 0x96, 0xdd, 0xae, 0x94, 0xd5, 0x96, 0x12, 0x93, 0x79, 0x7b, 0x4e,
-	0x57, 0xcb, 0x82, 0x37, 0x1b, 0x3a, 0x89, 0xee, 0x4f, 0xa6, 0xe9, 0x95, 0xca, 0x9d, 0x38, 0x8c,
-	0x63, 0xc5, 0x1e, 0x3b, 0xf2, 0xcb, 0xfc, 0xc3, 0x1b, 0x76, 0xfa, 0xac, 0xb3, 0xff, 0x00, 0x00,
-	0x00, 0xff, 0xff, 0xbd, 0x16, 0xf8, 0x45, 0x67, 0x01, 0x00, 0x00,
+	// 336 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x91, 0x5f, 0x4b, 0xeb, 0x30,
+	0x18, 0xc6, 0x4f, 0xff, 0xad, 0xdd, 0xbb, 0xed, 0x50, 0x5e, 0x0e, 0x87, 0xa0, 0x20, 0xa5, 0x17,
+	0xd2, 0xab, 0xca, 0xfc, 0x02, 0xa2, 0x8c, 0xa1, 0x17, 0x03, 0xc9, 0x86, 0x17, 0xde, 0x48, 0xb6,
+	0x66, 0x2c, 0xac, 0x6d, 0x4a, 0x93, 0xa9, 0xf3, 0xa3, 0xfa, 0x69, 0xa4, 0xe9, 0x3a, 0xa7, 0x37,
+	0x21, 0xbf, 0xe4, 0x79, 0xc2, 0xf3, 0xbc, 0x81, 0xe1, 0xae, 0x14, 0xef, 0x6b, 0x95, 0x56, 0xb5,
+	0xd4, 0x12, 0x3b, 0x7a, 0x1d, 0xa7, 0xd5, 0x32, 0xfe, 0xb4, 0xc1, 0x9d, 0x30, 0xcd, 0xf0, 0x0a,
+	0xdc, 0xc5, 0xbe, 0xe2, 0xc4, 0x8a, 0xec, 0xe4, 0xef, 0xf5, 0x79, 0x7a, 0xaa, 0x4a, 0x1b, 0x85,
+	0x59, 0x1a, 0x09, 0x35, 0x42, 0xc4, 0xd6, 0x48, 0xec, 0xc8, 0x4a, 0x86, 0xb4, 0x7d, 0xe4, 0x0c,
+	0x82, 0xb5, 0xc8, 0xb9, 0x12, 0x1f, 0x9c, 0x38, 0x91, 0x95, 0xb8, 0xf4, 0xc8, 0x78, 0x01, 0xb0,
+	0xcc, 0xe5, 0x6a, 0xdb, 0x80, 0x22, 0x6e, 0xe4, 0x24, 0x2e, 0x3d, 0x39, 0x69, 0xbc, 0x1b, 0xa6,
+	0x36, 0x26, 0x84, 0xd7, 0x7a, 0x3b, 0xc6, 0xff, 0xd0, 0x5b, 0xb3, 0x52, 0xee, 0x34, 0xe9, 0x99,
+	0x9b, 0x03, 0x35, 0x19, 0x0a, 0x99, 0x71, 0xe2, 0x47, 0x56, 0x32, 0xa2, 0x66, 0x8f, 0x63, 0xf0,
+	0x0a, 0x2d, 0x0a, 0x4e, 0x82, 0xc8, 0x4a, 0x06, 0xbf, 0x9b, 0x3c, 0x3c, 0x4e, 0xe7, 0x0b, 0x51,
+	0x70, 0xa5, 0x59, 0x51, 0xd1, 0x56, 0x19, 0x3f, 0x41, 0xd0, 0x95, 0x43, 0x1f, 0x1c, 0xca, 0xde,
+	0xc2, 0x3f, 0x38, 0x82, 0xfe, 0x44, 0xd4, 0x7c, 0xa5, 0x65, 0xbd, 0x0f, 0x2d, 0x0c, 0xc0, 0x9d,
+	0x8a, 0x9c, 0x87, 0x36, 0x0e, 0x21, 0x98, 0x71, 0xcd, 0x32, 0xa6, 0x59, 0xe8, 0xe0, 0x00, 0xfc,
+	0xf9, 0xbe, 0xc8, 0x45, 0xb9, 0x0d, 0xdd, 0xc6, 0x73, 0x7f, 0x3b, 0x5b, 0xcc, 0x37, 0xac, 0xce,
+	0x42, 0x2f, 0xbe, 0xfc, 0x56, 0x36, 0xf5, 0x66, 0xa2, 0xe0, 0x87, 0x19, 0x5b, 0x49, 0x9f, 0x1e,
+	0x39, 0xbe, 0x81, 0xd1, 0x8f, 0x5c, 0x48, 0xc0, 0x57, 0x7c, 0x25, 0xcb, 0x4c, 0x99, 0xff, 0x70,
+	0x68, 0x87, 0xf8, 0x0f, 0xbc, 0x92, 0x95, 0x52, 0x99, 0xb1, 0xfb, 0xb4, 0x85, 0xbb, 0xc1, 0x73,
+	0xbf, 0x6d, 0xf9, 0x52, 0x2d, 0xbf, 0x06, 0x00, 0x7e, 0x29, 0x8b, 0xb9, 0xef, 0x01, 0x00, 0x00,
 }
@@ -6,8 +6,11 @@ package unixfs
 import (
 	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	proto "github.com/gogo/protobuf/proto"
+	"github.com/ipfs/boxo/files"
 	dag "github.com/ipfs/boxo/ipld/merkledag"
 
 	pb "github.com/ipfs/boxo/ipld/unixfs/pb"
@@ -293,6 +296,49 @@ func (n *FSNode) Type() pb.Data_DataType {
 	return n.format.GetType()
 }
 
+// Mode returns the optional permissions and special mode bits of the node,
+// 0 is returned if they are not set.
+func (n *FSNode) Mode() os.FileMode {
+	if n.format.Mode == nil {
+		return 0
+	}
+	return files.UnixPermsToModePerms(n.format.GetMode())
+}
+
+// SetMode sets the permissions and special mode bits of the node, passing 0
+// removes them.
+func (n *FSNode) SetMode(mode os.FileMode) {
+	if mode == 0 {
+		n.format.Mode = nil
+		return
+	}
+	n.format.Mode = proto.Uint32(files.ModePermsToUnixPerms(mode))
+}
+
+// ModTime returns the optional modification time of the node, the zero time
+// is returned if it is not set.
+func (n *FSNode) ModTime() time.Time {
+	ts := n.format.GetMtime()
+	if ts == nil {
+		return time.Time{}
+	}
+	return time.Unix(ts.GetSeconds(), int64(ts.GetNanos()))
+}
+
+// SetModTime sets the modification time of the node, passing the zero time
+// removes it.
+func (n *FSNode) SetModTime(mtime time.Time) {
+	if mtime.IsZero() {
+		n.format.Mtime = nil
+		return
+	}
+	ts := &pb.IPFSTimestamp{Seconds: proto.Int64(mtime.Unix())}
+	if nanos := mtime.Nanosecond(); nanos != 0 {
+		ts.Nanos = proto.Uint32(uint32(nanos))
+	}
+	n.format.Mtime = ts
+}
+
 // IsDir checks whether the node represents a directory
 func (n *FSNode) IsDir() bool {
 	switch n.Type() {
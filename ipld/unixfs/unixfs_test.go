@@ -2,7 +2,9 @@ package unixfs
 
 import (
 	"bytes"
+	"os"
 	"testing"
+	"time"
 
 	proto "github.com/gogo/protobuf/proto"
 
@@ -183,3 +185,36 @@ func TestIsDir(t *testing.T) {
 		}
 	}
 }
+
+func TestModeAndModTime(t *testing.T) {
+	fsn := NewFSNode(TFile)
+	if fsn.Mode() != 0 || !fsn.ModTime().IsZero() {
+		t.Fatal("new node should not have a mode or mtime")
+	}
+
+	mode := os.FileMode(0o755) | os.ModeSetgid
+	mtime := time.Unix(1700000000, 12345)
+	fsn.SetMode(mode)
+	fsn.SetModTime(mtime)
+
+	b, err := fsn.GetBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsn, err = FSNodeFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fsn.Mode() != mode {
+		t.Fatalf("expected mode %s, got %s", mode, fsn.Mode())
+	}
+	if !fsn.ModTime().Equal(mtime) {
+		t.Fatalf("expected mtime %s, got %s", mtime, fsn.ModTime())
+	}
+
+	fsn.SetMode(0)
+	fsn.SetModTime(time.Time{})
+	if fsn.Mode() != 0 || !fsn.ModTime().IsZero() {
+		t.Fatal("mode and mtime should have been removed")
+	}
+}
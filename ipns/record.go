@@ -395,15 +395,17 @@ func needToEmbedPublicKey(pk ic.PubKey) (bool, error) {
 	return false, fmt.Errorf("cannot extract ID from public key: %w", err)
 }
 
-// compare compares two IPNS Records. It returns:
+// Compare compares two IPNS Records by signature version, then sequence
+// number, then validity. It returns:
 //
 //   - -1 if a is older than b
 //   - 0 if a and b cannot be ordered (this doesn't mean that they are equal)
 //   - +1 if a is newer than b
 //
-// This function does not validate the records. The caller is responsible for
-// ensuring that the Records are valid by using [Validate].
-func compare(a, b *Record) (int, error) {
+// This is the comparison used by [Validator.Select]. This function does not
+// validate the records. The caller is responsible for ensuring that the
+// Records are valid by using [Validate].
+func Compare(a, b *Record) (int, error) {
 	aHasV2Sig := a.pb.GetSignatureV2() != nil
 	bHasV2Sig := b.pb.GetSignatureV2() != nil
 
@@ -235,7 +235,7 @@ func selectRecord(recs []*Record, vals [][]byte) (int, error) {
 
 	var i int
 	for j := 1; j < len(recs); j++ {
-		cmp, err := compare(recs[i], recs[j])
+		cmp, err := Compare(recs[i], recs[j])
 		if err != nil {
 			return -1, err
 		}
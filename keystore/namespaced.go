@@ -0,0 +1,149 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+)
+
+// ErrUnauthorized is returned by a [NamespacedKeystore] when its [Authorizer]
+// refuses an operation.
+var ErrUnauthorized = errors.New("keystore operation not authorized")
+
+// namespaceSeparator separates the namespace from the key name in the
+// backing keystore.
+const namespaceSeparator = "/"
+
+// Op is a keystore operation checked by an [Authorizer].
+type Op int
+
+const (
+	OpHas Op = iota
+	OpGet
+	OpPut
+	OpDelete
+	OpList
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpHas:
+		return "has"
+	case OpGet:
+		return "get"
+	case OpPut:
+		return "put"
+	case OpDelete:
+		return "delete"
+	case OpList:
+		return "list"
+	default:
+		return fmt.Sprintf("Op(%d)", int(op))
+	}
+}
+
+// Authorizer decides whether the operation op on the key name, without the
+// namespace, is allowed in the namespace. It returns nil to allow it, or an
+// error explaining why it is refused. The name is empty for [OpList].
+type Authorizer func(namespace string, op Op, name string) error
+
+// NamespacedKeystore is a [Keystore] storing its keys in a namespace of
+// another keystore, so that several tenants can share a backing keystore
+// without seeing each other's keys. Every operation is checked with an
+// [Authorizer] first.
+type NamespacedKeystore struct {
+	ks        Keystore
+	namespace string
+	prefix    string
+	authorize Authorizer
+}
+
+var _ Keystore = (*NamespacedKeystore)(nil)
+
+// NewNamespacedKeystore returns a [NamespacedKeystore] storing its keys in ks
+// with names prefixed by the namespace and a slash. The namespace must not be
+// empty or contain a slash. A nil authorize allows every operation.
+func NewNamespacedKeystore(ks Keystore, namespace string, authorize Authorizer) (*NamespacedKeystore, error) {
+	if namespace == "" || strings.Contains(namespace, namespaceSeparator) {
+		return nil, fmt.Errorf("invalid keystore namespace %q", namespace)
+	}
+	return &NamespacedKeystore{
+		ks:        ks,
+		namespace: namespace,
+		prefix:    namespace + namespaceSeparator,
+		authorize: authorize,
+	}, nil
+}
+
+// check returns an error if the operation is not authorized.
+func (nk *NamespacedKeystore) check(op Op, name string) error {
+	if nk.authorize == nil {
+		return nil
+	}
+	if err := nk.authorize(nk.namespace, op, name); err != nil {
+		if errors.Is(err, ErrUnauthorized) {
+			return err
+		}
+		return fmt.Errorf("%w: %s %q in namespace %q: %s", ErrUnauthorized, op, name, nk.namespace, err)
+	}
+	return nil
+}
+
+// Has returns whether or not a key exists in the namespace
+func (nk *NamespacedKeystore) Has(name string) (bool, error) {
+	if err := nk.check(OpHas, name); err != nil {
+		return false, err
+	}
+	return nk.ks.Has(nk.prefix + name)
+}
+
+// Put stores a key in the namespace, if a key with the same name already
+// exists, returns ErrKeyExists
+func (nk *NamespacedKeystore) Put(name string, k ci.PrivKey) error {
+	if name == "" {
+		return errors.New("key name must be at least one character")
+	}
+	if err := nk.check(OpPut, name); err != nil {
+		return err
+	}
+	return nk.ks.Put(nk.prefix+name, k)
+}
+
+// Get retrieves a key from the namespace if it exists, and returns
+// ErrNoSuchKey otherwise.
+func (nk *NamespacedKeystore) Get(name string) (ci.PrivKey, error) {
+	if err := nk.check(OpGet, name); err != nil {
+		return nil, err
+	}
+	return nk.ks.Get(nk.prefix + name)
+}
+
+// Delete removes a key from the namespace
+func (nk *NamespacedKeystore) Delete(name string) error {
+	if err := nk.check(OpDelete, name); err != nil {
+		return err
+	}
+	return nk.ks.Delete(nk.prefix + name)
+}
+
+// List returns the names of the keys in the namespace, without the namespace
+func (nk *NamespacedKeystore) List() ([]string, error) {
+	if err := nk.check(OpList, ""); err != nil {
+		return nil, err
+	}
+
+	names, err := nk.ks.List()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, nk.prefix) {
+			list = append(list, name[len(nk.prefix):])
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,77 @@
+package keystore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNamespacedKeystore(t *testing.T) {
+	backing := NewMemKeystore()
+
+	alice, err := NewNamespacedKeystore(backing, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bob may only read keys.
+	bob, err := NewNamespacedKeystore(backing, "bob", func(namespace string, op Op, name string) error {
+		if op == OpPut || op == OpDelete {
+			return errors.New("read-only tenant")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewNamespacedKeystore(backing, "a/b", nil); err == nil {
+		t.Fatal("expected namespace with a slash to be refused")
+	}
+
+	k := privKeyOrFatal(t)
+	if err := alice.Put("foo", k); err != nil {
+		t.Fatal(err)
+	}
+	if err := backing.Put("bob/foo", k); err != nil {
+		t.Fatal(err)
+	}
+
+	if has, err := backing.Has("alice/foo"); err != nil || !has {
+		t.Fatalf("expected key in the namespace of the backing keystore, got %t, %v", has, err)
+	}
+
+	l, err := alice.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0] != "foo" {
+		t.Fatalf("expected only the keys of the namespace, got %v", l)
+	}
+
+	got, err := bob.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equals(k) {
+		t.Fatal("got the wrong key")
+	}
+	if _, err := bob.Get("bar"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("expected ErrNoSuchKey, got %v", err)
+	}
+
+	if err := bob.Put("bar", k); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if err := bob.Delete("foo"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if has, err := backing.Has("bob/foo"); err != nil || !has {
+		t.Fatalf("expected key not to be deleted, got %t, %v", has, err)
+	}
+
+	if err := alice.Delete("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if has, err := alice.Has("foo"); err != nil || has {
+		t.Fatalf("expected key to be deleted, got %t, %v", has, err)
+	}
+}
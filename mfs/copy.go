@@ -0,0 +1,382 @@
+package mfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	gopath "path"
+	"path/filepath"
+	"strings"
+
+	chunker "github.com/ipfs/boxo/chunker"
+	"github.com/ipfs/boxo/files"
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	ufile "github.com/ipfs/boxo/ipld/unixfs/file"
+	bal "github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
+	h "github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// CopyOpts is used by CopyFromOS and CopyToOS.
+type CopyOpts struct {
+	// Filter excludes the files and directories it matches, by their path
+	// relative to the copied directory, see [files.Filter.ExcludePath].
+	// Without filter, everything is copied, including hidden files.
+	Filter *files.Filter
+
+	// Progress is called after every file, symlink and directory copied.
+	Progress func(CopyProgress)
+
+	// PreserveMetadata copies the mode and modification time of files and
+	// directories, stored in UnixFS nodes since UnixFS 1.5. MFS directories
+	// do not keep them, so they are only preserved for the files and
+	// symlinks copied to MFS. The local filesystem does not let them be set
+	// on symlinks, so they are only preserved for the files and directories
+	// copied from MFS.
+	PreserveMetadata bool
+
+	// Overwrite replaces the files which already exist at the destination.
+	// Directories which already exist are always merged.
+	Overwrite bool
+
+	// Chunker is the chunker used to split files copied to MFS, in the
+	// format of [chunker.FromString]. Defaults to the default chunker.
+	Chunker string
+	// RawLeaves stores the data of the files copied to MFS in raw leaves
+	// rather than UnixFS nodes.
+	RawLeaves bool
+	// CidBuilder builds the CIDs of the files, symlinks and directories
+	// copied to MFS. By default, files and symlinks get CIDv0 and
+	// directories get the CID builder of their parent.
+	CidBuilder cid.Builder
+
+	// Mkparents creates the missing parent directories of the MFS
+	// destination.
+	Mkparents bool
+	// Flush flushes the MFS destination once copied, so that its new root
+	// is written to the DAG service.
+	Flush bool
+}
+
+// CopyProgress reports the progress of CopyFromOS and CopyToOS.
+type CopyProgress struct {
+	// Path is the slash-separated path of the entry which was copied,
+	// relative to the copied directory.
+	Path string
+	// Files and Bytes count the files copied so far, and their size.
+	Files int
+	Bytes int64
+}
+
+// CopyFromOS copies the file, symlink or directory at osPath on the local
+// filesystem to mfsPath. Directories are copied recursively.
+func CopyFromOS(ctx context.Context, r *Root, osPath, mfsPath string, opts CopyOpts) error {
+	if opts.Chunker == "" {
+		opts.Chunker = "default"
+	}
+	stat, err := os.Lstat(osPath)
+	if err != nil {
+		return err
+	}
+	// hidden files are excluded by the filter
+	nd, err := files.NewSerialFile(osPath, true, stat)
+	if err != nil {
+		return err
+	}
+	defer nd.Close()
+
+	mfsPath = gopath.Clean("/" + mfsPath)
+	if dir := gopath.Dir(mfsPath); opts.Mkparents && dir != "/" {
+		err := Mkdir(r, dir, MkdirOpts{Mkparents: true, CidBuilder: opts.CidBuilder})
+		if err != nil {
+			return err
+		}
+	} else if _, err := lookupDir(r, dir); err != nil {
+		return err
+	}
+
+	c := &copier{ctx: ctx, opts: opts}
+	if err := c.fromOS(r, nd, mfsPath, ""); err != nil {
+		return err
+	}
+
+	if opts.Flush {
+		_, err := FlushPath(ctx, r, mfsPath)
+		return err
+	}
+	return nil
+}
+
+// CopyToOS copies the file, symlink or directory at mfsPath to osPath on the
+// local filesystem. Directories are copied recursively.
+func CopyToOS(ctx context.Context, r *Root, mfsPath, osPath string, opts CopyOpts) error {
+	fsn, err := Lookup(r, mfsPath)
+	if err != nil {
+		return err
+	}
+	nd, err := fsn.GetNode()
+	if err != nil {
+		return err
+	}
+	f, err := ufile.NewUnixfsFile(ctx, r.GetDirectory().dagService, nd)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c := &copier{ctx: ctx, opts: opts}
+	return c.toOS(f, osPath, "")
+}
+
+type copier struct {
+	ctx      context.Context
+	opts     CopyOpts
+	progress CopyProgress
+}
+
+// skip returns true if the entry at the relative path rel is filtered out.
+// The copied directory itself is never filtered out.
+func (c *copier) skip(rel string, nd files.Node) bool {
+	return rel != "" && c.opts.Filter != nil && c.opts.Filter.ExcludePath(rel, nd)
+}
+
+func (c *copier) report(rel string, size int64, isFile bool) {
+	if isFile {
+		c.progress.Files++
+		c.progress.Bytes += size
+	}
+	if c.opts.Progress != nil {
+		c.progress.Path = rel
+		c.opts.Progress(c.progress)
+	}
+}
+
+func (c *copier) fromOS(r *Root, nd files.Node, mfsPath, rel string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	switch nd := nd.(type) {
+	case files.Directory:
+		err := Mkdir(r, mfsPath, MkdirOpts{Mkparents: true, CidBuilder: c.opts.CidBuilder})
+		if err != nil {
+			return err
+		}
+		it := nd.Entries()
+		for it.Next() {
+			childRel := gopath.Join(rel, it.Name())
+			if c.skip(childRel, it.Node()) {
+				continue
+			}
+			if err := c.fromOS(r, it.Node(), gopath.Join(mfsPath, it.Name()), childRel); err != nil {
+				return err
+			}
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		c.report(rel, 0, false)
+		return nil
+	case *files.Symlink:
+		fsn := ft.NewFSNode(ft.TSymlink)
+		fsn.SetData([]byte(nd.Target))
+		if c.opts.PreserveMetadata {
+			fsn.SetMode(nd.Mode())
+			fsn.SetModTime(nd.ModTime())
+		}
+		data, err := fsn.GetBytes()
+		if err != nil {
+			return err
+		}
+		node := dag.NodeWithData(data)
+		if c.opts.CidBuilder != nil {
+			if err := node.SetCidBuilder(c.opts.CidBuilder); err != nil {
+				return err
+			}
+		}
+		if err := r.GetDirectory().dagService.Add(c.ctx, node); err != nil {
+			return err
+		}
+		if err := c.put(r, mfsPath, node); err != nil {
+			return err
+		}
+		c.report(rel, 0, false)
+		return nil
+	case files.File:
+		defer nd.Close()
+		node, err := c.importFile(r.GetDirectory().dagService, nd)
+		if err != nil {
+			return fmt.Errorf("%s: %w", mfsPath, err)
+		}
+		if err := c.put(r, mfsPath, node); err != nil {
+			return err
+		}
+		size, err := nd.Size()
+		if err != nil {
+			return err
+		}
+		c.report(rel, size, true)
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported file type %T", mfsPath, nd)
+	}
+}
+
+func (c *copier) importFile(dserv ipld.DAGService, f files.File) (ipld.Node, error) {
+	spl, err := chunker.FromString(f, c.opts.Chunker)
+	if err != nil {
+		return nil, err
+	}
+	dbp := h.DagBuilderParams{
+		Dagserv:    dserv,
+		Maxlinks:   h.DefaultLinksPerBlock,
+		RawLeaves:  c.opts.RawLeaves,
+		CidBuilder: c.opts.CidBuilder,
+	}
+	if c.opts.PreserveMetadata {
+		dbp.FileMode = f.Mode()
+		dbp.FileModTime = f.ModTime()
+	}
+	db, err := dbp.New(spl)
+	if err != nil {
+		return nil, err
+	}
+	return bal.Layout(db)
+}
+
+// put adds nd at mfsPath, replacing the file already there if Overwrite is
+// set.
+func (c *copier) put(r *Root, mfsPath string, nd ipld.Node) error {
+	dirp, name := gopath.Split(mfsPath)
+	pdir, err := lookupDir(r, dirp)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := pdir.Child(name); err == nil {
+		if !c.opts.Overwrite || existing.Type() == TDir {
+			return fmt.Errorf("%s: %w", mfsPath, os.ErrExist)
+		}
+		if err := pdir.Unlink(name); err != nil {
+			return err
+		}
+	} else if err != os.ErrNotExist {
+		return err
+	}
+	return pdir.AddChild(name, nd)
+}
+
+func (c *copier) toOS(nd files.Node, osPath, rel string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	switch nd := nd.(type) {
+	case files.Directory:
+		err := os.Mkdir(osPath, 0o777)
+		if errors.Is(err, os.ErrExist) {
+			var stat os.FileInfo
+			if stat, err = os.Stat(osPath); err == nil && !stat.IsDir() {
+				err = fmt.Errorf("%s: %w", osPath, files.ErrPathExistsOverwrite)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		it := nd.Entries()
+		for it.Next() {
+			name := it.Name()
+			if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+				return fmt.Errorf("%q: %w", name, files.ErrInvalidDirectoryEntry)
+			}
+			childRel := gopath.Join(rel, name)
+			if c.skip(childRel, it.Node()) {
+				continue
+			}
+			if err := c.toOS(it.Node(), filepath.Join(osPath, name), childRel); err != nil {
+				return err
+			}
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		// applied last so that read-only directories can still be filled and
+		// writing the children does not bump the modification time
+		if err := c.setMetadata(nd, osPath); err != nil {
+			return err
+		}
+		c.report(rel, 0, false)
+		return nil
+	case *files.Symlink:
+		if err := c.replace(osPath); err != nil {
+			return err
+		}
+		if err := os.Symlink(nd.Target, osPath); err != nil {
+			return err
+		}
+		c.report(rel, 0, false)
+		return nil
+	case files.File:
+		if err := c.replace(osPath); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(osPath, os.O_EXCL|os.O_CREATE|os.O_WRONLY, 0o666)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(f, nd)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.setMetadata(nd, osPath); err != nil {
+			return err
+		}
+		c.report(rel, n, true)
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported file type %T", osPath, nd)
+	}
+}
+
+// replace removes the file at osPath if Overwrite is set, and fails if it
+// exists otherwise.
+func (c *copier) replace(osPath string) error {
+	stat, err := os.Lstat(osPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !c.opts.Overwrite || stat.IsDir() {
+		return fmt.Errorf("%s: %w", osPath, files.ErrPathExistsOverwrite)
+	}
+	return os.Remove(osPath)
+}
+
+// setMetadata applies the mode and modification time of nd to osPath when
+// PreserveMetadata is set and they are known.
+func (c *copier) setMetadata(nd files.Node, osPath string) error {
+	if !c.opts.PreserveMetadata {
+		return nil
+	}
+	if mode := nd.Mode(); mode != 0 {
+		if err := os.Chmod(osPath, mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+	}
+	if mtime := nd.ModTime(); !mtime.IsZero() {
+		if err := os.Chtimes(osPath, mtime, mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
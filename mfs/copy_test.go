@@ -0,0 +1,201 @@
+package mfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+)
+
+func writeOSFile(t *testing.T, path, content string, mode os.FileMode, mtime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyOS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, rt := setupRoot(ctx, t)
+
+	mtime := time.Unix(1700000000, 0)
+	src := t.TempDir()
+	writeOSFile(t, filepath.Join(src, "a.txt"), "hello", 0o640, mtime)
+	writeOSFile(t, filepath.Join(src, "sub", "b.txt"), "world!", 0o600, mtime)
+	writeOSFile(t, filepath.Join(src, "sub", "skip.log"), "ignored", 0o644, mtime)
+	writeOSFile(t, filepath.Join(src, ".hidden"), "hidden", 0o644, mtime)
+
+	filter, err := files.NewFilterWithOptions(files.FilterExclude("*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var copied []string
+	var last CopyProgress
+	opts := CopyOpts{
+		Filter: filter,
+		Progress: func(p CopyProgress) {
+			copied = append(copied, p.Path)
+			last = p
+		},
+		PreserveMetadata: true,
+		Mkparents:        true,
+	}
+	if err := CopyFromOS(ctx, rt, src, "/imported/tree", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(copied)
+	if expected := []string{"", "a.txt", "sub", "sub/b.txt"}; !slices.Equal(copied, expected) {
+		t.Fatalf("expected progress for %v, got %v", expected, copied)
+	}
+	if last.Files != 2 || last.Bytes != 11 {
+		t.Fatalf("expected 2 files and 11 bytes copied, got %d files and %d bytes", last.Files, last.Bytes)
+	}
+	if _, err := Lookup(rt, "/imported/tree/sub/skip.log"); err == nil {
+		t.Fatal("expected filtered file not to be copied")
+	}
+	if _, err := Lookup(rt, "/imported/tree/.hidden"); err == nil {
+		t.Fatal("expected hidden file not to be copied")
+	}
+
+	// copying again fails unless overwriting
+	if err := CopyFromOS(ctx, rt, src, "/imported/tree", CopyOpts{Filter: filter}); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("expected os.ErrExist, got %v", err)
+	}
+	if err := CopyFromOS(ctx, rt, src, "/imported/tree", CopyOpts{Filter: filter, Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyFromOS(ctx, rt, src, "/imported/tree", CopyOpts{Filter: filter, Overwrite: true, PreserveMetadata: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "exported")
+	if err := CopyToOS(ctx, rt, "/imported/tree", dst, CopyOpts{PreserveMetadata: true}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.txt": "hello", "sub/b.txt": "world!"} {
+		p := filepath.Join(dst, filepath.FromSlash(name))
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Fatalf("expected %q in %s, got %q", content, name, data)
+		}
+		stat, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stat.ModTime().Equal(mtime) {
+			t.Fatalf("expected mtime %s for %s, got %s", mtime, name, stat.ModTime())
+		}
+	}
+	if runtime.GOOS != "windows" {
+		stat, err := os.Stat(filepath.Join(dst, "sub", "b.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != 0o600 {
+			t.Fatalf("expected mode 0600, got %s", stat.Mode().Perm())
+		}
+	}
+
+	// existing files are only replaced when overwriting
+	if err := CopyToOS(ctx, rt, "/imported/tree", dst, CopyOpts{}); !errors.Is(err, files.ErrPathExistsOverwrite) {
+		t.Fatalf("expected ErrPathExistsOverwrite, got %v", err)
+	}
+	if err := CopyToOS(ctx, rt, "/imported/tree", dst, CopyOpts{Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyFromOSMissingParent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, rt := setupRoot(ctx, t)
+
+	src := filepath.Join(t.TempDir(), "file")
+	writeOSFile(t, src, "content", 0o644, time.Now())
+	if err := CopyFromOS(ctx, rt, src, "/missing/file", CopyOpts{}); err == nil {
+		t.Fatal("expected an error without Mkparents")
+	}
+	if err := CopyFromOS(ctx, rt, src, "/missing/file", CopyOpts{Mkparents: true}); err != nil {
+		t.Fatal(err)
+	}
+	fsn, err := Lookup(rt, "/missing/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fsn.Type() != TFile {
+		t.Fatal("expected a file")
+	}
+}
+
+func TestCopyFromOSSymlinkMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, rt := setupRoot(ctx, t)
+
+	src := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink("target", src); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Lstat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, preserve := range []bool{false, true} {
+		if err := CopyFromOS(ctx, rt, src, "/link", CopyOpts{PreserveMetadata: preserve, Overwrite: true}); err != nil {
+			t.Fatal(err)
+		}
+		fsn, err := Lookup(rt, "/link")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nd, err := fsn.GetNode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ufsn, err := ft.ExtractFSNode(nd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ufsn.Type() != ft.TSymlink || string(ufsn.Data()) != "target" {
+			t.Fatal("expected a symlink to target")
+		}
+		if !preserve {
+			if !ufsn.ModTime().IsZero() || ufsn.Mode() != 0 {
+				t.Fatal("expected no metadata")
+			}
+			continue
+		}
+		if !ufsn.ModTime().Equal(stat.ModTime()) {
+			t.Fatalf("expected mtime %s, got %s", stat.ModTime(), ufsn.ModTime())
+		}
+		if ufsn.Mode().Perm() != stat.Mode().Perm() {
+			t.Fatalf("expected mode %s, got %s", stat.Mode().Perm(), ufsn.Mode().Perm())
+		}
+	}
+}
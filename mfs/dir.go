@@ -263,32 +263,42 @@ func (d *Directory) ForEachEntry(ctx context.Context, f func(NodeListing) error)
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	return d.unixfsDir.ForEachLink(ctx, func(l *ipld.Link) error {
-		c, err := d.childUnsync(l.Name)
+		child, err := d.listingUnsync(l.Name)
 		if err != nil {
 			return err
 		}
+		return f(child)
+	})
+}
 
-		nd, err := c.GetNode()
-		if err != nil {
-			return err
-		}
+// listingUnsync returns the listing of a child, loading it if it is not
+// cached yet.
+func (d *Directory) listingUnsync(name string) (NodeListing, error) {
+	c, err := d.childUnsync(name)
+	if err != nil {
+		return NodeListing{}, err
+	}
 
-		child := NodeListing{
-			Name: l.Name,
-			Type: int(c.Type()),
-			Hash: nd.Cid().String(),
-		}
+	nd, err := c.GetNode()
+	if err != nil {
+		return NodeListing{}, err
+	}
 
-		if c, ok := c.(*File); ok {
-			size, err := c.Size()
-			if err != nil {
-				return err
-			}
-			child.Size = size
+	child := NodeListing{
+		Name: name,
+		Type: int(c.Type()),
+		Hash: nd.Cid().String(),
+	}
+
+	if c, ok := c.(*File); ok {
+		size, err := c.Size()
+		if err != nil {
+			return NodeListing{}, err
 		}
+		child.Size = size
+	}
 
-		return f(child)
-	})
+	return child, nil
 }
 
 func (d *Directory) Mkdir(name string) (*Directory, error) {
@@ -427,3 +437,34 @@ func (d *Directory) GetNode() (ipld.Node, error) {
 
 	return nd.Copy(), err
 }
+
+// replaceIfUnchanged replaces the content of the directory with nd, unless
+// the directory was modified since its node was base. The cached entries are
+// dropped, so references to the previous children become stale.
+func (d *Directory) replaceIfUnchanged(base cid.Cid, nd ipld.Node) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	err := d.sync()
+	if err != nil {
+		return err
+	}
+
+	cur, err := d.unixfsDir.GetNode()
+	if err != nil {
+		return err
+	}
+	if !cur.Cid().Equals(base) {
+		return ErrTransactionConflict
+	}
+
+	db, err := uio.NewDirectoryFromNode(d.dagService, nd)
+	if err != nil {
+		return err
+	}
+
+	d.unixfsDir = db
+	d.entriesCache = make(map[string]FSNode)
+	d.modTime = time.Now()
+	return nil
+}
@@ -0,0 +1,124 @@
+package mfs
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// SortOrder is the order of the entries returned by [Directory.ListEntries].
+type SortOrder int
+
+const (
+	// SortNone returns the entries in the order they are stored in the
+	// directory: by name for basic directories, and by hash of the name for
+	// HAMT sharded directories. It stops reading the directory once the page
+	// is complete.
+	SortNone SortOrder = iota
+	// SortByName returns the entries sorted by name.
+	SortByName
+	// SortByNameReverse returns the entries in reverse order of name.
+	SortByNameReverse
+)
+
+// errPageComplete stops the iteration over the links of a directory.
+var errPageComplete = errors.New("page complete")
+
+// ListEntries returns limit entries of the directory starting at offset, in
+// the given order. A limit of 0 returns all the entries after offset.
+//
+// Only the children on the page are loaded. Sorting keeps at most
+// offset+limit names in memory, so listing the first pages of large HAMT
+// sharded directories is cheap.
+func (d *Directory) ListEntries(ctx context.Context, offset, limit int, order SortOrder) ([]NodeListing, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var names []string
+	switch order {
+	case SortNone:
+		var i int
+		err := d.unixfsDir.ForEachLink(ctx, func(l *ipld.Link) error {
+			if i >= offset {
+				names = append(names, l.Name)
+			}
+			i++
+			if limit > 0 && len(names) == limit {
+				return errPageComplete
+			}
+			return nil
+		})
+		if err != nil && err != errPageComplete {
+			return nil, err
+		}
+	case SortByName, SortByNameReverse:
+		// Keep the first offset+limit names in a heap whose top is the
+		// last one kept.
+		h := &nameHeap{reverse: order == SortByNameReverse}
+		err := d.unixfsDir.ForEachLink(ctx, func(l *ipld.Link) error {
+			switch {
+			case limit == 0 || h.Len() < offset+limit:
+				heap.Push(h, l.Name)
+			case h.before(l.Name, h.names[0]):
+				h.names[0] = l.Name
+				heap.Fix(h, 0)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		names = h.names
+		sort.Slice(names, func(i, j int) bool { return h.before(names[i], names[j]) })
+		if offset >= len(names) {
+			names = nil
+		} else {
+			names = names[offset:]
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort order %d", order)
+	}
+
+	out := make([]NodeListing, 0, len(names))
+	for _, name := range names {
+		child, err := d.listingUnsync(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+	}
+	return out, nil
+}
+
+// nameHeap is a heap of names whose top is the last name in the sort order.
+type nameHeap struct {
+	names   []string
+	reverse bool
+}
+
+// before reports whether a comes before b in the sort order.
+func (h *nameHeap) before(a, b string) bool {
+	if h.reverse {
+		return a > b
+	}
+	return a < b
+}
+
+func (h *nameHeap) Len() int           { return len(h.names) }
+func (h *nameHeap) Less(i, j int) bool { return h.before(h.names[j], h.names[i]) }
+func (h *nameHeap) Swap(i, j int)      { h.names[i], h.names[j] = h.names[j], h.names[i] }
+func (h *nameHeap) Push(x any)         { h.names = append(h.names, x.(string)) }
+
+func (h *nameHeap) Pop() any {
+	n := h.names[len(h.names)-1]
+	h.names = h.names[:len(h.names)-1]
+	return n
+}
@@ -0,0 +1,131 @@
+package mfs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+)
+
+func TestListEntries(t *testing.T) {
+	for _, sharded := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sharded=%v", sharded), func(t *testing.T) {
+			if sharded {
+				oldShardingSize := uio.HAMTShardingSize
+				uio.HAMTShardingSize = 1
+				t.Cleanup(func() { uio.HAMTShardingSize = oldShardingSize })
+			}
+			testListEntries(t, sharded)
+		})
+	}
+}
+
+func testListEntries(t *testing.T, sharded bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds, rt := setupRoot(ctx, t)
+
+	dir, err := rt.GetDirectory().Mkdir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for i := 0; i < 40; i++ {
+		name := fmt.Sprintf("entry-%02d", (i*7)%40)
+		names = append(names, name)
+		if err := dir.AddChild(name, getRandFile(t, ds, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Strings(names)
+	nd, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// load the directory again, without any cached child
+	rt2, err := NewRoot(ctx, ds, nd.(*dag.ProtoNode), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir = rt2.GetDirectory()
+	if _, ok := dir.unixfsDir.(*uio.DynamicDirectory).Directory.(*uio.HAMTDirectory); ok != sharded {
+		t.Fatalf("expected sharded=%v directory", sharded)
+	}
+
+	page, err := dir.ListEntries(ctx, 5, 10, SortByName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 10 {
+		t.Fatalf("expected 10 entries, got %d", len(page))
+	}
+	for i, e := range page {
+		if e.Name != names[5+i] {
+			t.Fatalf("expected %s at %d, got %s", names[5+i], i, e.Name)
+		}
+		if e.Size != 10 || e.Type != int(TFile) || e.Hash == "" {
+			t.Fatalf("incomplete listing %+v", e)
+		}
+	}
+	if len(dir.entriesCache) != 10 {
+		t.Fatalf("expected only the page to be loaded, got %d children", len(dir.entriesCache))
+	}
+
+	page, err = dir.ListEntries(ctx, 0, 3, SortByNameReverse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range page {
+		if e.Name != names[len(names)-1-i] {
+			t.Fatalf("expected %s at %d, got %s", names[len(names)-1-i], i, e.Name)
+		}
+	}
+
+	page, err = dir.ListEntries(ctx, 35, 10, SortByName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 5 {
+		t.Fatalf("expected the 5 last entries, got %d", len(page))
+	}
+	page, err = dir.ListEntries(ctx, 40, 10, SortByName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 0 {
+		t.Fatalf("expected no entry past the end, got %d", len(page))
+	}
+
+	// unsorted pages cover the directory once
+	seen := make(map[string]bool)
+	for offset := 0; ; offset += 15 {
+		page, err := dir.ListEntries(ctx, offset, 15, SortNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, e := range page {
+			if seen[e.Name] {
+				t.Fatalf("%s listed twice", e.Name)
+			}
+			seen[e.Name] = true
+		}
+	}
+	if len(seen) != len(names) {
+		t.Fatalf("expected %d entries, got %d", len(names), len(seen))
+	}
+
+	all, err := dir.ListEntries(ctx, 0, 0, SortByName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(names) {
+		t.Fatalf("expected %d entries, got %d", len(names), len(all))
+	}
+}
@@ -0,0 +1,258 @@
+package mfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	gopath "path"
+	"sync"
+
+	dag "github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+var (
+	// ErrTransactionConflict is returned by [Transaction.Commit] when the root
+	// was modified after the transaction began.
+	ErrTransactionConflict = errors.New("mfs: root was modified since the transaction began")
+
+	// ErrTransactionDone is returned when using a transaction which was
+	// already committed or rolled back.
+	ErrTransactionDone = errors.New("mfs: transaction already committed or rolled back")
+)
+
+// Transaction batches several operations on a [Root] and applies them at
+// once. Operations are applied to a private copy of the root, which is swapped
+// in by [Transaction.Commit] if the root was not modified in the meantime. A
+// single new root is then published, so concurrent writers never observe nor
+// publish partially applied changes.
+//
+// If an operation fails, the transaction is rolled back and the root is left
+// untouched. A Transaction is safe for concurrent use, but operations are
+// applied in the order they are called.
+type Transaction struct {
+	root *Root
+	base cid.Cid
+
+	lk      sync.Mutex
+	scratch *Root
+	err     error
+}
+
+// Begin starts a transaction on the root.
+func (kr *Root) Begin(ctx context.Context) (*Transaction, error) {
+	nd, err := kr.GetDirectory().GetNode()
+	if err != nil {
+		return nil, err
+	}
+	pbnd, ok := nd.(*dag.ProtoNode)
+	if !ok {
+		return nil, dag.ErrNotProtobuf
+	}
+
+	// The copy has no republisher: only the commit publishes.
+	scratch, err := NewRoot(ctx, kr.GetDirectory().dagService, pbnd.Copy().(*dag.ProtoNode), nil)
+	if err != nil {
+		return nil, err
+	}
+	scratch.GetDirectory().SetCidBuilder(kr.GetDirectory().GetCidBuilder())
+
+	return &Transaction{
+		root:    kr,
+		base:    nd.Cid(),
+		scratch: scratch,
+	}, nil
+}
+
+// Transact runs fn in a transaction and commits it. If the commit fails with
+// [ErrTransactionConflict], a new transaction is started and fn is run again,
+// until it succeeds or ctx is done. fn must not commit nor roll back the
+// transaction; returning an error rolls it back.
+func (kr *Root) Transact(ctx context.Context, fn func(*Transaction) error) error {
+	for {
+		tx, err := kr.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = tx.Commit()
+		if !errors.Is(err, ErrTransactionConflict) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// do applies op to the private copy of the root. The transaction is rolled
+// back if op fails.
+func (t *Transaction) do(op func(r *Root) error) error {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if t.scratch == nil {
+		if t.err != nil {
+			return t.err
+		}
+		return ErrTransactionDone
+	}
+
+	if err := op(t.scratch); err != nil {
+		t.scratch = nil
+		t.err = fmt.Errorf("mfs: transaction rolled back: %w", err)
+		return err
+	}
+	return nil
+}
+
+// Mkdir creates a directory at path, see [Mkdir]. The Flush option is
+// ignored: the directory is only flushed on commit.
+func (t *Transaction) Mkdir(path string, opts MkdirOpts) error {
+	opts.Flush = false
+	return t.do(func(r *Root) error {
+		return Mkdir(r, path, opts)
+	})
+}
+
+// PutNode inserts nd at path, see [PutNode].
+func (t *Transaction) PutNode(path string, nd ipld.Node) error {
+	return t.do(func(r *Root) error {
+		return PutNode(r, path, nd)
+	})
+}
+
+// Write replaces the content of the file at path with the data read from
+// data. The file is created if it does not exist.
+func (t *Transaction) Write(path string, data io.Reader) error {
+	return t.do(func(r *Root) error {
+		fsn, err := Lookup(r, path)
+		if errors.Is(err, os.ErrNotExist) {
+			dirp, filename := gopath.Split(path)
+			pdir, err := lookupDir(r, dirp)
+			if err != nil {
+				return err
+			}
+			nd := dag.NodeWithData(ft.FilePBData(nil, 0))
+			nd.SetCidBuilder(pdir.GetCidBuilder())
+			if err := pdir.AddChild(filename, nd); err != nil {
+				return err
+			}
+			fsn, err = pdir.Child(filename)
+			if err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		fi, ok := fsn.(*File)
+		if !ok {
+			return fmt.Errorf("%s is not a file", path)
+		}
+
+		fd, err := fi.Open(Flags{Write: true, Sync: true})
+		if err != nil {
+			return err
+		}
+		if err := fd.Truncate(0); err != nil {
+			fd.Close()
+			return err
+		}
+		if _, err := io.Copy(fd, data); err != nil {
+			fd.Close()
+			return err
+		}
+		return fd.Close()
+	})
+}
+
+// Mv moves the file or directory at src to dst, see [Mv].
+func (t *Transaction) Mv(src, dst string) error {
+	return t.do(func(r *Root) error {
+		return Mv(r, src, dst)
+	})
+}
+
+// Rm removes the file or directory at path.
+func (t *Transaction) Rm(path string) error {
+	return t.do(func(r *Root) error {
+		dirp, name := gopath.Split(gopath.Clean(path))
+		if name == "" || name == "/" {
+			return errors.New("cannot remove the root directory")
+		}
+		pdir, err := lookupDir(r, dirp)
+		if err != nil {
+			return err
+		}
+		return pdir.Unlink(name)
+	})
+}
+
+// Lookup returns the file or directory at path, as modified by the
+// transaction so far. The returned node must not be used after the
+// transaction is committed or rolled back.
+func (t *Transaction) Lookup(path string) (FSNode, error) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if t.scratch == nil {
+		return nil, ErrTransactionDone
+	}
+	return Lookup(t.scratch, path)
+}
+
+// Commit applies the operations of the transaction to the root and publishes
+// the new root. It returns [ErrTransactionConflict] if the root was modified
+// since the transaction began, in which case nothing is applied.
+//
+// CAUTION: like with [Root.FlushMemFree], references to the children of the
+// root obtained before the commit become stale and must not be used anymore.
+func (t *Transaction) Commit() error {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if t.scratch == nil {
+		if t.err != nil {
+			return t.err
+		}
+		return ErrTransactionDone
+	}
+	scratch := t.scratch
+	t.scratch = nil
+	t.err = ErrTransactionDone
+
+	nd, err := scratch.GetDirectory().GetNode()
+	if err != nil {
+		return err
+	}
+
+	if err := t.root.GetDirectory().replaceIfUnchanged(t.base, nd); err != nil {
+		return err
+	}
+
+	if t.root.repub != nil {
+		t.root.repub.Update(nd.Cid())
+	}
+	return nil
+}
+
+// Rollback discards the operations of the transaction. It is a no-op if the
+// transaction was already committed or rolled back.
+func (t *Transaction) Rollback() {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if t.scratch != nil {
+		t.scratch = nil
+		t.err = ErrTransactionDone
+	}
+}
@@ -0,0 +1,245 @@
+package mfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+func setupTransactionRoot(ctx context.Context, t *testing.T) (*Root, <-chan cid.Cid) {
+	t.Helper()
+
+	published := make(chan cid.Cid, 16)
+	rt, err := NewRoot(ctx, getDagserv(t), emptyDirNode(), func(ctx context.Context, c cid.Cid) error {
+		published <- c
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rt, published
+}
+
+func readFileAtPath(t *testing.T, rt *Root, path string) string {
+	t.Helper()
+
+	fsn, err := Lookup(rt, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fsn.(*File).Open(Flags{Read: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	data, err := io.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestTransactionCommit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt, published := setupTransactionRoot(ctx, t)
+	if err := Mkdir(rt, "/old", MkdirOpts{Flush: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := rt.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Mkdir("/a/b", MkdirOpts{Mkparents: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Write("/a/b/file", strings.NewReader("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Write("/a/b/file", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Mv("/a/b", "/c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rm("/old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Lookup("/c/file"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is applied before the commit.
+	if _, err := Lookup(rt, "/a"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected /a not to exist before commit, got %v", err)
+	}
+	if _, err := Lookup(rt, "/old"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFileAtPath(t, rt, "/c/file"); got != "hello" {
+		t.Fatalf("expected file content %q, got %q", "hello", got)
+	}
+	if _, err := Lookup(rt, "/a/b"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected /a/b to be moved, got %v", err)
+	}
+	if _, err := Lookup(rt, "/old"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected /old to be removed, got %v", err)
+	}
+
+	if err := tx.Mkdir("/d", MkdirOpts{}); !errors.Is(err, ErrTransactionDone) {
+		t.Fatalf("expected ErrTransactionDone, got %v", err)
+	}
+
+	// The new root is published.
+	nd, err := rt.GetDirectory().GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case c := <-published:
+			if c.Equals(nd.Cid()) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("new root was not published")
+		}
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt, _ := setupTransactionRoot(ctx, t)
+	before, err := rt.GetDirectory().GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := rt.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Mkdir("/a", MkdirOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Mv("/missing", "/b"); err == nil {
+		t.Fatal("expected an error moving a missing file")
+	}
+
+	// The transaction was rolled back by the failed operation.
+	if err := tx.Mkdir("/c", MkdirOpts{}); err == nil {
+		t.Fatal("expected an error after the transaction was rolled back")
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected commit of a rolled back transaction to fail")
+	}
+
+	after, err := rt.GetDirectory().GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !before.Cid().Equals(after.Cid()) {
+		t.Fatal("root was modified by a rolled back transaction")
+	}
+
+	tx, err = rt.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Mkdir("/a", MkdirOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if err := tx.Commit(); !errors.Is(err, ErrTransactionDone) {
+		t.Fatalf("expected ErrTransactionDone, got %v", err)
+	}
+	if _, err := Lookup(rt, "/a"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected /a not to exist after rollback, got %v", err)
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt, _ := setupTransactionRoot(ctx, t)
+
+	tx, err := rt.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Mkdir("/a", MkdirOpts{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A concurrent modification of the root.
+	if err := Mkdir(rt, "/b", MkdirOpts{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); !errors.Is(err, ErrTransactionConflict) {
+		t.Fatalf("expected ErrTransactionConflict, got %v", err)
+	}
+	if _, err := Lookup(rt, "/a"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected /a not to exist after conflict, got %v", err)
+	}
+	if _, err := Lookup(rt, "/b"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransactConcurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt, _ := setupTransactionRoot(ctx, t)
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := string(rune('a' + i))
+			errs <- rt.Transact(ctx, func(tx *Transaction) error {
+				if err := tx.Mkdir("/"+name, MkdirOpts{}); err != nil {
+					return err
+				}
+				return tx.Write("/"+name+"/file", strings.NewReader(name))
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < writers; i++ {
+		name := string(rune('a' + i))
+		if got := readFileAtPath(t, rt, "/"+name+"/file"); got != name {
+			t.Fatalf("expected file content %q, got %q", name, got)
+		}
+	}
+}
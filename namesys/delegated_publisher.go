@@ -0,0 +1,175 @@
+package namesys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ipfs/boxo/routing/http/client"
+	"github.com/ipfs/boxo/routing/http/contentrouter"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/routing"
+)
+
+const (
+	// DefaultDelegatedPublishAttempts is the default number of times a
+	// record is sent to a delegated endpoint before giving up.
+	DefaultDelegatedPublishAttempts = 3
+
+	// DefaultDelegatedPublishBackoff is the default delay before the first
+	// retry. It doubles after each attempt.
+	DefaultDelegatedPublishBackoff = time.Second
+)
+
+type delegatedPublisherOptions struct {
+	clientOpts []client.Option
+	attempts   int
+	backoff    time.Duration
+}
+
+// DelegatedPublisherOption is an option for [NewDelegatedPublisher].
+type DelegatedPublisherOption func(*delegatedPublisherOptions)
+
+// WithDelegatedClientOptions sets the options used to create the Routing V1
+// HTTP [client.Client] of each endpoint.
+func WithDelegatedClientOptions(opts ...client.Option) DelegatedPublisherOption {
+	return func(o *delegatedPublisherOptions) {
+		o.clientOpts = append(o.clientOpts, opts...)
+	}
+}
+
+// WithDelegatedRetries sets how many times a record is sent to an endpoint
+// before giving up, and the delay before the first retry, which doubles after
+// each attempt. Only network errors and 429 or 5xx responses are retried.
+func WithDelegatedRetries(attempts int, backoff time.Duration) DelegatedPublisherOption {
+	return func(o *delegatedPublisherOptions) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// NewDelegatedPublisher creates an [IPNSPublisher] which PUTs signed IPNS
+// records to the given endpoints implementing the Routing V1 HTTP IPNS API,
+// so that nodes without a DHT, such as browsers and edge nodes, can publish.
+//
+// Records are sent to every endpoint in parallel and each endpoint counts as
+// one confirmation for [PublishWithConfirmations]. The datastore is used to
+// keep track of the sequence numbers of the published records.
+func NewDelegatedPublisher(ds ds.Datastore, endpoints []string, opts ...DelegatedPublisherOption) (*IPNSPublisher, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no delegated publishing endpoint")
+	}
+
+	o := delegatedPublisherOptions{
+		attempts: DefaultDelegatedPublishAttempts,
+		backoff:  DefaultDelegatedPublishBackoff,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.attempts < 1 {
+		o.attempts = 1
+	}
+
+	routers := make([]routing.ValueStore, len(endpoints))
+	for i, endpoint := range endpoints {
+		c, err := client.New(endpoint, o.clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating client for %q: %w", endpoint, err)
+		}
+		routers[i] = &delegatedValueStore{
+			ValueStore: contentrouter.NewContentRoutingClient(c),
+			attempts:   o.attempts,
+			backoff:    o.backoff,
+		}
+	}
+
+	return NewIPNSPublisher(delegatedValueStores(routers), ds, WithIPNSPublisherRouters(routers...)), nil
+}
+
+// delegatedValueStore publishes IPNS records to a single Routing V1 HTTP
+// endpoint, retrying transient failures.
+type delegatedValueStore struct {
+	routing.ValueStore
+	attempts int
+	backoff  time.Duration
+}
+
+func (d *delegatedValueStore) PutValue(ctx context.Context, key string, data []byte, opts ...routing.Option) error {
+	if strings.HasPrefix(key, "/pk/") {
+		// Routing V1 cannot store public keys, which are embedded in IPNS
+		// records when they cannot be extracted from the name.
+		return nil
+	}
+
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		err := d.ValueStore.PutValue(ctx, key, data, opts...)
+		if err == nil || attempt >= d.attempts || !isRetriable(err) {
+			return err
+		}
+
+		log.Debugf("delegated publishing attempt %d failed, retrying in %s: %s", attempt, backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func isRetriable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var httpErr *client.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	// network errors
+	return true
+}
+
+// delegatedValueStores looks up the previously published records, used to
+// pick the next sequence number, in each endpoint in turn.
+type delegatedValueStores []routing.ValueStore
+
+func (d delegatedValueStores) PutValue(ctx context.Context, key string, data []byte, opts ...routing.Option) error {
+	var errs []error
+	for _, r := range d {
+		if err := r.PutValue(ctx, key, data, opts...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d delegatedValueStores) GetValue(ctx context.Context, key string, opts ...routing.Option) ([]byte, error) {
+	err := routing.ErrNotFound
+	for _, r := range d {
+		var value []byte
+		value, err = r.GetValue(ctx, key, opts...)
+		if err == nil {
+			return value, nil
+		}
+	}
+	return nil, err
+}
+
+func (d delegatedValueStores) SearchValue(ctx context.Context, key string, opts ...routing.Option) (<-chan []byte, error) {
+	err := routing.ErrNotFound
+	for _, r := range d {
+		var ch <-chan []byte
+		ch, err = r.SearchValue(ctx, key, opts...)
+		if err == nil {
+			return ch, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,108 @@
+package namesys
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type delegatedEndpoint struct {
+	mu       sync.Mutex
+	failures int // number of PUTs to answer with 503
+	status   int // status of the other PUTs
+	puts     int
+	records  map[string][]byte
+}
+
+func (e *delegatedEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	name := r.URL.Path[len("/routing/v1/ipns/"):]
+	switch r.Method {
+	case http.MethodGet:
+		rec, ok := e.records[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.ipfs.ipns-record")
+		_, _ = w.Write(rec)
+	case http.MethodPut:
+		e.puts++
+		if e.failures > 0 {
+			e.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if e.status != http.StatusOK {
+			w.WriteHeader(e.status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		e.records[name] = body
+	}
+}
+
+func TestDelegatedPublisher(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	sk, pk, err := ci.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	pid, err := peer.IDFromPublicKey(pk)
+	require.NoError(t, err)
+	name := ipns.NameFromPeer(pid)
+
+	value, err := path.NewPath("/ipfs/bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4")
+	require.NoError(t, err)
+
+	flaky := &delegatedEndpoint{failures: 1, status: http.StatusOK, records: map[string][]byte{}}
+	rejecting := &delegatedEndpoint{status: http.StatusBadRequest, records: map[string][]byte{}}
+	flakySrv := httptest.NewServer(flaky)
+	defer flakySrv.Close()
+	rejectingSrv := httptest.NewServer(rejecting)
+	defer rejectingSrv.Close()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	p, err := NewDelegatedPublisher(dstore, []string{flakySrv.URL, rejectingSrv.URL}, WithDelegatedRetries(3, time.Millisecond))
+	require.NoError(t, err)
+
+	// The rejecting endpoint is not retried and fails the publication
+	err = p.Publish(ctx, sk, value)
+	var perr *PublishError
+	require.ErrorAs(t, err, &perr)
+	require.Equal(t, 1, perr.Confirmed)
+	require.Equal(t, 1, rejecting.puts)
+
+	// The flaky endpoint got the record after a retry
+	require.Equal(t, 2, flaky.puts)
+	rec, err := ipns.UnmarshalRecord(flaky.records[name.String()])
+	require.NoError(t, err)
+	require.NoError(t, ipns.ValidateWithName(rec, name))
+	got, err := rec.Value()
+	require.NoError(t, err)
+	require.Equal(t, value.String(), got.String())
+
+	// One confirmation is enough
+	require.NoError(t, p.Publish(ctx, sk, value, PublishWithConfirmations(1)))
+}
+
+func TestDelegatedPublisherNoEndpoint(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewDelegatedPublisher(dssync.MutexWrap(ds.NewMapDatastore()), nil)
+	require.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ipfs/boxo/ipns"
@@ -18,7 +19,8 @@ var (
 	// ErrResolveFailed signals an error when attempting to resolve.
 	ErrResolveFailed = errors.New("could not resolve name")
 
-	// ErrResolveRecursion signals a recursion-depth limit.
+	// ErrResolveRecursion signals a recursion-depth limit. It is wrapped by
+	// [RecursionLimitError] and [CycleError].
 	ErrResolveRecursion = errors.New("could not resolve name (recursion limit exceeded)")
 
 	// ErrNoNamesys is an explicit error for when no [NameSystem] is provided.
@@ -29,8 +31,94 @@ var (
 
 	// ErrMissingDNSLinkRecord signals that the domain has no DNSLink TXT entries.
 	ErrMissingDNSLinkRecord = fmt.Errorf("%w: DNSLink lookup could not find a TXT record (https://docs.ipfs.tech/concepts/dnslink/)", ErrResolveFailed)
+
+	// ErrNotEnoughRouters signals that more confirmations were requested with
+	// [PublishWithConfirmations] than there are routing backends to publish to.
+	ErrNotEnoughRouters = errors.New("not enough routing backends for the requested confirmations")
 )
 
+// PublishError is returned by [Publisher.Publish] when fewer routing backends
+// than required by [PublishOptions.Confirmations] accepted the record.
+type PublishError struct {
+	// Required is the number of confirmations which were required.
+	Required int
+
+	// Confirmed is the number of routing backends which accepted the record.
+	Confirmed int
+
+	// Errors contains the error returned by each routing backend, in the order
+	// they were configured. It is nil for the backends which accepted the record.
+	Errors []error
+}
+
+func (e *PublishError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "record accepted by %d routing backends, %d required", e.Confirmed, e.Required)
+	for i, err := range e.Errors {
+		if err != nil {
+			fmt.Fprintf(&b, "; backend %d: %s", i, err)
+		}
+	}
+	return b.String()
+}
+
+func (e *PublishError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// RecursionLimitError is returned when resolving a name requires more than
+// [ResolveOptions.Depth] steps, for example when DNSLink records point to IPNS
+// names whose records point to other DNSLink names. It wraps
+// [ErrResolveRecursion].
+type RecursionLimitError struct {
+	// Limit is the depth limit which was exceeded.
+	Limit uint
+
+	// Names are the names resolved, in order, starting with the name which
+	// was being resolved and ending with the name which was not resolved
+	// anymore.
+	Names []path.Path
+}
+
+func (e *RecursionLimitError) Error() string {
+	return fmt.Sprintf("could not resolve name (recursion limit of %d exceeded): %s", e.Limit, formatNames(e.Names))
+}
+
+func (e *RecursionLimitError) Unwrap() error {
+	return ErrResolveRecursion
+}
+
+// CycleError is returned when a name resolves to itself, directly or through
+// other names, which would never resolve to an immutable path. It wraps
+// [ErrResolveRecursion].
+type CycleError struct {
+	// Names are the names resolved, in order, starting with the name which
+	// was being resolved and ending with the first name found twice.
+	Names []path.Path
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("could not resolve name (cycle detected): %s", formatNames(e.Names))
+}
+
+func (e *CycleError) Unwrap() error {
+	return ErrResolveRecursion
+}
+
+func formatNames(names []path.Path) string {
+	s := make([]string, len(names))
+	for i, n := range names {
+		s[i] = n.String()
+	}
+	return strings.Join(s, " -> ")
+}
+
 const (
 	// DefaultDepthLimit is the default depth limit used by [Resolver].
 	DefaultDepthLimit = 32
@@ -113,11 +201,17 @@ type Resolver interface {
 
 // ResolveOptions specifies options for resolving an IPNS Path.
 type ResolveOptions struct {
-	// Depth is the recursion depth limit.
+	// Depth is the recursion depth limit: the maximum number of names, such
+	// as DNSLink names pointing to IPNS names, resolved to reach an
+	// immutable path. Exceeding it fails with a [RecursionLimitError].
+	// Regardless of the depth, resolving a name which leads back to itself
+	// fails with a [CycleError].
 	Depth uint
 
 	// DhtRecordCount is the number of IPNS Records to retrieve from the routing system
-	// (the best record is selected from this set).
+	// (the best record is selected from this set). A zero value uses the
+	// quorum of the [IPNSResolver], [DefaultResolverDhtRecordCount] unless
+	// set with [WithRecordQuorum].
 	DhtRecordCount uint
 
 	// DhtTimeout is the amount of time to wait for records to be fetched and
@@ -130,9 +224,8 @@ type ResolveOptions struct {
 // DefaultResolveOptions returns the default options for resolving an IPNS Path.
 func DefaultResolveOptions() ResolveOptions {
 	return ResolveOptions{
-		Depth:          DefaultDepthLimit,
-		DhtRecordCount: DefaultResolverDhtRecordCount,
-		DhtTimeout:     DefaultResolverDhtTimeout,
+		Depth:      DefaultDepthLimit,
+		DhtTimeout: DefaultResolverDhtTimeout,
 	}
 }
 
@@ -187,6 +280,10 @@ type PublishOptions struct {
 	// creating a new record to publish. With this options, you can further customize
 	// the way IPNS Records are created.
 	IPNSOptions []ipns.Option
+
+	// Confirmations is the number of routing backends which must accept the
+	// record for the publication to succeed. If zero, all of them must accept it.
+	Confirmations int
 }
 
 // DefaultPublishOptions returns the default options for publishing an IPNS Record.
@@ -223,6 +320,14 @@ func PublishWithIPNSOption(option ipns.Option) PublishOption {
 	}
 }
 
+// PublishWithConfirmations sets [PublishOptions.Confirmations]. A [PublishError]
+// is returned if fewer than n routing backends accepted the record.
+func PublishWithConfirmations(n int) PublishOption {
+	return func(o *PublishOptions) {
+		o.Confirmations = n
+	}
+}
+
 // ProcessPublishOptions converts an array of [PublishOption] into a [PublishOptions] object.
 func ProcessPublishOptions(opts []PublishOption) PublishOptions {
 	publishOptions := DefaultPublishOptions()
@@ -3,6 +3,7 @@ package namesys
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -24,20 +25,41 @@ type IPNSPublisher struct {
 	routing routing.ValueStore
 	ds      ds.Datastore
 
+	// If set, records are published to each of these routers instead of
+	// routing, so that confirmations can be counted.
+	publishRouters []routing.ValueStore
+
 	// Used to ensure we assign IPNS records sequential sequence numbers.
 	mu sync.Mutex
 }
 
 var _ Publisher = &IPNSPublisher{}
 
+// IPNSPublisherOption is an option for [NewIPNSPublisher].
+type IPNSPublisherOption func(*IPNSPublisher)
+
+// WithIPNSPublisherRouters configures the routing backends, such as the DHT
+// and delegated publishers, to which records are published. Each of them
+// counts as one confirmation for [PublishWithConfirmations]. By default, the
+// [routing.ValueStore] given to [NewIPNSPublisher] is used as a single backend.
+func WithIPNSPublisherRouters(routers ...routing.ValueStore) IPNSPublisherOption {
+	return func(p *IPNSPublisher) {
+		p.publishRouters = routers
+	}
+}
+
 // NewIPNSResolver constructs a new [IPNSResolver] from a [routing.ValueStore] and
 // a [ds.Datastore].
-func NewIPNSPublisher(route routing.ValueStore, ds ds.Datastore) *IPNSPublisher {
+func NewIPNSPublisher(route routing.ValueStore, ds ds.Datastore, opts ...IPNSPublisherOption) *IPNSPublisher {
 	if ds == nil {
 		panic("nil datastore")
 	}
 
-	return &IPNSPublisher{routing: route, ds: ds}
+	p := &IPNSPublisher{routing: route, ds: ds}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *IPNSPublisher) Publish(ctx context.Context, priv crypto.PrivKey, value path.Path, options ...PublishOption) error {
@@ -46,12 +68,30 @@ func (p *IPNSPublisher) Publish(ctx context.Context, priv crypto.PrivKey, value
 	ctx, span := startSpan(ctx, "IPNSPublisher.Publish", trace.WithAttributes(attribute.String("Value", value.String())))
 	defer span.End()
 
+	routers := p.publishRouters
+	if len(routers) == 0 {
+		routers = []routing.ValueStore{p.routing}
+	}
+
+	opts := ProcessPublishOptions(options)
+	required := opts.Confirmations
+	if required <= 0 {
+		required = len(routers)
+	}
+	if required > len(routers) {
+		return fmt.Errorf("%w: %d confirmations required, %d backends configured", ErrNotEnoughRouters, required, len(routers))
+	}
+
 	record, err := p.updateRecord(ctx, priv, value, options...)
 	if err != nil {
 		return err
 	}
 
-	return PublishIPNSRecord(ctx, p.routing, priv.GetPublic(), record)
+	if len(p.publishRouters) == 0 {
+		return PublishIPNSRecord(ctx, p.routing, priv.GetPublic(), record)
+	}
+
+	return publishIPNSRecordWithConfirmations(ctx, routers, priv.GetPublic(), record, required)
 }
 
 // IpnsDsKey returns a datastore key given an IPNS identifier (peer
@@ -238,6 +278,39 @@ func PublishIPNSRecord(ctx context.Context, r routing.ValueStore, pubKey crypto.
 	return waitOnErrChan(ctx, errs)
 }
 
+// publishIPNSRecordWithConfirmations publishes the given [ipns.Record] to each
+// of the routers in parallel, and returns a [PublishError] if fewer than
+// required of them accepted it.
+func publishIPNSRecordWithConfirmations(ctx context.Context, routers []routing.ValueStore, pubKey crypto.PubKey, rec *ipns.Record, required int) error {
+	ctx, span := startSpan(ctx, "PublishIPNSRecordWithConfirmations", trace.WithAttributes(attribute.Int("Routers", len(routers)), attribute.Int("Required", required)))
+	defer span.End()
+
+	errs := make([]error, len(routers))
+	var wg sync.WaitGroup
+	for i, r := range routers {
+		wg.Add(1)
+		go func(i int, r routing.ValueStore) {
+			defer wg.Done()
+			errs[i] = PublishIPNSRecord(ctx, r, pubKey, rec)
+		}(i, r)
+	}
+	wg.Wait()
+
+	var confirmed int
+	for i, err := range errs {
+		if err == nil {
+			confirmed++
+		} else {
+			log.Debugf("routing backend %d did not accept the IPNS record: %s", i, err)
+		}
+	}
+
+	if confirmed < required {
+		return &PublishError{Required: required, Confirmed: confirmed, Errors: errs}
+	}
+	return nil
+}
+
 func waitOnErrChan(ctx context.Context, errs chan error) error {
 	select {
 	case err := <-errs:
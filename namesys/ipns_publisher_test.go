@@ -14,9 +14,11 @@ import (
 	mockrouting "github.com/ipfs/boxo/routing/mock"
 	ds "github.com/ipfs/go-datastore"
 	dssync "github.com/ipfs/go-datastore/sync"
+	routinghelpers "github.com/libp2p/go-libp2p-routing-helpers"
 	testutil "github.com/libp2p/go-libp2p-testing/net"
 	ci "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
 )
 
 func TestIPNSPublisher(t *testing.T) {
@@ -119,3 +121,57 @@ func (d *checkSyncDS) Sync(ctx context.Context, prefix ds.Key) error {
 	d.syncKeys[prefix] = struct{}{}
 	return d.Datastore.Sync(ctx, prefix)
 }
+
+func TestPublishWithConfirmations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	id := testutil.RandIdentityOrFatal(t)
+	value, err := path.NewPath("/ipfs/bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4")
+	require.NoError(t, err)
+
+	serv := mockrouting.NewServer()
+	r1 := serv.Client(testutil.RandIdentityOrFatal(t))
+	r2 := serv.Client(testutil.RandIdentityOrFatal(t))
+
+	newPublisher := func() *IPNSPublisher {
+		return NewIPNSPublisher(r1, dssync.MutexWrap(ds.NewMapDatastore()), WithIPNSPublisherRouters(r1, r2, routinghelpers.Null{}))
+	}
+
+	t.Run("Enough confirmations", func(t *testing.T) {
+		t.Parallel()
+
+		err := newPublisher().Publish(ctx, id.PrivateKey(), value, PublishWithConfirmations(2))
+		require.NoError(t, err)
+
+		_, err = r2.GetValue(ctx, string(ipns.NameFromPeer(id.ID()).RoutingKey()))
+		require.NoError(t, err)
+	})
+
+	t.Run("Not enough confirmations", func(t *testing.T) {
+		t.Parallel()
+
+		for _, opts := range [][]PublishOption{
+			{PublishWithConfirmations(3)},
+			{}, // all routers are required by default
+		} {
+			err := newPublisher().Publish(ctx, id.PrivateKey(), value, opts...)
+
+			var publishErr *PublishError
+			require.ErrorAs(t, err, &publishErr)
+			require.Equal(t, 3, publishErr.Required)
+			require.Equal(t, 2, publishErr.Confirmed)
+			require.Len(t, publishErr.Errors, 3)
+			require.NoError(t, publishErr.Errors[0])
+			require.NoError(t, publishErr.Errors[1])
+			require.ErrorIs(t, err, routing.ErrNotSupported)
+		}
+	})
+
+	t.Run("More confirmations than routers", func(t *testing.T) {
+		t.Parallel()
+
+		err := newPublisher().Publish(ctx, id.PrivateKey(), value, PublishWithConfirmations(4))
+		require.ErrorIs(t, err, ErrNotEnoughRouters)
+	})
+}
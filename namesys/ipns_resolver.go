@@ -20,21 +20,76 @@ import (
 //  1. Provisory TTL is chosen: record TTL if it exists, otherwise `ipns.DefaultRecordTTL`.
 //  2. If provisory TTL expires before EOL, then returned TTL is duration between EOL and now.
 //  3. If record is expired, 0 is returned as TTL.
+//
+// Records are fetched from the routing system in parallel. Every record received
+// is compared to the best one so far with the [RecordComparator], and a new
+// result is only emitted when a better record is found.
 type IPNSResolver struct {
-	routing routing.ValueStore
+	routing  routing.ValueStore
+	quorum   uint
+	compare  RecordComparator
+	observer RecordObserver
 }
 
 var _ Resolver = &IPNSResolver{}
 
+// RecordComparator compares two IPNS Records. It must return a positive number
+// if a is better than b, a negative number if b is better than a, and zero if
+// they cannot be ordered. The default is [ipns.Compare], which prefers the
+// record with the highest sequence number and latest validity.
+type RecordComparator func(a, b *ipns.Record) (int, error)
+
+// RecordObserver is called once per resolution of name with all the candidate
+// records received from the routing system, in the order they were received,
+// and the record that was selected. It can be used to audit the selection.
+// The routing system may already filter out records it considers worse, for
+// example the DHT only returns successively better records.
+type RecordObserver func(name ipns.Name, candidates []*ipns.Record, best *ipns.Record)
+
+// IPNSResolverOption is an option for [NewIPNSResolver].
+type IPNSResolverOption func(*IPNSResolver)
+
+// WithRecordQuorum sets the number of IPNS Records to fetch from the routing
+// system before the best one is final. It is used unless a non-zero number is
+// set per resolution with [ResolveWithDhtRecordCount]. Defaults to
+// [DefaultResolverDhtRecordCount].
+func WithRecordQuorum(n uint) IPNSResolverOption {
+	return func(r *IPNSResolver) {
+		r.quorum = n
+	}
+}
+
+// WithRecordComparator sets the [RecordComparator] used to select the best
+// record among the candidates. Defaults to [ipns.Compare].
+func WithRecordComparator(compare RecordComparator) IPNSResolverOption {
+	return func(r *IPNSResolver) {
+		r.compare = compare
+	}
+}
+
+// WithRecordObserver sets a [RecordObserver] that is given every candidate
+// record and the selected record.
+func WithRecordObserver(observer RecordObserver) IPNSResolverOption {
+	return func(r *IPNSResolver) {
+		r.observer = observer
+	}
+}
+
 // NewIPNSResolver constructs a new [IPNSResolver] from a [routing.ValueStore].
-func NewIPNSResolver(route routing.ValueStore) *IPNSResolver {
+func NewIPNSResolver(route routing.ValueStore, opts ...IPNSResolverOption) *IPNSResolver {
 	if route == nil {
 		panic("attempt to create resolver with nil routing system")
 	}
 
-	return &IPNSResolver{
+	r := &IPNSResolver{
 		routing: route,
+		quorum:  DefaultResolverDhtRecordCount,
+		compare: ipns.Compare,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 func (r *IPNSResolver) Resolve(ctx context.Context, p path.Path, options ...ResolveOption) (Result, error) {
@@ -76,7 +131,12 @@ func (r *IPNSResolver) resolveOnceAsync(ctx context.Context, p path.Path, option
 		return out
 	}
 
-	vals, err := r.routing.SearchValue(ctx, string(name.RoutingKey()), dht.Quorum(int(options.DhtRecordCount)))
+	quorum := options.DhtRecordCount
+	if quorum == 0 {
+		quorum = r.quorum
+	}
+
+	vals, err := r.routing.SearchValue(ctx, string(name.RoutingKey()), dht.Quorum(int(quorum)))
 	if err != nil {
 		out <- AsyncResult{Err: err}
 		close(out)
@@ -90,6 +150,16 @@ func (r *IPNSResolver) resolveOnceAsync(ctx context.Context, p path.Path, option
 		ctx, span := startSpan(ctx, "IPNSResolver.ResolveOnceAsync.Worker")
 		defer span.End()
 
+		var candidates []*ipns.Record
+		var best *ipns.Record
+		if r.observer != nil {
+			defer func() {
+				if len(candidates) != 0 {
+					r.observer(name, candidates, best)
+				}
+			}()
+		}
+
 		for {
 			select {
 			case val, ok := <-vals:
@@ -102,6 +172,19 @@ func (r *IPNSResolver) resolveOnceAsync(ctx context.Context, p path.Path, option
 					emitOnceResult(ctx, out, AsyncResult{Err: err})
 					return
 				}
+				candidates = append(candidates, rec)
+
+				if best != nil {
+					cmp, err := r.compare(rec, best)
+					if err != nil {
+						emitOnceResult(ctx, out, AsyncResult{Err: err})
+						return
+					}
+					if cmp <= 0 {
+						continue
+					}
+				}
+				best = rec
 
 				resolvedBase, err := rec.Value()
 				if err != nil {
@@ -27,6 +27,22 @@ func (v noFailValidator) Select(key string, values [][]byte) (int, error) {
 	return 0, nil
 }
 
+// searchValueStore is a [routing.ValueStore] whose SearchValue returns the
+// given values in order.
+type searchValueStore struct {
+	routing.ValueStore
+	vals [][]byte
+}
+
+func (s searchValueStore) SearchValue(ctx context.Context, key string, opts ...routing.Option) (<-chan []byte, error) {
+	out := make(chan []byte, len(s.vals))
+	for _, v := range s.vals {
+		out <- v
+	}
+	close(out)
+	return out, nil
+}
+
 func TestResolver(t *testing.T) {
 	t.Parallel()
 
@@ -128,4 +144,47 @@ func TestResolver(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, pathDog, res.Path)
 	})
+
+	t.Run("Resolve uses record comparator and observer", func(t *testing.T) {
+		t.Parallel()
+
+		id := tnet.RandIdentityOrFatal(t)
+		name := ipns.NameFromPeer(id.ID())
+		eol := time.Now().Add(time.Hour)
+
+		var vals [][]byte
+		for i, p := range []path.Path{pathCat, pathDog} {
+			rec, err := ipns.NewRecord(id.PrivateKey(), p, uint64(i+1), eol, 0)
+			require.NoError(t, err)
+			raw, err := ipns.MarshalRecord(rec)
+			require.NoError(t, err)
+			vals = append(vals, raw)
+		}
+
+		var (
+			observedName       ipns.Name
+			observedCandidates []*ipns.Record
+			observedBest       *ipns.Record
+		)
+		resolver := NewIPNSResolver(searchValueStore{vals: vals},
+			// Prefer the lowest sequence number.
+			WithRecordComparator(func(a, b *ipns.Record) (int, error) {
+				cmp, err := ipns.Compare(a, b)
+				return -cmp, err
+			}),
+			WithRecordObserver(func(name ipns.Name, candidates []*ipns.Record, best *ipns.Record) {
+				observedName, observedCandidates, observedBest = name, candidates, best
+			}),
+		)
+
+		res, err := resolver.Resolve(context.Background(), name.AsPath())
+		require.NoError(t, err)
+		require.Equal(t, pathCat, res.Path)
+
+		require.True(t, observedName.Equal(name))
+		require.Len(t, observedCandidates, 2)
+		seq, err := observedBest.Sequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), seq)
+	})
 }
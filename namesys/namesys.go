@@ -19,7 +19,6 @@ import (
 	"strings"
 	"time"
 
-	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/ipfs/boxo/ipns"
 	"github.com/ipfs/boxo/path"
 	"github.com/ipfs/go-cid"
@@ -47,9 +46,11 @@ type namesys struct {
 
 	dnsResolver, ipnsResolver resolver
 	ipnsPublisher             Publisher
+	publishRouters            []routing.ValueStore
+	ipnsResolverOpts          []IPNSResolverOption
 
-	staticMap   map[string]*cacheEntry
-	cache       *lru.Cache[string, cacheEntry]
+	staticMap   map[string]CacheEntry
+	cache       Cache
 	maxCacheTTL *time.Duration
 }
 
@@ -60,11 +61,7 @@ type Option func(*namesys) error
 // WithCache is an option that instructs the name system to use a (LRU) cache of the given size.
 func WithCache(size int) Option {
 	return func(ns *namesys) error {
-		if size <= 0 {
-			return fmt.Errorf("invalid cache size %d; must be > 0", size)
-		}
-
-		cache, err := lru.New[string, cacheEntry](size)
+		cache, err := NewLRUCache(size)
 		if err != nil {
 			return err
 		}
@@ -74,6 +71,16 @@ func WithCache(size int) Option {
 	}
 }
 
+// WithCustomCache is an option that instructs the name system to use the
+// given [Cache], for example one shared with other name systems. It replaces
+// the cache set by [WithCache].
+func WithCustomCache(cache Cache) Option {
+	return func(ns *namesys) error {
+		ns.cache = cache
+		return nil
+	}
+}
+
 // WithMaxCacheTTL configures the maximum cache TTL. By default, if the cache is
 // enabled, the entry TTL will be used for caching. By setting this option, you
 // can limit how long that TTL is.
@@ -106,16 +113,35 @@ func WithDatastore(ds ds.Datastore) Option {
 	}
 }
 
+// WithPublishRouters is an option that configures the routing backends, such
+// as the DHT and delegated publishers, to which IPNS Records are published. See
+// [WithIPNSPublisherRouters] for details.
+func WithPublishRouters(routers ...routing.ValueStore) Option {
+	return func(ns *namesys) error {
+		ns.publishRouters = routers
+		return nil
+	}
+}
+
+// WithIPNSResolverOptions sets the options of the [IPNSResolver] used by the
+// name system, such as [WithRecordQuorum] or [WithRecordObserver].
+func WithIPNSResolverOptions(opts ...IPNSResolverOption) Option {
+	return func(ns *namesys) error {
+		ns.ipnsResolverOpts = append(ns.ipnsResolverOpts, opts...)
+		return nil
+	}
+}
+
 // NewNameSystem constructs an IPFS [NameSystem] based on the given [routing.ValueStore].
 func NewNameSystem(r routing.ValueStore, opts ...Option) (NameSystem, error) {
-	var staticMap map[string]*cacheEntry
+	var staticMap map[string]CacheEntry
 
 	// Prewarm namesys cache with static records for deterministic tests and debugging.
 	// Useful for testing things like DNSLink without real DNS lookup.
 	// Example:
 	// IPFS_NS_MAP="dnslink-test.example.com:/ipfs/bafkreicysg23kiwv34eg2d7qweipxwosdo2py4ldv42nbauguluen5v6am"
 	if list := os.Getenv("IPFS_NS_MAP"); list != "" {
-		staticMap = make(map[string]*cacheEntry)
+		staticMap = make(map[string]CacheEntry)
 		for _, pair := range strings.Split(list, ",") {
 			mapping := strings.SplitN(pair, ":", 2)
 			key := mapping[0]
@@ -123,7 +149,7 @@ func NewNameSystem(r routing.ValueStore, opts ...Option) (NameSystem, error) {
 			if err != nil {
 				return nil, err
 			}
-			staticMap[ipns.NamespacePrefix+key] = &cacheEntry{val: value, ttl: 0}
+			staticMap[ipns.NamespacePrefix+key] = CacheEntry{Path: value}
 		}
 	}
 
@@ -146,8 +172,8 @@ func NewNameSystem(r routing.ValueStore, opts ...Option) (NameSystem, error) {
 		ns.dnsResolver = NewDNSResolver(madns.DefaultResolver.LookupTXT)
 	}
 
-	ns.ipnsResolver = NewIPNSResolver(r)
-	ns.ipnsPublisher = NewIPNSPublisher(r, ns.ds)
+	ns.ipnsResolver = NewIPNSResolver(r, ns.ipnsResolverOpts...)
+	ns.ipnsPublisher = NewIPNSPublisher(r, ns.ds, WithIPNSPublisherRouters(ns.publishRouters...))
 
 	return ns, nil
 }
@@ -187,7 +213,7 @@ func (ns *namesys) resolveOnceAsync(ctx context.Context, p path.Path, options Re
 		return out
 	}
 
-	if resolvedBase, ttl, lastMod, ok := ns.cacheGet(resolvablePath.String()); ok {
+	if resolvedBase, ttl, lastMod, ok := ns.cacheGet(ctx, resolvablePath.String()); ok {
 		p, err = joinPaths(resolvedBase, p)
 		span.SetAttributes(attribute.Bool("CacheHit", true))
 		span.RecordError(err)
@@ -233,7 +259,7 @@ func (ns *namesys) resolveOnceAsync(ctx context.Context, p path.Path, options Re
 			case res, ok := <-resCh:
 				if !ok {
 					if best != (AsyncResult{}) {
-						ns.cacheSet(resolvablePath.String(), best.Path, best.TTL, best.LastMod)
+						ns.cacheSet(ctx, resolvablePath.String(), best.Path, best.TTL, best.LastMod)
 					}
 					return
 				}
@@ -284,13 +310,13 @@ func (ns *namesys) Publish(ctx context.Context, name ci.PrivKey, value path.Path
 	}
 
 	ipnsName := ipns.NameFromPeer(pid)
-	cacheKey := ipnsName.String()
+	cacheKey := ipnsName.AsPath().String()
 
 	span.SetAttributes(attribute.String("ID", pid.String()))
 	if err := ns.ipnsPublisher.Publish(ctx, name, value, options...); err != nil {
 		// Invalidate the cache. Publishing may _partially_ succeed but
 		// still return an error.
-		ns.cacheInvalidate(cacheKey)
+		ns.cacheInvalidate(ctx, cacheKey)
 		span.RecordError(err)
 		return err
 	}
@@ -302,7 +328,7 @@ func (ns *namesys) Publish(ctx context.Context, name ci.PrivKey, value path.Path
 	if ttEOL := time.Until(publishOpts.EOL); ttEOL < ttl {
 		ttl = ttEOL
 	}
-	ns.cacheSet(cacheKey, value, ttl, time.Now())
+	ns.cacheSet(ctx, cacheKey, value, ttl, time.Now())
 	return nil
 }
 
@@ -1,24 +1,83 @@
 package namesys
 
 import (
+	"context"
+	"fmt"
 	"time"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/ipfs/boxo/path"
 )
 
-type cacheEntry struct {
-	val      path.Path     // is the value of this entry
-	ttl      time.Duration // is the ttl of this entry
-	lastMod  time.Time     // is the last time this entry was modified
-	cacheEOL time.Time     // is until when we keep this entry in cache
+// CacheEntry is a name resolution result stored in a [Cache].
+type CacheEntry struct {
+	// Path is the path the name resolves to.
+	Path path.Path
+	// TTL is the TTL of the record the name was resolved from.
+	TTL time.Duration
+	// LastMod is the first time the name was seen resolving to Path.
+	LastMod time.Time
+	// Expires is the time until which the entry is used to answer
+	// resolutions, the TTL capped by [WithMaxCacheTTL].
+	Expires time.Time
 }
 
-func (ns *namesys) cacheGet(name string) (path.Path, time.Duration, time.Time, bool) {
+// Cache stores the results of name resolutions and publications of a
+// [NameSystem]. Implementations must be safe for concurrent use. A single
+// Cache can be shared by several name systems, for example by a fleet of
+// gateways storing the resolutions in a Redis or memcached backend.
+//
+// Errors of remote backends are not reported to the name system: they are
+// expected to be logged, and failed lookups treated as misses.
+type Cache interface {
+	// Get returns the entry of the name. The name system ignores entries
+	// that expired, but uses them to keep the LastMod of names that resolve
+	// to the same path again.
+	Get(ctx context.Context, name string) (CacheEntry, bool)
+	// Put stores the entry of the name. The ttl is the time until the entry
+	// expires, after which it can be dropped.
+	Put(ctx context.Context, name string, entry CacheEntry, ttl time.Duration)
+	// Delete removes the entry of the name.
+	Delete(ctx context.Context, name string)
+}
+
+type lruCache struct {
+	lru *lru.Cache[string, CacheEntry]
+}
+
+// NewLRUCache returns an in-memory [Cache] keeping up to size entries. Expired
+// entries are kept until they are evicted.
+func NewLRUCache(size int) (Cache, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid cache size %d; must be > 0", size)
+	}
+
+	c, err := lru.New[string, CacheEntry](size)
+	if err != nil {
+		return nil, err
+	}
+	return &lruCache{lru: c}, nil
+}
+
+func (c *lruCache) Get(_ context.Context, name string) (CacheEntry, bool) {
+	return c.lru.Get(name)
+}
+
+func (c *lruCache) Put(_ context.Context, name string, entry CacheEntry, _ time.Duration) {
+	// Add automatically evicts previous entry, so it works for updating.
+	c.lru.Add(name, entry)
+}
+
+func (c *lruCache) Delete(_ context.Context, name string) {
+	c.lru.Remove(name)
+}
+
+func (ns *namesys) cacheGet(ctx context.Context, name string) (path.Path, time.Duration, time.Time, bool) {
 	// existence of optional mapping defined via IPFS_NS_MAP is checked first
 	if ns.staticMap != nil {
 		entry, ok := ns.staticMap[name]
 		if ok {
-			return entry.val, entry.ttl, entry.lastMod, true
+			return entry.Path, entry.TTL, entry.LastMod, true
 		}
 	}
 
@@ -26,13 +85,13 @@ func (ns *namesys) cacheGet(name string) (path.Path, time.Duration, time.Time, b
 		return nil, 0, time.Now(), false
 	}
 
-	entry, ok := ns.cache.Get(name)
+	entry, ok := ns.cache.Get(ctx, name)
 	if !ok {
 		return nil, 0, time.Now(), false
 	}
 
-	if time.Now().Before(entry.cacheEOL) {
-		return entry.val, entry.ttl, entry.lastMod, true
+	if time.Now().Before(entry.Expires) {
+		return entry.Path, entry.TTL, entry.LastMod, true
 	}
 
 	// We do not delete the entry from the cache. Removals are handled by the
@@ -41,7 +100,7 @@ func (ns *namesys) cacheGet(name string) (path.Path, time.Duration, time.Time, b
 	return nil, 0, time.Now(), false
 }
 
-func (ns *namesys) cacheSet(name string, val path.Path, ttl time.Duration, lastMod time.Time) {
+func (ns *namesys) cacheSet(ctx context.Context, name string, val path.Path, ttl time.Duration, lastMod time.Time) {
 	if ns.cache == nil || ttl <= 0 {
 		return
 	}
@@ -53,10 +112,10 @@ func (ns *namesys) cacheSet(name string, val path.Path, ttl time.Duration, lastM
 
 	// If there's an already cached version with the same path, but
 	// different lastMod date, keep the oldest.
-	entry, ok := ns.cache.Get(name)
-	if ok && entry.val.String() == val.String() {
-		if lastMod.After(entry.lastMod) {
-			lastMod = entry.lastMod
+	entry, ok := ns.cache.Get(ctx, name)
+	if ok && entry.Path.String() == val.String() {
+		if lastMod.After(entry.LastMod) {
+			lastMod = entry.LastMod
 		}
 	}
 
@@ -66,21 +125,19 @@ func (ns *namesys) cacheSet(name string, val path.Path, ttl time.Duration, lastM
 	if ns.maxCacheTTL != nil && cacheTTL > *ns.maxCacheTTL {
 		cacheTTL = *ns.maxCacheTTL
 	}
-	cacheEOL := time.Now().Add(cacheTTL)
 
-	// Add automatically evicts previous entry, so it works for updating.
-	ns.cache.Add(name, cacheEntry{
-		val:      val,
-		ttl:      ttl,
-		lastMod:  lastMod,
-		cacheEOL: cacheEOL,
-	})
+	ns.cache.Put(ctx, name, CacheEntry{
+		Path:    val,
+		TTL:     ttl,
+		LastMod: lastMod,
+		Expires: time.Now().Add(cacheTTL),
+	}, cacheTTL)
 }
 
-func (ns *namesys) cacheInvalidate(name string) {
+func (ns *namesys) cacheInvalidate(ctx context.Context, name string) {
 	if ns.cache == nil {
 		return
 	}
 
-	ns.cache.Remove(name)
+	ns.cache.Delete(ctx, name)
 }
@@ -2,6 +2,7 @@ package namesys
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	record "github.com/libp2p/go-libp2p-record"
 	ci "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,6 +100,46 @@ func TestNamesysResolution(t *testing.T) {
 	}
 }
 
+func TestNamesysResolutionErrors(t *testing.T) {
+	t.Parallel()
+
+	const (
+		name   = "/ipns/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy"
+		domain = "/ipns/example.com"
+	)
+	r := &namesys{
+		ipnsResolver: &mockResolver{entries: map[string]string{name: domain + "/sub"}},
+		dnsResolver:  &mockResolver{entries: map[string]string{domain: name}},
+	}
+	p, err := path.NewPath(domain)
+	require.NoError(t, err)
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := r.Resolve(context.Background(), p)
+		require.ErrorIs(t, err, ErrResolveRecursion)
+
+		var cycleErr *CycleError
+		require.ErrorAs(t, err, &cycleErr)
+		names := make([]string, len(cycleErr.Names))
+		for i, n := range cycleErr.Names {
+			names[i] = n.String()
+		}
+		require.Equal(t, []string{domain, name, domain}, names)
+		require.ErrorContains(t, err, domain+" -> "+name+" -> "+domain)
+	})
+
+	t.Run("recursion limit", func(t *testing.T) {
+		_, err := r.Resolve(context.Background(), p, ResolveWithDepth(1))
+		require.ErrorIs(t, err, ErrResolveRecursion)
+
+		var limitErr *RecursionLimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, uint(1), limitErr.Limit)
+		require.Len(t, limitErr.Names, 2)
+		require.Equal(t, name, limitErr.Names[1].String())
+	})
+}
+
 func TestResolveIPNS(t *testing.T) {
 	ns := &namesys{
 		ipnsResolver: mockResolverOne(),
@@ -160,10 +202,10 @@ func TestPublishWithTTL(t *testing.T) {
 		err = ns.Publish(context.Background(), priv, p, PublishWithEOL(eol), PublishWithTTL(ttl))
 		require.NoError(t, err)
 
-		entry, ok := ns.(*namesys).cache.Get(ipns.NameFromPeer(pid).String())
+		entry, ok := ns.(*namesys).cache.Get(context.Background(), ipns.NameFromPeer(pid).AsPath().String())
 		require.True(t, ok)
-		require.Equal(t, ttl, entry.ttl)
-		require.LessOrEqual(t, time.Until(entry.cacheEOL), ttl)
+		require.Equal(t, ttl, entry.TTL)
+		require.LessOrEqual(t, time.Until(entry.Expires), ttl)
 	})
 
 	t.Run("With MaxCacheTTL", func(t *testing.T) {
@@ -175,9 +217,81 @@ func TestPublishWithTTL(t *testing.T) {
 		err = ns.Publish(context.Background(), priv, p, PublishWithEOL(eol), PublishWithTTL(ttl))
 		require.NoError(t, err)
 
-		entry, ok := ns.(*namesys).cache.Get(ipns.NameFromPeer(pid).String())
+		entry, ok := ns.(*namesys).cache.Get(context.Background(), ipns.NameFromPeer(pid).AsPath().String())
 		require.True(t, ok)
-		require.Equal(t, ttl, entry.ttl)
-		require.LessOrEqual(t, time.Until(entry.cacheEOL), cacheTTL)
+		require.Equal(t, ttl, entry.TTL)
+		require.LessOrEqual(t, time.Until(entry.Expires), cacheTTL)
 	})
 }
+
+type mapCache struct {
+	sync.Mutex
+	entries map[string]CacheEntry
+	ttls    map[string]time.Duration
+}
+
+func (c *mapCache) Get(_ context.Context, name string) (CacheEntry, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.entries[name]
+	return e, ok
+}
+
+func (c *mapCache) Put(_ context.Context, name string, entry CacheEntry, ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.entries[name] = entry
+	c.ttls[name] = ttl
+}
+
+func (c *mapCache) Delete(_ context.Context, name string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.entries, name)
+}
+
+func TestSharedCache(t *testing.T) {
+	priv, _, err := ci.GenerateKeyPair(ci.Ed25519, 0)
+	require.NoError(t, err)
+	pid, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+	name := ipns.NameFromPeer(pid)
+	key := name.AsPath().String()
+
+	newRouting := func() (ds.Datastore, routing.ValueStore) {
+		dst := dssync.MutexWrap(ds.NewMapDatastore())
+		return dst, offroute.NewOfflineRouter(dst, record.NamespacedValidator{
+			"ipns": ipns.Validator{},
+			"pk":   record.PublicKeyValidator{},
+		})
+	}
+
+	cache := &mapCache{entries: make(map[string]CacheEntry), ttls: make(map[string]time.Duration)}
+
+	dst, r := newRouting()
+	publisher, err := NewNameSystem(r, WithDatastore(dst), WithCustomCache(cache), WithMaxCacheTTL(time.Minute))
+	require.NoError(t, err)
+
+	// CID is arbitrary.
+	p, err := path.NewPath("/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), priv, p, PublishWithTTL(time.Hour)))
+	require.Equal(t, time.Minute, cache.ttls[key])
+
+	// a name system that cannot find the record resolves the name from the
+	// shared cache
+	_, r = newRouting()
+	resolver, err := NewNameSystem(r, WithCustomCache(cache))
+	require.NoError(t, err)
+	res, err := resolver.Resolve(context.Background(), name.AsPath())
+	require.NoError(t, err)
+	require.Equal(t, p.String(), res.Path.String())
+	require.Equal(t, time.Hour, res.TTL)
+
+	// expired entries are not used
+	entry := cache.entries[key]
+	entry.Expires = time.Now().Add(-time.Second)
+	cache.entries[key] = entry
+	_, err = resolver.Resolve(context.Background(), name.AsPath())
+	require.Error(t, err)
+}
@@ -5,6 +5,9 @@ package republisher
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/ipfs/boxo/keystore"
@@ -16,10 +19,12 @@ import (
 	"github.com/ipfs/boxo/ipns"
 	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
+	metrics "github.com/ipfs/go-metrics-interface"
 	"github.com/jbenet/goprocess"
 	gpctx "github.com/jbenet/goprocess/context"
 	ic "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -41,6 +46,64 @@ const (
 	DefaultRecordLifetime = ipns.DefaultRecordLifetime
 )
 
+// Option is an option for [NewRepublisher].
+type Option func(*Republisher)
+
+// WithInterval sets the interval at which records are republished. It
+// defaults to [DefaultRebroadcastInterval].
+func WithInterval(interval time.Duration) Option {
+	return func(rp *Republisher) {
+		rp.Interval = interval
+	}
+}
+
+// WithInitialDelay sets the delay before the first records are republished.
+// It defaults to [InitialRebroadcastDelay].
+func WithInitialDelay(delay time.Duration) Option {
+	return func(rp *Republisher) {
+		rp.initialDelay = delay
+	}
+}
+
+// WithNameInterval sets a function returning the republish interval of each
+// name. The default interval is used when it returns 0.
+func WithNameInterval(f func(ipns.Name) time.Duration) Option {
+	return func(rp *Republisher) {
+		rp.nameInterval = f
+	}
+}
+
+// WithJitter delays every republish by a random duration up to jitter, so that
+// names do not all get republished at the same time.
+func WithJitter(jitter time.Duration) Option {
+	return func(rp *Republisher) {
+		rp.jitter = jitter
+	}
+}
+
+// WithRecordLifetime sets how long republished records are valid for. It
+// defaults to [DefaultRecordLifetime].
+func WithRecordLifetime(lifetime time.Duration) Option {
+	return func(rp *Republisher) {
+		rp.RecordLifetime = lifetime
+	}
+}
+
+// WithSkip sets a function deciding which names are not republished.
+func WithSkip(skip func(ipns.Name) bool) Option {
+	return func(rp *Republisher) {
+		rp.skip = skip
+	}
+}
+
+// WithConcurrency sets how many names are republished at the same time. It
+// defaults to 1.
+func WithConcurrency(n int) Option {
+	return func(rp *Republisher) {
+		rp.concurrency = n
+	}
+}
+
 // Republisher facilitates the regular publishing of all the IPNS records
 // associated to keys in a [keystore.Keystore].
 type Republisher struct {
@@ -53,47 +116,86 @@ type Republisher struct {
 
 	// how long records that are republished should be valid for
 	RecordLifetime time.Duration
+
+	initialDelay time.Duration
+	nameInterval func(ipns.Name) time.Duration
+	jitter       time.Duration
+	skip         func(ipns.Name) bool
+	concurrency  int
+
+	// next republish of each name
+	lk   sync.Mutex
+	next map[ipns.Name]time.Time
+
+	republished metrics.Counter
+	failures    metrics.Counter
+	skipped     metrics.Counter
 }
 
-// NewRepublisher creates a new [Republisher] from the given options.
-func NewRepublisher(ns namesys.Publisher, ds ds.Datastore, self ic.PrivKey, ks keystore.Keystore) *Republisher {
-	return &Republisher{
+// NewRepublisher creates a new [Republisher] from the given options. The
+// records of self, if not nil, and of all the keys of ks, if not nil, are
+// republished.
+func NewRepublisher(ns namesys.Publisher, ds ds.Datastore, self ic.PrivKey, ks keystore.Keystore, opts ...Option) *Republisher {
+	ctx := context.Background()
+	rp := &Republisher{
 		ns:             ns,
 		ds:             ds,
 		self:           self,
 		ks:             ks,
 		Interval:       DefaultRebroadcastInterval,
 		RecordLifetime: DefaultRecordLifetime,
+		initialDelay:   InitialRebroadcastDelay,
+		concurrency:    1,
+		next:           make(map[ipns.Name]time.Time),
+		republished:    metrics.NewCtx(ctx, "boxo_namesys.republished_total", "Number of IPNS records republished").Counter(),
+		failures:       metrics.NewCtx(ctx, "boxo_namesys.republish_failures_total", "Number of IPNS records which failed to be republished").Counter(),
+		skipped:        metrics.NewCtx(ctx, "boxo_namesys.republish_skipped_total", "Number of IPNS records not republished because of the skip function").Counter(),
+	}
+	for _, opt := range opts {
+		opt(rp)
 	}
+	return rp
 }
 
 // Run starts the republisher facility. It can be stopped by stopping the provided proc.
 func (rp *Republisher) Run(proc goprocess.Process) {
-	timer := time.NewTimer(InitialRebroadcastDelay)
+	rp.RunContext(gpctx.OnClosingContext(proc))
+}
+
+// RunContext republishes the records until the context is cancelled. The
+// first records are republished after the initial delay, or after the
+// interval if it is shorter.
+func (rp *Republisher) RunContext(ctx context.Context) {
+	timer := time.NewTimer(min(rp.initialDelay, rp.Interval))
 	defer timer.Stop()
-	if rp.Interval < InitialRebroadcastDelay {
-		timer.Reset(rp.Interval)
-	}
 
 	for {
 		select {
 		case <-timer.C:
-			timer.Reset(rp.Interval)
-			err := rp.republishEntries(proc)
+			next, err := rp.republishEntries(ctx, false)
 			if err != nil {
 				log.Info("republisher failed to republish: ", err)
-				if FailureRetryInterval < rp.Interval {
-					timer.Reset(FailureRetryInterval)
-				}
 			}
-		case <-proc.Closing():
+			// look for new keys at least once per interval
+			wait := min(time.Until(next), rp.Interval)
+			timer.Reset(max(wait, 0))
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (rp *Republisher) republishEntries(p goprocess.Process) error {
-	ctx, cancel := context.WithCancel(gpctx.OnClosingContext(p))
+// Republish republishes the records of all the names now, except the skipped
+// ones, regardless of when they are due.
+func (rp *Republisher) Republish(ctx context.Context) error {
+	_, err := rp.republishEntries(ctx, true)
+	return err
+}
+
+// republishEntries republishes the names which are due, or all of them if
+// force is true. It returns when the next name is due.
+func (rp *Republisher) republishEntries(ctx context.Context, force bool) (time.Time, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	ctx, span := startSpan(ctx, "Republisher.RepublishEntries")
 	defer span.End()
@@ -102,30 +204,117 @@ func (rp *Republisher) republishEntries(p goprocess.Process) error {
 	// because:
 	// 1. There's no way to get keys from the keystore by ID.
 	// 2. We don't actually have access to the IPNS publisher.
-	err := rp.republishEntry(ctx, rp.self)
-	if err != nil {
-		return err
+	var keys []ic.PrivKey
+	if rp.self != nil {
+		keys = append(keys, rp.self)
 	}
-
 	if rp.ks != nil {
 		keyNames, err := rp.ks.List()
 		if err != nil {
-			return err
+			return time.Now().Add(FailureRetryInterval), err
 		}
 		for _, name := range keyNames {
 			priv, err := rp.ks.Get(name)
 			if err != nil {
-				return err
+				return time.Now().Add(FailureRetryInterval), err
 			}
-			err = rp.republishEntry(ctx, priv)
-			if err != nil {
-				return err
+			keys = append(keys, priv)
+		}
+	}
+
+	now := time.Now()
+	nextDue := now.Add(rp.Interval)
+	var (
+		errLk sync.Mutex
+		errs  []error
+	)
+	seen := make(map[ipns.Name]struct{}, len(keys))
+	g := new(errgroup.Group)
+	g.SetLimit(max(rp.concurrency, 1))
+	for _, priv := range keys {
+		id, err := peer.IDFromPrivateKey(priv)
+		if err != nil {
+			errLk.Lock()
+			errs = append(errs, err)
+			errLk.Unlock()
+			continue
+		}
+		name := ipns.NameFromPeer(id)
+		seen[name] = struct{}{}
+
+		rp.lk.Lock()
+		due := rp.next[name]
+		rp.lk.Unlock()
+		if !force && due.After(now) {
+			nextDue = minTime(nextDue, due)
+			continue
+		}
+
+		if rp.skip != nil && rp.skip(name) {
+			rp.skipped.Inc()
+			next := now.Add(rp.intervalFor(name))
+			rp.setNext(name, next)
+			nextDue = minTime(nextDue, next)
+			continue
+		}
+
+		priv := priv
+		g.Go(func() error {
+			next := time.Now()
+			if err := rp.republishEntry(ctx, priv); err != nil {
+				rp.failures.Inc()
+				errLk.Lock()
+				errs = append(errs, fmt.Errorf("republishing %s: %w", name, err))
+				errLk.Unlock()
+				next = next.Add(min(FailureRetryInterval, rp.intervalFor(name)))
+			} else {
+				next = next.Add(rp.intervalFor(name))
 			}
+			rp.setNext(name, next)
+			return nil
+		})
+	}
+	g.Wait()
+
+	rp.lk.Lock()
+	for name, due := range rp.next {
+		if _, ok := seen[name]; !ok {
+			// the key was removed
+			delete(rp.next, name)
+			continue
+		}
+		nextDue = minTime(nextDue, due)
+	}
+	rp.lk.Unlock()
 
+	return nextDue, errors.Join(errs...)
+}
+
+func (rp *Republisher) setNext(name ipns.Name, next time.Time) {
+	rp.lk.Lock()
+	rp.next[name] = next
+	rp.lk.Unlock()
+}
+
+// intervalFor returns the delay before name is republished again.
+func (rp *Republisher) intervalFor(name ipns.Name) time.Duration {
+	interval := rp.Interval
+	if rp.nameInterval != nil {
+		if i := rp.nameInterval(name); i > 0 {
+			interval = i
 		}
 	}
+	if rp.jitter > 0 {
+		interval += time.Duration(rand.Int63n(int64(rp.jitter)))
+	}
+	return interval
+}
 
-	return nil
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
 }
 
 func (rp *Republisher) republishEntry(ctx context.Context, priv ic.PrivKey) error {
@@ -3,6 +3,8 @@ package republisher_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -199,6 +201,119 @@ func TestLongEOLRepublish(t *testing.T) {
 	require.Equal(t, expiration.UTC(), finalEol.UTC())
 }
 
+// countingPublisher records the names it publishes.
+type countingPublisher struct {
+	lk        sync.Mutex
+	published map[ipns.Name]int
+	eols      map[ipns.Name]time.Time
+}
+
+func (p *countingPublisher) Publish(ctx context.Context, sk ic.PrivKey, value path.Path, options ...namesys.PublishOption) error {
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return err
+	}
+	name := ipns.NameFromPeer(id)
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	p.published[name]++
+	p.eols[name] = namesys.ProcessPublishOptions(options).EOL
+	return nil
+}
+
+func (p *countingPublisher) count(name ipns.Name) int {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	return p.published[name]
+}
+
+// newTestKeys stores records for n keys, the first one being self.
+func newTestKeys(t *testing.T, dstore ds.Datastore, n int) (ic.PrivKey, keystore.Keystore, []ipns.Name) {
+	t.Helper()
+
+	p, err := path.NewPath("/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")
+	require.NoError(t, err)
+
+	var self ic.PrivKey
+	ks := keystore.NewMemKeystore()
+	var names []ipns.Name
+	for i := 0; i < n; i++ {
+		sk, _, err := ic.GenerateEd25519Key(nil)
+		require.NoError(t, err)
+		if i == 0 {
+			self = sk
+		} else {
+			require.NoError(t, ks.Put(fmt.Sprint("key", i), sk))
+		}
+
+		rec, err := ipns.NewRecord(sk, p, 1, time.Now().Add(time.Hour), time.Minute)
+		require.NoError(t, err)
+		data, err := ipns.MarshalRecord(rec)
+		require.NoError(t, err)
+
+		id, err := peer.IDFromPrivateKey(sk)
+		require.NoError(t, err)
+		name := ipns.NameFromPeer(id)
+		require.NoError(t, dstore.Put(context.Background(), namesys.IpnsDsKey(name), data))
+		names = append(names, name)
+	}
+	return self, ks, names
+}
+
+func TestRepublishOptions(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	self, ks, names := newTestKeys(t, dstore, 4)
+	pub := &countingPublisher{published: make(map[ipns.Name]int), eols: make(map[ipns.Name]time.Time)}
+
+	lifetime := 48 * time.Hour
+	repub := NewRepublisher(pub, dstore, self, ks,
+		WithRecordLifetime(lifetime),
+		WithConcurrency(2),
+		WithSkip(func(name ipns.Name) bool { return name == names[1] }),
+	)
+
+	start := time.Now()
+	require.NoError(t, repub.Republish(ctx))
+	for i, name := range names {
+		if i == 1 {
+			require.Zero(t, pub.count(name), "skipped name should not be republished")
+			continue
+		}
+		require.Equal(t, 1, pub.count(name))
+		// the record lifetime extends the EOL of the records
+		require.False(t, pub.eols[name].Before(start.Add(lifetime)))
+	}
+}
+
+func TestRepublishNameInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	self, ks, names := newTestKeys(t, dstore, 2)
+	pub := &countingPublisher{published: make(map[ipns.Name]int), eols: make(map[ipns.Name]time.Time)}
+
+	repub := NewRepublisher(pub, dstore, self, ks,
+		WithInterval(time.Hour),
+		WithNameInterval(func(name ipns.Name) time.Duration {
+			if name == names[0] {
+				return 100 * time.Millisecond
+			}
+			return 0
+		}),
+		WithJitter(10*time.Millisecond),
+		WithInitialDelay(0),
+	)
+
+	go repub.RunContext(ctx)
+	require.Eventually(t, func() bool {
+		return pub.count(names[0]) >= 3
+	}, 10*time.Second, 10*time.Millisecond)
+	// the other name uses the default interval of an hour
+	require.Equal(t, 1, pub.count(names[1]))
+}
+
 func getLastIPNSRecord(ctx context.Context, dstore ds.Datastore, name ipns.Name) (*ipns.Record, error) {
 	// Look for it locally only
 	val, err := dstore.Get(ctx, namesys.IpnsDsKey(name))
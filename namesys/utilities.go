@@ -2,6 +2,7 @@ package namesys
 
 import (
 	"context"
+	"slices"
 	"strings"
 
 	"github.com/ipfs/boxo/path"
@@ -32,9 +33,20 @@ func resolve(ctx context.Context, r resolver, p path.Path, options ResolveOption
 }
 
 func resolveAsync(ctx context.Context, r resolver, p path.Path, options ResolveOptions) <-chan AsyncResult {
+	return resolveAsyncFrom(ctx, r, p, options, options.Depth, nil)
+}
+
+// resolveAsyncFrom resolves p, which was reached by resolving the names in
+// chain. limit is the depth limit of the whole resolution, options.Depth is
+// what is left of it.
+func resolveAsyncFrom(ctx context.Context, r resolver, p path.Path, options ResolveOptions, limit uint, chain []path.Path) <-chan AsyncResult {
 	ctx, span := startSpan(ctx, "ResolveAsync")
 	defer span.End()
 
+	if name, ok := nameOf(p); ok {
+		chain = append(chain[:len(chain):len(chain)], name)
+	}
+
 	resCh := r.resolveOnceAsync(ctx, p, options)
 	depth := options.Depth
 	outCh := make(chan AsyncResult, 1)
@@ -72,8 +84,18 @@ func resolveAsync(ctx context.Context, r resolver, p path.Path, options ResolveO
 					break
 				}
 
+				names := chain
+				if name, ok := nameOf(res.Path); ok {
+					names = append(chain[:len(chain):len(chain)], name)
+					if slices.ContainsFunc(chain, func(n path.Path) bool { return n.String() == name.String() }) {
+						res.Err = &CycleError{Names: names}
+						emitResult(ctx, outCh, res)
+						break
+					}
+				}
+
 				if depth == 1 {
-					res.Err = ErrResolveRecursion
+					res.Err = &RecursionLimitError{Limit: limit, Names: names}
 					emitResult(ctx, outCh, res)
 					break
 				}
@@ -92,7 +114,7 @@ func resolveAsync(ctx context.Context, r resolver, p path.Path, options ResolveO
 				subCtx, cancelSub = context.WithCancel(ctx)
 				_ = cancelSub
 
-				subCh = resolveAsync(subCtx, r, res.Path, subOpts)
+				subCh = resolveAsyncFrom(subCtx, r, res.Path, subOpts, limit, chain)
 			case res, ok := <-subCh:
 				if !ok {
 					subCh = nil
@@ -113,6 +135,20 @@ func resolveAsync(ctx context.Context, r resolver, p path.Path, options ResolveO
 	return outCh
 }
 
+// nameOf returns the name resolved for the mutable path p, without the
+// segments after it.
+func nameOf(p path.Path) (path.Path, bool) {
+	if p == nil || !p.Mutable() {
+		return nil, false
+	}
+	segments := p.Segments()
+	name, err := path.NewPathFromSegments(segments[0], segments[1])
+	if err != nil {
+		return nil, false
+	}
+	return name, true
+}
+
 func emitResult(ctx context.Context, outCh chan<- AsyncResult, r AsyncResult) {
 	select {
 	case outCh <- r:
@@ -0,0 +1,200 @@
+package path
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	_ encoding.TextMarshaler   = ImmutablePath{}
+	_ encoding.TextUnmarshaler = (*ImmutablePath)(nil)
+	_ json.Marshaler           = ImmutablePath{}
+	_ json.Unmarshaler         = (*ImmutablePath)(nil)
+	_ driver.Valuer            = ImmutablePath{}
+	_ sql.Scanner              = (*ImmutablePath)(nil)
+
+	_ encoding.TextMarshaler = path{}
+	_ json.Marshaler         = path{}
+	_ driver.Valuer          = path{}
+)
+
+func (p path) MarshalText() ([]byte, error) {
+	return []byte(p.str), nil
+}
+
+func (p path) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.str)
+}
+
+func (p path) Value() (driver.Value, error) {
+	return p.str, nil
+}
+
+// MarshalText implements [encoding.TextMarshaler]. Like [Serializable], the
+// zero value is encoded as an empty text, JSON null and SQL NULL.
+func (ip ImmutablePath) MarshalText() ([]byte, error) {
+	if ip.path == nil {
+		return []byte{}, nil
+	}
+	return []byte(ip.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler]. It fails if the path
+// is not immutable.
+func (ip *ImmutablePath) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*ip = ImmutablePath{}
+		return nil
+	}
+	p, err := NewPath(string(text))
+	if err != nil {
+		return err
+	}
+	v, err := NewImmutablePath(p)
+	if err != nil {
+		return err
+	}
+	*ip = v
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (ip ImmutablePath) MarshalJSON() ([]byte, error) {
+	if ip.path == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(ip.String())
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (ip *ImmutablePath) UnmarshalJSON(data []byte) error {
+	s, err := unmarshalJSONString(data)
+	if err != nil || s == nil {
+		return err
+	}
+	return ip.UnmarshalText([]byte(*s))
+}
+
+// Value implements [driver.Valuer].
+func (ip ImmutablePath) Value() (driver.Value, error) {
+	if ip.path == nil {
+		return nil, nil
+	}
+	return ip.String(), nil
+}
+
+// Scan implements [sql.Scanner].
+func (ip *ImmutablePath) Scan(src any) error {
+	text, err := scanText(src)
+	if err != nil {
+		return err
+	}
+	return ip.UnmarshalText(text)
+}
+
+// Serializable holds a [Path] of any namespace, to store it in configs and
+// databases: unlike the [Path] interface, it can be decoded.
+//
+// Paths are encoded as their string, in text, JSON and SQL, and are validated
+// by [NewPath] when decoded. The zero value holds no path, and is encoded as
+// an empty text, JSON null and SQL NULL, so that optional paths are decoded
+// back to the zero value.
+type Serializable struct {
+	Path
+}
+
+var (
+	_ encoding.TextMarshaler   = Serializable{}
+	_ encoding.TextUnmarshaler = (*Serializable)(nil)
+	_ json.Marshaler           = Serializable{}
+	_ json.Unmarshaler         = (*Serializable)(nil)
+	_ driver.Valuer            = Serializable{}
+	_ sql.Scanner              = (*Serializable)(nil)
+)
+
+// MarshalText implements [encoding.TextMarshaler].
+func (s Serializable) MarshalText() ([]byte, error) {
+	if s.Path == nil {
+		return []byte{}, nil
+	}
+	return []byte(s.Path.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (s *Serializable) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		s.Path = nil
+		return nil
+	}
+	p, err := NewPath(string(text))
+	if err != nil {
+		return err
+	}
+	s.Path = p
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (s Serializable) MarshalJSON() ([]byte, error) {
+	if s.Path == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(s.Path.String())
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (s *Serializable) UnmarshalJSON(data []byte) error {
+	str, err := unmarshalJSONString(data)
+	if err != nil || str == nil {
+		return err
+	}
+	return s.UnmarshalText([]byte(*str))
+}
+
+// Value implements [driver.Valuer].
+func (s Serializable) Value() (driver.Value, error) {
+	if s.Path == nil {
+		return nil, nil
+	}
+	return s.Path.String(), nil
+}
+
+// Scan implements [sql.Scanner].
+func (s *Serializable) Scan(src any) error {
+	text, err := scanText(src)
+	if err != nil {
+		return err
+	}
+	return s.UnmarshalText(text)
+}
+
+// unmarshalJSONString returns the JSON string in data, or nil if data is
+// null, which leaves the value unchanged as usual in JSON.
+func unmarshalJSONString(data []byte) (*string, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// scanText returns the text of a value read from a database. NULL is read as
+// an empty text.
+func scanText(src any) ([]byte, error) {
+	switch src := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(src), nil
+	case []byte:
+		return src, nil
+	default:
+		return nil, fmt.Errorf("cannot scan %T into a path", src)
+	}
+}
@@ -0,0 +1,123 @@
+package path
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImmutablePathEncoding(t *testing.T) {
+	t.Parallel()
+
+	const str = "/ipfs/bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku/a/b"
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		type config struct {
+			Root     ImmutablePath
+			Optional ImmutablePath
+		}
+		var c config
+		err := json.Unmarshal([]byte(`{"Root":"`+str+`","Optional":null}`), &c)
+		assert.NoError(t, err)
+		assert.Equal(t, str, c.Root.String())
+		assert.Equal(t, "bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku", c.Root.RootCid().String())
+		assert.Equal(t, ImmutablePath{}, c.Optional)
+
+		data, err := json.Marshal(c)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"Root":"`+str+`","Optional":null}`, string(data))
+
+		err = json.Unmarshal([]byte(`{"Root":"/ipns/example.net"}`), &c)
+		assert.ErrorIs(t, err, ErrExpectedImmutable)
+		err = json.Unmarshal([]byte(`{"Root":"/ipfs/invalid"}`), &c)
+		assert.ErrorIs(t, err, &ErrInvalidPath{})
+		err = json.Unmarshal([]byte(`{"Root":42}`), &c)
+		assert.Error(t, err)
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		t.Parallel()
+
+		var ip ImmutablePath
+		assert.NoError(t, ip.UnmarshalText([]byte(str)))
+		text, err := ip.MarshalText()
+		assert.NoError(t, err)
+		assert.Equal(t, str, string(text))
+
+		// map keys are encoded as text
+		data, err := json.Marshal(map[ImmutablePath]int{ip: 1})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"`+str+`":1}`, string(data))
+	})
+
+	t.Run("SQL", func(t *testing.T) {
+		t.Parallel()
+
+		var ip ImmutablePath
+		for _, src := range []any{str, []byte(str)} {
+			assert.NoError(t, ip.Scan(src))
+			v, err := ip.Value()
+			assert.NoError(t, err)
+			assert.Equal(t, str, v)
+		}
+
+		assert.NoError(t, ip.Scan(nil))
+		assert.Equal(t, ImmutablePath{}, ip)
+		v, err := ip.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+
+		assert.Error(t, ip.Scan(42))
+	})
+}
+
+func TestSerializable(t *testing.T) {
+	t.Parallel()
+
+	for _, str := range []string{
+		"/ipfs/bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku/a",
+		"/ipld/bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku",
+		"/ipns/example.net/a/",
+	} {
+		var s Serializable
+		assert.NoError(t, json.Unmarshal([]byte(`"`+str+`"`), &s))
+		assert.Equal(t, str, s.String())
+
+		p, err := NewPath(str)
+		assert.NoError(t, err)
+		assert.Equal(t, p.Mutable(), s.Mutable())
+
+		data, err := json.Marshal(s)
+		assert.NoError(t, err)
+		assert.Equal(t, `"`+str+`"`, string(data))
+
+		// paths are also encoded through the Path interface
+		data, err = json.Marshal(struct{ P Path }{p})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"P":"`+str+`"}`, string(data))
+
+		var scanned Serializable
+		assert.NoError(t, scanned.Scan([]byte(str)))
+		v, err := scanned.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, str, v)
+	}
+
+	var s Serializable
+	data, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+	v, err := s.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.NoError(t, s.UnmarshalText([]byte("/ipns/example.net")))
+	assert.NoError(t, s.Scan(nil))
+	assert.Nil(t, s.Path)
+
+	assert.ErrorIs(t, s.UnmarshalText([]byte("/unknown/example.net")), ErrUnknownNamespace)
+	assert.ErrorIs(t, s.UnmarshalText([]byte("example.net")), ErrInsufficientComponents)
+}
@@ -3,7 +3,10 @@ package resolver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -63,12 +66,39 @@ type Resolver interface {
 	ResolvePathComponents(context.Context, path.ImmutablePath) ([]ipld.Node, error)
 }
 
+// ManyResolver is implemented by the [Resolver] which can resolve many paths
+// at once, such as the one returned by [NewBasicResolver].
+type ManyResolver interface {
+	Resolver
+
+	// ResolveMany resolves many paths concurrently, like [Resolver.ResolveToLastNode].
+	// The paths share a single fetcher session and their common prefixes are only
+	// resolved once, which makes it suitable to resolve many sibling paths. The
+	// results are returned in the order of the paths.
+	ResolveMany(context.Context, []path.ImmutablePath) []ResolveResult
+}
+
+// ResolveResult is the result of resolving one of the paths given to
+// [ManyResolver.ResolveMany]. Cid and Remainder are the values returned by
+// [Resolver.ResolveToLastNode] for that path, or Err is set.
+type ResolveResult struct {
+	Cid       cid.Cid
+	Remainder []string
+	Err       error
+}
+
+// resolveManyConcurrency is the number of paths resolved at the same time by
+// [ManyResolver.ResolveMany].
+const resolveManyConcurrency = 32
+
 // basicResolver implements the [Resolver] interface. It requires a [fetcher.Factory],
 // which is used to resolve the nodes.
 type basicResolver struct {
 	FetcherFactory fetcher.Factory
 }
 
+var _ ManyResolver = (*basicResolver)(nil)
+
 // NewBasicResolver constructs a new basic resolver using the given [fetcher.Factory].
 func NewBasicResolver(factory fetcher.Factory) Resolver {
 	return &basicResolver{
@@ -87,15 +117,26 @@ func (r *basicResolver) ResolveToLastNode(ctx context.Context, fpath path.Immuta
 		return c, nil, nil
 	}
 
-	// create a selector to traverse and match all path segments
-	pathSelector := pathAllSelector(remainder[:len(remainder)-1])
-
 	// create a new cancellable session
 	ctx, cancel := context.WithTimeout(ctx, time.Minute)
 	defer cancel()
 
+	return r.resolveToLastNode(ctx, r.FetcherFactory.NewSession(ctx), fpath, c, remainder)
+}
+
+// resolveToLastNode resolves the remainder segments from c with the given
+// fetcher session, see [Resolver.ResolveToLastNode]. fpath is the path being
+// resolved, used in errors.
+func (r *basicResolver) resolveToLastNode(ctx context.Context, session fetcher.Fetcher, fpath path.ImmutablePath, c cid.Cid, remainder []string) (cid.Cid, []string, error) {
+	if len(remainder) == 0 {
+		return c, nil, nil
+	}
+
+	// create a selector to traverse and match all path segments
+	pathSelector := pathAllSelector(remainder[:len(remainder)-1])
+
 	// resolve node before last path segment
-	nodes, lastCid, depth, err := r.resolveNodes(ctx, c, pathSelector)
+	nodes, lastCid, depth, err := r.resolveNodes(ctx, session, c, pathSelector)
 	if err != nil {
 		return cid.Cid{}, nil, err
 	}
@@ -113,7 +154,7 @@ func (r *basicResolver) ResolveToLastNode(ctx context.Context, fpath path.Immuta
 	nd, err := parent.LookupBySegment(ipld.ParsePathSegment(lastSegment))
 	switch err.(type) {
 	case nil:
-	case schema.ErrNoSuchField:
+	case schema.ErrNoSuchField, ipld.ErrNotExists:
 		return cid.Undef, nil, &ErrNoLink{Name: lastSegment, Node: lastCid}
 	default:
 		return cid.Cid{}, nil, err
@@ -151,7 +192,7 @@ func (r *basicResolver) ResolvePath(ctx context.Context, fpath path.ImmutablePat
 	// create a selector to traverse all path segments but only match the last
 	pathSelector := pathLeafSelector(remainder)
 
-	nodes, c, _, err := r.resolveNodes(ctx, c, pathSelector)
+	nodes, c, _, err := r.resolveNodes(ctx, r.FetcherFactory.NewSession(ctx), c, pathSelector)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -176,16 +217,132 @@ func (r *basicResolver) ResolvePathComponents(ctx context.Context, fpath path.Im
 	// create a selector to traverse and match all path segments
 	pathSelector := pathAllSelector(remainder)
 
-	nodes, _, _, err = r.resolveNodes(ctx, c, pathSelector)
+	nodes, _, _, err = r.resolveNodes(ctx, r.FetcherFactory.NewSession(ctx), c, pathSelector)
 	return nodes, err
 }
 
+// ResolveMany implements [ManyResolver.ResolveMany]. Like
+// [Resolver.ResolveToLastNode], each path is given one minute to resolve. The
+// prefixes shared by several paths are resolved under ctx, so that they are
+// not bound to the timeout of the first path needing them.
+func (r *basicResolver) ResolveMany(ctx context.Context, fpaths []path.ImmutablePath) []ResolveResult {
+	ctx, span := startSpan(ctx, "basicResolver.ResolveMany", trace.WithAttributes(attribute.Int("Paths", len(fpaths))))
+	defer span.End()
+
+	// stop the prefix resolutions no path is waiting for anymore
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m := &manyResolver{
+		ctx:      ctx,
+		resolver: r,
+		session:  r.FetcherFactory.NewSession(ctx),
+		prefixes: make(map[string]*prefixResolution),
+	}
+
+	results := make([]ResolveResult, len(fpaths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < resolveManyConcurrency && i < len(fpaths); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fpath := fpaths[i]
+				pctx, cancel := context.WithTimeout(ctx, time.Minute)
+				c, remainder, err := m.resolve(pctx, fpath, fpath.RootCid(), fpath.Segments()[2:])
+				cancel()
+				results[i] = ResolveResult{Cid: c, Remainder: remainder, Err: err}
+			}
+		}()
+	}
+	for i := range fpaths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// manyResolver resolves paths one segment at a time, remembering the result
+// of every prefix so that the prefixes shared by several paths are only
+// resolved once.
+type manyResolver struct {
+	// ctx is the context of ResolveMany, the prefixes are resolved under it
+	ctx      context.Context
+	resolver *basicResolver
+	session  fetcher.Fetcher
+
+	lk       sync.Mutex
+	prefixes map[string]*prefixResolution
+}
+
+type prefixResolution struct {
+	done      chan struct{}
+	c         cid.Cid
+	remainder []string
+	err       error
+	// retry is set when the resolution was interrupted by a context error,
+	// which is not remembered
+	retry bool
+}
+
+// resolve resolves the given segments from root, see [Resolver.ResolveToLastNode].
+func (m *manyResolver) resolve(ctx context.Context, fpath path.ImmutablePath, root cid.Cid, segments []string) (cid.Cid, []string, error) {
+	if len(segments) == 0 {
+		return root, nil, nil
+	}
+
+	key := root.KeyString() + "/" + strings.Join(segments, "/")
+	for {
+		m.lk.Lock()
+		pr, ok := m.prefixes[key]
+		if !ok {
+			pr = &prefixResolution{done: make(chan struct{})}
+			m.prefixes[key] = pr
+			go m.resolvePrefix(key, pr, fpath, root, segments)
+		}
+		m.lk.Unlock()
+
+		select {
+		case <-pr.done:
+		case <-ctx.Done():
+			return cid.Undef, nil, ctx.Err()
+		}
+		if !pr.retry {
+			return pr.c, pr.remainder, pr.err
+		}
+	}
+}
+
+// resolvePrefix resolves the given segments from root under the context of
+// ResolveMany, and stores the result in pr.
+func (m *manyResolver) resolvePrefix(key string, pr *prefixResolution, fpath path.ImmutablePath, root cid.Cid, segments []string) {
+	defer close(pr.done)
+
+	c, remainder, err := m.resolve(m.ctx, fpath, root, segments[:len(segments)-1])
+	if err == nil {
+		// resolve the last segment from the block the parent resolved to
+		remainder = append(remainder[:len(remainder):len(remainder)], segments[len(segments)-1])
+		c, remainder, err = m.resolver.resolveToLastNode(m.ctx, m.session, fpath, c, remainder)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// forget the prefix, the paths still waiting for it resolve it again
+		m.lk.Lock()
+		delete(m.prefixes, key)
+		m.lk.Unlock()
+		pr.retry = true
+		return
+	}
+	pr.c, pr.remainder, pr.err = c, remainder, err
+}
+
 // Finds nodes matching the selector starting with a cid. Returns the matched nodes, the cid of the block containing
 // the last node, and the depth of the last node within its block (root is depth 0).
-func (r *basicResolver) resolveNodes(ctx context.Context, c cid.Cid, sel ipld.Node) ([]ipld.Node, cid.Cid, int, error) {
+func (r *basicResolver) resolveNodes(ctx context.Context, session fetcher.Fetcher, c cid.Cid, sel ipld.Node) ([]ipld.Node, cid.Cid, int, error) {
 	ctx, span := startSpan(ctx, "basicResolver.resolveNodes", trace.WithAttributes(attribute.Stringer("CID", c)))
 	defer span.End()
-	session := r.FetcherFactory.NewSession(ctx)
 
 	// traverse selector
 	lastLink := cid.Undef
@@ -268,3 +268,83 @@ func TestResolveToLastNode_MixedSegmentTypes(t *testing.T) {
 	require.Equal(t, 0, len(remainder))
 	require.True(t, cid.Equals(a.Cid()))
 }
+
+func TestResolveMany(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bsrv := dagmock.Bserv()
+	a := randNode()
+	b := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	for _, n := range []*merkledag.ProtoNode{a, b} {
+		require.NoError(t, bsrv.AddBlock(ctx, n))
+	}
+
+	nb := basicnode.Prototype.Any.NewBuilder()
+	json := `{"foo":{"bar":[0,{"boom":["baz",1,2,{"/":"CID"},"blop"]}]}}`
+	json = strings.ReplaceAll(json, "CID", a.Cid().String())
+	require.NoError(t, dagjson.Decode(nb, strings.NewReader(json)))
+
+	out := new(bytes.Buffer)
+	require.NoError(t, dagcbor.Encode(nb.Build(), out))
+
+	lnk, err := cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagCBOR,
+		MhType:   multihash.SHA2_256,
+		MhLength: 32,
+	}.Sum(out.Bytes())
+	require.NoError(t, err)
+
+	blk, err := blocks.NewBlockWithCid(out.Bytes(), lnk)
+	require.NoError(t, err)
+	require.NoError(t, bsrv.AddBlock(ctx, blk))
+
+	fetcherFactory := bsfetcher.NewFetcherConfig(bsrv)
+	fetcherFactory.PrototypeChooser = dagpb.AddSupportToChooser(func(lnk ipld.Link, lnkCtx ipld.LinkContext) (ipld.NodePrototype, error) {
+		if tlnkNd, ok := lnkCtx.LinkNode.(schema.TypedLinkNode); ok {
+			return tlnkNd.LinkTargetNodePrototype(), nil
+		}
+		return basicnode.Prototype.Any, nil
+	})
+	r := resolver.NewBasicResolver(fetcherFactory)
+
+	var paths []path.ImmutablePath
+	for _, segments := range [][]string{
+		{"foo", "bar", "1", "boom", "3"},
+		{"foo", "bar", "1", "boom", "3", "Links", "0", "Hash"},
+		{"foo", "bar", "1", "boom", "0"},
+		{"foo", "bar"},
+		{"foo", "nope", "boom"},
+		{"foo", "bar", "1", "boom", "3"},
+		{},
+	} {
+		p, err := path.Join(path.FromCid(lnk), segments...)
+		require.NoError(t, err)
+		imPath, err := path.NewImmutablePath(p)
+		require.NoError(t, err)
+		paths = append(paths, imPath)
+	}
+
+	results := r.(resolver.ManyResolver).ResolveMany(ctx, paths)
+	require.Len(t, results, len(paths))
+	for i, p := range paths {
+		c, remainder, err := r.ResolveToLastNode(ctx, p)
+		if err != nil {
+			require.ErrorIs(t, results[i].Err, err, p.String())
+			continue
+		}
+		require.NoError(t, results[i].Err, p.String())
+		require.Equal(t, c, results[i].Cid, p.String())
+		require.Equal(t, len(remainder), len(results[i].Remainder), p.String())
+		if len(remainder) > 0 {
+			require.Equal(t, remainder, results[i].Remainder, p.String())
+		}
+	}
+
+	require.Equal(t, a.Cid(), results[0].Cid)
+	require.Equal(t, b.Cid(), results[1].Cid)
+	require.Equal(t, []string{"foo", "bar", "1", "boom", "0"}, results[2].Remainder)
+	require.ErrorIs(t, results[4].Err, &resolver.ErrNoLink{})
+}
@@ -68,6 +68,17 @@ func init() {
 			AddField("Metadata", atlas.StructMapEntry{SerialName: "metadata", OmitEmpty: true}).
 			AddField("Mode", atlas.StructMapEntry{SerialName: "mode"}).
 			AddField("Name", atlas.StructMapEntry{SerialName: "name", OmitEmpty: true}).
+			AddField("Depth", atlas.StructMapEntry{SerialName: "depth", OmitEmpty: true}).
+			Complete(),
+		atlas.BuildEntry(snapshotHeader{}).StructMap().
+			AddField("Version", atlas.StructMapEntry{SerialName: "version"}).
+			Complete(),
+		atlas.BuildEntry(snapshotRecord{}).StructMap().
+			AddField("Pin", atlas.StructMapEntry{SerialName: "pin", OmitEmpty: true}).
+			AddField("End", atlas.StructMapEntry{SerialName: "end", OmitEmpty: true}).
+			Complete(),
+		atlas.BuildEntry(snapshotEnd{}).StructMap().
+			AddField("Count", atlas.StructMapEntry{SerialName: "count"}).
 			Complete(),
 		atlas.BuildEntry(cid.Cid{}).Transform().
 			TransformMarshal(atlas.MakeMarshalTransformFunc(func(live cid.Cid) ([]byte, error) { return live.MarshalBinary() })).
@@ -107,18 +118,20 @@ type pin struct {
 	Metadata map[string]interface{}
 	Mode     ipfspinner.Mode
 	Name     string
+	Depth    int
 }
 
 func (p *pin) dsKey() ds.Key {
 	return ds.NewKey(path.Join(pinKeyPath, p.Id))
 }
 
-func newPin(c cid.Cid, mode ipfspinner.Mode, name string) *pin {
+func newPin(c cid.Cid, mode ipfspinner.Mode, name string, depth int) *pin {
 	return &pin{
-		Id:   path.Base(ds.RandomKey().String()),
-		Cid:  c,
-		Name: name,
-		Mode: mode,
+		Id:    path.Base(ds.RandomKey().String()),
+		Cid:   c,
+		Name:  name,
+		Mode:  mode,
+		Depth: depth,
 	}
 }
 
@@ -181,13 +194,28 @@ func (p *pinner) Pin(ctx context.Context, node ipld.Node, recurse bool, name str
 	}
 
 	if recurse {
-		return p.doPinRecursive(ctx, node.Cid(), true, name)
+		return p.doPinRecursive(ctx, node.Cid(), true, name, 0)
 	} else {
 		return p.doPinDirect(ctx, node.Cid(), name)
 	}
 }
 
-func (p *pinner) doPinRecursive(ctx context.Context, c cid.Cid, fetch bool, name string) error {
+// PinWithDepth pins the given node and its descendants up to the given depth.
+// A depth of 0 pins the whole DAG.
+func (p *pinner) PinWithDepth(ctx context.Context, node ipld.Node, depth int, name string) error {
+	if depth < 0 {
+		return fmt.Errorf("invalid pin depth %d", depth)
+	}
+
+	err := p.dserv.Add(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	return p.doPinRecursive(ctx, node.Cid(), true, name, depth)
+}
+
+func (p *pinner) doPinRecursive(ctx context.Context, c cid.Cid, fetch bool, name string, depth int) error {
 	cidKey := c.KeyString()
 
 	p.lock.Lock()
@@ -211,10 +239,10 @@ func (p *pinner) doPinRecursive(ctx context.Context, c cid.Cid, fetch bool, name
 	dirtyBefore := p.dirty
 
 	if fetch {
-		// temporary unlock to fetch the entire graph
+		// temporary unlock to fetch the graph
 		p.lock.Unlock()
 		// Fetch graph starting at node identified by cid
-		err = merkledag.FetchGraph(ctx, c, p.dserv)
+		err = fetchGraph(ctx, c, depth, p.dserv)
 		p.lock.Lock()
 		if err != nil {
 			return err
@@ -250,7 +278,7 @@ func (p *pinner) doPinRecursive(ctx context.Context, c cid.Cid, fetch bool, name
 		}
 	}
 
-	_, err = p.addPin(ctx, c, ipfspinner.Recursive, name)
+	_, err = p.addPin(ctx, c, ipfspinner.Recursive, name, depth)
 	if err != nil {
 		return err
 	}
@@ -287,7 +315,7 @@ func (p *pinner) doPinDirect(ctx context.Context, c cid.Cid, name string) error
 		}
 	}
 
-	_, err = p.addPin(ctx, c, ipfspinner.Direct, name)
+	_, err = p.addPin(ctx, c, ipfspinner.Direct, name, 0)
 	if err != nil {
 		return err
 	}
@@ -295,14 +323,23 @@ func (p *pinner) doPinDirect(ctx context.Context, c cid.Cid, name string) error
 	return p.flushPins(ctx, false)
 }
 
-func (p *pinner) addPin(ctx context.Context, c cid.Cid, mode ipfspinner.Mode, name string) (string, error) {
+func (p *pinner) addPin(ctx context.Context, c cid.Cid, mode ipfspinner.Mode, name string, depth int) (string, error) {
 	// Create new pin and store in datastore
-	pp := newPin(c, mode, name)
+	pp := newPin(c, mode, name, depth)
+	if err := p.storePin(ctx, pp); err != nil {
+		return "", err
+	}
+	return pp.Id, nil
+}
+
+// storePin writes a new pin and its indexes to the datastore.
+func (p *pinner) storePin(ctx context.Context, pp *pin) error {
+	c, mode, name := pp.Cid, pp.Mode, pp.Name
 
 	// Serialize pin
 	pinData, err := encodePin(pp)
 	if err != nil {
-		return "", fmt.Errorf("could not encode pin: %v", err)
+		return fmt.Errorf("could not encode pin: %v", err)
 	}
 
 	p.setDirty(ctx)
@@ -310,7 +347,7 @@ func (p *pinner) addPin(ctx context.Context, c cid.Cid, mode ipfspinner.Mode, na
 	// Store the pin
 	err = p.dstore.Put(ctx, pp.dsKey(), pinData)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	// Store CID index
@@ -323,7 +360,7 @@ func (p *pinner) addPin(ctx context.Context, c cid.Cid, mode ipfspinner.Mode, na
 		panic("pin mode must be recursive or direct")
 	}
 	if err != nil {
-		return "", fmt.Errorf("could not add pin cid index: %v", err)
+		return fmt.Errorf("could not add pin cid index: %v", err)
 	}
 
 	if name != "" {
@@ -341,11 +378,11 @@ func (p *pinner) addPin(ctx context.Context, c cid.Cid, mode ipfspinner.Mode, na
 					log.Errorf("error deleting index: %s", e)
 				}
 			}
-			return "", fmt.Errorf("could not add pin name index: %v", err)
+			return fmt.Errorf("could not add pin name index: %v", err)
 		}
 	}
 
-	return pp.Id, nil
+	return nil
 }
 
 func (p *pinner) removePin(ctx context.Context, pp *pin) error {
@@ -499,7 +536,18 @@ func (p *pinner) isPinnedWithType(ctx context.Context, c cid.Cid, mode ipfspinne
 		if e != nil {
 			return false
 		}
-		has, e = hasChild(ctx, p.dserv, rc, c, visitedSet.Visit)
+		var depth int
+		depth, e = p.pinDepth(ctx, value)
+		if e != nil {
+			return false
+		}
+		if depth > 0 {
+			// The branches visited for an unlimited pin may be deeper than
+			// this pin protects, so they are searched again.
+			has, e = hasChildWithinDepth(ctx, p.dserv, rc, c, depth)
+		} else {
+			has, e = hasChild(ctx, p.dserv, rc, c, visitedSet.Visit)
+		}
 		if e != nil {
 			return false
 		}
@@ -563,18 +611,45 @@ func (p *pinner) CheckIfPinned(ctx context.Context, cids ...cid.Cid) ([]ipfspinn
 		if e != nil {
 			return false
 		}
-		e = merkledag.Walk(ctx, merkledag.GetLinksWithDAG(p.dserv), rk, func(c cid.Cid) bool {
-			if toCheck.Len() == 0 || !visited.Visit(c) {
-				return false
-			}
+		var maxDepth int
+		maxDepth, e = p.pinDepth(ctx, value)
+		if e != nil {
+			return false
+		}
+		if maxDepth > 0 {
+			// Depth-limited pins keep their own visited set, as a node
+			// reached here may be deeper than the pin protects.
+			seen := make(map[cid.Cid]int)
+			e = merkledag.WalkDepth(ctx, merkledag.GetLinksWithDAG(p.dserv), rk, func(c cid.Cid, depth int) bool {
+				if toCheck.Len() == 0 {
+					return false
+				}
+				if d, ok := seen[c]; ok && d <= depth {
+					return false
+				}
+				seen[c] = depth
 
-			if toCheck.Has(c) {
-				pinned = append(pinned, ipfspinner.Pinned{Key: c, Mode: ipfspinner.Indirect, Via: rk})
-				toCheck.Remove(c)
-			}
+				if toCheck.Has(c) {
+					pinned = append(pinned, ipfspinner.Pinned{Key: c, Mode: ipfspinner.Indirect, Via: rk})
+					toCheck.Remove(c)
+				}
 
-			return true
-		}, merkledag.Concurrent())
+				return depth < maxDepth
+			})
+		} else {
+			e = merkledag.Walk(ctx, merkledag.GetLinksWithDAG(p.dserv), rk, func(c cid.Cid) bool {
+				if toCheck.Len() == 0 || !visited.Visit(c) {
+					return false
+				}
+
+				if toCheck.Has(c) {
+					pinned = append(pinned, ipfspinner.Pinned{Key: c, Mode: ipfspinner.Indirect, Via: rk})
+					toCheck.Remove(c)
+				}
+
+				return true
+			}, merkledag.Concurrent())
+		}
 		if e != nil {
 			return false
 		}
@@ -687,6 +762,15 @@ func (p *pinner) loadPin(ctx context.Context, pid string) (*pin, error) {
 	return decodePin(pid, pinData)
 }
 
+// pinDepth returns the depth limit of a single pin, 0 meaning unlimited.
+func (p *pinner) pinDepth(ctx context.Context, pid string) (int, error) {
+	pp, err := p.loadPin(ctx, pid)
+	if err != nil {
+		return 0, err
+	}
+	return pp.Depth, nil
+}
+
 // DirectKeys returns a slice containing the directly pinned keys
 func (p *pinner) DirectKeys(ctx context.Context, detailed bool) <-chan ipfspinner.StreamedPin {
 	return p.streamIndex(ctx, p.cidDIndex, detailed)
@@ -726,6 +810,7 @@ func (p *pinner) streamIndex(ctx context.Context, index dsindex.Indexer, detaile
 				pin.Key = pp.Cid
 				pin.Mode = pp.Mode
 				pin.Name = pp.Name
+				pin.Depth = pp.Depth
 			} else {
 				pin.Key = c
 			}
@@ -786,22 +871,27 @@ func (p *pinner) Update(ctx context.Context, from, to cid.Cid, unpin bool) error
 		return errors.New("'to' cid was already recursively pinned")
 	}
 
-	// Temporarily unlock while we fetch the differences.
-	p.lock.Unlock()
-	err = dagutils.DiffEnumerate(ctx, p.dserv, from, to)
-	p.lock.Lock()
-
+	// Get pin information so that we can keep the name and depth.
+	pin, err := p.loadPin(ctx, fromValues[0])
 	if err != nil {
 		return err
 	}
 
-	// Get pin information so that we can keep the name.
-	pin, err := p.loadPin(ctx, fromValues[0])
+	// Temporarily unlock while we fetch the differences.
+	p.lock.Unlock()
+	if pin.Depth > 0 {
+		// The differences may be deeper than the new pin protects.
+		err = fetchGraph(ctx, to, pin.Depth, p.dserv)
+	} else {
+		err = dagutils.DiffEnumerate(ctx, p.dserv, from, to)
+	}
+	p.lock.Lock()
+
 	if err != nil {
 		return err
 	}
 
-	_, err = p.addPin(ctx, to, ipfspinner.Recursive, pin.Name)
+	_, err = p.addPin(ctx, to, ipfspinner.Recursive, pin.Name, pin.Depth)
 	if err != nil {
 		return err
 	}
@@ -858,7 +948,7 @@ func (p *pinner) PinWithMode(ctx context.Context, c cid.Cid, mode ipfspinner.Mod
 	// TODO: remove his to support multiple pins per CID
 	switch mode {
 	case ipfspinner.Recursive:
-		return p.doPinRecursive(ctx, c, false, name)
+		return p.doPinRecursive(ctx, c, false, name, 0)
 	case ipfspinner.Direct:
 		return p.doPinDirect(ctx, c, name)
 	default:
@@ -892,6 +982,49 @@ func hasChild(ctx context.Context, ng ipld.NodeGetter, root cid.Cid, child cid.C
 	return false, nil
 }
 
+// hasChildWithinDepth looks for a Cid among the descendants of a root Cid,
+// up to the given depth.
+func hasChildWithinDepth(ctx context.Context, ng ipld.NodeGetter, root cid.Cid, child cid.Cid, maxDepth int) (bool, error) {
+	// visited keeps the smallest depth each node was reached at, as a node
+	// reached again closer to the root may have more descendants in range.
+	visited := make(map[cid.Cid]int)
+
+	var search func(c cid.Cid, depth int) (bool, error)
+	search = func(c cid.Cid, depth int) (bool, error) {
+		links, err := ipld.GetLinks(ctx, ng, c)
+		if err != nil {
+			return false, err
+		}
+		for _, lnk := range links {
+			if lnk.Cid.Equals(child) {
+				return true, nil
+			}
+			if depth+1 >= maxDepth {
+				continue
+			}
+			if d, ok := visited[lnk.Cid]; ok && d <= depth+1 {
+				continue
+			}
+			visited[lnk.Cid] = depth + 1
+			has, err := search(lnk.Cid, depth+1)
+			if err != nil || has {
+				return has, err
+			}
+		}
+		return false, nil
+	}
+	return search(root, 0)
+}
+
+// fetchGraph fetches the graph under root up to the given depth, or the
+// whole graph when depth is 0.
+func fetchGraph(ctx context.Context, root cid.Cid, depth int, dserv ipld.DAGService) error {
+	if depth > 0 {
+		return merkledag.FetchGraphWithDepthLimit(ctx, root, depth, dserv)
+	}
+	return merkledag.FetchGraph(ctx, root, dserv)
+}
+
 func encodePin(p *pin) ([]byte, error) {
 	b, err := cbor.MarshalAtlased(p, pinAtl)
 	if err != nil {
@@ -364,7 +364,7 @@ func TestAddLoadPin(t *testing.T) {
 
 	mode := ipfspin.Recursive
 	name := "my-pin"
-	pid, err := p.addPin(ctx, ak, mode, name)
+	pid, err := p.addPin(ctx, ak, mode, name, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -667,6 +667,80 @@ func TestPinUpdate(t *testing.T) {
 	assertPinned(t, p, c1, "c1 should be pinned now")
 }
 
+func TestPinWithDepth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dstore, dserv := makeStore()
+	p, err := New(ctx, dstore, dserv)
+	require.NoError(t, err)
+
+	// n0 -> n1 -> n2 -> n3 -> n4
+	//  |          |
+	//  +--> n3    +--> n5
+	n4, c4 := randNode()
+	n5, c5 := randNode()
+	n3, _ := randNode()
+	require.NoError(t, n3.AddNodeLink("child", n4))
+	n2, _ := randNode()
+	require.NoError(t, n2.AddNodeLink("child", n3))
+	require.NoError(t, n2.AddNodeLink("other", n5))
+	n1, _ := randNode()
+	require.NoError(t, n1.AddNodeLink("child", n2))
+	n0, _ := randNode()
+	require.NoError(t, n0.AddNodeLink("child", n1))
+	require.NoError(t, n0.AddNodeLink("shortcut", n3))
+	c0 := n0.Cid()
+	for _, nd := range []ipld.Node{n1, n2, n3, n4, n5} {
+		require.NoError(t, dserv.Add(ctx, nd))
+	}
+
+	require.Error(t, p.PinWithDepth(ctx, n0, -1, ""))
+	require.NoError(t, p.PinWithDepth(ctx, n0, 2, "shallow"))
+
+	assertPinnedWithType(t, p, c0, ipfspin.Recursive, "root should be pinned")
+	for _, nd := range []ipld.Node{n1, n2, n3, n4} {
+		assertPinned(t, p, nd.Cid(), "node within depth should be pinned")
+	}
+	assertUnpinned(t, p, c5, "node below depth should not be pinned")
+
+	pinned, err := p.CheckIfPinned(ctx, c4, c5)
+	require.NoError(t, err)
+	require.Len(t, pinned, 2)
+	for _, pn := range pinned {
+		if pn.Key == c4 {
+			require.Equal(t, ipfspin.Indirect, pn.Mode)
+			require.Equal(t, c0, pn.Via)
+		} else {
+			require.Equal(t, ipfspin.NotPinned, pn.Mode)
+		}
+	}
+
+	pins := allPins(t, p.RecursiveKeys(ctx, true))
+	require.Len(t, pins, 1)
+	require.Equal(t, c0, pins[0].Key)
+	require.Equal(t, "shallow", pins[0].Name)
+	require.Equal(t, 2, pins[0].Depth)
+
+	// Updating keeps the depth
+	require.NoError(t, p.Update(ctx, c0, n1.Cid(), true))
+	pins = allPins(t, p.RecursiveKeys(ctx, true))
+	require.Len(t, pins, 1)
+	require.Equal(t, 2, pins[0].Depth)
+	assertPinned(t, p, c5, "node within depth of the new pin should be pinned")
+	assertUnpinned(t, p, c4, "node below depth of the new pin should not be pinned")
+
+	// Pinning the whole DAG again removes the limit
+	require.NoError(t, p.Pin(ctx, n0, true, ""))
+	assertPinned(t, p, c4, "node should be pinned by the unlimited pin")
+	pins = allPins(t, p.RecursiveKeys(ctx, true))
+	for _, pn := range pins {
+		if pn.Key == c0 {
+			require.Zero(t, pn.Depth)
+		}
+	}
+}
+
 func TestLoadDirty(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -778,7 +852,7 @@ func TestLoadDirty(t *testing.T) {
 func TestEncodeDecodePin(t *testing.T) {
 	_, c := randNode()
 
-	pin := newPin(c, ipfspin.Recursive, "testpin")
+	pin := newPin(c, ipfspin.Recursive, "testpin", 0)
 	pin.Metadata = make(map[string]interface{}, 2)
 	pin.Metadata["hello"] = "world"
 	pin.Metadata["foo"] = "bar"
@@ -1210,7 +1284,7 @@ func TestCidIndex(t *testing.T) {
 	cidKey := c.KeyString()
 
 	// Pin the cid
-	pid, err := pinner.addPin(ctx, c, ipfspin.Recursive, "")
+	pid, err := pinner.addPin(ctx, c, ipfspin.Recursive, "", 0)
 	if err != nil {
 		t.Fatal(err)
 	}
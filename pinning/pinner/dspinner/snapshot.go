@@ -0,0 +1,198 @@
+package dspinner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/polydawn/refmt/cbor"
+
+	ipfspinner "github.com/ipfs/boxo/pinning/pinner"
+)
+
+// snapshotVersion is the version of the pinset snapshot format written by
+// ExportPins.
+const snapshotVersion = 1
+
+// A pinset snapshot is a sequence of CBOR items (RFC 8742): a snapshotHeader,
+// a snapshotRecord per pin, and a final snapshotRecord holding the number of
+// pins, which tells a complete snapshot from a truncated one.
+type snapshotHeader struct {
+	Version int
+}
+
+type snapshotRecord struct {
+	Pin *pin
+	End *snapshotEnd
+}
+
+type snapshotEnd struct {
+	Count int
+}
+
+// ErrInvalidSnapshot is returned by ImportPins when the snapshot is truncated
+// or is not a pinset snapshot.
+var ErrInvalidSnapshot = errors.New("invalid pinset snapshot")
+
+// ExportPins writes a snapshot of all the pins, with their mode, name, depth
+// and metadata, to w. It returns the number of pins written. The pinner is
+// read-locked while the snapshot is written.
+//
+// The snapshot only holds the pins, the blocks they protect must be copied
+// separately, for example as CAR files.
+func (p *pinner) ExportPins(ctx context.Context, w io.Writer) (int, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	results, err := p.dstore.Query(ctx, query.Query{Prefix: pinKeyPath})
+	if err != nil {
+		return 0, err
+	}
+	defer results.Close()
+
+	bw := bufio.NewWriter(w)
+	enc := cbor.NewMarshallerAtlased(bw, pinAtl)
+	if err = enc.Marshal(&snapshotHeader{Version: snapshotVersion}); err != nil {
+		return 0, err
+	}
+
+	var count int
+	for r := range results.Next() {
+		if r.Error != nil {
+			return count, fmt.Errorf("cannot read pin: %w", r.Error)
+		}
+		pp, err := decodePin(path.Base(r.Key), r.Value)
+		if err != nil {
+			return count, err
+		}
+		if err = enc.Marshal(&snapshotRecord{Pin: pp}); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err = enc.Marshal(&snapshotRecord{End: &snapshotEnd{Count: count}}); err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// ImportPins adds the pins of a snapshot written by ExportPins. It returns
+// the number of pins added.
+//
+// The whole snapshot is read and checked before any pin is added, and the
+// pins already added are removed if one cannot be, so the pinset is left
+// unchanged on error. The pinner is locked during the import. Blocks are not
+// fetched: the DAGs of the pins must be imported separately.
+//
+// CIDs that are already pinned recursively, or directly for direct pins, keep
+// their current pin. A recursive pin replaces the direct pin of its CID.
+func (p *pinner) ImportPins(ctx context.Context, r io.Reader) (int, error) {
+	pins, err := readSnapshot(r)
+	if err != nil {
+		return 0, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var added []*pin
+	var replaced []cid.Cid
+	for _, pp := range pins {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		var skip bool
+		skip, err = p.cidRIndex.HasAny(ctx, pp.Cid.KeyString())
+		if err != nil {
+			break
+		}
+		if skip {
+			continue
+		}
+		var direct bool
+		direct, err = p.cidDIndex.HasAny(ctx, pp.Cid.KeyString())
+		if err != nil {
+			break
+		}
+		if direct {
+			if pp.Mode == ipfspinner.Direct {
+				continue
+			}
+			replaced = append(replaced, pp.Cid)
+		}
+
+		pp.Id = path.Base(ds.RandomKey().String())
+		if err = p.storePin(ctx, pp); err != nil {
+			break
+		}
+		added = append(added, pp)
+	}
+	if err != nil {
+		// Use a context that is not canceled to undo the import.
+		rctx := context.WithoutCancel(ctx)
+		for i := len(added) - 1; i >= 0; i-- {
+			if e := p.removePin(rctx, added[i]); e != nil {
+				log.Errorf("error removing imported pin: %s", e)
+			}
+		}
+		if e := p.flushPins(rctx, false); e != nil {
+			log.Errorf("error flushing pins: %s", e)
+		}
+		return 0, err
+	}
+
+	// The direct pins replaced by recursive pins are removed once all the pins
+	// are added, as the recursive pins keep the blocks pinned.
+	for _, c := range replaced {
+		if _, err = p.removePinsForCid(ctx, c, ipfspinner.Direct); err != nil {
+			return len(added), err
+		}
+	}
+
+	return len(added), p.flushPins(ctx, false)
+}
+
+// readSnapshot reads and validates all the pins of a snapshot.
+func readSnapshot(r io.Reader) ([]*pin, error) {
+	dec := cbor.NewUnmarshallerAtlased(cbor.DecodeOptions{}, bufio.NewReader(r), pinAtl)
+
+	var hdr snapshotHeader
+	if err := dec.Unmarshal(&hdr); err != nil {
+		return nil, fmt.Errorf("%w: cannot read header: %w", ErrInvalidSnapshot, err)
+	}
+	if hdr.Version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, hdr.Version)
+	}
+
+	var pins []*pin
+	for {
+		var rec snapshotRecord
+		if err := dec.Unmarshal(&rec); err != nil {
+			return nil, fmt.Errorf("%w: cannot read pin %d: %w", ErrInvalidSnapshot, len(pins), err)
+		}
+		if rec.End != nil {
+			if rec.End.Count != len(pins) {
+				return nil, fmt.Errorf("%w: expected %d pins, read %d", ErrInvalidSnapshot, rec.End.Count, len(pins))
+			}
+			return pins, nil
+		}
+		pp := rec.Pin
+		if pp == nil || !pp.Cid.Defined() {
+			return nil, fmt.Errorf("%w: pin %d has no cid", ErrInvalidSnapshot, len(pins))
+		}
+		if pp.Mode != ipfspinner.Recursive && pp.Mode != ipfspinner.Direct {
+			return nil, fmt.Errorf("%w: pin %d has invalid mode %d", ErrInvalidSnapshot, len(pins), pp.Mode)
+		}
+		if pp.Depth < 0 || (pp.Mode == ipfspinner.Direct && pp.Depth != 0) {
+			return nil, fmt.Errorf("%w: pin %d has invalid depth %d", ErrInvalidSnapshot, len(pins), pp.Depth)
+		}
+		pins = append(pins, pp)
+	}
+}
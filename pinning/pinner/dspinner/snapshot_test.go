@@ -0,0 +1,91 @@
+package dspinner
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	ipfspin "github.com/ipfs/boxo/pinning/pinner"
+)
+
+func TestExportImportPins(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dstore, dserv := makeStore()
+	src, err := New(ctx, dstore, dserv)
+	require.NoError(t, err)
+
+	n0, c0 := randNode()
+	n1, c1 := randNode()
+	require.NoError(t, n0.AddNodeLink("child", n1))
+	c0 = n0.Cid()
+	n2, c2 := randNode()
+	require.NoError(t, dserv.Add(ctx, n1))
+	require.NoError(t, src.PinWithDepth(ctx, n0, 1, "tree"))
+	require.NoError(t, src.Pin(ctx, n2, false, "direct"))
+
+	// metadata has no API yet, but is kept by snapshots
+	src.lock.Lock()
+	meta := newPin(c1, ipfspin.Direct, "", 0)
+	meta.Metadata = map[string]interface{}{"source": "test"}
+	require.NoError(t, src.storePin(ctx, meta))
+	src.lock.Unlock()
+
+	var snap bytes.Buffer
+	n, err := src.ExportPins(ctx, &snap)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	// the destination has a direct pin replaced by the recursive pin, and a
+	// direct pin already imported
+	dstore2, dserv2 := makeStore()
+	dst, err := New(ctx, dstore2, dserv2)
+	require.NoError(t, err)
+	require.NoError(t, dst.PinWithMode(ctx, c0, ipfspin.Direct, "old"))
+	require.NoError(t, dst.PinWithMode(ctx, c2, ipfspin.Direct, "kept"))
+
+	// a truncated snapshot leaves the pinset unchanged
+	_, err = dst.ImportPins(ctx, bytes.NewReader(snap.Bytes()[:snap.Len()-1]))
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+	_, err = dst.ImportPins(ctx, bytes.NewReader([]byte("not a snapshot")))
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+	require.Len(t, allPins(t, dst.DirectKeys(ctx, false)), 2)
+	require.Empty(t, allPins(t, dst.RecursiveKeys(ctx, false)))
+
+	n, err = dst.ImportPins(ctx, bytes.NewReader(snap.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	pins := allPins(t, dst.RecursiveKeys(ctx, true))
+	require.Len(t, pins, 1)
+	require.Equal(t, c0, pins[0].Key)
+	require.Equal(t, "tree", pins[0].Name)
+	require.Equal(t, 1, pins[0].Depth)
+
+	pins = allPins(t, dst.DirectKeys(ctx, true))
+	require.Len(t, pins, 2)
+	for _, pn := range pins {
+		switch pn.Key {
+		case c1:
+		case c2:
+			require.Equal(t, "kept", pn.Name)
+		default:
+			t.Fatalf("unexpected direct pin %s", pn.Key)
+		}
+	}
+
+	ids, err := dst.cidDIndex.Search(ctx, c1.KeyString())
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	pp, err := dst.loadPin(ctx, ids[0])
+	require.NoError(t, err)
+	require.Equal(t, "test", pp.Metadata["source"])
+
+	// importing again is a no-op
+	n, err = dst.ImportPins(ctx, bytes.NewReader(snap.Bytes()))
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
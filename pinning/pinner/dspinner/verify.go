@@ -0,0 +1,184 @@
+package dspinner
+
+import (
+	"context"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+
+	"github.com/ipfs/boxo/blockservice"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	ipfspinner "github.com/ipfs/boxo/pinning/pinner"
+)
+
+// verifyCacheSize is the number of whole DAGs whose bad nodes are remembered
+// by Verify, so that the DAGs shared by several pins are not checked again.
+const verifyCacheSize = 64 << 10
+
+// Verify checks the blocks of all the recursive and direct pins, see
+// [ipfspinner.Pinner.Verify]. The pins are listed first, so that pinning is
+// not blocked while their DAGs are walked.
+func (p *pinner) Verify(ctx context.Context, opts ipfspinner.VerifyOptions) <-chan ipfspinner.VerifiedPin {
+	out := make(chan ipfspinner.VerifiedPin)
+
+	go func() {
+		defer close(out)
+
+		send := func(v ipfspinner.VerifiedPin) bool {
+			select {
+			case out <- v:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var pins []ipfspinner.Pinned
+		var listErr error
+		for _, ch := range []<-chan ipfspinner.StreamedPin{p.RecursiveKeys(ctx, true), p.DirectKeys(ctx, true)} {
+			// drain the channels even on errors, as they are not closed
+			// before their errors are read
+			for sp := range ch {
+				if sp.Err != nil {
+					if listErr == nil {
+						listErr = sp.Err
+					}
+					continue
+				}
+				pins = append(pins, sp.Pin)
+			}
+		}
+		if listErr != nil {
+			send(ipfspinner.VerifiedPin{Err: listErr})
+			return
+		}
+
+		complete, err := lru.New[cid.Cid, []ipfspinner.BadNode](verifyCacheSize)
+		if err != nil {
+			send(ipfspinner.VerifiedPin{Err: err})
+			return
+		}
+		v := &verifier{
+			ng:       opts.NodeGetter,
+			rehash:   opts.Rehash,
+			complete: complete,
+		}
+		if v.ng == nil {
+			v.ng = p.offlineNodeGetter()
+		}
+
+		for _, pin := range pins {
+			// direct pins only protect their root, and a depth of 0 protects
+			// the whole DAG of recursive pins
+			maxDepth := pin.Depth
+			if pin.Mode == ipfspinner.Direct {
+				maxDepth = 0
+			} else if maxDepth == 0 {
+				maxDepth = -1
+			}
+
+			v.blocks = 0
+			v.depths = make(map[cid.Cid]int)
+			bad := dedupBadNodes(v.check(ctx, pin.Key, 0, maxDepth))
+			if err := ctx.Err(); err != nil {
+				send(ipfspinner.VerifiedPin{Pin: pin, Err: err})
+				return
+			}
+			res := ipfspinner.VerifiedPin{Pin: pin, Blocks: v.blocks, BadNodes: bad}
+			if res.OK() && !opts.IncludeOK {
+				continue
+			}
+			if !send(res) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// offlineNodeGetter returns a node getter over the local blocks of the DAG
+// service of the pinner, so that missing blocks are reported rather than
+// fetched. The DAG service is used as is when its blocks cannot be reached.
+func (p *pinner) offlineNodeGetter() ipld.NodeGetter {
+	bs, ok := p.dserv.(interface {
+		BlockService() blockservice.BlockService
+	})
+	if !ok {
+		return p.dserv
+	}
+	return merkledag.NewDAGService(blockservice.New(bs.BlockService().Blockstore(), nil))
+}
+
+// verifier checks the blocks of pinned DAGs.
+type verifier struct {
+	ng     ipld.NodeGetter
+	rehash bool
+
+	// complete are the bad nodes of the whole DAGs recently checked, which
+	// are not checked again.
+	complete *lru.Cache[cid.Cid, []ipfspinner.BadNode]
+	// depths are the smallest depths at which the blocks of the current
+	// depth-limited pin were checked.
+	depths map[cid.Cid]int
+	// blocks is the number of blocks checked for the current pin.
+	blocks int
+}
+
+// check returns the bad nodes of the DAG of c, found at the given depth, down
+// to maxDepth, or the whole DAG if maxDepth is negative.
+func (v *verifier) check(ctx context.Context, c cid.Cid, depth, maxDepth int) []ipfspinner.BadNode {
+	whole := maxDepth < 0
+	if whole {
+		if bad, ok := v.complete.Get(c); ok {
+			return bad
+		}
+	} else {
+		// a block already checked at a smaller depth was checked with
+		// its links down to at least the same depth, and its bad nodes
+		// were already reported for this pin
+		if d, ok := v.depths[c]; ok && d <= depth {
+			return nil
+		}
+		v.depths[c] = depth
+	}
+
+	nd, err := v.ng.Get(ctx, c)
+	if err != nil {
+		bad := []ipfspinner.BadNode{{Cid: c, Err: err}}
+		if whole && ipld.IsNotFound(err) {
+			v.complete.Add(c, bad)
+		}
+		return bad
+	}
+	v.blocks++
+
+	var bad []ipfspinner.BadNode
+	if v.rehash {
+		if h, err := c.Prefix().Sum(nd.RawData()); err != nil || !h.Equals(c) {
+			bad = append(bad, ipfspinner.BadNode{Cid: c, Err: ipfspinner.ErrCorruptBlock})
+		}
+	}
+	if whole || depth < maxDepth {
+		for _, l := range nd.Links() {
+			bad = append(bad, v.check(ctx, l.Cid, depth+1, maxDepth)...)
+		}
+	}
+	if whole && ctx.Err() == nil {
+		v.complete.Add(c, bad)
+	}
+	return bad
+}
+
+// dedupBadNodes removes the bad nodes reached several times in a DAG.
+func dedupBadNodes(bad []ipfspinner.BadNode) []ipfspinner.BadNode {
+	seen := cid.NewSet()
+	var out []ipfspinner.BadNode
+	for _, b := range bad {
+		if seen.Visit(b.Cid) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package dspinner
+
+import (
+	"context"
+	"testing"
+
+	bs "github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	mdag "github.com/ipfs/boxo/ipld/merkledag"
+	ipfspin "github.com/ipfs/boxo/pinning/pinner"
+	blocks "github.com/ipfs/go-block-format"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	dstore := &batchWrap{dssync.MutexWrap(ds.NewMapDatastore())}
+	bstore := blockstore.NewBlockstore(dstore)
+	dserv := mdag.NewDAGService(bs.New(bstore, offline.Exchange(bstore)))
+	p, err := New(ctx, dstore, dserv)
+	require.NoError(t, err)
+
+	// a -> b -> c, a -> raw, direct d, and e -> f -> g pinned with depth 1
+	c, _ := randNode()
+	b, _ := randNode()
+	require.NoError(t, b.AddNodeLink("child", c))
+	raw := mdag.NewRawNode([]byte("raw leaf"))
+	a, _ := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	require.NoError(t, a.AddNodeLink("raw", raw))
+	d, _ := randNode()
+	g, _ := randNode()
+	f, _ := randNode()
+	require.NoError(t, f.AddNodeLink("child", g))
+	e, _ := randNode()
+	require.NoError(t, e.AddNodeLink("child", f))
+	require.NoError(t, dserv.AddMany(ctx, []ipld.Node{a, b, c, raw, d, e, f, g}))
+
+	require.NoError(t, p.Pin(ctx, a, true, ""))
+	require.NoError(t, p.Pin(ctx, d, false, ""))
+	require.NoError(t, p.PinWithDepth(ctx, e, 1, ""))
+
+	verify := func(opts ipfspin.VerifyOptions) map[cid.Cid]ipfspin.VerifiedPin {
+		results := make(map[cid.Cid]ipfspin.VerifiedPin)
+		for v := range p.Verify(ctx, opts) {
+			require.NoError(t, v.Err)
+			results[v.Pin.Key] = v
+		}
+		return results
+	}
+
+	require.Empty(t, verify(ipfspin.VerifyOptions{}))
+	results := verify(ipfspin.VerifyOptions{IncludeOK: true})
+	require.Len(t, results, 3)
+	for _, v := range results {
+		require.True(t, v.OK())
+	}
+	require.Equal(t, 4, results[a.Cid()].Blocks)
+	require.Equal(t, 1, results[d.Cid()].Blocks)
+	require.Equal(t, 2, results[e.Cid()].Blocks)
+
+	// blocks below the depth of a pin are not checked
+	require.NoError(t, dserv.Remove(ctx, g.Cid()))
+	require.Empty(t, verify(ipfspin.VerifyOptions{}))
+
+	require.NoError(t, dserv.Remove(ctx, c.Cid()))
+	require.NoError(t, dserv.Remove(ctx, d.Cid()))
+	results = verify(ipfspin.VerifyOptions{})
+	require.Len(t, results, 2)
+	require.Len(t, results[a.Cid()].BadNodes, 1)
+	require.Equal(t, c.Cid(), results[a.Cid()].BadNodes[0].Cid)
+	require.True(t, ipld.IsNotFound(results[a.Cid()].BadNodes[0].Err))
+	require.Len(t, results[d.Cid()].BadNodes, 1)
+	require.Equal(t, d.Cid(), results[d.Cid()].BadNodes[0].Cid)
+	require.Equal(t, ipfspin.Direct, results[d.Cid()].Pin.Mode)
+
+	// corrupt blocks are only found when re-hashing
+	require.NoError(t, dserv.Add(ctx, c))
+	require.NoError(t, dserv.Add(ctx, d))
+	require.NoError(t, bstore.DeleteBlock(ctx, raw.Cid()))
+	corrupt, err := blocks.NewBlockWithCid([]byte("corrupt"), raw.Cid())
+	require.NoError(t, err)
+	require.NoError(t, bstore.Put(ctx, corrupt))
+	require.Empty(t, verify(ipfspin.VerifyOptions{}))
+	results = verify(ipfspin.VerifyOptions{Rehash: true})
+	require.Len(t, results, 1)
+	require.Equal(t, []ipfspin.BadNode{{Cid: raw.Cid(), Err: ipfspin.ErrCorruptBlock}}, results[a.Cid()].BadNodes)
+}
+
+func TestVerifyOffline(t *testing.T) {
+	ctx := context.Background()
+	dstore := &batchWrap{dssync.MutexWrap(ds.NewMapDatastore())}
+	bstore := blockstore.NewBlockstore(dstore)
+	// the exchange gets the blocks missing locally from another blockstore
+	remote := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	dserv := mdag.NewDAGService(bs.New(bstore, offline.Exchange(remote)))
+	p, err := New(ctx, dstore, dserv)
+	require.NoError(t, err)
+
+	// a -> b -> c and a -> c, with c only in the remote blockstore
+	c, _ := randNode()
+	b, _ := randNode()
+	require.NoError(t, b.AddNodeLink("child", c))
+	a, _ := randNode()
+	require.NoError(t, a.AddNodeLink("child", b))
+	require.NoError(t, a.AddNodeLink("other", c))
+	require.NoError(t, dserv.AddMany(ctx, []ipld.Node{a, b}))
+	require.NoError(t, remote.Put(ctx, c))
+	require.NoError(t, p.PinWithDepth(ctx, a, 2, ""))
+	// pinning fetched c
+	require.NoError(t, bstore.DeleteBlock(ctx, c.Cid()))
+
+	var results []ipfspin.VerifiedPin
+	for v := range p.Verify(ctx, ipfspin.VerifyOptions{}) {
+		require.NoError(t, v.Err)
+		results = append(results, v)
+	}
+	require.Len(t, results, 1)
+	require.Len(t, results[0].BadNodes, 1)
+	require.Equal(t, c.Cid(), results[0].BadNodes[0].Cid)
+	require.True(t, ipld.IsNotFound(results[0].BadNodes[0].Err))
+	// c is only checked once, at depth 1
+	require.Equal(t, 2, results[0].Blocks)
+}
@@ -98,6 +98,12 @@ type Pinner interface {
 	// pin must replace the existing name.
 	Pin(ctx context.Context, node ipld.Node, recursive bool, name string) error
 
+	// PinWithDepth pins the given node recursively, but only protects its
+	// descendants up to the given depth: 1 pins the node and its direct
+	// children, 2 their children too, and so on. A depth of 0 pins the whole
+	// DAG like a regular recursive pin.
+	PinWithDepth(ctx context.Context, node ipld.Node, depth int, name string) error
+
 	// Unpin the given cid. If recursive is true, removes either a recursive or
 	// a direct pin. If recursive is false, only removes a direct pin.
 	// If the pin doesn't exist, return ErrNotPinned
@@ -123,12 +129,21 @@ type Pinner interface {
 	// DirectKeys returns all directly pinned cids
 	DirectKeys(ctx context.Context, detailed bool) <-chan StreamedPin
 
-	// RecursiveKeys returns all recursively pinned cids
+	// RecursiveKeys returns all recursively pinned cids. Recursive pins with
+	// a non-zero Depth only protect the descendants up to that depth, so a
+	// garbage collector must request detailed pins and limit its walk
+	// accordingly.
 	RecursiveKeys(ctx context.Context, detailed bool) <-chan StreamedPin
 
 	// InternalPins returns all cids kept pinned for the internal state of the
 	// pinner
 	InternalPins(ctx context.Context, detailed bool) <-chan StreamedPin
+
+	// Verify checks that the blocks of all the recursive and direct pins are
+	// present, for instance before a garbage collection or after datastore
+	// corruption. It streams a result per broken pin, with its missing or
+	// corrupt blocks, or per pin with VerifyOptions.IncludeOK.
+	Verify(ctx context.Context, opts VerifyOptions) <-chan VerifiedPin
 }
 
 // Pinned represents CID which has been pinned with a pinning strategy.
@@ -140,6 +155,10 @@ type Pinned struct {
 	Mode Mode
 	Name string
 	Via  cid.Cid
+
+	// Depth is the depth limit of a recursive pin, as set with
+	// PinWithDepth. It is 0 when the whole DAG is pinned.
+	Depth int
 }
 
 // Pinned returns whether or not the given cid is pinned
@@ -160,6 +179,56 @@ func (p Pinned) String() string {
 	}
 }
 
+// ErrCorruptBlock is the error of the [BadNode]s whose data does not match
+// their CID, see VerifyOptions.Rehash.
+var ErrCorruptBlock = errors.New("block data does not match its CID")
+
+// VerifyOptions configures [Pinner.Verify].
+type VerifyOptions struct {
+	// NodeGetter gets the pinned blocks. It should only get local blocks, for
+	// instance with an offline exchange, so that missing blocks are reported
+	// rather than fetched. It defaults to an offline DAG service over the
+	// blockstore of the DAG service of the pinner.
+	NodeGetter ipld.NodeGetter
+
+	// Rehash re-hashes the data of the blocks to check that it matches their
+	// CID.
+	Rehash bool
+
+	// IncludeOK streams the results of the valid pins too, which can be used
+	// to report progress.
+	IncludeOK bool
+}
+
+// BadNode is a missing or corrupt block of a pinned DAG.
+type BadNode struct {
+	Cid cid.Cid
+	// Err is the error getting the block, which matches [ipld.ErrNotFound]
+	// for missing blocks, or [ErrCorruptBlock].
+	Err error
+}
+
+// VerifiedPin is the result of the verification of a pin by [Pinner.Verify].
+type VerifiedPin struct {
+	Pin Pinned
+
+	// Blocks is the number of blocks of the pin which were checked. The
+	// blocks shared with the pins verified before are not checked again.
+	Blocks int
+
+	// BadNodes are the missing or corrupt blocks of the pin. The
+	// descendants of missing blocks cannot be checked.
+	BadNodes []BadNode
+
+	// Err is set when the verification failed, in which case it stops.
+	Err error
+}
+
+// OK returns whether all the blocks of the pin are present and valid.
+func (v VerifiedPin) OK() bool {
+	return v.Err == nil && len(v.BadNodes) == 0
+}
+
 // StreamedPin encapsulate a [Pin] and an error for a function to return a channel of [Pin]s.
 type StreamedPin struct {
 	Pin Pinned
@@ -0,0 +1,411 @@
+package go_pinning_service_http_client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	defaultBulkConcurrency = 8
+	defaultBulkRetries     = 3
+	defaultBulkMinBackoff  = time.Second
+	defaultBulkMaxBackoff  = time.Minute
+)
+
+type bulkSettings struct {
+	concurrency int
+	retries     int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	journal     string
+	addOpts     []AddOption
+}
+
+type BulkOption func(options *bulkSettings) error
+
+type pinBulkOpts struct{}
+
+// BulkConcurrency sets how many pin requests AddBulk may have in flight at
+// the same time.
+func (pinBulkOpts) BulkConcurrency(n int) BulkOption {
+	return func(options *bulkSettings) error {
+		if n < 1 {
+			return fmt.Errorf("concurrency must be at least 1, got %d", n)
+		}
+		options.concurrency = n
+		return nil
+	}
+}
+
+// BulkRetries sets how many times a pin request is retried after a transient
+// failure, such as a network error, an HTTP 429 or an HTTP 5xx response. As
+// the pin may have been created despite the failure, it is looked up by CID
+// and name before every retry, and the request is only sent again if the
+// pinning service has no such pin.
+func (pinBulkOpts) BulkRetries(n int) BulkOption {
+	return func(options *bulkSettings) error {
+		if n < 0 {
+			return fmt.Errorf("retries cannot be negative, got %d", n)
+		}
+		options.retries = n
+		return nil
+	}
+}
+
+// BulkBackoff sets the delay before the first retry of a pin request. The
+// delay is doubled for every following retry, up to max.
+func (pinBulkOpts) BulkBackoff(min, max time.Duration) BulkOption {
+	return func(options *bulkSettings) error {
+		if min <= 0 || max < min {
+			return fmt.Errorf("invalid backoff range [%s, %s]", min, max)
+		}
+		options.minBackoff = min
+		options.maxBackoff = max
+		return nil
+	}
+}
+
+// BulkJournal sets a file in which every successfully pinned CID is recorded.
+// When AddBulk is called again with the same journal, for example after an
+// interruption, the CIDs found in it are not sent to the pinning service again.
+func (pinBulkOpts) BulkJournal(path string) BulkOption {
+	return func(options *bulkSettings) error {
+		options.journal = path
+		return nil
+	}
+}
+
+// BulkAddOptions sets the options used for every pin request sent by AddBulk.
+func (pinBulkOpts) BulkAddOptions(opts ...AddOption) BulkOption {
+	return func(options *bulkSettings) error {
+		options.addOpts = append(options.addOpts, opts...)
+		return nil
+	}
+}
+
+// BulkResult is the outcome of pinning a single CID with AddBulk.
+type BulkResult struct {
+	Cid cid.Cid
+	// RequestID of the pin request, empty if it failed.
+	RequestID string
+	// Status of the pin request, StatusUnknown if it failed or was resumed
+	// from the journal.
+	Status Status
+	// PinStatus returned by the pinning service, nil if the request failed
+	// or was resumed from the journal.
+	PinStatus PinStatusGetter
+	// Resumed is true when the CID was already pinned according to the
+	// journal and no request was sent.
+	Resumed bool
+	// Attempts is the number of pin requests sent for this CID.
+	Attempts int
+	Err      error
+}
+
+type journalEntry struct {
+	Cid       string `json:"cid"`
+	RequestID string `json:"requestid"`
+}
+
+// AddBulk pins every CID in cids. Results are sent on the first channel, one
+// per unique CID, in no particular order. Failures of individual CIDs are
+// reported in BulkResult.Err, while errors stopping the whole operation are
+// sent on the second channel. Both channels are closed once all CIDs have
+// been processed.
+func (c *Client) AddBulk(ctx context.Context, cids []cid.Cid, opts ...BulkOption) (chan BulkResult, chan error) {
+	res := make(chan BulkResult, 1)
+	errs := make(chan error, 1)
+
+	settings := &bulkSettings{
+		concurrency: defaultBulkConcurrency,
+		retries:     defaultBulkRetries,
+		minBackoff:  defaultBulkMinBackoff,
+		maxBackoff:  defaultBulkMaxBackoff,
+	}
+	fail := func(err error) (chan BulkResult, chan error) {
+		close(res)
+		errs <- err
+		close(errs)
+		return res, errs
+	}
+	for _, o := range opts {
+		if err := o(settings); err != nil {
+			return fail(err)
+		}
+	}
+
+	addSettings := new(addSettings)
+	for _, o := range settings.addOpts {
+		if err := o(addSettings); err != nil {
+			return fail(err)
+		}
+	}
+
+	var journal *bulkJournal
+	done := map[cid.Cid]string{}
+	if settings.journal != "" {
+		var err error
+		journal, done, err = openBulkJournal(settings.journal)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(res)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var (
+			wg       sync.WaitGroup
+			errOnce  sync.Once
+			firstErr error
+		)
+		stop := func(err error) {
+			errOnce.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+
+		todo := make(chan cid.Cid)
+		for i := 0; i < settings.concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := range todo {
+					r := c.addWithRetries(ctx, k, addSettings, settings)
+					if r.Err == nil && journal != nil {
+						if err := journal.Record(k, r.RequestID); err != nil {
+							stop(err)
+						}
+					}
+					select {
+					case res <- r:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		seen := make(map[cid.Cid]struct{}, len(cids))
+	loop:
+		for _, k := range cids {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+
+			if requestID, ok := done[k]; ok {
+				select {
+				case res <- BulkResult{Cid: k, RequestID: requestID, Resumed: true}:
+					continue
+				case <-ctx.Done():
+					break loop
+				}
+			}
+
+			select {
+			case todo <- k:
+			case <-ctx.Done():
+				break loop
+			}
+		}
+		close(todo)
+		wg.Wait()
+
+		err := firstErr
+		if err == nil {
+			err = ctx.Err()
+		}
+		if journal != nil {
+			if cerr := journal.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return res, errs
+}
+
+// AddBulkSync is like AddBulk but waits for all CIDs to be processed.
+func (c *Client) AddBulkSync(ctx context.Context, cids []cid.Cid, opts ...BulkOption) ([]BulkResult, error) {
+	resCh, errCh := c.AddBulk(ctx, cids, opts...)
+
+	var res []BulkResult
+	for r := range resCh {
+		res = append(res, r)
+	}
+
+	return res, <-errCh
+}
+
+func (c *Client) addWithRetries(ctx context.Context, k cid.Cid, addSettings *addSettings, settings *bulkSettings) BulkResult {
+	r := BulkResult{Cid: k}
+	backoff := settings.minBackoff
+	for retry := 0; ; retry++ {
+		var (
+			ps       PinStatusGetter
+			httpresp *http.Response
+			err      error
+		)
+		if retry > 0 {
+			// A failed request may have created the pin anyway, so look
+			// it up rather than sending a duplicate.
+			ps, err = c.findPin(ctx, k, addSettings.name)
+		}
+		if err == nil && ps == nil {
+			r.Attempts++
+			ps, httpresp, err = c.addInternal(ctx, k, addSettings)
+			if err != nil {
+				err = httperr(httpresp, err)
+			}
+		}
+		if err == nil {
+			r.RequestID = ps.GetRequestId()
+			r.Status = ps.GetStatus()
+			r.PinStatus = ps
+			r.Err = nil
+			return r
+		}
+		r.Err = err
+
+		if retry >= settings.retries || !isRetryable(ctx, httpresp) {
+			return r
+		}
+		logger.Debugf("pinning %s failed, retrying in %s: %s", k, backoff, r.Err)
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return r
+		}
+
+		backoff *= 2
+		if backoff > settings.maxBackoff {
+			backoff = settings.maxBackoff
+		}
+	}
+}
+
+// findPin returns the pin of k with the given name which is not failed, or nil
+// if the pinning service has none.
+func (c *Client) findPin(ctx context.Context, k cid.Cid, name string) (PinStatusGetter, error) {
+	opts := []LsOption{
+		PinOpts.FilterCIDs(k),
+		PinOpts.FilterStatus(StatusQueued, StatusPinning, StatusPinned),
+		PinOpts.Limit(1),
+	}
+	if name != "" {
+		opts = append(opts, PinOpts.FilterName(name))
+	}
+	pins, _, err := c.LsBatchSync(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("looking up existing pin: %w", err)
+	}
+	if len(pins) == 0 {
+		return nil, nil
+	}
+	return pins[0], nil
+}
+
+func isRetryable(ctx context.Context, resp *http.Response) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if resp == nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// bulkJournal is an append-only file of JSON lines, one per pinned CID. A last
+// line left incomplete by an interruption is truncated when it is opened, so
+// that the next entry starts on its own line.
+type bulkJournal struct {
+	lk sync.Mutex
+	f  *os.File
+}
+
+func openBulkJournal(path string) (*bulkJournal, map[cid.Cid]string, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening bulk pin journal: %w", err)
+	}
+
+	done := map[cid.Cid]string{}
+	var size int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// We were interrupted while recording the last entry.
+				logger.Warnf("truncating incomplete bulk pin journal entry")
+				if err := f.Truncate(size); err != nil {
+					f.Close()
+					return nil, nil, fmt.Errorf("truncating bulk pin journal: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("reading bulk pin journal: %w", err)
+		}
+		size += int64(len(line))
+
+		var e journalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			logger.Warnf("skipping invalid bulk pin journal entry: %s", err)
+			continue
+		}
+		c, err := cid.Decode(e.Cid)
+		if err != nil {
+			logger.Warnf("skipping invalid bulk pin journal entry: %s", err)
+			continue
+		}
+		done[c] = e.RequestID
+	}
+
+	return &bulkJournal{f: f}, done, nil
+}
+
+func (j *bulkJournal) Record(c cid.Cid, requestID string) error {
+	b, err := json.Marshal(journalEntry{Cid: c.Encode(getCIDEncoder()), RequestID: requestID})
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	if _, err := j.f.Write(b); err != nil {
+		return fmt.Errorf("writing bulk pin journal: %w", err)
+	}
+	return nil
+}
+
+func (j *bulkJournal) Close() error {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	return errors.Join(j.f.Sync(), j.f.Close())
+}
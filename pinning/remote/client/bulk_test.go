@@ -0,0 +1,284 @@
+package go_pinning_service_http_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/pinning/remote/client/openapi"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPinningService struct {
+	lk       sync.Mutex
+	requests map[string]int
+	// failures is the number of times a request for a given CID fails
+	// before succeeding.
+	failures map[string]int
+	// lost are the CIDs whose failing requests still create the pin, as if
+	// the response was lost.
+	lost   map[string]bool
+	status int
+	pins   map[string]openapi.PinStatus
+}
+
+func newMockPinningService(t *testing.T) (*mockPinningService, *Client) {
+	ps := &mockPinningService{
+		requests: map[string]int{},
+		failures: map[string]int{},
+		lost:     map[string]bool{},
+		status:   http.StatusInternalServerError,
+		pins:     map[string]openapi.PinStatus{},
+	}
+	ts := httptest.NewServer(ps)
+	t.Cleanup(ts.Close)
+	return ps, NewClient(ts.URL, "secret")
+}
+
+func (ps *mockPinningService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		ps.serveLs(w, r)
+		return
+	}
+
+	var p openapi.Pin
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ps.lk.Lock()
+	ps.requests[p.Cid]++
+	n := ps.requests[p.Cid]
+	fail := n <= ps.failures[p.Cid]
+	status := ps.status
+	pin := openapi.PinStatus{
+		Requestid: fmt.Sprintf("%s-%d", p.Cid, n),
+		Status:    openapi.QUEUED,
+		Created:   time.Now(),
+		Pin:       p,
+		Delegates: []string{},
+	}
+	if !fail || ps.lost[p.Cid] {
+		ps.pins[p.Cid] = pin
+	}
+	ps.lk.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if fail {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(openapi.Failure{Error: openapi.FailureError{Reason: "FAILED"}})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(pin)
+}
+
+func (ps *mockPinningService) serveLs(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	results := []openapi.PinStatus{}
+
+	ps.lk.Lock()
+	for _, c := range strings.Split(r.URL.Query().Get("cid"), ",") {
+		if pin, ok := ps.pins[c]; ok && (name == "" || pin.Pin.GetName() == name) {
+			results = append(results, pin)
+		}
+	}
+	ps.lk.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(openapi.PinResults{Count: int32(len(results)), Results: results})
+}
+
+func (ps *mockPinningService) count(c cid.Cid) int {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+	return ps.requests[c.Encode(getCIDEncoder())]
+}
+
+func makeCids(t *testing.T, n int) []cid.Cid {
+	cids := make([]cid.Cid, n)
+	for i := range cids {
+		h, err := mh.Sum([]byte(fmt.Sprint(i)), mh.SHA2_256, -1)
+		require.NoError(t, err)
+		cids[i] = cid.NewCidV1(cid.Raw, h)
+	}
+	return cids
+}
+
+func TestAddBulk(t *testing.T) {
+	ps, c := newMockPinningService(t)
+	cids := makeCids(t, 20)
+	ps.failures[cids[0].Encode(getCIDEncoder())] = 2
+
+	// Duplicates are only pinned once.
+	res, err := c.AddBulkSync(context.Background(), append(cids, cids[1]),
+		PinOpts.BulkConcurrency(4),
+		PinOpts.BulkBackoff(time.Millisecond, 2*time.Millisecond),
+	)
+	require.NoError(t, err)
+	require.Len(t, res, len(cids))
+
+	for _, r := range res {
+		require.NoError(t, r.Err)
+		require.Equal(t, StatusQueued, r.Status)
+		require.NotEmpty(t, r.RequestID)
+		require.Equal(t, r.Attempts, ps.count(r.Cid))
+		if r.Cid == cids[0] {
+			require.Equal(t, 3, r.Attempts)
+		} else {
+			require.Equal(t, 1, r.Attempts)
+		}
+	}
+}
+
+func TestAddBulkRetriesExhausted(t *testing.T) {
+	ps, c := newMockPinningService(t)
+	cids := makeCids(t, 1)
+	ps.failures[cids[0].Encode(getCIDEncoder())] = 10
+
+	res, err := c.AddBulkSync(context.Background(), cids,
+		PinOpts.BulkRetries(2),
+		PinOpts.BulkBackoff(time.Millisecond, time.Millisecond),
+	)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Error(t, res[0].Err)
+	require.Equal(t, 3, res[0].Attempts)
+	require.Empty(t, res[0].RequestID)
+}
+
+func TestAddBulkDoesNotDuplicateLostPins(t *testing.T) {
+	ps, c := newMockPinningService(t)
+	cids := makeCids(t, 2)
+	for _, k := range cids {
+		ps.failures[k.Encode(getCIDEncoder())] = 1
+	}
+	lost := cids[0]
+	ps.lost[lost.Encode(getCIDEncoder())] = true
+
+	res, err := c.AddBulkSync(context.Background(), cids,
+		PinOpts.BulkBackoff(time.Millisecond, time.Millisecond),
+		PinOpts.BulkAddOptions(PinOpts.WithName("bulk")),
+	)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	for _, r := range res {
+		require.NoError(t, r.Err)
+		require.Equal(t, r.Attempts, ps.count(r.Cid))
+		if r.Cid == lost {
+			// the pin created by the failed request is found
+			require.Equal(t, 1, r.Attempts)
+			require.Equal(t, lost.Encode(getCIDEncoder())+"-1", r.RequestID)
+		} else {
+			require.Equal(t, 2, r.Attempts)
+		}
+	}
+}
+
+func TestAddBulkDoesNotRetryClientErrors(t *testing.T) {
+	ps, c := newMockPinningService(t)
+	ps.status = http.StatusBadRequest
+	cids := makeCids(t, 1)
+	ps.failures[cids[0].Encode(getCIDEncoder())] = 1
+
+	res, err := c.AddBulkSync(context.Background(), cids,
+		PinOpts.BulkBackoff(time.Millisecond, time.Millisecond),
+	)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Error(t, res[0].Err)
+	require.Equal(t, 1, res[0].Attempts)
+}
+
+func TestAddBulkJournal(t *testing.T) {
+	ps, c := newMockPinningService(t)
+	cids := makeCids(t, 10)
+	journal := filepath.Join(t.TempDir(), "journal")
+
+	// The first run fails on the last CID, simulating an interruption.
+	failing := cids[len(cids)-1]
+	ps.failures[failing.Encode(getCIDEncoder())] = 1
+
+	res, err := c.AddBulkSync(context.Background(), cids,
+		PinOpts.BulkRetries(0),
+		PinOpts.BulkJournal(journal),
+	)
+	require.NoError(t, err)
+	require.Len(t, res, len(cids))
+
+	requestIDs := map[cid.Cid]string{}
+	for _, r := range res {
+		if r.Cid == failing {
+			require.Error(t, r.Err)
+			continue
+		}
+		require.NoError(t, r.Err)
+		requestIDs[r.Cid] = r.RequestID
+	}
+
+	// The second run only pins the CID which failed.
+	res, err = c.AddBulkSync(context.Background(), cids,
+		PinOpts.BulkRetries(0),
+		PinOpts.BulkJournal(journal),
+	)
+	require.NoError(t, err)
+	require.Len(t, res, len(cids))
+
+	for _, r := range res {
+		require.NoError(t, r.Err)
+		if r.Cid == failing {
+			require.False(t, r.Resumed)
+			require.Equal(t, 2, ps.count(r.Cid))
+			continue
+		}
+		require.True(t, r.Resumed)
+		require.Zero(t, r.Attempts)
+		require.Equal(t, requestIDs[r.Cid], r.RequestID)
+		require.Equal(t, 1, ps.count(r.Cid))
+	}
+}
+
+func TestAddBulkJournalTruncated(t *testing.T) {
+	_, c := newMockPinningService(t)
+	cids := makeCids(t, 2)
+	journal := filepath.Join(t.TempDir(), "journal")
+
+	_, err := c.AddBulkSync(context.Background(), cids[:1], PinOpts.BulkJournal(journal))
+	require.NoError(t, err)
+
+	// An entry cut short by an interruption is dropped, and does not
+	// corrupt the next one.
+	f, err := os.OpenFile(journal, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"cid":"bafy`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = c.AddBulkSync(context.Background(), cids, PinOpts.BulkJournal(journal))
+	require.NoError(t, err)
+
+	res, err := c.AddBulkSync(context.Background(), cids, PinOpts.BulkJournal(journal))
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	for _, r := range res {
+		require.True(t, r.Resumed)
+	}
+}
+
+func TestAddBulkInvalidOption(t *testing.T) {
+	_, c := newMockPinningService(t)
+
+	_, err := c.AddBulkSync(context.Background(), makeCids(t, 1), PinOpts.BulkConcurrency(0))
+	require.Error(t, err)
+}
@@ -55,6 +55,8 @@ var PinOpts = pinOpts{}
 type pinOpts struct {
 	pinLsOpts
 	pinAddOpts
+	pinBulkOpts
+	pinWatchOpts
 }
 
 type pinLsOpts struct{}
@@ -328,6 +330,16 @@ func (c *Client) Add(ctx context.Context, cid cid.Cid, opts ...AddOption) (PinSt
 		}
 	}
 
+	result, httpresp, err := c.addInternal(ctx, cid, settings)
+	if err != nil {
+		err := httperr(httpresp, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *Client) addInternal(ctx context.Context, cid cid.Cid, settings *addSettings) (PinStatusGetter, *http.Response, error) {
 	adder := c.client.PinsApi.PinsPost(ctx)
 	p := openapi.Pin{
 		Cid: cid.Encode(getCIDEncoder()),
@@ -345,11 +357,10 @@ func (c *Client) Add(ctx context.Context, cid cid.Cid, opts ...AddOption) (PinSt
 
 	result, httpresp, err := adder.Pin(p).Execute()
 	if err != nil {
-		err := httperr(httpresp, err)
-		return nil, err
+		return nil, httpresp, err
 	}
 
-	return &pinStatusObject{result}, nil
+	return &pinStatusObject{result}, httpresp, nil
 }
 
 func (c *Client) GetStatusByID(ctx context.Context, pinID string) (PinStatusGetter, error) {
@@ -0,0 +1,331 @@
+package go_pinning_service_http_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ipfs/boxo/pinning/remote/client/openapi"
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	defaultWatchMinInterval = 2 * time.Second
+	defaultWatchMaxInterval = time.Minute
+
+	// maxLsCids is the maximum number of CIDs the pinning service API
+	// accepts in a single listing filter.
+	maxLsCids = 10
+)
+
+type watchSettings struct {
+	minInterval time.Duration
+	maxInterval time.Duration
+	callbacks   []func(StatusChange)
+	webhooks    []string
+	httpClient  *http.Client
+}
+
+type WatchOption func(options *watchSettings) error
+
+type pinWatchOpts struct{}
+
+// WatchInterval sets the delay between two polls of the pinning service.
+// Watch polls every min while pins change status, and doubles the delay,
+// up to max, every time nothing changed. Delays are randomized by up to
+// half of their value so that many watchers do not poll in lockstep.
+func (pinWatchOpts) WatchInterval(min, max time.Duration) WatchOption {
+	return func(options *watchSettings) error {
+		if min <= 0 || max < min {
+			return fmt.Errorf("invalid watch interval range [%s, %s]", min, max)
+		}
+		options.minInterval = min
+		options.maxInterval = max
+		return nil
+	}
+}
+
+// OnStatusChange registers a function called, from the goroutine running
+// Watch, every time a pin changes status.
+func (pinWatchOpts) OnStatusChange(fn func(StatusChange)) WatchOption {
+	return func(options *watchSettings) error {
+		if fn == nil {
+			return fmt.Errorf("status change callback cannot be nil")
+		}
+		options.callbacks = append(options.callbacks, fn)
+		return nil
+	}
+}
+
+// StatusWebhook registers a URL to which every status change is POSTed as
+// JSON. Failed deliveries are logged and not retried.
+func (pinWatchOpts) StatusWebhook(webhook string) WatchOption {
+	return func(options *watchSettings) error {
+		u, err := url.Parse(webhook)
+		if err != nil {
+			return fmt.Errorf("invalid webhook URL: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid webhook URL %q: scheme must be http or https", webhook)
+		}
+		options.webhooks = append(options.webhooks, webhook)
+		return nil
+	}
+}
+
+// WebhookClient sets the HTTP client used to deliver webhooks. Defaults to
+// [http.DefaultClient].
+func (pinWatchOpts) WebhookClient(client *http.Client) WatchOption {
+	return func(options *watchSettings) error {
+		options.httpClient = client
+		return nil
+	}
+}
+
+// StatusChange describes the transition of a pin request to a new status.
+type StatusChange struct {
+	RequestID string
+	Cid       cid.Cid
+	Previous  Status
+	Status    Status
+	// PinStatus is the latest state of the pin request returned by the
+	// pinning service.
+	PinStatus PinStatusGetter
+	// Removed is set when the pin request was deleted on the pinning service.
+	// Status is then StatusUnknown, and PinStatus the last known state.
+	Removed bool
+}
+
+// MarshalJSON encodes the status change as sent to webhooks, with the pin
+// status in the format of the pinning service API.
+func (s StatusChange) MarshalJSON() ([]byte, error) {
+	var ps *openapi.PinStatus
+	if p, ok := s.PinStatus.(*pinStatusObject); ok {
+		ps = &p.PinStatus
+	}
+	return json.Marshal(struct {
+		RequestID string             `json:"requestid"`
+		Cid       string             `json:"cid"`
+		Previous  Status             `json:"previous"`
+		Status    Status             `json:"status"`
+		PinStatus *openapi.PinStatus `json:"pinstatus,omitempty"`
+		Removed   bool               `json:"removed,omitempty"`
+	}{
+		RequestID: s.RequestID,
+		Cid:       s.Cid.Encode(getCIDEncoder()),
+		Previous:  s.Previous,
+		Status:    s.Status,
+		PinStatus: ps,
+		Removed:   s.Removed,
+	})
+}
+
+// Watch polls the pinning service until every pin request in pins is either
+// pinned or failed, notifying the callbacks and webhooks registered with
+// [pinWatchOpts.OnStatusChange] and [pinWatchOpts.StatusWebhook] of every
+// status change. The statuses in pins are the starting point, pins which are
+// already pinned or failed are not watched.
+//
+// Pins are polled in batches by listing their CIDs, a pin request missing
+// from the listing is fetched by its request ID. A pin request the pinning
+// service does not know anymore is reported as a change with Removed set, and
+// is not watched anymore. Polling errors are logged and retried with backoff. Watch returns nil once all pins are settled, or
+// the context error if it is canceled first.
+func (c *Client) Watch(ctx context.Context, pins []PinStatusGetter, opts ...WatchOption) error {
+	settings := &watchSettings{
+		minInterval: defaultWatchMinInterval,
+		maxInterval: defaultWatchMaxInterval,
+		httpClient:  http.DefaultClient,
+	}
+	for _, o := range opts {
+		if err := o(settings); err != nil {
+			return err
+		}
+	}
+
+	pending := make(map[string]StatusChange, len(pins))
+	for _, p := range pins {
+		if settled(p.GetStatus()) {
+			continue
+		}
+		pending[p.GetRequestId()] = StatusChange{
+			RequestID: p.GetRequestId(),
+			Cid:       p.GetPin().GetCid(),
+			Status:    p.GetStatus(),
+			PinStatus: p,
+		}
+	}
+
+	interval := settings.minInterval
+	for len(pending) != 0 {
+		t := time.NewTimer(jitter(interval))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+
+		statuses, removed, err := c.pollStatuses(ctx, pending)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Debugf("polling the status of %d pins failed: %s", len(pending), err)
+		}
+
+		changed := len(removed) != 0
+		for _, id := range removed {
+			prev := pending[id]
+			settings.notify(ctx, StatusChange{
+				RequestID: id,
+				Cid:       prev.Cid,
+				Previous:  prev.Status,
+				Status:    StatusUnknown,
+				PinStatus: prev.PinStatus,
+				Removed:   true,
+			})
+			delete(pending, id)
+		}
+		for id, ps := range statuses {
+			prev, ok := pending[id]
+			if !ok || prev.Status == ps.GetStatus() {
+				continue
+			}
+			changed = true
+			change := StatusChange{
+				RequestID: id,
+				Cid:       prev.Cid,
+				Previous:  prev.Status,
+				Status:    ps.GetStatus(),
+				PinStatus: ps,
+			}
+			settings.notify(ctx, change)
+			if settled(change.Status) {
+				delete(pending, id)
+			} else {
+				pending[id] = change
+			}
+		}
+
+		if changed && err == nil {
+			interval = settings.minInterval
+		} else {
+			interval = min(2*interval, settings.maxInterval)
+		}
+	}
+	return nil
+}
+
+// pollStatuses returns the current statuses of the pending pins, by request
+// ID, and the request IDs the pinning service does not know anymore. It
+// returns the statuses it could fetch along with the first error.
+func (c *Client) pollStatuses(ctx context.Context, pending map[string]StatusChange) (map[string]PinStatusGetter, []string, error) {
+	statuses := make(map[string]PinStatusGetter, len(pending))
+	var firstErr error
+
+	cids := make([]cid.Cid, 0, len(pending))
+	seen := make(map[cid.Cid]struct{}, len(pending))
+	for _, p := range pending {
+		if _, ok := seen[p.Cid]; ok || !p.Cid.Defined() {
+			continue
+		}
+		seen[p.Cid] = struct{}{}
+		cids = append(cids, p.Cid)
+	}
+
+	for len(cids) != 0 {
+		batch := cids[:min(len(cids), maxLsCids)]
+		cids = cids[len(batch):]
+
+		res, _, err := c.LsBatchSync(ctx,
+			PinOpts.FilterCIDs(batch...),
+			PinOpts.FilterStatus(validStatuses...),
+			PinOpts.Limit(recordLimit),
+		)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, ps := range res {
+			if _, ok := pending[ps.GetRequestId()]; ok {
+				statuses[ps.GetRequestId()] = ps
+			}
+		}
+	}
+
+	var removed []string
+	for id := range pending {
+		if _, ok := statuses[id]; ok {
+			continue
+		}
+		res, httpresp, err := c.client.PinsApi.PinsRequestidGet(ctx, id).Execute()
+		if err != nil {
+			if httpresp != nil && httpresp.StatusCode == http.StatusNotFound {
+				removed = append(removed, id)
+				continue
+			}
+			if firstErr == nil {
+				firstErr = httperr(httpresp, err)
+			}
+			continue
+		}
+		statuses[id] = &pinStatusObject{res}
+	}
+
+	return statuses, removed, firstErr
+}
+
+func (s *watchSettings) notify(ctx context.Context, change StatusChange) {
+	for _, fn := range s.callbacks {
+		fn(change)
+	}
+	if len(s.webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(change)
+	if err != nil {
+		logger.Errorf("encoding the status change of %s: %s", change.RequestID, err)
+		return
+	}
+	for _, webhook := range s.webhooks {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+		if err != nil {
+			logger.Errorf("delivering the status change of %s to %s: %s", change.RequestID, webhook, err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", UserAgent)
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			logger.Errorf("delivering the status change of %s to %s: %s", change.RequestID, webhook, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			logger.Errorf("delivering the status change of %s to %s: webhook returned http error %d", change.RequestID, webhook, resp.StatusCode)
+		}
+	}
+}
+
+// settled returns true if a pin with status s will not change anymore.
+func settled(s Status) bool {
+	return s == StatusPinned || s == StatusFailed
+}
+
+// jitter returns a random duration in [d/2, d].
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
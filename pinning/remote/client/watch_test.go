@@ -0,0 +1,217 @@
+package go_pinning_service_http_client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/pinning/remote/client/openapi"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+)
+
+// statusService is a pinning service in which every pin request moves to its
+// next status each time it is listed or fetched.
+type statusService struct {
+	lk       sync.Mutex
+	pins     map[string]openapi.PinStatus
+	next     map[string][]openapi.Status
+	lsCalls  int
+	getCalls int
+	hidden   map[string]bool
+}
+
+func (s *statusService) advance(id string) openapi.PinStatus {
+	ps := s.pins[id]
+	if next := s.next[id]; len(next) > 0 {
+		ps.Status = next[0]
+		s.next[id] = next[1:]
+		s.pins[id] = ps
+	}
+	return ps
+}
+
+func (s *statusService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if id, ok := strings.CutPrefix(r.URL.Path, "/pins/"); ok {
+		s.getCalls++
+		if _, ok := s.pins[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(openapi.Failure{Error: openapi.FailureError{Reason: "NOT_FOUND"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.advance(id))
+		return
+	}
+
+	s.lsCalls++
+	cids := strings.Split(r.URL.Query().Get("cid"), ",")
+	res := openapi.PinResults{Results: []openapi.PinStatus{}}
+	for id, ps := range s.pins {
+		if s.hidden[id] {
+			continue
+		}
+		for _, c := range cids {
+			if ps.Pin.Cid == c {
+				res.Results = append(res.Results, s.advance(id))
+			}
+		}
+	}
+	res.Count = int32(len(res.Results))
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func newStatusService(t *testing.T, cids []cid.Cid, next ...openapi.Status) (*statusService, *Client, []PinStatusGetter) {
+	s := &statusService{
+		pins:   map[string]openapi.PinStatus{},
+		next:   map[string][]openapi.Status{},
+		hidden: map[string]bool{},
+	}
+	var pins []PinStatusGetter
+	for _, c := range cids {
+		ps := openapi.PinStatus{
+			Requestid: "req-" + c.String(),
+			Status:    openapi.QUEUED,
+			Created:   time.Now(),
+			Pin:       openapi.Pin{Cid: c.Encode(getCIDEncoder())},
+			Delegates: []string{},
+		}
+		s.pins[ps.Requestid] = ps
+		s.next[ps.Requestid] = next
+		pins = append(pins, &pinStatusObject{ps})
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, NewClient(ts.URL, "secret"), pins
+}
+
+func TestWatch(t *testing.T) {
+	cids := makeCids(t, 15)
+	s, c, pins := newStatusService(t, cids, openapi.PINNING, openapi.PINNING, openapi.PINNED)
+	// one pin is not listed by the service and must be fetched by ID
+	s.hidden[pins[0].GetRequestId()] = true
+
+	var changes []StatusChange
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := c.Watch(ctx, pins,
+		PinOpts.WatchInterval(time.Millisecond, 4*time.Millisecond),
+		PinOpts.OnStatusChange(func(sc StatusChange) { changes = append(changes, sc) }),
+	)
+	require.NoError(t, err)
+
+	// every pin went through queued -> pinning -> pinned
+	require.Len(t, changes, 2*len(cids))
+	got := map[string][]Status{}
+	for _, sc := range changes {
+		if len(got[sc.RequestID]) == 0 {
+			got[sc.RequestID] = append(got[sc.RequestID], sc.Previous)
+		}
+		require.Equal(t, got[sc.RequestID][len(got[sc.RequestID])-1], sc.Previous)
+		require.Equal(t, sc.Status, sc.PinStatus.GetStatus())
+		got[sc.RequestID] = append(got[sc.RequestID], sc.Status)
+	}
+	for _, p := range pins {
+		require.Equal(t, []Status{StatusQueued, StatusPinning, StatusPinned}, got[p.GetRequestId()])
+	}
+
+	// pins are listed 10 CIDs at a time, only the hidden one is fetched
+	require.Equal(t, 2*3, s.lsCalls)
+	require.Equal(t, 3, s.getCalls)
+}
+
+func TestWatchRemoved(t *testing.T) {
+	cids := makeCids(t, 2)
+	s, c, pins := newStatusService(t, cids, openapi.PINNED)
+	// one pin request is deleted on the service while it is watched
+	removed := pins[0].GetRequestId()
+	delete(s.pins, removed)
+
+	var changes []StatusChange
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := c.Watch(ctx, pins,
+		PinOpts.WatchInterval(time.Millisecond, 4*time.Millisecond),
+		PinOpts.OnStatusChange(func(sc StatusChange) { changes = append(changes, sc) }),
+	)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	var gone []StatusChange
+	for _, sc := range changes {
+		if sc.RequestID == removed {
+			gone = append(gone, sc)
+		}
+	}
+	require.Len(t, gone, 1)
+	require.True(t, gone[0].Removed)
+	require.Equal(t, StatusQueued, gone[0].Previous)
+	require.Equal(t, StatusUnknown, gone[0].Status)
+	require.Equal(t, cids[0], gone[0].Cid)
+
+	b, err := json.Marshal(gone[0])
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"removed":true`)
+}
+
+func TestWatchWebhook(t *testing.T) {
+	cids := makeCids(t, 2)
+	_, c, pins := newStatusService(t, cids, openapi.FAILED)
+
+	var (
+		lk       sync.Mutex
+		received []map[string]any
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var m map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		lk.Lock()
+		received = append(received, m)
+		lk.Unlock()
+	}))
+	defer hook.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := c.Watch(ctx, pins,
+		PinOpts.WatchInterval(time.Millisecond, time.Millisecond),
+		PinOpts.StatusWebhook(hook.URL),
+	)
+	require.NoError(t, err)
+
+	require.Len(t, received, 2)
+	for _, m := range received {
+		require.Equal(t, "queued", m["previous"])
+		require.Equal(t, "failed", m["status"])
+		require.Contains(t, []string{cids[0].Encode(getCIDEncoder()), cids[1].Encode(getCIDEncoder())}, m["cid"])
+		require.Equal(t, "failed", m["pinstatus"].(map[string]any)["status"])
+	}
+}
+
+func TestWatchCanceled(t *testing.T) {
+	_, c, pins := newStatusService(t, makeCids(t, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.Watch(ctx, pins, PinOpts.WatchInterval(time.Millisecond, 10*time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWatchInvalidOptions(t *testing.T) {
+	c := NewClient("http://127.0.0.1:1", "secret")
+	require.Error(t, c.Watch(context.Background(), nil, PinOpts.WatchInterval(time.Second, time.Millisecond)))
+	require.Error(t, c.Watch(context.Background(), nil, PinOpts.StatusWebhook("ftp://example.com")))
+	require.Error(t, c.Watch(context.Background(), nil, PinOpts.OnStatusChange(nil)))
+	// nothing to watch
+	require.NoError(t, c.Watch(context.Background(), nil))
+}
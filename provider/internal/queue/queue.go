@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 
 	cid "github.com/ipfs/go-cid"
@@ -11,47 +12,153 @@ import (
 	namespace "github.com/ipfs/go-datastore/namespace"
 	query "github.com/ipfs/go-datastore/query"
 	logging "github.com/ipfs/go-log/v2"
+	metrics "github.com/ipfs/go-metrics-interface"
 )
 
 var log = logging.Logger("provider.queue")
 
+// DefaultCapacity is the default capacity of the queue, see [WithCapacity].
+const DefaultCapacity = 1024
+
+// OverflowPolicy defines what [Queue.Enqueue] does once the queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowSpill writes the CIDs which do not fit in the in-memory buffer
+	// straight to the datastore, in the goroutine of the caller. The queue is
+	// unbounded.
+	OverflowSpill OverflowPolicy = iota
+	// OverflowBlock makes Enqueue wait until a CID is dequeued while the
+	// queue holds capacity CIDs.
+	OverflowBlock
+	// OverflowDropOldest drops the oldest CID of the queue, buffered or
+	// persisted, to make room for the new one while the queue holds capacity
+	// CIDs.
+	OverflowDropOldest
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowSpill:
+		return "spill"
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// Option configures a [Queue].
+type Option func(*Queue)
+
+// WithCapacity sets the capacity of the queue and what to do when it is full.
+// With [OverflowSpill], it is the number of CIDs buffered in memory before
+// being written to the datastore. With the other policies, it is the total
+// number of CIDs in the queue, in memory and in the datastore. A capacity of
+// 0 uses [DefaultCapacity].
+func WithCapacity(capacity int, policy OverflowPolicy) Option {
+	return func(q *Queue) {
+		if capacity <= 0 {
+			capacity = DefaultCapacity
+		}
+		q.capacity = capacity
+		q.policy = policy
+	}
+}
+
 // Queue provides a best-effort durability, FIFO interface to the datastore for storing cids
 //
 // Best-effort durability just means that cids in the process of being provided when a
 // crash or shutdown occurs may be in the queue when the node is brought back online
 // depending on whether the underlying datastore has synchronous or asynchronous writes.
+// Enqueued cids are buffered in memory and written to the datastore in
+// batches, the buffer is flushed when the queue is closed.
+//
+// A cid which is already in the queue is not enqueued again.
 type Queue struct {
 	// used to differentiate queues in datastore
 	// e.g. provider vs reprovider
 	ctx     context.Context
 	ds      datastore.Datastore // Must be threadsafe
 	dequeue chan cid.Cid
-	enqueue chan cid.Cid
 	close   context.CancelFunc
 	closed  sync.WaitGroup
 
+	capacity int
+	policy   OverflowPolicy
+
+	lk sync.Mutex
+	// room is closed, and replaced, when cids leave the buffer or the queue
+	room chan struct{}
+	// inflight is the key of the cid the worker is trying to dequeue
+	inflight datastore.Key
+	// wake tells the worker there are cids in the buffer
+	wake    chan struct{}
+	buffer  []entry
+	queued  map[cid.Cid]struct{}
 	counter uint64
+
+	depth        metrics.Gauge
+	deduplicated metrics.Counter
+	dropped      metrics.Counter
+	spilled      metrics.Counter
+}
+
+type entry struct {
+	key datastore.Key
+	c   cid.Cid
 }
 
 // NewQueue creates a queue for cids
-func NewQueue(ds datastore.Datastore) *Queue {
+func NewQueue(ds datastore.Datastore, opts ...Option) *Queue {
+	q := newQueue(ds, opts...)
+	q.start()
+	return q
+}
+
+// newQueue creates a queue without starting its worker.
+func newQueue(ds datastore.Datastore, opts ...Option) *Queue {
 	namespaced := namespace.Wrap(ds, datastore.NewKey("/queue"))
 	cancelCtx, cancel := context.WithCancel(context.Background())
 	q := &Queue{
-		ctx:     cancelCtx,
-		ds:      namespaced,
-		dequeue: make(chan cid.Cid),
-		enqueue: make(chan cid.Cid),
-		close:   cancel,
+		ctx:      cancelCtx,
+		ds:       namespaced,
+		dequeue:  make(chan cid.Cid),
+		close:    cancel,
+		capacity: DefaultCapacity,
+		policy:   OverflowSpill,
+		wake:     make(chan struct{}, 1),
+		room:     make(chan struct{}),
+		queued:   make(map[cid.Cid]struct{}),
+
+		depth:        metrics.NewCtx(cancelCtx, "boxo_provider.queue_depth", "Number of CIDs waiting in the provide queue").Gauge(),
+		deduplicated: metrics.NewCtx(cancelCtx, "boxo_provider.queue_deduplicated_total", "Number of CIDs not enqueued because they were already in the provide queue").Counter(),
+		dropped:      metrics.NewCtx(cancelCtx, "boxo_provider.queue_dropped_total", "Number of CIDs dropped from the provide queue because it was full").Counter(),
+		spilled:      metrics.NewCtx(cancelCtx, "boxo_provider.queue_spilled_total", "Number of CIDs written straight to the datastore because the provide queue was full").Counter(),
+	}
+	for _, o := range opts {
+		o(q)
 	}
+
+	if err := q.load(); err != nil {
+		log.Errorf("error loading the persisted queue: %s", err)
+	}
+	return q
+}
+
+func (q *Queue) start() {
 	q.closed.Add(1)
 	go q.worker()
-	return q
 }
 
 // Close stops the queue
 func (q *Queue) Close() error {
+	q.lk.Lock()
 	q.close()
+	q.lk.Unlock()
+
 	q.closed.Wait()
 	// We don't close dequeue because the provider which consume this get caught in
 	// an infinite loop dequeing cid.Undef if we do that.
@@ -60,29 +167,250 @@ func (q *Queue) Close() error {
 }
 
 // Enqueue puts a cid in the queue
-func (q *Queue) Enqueue(cid cid.Cid) error {
-	select {
-	case q.enqueue <- cid:
+func (q *Queue) Enqueue(c cid.Cid) error {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	if q.ctx.Err() != nil {
+		return errors.New("failed to enqueue CID: shutting down")
+	}
+	if _, ok := q.queued[c]; ok {
+		q.deduplicated.Inc()
 		return nil
+	}
+
+	for q.full() {
+		switch q.policy {
+		case OverflowBlock:
+			q.waitRoom()
+		case OverflowDropOldest:
+			dropped, err := q.dropOldest()
+			if err != nil {
+				return fmt.Errorf("failed to enqueue CID: %w", err)
+			}
+			if !dropped {
+				// the oldest cids are being written, wait for them
+				q.waitRoom()
+			}
+		default:
+			e := q.nextEntry(c)
+			if err := q.ds.Put(q.ctx, e.key, c.Bytes()); err != nil {
+				return fmt.Errorf("failed to enqueue CID: %w", err)
+			}
+			q.queued[c] = struct{}{}
+			q.spilled.Inc()
+			q.depth.Inc()
+			return nil
+		}
+
+		if q.ctx.Err() != nil {
+			return errors.New("failed to enqueue CID: shutting down")
+		}
+		if _, ok := q.queued[c]; ok {
+			q.deduplicated.Inc()
+			return nil
+		}
+	}
+
+	q.buffer = append(q.buffer, q.nextEntry(c))
+	q.queued[c] = struct{}{}
+	q.depth.Inc()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// waitRoom waits until cids leave the buffer or the queue, or the queue is
+// closed. q.lk must be held, it is released while waiting.
+func (q *Queue) waitRoom() {
+	room := q.room
+	q.lk.Unlock()
+	defer q.lk.Lock()
+	select {
+	case <-room:
 	case <-q.ctx.Done():
-		return errors.New("failed to enqueue CID: shutting down")
 	}
 }
 
+// signalRoom wakes up the calls waiting in waitRoom. q.lk must be held.
+func (q *Queue) signalRoom() {
+	close(q.room)
+	q.room = make(chan struct{})
+}
+
+// full returns whether the overflow policy applies. q.lk must be held.
+func (q *Queue) full() bool {
+	if q.policy == OverflowSpill {
+		return len(q.buffer) >= q.capacity
+	}
+	return len(q.queued) >= q.capacity
+}
+
+// dropOldest removes the oldest cid from the queue, except the one being
+// dequeued. It returns false if there is no cid to drop, because they are
+// being written to the datastore. q.lk must be held.
+func (q *Queue) dropOldest() (bool, error) {
+	// persisted cids are older than the buffered ones
+	results, err := q.ds.Query(q.ctx, query.Query{Orders: []query.Order{query.OrderByKey{}}})
+	if err != nil {
+		return false, err
+	}
+	defer results.Close()
+
+	for r := range results.Next() {
+		if r.Error != nil {
+			return false, r.Error
+		}
+		k := datastore.NewKey(r.Key)
+		if k == q.inflight {
+			continue
+		}
+		if err := q.ds.Delete(q.ctx, k); err != nil {
+			return false, err
+		}
+		if c, err := cid.Cast(r.Value); err == nil {
+			if _, ok := q.queued[c]; ok {
+				delete(q.queued, c)
+				q.depth.Dec()
+			}
+		}
+		q.dropped.Inc()
+		return true, nil
+	}
+
+	if len(q.buffer) == 0 {
+		return false, nil
+	}
+	delete(q.queued, q.buffer[0].c)
+	q.buffer[0] = entry{}
+	q.buffer = q.buffer[1:]
+	q.dropped.Inc()
+	q.depth.Dec()
+	return true, nil
+}
+
 // Dequeue returns a channel that if listened to will remove entries from the queue
 func (q *Queue) Dequeue() <-chan cid.Cid {
 	return q.dequeue
 }
 
+// Len returns the number of cids in the queue.
+func (q *Queue) Len() int {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+	return len(q.queued)
+}
+
+// nextEntry returns a new entry for c, which sorts after all the entries in
+// the queue. q.lk must be held.
+func (q *Queue) nextEntry(c cid.Cid) entry {
+	k := datastore.NewKey(fmt.Sprintf("%020d/%s", q.counter, c.String()))
+	q.counter++
+	return entry{key: k, c: c}
+}
+
+// load fills the deduplication set with the cids persisted in the datastore,
+// and resumes the counter after their keys, so that new entries are dequeued
+// after them.
+func (q *Queue) load() error {
+	results, err := q.ds.Query(q.ctx, query.Query{})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		k := datastore.NewKey(r.Key)
+		if n, err := strconv.ParseUint(k.Parent().BaseNamespace(), 10, 64); err == nil && n >= q.counter {
+			q.counter = n + 1
+		}
+		// invalid entries are removed by the worker
+		if c, err := cid.Cast(r.Value); err == nil {
+			q.queued[c] = struct{}{}
+		}
+	}
+	q.depth.Set(float64(len(q.queued)))
+	return nil
+}
+
+// flush writes the buffered entries to the datastore.
+func (q *Queue) flush(ctx context.Context) error {
+	q.lk.Lock()
+	buffer := q.buffer
+	q.buffer = nil
+	q.signalRoom()
+	q.lk.Unlock()
+
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	err := q.write(ctx, buffer)
+	q.lk.Lock()
+	if err != nil {
+		// put the cids back in front of the ones buffered since, so that
+		// they are written by the next flush instead of being lost
+		q.buffer = append(buffer, q.buffer...)
+	}
+	// the written cids can be dropped now
+	q.signalRoom()
+	q.lk.Unlock()
+	return err
+}
+
+// write writes the entries to the datastore.
+func (q *Queue) write(ctx context.Context, buffer []entry) error {
+	if bds, ok := q.ds.(datastore.Batching); ok {
+		b, err := bds.Batch(ctx)
+		switch {
+		case err == nil:
+			for _, e := range buffer {
+				if err := b.Put(ctx, e.key, e.c.Bytes()); err != nil {
+					return err
+				}
+			}
+			return b.Commit(ctx)
+		case !errors.Is(err, datastore.ErrBatchUnsupported):
+			return err
+		}
+		// the namespace wrapper is always batching, fall back to single puts
+	}
+
+	for _, e := range buffer {
+		if err := q.ds.Put(ctx, e.key, e.c.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // worker run dequeues and enqueues when available.
 func (q *Queue) worker() {
 	var k datastore.Key = datastore.Key{}
 	var c cid.Cid = cid.Undef
 
 	defer q.closed.Done()
+	defer func() {
+		// persist what is left in the buffer so it survives a restart
+		if err := q.flush(context.Background()); err != nil {
+			log.Errorf("Failed to persist queued cids: %s", err)
+		}
+	}()
 	defer q.close()
 
 	for {
+		if err := q.flush(q.ctx); err != nil {
+			if q.ctx.Err() != nil {
+				return
+			}
+			log.Errorf("Failed to enqueue cids: %s", err)
+		}
+
 		if c == cid.Undef {
 			head, err := q.getQueueHead()
 
@@ -93,6 +421,9 @@ func (q *Queue) worker() {
 			case head != nil:
 				k = datastore.NewKey(head.Key)
 				c, err = cid.Parse(head.Value)
+				q.lk.Lock()
+				q.inflight = k
+				q.lk.Unlock()
 				if err != nil {
 					log.Warnf("error parsing queue entry cid with key (%s), removing it from queue: %s", head.Key, err)
 					err = q.ds.Delete(q.ctx, k)
@@ -114,27 +445,21 @@ func (q *Queue) worker() {
 		}
 
 		select {
-		case toQueue := <-q.enqueue:
-			keyPath := fmt.Sprintf("%020d/%s", q.counter, c.String())
-			q.counter++
-			nextKey := datastore.NewKey(keyPath)
-
-			if c == cid.Undef {
-				// fast path, skip rereading the datastore if we don't have anything in hand yet
-				c = toQueue
-				k = nextKey
-			}
-
-			if err := q.ds.Put(q.ctx, nextKey, toQueue.Bytes()); err != nil {
-				log.Errorf("Failed to enqueue cid: %s", err)
-				continue
-			}
+		case <-q.wake:
 		case dequeue <- c:
 			err := q.ds.Delete(q.ctx, k)
 			if err != nil {
 				log.Errorf("Failed to delete queued cid %s with key %s: %s", c, k, err)
 				continue
 			}
+			q.lk.Lock()
+			if _, ok := q.queued[c]; ok {
+				delete(q.queued, c)
+				q.depth.Dec()
+			}
+			q.inflight = datastore.Key{}
+			q.signalRoom()
+			q.lk.Unlock()
 			c = cid.Undef
 		case <-q.ctx.Done():
 			return
@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -125,3 +126,210 @@ func TestInitializationWithManyCids(t *testing.T) {
 
 	assertOrdered(cids, queue, t)
 }
+
+func TestDeduplication(t *testing.T) {
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	queue := newQueue(ds)
+	defer queue.Close()
+
+	cids := makeCids(5)
+	for _, c := range append(cids, cids...) {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if queue.Len() != len(cids) {
+		t.Fatalf("expected %d queued cids, got %d", len(cids), queue.Len())
+	}
+
+	queue.start()
+	assertOrdered(cids, queue, t)
+	if queue.Len() != 0 {
+		t.Fatalf("expected an empty queue, got %d cids", queue.Len())
+	}
+
+	// dequeued cids can be queued again
+	if err := queue.Enqueue(cids[0]); err != nil {
+		t.Fatal(err)
+	}
+	assertOrdered(cids[:1], queue, t)
+}
+
+func TestPersistence(t *testing.T) {
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	queue := newQueue(ds)
+
+	cids := makeCids(10)
+	for _, c := range cids[:5] {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// buffered cids are persisted on close
+	queue.start()
+	queue.Close()
+
+	queue = NewQueue(ds)
+	defer queue.Close()
+	if queue.Len() != 5 {
+		t.Fatalf("expected 5 queued cids after restart, got %d", queue.Len())
+	}
+	// already persisted cids are not enqueued twice, and new ones come after
+	for _, c := range cids {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertOrdered(cids, queue, t)
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	queue := NewQueue(ds, WithCapacity(3, OverflowDropOldest))
+	defer queue.Close()
+
+	cids := makeCids(5)
+	for _, c := range cids {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+		// let the worker persist the cids
+		time.Sleep(10 * time.Millisecond)
+	}
+	if queue.Len() != 3 {
+		t.Fatalf("expected 3 queued cids, got %d", queue.Len())
+	}
+
+	// The first cid is waiting to be dequeued by the worker, so the persisted
+	// cids after it are dropped.
+	assertOrdered([]cid.Cid{cids[0], cids[3], cids[4]}, queue, t)
+	select {
+	case c := <-queue.dequeue:
+		t.Fatalf("unexpected cid dequeued: %s", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOverflowSpill(t *testing.T) {
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	queue := newQueue(ds, WithCapacity(3, OverflowSpill))
+	defer queue.Close()
+
+	cids := makeCids(5)
+	for _, c := range cids {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if queue.Len() != 5 {
+		t.Fatalf("expected 5 queued cids, got %d", queue.Len())
+	}
+	if len(queue.buffer) != 3 {
+		t.Fatalf("expected 3 buffered cids, got %d", len(queue.buffer))
+	}
+
+	queue.start()
+	assertOrdered(cids, queue, t)
+}
+
+func TestOverflowBlock(t *testing.T) {
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	queue := NewQueue(ds, WithCapacity(3, OverflowBlock))
+	defer queue.Close()
+
+	cids := makeCids(5)
+	for _, c := range cids[:3] {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enqueued := make(chan error)
+	go func() {
+		for _, c := range cids[3:] {
+			enqueued <- queue.Enqueue(c)
+		}
+	}()
+
+	// the persisted cids count against the capacity
+	for i := 3; i < 5; i++ {
+		select {
+		case <-enqueued:
+			t.Fatal("enqueue should block while the queue is full")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		assertOrdered(cids[i-3:i-2], queue, t)
+		if err := <-enqueued; err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertOrdered(cids[2:], queue, t)
+}
+
+func TestOverflowBlockClose(t *testing.T) {
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	queue := NewQueue(ds, WithCapacity(3, OverflowBlock))
+
+	cids := makeCids(4)
+	for _, c := range cids[:3] {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enqueued := make(chan error)
+	go func() {
+		enqueued <- queue.Enqueue(cids[3])
+	}()
+	select {
+	case <-enqueued:
+		t.Fatal("enqueue should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	queue.Close()
+	select {
+	case err := <-enqueued:
+		if err == nil {
+			t.Fatal("expected enqueue to fail after close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("enqueue still blocked after close")
+	}
+}
+
+type failingDatastore struct {
+	datastore.Datastore
+	fail bool
+}
+
+func (d *failingDatastore) Put(ctx context.Context, k datastore.Key, v []byte) error {
+	if d.fail {
+		return errors.New("put failed")
+	}
+	return d.Datastore.Put(ctx, k, v)
+}
+
+func TestFlushFailureKeepsCids(t *testing.T) {
+	ds := &failingDatastore{Datastore: datastore.NewMapDatastore(), fail: true}
+	queue := newQueue(ds)
+	defer queue.Close()
+
+	cids := makeCids(3)
+	for _, c := range cids {
+		if err := queue.Enqueue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := queue.flush(context.Background()); err == nil {
+		t.Fatal("expected flush to fail")
+	}
+	if len(queue.buffer) != len(cids) {
+		t.Fatalf("expected %d buffered cids after a failed flush, got %d", len(cids), len(queue.buffer))
+	}
+
+	ds.fail = false
+	queue.start()
+	assertOrdered(cids, queue, t)
+}
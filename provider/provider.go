@@ -11,6 +11,7 @@ import (
 	"github.com/ipfs/go-cidutil"
 	logging "github.com/ipfs/go-log/v2"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/traversal"
 )
 
 var logR = logging.Logger("reprovider.simple")
@@ -89,27 +90,67 @@ func pinSet(ctx context.Context, pinning pin.Pinner, fetchConfig fetcher.Factory
 		}
 
 		session := fetchConfig.NewSession(ctx)
-		for sc := range pinning.RecursiveKeys(ctx, false) {
+		// Detailed pins are needed to know the depth of recursive pins.
+		for sc := range pinning.RecursiveKeys(ctx, !onlyRoots) {
 			if sc.Err != nil {
 				logR.Errorf("reprovide recursive pins: %s", sc.Err)
 				return
 			}
 			set.Visitor(ctx)(sc.Pin.Key)
-			if !onlyRoots {
-				err := fetcherhelpers.BlockAll(ctx, session, cidlink.Link{Cid: sc.Pin.Key}, func(res fetcher.FetchResult) error {
+			if onlyRoots {
+				continue
+			}
+
+			var err error
+			if sc.Pin.Depth == 0 {
+				err = fetcherhelpers.BlockAll(ctx, session, cidlink.Link{Cid: sc.Pin.Key}, func(res fetcher.FetchResult) error {
 					clink, ok := res.LastBlockLink.(cidlink.Link)
 					if ok {
 						set.Visitor(ctx)(clink.Cid)
 					}
 					return nil
 				})
-				if err != nil {
-					logR.Errorf("reprovide indirect pins: %s", err)
-					return
-				}
+			} else {
+				err = walkDepth(ctx, session, sc.Pin.Key, sc.Pin.Depth, set.Visitor(ctx))
+			}
+			if err != nil {
+				logR.Errorf("reprovide indirect pins: %s", err)
+				return
 			}
 		}
 	}()
 
 	return set, nil
 }
+
+// walkDepth visits the blocks linked from root up to the given depth, the
+// descendants a depth-limited pin keeps. Deeper blocks are not fetched.
+func walkDepth(ctx context.Context, session fetcher.Fetcher, root cid.Cid, depth int, visit func(cid.Cid) bool) error {
+	// breadth first, so that every block is reached at its lowest depth
+	seen := cid.NewSet()
+	seen.Add(root)
+	level := []cid.Cid{root}
+	for d := 0; d < depth && len(level) > 0; d++ {
+		var next []cid.Cid
+		for _, c := range level {
+			nd, err := fetcherhelpers.Block(ctx, session, cidlink.Link{Cid: c})
+			if err != nil {
+				return err
+			}
+			links, err := traversal.SelectLinks(nd)
+			if err != nil {
+				return err
+			}
+			for _, l := range links {
+				clink, ok := l.(cidlink.Link)
+				if !ok || !seen.Visit(clink.Cid) {
+					continue
+				}
+				visit(clink.Cid)
+				next = append(next, clink.Cid)
+			}
+		}
+		level = next
+	}
+	return nil
+}
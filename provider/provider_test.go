@@ -0,0 +1,68 @@
+package provider_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/boxo/blockservice"
+	blockstore "github.com/ipfs/boxo/blockstore"
+	offline "github.com/ipfs/boxo/exchange/offline"
+	bsfetcher "github.com/ipfs/boxo/fetcher/impl/blockservice"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/pinning/pinner/dspinner"
+	"github.com/ipfs/boxo/provider"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	dagpb "github.com/ipld/go-codec-dagpb"
+)
+
+func TestPinnedProviderDepth(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	bs := blockstore.NewBlockstore(ds)
+	bserv := blockservice.New(bs, offline.Exchange(bs))
+	dserv := merkledag.NewDAGService(bserv)
+
+	// root -> a -> b -> c
+	nodes := make([]*merkledag.ProtoNode, 4)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		nodes[i] = merkledag.NodeWithData([]byte{byte(i)})
+		if i < len(nodes)-1 {
+			if err := nodes[i].AddNodeLink("child", nodes[i+1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := dserv.Add(ctx, nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pinner, err := dspinner.New(ctx, ds, dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pinner.PinWithDepth(ctx, nodes[0], 2, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcherConfig := bsfetcher.NewFetcherConfig(bserv)
+	fetcherConfig.PrototypeChooser = dagpb.AddSupportToChooser(bsfetcher.DefaultPrototypeChooser)
+	keyChan, err := provider.NewPinnedProvider(false, pinner, fetcherConfig)(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := cid.NewSet()
+	for c := range keyChan {
+		got.Add(c)
+	}
+	if got.Len() != 3 {
+		t.Fatalf("expected the blocks up to the pin depth, got %d", got.Len())
+	}
+	for _, nd := range nodes[:3] {
+		if !got.Has(nd.Cid()) {
+			t.Fatalf("expected %s to be provided", nd.Cid())
+		}
+	}
+}
@@ -51,17 +51,24 @@ type reprovider struct {
 	rsys        Provide
 	keyProvider KeyChanFunc
 
-	q  *queue.Queue
-	ds datastore.Batching
+	q         *queue.Queue
+	queueOpts []queue.Option
+	ds        datastore.Batching
 
 	reprovideCh         chan cid.Cid
 	noReprovideInFlight chan struct{}
 
 	maxReprovideBatchSize uint
 
+	sweep          bool
+	sweepBatchSize uint
+
 	statLk                                    sync.Mutex
 	totalProvides, lastReprovideBatchSize     uint64
 	avgProvideDuration, lastReprovideDuration time.Duration
+	nextReprovide                             time.Time
+
+	trackStatus bool
 
 	throughputCallback ThroughputCallback
 	// throughputProvideCurrentCount counts how many provides has been done since the last call to throughputCallback
@@ -134,7 +141,7 @@ func New(ds datastore.Batching, opts ...Option) (System, error) {
 	}
 
 	s.ds = namespace.Wrap(ds, s.keyPrefix)
-	s.q = queue.NewQueue(s.ds)
+	s.q = queue.NewQueue(s.ds, s.queueOpts...)
 
 	// This is after the options processing so we do not have to worry about leaking a context if there is an
 	// initialization error processing the options
@@ -144,7 +151,11 @@ func New(ds datastore.Batching, opts ...Option) (System, error) {
 
 	if s.rsys != nil {
 		if _, ok := s.rsys.(ProvideMany); !ok {
-			s.maxReprovideBatchSize = 1
+			if s.sweep {
+				s.maxReprovideBatchSize = s.sweepBatchSize
+			} else {
+				s.maxReprovideBatchSize = 1
+			}
 		}
 
 		s.run()
@@ -212,6 +223,44 @@ func Online(rsys Provide) Option {
 	}
 }
 
+// OverflowPolicy defines what happens when a CID is provided while the
+// provide queue is full, see [QueueCapacity].
+type OverflowPolicy = queue.OverflowPolicy
+
+const (
+	// OverflowSpill writes the CIDs which do not fit in memory straight to the
+	// datastore. This is the default.
+	OverflowSpill = queue.OverflowSpill
+	// OverflowBlock makes Provide wait until a CID is dequeued.
+	OverflowBlock = queue.OverflowBlock
+	// OverflowDropOldest drops the oldest queued CID to make room.
+	OverflowDropOldest = queue.OverflowDropOldest
+)
+
+// DefaultQueueCapacity is the default capacity of the provide queue.
+const DefaultQueueCapacity = queue.DefaultCapacity
+
+// QueueCapacity sets the capacity of the provide queue, and what to do with
+// new CIDs when it is full. With [OverflowSpill], it is the number of CIDs
+// buffered in memory before being persisted to the datastore, and the queue is
+// unbounded. With [OverflowBlock] and [OverflowDropOldest], it is the total
+// number of CIDs in the queue, including the persisted ones. A capacity of 0
+// uses [DefaultQueueCapacity].
+//
+// The provide queue never holds the same CID twice, and the CIDs it holds
+// are provided after a restart.
+func QueueCapacity(capacity int, policy OverflowPolicy) Option {
+	return func(system *reprovider) error {
+		switch policy {
+		case OverflowSpill, OverflowBlock, OverflowDropOldest:
+		default:
+			return fmt.Errorf("unknown queue overflow policy: %s", policy)
+		}
+		system.queueOpts = append(system.queueOpts, queue.WithCapacity(capacity, policy))
+		return nil
+	}
+}
+
 func initialReprovideDelay(duration time.Duration) Option {
 	return func(system *reprovider) error {
 		system.initialReprovideDelaySet = true
@@ -331,6 +380,10 @@ func (s *reprovider) run() {
 				ticker.Stop()
 			}
 
+			if s.sweep {
+				sortByKeyspace(keys)
+			}
+
 			log.Debugf("starting provide of %d keys", len(keys))
 			start := time.Now()
 			err := doProvideMany(s.ctx, s.rsys, keys)
@@ -340,6 +393,12 @@ func (s *reprovider) run() {
 			}
 			dur := time.Since(start)
 
+			if s.trackStatus {
+				if err := s.recordProvided(s.ctx, keys, start.Add(dur)); err != nil {
+					log.Errorf("could not store provide status: %v", err)
+				}
+			}
+
 			totalProvideTime := time.Duration(s.totalProvides) * s.avgProvideDuration
 			recentAvgProvideDuration := dur / time.Duration(len(keys))
 
@@ -385,11 +444,15 @@ func (s *reprovider) run() {
 
 		var initialReprovideCh, reprovideCh <-chan time.Time
 
+		var nextTick time.Time
+
 		// If reproviding is enabled (non-zero)
 		if s.reprovideInterval > 0 {
 			reprovideTicker := time.NewTicker(s.reprovideInterval)
 			defer reprovideTicker.Stop()
 			reprovideCh = reprovideTicker.C
+			nextTick = time.Now().Add(s.reprovideInterval)
+			s.setNextReprovide(nextTick)
 
 			// if there is a non-zero initial reprovide time that was set in the initializer or if the fallback has been
 			if s.initialReprovideDelaySet {
@@ -397,13 +460,17 @@ func (s *reprovider) run() {
 				defer initialReprovideTimer.Stop()
 
 				initialReprovideCh = initialReprovideTimer.C
+				s.setNextReprovide(time.Now().Add(s.initalReprovideDelay))
 			}
 		}
 
 		for s.ctx.Err() == nil {
 			select {
 			case <-initialReprovideCh:
+				s.setNextReprovide(nextTick)
 			case <-reprovideCh:
+				nextTick = nextTick.Add(s.reprovideInterval)
+				s.setNextReprovide(nextTick)
 			case <-s.ctx.Done():
 				return
 			}
@@ -418,6 +485,12 @@ func (s *reprovider) run() {
 	}()
 }
 
+func (s *reprovider) setNextReprovide(t time.Time) {
+	s.statLk.Lock()
+	s.nextReprovide = t
+	s.statLk.Unlock()
+}
+
 func stopAndEmptyTimer(t *time.Timer) {
 	if !t.Stop() {
 		<-t.C
@@ -487,6 +560,12 @@ reprovideCidLoop:
 	// Wait until the underlying operation has completed
 	select {
 	case <-s.noReprovideInFlight:
+		if s.trackStatus && s.reprovideInterval > 0 {
+			cutoff := time.Now().Add(-statusExpiryIntervals * s.reprovideInterval)
+			if err := s.pruneStatus(ctx, cutoff); err != nil {
+				log.Errorf("could not prune provide status index: %v", err)
+			}
+		}
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
@@ -528,6 +607,8 @@ func (s *reprovider) shouldReprovide() bool {
 type ReproviderStats struct {
 	TotalProvides, LastReprovideBatchSize     uint64
 	AvgProvideDuration, LastReprovideDuration time.Duration
+	// QueueDepth is the number of CIDs waiting in the provide queue.
+	QueueDepth uint64
 }
 
 // Stat returns various stats about this provider system
@@ -539,6 +620,7 @@ func (s *reprovider) Stat() (ReproviderStats, error) {
 		LastReprovideBatchSize: s.lastReprovideBatchSize,
 		AvgProvideDuration:     s.avgProvideDuration,
 		LastReprovideDuration:  s.lastReprovideDuration,
+		QueueDepth:             uint64(s.q.Len()),
 	}, nil
 }
 
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/boxo/datastore/dshelp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/multiformats/go-multihash"
+)
+
+// ErrProvideStatusDisabled is returned by [StatusReporter.ProvideStatus] when
+// the provide status index is not enabled with [TrackProvideStatus].
+var ErrProvideStatusDisabled = errors.New("provide status tracking is disabled")
+
+var statusKey = datastore.NewKey("/status")
+
+// statusExpiryIntervals is the number of reprovide intervals after which a
+// multihash which was not provided again is removed from the status index.
+// It is more than one so that the multihashes which failed to be reprovided
+// once are kept.
+const statusExpiryIntervals = 2
+
+// ProvideStatus describes when a CID was last announced to the network and
+// when it is next expected to be.
+type ProvideStatus struct {
+	Cid cid.Cid
+	// LastProvided is when the CID was last successfully provided, or the zero
+	// time if it never was since the index was enabled.
+	LastProvided time.Time
+	// NextReprovide is when the next reprovide run is scheduled, or the zero
+	// time if reproviding is disabled. The CID is only reprovided then if it is
+	// returned by the [KeyProvider].
+	NextReprovide time.Time
+}
+
+// StatusReporter is implemented by the [System] returned by [New] and
+// [NewNoopProvider].
+type StatusReporter interface {
+	// ProvideStatus returns the [ProvideStatus] of c.
+	ProvideStatus(ctx context.Context, c cid.Cid) (ProvideStatus, error)
+}
+
+// TrackProvideStatus enables a persisted index recording when every
+// multihash was last provided, which can be queried with
+// [StatusReporter.ProvideStatus]. This adds a datastore write per provided
+// key. After every reprovide run, the multihashes not provided for two
+// reprovide intervals are removed from the index, as they are no longer
+// returned by the [KeyProvider]. The index is never pruned when reproviding is
+// disabled.
+func TrackProvideStatus() Option {
+	return func(system *reprovider) error {
+		system.trackStatus = true
+		return nil
+	}
+}
+
+var (
+	_ StatusReporter = (*reprovider)(nil)
+	_ StatusReporter = (*noopProvider)(nil)
+)
+
+func (s *reprovider) ProvideStatus(ctx context.Context, c cid.Cid) (ProvideStatus, error) {
+	if !s.trackStatus {
+		return ProvideStatus{}, ErrProvideStatusDisabled
+	}
+
+	s.statLk.Lock()
+	st := ProvideStatus{
+		Cid:           c,
+		NextReprovide: s.nextReprovide,
+	}
+	s.statLk.Unlock()
+
+	val, err := s.ds.Get(ctx, statusKey.Child(dshelp.MultihashToDsKey(c.Hash())))
+	switch {
+	case errors.Is(err, datastore.ErrNotFound):
+		return st, nil
+	case err != nil:
+		return ProvideStatus{}, err
+	}
+
+	st.LastProvided, err = parseTime(val)
+	if err != nil {
+		return ProvideStatus{}, fmt.Errorf("could not decode provide time of %s, got %q", c, string(val))
+	}
+	return st, nil
+}
+
+// recordProvided stores t as the last provide time of keys in the status
+// index.
+func (s *reprovider) recordProvided(ctx context.Context, keys []multihash.Multihash, t time.Time) error {
+	b, err := s.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+
+	val := storeTime(t)
+	for _, k := range keys {
+		if err := b.Put(ctx, statusKey.Child(dshelp.MultihashToDsKey(k)), val); err != nil {
+			return err
+		}
+	}
+	return b.Commit(ctx)
+}
+
+// pruneStatus removes the keys last provided before cutoff from the status
+// index.
+func (s *reprovider) pruneStatus(ctx context.Context, cutoff time.Time) error {
+	res, err := s.ds.Query(ctx, query.Query{Prefix: statusKey.String()})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	b, err := s.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	var pruned int
+	for r := range res.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		t, err := parseTime(r.Value)
+		if err == nil && !t.Before(cutoff) {
+			continue
+		}
+		if err := b.Delete(ctx, datastore.NewKey(r.Key)); err != nil {
+			return err
+		}
+		pruned++
+	}
+	if pruned == 0 {
+		return nil
+	}
+	log.Debugf("removing %d expired keys from the provide status index", pruned)
+	return b.Commit(ctx)
+}
+
+func (op *noopProvider) ProvideStatus(context.Context, cid.Cid) (ProvideStatus, error) {
+	return ProvideStatus{}, ErrProvideStatusDisabled
+}
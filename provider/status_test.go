@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestProvideStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	h, err := mh.Sum([]byte("status"), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cid.NewCidV1(cid.Raw, h)
+
+	sys, err := New(ds, Online(&mockProvideMany{}), ReproviderInterval(time.Hour), TrackProvideStatus())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	if err := sys.Provide(c); err != nil {
+		t.Fatal(err)
+	}
+
+	var st ProvideStatus
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		st, err = sys.(StatusReporter).ProvideStatus(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !st.LastProvided.IsZero() || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st.LastProvided.Before(before) {
+		t.Fatalf("expected last provide time after %v, got %v", before, st.LastProvided)
+	}
+	if st.NextReprovide.Before(before) || st.NextReprovide.After(time.Now().Add(defaultInitialReprovideDelay)) {
+		t.Fatalf("expected next reprovide within the initial reprovide delay, got %v", st.NextReprovide)
+	}
+
+	if err := sys.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The index is persisted.
+	sys, err = New(ds, TrackProvideStatus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sys.Close()
+
+	st2, err := sys.(StatusReporter).ProvideStatus(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st2.LastProvided.Equal(st.LastProvided) {
+		t.Fatalf("expected last provide time %v, got %v", st.LastProvided, st2.LastProvided)
+	}
+
+	// Without tracking, the status is not available.
+	disabled, err := New(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer disabled.Close()
+
+	if _, err := disabled.(StatusReporter).ProvideStatus(ctx, c); !errors.Is(err, ErrProvideStatusDisabled) {
+		t.Fatalf("expected ErrProvideStatusDisabled, got %v", err)
+	}
+}
+
+func TestProvideStatusPruned(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	var keys []mh.Multihash
+	for _, data := range []string{"old", "recent"} {
+		h, err := mh.Sum([]byte(data), mh.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, h)
+	}
+
+	sys, err := New(ds, Online(&mockProvideMany{}), ReproviderInterval(time.Hour), TrackProvideStatus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sys.Close()
+	r := sys.(*reprovider)
+
+	if err := r.recordProvided(ctx, keys[:1], time.Now().Add(-3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.recordProvided(ctx, keys[1:], time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sys.Reprovide(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expired := range []bool{true, false} {
+		st, err := r.ProvideStatus(ctx, cid.NewCidV1(cid.Raw, keys[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.LastProvided.IsZero() != expired {
+			t.Fatalf("expected key %d to be expired: %t, got last provide time %v", i, expired, st.LastProvided)
+		}
+	}
+}
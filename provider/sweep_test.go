@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestSweepReprovide(t *testing.T) {
+	t.Parallel()
+	t.Run("many", func(t *testing.T) {
+		t.Parallel()
+		testSweepReprovide(t, false)
+	})
+	t.Run("single", func(t *testing.T) {
+		t.Parallel()
+		testSweepReprovide(t, true)
+	})
+}
+
+func testSweepReprovide(t *testing.T, singleProvide bool) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	orig := &mockProvideMany{}
+	var provider Provide = orig
+	if singleProvide {
+		provider = singleMockWrapper{orig}
+	}
+
+	const numProvides = 100
+	keysToProvide := make([]cid.Cid, numProvides)
+	for i := range keysToProvide {
+		h, err := mh.Sum([]byte(strconv.Itoa(i)), mh.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keysToProvide[i] = cid.NewCidV1(cid.Raw, h)
+	}
+
+	sys, err := New(ds, Online(provider), ReproviderInterval(0), SweepReprovide(0), KeyProvider(func(ctx context.Context) (<-chan cid.Cid, error) {
+		ch := make(chan cid.Cid)
+		go func() {
+			defer close(ch)
+			for _, k := range keysToProvide {
+				select {
+				case ch <- k:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ch, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sys.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sys.Reprovide(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, calls := orig.GetKeys()
+	if len(keys) != numProvides {
+		t.Fatalf("expected %d provided keys, got %d", numProvides, len(keys))
+	}
+	if !singleProvide && calls != 1 {
+		t.Fatalf("expected a single batch, got %d", calls)
+	}
+	for i := 1; i < len(keys); i++ {
+		prev, cur := sha256.Sum256(keys[i-1]), sha256.Sum256(keys[i])
+		if bytes.Compare(prev[:], cur[:]) > 0 {
+			t.Fatalf("keys not provided in keyspace order at %d", i)
+		}
+	}
+}
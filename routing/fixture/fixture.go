@@ -0,0 +1,225 @@
+// Package fixture implements routers which record the responses of another
+// router to a file, and replay them later without network access, so tests
+// involving routing are deterministic.
+//
+// A fixture is recorded once by wrapping a real router with [NewRecorder] and
+// saving it with [Recorder.WriteFile], and replayed by the tests with
+// [LoadFile]:
+//
+//	r, err := fixture.LoadFile("testdata/routing.json")
+package fixture
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+)
+
+// version is the version of the fixture format.
+const version = 1
+
+// fixture is the JSON representation of the recorded responses.
+type fixture struct {
+	Version   int
+	Providers []providerRecord `json:",omitempty"`
+	Values    []valueRecord    `json:",omitempty"`
+	Peers     []peer.AddrInfo  `json:",omitempty"`
+}
+
+type providerRecord struct {
+	// Multihash is the base58 multihash of the CID, as providers do not
+	// depend on the CID version and codec.
+	Multihash string
+	Providers []peer.AddrInfo
+}
+
+type valueRecord struct {
+	Key   []byte
+	Value []byte
+}
+
+// responses are the recorded responses, indexed for lookups.
+type responses struct {
+	lk        sync.RWMutex
+	providers map[string][]peer.AddrInfo
+	values    map[string][]byte
+	peers     map[peer.ID]peer.AddrInfo
+}
+
+func newResponses() *responses {
+	return &responses{
+		providers: make(map[string][]peer.AddrInfo),
+		values:    make(map[string][]byte),
+		peers:     make(map[peer.ID]peer.AddrInfo),
+	}
+}
+
+// addProviders records providers of the multihash, merging them with the
+// providers already recorded.
+func (rs *responses) addProviders(mh string, provs []peer.AddrInfo) {
+	rs.lk.Lock()
+	defer rs.lk.Unlock()
+	recorded := rs.providers[mh]
+	for _, p := range provs {
+		i := slices.IndexFunc(recorded, func(ai peer.AddrInfo) bool { return ai.ID == p.ID })
+		if i < 0 {
+			recorded = append(recorded, p)
+		} else {
+			recorded[i] = p
+		}
+	}
+	rs.providers[mh] = recorded
+}
+
+func (rs *responses) setValue(key string, val []byte) {
+	rs.lk.Lock()
+	defer rs.lk.Unlock()
+	rs.values[key] = slices.Clone(val)
+}
+
+func (rs *responses) setPeer(ai peer.AddrInfo) {
+	rs.lk.Lock()
+	defer rs.lk.Unlock()
+	rs.peers[ai.ID] = ai
+}
+
+func (rs *responses) write(w io.Writer) error {
+	rs.lk.RLock()
+	f := fixture{Version: version}
+	for mh, provs := range rs.providers {
+		provs = slices.Clone(provs)
+		slices.SortFunc(provs, func(a, b peer.AddrInfo) int { return cmp.Compare(string(a.ID), string(b.ID)) })
+		f.Providers = append(f.Providers, providerRecord{Multihash: mh, Providers: provs})
+	}
+	for key, val := range rs.values {
+		f.Values = append(f.Values, valueRecord{Key: []byte(key), Value: val})
+	}
+	for _, ai := range rs.peers {
+		f.Peers = append(f.Peers, ai)
+	}
+	rs.lk.RUnlock()
+
+	// sort the records so that recording the same responses gives the same
+	// file
+	slices.SortFunc(f.Providers, func(a, b providerRecord) int { return cmp.Compare(a.Multihash, b.Multihash) })
+	slices.SortFunc(f.Values, func(a, b valueRecord) int { return cmp.Compare(string(a.Key), string(b.Key)) })
+	slices.SortFunc(f.Peers, func(a, b peer.AddrInfo) int { return cmp.Compare(string(a.ID), string(b.ID)) })
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&f)
+}
+
+// Replayer is a [routing.Routing] answering with the responses of a fixture.
+// Lookups that were not recorded find nothing: FindProvidersAsync and
+// SearchValue return no result, and GetValue and FindPeer return
+// [routing.ErrNotFound].
+//
+// Provide does nothing and PutValue stores the value in memory, so that it can
+// be read back, without changing the fixture.
+type Replayer struct {
+	rs *responses
+}
+
+var _ routing.Routing = (*Replayer)(nil)
+
+// Load reads a fixture written by [Recorder.Write].
+func Load(r io.Reader) (*Replayer, error) {
+	var f fixture
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("cannot read routing fixture: %w", err)
+	}
+	if f.Version != version {
+		return nil, fmt.Errorf("unsupported routing fixture version %d", f.Version)
+	}
+
+	rs := newResponses()
+	for _, pr := range f.Providers {
+		rs.providers[pr.Multihash] = pr.Providers
+	}
+	for _, vr := range f.Values {
+		rs.values[string(vr.Key)] = vr.Value
+	}
+	for _, ai := range f.Peers {
+		rs.peers[ai.ID] = ai
+	}
+	return &Replayer{rs: rs}, nil
+}
+
+// LoadFile reads the fixture file written by [Recorder.WriteFile].
+func LoadFile(name string) (*Replayer, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func (r *Replayer) Provide(context.Context, cid.Cid, bool) error {
+	return nil
+}
+
+func (r *Replayer) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	r.rs.lk.RLock()
+	provs := r.rs.providers[c.Hash().B58String()]
+	if count > 0 && len(provs) > count {
+		provs = provs[:count]
+	}
+	provs = slices.Clone(provs)
+	r.rs.lk.RUnlock()
+
+	out := make(chan peer.AddrInfo, len(provs))
+	for _, p := range provs {
+		out <- p
+	}
+	close(out)
+	return out
+}
+
+func (r *Replayer) FindPeer(_ context.Context, p peer.ID) (peer.AddrInfo, error) {
+	r.rs.lk.RLock()
+	defer r.rs.lk.RUnlock()
+	ai, ok := r.rs.peers[p]
+	if !ok {
+		return peer.AddrInfo{}, routing.ErrNotFound
+	}
+	return ai, nil
+}
+
+func (r *Replayer) PutValue(_ context.Context, key string, val []byte, _ ...routing.Option) error {
+	r.rs.setValue(key, val)
+	return nil
+}
+
+func (r *Replayer) GetValue(_ context.Context, key string, _ ...routing.Option) ([]byte, error) {
+	r.rs.lk.RLock()
+	defer r.rs.lk.RUnlock()
+	val, ok := r.rs.values[key]
+	if !ok {
+		return nil, routing.ErrNotFound
+	}
+	return slices.Clone(val), nil
+}
+
+func (r *Replayer) SearchValue(ctx context.Context, key string, opts ...routing.Option) (<-chan []byte, error) {
+	out := make(chan []byte, 1)
+	if val, err := r.GetValue(ctx, key, opts...); err == nil {
+		out <- val
+	}
+	close(out)
+	return out, nil
+}
+
+func (r *Replayer) Bootstrap(context.Context) error {
+	return nil
+}
@@ -0,0 +1,197 @@
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"github.com/libp2p/go-libp2p/core/test"
+	ma "github.com/multiformats/go-multiaddr"
+	mh "github.com/multiformats/go-multihash"
+)
+
+type mockRouter struct {
+	providers map[string][]peer.AddrInfo
+	values    map[string][]byte
+	peers     map[peer.ID]peer.AddrInfo
+}
+
+func (m *mockRouter) Provide(context.Context, cid.Cid, bool) error {
+	return nil
+}
+
+func (m *mockRouter) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	provs := m.providers[c.Hash().B58String()]
+	out := make(chan peer.AddrInfo, len(provs))
+	for _, p := range provs {
+		out <- p
+	}
+	close(out)
+	return out
+}
+
+func (m *mockRouter) FindPeer(_ context.Context, p peer.ID) (peer.AddrInfo, error) {
+	ai, ok := m.peers[p]
+	if !ok {
+		return peer.AddrInfo{}, routing.ErrNotFound
+	}
+	return ai, nil
+}
+
+func (m *mockRouter) PutValue(context.Context, string, []byte, ...routing.Option) error {
+	return nil
+}
+
+func (m *mockRouter) GetValue(_ context.Context, key string, _ ...routing.Option) ([]byte, error) {
+	val, ok := m.values[key]
+	if !ok {
+		return nil, routing.ErrNotFound
+	}
+	return val, nil
+}
+
+func (m *mockRouter) SearchValue(ctx context.Context, key string, opts ...routing.Option) (<-chan []byte, error) {
+	out := make(chan []byte, 2)
+	if val, ok := m.values[key]; ok {
+		out <- []byte("older")
+		out <- val
+	}
+	close(out)
+	return out, nil
+}
+
+func (m *mockRouter) Bootstrap(context.Context) error {
+	return nil
+}
+
+func makeCid(t *testing.T, data string) cid.Cid {
+	h, err := mh.Sum([]byte(data), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func findProviders(r routing.ContentRouting, c cid.Cid, count int) []peer.AddrInfo {
+	var found []peer.AddrInfo
+	for p := range r.FindProvidersAsync(context.Background(), c, count) {
+		found = append(found, p)
+	}
+	return found
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	c := makeCid(t, "content")
+	p1, p2, p3 := test.RandPeerIDFatal(t), test.RandPeerIDFatal(t), test.RandPeerIDFatal(t)
+	addr := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	ipnsKey := "/ipns/\x00\x24\x08\x01\x12\x20binary"
+
+	mock := &mockRouter{
+		providers: map[string][]peer.AddrInfo{
+			c.Hash().B58String(): {{ID: p1, Addrs: []ma.Multiaddr{addr}}, {ID: p2}},
+		},
+		values: map[string][]byte{ipnsKey: []byte("record")},
+		peers:  map[peer.ID]peer.AddrInfo{p3: {ID: p3, Addrs: []ma.Multiaddr{addr}}},
+	}
+
+	rec := NewRecorder(mock)
+	if found := findProviders(rec, c, 0); len(found) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(found))
+	}
+	if _, err := rec.FindPeer(ctx, p3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.FindPeer(ctx, p1); !errors.Is(err, routing.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	vals, err := rec.SearchValue(ctx, ipnsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range vals {
+	}
+
+	name := filepath.Join(t.TempDir(), "routing.json")
+	if err := rec.WriteFile(name); err != nil {
+		t.Fatal(err)
+	}
+
+	// recording the same responses gives the same fixture
+	var first, second bytes.Buffer
+	if err := rec.Write(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Write(&second); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatal("expected the same fixture")
+	}
+
+	rep, err := LoadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// providers are replayed for any CID with the same multihash
+	found := findProviders(rep, cid.NewCidV0(c.Hash()), 0)
+	if len(found) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(found))
+	}
+	for _, p := range found {
+		if p.ID == p1 && (len(p.Addrs) != 1 || !p.Addrs[0].Equal(addr)) {
+			t.Fatalf("expected the provider addresses to be replayed, got %v", p.Addrs)
+		}
+	}
+	if found := findProviders(rep, c, 1); len(found) != 1 {
+		t.Fatalf("expected 1 provider, got %d", len(found))
+	}
+	if found := findProviders(rep, makeCid(t, "other"), 0); len(found) != 0 {
+		t.Fatalf("expected no provider, got %d", len(found))
+	}
+
+	val, err := rep.GetValue(ctx, ipnsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(val) != "record" {
+		t.Fatalf("expected the last value found, got %q", val)
+	}
+	if _, err := rep.GetValue(ctx, "/ipns/other"); !errors.Is(err, routing.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	ai, err := rep.FindPeer(ctx, p3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ai.Addrs) != 1 || !ai.Addrs[0].Equal(addr) {
+		t.Fatalf("unexpected peer addresses %v", ai.Addrs)
+	}
+	if _, err := rep.FindPeer(ctx, p1); !errors.Is(err, routing.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	// values put are read back
+	if err := rep.PutValue(ctx, "/ipns/other", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := rep.GetValue(ctx, "/ipns/other"); err != nil || string(val) != "new" {
+		t.Fatalf("expected the value put, got %q, %v", val, err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(bytes.NewReader([]byte(`{"Version": 2}`))); err == nil {
+		t.Fatal("expected an error for an unsupported version")
+	}
+	if _, err := Load(bytes.NewReader([]byte(`not json`))); err == nil {
+		t.Fatal("expected an error for an invalid fixture")
+	}
+}
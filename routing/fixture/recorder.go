@@ -0,0 +1,119 @@
+package fixture
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+)
+
+// Recorder is a [routing.Routing] forwarding all the calls to another router,
+// and recording the providers, values and peers it finds. Lookups which find
+// nothing are not recorded, as they find nothing when replayed too.
+type Recorder struct {
+	router routing.Routing
+	rs     *responses
+}
+
+var _ routing.Routing = (*Recorder)(nil)
+
+// NewRecorder returns a [Recorder] recording the responses of router.
+func NewRecorder(router routing.Routing) *Recorder {
+	return &Recorder{
+		router: router,
+		rs:     newResponses(),
+	}
+}
+
+// Write writes the responses recorded so far as a fixture, which can be
+// replayed with [Load]. The records are sorted, so that the same responses
+// give the same fixture.
+func (r *Recorder) Write(w io.Writer) error {
+	return r.rs.write(w)
+}
+
+// WriteFile writes the responses recorded so far to the named fixture file,
+// which can be replayed with [LoadFile].
+func (r *Recorder) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = r.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (r *Recorder) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	return r.router.Provide(ctx, c, announce)
+}
+
+// FindProvidersAsync records the providers as they are found, so the
+// providers of lookups stopped early are recorded too.
+func (r *Recorder) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	in := r.router.FindProvidersAsync(ctx, c, count)
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		mh := c.Hash().B58String()
+		for p := range in {
+			r.rs.addProviders(mh, []peer.AddrInfo{p})
+			select {
+			case out <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (r *Recorder) FindPeer(ctx context.Context, p peer.ID) (peer.AddrInfo, error) {
+	ai, err := r.router.FindPeer(ctx, p)
+	if err == nil {
+		r.rs.setPeer(ai)
+	}
+	return ai, err
+}
+
+func (r *Recorder) PutValue(ctx context.Context, key string, val []byte, opts ...routing.Option) error {
+	return r.router.PutValue(ctx, key, val, opts...)
+}
+
+func (r *Recorder) GetValue(ctx context.Context, key string, opts ...routing.Option) ([]byte, error) {
+	val, err := r.router.GetValue(ctx, key, opts...)
+	if err == nil {
+		r.rs.setValue(key, val)
+	}
+	return val, err
+}
+
+// SearchValue records the last, and best, value found.
+func (r *Recorder) SearchValue(ctx context.Context, key string, opts ...routing.Option) (<-chan []byte, error) {
+	in, err := r.router.SearchValue(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		for val := range in {
+			r.rs.setValue(key, val)
+			select {
+			case out <- val:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *Recorder) Bootstrap(ctx context.Context) error {
+	return r.router.Bootstrap(ctx)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/routing/http/types"
+	"github.com/ipfs/boxo/routing/http/types/iter"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+)
+
+// Libp2pRouter is a [ContentRouter] backed by libp2p routing implementations,
+// such as a DHT. It allows exposing them over the Routing V1 HTTP API with
+// [Handler], so that a node can act as a delegated router for light clients.
+//
+// Requests for which no implementation is set fail with
+// [routing.ErrNotSupported], which is returned to clients as 501 Not
+// Implemented.
+type Libp2pRouter struct {
+	// ContentRouting answers provider requests.
+	ContentRouting routing.ContentRouting
+	// PeerRouting answers peer requests.
+	PeerRouting routing.PeerRouting
+	// ValueStore answers IPNS requests.
+	ValueStore routing.ValueStore
+}
+
+var _ ContentRouter = (*Libp2pRouter)(nil)
+
+// NewLibp2pRouter returns a [Libp2pRouter] answering all requests with r.
+func NewLibp2pRouter(r routing.Routing) *Libp2pRouter {
+	return &Libp2pRouter{
+		ContentRouting: r,
+		PeerRouting:    r,
+		ValueStore:     r,
+	}
+}
+
+func (r *Libp2pRouter) FindProviders(ctx context.Context, key cid.Cid, limit int) (iter.ResultIter[types.Record], error) {
+	if r.ContentRouting == nil {
+		return nil, routing.ErrNotSupported
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &providersIter{
+		ch:     r.ContentRouting.FindProvidersAsync(ctx, key, limit),
+		cancel: cancel,
+	}, nil
+}
+
+// ProvideBitswap is not supported: libp2p content routing can only announce
+// the local node as a provider, not the peer making the request.
+func (r *Libp2pRouter) ProvideBitswap(ctx context.Context, req *BitswapWriteProvideRequest) (time.Duration, error) {
+	return 0, routing.ErrNotSupported
+}
+
+func (r *Libp2pRouter) FindPeers(ctx context.Context, pid peer.ID, limit int) (iter.ResultIter[*types.PeerRecord], error) {
+	if r.PeerRouting == nil {
+		return nil, routing.ErrNotSupported
+	}
+
+	ai, err := r.PeerRouting.FindPeer(ctx, pid)
+	if errors.Is(err, routing.ErrNotFound) {
+		return iter.FromSlice([]iter.Result[*types.PeerRecord]{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return iter.FromSlice([]iter.Result[*types.PeerRecord]{{Val: peerRecord(ai)}}), nil
+}
+
+func (r *Libp2pRouter) GetIPNS(ctx context.Context, name ipns.Name) (*ipns.Record, error) {
+	if r.ValueStore == nil {
+		return nil, routing.ErrNotSupported
+	}
+
+	raw, err := r.ValueStore.GetValue(ctx, string(name.RoutingKey()))
+	if err != nil {
+		return nil, err
+	}
+	return ipns.UnmarshalRecord(raw)
+}
+
+func (r *Libp2pRouter) PutIPNS(ctx context.Context, name ipns.Name, record *ipns.Record) error {
+	if r.ValueStore == nil {
+		return routing.ErrNotSupported
+	}
+
+	raw, err := ipns.MarshalRecord(record)
+	if err != nil {
+		return err
+	}
+	return r.ValueStore.PutValue(ctx, string(name.RoutingKey()), raw)
+}
+
+func peerRecord(ai peer.AddrInfo) *types.PeerRecord {
+	addrs := make([]types.Multiaddr, len(ai.Addrs))
+	for i, a := range ai.Addrs {
+		addrs[i] = types.Multiaddr{Multiaddr: a}
+	}
+	return &types.PeerRecord{
+		Schema: types.SchemaPeer,
+		ID:     &ai.ID,
+		Addrs:  addrs,
+	}
+}
+
+// providersIter iterates over the results of
+// [routing.ContentRouting.FindProvidersAsync]. Closing it cancels the search.
+type providersIter struct {
+	ch     <-chan peer.AddrInfo
+	cancel context.CancelFunc
+	val    iter.Result[types.Record]
+}
+
+func (it *providersIter) Next() bool {
+	ai, ok := <-it.ch
+	if !ok {
+		return false
+	}
+	it.val = iter.Result[types.Record]{Val: peerRecord(ai)}
+	return true
+}
+
+func (it *providersIter) Val() iter.Result[types.Record] {
+	return it.val
+}
+
+func (it *providersIter) Close() error {
+	it.cancel()
+	return nil
+}
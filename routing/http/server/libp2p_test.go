@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/routing/http/types"
+	jsontypes "github.com/ipfs/boxo/routing/http/types/json"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLibp2pRouting struct {
+	providers []peer.AddrInfo
+	peers     map[peer.ID]peer.AddrInfo
+
+	lk     sync.Mutex
+	values map[string][]byte
+}
+
+func (r *fakeLibp2pRouting) Provide(context.Context, cid.Cid, bool) error {
+	return routing.ErrNotSupported
+}
+
+func (r *fakeLibp2pRouting) FindProvidersAsync(ctx context.Context, _ cid.Cid, limit int) <-chan peer.AddrInfo {
+	ch := make(chan peer.AddrInfo)
+	go func() {
+		defer close(ch)
+		for i, ai := range r.providers {
+			if limit > 0 && i >= limit {
+				return
+			}
+			select {
+			case ch <- ai:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (r *fakeLibp2pRouting) FindPeer(_ context.Context, pid peer.ID) (peer.AddrInfo, error) {
+	ai, ok := r.peers[pid]
+	if !ok {
+		return peer.AddrInfo{}, routing.ErrNotFound
+	}
+	return ai, nil
+}
+
+func (r *fakeLibp2pRouting) PutValue(_ context.Context, key string, val []byte, _ ...routing.Option) error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.values[key] = val
+	return nil
+}
+
+func (r *fakeLibp2pRouting) GetValue(_ context.Context, key string, _ ...routing.Option) ([]byte, error) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	val, ok := r.values[key]
+	if !ok {
+		return nil, routing.ErrNotFound
+	}
+	return val, nil
+}
+
+func (r *fakeLibp2pRouting) SearchValue(ctx context.Context, key string, opts ...routing.Option) (<-chan []byte, error) {
+	return nil, routing.ErrNotSupported
+}
+
+func (r *fakeLibp2pRouting) Bootstrap(context.Context) error {
+	return nil
+}
+
+func TestLibp2pRouter(t *testing.T) {
+	_, pid1 := makePeerID(t)
+	_, pid2 := makePeerID(t)
+	addr := multiaddr.StringCast("/ip4/127.0.0.1/tcp/4001")
+	c := cid.MustParse("bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4")
+
+	r := &fakeLibp2pRouting{
+		providers: []peer.AddrInfo{{ID: pid1, Addrs: []multiaddr.Multiaddr{addr}}, {ID: pid2}},
+		peers:     map[peer.ID]peer.AddrInfo{pid1: {ID: pid1, Addrs: []multiaddr.Multiaddr{addr}}},
+		values:    map[string][]byte{},
+	}
+	server := httptest.NewServer(Handler(NewLibp2pRouter(r)))
+	t.Cleanup(server.Close)
+
+	do := func(t *testing.T, method, path, contentType string, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, body)
+		require.NoError(t, err)
+		if method == http.MethodPut {
+			req.Header.Set("Content-Type", contentType)
+		} else {
+			req.Header.Set("Accept", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("FindProviders", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/routing/v1/providers/"+c.String(), mediaTypeJSON, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var providers jsontypes.ProvidersResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&providers))
+		require.Len(t, providers.Providers, 2)
+		rec, ok := providers.Providers[0].(*types.PeerRecord)
+		require.True(t, ok)
+		require.Equal(t, pid1, *rec.ID)
+		require.Len(t, rec.Addrs, 1)
+		require.Equal(t, addr.String(), rec.Addrs[0].String())
+	})
+
+	t.Run("FindPeers", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/routing/v1/peers/"+peer.ToCid(pid1).String(), mediaTypeNDJSON, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), pid1.String())
+		require.Contains(t, string(body), addr.String())
+
+		resp = do(t, http.MethodGet, "/routing/v1/peers/"+peer.ToCid(pid2).String(), mediaTypeNDJSON, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Empty(t, body)
+	})
+
+	t.Run("IPNS", func(t *testing.T) {
+		sk, name := makeName(t)
+		_, rawRecord := makeIPNSRecord(t, c, time.Now().Add(time.Hour), time.Minute, sk)
+
+		resp := do(t, http.MethodGet, "/routing/v1/ipns/"+name.String(), mediaTypeIPNSRecord, nil)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = do(t, http.MethodPut, "/routing/v1/ipns/"+name.String(), mediaTypeIPNSRecord, bytes.NewReader(rawRecord))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, rawRecord, r.values[string(name.RoutingKey())])
+
+		resp = do(t, http.MethodGet, "/routing/v1/ipns/"+name.String(), mediaTypeIPNSRecord, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, rawRecord, body)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		server := httptest.NewServer(Handler(&Libp2pRouter{ContentRouting: r}))
+		t.Cleanup(server.Close)
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/routing/v1/ipns/"+ipns.NameFromPeer(pid1).String(), nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", mediaTypeIPNSRecord)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+	})
+}
@@ -21,6 +21,7 @@ import (
 	jsontypes "github.com/ipfs/boxo/routing/http/types/json"
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
 	"github.com/multiformats/go-multiaddr"
 
 	logging "github.com/ipfs/go-log/v2"
@@ -213,7 +214,7 @@ func (s *server) findProviders(w http.ResponseWriter, httpReq *http.Request) {
 
 	provIter, err := s.svc.FindProviders(httpReq.Context(), cid, recordsLimit)
 	if err != nil {
-		writeErr(w, "FindProviders", http.StatusInternalServerError, fmt.Errorf("delegate error: %w", err))
+		writeErr(w, "FindProviders", delegateErrStatus(err), fmt.Errorf("delegate error: %w", err))
 		return
 	}
 
@@ -285,7 +286,7 @@ func (s *server) findPeers(w http.ResponseWriter, r *http.Request) {
 
 	provIter, err := s.svc.FindPeers(r.Context(), pid, recordsLimit)
 	if err != nil {
-		writeErr(w, "FindPeers", http.StatusInternalServerError, fmt.Errorf("delegate error: %w", err))
+		writeErr(w, "FindPeers", delegateErrStatus(err), fmt.Errorf("delegate error: %w", err))
 		return
 	}
 
@@ -332,7 +333,7 @@ func (s *server) provide(w http.ResponseWriter, httpReq *http.Request) {
 				Addrs:       addrs,
 			})
 			if err != nil {
-				writeErr(w, "Provide", http.StatusInternalServerError, fmt.Errorf("delegate error: %w", err))
+				writeErr(w, "Provide", delegateErrStatus(err), fmt.Errorf("delegate error: %w", err))
 				return
 			}
 			resp.ProvideResults = append(resp.ProvideResults,
@@ -391,7 +392,7 @@ func (s *server) GetIPNS(w http.ResponseWriter, r *http.Request) {
 
 	record, err := s.svc.GetIPNS(r.Context(), name)
 	if err != nil {
-		writeErr(w, "GetIPNS", http.StatusInternalServerError, fmt.Errorf("delegate error: %w", err))
+		writeErr(w, "GetIPNS", delegateErrStatus(err), fmt.Errorf("delegate error: %w", err))
 		return
 	}
 
@@ -465,7 +466,7 @@ func (s *server) PutIPNS(w http.ResponseWriter, r *http.Request) {
 
 	err = s.svc.PutIPNS(r.Context(), name, record)
 	if err != nil {
-		writeErr(w, "PutIPNS", http.StatusInternalServerError, fmt.Errorf("delegate error: %w", err))
+		writeErr(w, "PutIPNS", delegateErrStatus(err), fmt.Errorf("delegate error: %w", err))
 		return
 	}
 
@@ -511,6 +512,19 @@ func writeJSONResult(w http.ResponseWriter, method string, val interface{ Length
 	}
 }
 
+// delegateErrStatus returns the HTTP status code for an error returned by the
+// [ContentRouter].
+func delegateErrStatus(err error) int {
+	switch {
+	case errors.Is(err, routing.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, routing.ErrNotSupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func writeErr(w http.ResponseWriter, method string, statusCode int, cause error) {
 	w.WriteHeader(statusCode)
 	causeStr := cause.Error()
@@ -0,0 +1,238 @@
+// Package providercache implements a content router which merges the
+// providers found by several content routers, and caches them for a while to
+// avoid repeating the lookups of frequently requested CIDs.
+package providercache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+var log = logging.Logger("routing/providercache")
+
+const (
+	// DefaultTTL is how long the providers found for a CID are cached.
+	DefaultTTL = 5 * time.Minute
+	// DefaultCacheSize is the number of CIDs whose providers are cached.
+	DefaultCacheSize = 1024
+)
+
+// Option is an option for [New].
+type Option func(*Router)
+
+// WithTTL sets how long the providers found for a CID are cached. It
+// defaults to [DefaultTTL].
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Router) {
+		r.ttl = ttl
+	}
+}
+
+// WithCacheSize sets the number of CIDs whose providers are cached. It
+// defaults to [DefaultCacheSize].
+func WithCacheSize(n int) Option {
+	return func(r *Router) {
+		r.cacheSize = n
+	}
+}
+
+// entry holds the providers found for a multihash.
+type entry struct {
+	providers []peer.AddrInfo
+	// complete is false when the lookup was stopped before all the routers
+	// were done, in which case the entry can only serve requests for at most
+	// len(providers) providers.
+	complete bool
+	expires  time.Time
+}
+
+// Router is a [routing.ContentRouting] which looks for providers using
+// several content routers at the same time. The providers they return are
+// deduplicated by peer ID and cached, so that the next lookups for the same
+// multihash are answered without querying the routers until the TTL expires.
+// Lookups finding no provider are not cached.
+//
+// Provide announces to all the routers.
+type Router struct {
+	routers   []routing.ContentRouting
+	ttl       time.Duration
+	cacheSize int
+
+	cache *lru.Cache[string, *entry]
+	now   func() time.Time
+}
+
+var _ routing.ContentRouting = (*Router)(nil)
+
+// New returns a [Router] finding providers with the given routers.
+func New(routers []routing.ContentRouting, opts ...Option) (*Router, error) {
+	r := &Router{
+		routers:   routers,
+		ttl:       DefaultTTL,
+		cacheSize: DefaultCacheSize,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive, got %s", r.ttl)
+	}
+	cache, err := lru.New[string, *entry](r.cacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("creating provider cache: %w", err)
+	}
+	r.cache = cache
+	return r, nil
+}
+
+// Provide announces the CID to all the routers, and returns the errors of
+// the ones which failed.
+func (r *Router) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	errs := make([]error, len(r.routers))
+	var wg sync.WaitGroup
+	for i, router := range r.routers {
+		wg.Add(1)
+		go func(i int, router routing.ContentRouting) {
+			defer wg.Done()
+			errs[i] = router.Provide(ctx, c, announce)
+		}(i, router)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// FindProvidersAsync returns the providers of the CID, from the cache if they
+// were found recently, otherwise from all the routers. A count of 0 returns
+// all the providers found.
+func (r *Router) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	key := string(c.Hash())
+
+	if providers, ok := r.cached(key, count); ok {
+		go func() {
+			defer close(out)
+			for _, ai := range providers {
+				select {
+				case out <- ai:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return out
+	}
+
+	go r.findProviders(ctx, key, c, count, out)
+	return out
+}
+
+// cached returns the cached providers for the multihash if there are enough
+// of them to serve a request for count providers.
+func (r *Router) cached(key string, count int) ([]peer.AddrInfo, bool) {
+	e, ok := r.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if r.now().After(e.expires) {
+		r.cache.Remove(key)
+		return nil, false
+	}
+	if count <= 0 || count > len(e.providers) {
+		if !e.complete {
+			return nil, false
+		}
+		count = len(e.providers)
+	}
+	return e.providers[:count], true
+}
+
+func (r *Router) findProviders(ctx context.Context, key string, c cid.Cid, count int, out chan<- peer.AddrInfo) {
+	defer close(out)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan peer.AddrInfo)
+	var wg sync.WaitGroup
+	for _, router := range r.routers {
+		wg.Add(1)
+		go func(router routing.ContentRouting) {
+			defer wg.Done()
+			for ai := range router.FindProvidersAsync(ctx, c, count) {
+				select {
+				case results <- ai:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(router)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var providers []peer.AddrInfo
+	seen := make(map[peer.ID]int) // index in providers
+	complete := false
+loop:
+	for {
+		select {
+		case ai, ok := <-results:
+			if !ok {
+				complete = true
+				break loop
+			}
+			if i, ok := seen[ai.ID]; ok {
+				// already returned, keep the new addresses for the cache
+				providers[i].Addrs = mergeAddrs(providers[i].Addrs, ai.Addrs)
+				continue
+			}
+			seen[ai.ID] = len(providers)
+			providers = append(providers, peer.AddrInfo{ID: ai.ID, Addrs: slices.Clone(ai.Addrs)})
+
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				break loop
+			}
+			if count > 0 && len(providers) >= count {
+				break loop
+			}
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	if len(providers) == 0 {
+		return
+	}
+	log.Debugw("caching providers", "cid", c, "providers", len(providers), "complete", complete)
+	r.cache.Add(key, &entry{
+		providers: providers,
+		complete:  complete,
+		expires:   r.now().Add(r.ttl),
+	})
+}
+
+// mergeAddrs appends the addresses of b missing from a.
+func mergeAddrs(a, b []ma.Multiaddr) []ma.Multiaddr {
+	for _, addr := range b {
+		if !slices.ContainsFunc(a, addr.Equal) {
+			a = append(a, addr)
+		}
+	}
+	return a
+}
@@ -0,0 +1,178 @@
+package providercache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"github.com/libp2p/go-libp2p/core/test"
+	ma "github.com/multiformats/go-multiaddr"
+	mh "github.com/multiformats/go-multihash"
+)
+
+type mockRouter struct {
+	providers []peer.AddrInfo
+	err       error
+	finds     atomic.Int32
+	provides  atomic.Int32
+}
+
+func (m *mockRouter) Provide(context.Context, cid.Cid, bool) error {
+	m.provides.Add(1)
+	return m.err
+}
+
+func (m *mockRouter) FindProvidersAsync(ctx context.Context, _ cid.Cid, count int) <-chan peer.AddrInfo {
+	m.finds.Add(1)
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		for i, ai := range m.providers {
+			if count > 0 && i >= count {
+				return
+			}
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func testCid(t *testing.T, s string) cid.Cid {
+	h, err := mh.Sum([]byte(s), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func collect(ch <-chan peer.AddrInfo) map[peer.ID]peer.AddrInfo {
+	found := make(map[peer.ID]peer.AddrInfo)
+	for ai := range ch {
+		found[ai.ID] = ai
+	}
+	return found
+}
+
+func TestFindProvidersMergesAndCaches(t *testing.T) {
+	ctx := context.Background()
+	p1, p2, p3 := test.RandPeerIDFatal(t), test.RandPeerIDFatal(t), test.RandPeerIDFatal(t)
+	a1 := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	a2 := ma.StringCast("/ip4/5.6.7.8/tcp/4001")
+
+	r1 := &mockRouter{providers: []peer.AddrInfo{{ID: p1, Addrs: []ma.Multiaddr{a1}}, {ID: p2}}}
+	r2 := &mockRouter{providers: []peer.AddrInfo{{ID: p1, Addrs: []ma.Multiaddr{a2}}, {ID: p3}}}
+	r, err := New([]routing.ContentRouting{r1, r2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	c := testCid(t, "hot")
+
+	found := collect(r.FindProvidersAsync(ctx, c, 0))
+	if len(found) != 3 {
+		t.Fatalf("expected 3 providers, got %d", len(found))
+	}
+
+	// the second lookup is answered from the cache, with the merged addresses
+	found = collect(r.FindProvidersAsync(ctx, c, 0))
+	if len(found) != 3 {
+		t.Fatalf("expected 3 providers, got %d", len(found))
+	}
+	if len(found[p1].Addrs) != 2 {
+		t.Fatalf("expected the addresses of p1 to be merged, got %v", found[p1].Addrs)
+	}
+	if r1.finds.Load() != 1 || r2.finds.Load() != 1 {
+		t.Fatal("expected the routers to be queried once")
+	}
+
+	// until the entry expires
+	now = now.Add(DefaultTTL + time.Second)
+	found = collect(r.FindProvidersAsync(ctx, c, 0))
+	if len(found) != 3 {
+		t.Fatalf("expected 3 providers, got %d", len(found))
+	}
+	if r1.finds.Load() != 2 || r2.finds.Load() != 2 {
+		t.Fatal("expected the routers to be queried again")
+	}
+}
+
+func TestFindProvidersCount(t *testing.T) {
+	ctx := context.Background()
+	var providers []peer.AddrInfo
+	for i := 0; i < 5; i++ {
+		providers = append(providers, peer.AddrInfo{ID: test.RandPeerIDFatal(t)})
+	}
+	m := &mockRouter{providers: providers}
+	r, err := New([]routing.ContentRouting{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := testCid(t, "count")
+
+	if found := collect(r.FindProvidersAsync(ctx, c, 2)); len(found) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(found))
+	}
+	// a partial entry serves smaller requests
+	if found := collect(r.FindProvidersAsync(ctx, c, 1)); len(found) != 1 {
+		t.Fatalf("expected 1 provider, got %d", len(found))
+	}
+	if m.finds.Load() != 1 {
+		t.Fatal("expected the router to be queried once")
+	}
+	// but not bigger ones
+	if found := collect(r.FindProvidersAsync(ctx, c, 0)); len(found) != 5 {
+		t.Fatalf("expected 5 providers, got %d", len(found))
+	}
+	if m.finds.Load() != 2 {
+		t.Fatal("expected the router to be queried again")
+	}
+	if found := collect(r.FindProvidersAsync(ctx, c, 10)); len(found) != 5 {
+		t.Fatalf("expected 5 providers, got %d", len(found))
+	}
+	if m.finds.Load() != 2 {
+		t.Fatal("expected the complete entry to be used")
+	}
+}
+
+func TestFindProvidersNoneNotCached(t *testing.T) {
+	m := &mockRouter{}
+	r, err := New([]routing.ContentRouting{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := testCid(t, "none")
+	for i := 0; i < 2; i++ {
+		if found := collect(r.FindProvidersAsync(context.Background(), c, 0)); len(found) != 0 {
+			t.Fatalf("expected no provider, got %d", len(found))
+		}
+	}
+	if m.finds.Load() != 2 {
+		t.Fatal("expected empty results not to be cached")
+	}
+}
+
+func TestProvide(t *testing.T) {
+	errFail := errors.New("fail")
+	r1, r2 := &mockRouter{}, &mockRouter{err: errFail}
+	r, err := New([]routing.ContentRouting{r1, r2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Provide(context.Background(), testCid(t, "provide"), true)
+	if !errors.Is(err, errFail) {
+		t.Fatalf("expected the error of the failing router, got %v", err)
+	}
+	if r1.provides.Load() != 1 || r2.provides.Load() != 1 {
+		t.Fatal("expected both routers to be called")
+	}
+}
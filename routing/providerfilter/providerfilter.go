@@ -0,0 +1,143 @@
+// Package providerfilter implements a content router which filters the
+// providers found by another content router, for instance to only return the
+// providers a browser can dial, or to exclude a denylist of peers, before
+// they are dialed by bitswap.
+package providerfilter
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+var log = logging.Logger("routing/providerfilter")
+
+// Filter decides whether a provider is returned, and can return it with fewer
+// addresses.
+type Filter func(peer.AddrInfo) (peer.AddrInfo, bool)
+
+// AllowPeers only returns the given peers.
+func AllowPeers(ids ...peer.ID) Filter {
+	allowed := make(map[peer.ID]struct{}, len(ids))
+	for _, id := range ids {
+		allowed[id] = struct{}{}
+	}
+	return func(ai peer.AddrInfo) (peer.AddrInfo, bool) {
+		_, ok := allowed[ai.ID]
+		return ai, ok
+	}
+}
+
+// DenyPeers does not return the given peers.
+func DenyPeers(ids ...peer.ID) Filter {
+	denied := make(map[peer.ID]struct{}, len(ids))
+	for _, id := range ids {
+		denied[id] = struct{}{}
+	}
+	return func(ai peer.AddrInfo) (peer.AddrInfo, bool) {
+		_, ok := denied[ai.ID]
+		return ai, !ok
+	}
+}
+
+// Addrs only keeps the addresses of the providers matching keep, and does
+// not return the providers left without addresses, including the ones found
+// without addresses.
+func Addrs(keep func(ma.Multiaddr) bool) Filter {
+	return func(ai peer.AddrInfo) (peer.AddrInfo, bool) {
+		var addrs []ma.Multiaddr
+		for _, addr := range ai.Addrs {
+			if keep(addr) {
+				addrs = append(addrs, addr)
+			}
+		}
+		return peer.AddrInfo{ID: ai.ID, Addrs: addrs}, len(addrs) > 0
+	}
+}
+
+// Transports only keeps the addresses of the providers using one of the
+// given protocols, such as [ma.P_WEBTRANSPORT], [ma.P_WEBRTC_DIRECT] or
+// [ma.P_WSS], see [Addrs].
+func Transports(codes ...int) Filter {
+	return Addrs(func(addr ma.Multiaddr) bool {
+		for _, p := range addr.Protocols() {
+			for _, code := range codes {
+				if p.Code == code {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// Router is a [routing.ContentRouting] which only returns the providers found
+// by another content router which pass all its filters.
+//
+// Provide announces to the other router.
+type Router struct {
+	router  routing.ContentRouting
+	filters []Filter
+}
+
+var _ routing.ContentRouting = (*Router)(nil)
+
+// New returns a [Router] filtering the providers found by router.
+func New(router routing.ContentRouting, filters ...Filter) *Router {
+	return &Router{
+		router:  router,
+		filters: filters,
+	}
+}
+
+// Provide announces the CID with the other router.
+func (r *Router) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	return r.router.Provide(ctx, c, announce)
+}
+
+// FindProvidersAsync returns the providers of the CID passing the filters. As
+// providers can be filtered out, the other router is asked for all the
+// providers it finds, and the lookup is stopped once count providers were
+// returned. A count of 0 returns all the providers found.
+func (r *Router) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var found int
+		for ai := range r.router.FindProvidersAsync(ctx, c, 0) {
+			ai, ok := r.filter(ai)
+			if !ok {
+				log.Debugw("filtered out provider", "cid", c, "peer", ai.ID)
+				continue
+			}
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+			found++
+			if count > 0 && found >= count {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// filter applies all the filters to the provider.
+func (r *Router) filter(ai peer.AddrInfo) (peer.AddrInfo, bool) {
+	for _, f := range r.filters {
+		var ok bool
+		if ai, ok = f(ai); !ok {
+			return ai, false
+		}
+	}
+	return ai, true
+}
@@ -0,0 +1,104 @@
+package providerfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/test"
+	ma "github.com/multiformats/go-multiaddr"
+	mh "github.com/multiformats/go-multihash"
+)
+
+type mockRouter struct {
+	providers []peer.AddrInfo
+	count     int
+	provided  bool
+}
+
+func (m *mockRouter) Provide(context.Context, cid.Cid, bool) error {
+	m.provided = true
+	return nil
+}
+
+func (m *mockRouter) FindProvidersAsync(ctx context.Context, _ cid.Cid, count int) <-chan peer.AddrInfo {
+	m.count = count
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		for _, ai := range m.providers {
+			select {
+			case out <- ai:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func testCid(t *testing.T) cid.Cid {
+	h, err := mh.Sum([]byte("providerfilter"), mh.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func TestFindProviders(t *testing.T) {
+	ctx := context.Background()
+	p1, p2, p3, p4 := test.RandPeerIDFatal(t), test.RandPeerIDFatal(t), test.RandPeerIDFatal(t), test.RandPeerIDFatal(t)
+	tcp := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	wss := ma.StringCast("/dns4/example.com/tcp/443/wss")
+	webtransport := ma.StringCast("/ip4/1.2.3.4/udp/4001/quic-v1/webtransport")
+
+	mock := &mockRouter{providers: []peer.AddrInfo{
+		{ID: p1, Addrs: []ma.Multiaddr{tcp, wss}},
+		{ID: p2, Addrs: []ma.Multiaddr{tcp}},
+		{ID: p3},
+		{ID: p4, Addrs: []ma.Multiaddr{webtransport}},
+	}}
+
+	find := func(r *Router, count int) []peer.AddrInfo {
+		var found []peer.AddrInfo
+		for ai := range r.FindProvidersAsync(ctx, testCid(t), count) {
+			found = append(found, ai)
+		}
+		return found
+	}
+
+	if found := find(New(mock), 0); len(found) != 4 {
+		t.Fatalf("expected all the providers without filters, got %v", found)
+	}
+
+	found := find(New(mock, Transports(ma.P_WSS, ma.P_WEBTRANSPORT), DenyPeers(p4)), 0)
+	if len(found) != 1 || found[0].ID != p1 {
+		t.Fatalf("expected only %s, got %v", p1, found)
+	}
+	if len(found[0].Addrs) != 1 || !found[0].Addrs[0].Equal(wss) {
+		t.Fatalf("expected only the wss address, got %v", found[0].Addrs)
+	}
+
+	found = find(New(mock, AllowPeers(p2, p3)), 0)
+	if len(found) != 2 || found[0].ID != p2 || found[1].ID != p3 {
+		t.Fatalf("expected %s and %s, got %v", p2, p3, found)
+	}
+
+	// the count applies to the providers passing the filters
+	found = find(New(mock, Transports(ma.P_WSS, ma.P_WEBTRANSPORT)), 2)
+	if len(found) != 2 || found[0].ID != p1 || found[1].ID != p4 {
+		t.Fatalf("expected %s and %s, got %v", p1, p4, found)
+	}
+	if mock.count != 0 {
+		t.Fatalf("expected the other router to be asked for all the providers, got %d", mock.count)
+	}
+	found = find(New(mock, Transports(ma.P_WSS, ma.P_WEBTRANSPORT)), 1)
+	if len(found) != 1 || found[0].ID != p1 {
+		t.Fatalf("expected only %s, got %v", p1, found)
+	}
+
+	if err := New(mock, DenyPeers(p1)).Provide(ctx, testCid(t), true); err != nil || !mock.provided {
+		t.Fatal("expected the other router to provide")
+	}
+}
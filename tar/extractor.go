@@ -14,8 +14,43 @@ var (
 	errTraverseSymlink          = errors.New("cannot traverse symlinks")
 	errInvalidRoot              = errors.New("tar has invalid root")
 	errInvalidRootMultipleRoots = fmt.Errorf("contains more than one root or the root directory is not the first entry : %w", errInvalidRoot)
+
+	// ErrSymlinkNotAllowed is returned when extracting a symlink which is not allowed by the [SymlinkPolicy] of the
+	// Extractor.
+	ErrSymlinkNotAllowed = errors.New("symlink not allowed")
+	// ErrExtractLimit is returned when extracting a tar file exceeds the Extractor.MaxBytes or Extractor.MaxEntries
+	// limits.
+	ErrExtractLimit = errors.New("tar extraction limit exceeded")
+)
+
+// SymlinkPolicy defines how an Extractor handles symlinks.
+type SymlinkPolicy int
+
+const (
+	// SymlinksAllow extracts symlinks as they are, whatever they point to.
+	SymlinksAllow SymlinkPolicy = iota
+	// SymlinksDeny fails the extraction with [ErrSymlinkNotAllowed] when the tar file contains a symlink.
+	SymlinksDeny
+	// SymlinksSkip silently ignores symlinks.
+	SymlinksSkip
+	// SymlinksRewriteAbsolute rewrites absolute symlink targets to point inside the extraction directory, as if it
+	// was the root of the file system. Relative targets which lexically point outside of the extraction directory
+	// fail the extraction with [ErrSymlinkNotAllowed].
+	SymlinksRewriteAbsolute
 )
 
+// ExtractEntry describes an object written by an Extractor, see Extractor.Report.
+type ExtractEntry struct {
+	// Path is the platform path the object is written to.
+	Path string
+	// Typeflag is the tar type of the object: [tar.TypeDir], [tar.TypeReg] or [tar.TypeSymlink].
+	Typeflag byte
+	// Size is the size of files, in bytes.
+	Size int64
+	// Linkname is the target of symlinks, after it was rewritten by the [SymlinkPolicy].
+	Linkname string
+}
+
 // Extractor is used for extracting tar files to a filesystem.
 //
 // The Extractor can only extract tar files containing files, directories and symlinks. Additionally, the tar files must
@@ -28,9 +63,32 @@ var (
 //
 // Overwriting: Extraction of files and symlinks will result in overwriting the existing objects with the same name
 // when possible (i.e. other files, symlinks, and empty directories).
+//
+// Untrusted tar files can be sandboxed with a [SymlinkPolicy] and limits on the total size and number of extracted
+// objects, and inspected beforehand with DryRun.
 type Extractor struct {
 	Path     string
 	Progress func(int64) int64
+
+	// Symlinks is the policy applied to symlinks. Defaults to [SymlinksAllow].
+	Symlinks SymlinkPolicy
+	// MaxBytes is the maximum total size of the extracted files, in bytes. Zero means unbounded.
+	MaxBytes int64
+	// MaxEntries is the maximum number of extracted files, directories and symlinks. Zero means unbounded.
+	MaxEntries int
+	// DryRun validates the tar file and reports the objects which would be written, without modifying the file
+	// system.
+	DryRun bool
+	// Report, if set, is called for every object before it is written, or instead of writing it when DryRun is set.
+	Report func(ExtractEntry)
+}
+
+// extractState tracks the progress of a single extraction.
+type extractState struct {
+	bytes   int64
+	entries int
+	// dirs are the directories which would have been created in dry-run mode.
+	dirs map[string]struct{}
 }
 
 // Extract extracts a tar file to the file system. See the Extractor for more information on the limitations on the
@@ -41,6 +99,7 @@ func (te *Extractor) Extract(reader io.Reader) error {
 	}
 
 	tarReader := tar.NewReader(reader)
+	st := &extractState{dirs: make(map[string]struct{})}
 
 	var firstObjectWasDir bool
 
@@ -82,7 +141,7 @@ func (te *Extractor) Extract(reader io.Reader) error {
 	case tar.TypeDir:
 		// if this is the root directory, use it as the output path for remaining files
 		firstObjectWasDir = true
-		if err := te.extractDir(rootOutputPath); err != nil {
+		if err := te.extractEntry(st, rootOutputPath, rootOutputPath, header, tarReader); err != nil {
 			return err
 		}
 	case tar.TypeReg, tar.TypeSymlink:
@@ -113,11 +172,7 @@ func (te *Extractor) Extract(reader io.Reader) error {
 		}
 
 		// If an object with the target name already exists overwrite it
-		if header.Typeflag == tar.TypeReg {
-			if err := te.extractFile(outputPath, tarReader); err != nil {
-				return err
-			}
-		} else if err := te.extractSymlink(outputPath, header); err != nil {
+		if err := te.extractEntry(st, outputPath, fp.Dir(outputPath), header, tarReader); err != nil {
 			return err
 		}
 	default:
@@ -150,7 +205,7 @@ func (te *Extractor) Extract(reader io.Reader) error {
 			return err
 		}
 
-		outputPath, err := te.outputPath(rootOutputPath, relPath)
+		outputPath, err := te.outputPath(st, rootOutputPath, relPath)
 		if err != nil {
 			return err
 		}
@@ -168,24 +223,94 @@ func (te *Extractor) Extract(reader io.Reader) error {
 			}
 		}
 
-		switch header.Typeflag {
-		case tar.TypeDir:
-			if err := te.extractDir(outputPath); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			if err := te.extractFile(outputPath, tarReader); err != nil {
-				return err
-			}
-		case tar.TypeSymlink:
-			if err := te.extractSymlink(outputPath, header); err != nil {
-				return err
+		if err := te.extractEntry(st, outputPath, rootOutputPath, header, tarReader); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractEntry applies the policies and limits of the Extractor to the object described by h, and extracts it to
+// path. sandbox is the directory symlinks are confined to by [SymlinksRewriteAbsolute].
+func (te *Extractor) extractEntry(st *extractState, path, sandbox string, h *tar.Header, r *tar.Reader) error {
+	entry := ExtractEntry{Path: path, Typeflag: h.Typeflag}
+	switch h.Typeflag {
+	case tar.TypeDir:
+	case tar.TypeReg:
+		entry.Size = h.Size
+	case tar.TypeSymlink:
+		switch te.Symlinks {
+		case SymlinksAllow:
+			entry.Linkname = h.Linkname
+		case SymlinksDeny:
+			return fmt.Errorf("%q : %w", h.Name, ErrSymlinkNotAllowed)
+		case SymlinksSkip:
+			return nil
+		case SymlinksRewriteAbsolute:
+			target, err := confineSymlink(path, sandbox, h.Linkname)
+			if err != nil {
+				return fmt.Errorf("%q : %w", h.Name, err)
 			}
+			entry.Linkname = target
 		default:
-			return fmt.Errorf("unrecognized tar header type: %d", header.Typeflag)
+			return fmt.Errorf("unrecognized symlink policy: %d", te.Symlinks)
 		}
+	default:
+		return fmt.Errorf("unrecognized tar header type: %d", h.Typeflag)
+	}
+
+	st.entries++
+	if te.MaxEntries > 0 && st.entries > te.MaxEntries {
+		return fmt.Errorf("more than %d entries : %w", te.MaxEntries, ErrExtractLimit)
+	}
+	st.bytes += entry.Size
+	if te.MaxBytes > 0 && st.bytes > te.MaxBytes {
+		return fmt.Errorf("more than %d bytes : %w", te.MaxBytes, ErrExtractLimit)
+	}
+
+	if te.Report != nil {
+		te.Report(entry)
+	}
+	if te.DryRun {
+		if h.Typeflag == tar.TypeDir {
+			st.dirs[path] = struct{}{}
+		}
+		return nil
+	}
+
+	switch h.Typeflag {
+	case tar.TypeDir:
+		return te.extractDir(path)
+	case tar.TypeReg:
+		return te.extractFile(path, r)
+	default:
+		return te.extractSymlink(path, entry.Linkname)
 	}
-	return nil
+}
+
+// confineSymlink rewrites an absolute symlink target so that it is relative to the sandbox directory, and returns an
+// error if the target lexically points outside of the sandbox directory.
+func confineSymlink(linkPath, sandbox, target string) (string, error) {
+	dir := fp.Dir(linkPath)
+	platformTarget := fp.FromSlash(target)
+	absolute := strings.HasPrefix(target, "/") || fp.IsAbs(platformTarget)
+
+	var resolved string
+	if absolute {
+		// Cleaning the target first removes leading ".." elements, which cannot escape the root.
+		resolved = fp.Join(sandbox, fp.Clean(platformTarget))
+	} else {
+		resolved = fp.Join(dir, platformTarget)
+	}
+
+	rel, err := fp.Rel(sandbox, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(fp.Separator)) {
+		return "", fmt.Errorf("target %q is outside of the extraction directory : %w", target, ErrSymlinkNotAllowed)
+	}
+	if !absolute {
+		return target, nil
+	}
+	return fp.Rel(dir, resolved)
 }
 
 // validateTarPath returns an error if the path has problematic characters
@@ -218,7 +343,7 @@ func getRelativePath(rootName, tarPath string) (string, error) {
 }
 
 // outputPath returns the directory path at which to place the file relativeTarPath. Assumes relativeTarPath is cleaned.
-func (te *Extractor) outputPath(basePlatformPath, relativeTarPath string) (string, error) {
+func (te *Extractor) outputPath(st *extractState, basePlatformPath, relativeTarPath string) (string, error) {
 	elems := strings.Split(relativeTarPath, "/")
 
 	platformPath := basePlatformPath
@@ -237,6 +362,10 @@ func (te *Extractor) outputPath(basePlatformPath, relativeTarPath string) (strin
 
 		fi, err := os.Lstat(platformPath)
 		if err != nil {
+			// In dry-run mode the parent directories are not created.
+			if _, ok := st.dirs[platformPath]; ok && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
 			return "", err
 		}
 
@@ -267,12 +396,12 @@ func (te *Extractor) extractDir(path string) error {
 	return nil
 }
 
-func (te *Extractor) extractSymlink(path string, h *tar.Header) error {
+func (te *Extractor) extractSymlink(path, target string) error {
 	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
 
-	return os.Symlink(h.Linkname, path)
+	return os.Symlink(target, path)
 }
 
 func (te *Extractor) extractFile(path string, r *tar.Reader) error {
@@ -311,6 +311,123 @@ func TestLastElementOverwrite(t *testing.T) {
 	)
 }
 
+func TestSymlinkPolicyDeny(t *testing.T) {
+	extractDir := fp.Join(t.TempDir(), tarOutRoot)
+	err := extractWith(t, &Extractor{Path: extractDir, Symlinks: SymlinksDeny}, []tarEntry{
+		&dirTarEntry{"root"},
+		&symlinkTarEntry{"file", "root/symlink"},
+	})
+	assert.ErrorIs(t, err, ErrSymlinkNotAllowed)
+}
+
+func TestSymlinkPolicySkip(t *testing.T) {
+	extractDir := fp.Join(t.TempDir(), tarOutRoot)
+	err := extractWith(t, &Extractor{Path: extractDir, Symlinks: SymlinksSkip}, []tarEntry{
+		&dirTarEntry{"root"},
+		&symlinkTarEntry{"file", "root/symlink"},
+		&fileTarEntry{"root/file", []byte("data")},
+	})
+	assert.NoError(t, err)
+
+	_, err = os.Lstat(fp.Join(extractDir, "symlink"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(fp.Join(extractDir, "file"))
+	assert.NoError(t, err)
+}
+
+func TestSymlinkPolicyRewriteAbsolute(t *testing.T) {
+	if !symlinksEnabled {
+		t.Skip("symlinks disabled on this platform", symlinksEnabledErr)
+	}
+
+	extractDir := fp.Join(t.TempDir(), tarOutRoot)
+	te := &Extractor{Path: extractDir, Symlinks: SymlinksRewriteAbsolute}
+	err := extractWith(t, te, []tarEntry{
+		&dirTarEntry{"root"},
+		&dirTarEntry{"root/sub"},
+		&symlinkTarEntry{"/etc/passwd", "root/abs"},
+		&symlinkTarEntry{"/../../file", "root/sub/abs"},
+		&symlinkTarEntry{"../file", "root/sub/rel"},
+	})
+	assert.NoError(t, err)
+
+	for link, expected := range map[string]string{
+		"abs":     fp.Join("etc", "passwd"),
+		"sub/abs": fp.Join("..", "file"),
+		"sub/rel": "../file",
+	} {
+		target, err := os.Readlink(fp.Join(extractDir, fp.FromSlash(link)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, target, link)
+	}
+
+	// Relative targets cannot point outside of the extraction directory.
+	err = extractWith(t, te, []tarEntry{
+		&dirTarEntry{"root"},
+		&symlinkTarEntry{"../outside", "root/escape"},
+	})
+	assert.ErrorIs(t, err, ErrSymlinkNotAllowed)
+}
+
+func TestExtractLimits(t *testing.T) {
+	entries := []tarEntry{
+		&dirTarEntry{"root"},
+		&fileTarEntry{"root/file1", []byte("abc")},
+		&fileTarEntry{"root/file2", []byte("def")},
+	}
+
+	err := extractWith(t, &Extractor{Path: fp.Join(t.TempDir(), tarOutRoot), MaxBytes: 5}, entries)
+	assert.ErrorIs(t, err, ErrExtractLimit)
+
+	err = extractWith(t, &Extractor{Path: fp.Join(t.TempDir(), tarOutRoot), MaxEntries: 2}, entries)
+	assert.ErrorIs(t, err, ErrExtractLimit)
+
+	err = extractWith(t, &Extractor{Path: fp.Join(t.TempDir(), tarOutRoot), MaxBytes: 6, MaxEntries: 3}, entries)
+	assert.NoError(t, err)
+}
+
+func TestDryRun(t *testing.T) {
+	extractDir := fp.Join(t.TempDir(), tarOutRoot)
+
+	var reported []ExtractEntry
+	err := extractWith(t, &Extractor{
+		Path:   extractDir,
+		DryRun: true,
+		Report: func(e ExtractEntry) {
+			reported = append(reported, e)
+		},
+	}, []tarEntry{
+		&dirTarEntry{"root"},
+		&dirTarEntry{"root/a"},
+		&dirTarEntry{"root/a/b"},
+		&fileTarEntry{"root/a/b/file", []byte("data")},
+		&symlinkTarEntry{"b/file", "root/a/symlink"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []ExtractEntry{
+		{Path: extractDir, Typeflag: tar.TypeDir},
+		{Path: fp.Join(extractDir, "a"), Typeflag: tar.TypeDir},
+		{Path: fp.Join(extractDir, "a", "b"), Typeflag: tar.TypeDir},
+		{Path: fp.Join(extractDir, "a", "b", "file"), Typeflag: tar.TypeReg, Size: 4},
+		{Path: fp.Join(extractDir, "a", "symlink"), Typeflag: tar.TypeSymlink, Linkname: "b/file"},
+	}, reported)
+
+	_, err = os.Lstat(extractDir)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func extractWith(t *testing.T, te *Extractor, tarEntries []tarEntry) error {
+	tarFilename := fp.Join(t.TempDir(), "generated.tar")
+	writeTarFile(t, tarFilename, tarEntries)
+
+	tarReader, err := os.Open(tarFilename)
+	assert.NoError(t, err)
+	defer tarReader.Close()
+
+	return te.Extract(tarReader)
+}
+
 const tarOutRoot = "tar-out-root"
 
 func testTarExtraction(t *testing.T, setup func(t *testing.T, rootDir string), tarEntries []tarEntry, check func(t *testing.T, extractDir string), extractError error) {
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/ipfs/go-cid"
-	mh "github.com/multiformats/go-multihash"
 )
 
 var (
@@ -21,18 +20,5 @@ const (
 
 // ValidateCid validates multihash allowance behind given CID.
 func ValidateCid(allowlist Allowlist, c cid.Cid) error {
-	pref := c.Prefix()
-	if !allowlist.IsAllowed(pref.MhType) {
-		return ErrPossiblyInsecureHashFunction
-	}
-
-	if pref.MhType != mh.IDENTITY && pref.MhLength < minimumHashLength {
-		return ErrBelowMinimumHashLength
-	}
-
-	if pref.MhType != mh.IDENTITY && pref.MhLength > maximumHashLength {
-		return ErrAboveMaximumHashLength
-	}
-
-	return nil
+	return (&Policy{Hashes: allowlist}).Validate(c)
 }
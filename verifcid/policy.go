@@ -0,0 +1,90 @@
+package verifcid
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multicodec"
+	mh "github.com/multiformats/go-multihash"
+)
+
+var (
+	ErrCodecNotAllowed = errors.New("codec not allowed")
+	ErrCIDv0NotAllowed = errors.New("CIDv0 not allowed")
+)
+
+// Policy decides which CIDs are valid. The zero value, like a nil *Policy,
+// accepts the same CIDs as [ValidateCid] with the [DefaultAllowlist].
+type Policy struct {
+	// Hashes are the allowed multihash functions. It defaults to
+	// [DefaultAllowlist].
+	Hashes Allowlist
+
+	// Codecs are the allowed codecs. All the codecs are allowed when nil.
+	Codecs Allowlist
+
+	// MinHashLength and MaxHashLength bound the length of the digests, except
+	// for identity multihashes. They default to 20 and 128 bytes.
+	MinHashLength int
+	MaxHashLength int
+
+	// DisallowCIDv0 refuses CIDv0, which only exist for dag-pb and sha2-256.
+	DisallowCIDv0 bool
+}
+
+// DefaultPolicy is the policy applied by default.
+var DefaultPolicy = &Policy{}
+
+// Validate returns an error if the CID is not allowed by the policy.
+func (p *Policy) Validate(c cid.Cid) error {
+	if p == nil {
+		p = DefaultPolicy
+	}
+	pref := c.Prefix()
+
+	if pref.Version == 0 && p.DisallowCIDv0 {
+		return ErrCIDv0NotAllowed
+	}
+	if p.Codecs != nil && !p.Codecs.IsAllowed(pref.Codec) {
+		return fmt.Errorf("%w: %s", ErrCodecNotAllowed, multicodec.Code(pref.Codec))
+	}
+
+	hashes := p.Hashes
+	if hashes == nil {
+		hashes = DefaultAllowlist
+	}
+	if !hashes.IsAllowed(pref.MhType) {
+		return ErrPossiblyInsecureHashFunction
+	}
+
+	if pref.MhType == mh.IDENTITY {
+		return nil
+	}
+	if minLen := p.MinHashLength; minLen != 0 && minLen != minimumHashLength {
+		if pref.MhLength < minLen {
+			return &lengthError{ErrBelowMinimumHashLength, fmt.Sprintf("hashes must be at least %d bytes long", minLen)}
+		}
+	} else if pref.MhLength < minimumHashLength {
+		return ErrBelowMinimumHashLength
+	}
+	if maxLen := p.MaxHashLength; maxLen != 0 && maxLen != maximumHashLength {
+		if pref.MhLength > maxLen {
+			return &lengthError{ErrAboveMaximumHashLength, fmt.Sprintf("hashes must be at most %d bytes long", maxLen)}
+		}
+	} else if pref.MhLength > maximumHashLength {
+		return ErrAboveMaximumHashLength
+	}
+
+	return nil
+}
+
+// lengthError reports a digest length outside of the custom bounds of a
+// policy, and matches the default errors with [errors.Is].
+type lengthError struct {
+	err error
+	msg string
+}
+
+func (e *lengthError) Error() string { return e.msg }
+func (e *lengthError) Unwrap() error { return e.err }
@@ -0,0 +1,59 @@
+package verifcid
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestPolicy(t *testing.T) {
+	mhcid := func(version uint64, codec uint64, code uint64, length int) cid.Cid {
+		mhash, err := mh.Sum([]byte{}, code, length)
+		if err != nil {
+			t.Fatalf("%v: code: %x length: %d", err, code, length)
+		}
+		if version == 0 {
+			return cid.NewCidV0(mhash)
+		}
+		return cid.NewCidV1(codec, mhash)
+	}
+
+	strict := &Policy{
+		Hashes:        NewAllowlist(map[uint64]bool{mh.SHA2_256: true, mh.BLAKE3: true}),
+		Codecs:        NewAllowlist(map[uint64]bool{cid.Raw: true, cid.DagProtobuf: true}),
+		MinHashLength: 32,
+		MaxHashLength: 64,
+		DisallowCIDv0: true,
+	}
+
+	cases := []struct {
+		policy *Policy
+		cid    cid.Cid
+		err    error
+	}{
+		{nil, mhcid(0, cid.DagProtobuf, mh.SHA2_256, 32), nil},
+		{nil, mhcid(1, cid.DagCBOR, mh.SHA1, 20), nil},
+		{nil, mhcid(1, cid.Raw, mh.BLAKE3, 128), nil},
+		{&Policy{}, mhcid(1, cid.Raw, mh.SHA2_256, 16), ErrBelowMinimumHashLength},
+		{strict, mhcid(0, cid.DagProtobuf, mh.SHA2_256, 32), ErrCIDv0NotAllowed},
+		{strict, mhcid(1, cid.DagProtobuf, mh.SHA2_256, 32), nil},
+		{strict, mhcid(1, cid.DagCBOR, mh.SHA2_256, 32), ErrCodecNotAllowed},
+		{strict, mhcid(1, cid.Raw, mh.SHA1, 20), ErrPossiblyInsecureHashFunction},
+		{strict, mhcid(1, cid.Raw, mh.SHA2_256, 24), ErrBelowMinimumHashLength},
+		{strict, mhcid(1, cid.Raw, mh.BLAKE3, 64), nil},
+		{strict, mhcid(1, cid.Raw, mh.BLAKE3, 65), ErrAboveMaximumHashLength},
+	}
+
+	for i, cas := range cases {
+		err := cas.policy.Validate(cas.cid)
+		if !errors.Is(err, cas.err) {
+			t.Errorf("wrong result in case of %s (index %d). Expected: %v, got %v", cas.cid, i, cas.err, err)
+		}
+	}
+
+	if err := strict.Validate(mhcid(1, cid.Raw, mh.SHA2_256, 24)); err.Error() != "hashes must be at least 32 bytes long" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+}